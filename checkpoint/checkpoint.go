@@ -0,0 +1,458 @@
+// Package checkpoint records the state of files before mutating tools change them, so a workspace
+// can be rolled back to the state before any recorded call or explicit checkpoint.
+//
+// A Manager keeps an ordered manifest of entries: one per tool call (keyed by the call ID set with
+// WithCallID, see llmtools.WithCallID) listing the prior state of every path the call touched, plus
+// markers created with Checkpoint. File contents are stored once per digest in a directory on the OS
+// filesystem, bounded by disk usage caps: when the store is full, the oldest entries are pruned.
+//
+// Hosts pass a Manager to fstool.WithCheckpoints and texttool.WithCheckpoints; writefile,
+// deletefile, the text edit tools and applypatch then snapshot their targets before each change.
+package checkpoint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const (
+	// DefaultMaxBytes is the default cap on stored file contents (all entries).
+	DefaultMaxBytes int64 = 256 << 20
+	// DefaultMaxFileBytes is the default cap on the stored content of a single file.
+	DefaultMaxFileBytes int64 = 16 << 20
+
+	manifestName = "manifest.json"
+	blobDirName  = "blobs"
+)
+
+// ErrUnknownID is returned by RollbackTo for IDs that were never recorded, were already rolled back
+// or were pruned to stay within the disk usage caps.
+var ErrUnknownID = errors.New("unknown checkpoint id")
+
+type EntryKind string
+
+const (
+	EntryCall       EntryKind = "call"
+	EntryCheckpoint EntryKind = "checkpoint"
+)
+
+type FileType string
+
+const (
+	FileRegular FileType = "file"
+	FileDir     FileType = "dir"
+	FileSymlink FileType = "symlink"
+	FileMissing FileType = "missing"
+)
+
+// FileState is the state of one path before a call changed it.
+type FileState struct {
+	Path   string      `json:"path"`
+	Type   FileType    `json:"type"`
+	Mode   fs.FileMode `json:"mode,omitempty"`
+	Size   int64       `json:"size,omitempty"`
+	Blob   string      `json:"blob,omitempty"`   // SHA-256 of the content (regular files)
+	Target string      `json:"target,omitempty"` // symlinks
+	// Skipped is set when the content was not stored (over a cap); rollback cannot restore it.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Entry is one manifest record: a tool call with the prior state of the paths it touched, or an
+// explicit checkpoint marker.
+type Entry struct {
+	ID    string      `json:"id"`
+	Kind  EntryKind   `json:"kind"`
+	Tool  string      `json:"tool,omitempty"`
+	Label string      `json:"label,omitempty"`
+	Time  time.Time   `json:"time"`
+	Files []FileState `json:"files,omitempty"`
+}
+
+type manifest struct {
+	Next    uint64  `json:"next"` // sequence for generated IDs
+	Entries []Entry `json:"entries"`
+}
+
+// Manager records file states and rolls them back. It is safe for concurrent use.
+type Manager struct {
+	mu           sync.Mutex
+	fsys         vfs.FS
+	dir          string
+	maxBytes     int64
+	maxFileBytes int64
+
+	m     manifest
+	blobs map[string]int64 // stored blob digest -> size
+	used  int64
+}
+
+type Option func(*Manager) error
+
+// WithFS sets the filesystem whose files are recorded and restored (default: the OS filesystem). It
+// must be the filesystem the tools use.
+func WithFS(fsys vfs.FS) Option {
+	return func(m *Manager) error {
+		m.fsys = vfs.OrOS(fsys)
+		return nil
+	}
+}
+
+// WithMaxBytes caps the total size of stored file contents (default DefaultMaxBytes).
+func WithMaxBytes(n int64) Option {
+	return func(m *Manager) error {
+		if n <= 0 {
+			return errors.New("max bytes must be positive")
+		}
+		m.maxBytes = n
+		return nil
+	}
+}
+
+// WithMaxFileBytes caps the stored content of a single file (default DefaultMaxFileBytes). Larger
+// files are recorded as skipped.
+func WithMaxFileBytes(n int64) Option {
+	return func(m *Manager) error {
+		if n <= 0 {
+			return errors.New("max file bytes must be positive")
+		}
+		m.maxFileBytes = n
+		return nil
+	}
+}
+
+// New returns a Manager storing its manifest and file contents in the OS directory dir, which is
+// created if needed. An existing manifest in dir is loaded, so a session can resume its history.
+func New(dir string, opts ...Option) (*Manager, error) {
+	if !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("checkpoint dir must be absolute: %q", dir)
+	}
+	m := &Manager{
+		fsys:         vfs.OS(),
+		dir:          filepath.Clean(dir),
+		maxBytes:     DefaultMaxBytes,
+		maxFileBytes: DefaultMaxFileBytes,
+		blobs:        map[string]int64{},
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+	m.maxFileBytes = min(m.maxFileBytes, m.maxBytes)
+	if err := os.MkdirAll(filepath.Join(m.dir, blobDirName), 0o700); err != nil {
+		return nil, err
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FS returns the filesystem the manager records and restores.
+func (m *Manager) FS() vfs.FS { return m.fsys }
+
+// Usage reports the bytes of stored file contents and the cap.
+func (m *Manager) Usage() (used, limit int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used, m.maxBytes
+}
+
+// Entries returns a copy of the manifest, oldest first.
+func (m *Manager) Entries() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Entry, len(m.m.Entries))
+	for i, e := range m.m.Entries {
+		e.Files = slices.Clone(e.Files)
+		out[i] = e
+	}
+	return out
+}
+
+// Checkpoint records a marker and returns its ID; RollbackTo(id) restores the workspace as it was
+// when Checkpoint was called.
+func (m *Manager) Checkpoint(label string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := m.nextID("checkpoint")
+	m.m.Entries = append(m.m.Entries, Entry{ID: id, Kind: EntryCheckpoint, Label: label, Time: time.Now().UTC()})
+	if err := m.save(); err != nil {
+		m.m.Entries = m.m.Entries[:len(m.m.Entries)-1]
+		return "", err
+	}
+	return id, nil
+}
+
+// Snapshot records the current state of the absolute paths (directories recursively) under the call
+// ID in ctx, before tool changes them. Without a call ID, each Snapshot gets a generated one. Paths the
+// call already recorded are kept as they were, so the entry holds the state before the whole call.
+func (m *Manager) Snapshot(ctx context.Context, tool string, paths ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := CallID(ctx)
+	idx := -1
+	if id != "" {
+		idx = slices.IndexFunc(m.m.Entries, func(e Entry) bool { return e.ID == id })
+		// Only the latest entry can grow; anything else would record state out of order.
+		if idx >= 0 && (idx != len(m.m.Entries)-1 || m.m.Entries[idx].Kind != EntryCall) {
+			return fmt.Errorf("call id %q is already recorded", id)
+		}
+	}
+	if idx < 0 {
+		if id == "" {
+			id = m.nextID("call")
+		}
+		m.m.Entries = append(m.m.Entries, Entry{ID: id, Kind: EntryCall, Tool: tool, Time: time.Now().UTC()})
+		idx = len(m.m.Entries) - 1
+	}
+	seen := map[string]bool{}
+	for _, f := range m.m.Entries[idx].Files {
+		seen[f.Path] = true
+	}
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("checkpoint path must be absolute: %q", p)
+		}
+		err := m.record(ctx, filepath.Clean(p), seen, &idx)
+		if err != nil {
+			return err
+		}
+	}
+	return m.save()
+}
+
+// record appends the state of p (and, for directories, everything below it) to entry *idx. A missing
+// p is recorded together with its missing ancestors, so rollback also removes parents the call
+// creates. Pruning older entries to make room shifts *idx.
+func (m *Manager) record(ctx context.Context, p string, seen map[string]bool, idx *int) error {
+	var missing []string
+	for a := filepath.Dir(p); filepath.Dir(a) != a; a = filepath.Dir(a) {
+		if _, err := m.fsys.Lstat(a); err == nil {
+			break
+		}
+		missing = append(missing, a)
+	}
+	for _, a := range slices.Backward(missing) {
+		if !seen[a] {
+			seen[a] = true
+			m.m.Entries[*idx].Files = append(m.m.Entries[*idx].Files, FileState{Path: a, Type: FileMissing})
+		}
+	}
+	return vfs.WalkDir(m.fsys, p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if cerr := ctx.Err(); cerr != nil {
+			return cerr
+		}
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		st := FileState{Path: path, Type: FileMissing}
+		if err == nil {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if st, err = m.state(path, info, idx); err != nil {
+				return err
+			}
+		}
+		m.m.Entries[*idx].Files = append(m.m.Entries[*idx].Files, st)
+		return nil
+	})
+}
+
+func (m *Manager) state(path string, info fs.FileInfo, idx *int) (FileState, error) {
+	mode := info.Mode()
+	st := FileState{Path: path, Mode: mode}
+	switch {
+	case mode.IsDir():
+		st.Type = FileDir
+	case mode&fs.ModeSymlink != 0:
+		st.Type = FileSymlink
+		target, err := m.fsys.Readlink(path)
+		if err != nil {
+			return st, err
+		}
+		st.Target = target
+	case mode.IsRegular():
+		st.Type, st.Size = FileRegular, info.Size()
+		if info.Size() > m.maxFileBytes {
+			st.Skipped = fmt.Sprintf("file larger than %d bytes", m.maxFileBytes)
+			return st, nil
+		}
+		data, err := vfs.ReadFile(m.fsys, path)
+		if err != nil {
+			return st, err
+		}
+		sum := sha256.Sum256(data)
+		st.Blob, st.Size = hex.EncodeToString(sum[:]), int64(len(data))
+		if _, ok := m.blobs[st.Blob]; ok {
+			return st, nil
+		}
+		if !m.makeRoom(st.Size, idx) {
+			st.Blob, st.Skipped = "", fmt.Sprintf("checkpoint store full (%d bytes)", m.maxBytes)
+			return st, nil
+		}
+		if err := m.writeBlob(st.Blob, data); err != nil {
+			return st, err
+		}
+	default:
+		return st, fmt.Errorf("cannot checkpoint special file: %s", path)
+	}
+	return st, nil
+}
+
+// makeRoom prunes the oldest entries (never entry *idx) until n more bytes fit under the cap.
+func (m *Manager) makeRoom(n int64, idx *int) bool {
+	for m.used+n > m.maxBytes && *idx > 0 {
+		m.m.Entries = m.m.Entries[1:]
+		*idx--
+		m.gc()
+	}
+	return m.used+n <= m.maxBytes
+}
+
+func (m *Manager) nextID(prefix string) string {
+	m.m.Next++
+	return prefix + "-" + strconv.FormatUint(m.m.Next, 10)
+}
+
+func (m *Manager) blobPath(sum string) string {
+	return filepath.Join(m.dir, blobDirName, sum[:2], sum)
+}
+
+func (m *Manager) writeBlob(sum string, data []byte) error {
+	dst := m.blobPath(sum)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o700); err != nil {
+		return err
+	}
+	if err := writeCommit(vfs.OS(), dst, data, 0o600); err != nil {
+		return err
+	}
+	m.blobs[sum] = int64(len(data))
+	m.used += int64(len(data))
+	return nil
+}
+
+func (m *Manager) readBlob(sum string) ([]byte, error) {
+	data, err := os.ReadFile(m.blobPath(sum))
+	if err != nil {
+		return nil, err
+	}
+	if got := sha256.Sum256(data); hex.EncodeToString(got[:]) != sum {
+		return nil, fmt.Errorf("checkpoint blob %s is corrupt", sum)
+	}
+	return data, nil
+}
+
+// gc removes stored blobs that no entry references.
+func (m *Manager) gc() {
+	live := map[string]bool{}
+	for _, e := range m.m.Entries {
+		for _, f := range e.Files {
+			if f.Blob != "" {
+				live[f.Blob] = true
+			}
+		}
+	}
+	for sum, size := range m.blobs {
+		if !live[sum] {
+			_ = os.Remove(m.blobPath(sum))
+			delete(m.blobs, sum)
+			m.used -= size
+		}
+	}
+}
+
+func (m *Manager) load() error {
+	data, err := os.ReadFile(filepath.Join(m.dir, manifestName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &m.m); err != nil {
+		return fmt.Errorf("invalid checkpoint manifest: %w", err)
+	}
+	for _, e := range m.m.Entries {
+		for _, f := range e.Files {
+			if f.Blob != "" {
+				m.blobs[f.Blob] = f.Size
+			}
+		}
+	}
+	for _, size := range m.blobs {
+		m.used += size
+	}
+	return nil
+}
+
+func (m *Manager) save() error {
+	data, err := json.MarshalIndent(m.m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeCommit(vfs.OS(), filepath.Join(m.dir, manifestName), data, 0o600)
+}
+
+// writeCommit writes data to a temp file next to dst and atomically moves it into place.
+func writeCommit(fsys vfs.FS, dst string, data []byte, perm fs.FileMode) error {
+	tmp, err := fsys.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	name := tmp.Name()
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = ioutil.CommitTempFile(fsys, name, dst, perm, true)
+	}
+	if err != nil {
+		_ = fsys.Remove(name)
+	}
+	return err
+}
+
+type callIDKey struct{}
+
+// WithCallID returns a context carrying the ID of the tool call being made.
+func WithCallID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, callIDKey{}, id)
+}
+
+// CallID returns the call ID carried by ctx, or "".
+func CallID(ctx context.Context) string {
+	id, _ := ctx.Value(callIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,326 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// newWorkspace returns a MemFS holding root/{a.txt, sub/b.txt} and a Manager recording it.
+func newWorkspace(t *testing.T, opts ...Option) (m *Manager, fsys *vfs.MemFS, root string) {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys = vfs.NewMemFS()
+	if err := fsys.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, fsys, filepath.Join(root, "a.txt"), "alpha\n")
+	mustWrite(t, fsys, filepath.Join(root, "sub", "b.txt"), "beta\n")
+	m, err = New(t.TempDir(), append([]Option{WithFS(fsys)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, fsys, root
+}
+
+func mustWrite(t *testing.T, fsys vfs.FS, name, content string) {
+	t.Helper()
+	if err := vfs.WriteFile(fsys, name, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+// contents renders every file under root as "rel=content" (directories as "rel/").
+func contents(t *testing.T, fsys vfs.FS, root string) []string {
+	t.Helper()
+	var out []string
+	err := vfs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == root {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			out = append(out, rel+"/")
+			return nil
+		}
+		b, err := vfs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		out = append(out, rel+"="+string(b))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// change snapshots paths under the call ID and then applies fn, like a mutating tool would.
+func change(t *testing.T, m *Manager, id string, paths []string, fn func()) {
+	t.Helper()
+	ctx := t.Context()
+	if id != "" {
+		ctx = WithCallID(ctx, id)
+	}
+	if err := m.Snapshot(ctx, "test", paths...); err != nil {
+		t.Fatalf("snapshot %s: %v", id, err)
+	}
+	fn()
+}
+
+func TestRollbackTo(t *testing.T) {
+	t.Parallel()
+
+	initial := []string{"a.txt=alpha\n", "sub/", "sub/b.txt=beta\n"}
+	tests := []struct {
+		name string
+		// run changes the workspace and returns the ID to roll back to.
+		run          func(t *testing.T, m *Manager, fsys vfs.FS, root string) string
+		want         []string
+		wantRestored []string
+		wantRemoved  []string
+		wantEntries  []string
+	}{
+		{
+			name: "call id restores state before the call",
+			run: func(t *testing.T, m *Manager, fsys vfs.FS, root string) string {
+				t.Helper()
+				a := filepath.Join(root, "a.txt")
+				change(t, m, "c1", []string{a}, func() { mustWrite(t, fsys, a, "one\n") })
+				change(t, m, "c2", []string{a}, func() { mustWrite(t, fsys, a, "two\n") })
+				return "c2"
+			},
+			want:         []string{"a.txt=one\n", "sub/", "sub/b.txt=beta\n"},
+			wantRestored: []string{"a.txt"},
+			wantEntries:  []string{"c1"},
+		},
+		{
+			name: "checkpoint id keeps the marker",
+			run: func(t *testing.T, m *Manager, fsys vfs.FS, root string) string {
+				t.Helper()
+				id, err := m.Checkpoint("start")
+				if err != nil {
+					t.Fatal(err)
+				}
+				a := filepath.Join(root, "a.txt")
+				change(t, m, "c1", []string{a}, func() { mustWrite(t, fsys, a, "one\n") })
+				change(t, m, "c2", []string{a}, func() { mustWrite(t, fsys, a, "two\n") })
+				return id
+			},
+			want:         initial,
+			wantRestored: []string{"a.txt"},
+			wantEntries:  []string{"checkpoint-1"},
+		},
+		{
+			name: "created files and parents are removed",
+			run: func(t *testing.T, m *Manager, fsys vfs.FS, root string) string {
+				t.Helper()
+				p := filepath.Join(root, "new", "deep", "c.txt")
+				change(t, m, "c1", []string{p}, func() {
+					if err := fsys.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+						t.Fatal(err)
+					}
+					mustWrite(t, fsys, p, "gamma\n")
+				})
+				return "c1"
+			},
+			want:        initial,
+			wantRemoved: []string{"new", "new/deep", "new/deep/c.txt"},
+		},
+		{
+			name: "deleted directory is restored",
+			run: func(t *testing.T, m *Manager, fsys vfs.FS, root string) string {
+				t.Helper()
+				sub := filepath.Join(root, "sub")
+				change(t, m, "", []string{sub}, func() {
+					if err := fsys.RemoveAll(sub); err != nil {
+						t.Fatal(err)
+					}
+				})
+				return m.Entries()[0].ID
+			},
+			want:         initial,
+			wantRestored: []string{"sub", "sub/b.txt"},
+		},
+		{
+			name: "repeated snapshots in one call keep the first state",
+			run: func(t *testing.T, m *Manager, fsys vfs.FS, root string) string {
+				t.Helper()
+				a := filepath.Join(root, "a.txt")
+				change(t, m, "c1", []string{a}, func() { mustWrite(t, fsys, a, "one\n") })
+				change(t, m, "c1", []string{a}, func() { mustWrite(t, fsys, a, "two\n") })
+				return "c1"
+			},
+			want:         initial,
+			wantRestored: []string{"a.txt"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			m, fsys, root := newWorkspace(t)
+			id := tc.run(t, m, fsys, root)
+
+			res, err := m.RollbackTo(t.Context(), id)
+			if err != nil {
+				t.Fatalf("RollbackTo(%q): %v", id, err)
+			}
+			if got := contents(t, fsys, root); !slices.Equal(got, tc.want) {
+				t.Fatalf("contents = %q, want %q", got, tc.want)
+			}
+			if got := rels(res.Restored, root); !slices.Equal(got, tc.wantRestored) {
+				t.Errorf("restored = %q, want %q", got, tc.wantRestored)
+			}
+			if got := rels(res.Removed, root); !slices.Equal(got, tc.wantRemoved) {
+				t.Errorf("removed = %q, want %q", got, tc.wantRemoved)
+			}
+			var ids []string
+			for _, e := range m.Entries() {
+				ids = append(ids, e.ID)
+			}
+			if !slices.Equal(ids, tc.wantEntries) {
+				t.Errorf("entries = %q, want %q", ids, tc.wantEntries)
+			}
+		})
+	}
+}
+
+func rels(paths []string, root string) []string {
+	var out []string
+	for _, p := range paths {
+		rel, _ := filepath.Rel(root, p)
+		out = append(out, filepath.ToSlash(rel))
+	}
+	return out
+}
+
+func TestManager_Errors(t *testing.T) {
+	t.Parallel()
+
+	m, fsys, root := newWorkspace(t)
+	a := filepath.Join(root, "a.txt")
+	if _, err := m.RollbackTo(t.Context(), "nope"); !errors.Is(err, ErrUnknownID) {
+		t.Fatalf("unknown id: err = %v, want ErrUnknownID", err)
+	}
+	if err := m.Snapshot(t.Context(), "test", "rel.txt"); err == nil {
+		t.Fatal("expected error for a relative path")
+	}
+	change(t, m, "c1", []string{a}, func() { mustWrite(t, fsys, a, "one\n") })
+	change(t, m, "c2", []string{a}, func() {})
+	if err := m.Snapshot(WithCallID(t.Context(), "c1"), "test", a); err == nil {
+		t.Fatal("expected error for reusing an older call id")
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := m.Snapshot(ctx, "test", a); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled ctx: err = %v", err)
+	}
+	if _, err := New("rel"); err == nil {
+		t.Fatal("expected error for a relative dir")
+	}
+}
+
+func TestManager_Caps(t *testing.T) {
+	t.Parallel()
+
+	m, fsys, root := newWorkspace(t, WithMaxBytes(16), WithMaxFileBytes(8))
+	a := filepath.Join(root, "a.txt")
+	big := filepath.Join(root, "big.txt")
+	mustWrite(t, fsys, big, strings.Repeat("x", 9))
+
+	// Over the per-file cap: recorded as skipped, left alone by rollback.
+	change(t, m, "c1", []string{big}, func() { mustWrite(t, fsys, big, "small\n") })
+	res, err := m.RollbackTo(t.Context(), "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rels(res.Skipped, root); !slices.Equal(got, []string{"big.txt"}) {
+		t.Fatalf("skipped = %q", got)
+	}
+
+	// Distinct contents beyond the total cap prune the oldest entries.
+	for i, s := range []string{"1111111\n", "2222222\n", "3333333\n"} {
+		change(t, m, "", []string{a}, func() { mustWrite(t, fsys, a, s) })
+		if used, limit := m.Usage(); used > limit {
+			t.Fatalf("step %d: used %d > limit %d", i, used, limit)
+		}
+	}
+	entries := m.Entries()
+	if len(entries) == 0 || len(entries) >= 3 {
+		t.Fatalf("entries = %d, want pruning to keep 1-2", len(entries))
+	}
+	if _, err := m.RollbackTo(t.Context(), "call-1"); !errors.Is(err, ErrUnknownID) {
+		t.Fatalf("pruned id: err = %v, want ErrUnknownID", err)
+	}
+}
+
+func TestManager_Reload(t *testing.T) {
+	t.Parallel()
+
+	m, fsys, root := newWorkspace(t)
+	a := filepath.Join(root, "a.txt")
+	change(t, m, "c1", []string{a}, func() { mustWrite(t, fsys, a, "one\n") })
+
+	m2, err := New(m.dir, WithFS(fsys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used, _ := m2.Usage(); used == 0 {
+		t.Fatal("reloaded manager has no stored contents")
+	}
+	if _, err := m2.RollbackTo(t.Context(), "c1"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := vfs.ReadFile(fsys, a)
+	if err != nil || string(b) != "alpha\n" {
+		t.Fatalf("a.txt = %q, %v", b, err)
+	}
+	if used, _ := m2.Usage(); used != 0 {
+		t.Fatalf("usage after rollback = %d, want 0", used)
+	}
+}
+
+func TestManager_Tools(t *testing.T) {
+	t.Parallel()
+
+	m, fsys, root := newWorkspace(t)
+	a := filepath.Join(root, "a.txt")
+	cp, err := m.CreateCheckpoint(t.Context(), CreateCheckpointArgs{Label: " before edit "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	change(t, m, "c1", []string{a}, func() { mustWrite(t, fsys, a, "one\n") })
+
+	list, err := m.ListCheckpoints(t.Context(), ListCheckpointsArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entries) != 2 || list.Entries[0].Label != "before edit" || list.Entries[1].ID != "c1" ||
+		!slices.Equal(list.Entries[1].Paths, []string{a}) || list.MaxBytes != DefaultMaxBytes {
+		t.Fatalf("list = %+v", list)
+	}
+
+	res, err := m.RollbackCheckpoint(t.Context(), RollbackCheckpointArgs{ID: cp.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(res.Undone, []string{"c1"}) {
+		t.Fatalf("undone = %q", res.Undone)
+	}
+	b, err := vfs.ReadFile(fsys, a)
+	if err != nil || string(b) != "alpha\n" {
+		t.Fatalf("a.txt = %q, %v", b, err)
+	}
+}
@@ -0,0 +1,143 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+)
+
+// RollbackResult summarizes a rollback.
+type RollbackResult struct {
+	// Undone lists the IDs of the rolled-back entries, newest first.
+	Undone []string `json:"undone"`
+	// Restored lists paths whose content, directory or symlink was put back; Removed lists paths that
+	// did not exist before and were deleted.
+	Restored []string `json:"restored,omitempty"`
+	Removed  []string `json:"removed,omitempty"`
+	// Skipped lists paths whose content was never stored (see FileState.Skipped); they are left as is.
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// RollbackTo restores the files recorded after id to their earlier state, newest change first:
+//   - for a call ID, the state before that call;
+//   - for a checkpoint ID, the state when Checkpoint returned it.
+//
+// The undone entries are dropped from the manifest (a checkpoint marker itself is kept, so it can be
+// rolled back to again). Paths whose content was skipped are reported in the result and left as is.
+// If restoring a path fails, the remaining paths are still restored, the manifest is left unchanged
+// (so the rollback can be retried) and the errors are returned with the partial result.
+func (m *Manager) RollbackTo(ctx context.Context, id string) (*RollbackResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := slices.IndexFunc(m.m.Entries, func(e Entry) bool { return e.ID == id })
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownID, id)
+	}
+	from := idx
+	if m.m.Entries[idx].Kind == EntryCheckpoint {
+		from++
+	}
+
+	res := &RollbackResult{Undone: []string{}}
+	// The last action on a path wins: entries are undone newest first, so it is the oldest state.
+	actions := map[string]*[]string{}
+	var errs []error
+	for i := len(m.m.Entries) - 1; i >= from; i-- {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		e := m.m.Entries[i]
+		res.Undone = append(res.Undone, e.ID)
+		for _, f := range slices.Backward(e.Files) {
+			list, err := m.restore(f, res)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.Path, err))
+			} else if list != nil {
+				actions[f.Path] = list
+			}
+		}
+	}
+	for p, list := range actions {
+		*list = append(*list, p)
+	}
+	slices.Sort(res.Restored)
+	slices.Sort(res.Removed)
+	slices.Sort(res.Skipped)
+	if len(errs) > 0 {
+		return res, fmt.Errorf("rollback incomplete: %w", errors.Join(errs...))
+	}
+
+	m.m.Entries = m.m.Entries[:from]
+	m.gc()
+	if err := m.save(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// restore puts f back and returns the result list the path belongs in (nil if nothing changed).
+func (m *Manager) restore(f FileState, res *RollbackResult) (*[]string, error) {
+	cur, err := m.fsys.Lstat(f.Path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if f.Type == FileRegular && f.Skipped != "" {
+		return &res.Skipped, nil
+	}
+	// Replace anything of a different type. Directories are kept: their contents are restored entry
+	// by entry.
+	if exists {
+		replace := true
+		switch f.Type {
+		case FileDir:
+			replace = !cur.IsDir()
+		case FileRegular:
+			replace = !cur.Mode().IsRegular()
+		}
+		if replace {
+			if err := m.fsys.RemoveAll(f.Path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	switch f.Type {
+	case FileMissing:
+		if !exists {
+			return nil, nil
+		}
+		return &res.Removed, nil
+	case FileDir:
+		if err := m.fsys.MkdirAll(f.Path, f.Mode.Perm()); err != nil {
+			return nil, err
+		}
+		if err := m.fsys.Chmod(f.Path, f.Mode.Perm()); err != nil {
+			return nil, err
+		}
+	case FileSymlink:
+		if err := m.fsys.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := m.fsys.Symlink(f.Target, f.Path); err != nil {
+			return nil, err
+		}
+	case FileRegular:
+		data, err := m.readBlob(f.Blob)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.fsys.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+			return nil, err
+		}
+		if err := writeCommit(m.fsys, f.Path, data, f.Mode.Perm()); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown file type %q", f.Type)
+	}
+	return &res.Restored, nil
+}
@@ -0,0 +1,164 @@
+package checkpoint
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+)
+
+const (
+	createCheckpointFuncID   spec.FuncID = "github.com/flexigpt/llmtools-go/checkpoint/createcheckpoint.CreateCheckpoint"
+	listCheckpointsFuncID    spec.FuncID = "github.com/flexigpt/llmtools-go/checkpoint/listcheckpoints.ListCheckpoints"
+	rollbackCheckpointFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/checkpoint/rollbackcheckpoint.RollbackCheckpoint"
+)
+
+var createCheckpointTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f9b-7578-76b5-a296-dd696f7cee88",
+	Slug:          "createcheckpoint",
+	Version:       "v1.0.0",
+	DisplayName:   "Create checkpoint",
+	Description:   "Mark the current workspace state so later file changes (writes, deletes, text edits, patches) can be rolled back to it with rollbackcheckpoint.",
+	Tags:          []string{"fs", "checkpoint"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"label": {
+		"type": "string",
+		"description": "Optional short description of the state, shown by listcheckpoints."
+	}
+},
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: createCheckpointFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+var listCheckpointsTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f9b-759d-7d78-985e-fb6a54acc87e",
+	Slug:          "listcheckpoints",
+	Version:       "v1.0.0",
+	DisplayName:   "List checkpoints",
+	Description:   "List the recorded file-changing tool calls and checkpoints, oldest first, with the paths each call changed. Any listed ID can be passed to rollbackcheckpoint.",
+	Tags:          []string{"fs", "checkpoint"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {},
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: listCheckpointsFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+var rollbackCheckpointTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f9b-75c2-74e5-a33f-fdede1e48797",
+	Slug:          "rollbackcheckpoint",
+	Version:       "v1.0.0",
+	DisplayName:   "Roll back to checkpoint",
+	Description:   "Restore the files changed since a checkpoint (or since just before a recorded call) to their earlier content, and forget the undone history. Files created since then are deleted.",
+	Tags:          []string{"fs", "checkpoint"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"id": {
+		"type": "string",
+		"description": "Checkpoint ID, or call ID to restore the state before that call (see listcheckpoints)."
+	}
+},
+"required": ["id"],
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: rollbackCheckpointFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type CreateCheckpointArgs struct {
+	Label string `json:"label,omitempty"`
+}
+
+type CreateCheckpointOut struct {
+	ID string `json:"id"`
+}
+
+type ListCheckpointsArgs struct{}
+
+// ListedEntry is a manifest entry as shown to the model.
+type ListedEntry struct {
+	ID    string    `json:"id"`
+	Kind  EntryKind `json:"kind"`
+	Tool  string    `json:"tool,omitempty"`
+	Label string    `json:"label,omitempty"`
+	Time  time.Time `json:"time"`
+	Paths []string  `json:"paths,omitempty"`
+}
+
+type ListCheckpointsOut struct {
+	Entries   []ListedEntry `json:"entries"`
+	UsedBytes int64         `json:"usedBytes"`
+	MaxBytes  int64         `json:"maxBytes"`
+}
+
+type RollbackCheckpointArgs struct {
+	ID string `json:"id"`
+}
+
+func (m *Manager) CreateCheckpointTool() spec.Tool { return toolutil.CloneTool(createCheckpointTool) }
+func (m *Manager) ListCheckpointsTool() spec.Tool  { return toolutil.CloneTool(listCheckpointsTool) }
+func (m *Manager) RollbackCheckpointTool() spec.Tool {
+	return toolutil.CloneTool(rollbackCheckpointTool)
+}
+
+func (m *Manager) CreateCheckpoint(ctx context.Context, args CreateCheckpointArgs) (*CreateCheckpointOut, error) {
+	return toolutil.WithRecoveryResp(func() (*CreateCheckpointOut, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		id, err := m.Checkpoint(strings.TrimSpace(args.Label))
+		if err != nil {
+			return nil, err
+		}
+		return &CreateCheckpointOut{ID: id}, nil
+	})
+}
+
+func (m *Manager) ListCheckpoints(ctx context.Context, _ ListCheckpointsArgs) (*ListCheckpointsOut, error) {
+	return toolutil.WithRecoveryResp(func() (*ListCheckpointsOut, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entries := m.Entries()
+		out := &ListCheckpointsOut{Entries: make([]ListedEntry, 0, len(entries))}
+		for _, e := range entries {
+			le := ListedEntry{ID: e.ID, Kind: e.Kind, Tool: e.Tool, Label: e.Label, Time: e.Time}
+			for _, f := range e.Files {
+				le.Paths = append(le.Paths, f.Path)
+			}
+			out.Entries = append(out.Entries, le)
+		}
+		out.UsedBytes, out.MaxBytes = m.Usage()
+		return out, nil
+	})
+}
+
+func (m *Manager) RollbackCheckpoint(ctx context.Context, args RollbackCheckpointArgs) (*RollbackResult, error) {
+	return toolutil.WithRecoveryResp(func() (*RollbackResult, error) {
+		return m.RollbackTo(ctx, strings.TrimSpace(args.ID))
+	})
+}
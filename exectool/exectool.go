@@ -27,6 +27,7 @@ type ExecTool struct {
 	cfg        execToolConfig
 	toolPolicy *execToolPolicy
 	sessions   *executil.SessionStore
+	view       *overlayView // nil unless WithOverlay
 }
 
 type ExecToolOption func(*ExecTool) error
@@ -146,6 +147,12 @@ func NewExecTool(opts ...ExecToolOption) (*ExecTool, error) {
 		}
 	}
 
+	if et.view != nil {
+		if err := et.view.init(&et.cfg); err != nil {
+			return nil, err
+		}
+	}
+
 	// Canonicalize/initialize path policy (fspolicy is the single source of truth).
 	fsPol, err := fspolicy.New(et.cfg.workBaseDir, et.cfg.allowedRoots, et.cfg.blockSymlinks)
 	if err != nil {
@@ -174,14 +181,34 @@ func (et *ExecTool) ShellCommandTool() spec.Tool { return toolutil.CloneTool(she
 func (et *ExecTool) RunScript(ctx context.Context, args RunScriptArgs) (*RunScriptOut, error) {
 	return toolutil.WithRecoveryResp(func() (*RunScriptOut, error) {
 		p := et.snapshotPolicy()
-		return runScript(ctx, args, *p)
+		if et.view != nil {
+			args.Path = et.view.mapArg(args.Path)
+			args.WorkDir = et.view.mapArg(args.WorkDir)
+		}
+		out, err := inView(ctx, et.view, func() (*RunScriptOut, error) { return runScript(ctx, args, *p) })
+		if out != nil && et.view != nil {
+			out.Path = et.view.fromView(out.Path)
+		}
+		return out, err
 	})
 }
 
 func (et *ExecTool) ShellCommand(ctx context.Context, args ShellCommandArgs) (*ShellCommandOut, error) {
 	return toolutil.WithRecoveryResp(func() (*ShellCommandOut, error) {
 		p := et.snapshotPolicy()
-		return shellCommand(ctx, args, *p, et.sessions)
+		if et.view != nil {
+			args.WorkDir = et.view.mapArg(args.WorkDir)
+		}
+		out, err := inView(ctx, et.view, func() (*ShellCommandOut, error) {
+			return shellCommand(ctx, args, *p, et.sessions)
+		})
+		if out != nil && et.view != nil {
+			out.WorkDir = et.view.fromView(out.WorkDir)
+			for i := range out.Results {
+				out.Results[i].WorkDir = et.view.fromView(out.Results[i].WorkDir)
+			}
+		}
+		return out, err
 	})
 }
 
@@ -0,0 +1,130 @@
+package exectool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/flexigpt/llmtools-go/overlay"
+)
+
+// WithOverlay runs commands against a materialized view of the overlay workspace ws instead of the
+// real tree. Before each call the merged view of root is mirrored into the OS directory viewDir (see
+// overlay.Workspace.Materialize); afterwards whatever the command changed there is absorbed back into
+// ws as pending changes, so exec tools see and produce the same speculative state as the file tools.
+//
+// With an overlay, workBaseDir and allowedRoots are overlay paths inside root (both default to root;
+// a relative workBaseDir is relative to root). Absolute workDir/path arguments under root are mapped
+// into the view, and returned work dirs are mapped back. Changes a command makes outside viewDir are
+// not captured. Calls are serialized.
+func WithOverlay(ws *overlay.Workspace, root, viewDir string) ExecToolOption {
+	return func(et *ExecTool) error {
+		if ws == nil {
+			return errors.New("overlay workspace is required")
+		}
+		if !filepath.IsAbs(root) || !filepath.IsAbs(viewDir) {
+			return fmt.Errorf("overlay root and view dir must be absolute: %q, %q", root, viewDir)
+		}
+		et.view = &overlayView{ws: ws, root: filepath.Clean(root), dir: filepath.Clean(viewDir)}
+		return nil
+	}
+}
+
+// overlayView is the materialized view of an overlay workspace that commands run in.
+type overlayView struct {
+	mu   sync.Mutex
+	ws   *overlay.Workspace
+	root string // overlay path
+	dir  string // OS directory holding the view
+}
+
+// init materializes the view and maps the configured base dir and roots into it.
+func (v *overlayView) init(cfg *execToolConfig) error {
+	if err := os.MkdirAll(v.dir, 0o755); err != nil {
+		return err
+	}
+	if err := v.ws.Materialize(context.Background(), v.root, v.dir); err != nil {
+		return fmt.Errorf("materialize overlay view: %w", err)
+	}
+
+	base := strings.TrimSpace(cfg.workBaseDir)
+	if base == "" {
+		base = v.root
+	} else if !filepath.IsAbs(base) {
+		base = filepath.Join(v.root, base)
+	}
+	mapped, ok := v.toView(base)
+	if !ok {
+		return fmt.Errorf("work base dir is outside the overlay root: %s", base)
+	}
+	cfg.workBaseDir = mapped
+
+	roots := cfg.allowedRoots
+	if len(roots) == 0 {
+		roots = []string{v.root}
+	}
+	cfg.allowedRoots = make([]string, 0, len(roots))
+	for _, r := range roots {
+		mapped, ok := v.toView(filepath.Clean(r))
+		if !ok {
+			return fmt.Errorf("allowed root is outside the overlay root: %s", r)
+		}
+		cfg.allowedRoots = append(cfg.allowedRoots, mapped)
+	}
+	return nil
+}
+
+// toView maps an absolute overlay path under root into the view.
+func (v *overlayView) toView(p string) (string, bool) {
+	return rebase(p, v.root, v.dir)
+}
+
+// fromView maps a path inside the view back to the overlay.
+func (v *overlayView) fromView(p string) string {
+	if mapped, ok := rebase(p, v.dir, v.root); ok {
+		return mapped
+	}
+	return p
+}
+
+// mapArg maps an absolute path argument into the view; relative and other paths pass through.
+func (v *overlayView) mapArg(p string) string {
+	if t := strings.TrimSpace(p); filepath.IsAbs(t) {
+		if mapped, ok := v.toView(filepath.Clean(t)); ok {
+			return mapped
+		}
+	}
+	return p
+}
+
+func rebase(p, from, to string) (string, bool) {
+	rel, err := filepath.Rel(from, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.Join(to, rel), true
+}
+
+// inView runs fn against a fresh view of the overlay and absorbs its changes afterwards, even if fn
+// failed or ctx was canceled (the command may have changed files before stopping). With no overlay,
+// it just calls fn.
+func inView[T any](ctx context.Context, v *overlayView, fn func() (T, error)) (T, error) {
+	if v == nil {
+		return fn()
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.ws.Materialize(ctx, v.root, v.dir); err != nil {
+		var zero T
+		return zero, fmt.Errorf("materialize overlay view: %w", err)
+	}
+	out, err := fn()
+	if aerr := v.ws.Absorb(context.WithoutCancel(ctx), v.root, v.dir); aerr != nil {
+		err = errors.Join(err, fmt.Errorf("absorb overlay view: %w", aerr))
+	}
+	return out, err
+}
@@ -0,0 +1,63 @@
+package exectool
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/overlay"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestShellCommand_Overlay(t *testing.T) {
+	if runtime.GOOS == toolutil.GOOSWindows {
+		t.Skip("unix command expectations")
+	}
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("disk\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ws := overlay.New(nil)
+	if err := vfs.WriteFile(ws, filepath.Join(root, "pending.txt"), []byte("pending\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	view := filepath.Join(t.TempDir(), "view")
+	et, err := NewExecTool(WithOverlay(ws, root, view))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := et.ShellCommand(t.Context(), ShellCommandArgs{
+		Shell:    ShellNameSh,
+		WorkDir:  root,
+		Commands: []string{`cat pending.txt && printf 'edited\n' > a.txt`},
+	})
+	if err != nil {
+		t.Fatalf("ShellCommand error: %v", err)
+	}
+	if r := out.Results[0]; r.ExitCode != 0 || r.Stdout != "pending\n" {
+		t.Fatalf("result = %+v", r)
+	}
+	if out.WorkDir != root {
+		t.Fatalf("workDir = %q, want the overlay path %q", out.WorkDir, root)
+	}
+
+	// The command's edit is a pending overlay change; the real file is untouched.
+	b, err := os.ReadFile(filepath.Join(root, "a.txt"))
+	if err != nil || string(b) != "disk\n" {
+		t.Fatalf("disk a.txt = %q, %v", b, err)
+	}
+	b, err = vfs.ReadFile(ws, filepath.Join(root, "a.txt"))
+	if err != nil || string(b) != "edited\n" {
+		t.Fatalf("overlay a.txt = %q, %v", b, err)
+	}
+
+	if _, err := NewExecTool(WithOverlay(ws, root, view), WithWorkBaseDir(t.TempDir())); err == nil {
+		t.Fatal("expected error for a work base dir outside the overlay root")
+	}
+}
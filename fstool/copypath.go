@@ -0,0 +1,186 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+)
+
+const copyPathFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/copypath.CopyPath"
+
+var copyPathTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f13-c81c-7991-a565-1d23aa7fdc60",
+	Slug:          "copypath",
+	Version:       "v1.0.0",
+	DisplayName:   "Copy path",
+	Description:   "Copy a file or a directory tree (recursive, bounded) to a new path. Writes are atomic per file; directory copies appear complete or not at all unless merging into an existing directory with overwrite=true.",
+	Tags:          []string{"fs"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"sourcePath": {
+		"type": "string",
+		"description": "Path of the file or directory to copy."
+	},
+	"destinationPath": {
+		"type": "string",
+		"description": "Destination path (the full new path, not a parent directory)."
+	},
+	"overwrite": {
+		"type": "boolean",
+		"description": "If false and the destination exists, return an error. If true, files are replaced and directories are merged.",
+		"default": false
+	},
+	"createParents": {
+		"type": "boolean",
+		"description": "If true, create missing parent directories of the destination. Max new directories created is 8.",
+		"default": false
+	}
+},
+"required": ["sourcePath", "destinationPath"],
+"additionalProperties": false
+}`),
+
+	GoImpl: spec.GoToolImpl{FuncID: copyPathFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type CopyPathArgs struct {
+	SourcePath      string `json:"sourcePath"`
+	DestinationPath string `json:"destinationPath"`
+	Overwrite       bool   `json:"overwrite,omitempty"`
+	CreateParents   bool   `json:"createParents,omitempty"`
+}
+
+type CopyPathOut struct {
+	SourcePath      string `json:"sourcePath"`
+	DestinationPath string `json:"destinationPath"`
+	IsDir           bool   `json:"isDir"`
+	FilesCopied     int    `json:"filesCopied"`
+	DirsCopied      int    `json:"dirsCopied,omitempty"`
+	SymlinksCopied  int    `json:"symlinksCopied,omitempty"`
+	BytesCopied     int64  `json:"bytesCopied"`
+}
+
+// copyPath copies a file or directory tree.
+//
+// Behavior notes (entry point):
+//   - Source and destination are both resolved and checked via policy.
+//   - Files are streamed into a temp file and committed atomically.
+//   - Directory copies are bounded by toolutil.MaxTreeEntries/MaxTreeBytes and checked before any write.
+//   - Symlinks are never followed. A symlink source is refused; symlinks inside a directory tree are refused when the
+//     policy blocks symlinks, else recreated as links.
+func copyPath(
+	ctx context.Context,
+	args CopyPathArgs,
+	p fspolicy.FSPolicy,
+) (*CopyPathOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	src, dst, srcInfo, err := resolveSourceAndDestination(p, args.SourcePath, args.DestinationPath, args.CreateParents)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &CopyPathOut{
+		SourcePath:      src,
+		DestinationPath: dst,
+		IsDir:           srcInfo.IsDir(),
+	}
+
+	if srcInfo.IsDir() {
+		stats, err := ioutil.CopyTreeResolved(ctx, p, src, dst, args.Overwrite, ioutil.TreeLimits{
+			MaxEntries: toolutil.MaxTreeEntries,
+			MaxBytes:   toolutil.MaxTreeBytes,
+		})
+		if err != nil {
+			return nil, wrapExistErr(err, dst, args.Overwrite)
+		}
+		out.FilesCopied = stats.Files
+		out.DirsCopied = stats.Dirs
+		out.SymlinksCopied = stats.Symlinks
+		out.BytesCopied = stats.Bytes
+		return out, nil
+	}
+
+	if (srcInfo.Mode() & os.ModeSymlink) != 0 {
+		return nil, fmt.Errorf("refusing to copy symlink file: %s", src)
+	}
+	if srcInfo.Size() > toolutil.MaxTreeBytes {
+		return nil, fmt.Errorf("file too large to copy (%d bytes; max %d)", srcInfo.Size(), toolutil.MaxTreeBytes)
+	}
+	n, err := ioutil.CopyFileAtomicResolved(ctx, p, src, dst, srcInfo.Mode().Perm(), args.Overwrite)
+	if err != nil {
+		return nil, wrapExistErr(err, dst, args.Overwrite)
+	}
+	out.FilesCopied = 1
+	out.BytesCopied = n
+	return out, nil
+}
+
+// resolveSourceAndDestination resolves and policy-checks both sides of a copy/move:
+//   - source must exist; symlink sources are refused when the policy blocks symlinks.
+//   - destination parent is verified (or created, bounded, when createParents is true).
+//   - source and destination must differ.
+func resolveSourceAndDestination(
+	p fspolicy.FSPolicy,
+	srcPath, dstPath string,
+	createParents bool,
+) (src, dst string, srcInfo os.FileInfo, err error) {
+	src, err = p.ResolvePath(srcPath, "")
+	if err != nil {
+		return "", "", nil, err
+	}
+	dst, err = p.ResolvePath(dstPath, "")
+	if err != nil {
+		return "", "", nil, err
+	}
+	if src == dst {
+		return "", "", nil, fmt.Errorf("source and destination are the same path: %s", src)
+	}
+
+	if p.BlockSymlinks() {
+		if err := p.VerifyDirResolved(filepath.Dir(src)); err != nil {
+			return "", "", nil, err
+		}
+	}
+	srcInfo, err = p.FS().Lstat(src)
+	if err != nil {
+		return "", "", nil, err // preserves os.IsNotExist
+	}
+	if (srcInfo.Mode()&os.ModeSymlink) != 0 && p.BlockSymlinks() {
+		return "", "", nil, fmt.Errorf("%w: refusing to operate on symlink: %s", fspolicy.ErrSymlinkDisallowed, src)
+	}
+
+	parent := filepath.Dir(dst)
+	if createParents {
+		if _, err := p.EnsureDirResolved(parent, 8); err != nil {
+			return "", "", nil, err
+		}
+	} else {
+		if err := p.VerifyDirResolved(parent); err != nil {
+			return "", "", nil, err
+		}
+	}
+	return src, dst, srcInfo, nil
+}
+
+func wrapExistErr(err error, dst string, overwrite bool) error {
+	if !overwrite && errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("destination already exists and overwrite=false: %s: %w", dst, err)
+	}
+	return err
+}
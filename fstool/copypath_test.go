@@ -0,0 +1,308 @@
+package fstool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+)
+
+func TestCopyPath(t *testing.T) {
+	type cfg struct {
+		workBaseDir   string
+		allowedRoots  []string
+		blockSymlinks bool
+	}
+
+	makeTool := func(t *testing.T, c cfg) *FSTool {
+		t.Helper()
+		opts := []FSToolOption{WithWorkBaseDir(c.workBaseDir), WithBlockSymlinks(c.blockSymlinks)}
+		if c.allowedRoots != nil {
+			opts = append(opts, WithAllowedRoots(c.allowedRoots))
+		}
+		return mustNewFSTool(t, opts...)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     func(t *testing.T) cfg
+		ctx     func(t *testing.T) context.Context
+		args    func(t *testing.T, c cfg) CopyPathArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, c cfg, out *CopyPathOut)
+	}{
+		{
+			name: "context_canceled",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			ctx: canceledContext,
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				return CopyPathArgs{SourcePath: "a.txt", DestinationPath: "b.txt"}
+			},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name: "copies_file",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("hello"))
+				return CopyPathArgs{SourcePath: "a.txt", DestinationPath: "b.txt"}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *CopyPathOut) {
+				t.Helper()
+				if out.IsDir || out.FilesCopied != 1 || out.BytesCopied != 5 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "b.txt"))); got != "hello" {
+					t.Fatalf("dst content=%q", got)
+				}
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "a.txt"))); got != "hello" {
+					t.Fatalf("src content=%q", got)
+				}
+			},
+		},
+		{
+			name: "file_exists_overwrite_false_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("new"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "b.txt"), []byte("old"))
+				return CopyPathArgs{SourcePath: "a.txt", DestinationPath: "b.txt"}
+			},
+			wantErr: wantErrContains("overwrite=false"),
+			check: func(t *testing.T, c cfg, out *CopyPathOut) {
+				t.Helper()
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "b.txt"))); got != "old" {
+					t.Fatalf("dst content=%q want old", got)
+				}
+			},
+		},
+		{
+			name: "file_exists_overwrite_true_replaces",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("new"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "b.txt"), []byte("old"))
+				return CopyPathArgs{SourcePath: "a.txt", DestinationPath: "b.txt", Overwrite: true}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *CopyPathOut) {
+				t.Helper()
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "b.txt"))); got != "new" {
+					t.Fatalf("dst content=%q want new", got)
+				}
+			},
+		},
+		{
+			name: "copies_directory_tree_with_create_parents",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "src", "sub"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "src", "a.txt"), []byte("a"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "src", "sub", "b.txt"), []byte("bb"))
+				return CopyPathArgs{SourcePath: "src", DestinationPath: "x/y/dst", CreateParents: true}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *CopyPathOut) {
+				t.Helper()
+				if !out.IsDir || out.FilesCopied != 2 || out.DirsCopied != 1 || out.BytesCopied != 3 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				dst := filepath.Join(c.workBaseDir, "x", "y", "dst")
+				if got := string(mustReadFile(t, filepath.Join(dst, "sub", "b.txt"))); got != "bb" {
+					t.Fatalf("nested content=%q", got)
+				}
+				entries, err := os.ReadDir(filepath.Join(c.workBaseDir, "x", "y"))
+				if err != nil || len(entries) != 1 {
+					t.Fatalf("expected no staging leftovers, entries=%v err=%v", entries, err)
+				}
+			},
+		},
+		{
+			name: "directory_merge_requires_overwrite",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "src"))
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "dst"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "src", "a.txt"), []byte("a"))
+				return CopyPathArgs{SourcePath: "src", DestinationPath: "dst"}
+			},
+			wantErr: wantErrContains("overwrite=false"),
+		},
+		{
+			name: "directory_merge_keeps_existing_and_replaces_same_names",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "src"))
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "dst"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "src", "a.txt"), []byte("new"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "dst", "a.txt"), []byte("old"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "dst", "keep.txt"), []byte("keep"))
+				return CopyPathArgs{SourcePath: "src", DestinationPath: "dst", Overwrite: true}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *CopyPathOut) {
+				t.Helper()
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "dst", "a.txt"))); got != "new" {
+					t.Fatalf("a.txt=%q want new", got)
+				}
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "dst", "keep.txt"))); got != "keep" {
+					t.Fatalf("keep.txt=%q want keep", got)
+				}
+			},
+		},
+		{
+			name: "directory_into_itself_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "src"))
+				return CopyPathArgs{SourcePath: "src", DestinationPath: "src/inner"}
+			},
+			wantErr: wantErrContains("inside source"),
+		},
+		{
+			name: "same_path_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("a"))
+				return CopyPathArgs{SourcePath: "a.txt", DestinationPath: "./a.txt"}
+			},
+			wantErr: wantErrContains("same path"),
+		},
+		{
+			name: "missing_parent_without_create_parents_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("a"))
+				return CopyPathArgs{SourcePath: "a.txt", DestinationPath: "missing/b.txt"}
+			},
+			wantErr: wantErrAny,
+		},
+		{
+			name: "destination_outside_allowed_roots_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				root := t.TempDir()
+				return cfg{workBaseDir: root, allowedRoots: []string{root}}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("a"))
+				return CopyPathArgs{SourcePath: "a.txt", DestinationPath: filepath.Join(t.TempDir(), "b.txt")}
+			},
+			wantErr: wantErrContains("outside allowed roots"),
+		},
+		{
+			name: "tree_with_symlink_refused_when_blockSymlinks_true",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				if runtime.GOOS == toolutil.GOOSWindows {
+					t.Skip("symlink tests are unreliable on Windows CI")
+				}
+				return cfg{workBaseDir: t.TempDir(), blockSymlinks: true}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "src"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "target.txt"), []byte("t"))
+				mustSymlinkOrSkip(t, filepath.Join(c.workBaseDir, "target.txt"), filepath.Join(c.workBaseDir, "src", "l"))
+				return CopyPathArgs{SourcePath: "src", DestinationPath: "dst"}
+			},
+			wantErr: wantErrContains("symlink"),
+			check: func(t *testing.T, c cfg, out *CopyPathOut) {
+				t.Helper()
+				if _, err := os.Lstat(filepath.Join(c.workBaseDir, "dst")); !os.IsNotExist(err) {
+					t.Fatalf("expected no destination, stat err=%v", err)
+				}
+			},
+		},
+		{
+			name: "tree_symlink_recreated_when_allowed",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				if runtime.GOOS == toolutil.GOOSWindows {
+					t.Skip("symlink tests are unreliable on Windows CI")
+				}
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CopyPathArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "src"))
+				mustSymlinkOrSkip(t, "nowhere", filepath.Join(c.workBaseDir, "src", "l"))
+				return CopyPathArgs{SourcePath: "src", DestinationPath: "dst"}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *CopyPathOut) {
+				t.Helper()
+				target, err := os.Readlink(filepath.Join(c.workBaseDir, "dst", "l"))
+				if err != nil || target != "nowhere" {
+					t.Fatalf("Readlink=%q err=%v", target, err)
+				}
+				if out.SymlinksCopied != 1 {
+					t.Fatalf("SymlinksCopied=%d want 1", out.SymlinksCopied)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.cfg(t)
+			ft := makeTool(t, c)
+			ctx := t.Context()
+			if tt.ctx != nil {
+				ctx = tt.ctx(t)
+			}
+			out, err := ft.CopyPath(ctx, tt.args(t, c))
+			if !tt.wantErr(err) {
+				t.Fatalf("err=%v did not match expectation", err)
+			}
+			if tt.check != nil {
+				tt.check(t, c, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/archiveutil"
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/spec"
+)
+
+const createArchiveFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/createarchive.CreateArchive"
+
+// createArchiveMaxSources bounds the number of source paths per call.
+const createArchiveMaxSources = 256
+
+var createArchiveTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f3e-6166-753c-8c67-0f56ad8ace4f",
+	Slug:          "createarchive",
+	Version:       "v1.0.0",
+	DisplayName:   "Create archive",
+	Description:   "Create a zip, tar, tar.gz or tar.zst archive from files and directories. Each source is stored under its base name; directories are added recursively (bounded). The archive is written atomically.",
+	Tags:          []string{"fs", "archive", "write"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"archivePath": {
+		"type": "string",
+		"description": "Archive file to create."
+	},
+	"sources": {
+		"type": "array",
+		"items": {"type": "string"},
+		"minItems": 1,
+		"maxItems": 256,
+		"description": "Files and directories to add. Each is stored under its base name, which must be unique."
+	},
+	"format": {
+		"type": "string",
+		"enum": ["zip", "tar", "tar.gz", "tar.zst"],
+		"description": "Archive format. Default: inferred from the archivePath extension."
+	},
+	"overwrite": {
+		"type": "boolean",
+		"description": "If false and archivePath exists, return an error.",
+		"default": false
+	},
+	"createParents": {
+		"type": "boolean",
+		"description": "If true, create missing parent directories of archivePath. Max new directories created is 8.",
+		"default": false
+	}
+},
+"required": ["archivePath", "sources"],
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: createArchiveFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type CreateArchiveArgs struct {
+	ArchivePath   string   `json:"archivePath"`
+	Sources       []string `json:"sources"`
+	Format        string   `json:"format,omitempty"`
+	Overwrite     bool     `json:"overwrite,omitempty"`
+	CreateParents bool     `json:"createParents,omitempty"`
+}
+
+type CreateArchiveOut struct {
+	ArchivePath string             `json:"archivePath"`
+	Format      archiveutil.Format `json:"format"`
+	SizeBytes   int64              `json:"sizeBytes"` // size of the archive file
+	archiveutil.Stats
+}
+
+// createArchive writes an archive of the given sources.
+//
+// Behavior notes (entry point):
+//   - Sources are resolved and checked via policy. Directories are walked without following symlinks;
+//     symlinks are refused when the policy blocks them, else stored as links.
+//   - Totals are capped by toolutil.MaxTreeEntries/MaxTreeBytes.
+//   - The archive is streamed into a temp file next to archivePath and committed atomically, so a failed
+//     call leaves no partial archive. archivePath may not be inside a source directory.
+func createArchive(ctx context.Context, args CreateArchiveArgs, p fspolicy.FSPolicy) (*CreateArchiveOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(args.Sources) == 0 {
+		return nil, errors.New("sources is required")
+	}
+	if len(args.Sources) > createArchiveMaxSources {
+		return nil, fmt.Errorf("too many sources: %d (max %d)", len(args.Sources), createArchiveMaxSources)
+	}
+	limits, err := archiveLimits(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := resolveArchiveOutputPath(p, args.ArchivePath, args.CreateParents)
+	if err != nil {
+		return nil, err
+	}
+	format, err := archiveFormat(p.FS(), args.Format, dst, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]archiveutil.Source, 0, len(args.Sources))
+	names := make(map[string]string, len(args.Sources))
+	for _, s := range args.Sources {
+		abs, isDir, err := resolveExistingFileOrDir(p, s)
+		if err != nil {
+			return nil, err
+		}
+		if abs == dst || (isDir && strings.HasPrefix(dst, abs+string(filepath.Separator))) {
+			return nil, fmt.Errorf("archivePath must not be inside a source: %s", abs)
+		}
+		name := filepath.Base(abs)
+		if prev, ok := names[name]; ok {
+			return nil, fmt.Errorf("sources %s and %s have the same base name %q", prev, abs, name)
+		}
+		names[name] = abs
+		sources = append(sources, archiveutil.Source{AbsPath: abs, Name: name})
+	}
+
+	pr, pw := io.Pipe()
+	var stats archiveutil.Stats
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var cerr error
+		stats, cerr = archiveutil.Create(ctx, p, pw, format, sources, limits)
+		_ = pw.CloseWithError(cerr)
+	}()
+	n, err := ioutil.WriteFileAtomicReaderResolved(ctx, p, dst, pr, 0o600, args.Overwrite)
+	_ = pr.CloseWithError(err) // unblocks the writer if the commit side failed first
+	<-done
+	if err != nil {
+		return nil, wrapExistErr(err, dst, args.Overwrite)
+	}
+	return &CreateArchiveOut{ArchivePath: dst, Format: format, SizeBytes: n, Stats: stats}, nil
+}
@@ -0,0 +1,124 @@
+package fstool
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/archiveutil"
+)
+
+func TestCreateArchive(t *testing.T) {
+	setupTree := func(t *testing.T, tmp string) {
+		t.Helper()
+		mustMkdirAll(t, filepath.Join(tmp, "d", "sub"))
+		mustWriteFile(t, filepath.Join(tmp, "d", "a.txt"), []byte("alpha"))
+		mustWriteFile(t, filepath.Join(tmp, "d", "sub", "b.txt"), []byte("beta"))
+		mustWriteFile(t, filepath.Join(tmp, "f.txt"), []byte("file"))
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, tmp string)
+		ctx     func(t *testing.T) context.Context
+		args    CreateArchiveArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, ft *FSTool, out *CreateArchiveOut)
+	}{
+		{
+			name:    "context_canceled",
+			setup:   setupTree,
+			ctx:     canceledContext,
+			args:    CreateArchiveArgs{ArchivePath: "a.zip", Sources: []string{"d"}},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name:    "zip_round_trip",
+			setup:   setupTree,
+			args:    CreateArchiveArgs{ArchivePath: "a.zip", Sources: []string{"d", "f.txt"}},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, ft *FSTool, out *CreateArchiveOut) {
+				t.Helper()
+				if out.Format != archiveutil.FormatZip || out.Files != 3 || out.Dirs != 2 || out.Bytes != 13 || out.SizeBytes == 0 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				list, err := ft.ExtractArchive(t.Context(), ExtractArchiveArgs{ArchivePath: "a.zip", ListOnly: true})
+				if err != nil || list.Files != 3 || list.Entries[0].Name != "d/" {
+					t.Fatalf("list: %+v, %v", list, err)
+				}
+			},
+		},
+		{
+			name:    "tar_zst_explicit_format",
+			setup:   setupTree,
+			args:    CreateArchiveArgs{ArchivePath: "out/bundle", Sources: []string{"d"}, Format: "tar.zst", CreateParents: true},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, ft *FSTool, out *CreateArchiveOut) {
+				t.Helper()
+				ex, err := ft.ExtractArchive(t.Context(), ExtractArchiveArgs{ArchivePath: "out/bundle", DestinationPath: "x"})
+				if err != nil || ex.Format != archiveutil.FormatTarZst || ex.Files != 2 {
+					t.Fatalf("extract: %+v, %v", ex, err)
+				}
+			},
+		},
+		{
+			name: "existing_without_overwrite",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				setupTree(t, tmp)
+				mustWriteFile(t, filepath.Join(tmp, "a.tar"), []byte("old"))
+			},
+			args:    CreateArchiveArgs{ArchivePath: "a.tar", Sources: []string{"f.txt"}},
+			wantErr: wantErrContains("overwrite=false"),
+		},
+		{
+			name:    "unknown_extension_errors",
+			setup:   setupTree,
+			args:    CreateArchiveArgs{ArchivePath: "a.rar", Sources: []string{"f.txt"}},
+			wantErr: wantErrContains("cannot infer archive format"),
+		},
+		{
+			name:    "archive_inside_source_errors",
+			setup:   setupTree,
+			args:    CreateArchiveArgs{ArchivePath: "d/self.zip", Sources: []string{"d"}},
+			wantErr: wantErrContains("must not be inside a source"),
+		},
+		{
+			name:    "duplicate_base_names_error",
+			setup:   setupTree,
+			args:    CreateArchiveArgs{ArchivePath: "a.zip", Sources: []string{"d/a.txt", "d/sub/../a.txt"}},
+			wantErr: wantErrContains("same base name"),
+		},
+		{
+			name:    "missing_source_errors",
+			args:    CreateArchiveArgs{ArchivePath: "a.zip", Sources: []string{"nope"}},
+			wantErr: wantErrContains("path does not exist"),
+		},
+		{
+			name:    "no_sources_errors",
+			args:    CreateArchiveArgs{ArchivePath: "a.zip"},
+			wantErr: wantErrContains("sources is required"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			if tc.setup != nil {
+				tc.setup(t, tmp)
+			}
+			ft := mustNewFSTool(t, WithWorkBaseDir(tmp), WithBlockSymlinks(true))
+			ctx := t.Context()
+			if tc.ctx != nil {
+				ctx = tc.ctx(t)
+			}
+			out, err := ft.CreateArchive(ctx, tc.args)
+			if !tc.wantErr(err) {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if err == nil && tc.check != nil {
+				tc.check(t, ft, out)
+			}
+		})
+	}
+}
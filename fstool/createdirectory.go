@@ -0,0 +1,141 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const createDirectoryFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/createdirectory.CreateDirectory"
+
+// createDirectoryMaxNewDirs bounds directories created by a single call when createParents is set.
+const createDirectoryMaxNewDirs = 8
+
+var createDirectoryTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f19-b5ca-73c6-8ccf-0fed4364071d",
+	Slug:          "createdirectory",
+	Version:       "v1.0.0",
+	DisplayName:   "Create directory",
+	Description:   "Create a directory. Succeeds without changes if the directory already exists. Use createParents to also create missing parent directories (bounded).",
+	Tags:          []string{"fs"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"path": {
+		"type": "string",
+		"description": "Path of the directory to create."
+	},
+	"createParents": {
+		"type": "boolean",
+		"description": "If true, create missing parent directories too. Max new directories created (including the target) is 8.",
+		"default": false
+	}
+},
+"required": ["path"],
+"additionalProperties": false
+}`),
+
+	GoImpl: spec.GoToolImpl{FuncID: createDirectoryFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type CreateDirectoryArgs struct {
+	Path          string `json:"path"`
+	CreateParents bool   `json:"createParents,omitempty"`
+}
+
+type CreateDirectoryOut struct {
+	Path           string `json:"path"`
+	AlreadyExisted bool   `json:"alreadyExisted"`
+	DirsCreated    int    `json:"dirsCreated"`
+}
+
+// createDirectory creates a directory (and optionally its parents).
+//
+// Behavior notes (entry point):
+//   - Existing directories are reported with AlreadyExisted=true; existing non-directories fail.
+//   - Without CreateParents only the final component may be missing.
+//   - The number of new directories is bounded by createDirectoryMaxNewDirs and checked before creating anything.
+//   - When the policy blocks symlinks, every path component is verified (and created) without traversing symlinks.
+func createDirectory(
+	ctx context.Context,
+	args CreateDirectoryArgs,
+	p fspolicy.FSPolicy,
+) (*CreateDirectoryOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dir, err := p.ResolvePath(args.Path, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if st, err := p.FS().Lstat(dir); err == nil {
+		if (st.Mode()&os.ModeSymlink) != 0 && p.BlockSymlinks() {
+			return nil, fmt.Errorf("%w: path is a symlink: %s", fspolicy.ErrSymlinkDisallowed, dir)
+		}
+		if err := p.VerifyDirResolved(dir); err != nil {
+			return nil, err
+		}
+		return &CreateDirectoryOut{Path: dir, AlreadyExisted: true}, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	maxNewDirs := 1
+	if args.CreateParents {
+		maxNewDirs = createDirectoryMaxNewDirs
+	}
+	missing, err := countMissingDirs(p.FS(), dir)
+	if err != nil {
+		return nil, err
+	}
+	if missing > maxNewDirs {
+		if !args.CreateParents {
+			return nil, fmt.Errorf("parent directory does not exist (set createParents=true): %s", filepath.Dir(dir))
+		}
+		return nil, fmt.Errorf("too many directories to create: %d (max %d)", missing, maxNewDirs)
+	}
+
+	created, err := p.EnsureDirResolved(dir, maxNewDirs)
+	if err != nil {
+		return nil, err
+	}
+	if !p.BlockSymlinks() {
+		// EnsureDirResolved does not count when symlinks are allowed.
+		created = missing
+	}
+	return &CreateDirectoryOut{Path: dir, DirsCreated: created}, nil
+}
+
+// countMissingDirs counts how many trailing components of an absolute dir do not exist yet.
+func countMissingDirs(fsys vfs.FS, dir string) (int, error) {
+	missing := 0
+	cur := filepath.Clean(dir)
+	for {
+		_, err := fsys.Lstat(cur)
+		if err == nil {
+			return missing, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return missing, err
+		}
+		missing++
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return missing, nil
+		}
+		cur = parent
+	}
+}
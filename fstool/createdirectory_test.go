@@ -0,0 +1,213 @@
+package fstool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+)
+
+func TestCreateDirectory(t *testing.T) {
+	type cfg struct {
+		workBaseDir   string
+		allowedRoots  []string
+		blockSymlinks bool
+	}
+
+	makeTool := func(t *testing.T, c cfg) *FSTool {
+		t.Helper()
+		opts := []FSToolOption{WithWorkBaseDir(c.workBaseDir), WithBlockSymlinks(c.blockSymlinks)}
+		if c.allowedRoots != nil {
+			opts = append(opts, WithAllowedRoots(c.allowedRoots))
+		}
+		return mustNewFSTool(t, opts...)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     func(t *testing.T) cfg
+		ctx     func(t *testing.T) context.Context
+		args    func(t *testing.T, c cfg) CreateDirectoryArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, c cfg, out *CreateDirectoryOut)
+	}{
+		{
+			name: "context_canceled",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			ctx: canceledContext,
+			args: func(t *testing.T, c cfg) CreateDirectoryArgs {
+				t.Helper()
+				return CreateDirectoryArgs{Path: "d"}
+			},
+			wantErr: wantErrIs(context.Canceled),
+			check: func(t *testing.T, c cfg, out *CreateDirectoryOut) {
+				t.Helper()
+				if _, err := os.Lstat(filepath.Join(c.workBaseDir, "d")); !os.IsNotExist(err) {
+					t.Fatalf("expected no directory, stat err=%v", err)
+				}
+			},
+		},
+		{
+			name: "creates_single_directory",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir(), blockSymlinks: true}
+			},
+			args: func(t *testing.T, c cfg) CreateDirectoryArgs {
+				t.Helper()
+				return CreateDirectoryArgs{Path: "d"}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *CreateDirectoryOut) {
+				t.Helper()
+				if out.AlreadyExisted || out.DirsCreated != 1 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if st, err := os.Stat(filepath.Join(c.workBaseDir, "d")); err != nil || !st.IsDir() {
+					t.Fatalf("expected directory, err=%v", err)
+				}
+			},
+		},
+		{
+			name: "existing_directory_is_idempotent",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CreateDirectoryArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "d"))
+				return CreateDirectoryArgs{Path: "d"}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *CreateDirectoryOut) {
+				t.Helper()
+				if !out.AlreadyExisted || out.DirsCreated != 0 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "existing_file_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CreateDirectoryArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "f"), []byte("x"))
+				return CreateDirectoryArgs{Path: "f"}
+			},
+			wantErr: wantErrContains("not a directory"),
+		},
+		{
+			name: "missing_parent_without_createParents_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CreateDirectoryArgs {
+				t.Helper()
+				return CreateDirectoryArgs{Path: "a/b"}
+			},
+			wantErr: wantErrContains("createParents"),
+			check: func(t *testing.T, c cfg, out *CreateDirectoryOut) {
+				t.Helper()
+				if _, err := os.Lstat(filepath.Join(c.workBaseDir, "a")); !os.IsNotExist(err) {
+					t.Fatalf("expected nothing created, stat err=%v", err)
+				}
+			},
+		},
+		{
+			name: "createParents_creates_and_counts",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) CreateDirectoryArgs {
+				t.Helper()
+				return CreateDirectoryArgs{Path: "a/b/c", CreateParents: true}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *CreateDirectoryOut) {
+				t.Helper()
+				if out.DirsCreated != 3 {
+					t.Fatalf("dirsCreated=%d want 3", out.DirsCreated)
+				}
+			},
+		},
+		{
+			name: "createParents_bound_exceeded_creates_nothing",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir(), blockSymlinks: true}
+			},
+			args: func(t *testing.T, c cfg) CreateDirectoryArgs {
+				t.Helper()
+				return CreateDirectoryArgs{Path: "1/2/3/4/5/6/7/8/9", CreateParents: true}
+			},
+			wantErr: wantErrContains("too many directories"),
+			check: func(t *testing.T, c cfg, out *CreateDirectoryOut) {
+				t.Helper()
+				if _, err := os.Lstat(filepath.Join(c.workBaseDir, "1")); !os.IsNotExist(err) {
+					t.Fatalf("expected nothing created, stat err=%v", err)
+				}
+			},
+		},
+		{
+			name: "outside_allowed_roots_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				root := t.TempDir()
+				return cfg{workBaseDir: root, allowedRoots: []string{root}}
+			},
+			args: func(t *testing.T, c cfg) CreateDirectoryArgs {
+				t.Helper()
+				return CreateDirectoryArgs{Path: filepath.Join(t.TempDir(), "d")}
+			},
+			wantErr: wantErrContains("outside allowed roots"),
+		},
+		{
+			name: "symlink_parent_refused_when_blockSymlinks_true",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				if runtime.GOOS == toolutil.GOOSWindows {
+					t.Skip("symlink tests are unreliable on Windows CI")
+				}
+				return cfg{workBaseDir: t.TempDir(), blockSymlinks: true}
+			},
+			args: func(t *testing.T, c cfg) CreateDirectoryArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "real"))
+				mustSymlinkOrSkip(t, filepath.Join(c.workBaseDir, "real"), filepath.Join(c.workBaseDir, "link"))
+				return CreateDirectoryArgs{Path: "link/d"}
+			},
+			wantErr: wantErrIs(fspolicy.ErrSymlinkDisallowed),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.cfg(t)
+			ft := makeTool(t, c)
+			ctx := t.Context()
+			if tt.ctx != nil {
+				ctx = tt.ctx(t)
+			}
+			out, err := ft.CreateDirectory(ctx, tt.args(t, c))
+			if !tt.wantErr(err) {
+				t.Fatalf("err=%v did not match expectation", err)
+			}
+			if tt.check != nil {
+				tt.check(t, c, out)
+			}
+		})
+	}
+}
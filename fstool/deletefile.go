@@ -8,12 +8,13 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
-	"syscall"
+	"time"
 
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
 	"github.com/flexigpt/llmtools-go/internal/ioutil"
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
 	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 const deleteFileFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/deletefile.DeleteFile"
@@ -24,7 +25,7 @@ var deleteFileTool = spec.Tool{
 	Slug:          "deletefile",
 	Version:       "v1.0.0",
 	DisplayName:   "Delete file",
-	Description:   "Safely delete a file by moving it to a trash directory. trashDir=auto will try to use the system trash when possible; otherwise falls back to a local .trash directory.",
+	Description:   "Safely delete a file (or, with recursive=true, a directory tree) by moving it to a trash directory. trashDir=auto will try to use the system trash when possible; otherwise falls back to a local .trash directory in the workspace root holding the file.",
 	Tags:          []string{"fs"},
 
 	ArgSchema: spec.JSONSchema(`{
@@ -33,7 +34,12 @@ var deleteFileTool = spec.Tool{
 "properties": {
 	"path": {
 		"type": "string",
-		"description": "Path of the file to delete."
+		"description": "Path of the file or directory to delete."
+	},
+	"recursive": {
+		"type": "boolean",
+		"default": false,
+		"description": "Required to delete a directory. The whole tree is moved to trash; max entries is 10000 and max total size is 256MB."
 	},
 	"trashDir": {
 		"type": "string",
@@ -52,8 +58,9 @@ var deleteFileTool = spec.Tool{
 }
 
 type DeleteFileArgs struct {
-	Path     string `json:"path"`
-	TrashDir string `json:"trashDir,omitempty"` // "auto" default
+	Path      string `json:"path"`
+	TrashDir  string `json:"trashDir,omitempty"` // "auto" default
+	Recursive bool   `json:"recursive,omitempty"`
 }
 
 type DeleteFileMethod string
@@ -68,6 +75,20 @@ type DeleteFileOut struct {
 	OriginalPath string           `json:"originalPath"`
 	TrashedPath  string           `json:"trashedPath"`
 	Method       DeleteFileMethod `json:"method"`
+	// InfoPath is the .trashinfo metadata file used by restore/list/purge (empty if it could not be written).
+	InfoPath string `json:"infoPath,omitempty"`
+
+	// Directory deletes only: summary of the trashed tree (root not counted).
+	IsDir           bool  `json:"isDir,omitempty"`
+	FilesTrashed    int   `json:"filesTrashed,omitempty"`
+	DirsTrashed     int   `json:"dirsTrashed,omitempty"`
+	SymlinksTrashed int   `json:"symlinksTrashed,omitempty"`
+	BytesTrashed    int64 `json:"bytesTrashed,omitempty"`
+}
+
+var deleteTreeLimits = ioutil.TreeLimits{
+	MaxEntries: toolutil.MaxTreeEntries,
+	MaxBytes:   toolutil.MaxTreeBytes,
 }
 
 type trashCandidate struct {
@@ -75,6 +96,13 @@ type trashCandidate struct {
 	allowCrossDeviceCopy bool
 }
 
+// deleteFile moves a file, symlink or (with Recursive) a directory tree into a trash directory.
+//
+// Behavior notes (entry point):
+//   - Directories require Recursive=true and are bounded by toolutil.MaxTreeEntries/MaxTreeBytes.
+//   - The work base dir, allowed roots and filesystem roots are never deleted.
+//   - Symlinks are trashed as links (never followed) and refused when the policy blocks symlinks.
+//   - Original path and deletion time are recorded in a .trashinfo file (see trashinfo.go).
 func deleteFile(
 	ctx context.Context,
 	args DeleteFileArgs,
@@ -97,16 +125,24 @@ func deleteFile(
 		}
 	}
 
-	st, err := os.Lstat(src)
+	st, err := p.FS().Lstat(src)
 	if err != nil {
 		return nil, err // preserves os.IsNotExist
 	}
+	var stats ioutil.TreeStats
 	if st.IsDir() {
-		return nil, fmt.Errorf("path is a directory, not a file: %s", src)
-	}
-
-	// Allow regular files and symlinks; refuse other special files.
-	if !st.Mode().IsRegular() && (st.Mode()&os.ModeSymlink) == 0 {
+		if !args.Recursive {
+			return nil, fmt.Errorf("path is a directory, not a file (set recursive=true to delete it): %s", src)
+		}
+		if err := refuseProtectedDir(p, src); err != nil {
+			return nil, err
+		}
+		stats, err = ioutil.ScanTree(ctx, p, src, deleteTreeLimits)
+		if err != nil {
+			return nil, err
+		}
+	} else if !st.Mode().IsRegular() && (st.Mode()&os.ModeSymlink) == 0 {
+		// Allow regular files and symlinks; refuse other special files.
 		return nil, fmt.Errorf("refusing to delete non-regular file: %s", src)
 	}
 	if (st.Mode()&os.ModeSymlink) != 0 && p.BlockSymlinks() {
@@ -120,15 +156,16 @@ func deleteFile(
 
 	candidates := []trashCandidate{}
 	if trashDirIn == "auto" {
-		if sys, ok := detectSystemTrashDir(); ok {
+		// The system trash exists only on the real filesystem.
+		if sys, ok := detectSystemTrashDir(); ok && vfs.IsOS(p.FS()) {
 			if td, rerr := p.ResolvePath(sys, ""); rerr == nil {
 				// "auto" should prefer system trash *when possible*; treat EXDEV as "not possible"
 				// so we can fall back to a same-filesystem .trash instead of doing a huge copy.
 				candidates = append(candidates, trashCandidate{dir: td, allowCrossDeviceCopy: false})
 			}
 		}
-		// Always provide a same-filesystem-ish fallback near the file.
-		local := filepath.Join(filepath.Dir(src), ".trash")
+		// Always provide a local fallback in the root holding the file.
+		local := localTrashDir(p, src)
 		if td, rerr := p.ResolvePath(local, ""); rerr == nil {
 			candidates = append(candidates, trashCandidate{dir: td, allowCrossDeviceCopy: true})
 		}
@@ -148,6 +185,10 @@ func deleteFile(
 		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
+		if st.IsDir() && isSameOrInside(src, td) {
+			lastErr = fmt.Errorf("trash directory is inside the directory being deleted: %s", td)
+			continue
+		}
 
 		// Create trash dir if needed (policy enforces symlink rules when enabled).
 		if _, err := p.EnsureDirResolved(td, 0 /*unlimited*/); err != nil {
@@ -155,13 +196,21 @@ func deleteFile(
 			continue
 		}
 
-		trashedPath, method, _, err := moveToTrash(ctx, src, td, st, c.allowCrossDeviceCopy)
+		trashedPath, method, _, err := moveToTrash(ctx, p, src, td, st, c.allowCrossDeviceCopy)
 
 		if err == nil {
+			// Metadata is best-effort: the delete already succeeded and must not be reported as failed.
+			infoPath, _ := writeTrashInfo(p, td, trashedPath, src, time.Now())
 			return &DeleteFileOut{
-				OriginalPath: src,
-				TrashedPath:  trashedPath,
-				Method:       method,
+				OriginalPath:    src,
+				TrashedPath:     trashedPath,
+				Method:          method,
+				InfoPath:        infoPath,
+				IsDir:           st.IsDir(),
+				FilesTrashed:    stats.Files,
+				DirsTrashed:     stats.Dirs,
+				SymlinksTrashed: stats.Symlinks,
+				BytesTrashed:    stats.Bytes,
 			}, nil
 		}
 
@@ -195,6 +244,7 @@ func detectSystemTrashDir() (string, bool) {
 
 func moveToTrash(
 	ctx context.Context,
+	p fspolicy.FSPolicy,
 	src, trashDir string,
 	srcInfo os.FileInfo,
 	allowCrossDeviceCopy bool,
@@ -203,18 +253,20 @@ func moveToTrash(
 	if base == "" || base == string(os.PathSeparator) || base == "." {
 		return "", "", 0, ioutil.ErrInvalidPath
 	}
+	base = trashEntryBase(p.FS(), trashDir, base)
 
 	for range 12 {
-		dest, err := ioutil.UniquePathInDir(trashDir, base)
+		dest, err := ioutil.UniquePathInDir(p.FS(), trashDir, base)
 		if err != nil {
 			return "", "", 0, err
 		}
 
 		// On Unix, reserve with a placeholder so rename can't race-overwrite an entry.
 		// On Windows, a placeholder breaks rename (rename fails if dest exists), so skip it.
+		// Directories are never reserved: os.Rename of a directory refuses any existing dest.
 		reserved := false
-		if runtime.GOOS != toolutil.GOOSWindows {
-			f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if runtime.GOOS != toolutil.GOOSWindows && !srcInfo.IsDir() {
+			f, err := p.FS().OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
 			if err != nil {
 				if errors.Is(err, os.ErrExist) {
 					continue
@@ -226,52 +278,68 @@ func moveToTrash(
 		}
 
 		// Try rename first.
-		if err := os.Rename(src, dest); err == nil {
+		if err := p.FS().Rename(src, dest); err == nil {
 			return dest, DeleteFileMethodRename, 0, nil
 		} else {
-			// Windows: if we lost a race and dest now exists, retry with a new name.
-			if runtime.GOOS == toolutil.GOOSWindows {
-				if _, stErr := os.Lstat(dest); stErr == nil {
+			// Unreserved dest (Windows, directories): if we lost a race and dest now exists, retry with a new name.
+			if !reserved {
+				if _, stErr := p.FS().Lstat(dest); stErr == nil {
 					continue
 				}
 			}
 
 			// If not EXDEV, fail (but clean placeholder on Unix).
-			if !isCrossDeviceRenameErr(err) {
+			if !ioutil.IsCrossDeviceErr(err) {
 				if reserved {
-					_ = os.Remove(dest)
+					_ = p.FS().Remove(dest)
 				}
 				return "", "", 0, err
 			}
 			// EXDEV: only do copy fallback when allowed; otherwise let caller try next candidate.
 			if !allowCrossDeviceCopy {
 				if reserved {
-					_ = os.Remove(dest)
+					_ = p.FS().Remove(dest)
 				}
 				return "", "", 0, err
 			}
 
+			// Directory: bounded, staged tree copy then remove.
+			if srcInfo.IsDir() {
+				stats, cerr := ioutil.CopyTreeResolved(ctx, p, src, dest, false, deleteTreeLimits)
+				if cerr != nil {
+					if errors.Is(cerr, os.ErrExist) {
+						continue
+					}
+					return "", "", 0, cerr
+				}
+				if rmErr := p.FS().RemoveAll(src); rmErr != nil {
+					// The trash copy is complete; keep it so nothing is lost and report the partial removal.
+					return "", "", 0, fmt.Errorf("copied to trash at %s but failed to remove original: %w", dest, rmErr)
+				}
+				return dest, DeleteFileMethodCopyAndRemove, stats.Bytes, nil
+			}
+
 			// Symlink: recreate link in trash then remove original link.
 			if (srcInfo.Mode() & os.ModeSymlink) != 0 {
 				if reserved {
-					_ = os.Remove(dest)
+					_ = p.FS().Remove(dest)
 				}
-				target, rerr := os.Readlink(src)
+				target, rerr := p.FS().Readlink(src)
 				if rerr != nil {
 					return "", "", 0, rerr
 				}
-				if serr := os.Symlink(target, dest); serr != nil {
+				if serr := p.FS().Symlink(target, dest); serr != nil {
 					// If it now exists, retry with a new dest.
 					if errors.Is(serr, os.ErrExist) {
 						continue
 					}
-					if _, stErr := os.Lstat(dest); stErr == nil {
+					if _, stErr := p.FS().Lstat(dest); stErr == nil {
 						continue
 					}
 					return "", "", 0, serr
 				}
-				if rmErr := os.Remove(src); rmErr != nil {
-					_ = os.Remove(dest)
+				if rmErr := p.FS().Remove(src); rmErr != nil {
+					_ = p.FS().Remove(dest)
 					return "", "", 0, rmErr
 				}
 				return dest, DeleteFileMethodSymlinkRehome, 0, nil
@@ -282,20 +350,20 @@ func moveToTrash(
 			var cerr error
 			if reserved {
 				// Copy into the already-reserved placeholder to avoid a remove+race+recreate window.
-				n, cerr = ioutil.CopyFileToExistingCtx(ctx, src, dest)
+				n, cerr = ioutil.CopyFileToExistingCtx(ctx, p.FS(), src, dest)
 			} else {
-				n, cerr = ioutil.CopyFileCtx(ctx, src, dest, 0o600)
+				n, cerr = ioutil.CopyFileCtx(ctx, p.FS(), src, dest, 0o600)
 			}
 			if cerr != nil {
-				_ = os.Remove(dest)
+				_ = p.FS().Remove(dest)
 				// If destination now exists (race), retry with a new name.
 				if errors.Is(cerr, os.ErrExist) {
 					continue
 				}
 				return "", "", 0, cerr
 			}
-			if rmErr := os.Remove(src); rmErr != nil {
-				_ = os.Remove(dest)
+			if rmErr := p.FS().Remove(src); rmErr != nil {
+				_ = p.FS().Remove(dest)
 				return "", "", 0, rmErr
 			}
 			return dest, DeleteFileMethodCopyAndRemove, n, nil
@@ -304,7 +372,33 @@ func moveToTrash(
 	return "", "", 0, fmt.Errorf("could not allocate a unique trash path for %q", base)
 }
 
-// isCrossDeviceRenameErr - In go 1.25: syscall.EXDEV exists on Windows too, and os.LinkError unwraps to the errno.
-func isCrossDeviceRenameErr(err error) bool {
-	return errors.Is(err, syscall.EXDEV)
+// refuseProtectedDir refuses deleting a filesystem root, or a directory that is (or contains)
+// the work base dir or an allowed root. dir is compared both as given and with symlinks resolved, since
+// the roots are canonical and dir may be reached through a symlinked parent.
+func refuseProtectedDir(p fspolicy.FSPolicy, dir string) error {
+	dirs := []string{dir}
+	if real, err := p.FS().EvalSymlinks(dir); err == nil && real != dir {
+		dirs = append(dirs, real)
+	}
+	protected := append([]string{p.WorkBaseDir()}, p.AllowedRoots()...)
+	for _, d := range dirs {
+		if filepath.Dir(d) == d {
+			return fmt.Errorf("refusing to delete filesystem root: %s", dir)
+		}
+		for _, r := range protected {
+			if r != "" && isSameOrInside(d, r) {
+				return fmt.Errorf("refusing to delete a directory containing a configured root: %s", dir)
+			}
+		}
+	}
+	return nil
+}
+
+// isSameOrInside reports whether path is root or lies inside root (lexically).
+func isSameOrInside(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)))
 }
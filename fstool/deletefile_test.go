@@ -107,6 +107,120 @@ func TestDeleteFile(t *testing.T) {
 			},
 			wantErr: wantErrAny,
 		},
+		{
+			name: "recursive_directory_moves_tree_with_summary",
+			cfg: func(t *testing.T) policyCfg {
+				t.Helper()
+				tmp := t.TempDir()
+				return policyCfg{workBaseDir: tmp, blockSymlinks: true}
+			},
+			setup: func(t *testing.T, cfg policyCfg) (string, DeleteFileArgs, func(t *testing.T, out *DeleteFileOut)) {
+				t.Helper()
+				src := filepath.Join(cfg.workBaseDir, "d")
+				mustMkdirAll(t, filepath.Join(src, "sub"))
+				mustWriteFile(t, filepath.Join(src, "a.txt"), []byte("abc"))
+				mustWriteFile(t, filepath.Join(src, "sub", "b.txt"), []byte("de"))
+				trash := filepath.Join(cfg.workBaseDir, "trash")
+				return src, DeleteFileArgs{Path: src, TrashDir: trash, Recursive: true}, func(t *testing.T, out *DeleteFileOut) {
+					t.Helper()
+					if !out.IsDir || out.FilesTrashed != 2 || out.DirsTrashed != 1 || out.BytesTrashed != 5 {
+						t.Fatalf("unexpected summary: %+v", out)
+					}
+					if _, err := os.Lstat(src); !os.IsNotExist(err) {
+						t.Fatalf("expected original dir gone, stat err=%v", err)
+					}
+					if got := string(mustReadFile(t, filepath.Join(out.TrashedPath, "sub", "b.txt"))); got != "de" {
+						t.Fatalf("trashed content=%q", got)
+					}
+				}
+			},
+		},
+		{
+			name: "recursive_directory_name_collision_gets_unique_name",
+			cfg: func(t *testing.T) policyCfg {
+				t.Helper()
+				tmp := t.TempDir()
+				return policyCfg{workBaseDir: tmp}
+			},
+			setup: func(t *testing.T, cfg policyCfg) (string, DeleteFileArgs, func(t *testing.T, out *DeleteFileOut)) {
+				t.Helper()
+				src := filepath.Join(cfg.workBaseDir, "d")
+				trash := filepath.Join(cfg.workBaseDir, "trash")
+				mustMkdirAll(t, src)
+				mustMkdirAll(t, filepath.Join(trash, "d"))
+				mustWriteFile(t, filepath.Join(trash, "d", "keep.txt"), []byte("keep"))
+				return src, DeleteFileArgs{Path: src, TrashDir: trash, Recursive: true}, func(t *testing.T, out *DeleteFileOut) {
+					t.Helper()
+					if out.TrashedPath == filepath.Join(trash, "d") {
+						t.Fatalf("expected unique trash name, got %s", out.TrashedPath)
+					}
+					if got := string(mustReadFile(t, filepath.Join(trash, "d", "keep.txt"))); got != "keep" {
+						t.Fatalf("existing trash entry clobbered: %q", got)
+					}
+				}
+			},
+		},
+		{
+			name: "recursive_work_base_dir_refused",
+			cfg: func(t *testing.T) policyCfg {
+				t.Helper()
+				work := filepath.Join(t.TempDir(), "work")
+				mustMkdirAll(t, work)
+				return policyCfg{workBaseDir: work}
+			},
+			setup: func(t *testing.T, cfg policyCfg) (string, DeleteFileArgs, func(t *testing.T, out *DeleteFileOut)) {
+				t.Helper()
+				parent := filepath.Dir(cfg.workBaseDir)
+				trash := filepath.Join(t.TempDir(), "trash")
+				return parent, DeleteFileArgs{Path: parent, TrashDir: trash, Recursive: true}, func(t *testing.T, out *DeleteFileOut) {
+					t.Helper()
+					if _, err := os.Stat(cfg.workBaseDir); err != nil {
+						t.Fatalf("expected work dir to remain, stat err=%v", err)
+					}
+				}
+			},
+			wantErr: wantErrContains("configured root"),
+		},
+		{
+			name: "recursive_root_parent_via_symlink_refused",
+			cfg: func(t *testing.T) policyCfg {
+				t.Helper()
+				work := filepath.Join(t.TempDir(), "real", "work")
+				mustMkdirAll(t, work)
+				return policyCfg{workBaseDir: work}
+			},
+			setup: func(t *testing.T, cfg policyCfg) (string, DeleteFileArgs, func(t *testing.T, out *DeleteFileOut)) {
+				t.Helper()
+				// alias points at the temp dir holding real/work, so alias/real is the work dir's parent.
+				tmp := filepath.Dir(filepath.Dir(cfg.workBaseDir))
+				alias := filepath.Join(t.TempDir(), "alias")
+				mustSymlinkOrSkip(t, tmp, alias)
+				src := filepath.Join(alias, "real")
+				trash := filepath.Join(t.TempDir(), "trash")
+				return src, DeleteFileArgs{Path: src, TrashDir: trash, Recursive: true}, func(t *testing.T, out *DeleteFileOut) {
+					t.Helper()
+					if _, err := os.Stat(cfg.workBaseDir); err != nil {
+						t.Fatalf("expected work dir to remain, stat err=%v", err)
+					}
+				}
+			},
+			wantErr: wantErrContains("configured root"),
+		},
+		{
+			name: "recursive_trashdir_inside_directory_errors",
+			cfg: func(t *testing.T) policyCfg {
+				t.Helper()
+				tmp := t.TempDir()
+				return policyCfg{workBaseDir: tmp}
+			},
+			setup: func(t *testing.T, cfg policyCfg) (string, DeleteFileArgs, func(t *testing.T, out *DeleteFileOut)) {
+				t.Helper()
+				src := filepath.Join(cfg.workBaseDir, "d")
+				mustMkdirAll(t, src)
+				return src, DeleteFileArgs{Path: src, TrashDir: filepath.Join(src, "trash"), Recursive: true}, nil
+			},
+			wantErr: wantErrContains("inside the directory"),
+		},
 		{
 			name: "explicit_trashdir_trims_args_and_moves",
 			cfg: func(t *testing.T) policyCfg {
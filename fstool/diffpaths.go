@@ -0,0 +1,404 @@
+package fstool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/flexigpt/llmtools-go/internal/diffutil"
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const diffPathsFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/diffpaths.DiffPaths"
+
+const (
+	diffPathsMaxContextLines       = 100
+	diffPathsDefaultMaxFileBytes   = 1 * 1024 * 1024
+	diffPathsDefaultMaxOutputBytes = 256 * 1024
+)
+
+var diffPathsTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f30-0102-7996-acbf-2154ec76d4b1",
+	Slug:          "diffpaths",
+	Version:       "v1.0.0",
+	DisplayName:   "Diff files or directories",
+	Description:   "Compare two text files (unified diff) or two directories recursively (added/removed/changed entries, with a unified diff per changed text file). Read-only.",
+	Tags:          []string{"fs", "diff"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"oldPath": {
+		"type": "string",
+		"description": "Original file or directory."
+	},
+	"newPath": {
+		"type": "string",
+		"description": "Changed file or directory. Must be the same kind (file or directory) as oldPath."
+	},
+	"contextLines": {
+		"type": "integer",
+		"minimum": 0,
+		"maximum": 100,
+		"description": "Unchanged lines shown around each change. Default 3."
+	},
+	"ignoreWhitespace": {
+		"type": "boolean",
+		"description": "Treat lines that differ only in whitespace as equal.",
+		"default": false
+	},
+	"maxFileBytes": {
+		"type": "integer",
+		"minimum": 1,
+		"description": "Files larger than this are compared but not diffed. Default 1MB, max 16MB."
+	},
+	"maxOutputBytes": {
+		"type": "integer",
+		"minimum": 1,
+		"description": "Total size budget for diff text. Once reached, remaining diffs are omitted (truncated is set). Default 256KB, max 16MB."
+	}
+},
+"required": ["oldPath", "newPath"],
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: diffPathsFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type DiffPathsArgs struct {
+	OldPath          string `json:"oldPath"`
+	NewPath          string `json:"newPath"`
+	ContextLines     *int   `json:"contextLines,omitempty"` // default 3
+	IgnoreWhitespace bool   `json:"ignoreWhitespace,omitempty"`
+	MaxFileBytes     int64  `json:"maxFileBytes,omitempty"`
+	MaxOutputBytes   int64  `json:"maxOutputBytes,omitempty"`
+}
+
+type DiffPathsStatus string
+
+const (
+	DiffPathsAdded       DiffPathsStatus = "added"
+	DiffPathsRemoved     DiffPathsStatus = "removed"
+	DiffPathsChanged     DiffPathsStatus = "changed"
+	DiffPathsTypeChanged DiffPathsStatus = "typeChanged"
+)
+
+// DiffPathsOmitReason says why an entry has no diff text.
+type DiffPathsOmitReason string
+
+const (
+	DiffPathsOmitBinary      DiffPathsOmitReason = "binary"
+	DiffPathsOmitTooLarge    DiffPathsOmitReason = "tooLarge"
+	DiffPathsOmitOutputLimit DiffPathsOmitReason = "outputLimit"
+)
+
+type DiffPathsEntry struct {
+	Path         string               `json:"path"` // relative to the compared directories (slash-separated), or the new file path
+	Status       DiffPathsStatus      `json:"status"`
+	OldKind      ioutil.TreeEntryKind `json:"oldKind,omitempty"`
+	NewKind      ioutil.TreeEntryKind `json:"newKind,omitempty"`
+	LinesAdded   int                  `json:"linesAdded,omitempty"`
+	LinesRemoved int                  `json:"linesRemoved,omitempty"`
+	Diff         string               `json:"diff,omitempty"`
+	DiffOmitted  DiffPathsOmitReason  `json:"diffOmitted,omitempty"`
+	OldTarget    string               `json:"oldTarget,omitempty"` // symlinks only
+	NewTarget    string               `json:"newTarget,omitempty"` // symlinks only
+}
+
+type DiffPathsOut struct {
+	OldPath   string           `json:"oldPath"`
+	NewPath   string           `json:"newPath"`
+	IsDir     bool             `json:"isDir"`
+	Identical bool             `json:"identical"`
+	Entries   []DiffPathsEntry `json:"entries,omitempty"`
+	Added     int              `json:"added,omitempty"`
+	Removed   int              `json:"removed,omitempty"`
+	Changed   int              `json:"changed,omitempty"`
+	Unchanged int              `json:"unchanged,omitempty"` // directory mode only
+	Truncated bool             `json:"truncated,omitempty"` // some diffs omitted because maxOutputBytes was reached
+}
+
+// diffPaths compares two files or two directory trees without modifying anything.
+//
+// Behavior notes (entry point):
+//   - Both paths must exist and be the same kind. Directory trees are walked without following
+//     symlinks (symlinks are compared by target) and are capped at toolutil.MaxTreeEntries entries each.
+//   - Unchanged entries are only counted. Directories present on both sides are not reported.
+//   - Text diffs are produced for files up to maxFileBytes; NUL bytes or invalid UTF-8 mark a file as binary.
+//     Diffs of added/removed files are against /dev/null. Directory-mode headers use git-style a/ and b/
+//     prefixes, so the output can be fed to applypatch from the old directory.
+func diffPaths(ctx context.Context, args DiffPathsArgs, p fspolicy.FSPolicy) (*DiffPathsOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	d := &pathDiffer{
+		fsys:         p.FS(),
+		opts:         diffutil.DiffOptions{Context: diffutil.DefaultContext, IgnoreWhitespace: args.IgnoreWhitespace},
+		maxFileBytes: diffPathsDefaultMaxFileBytes,
+		budget:       diffPathsDefaultMaxOutputBytes,
+	}
+	if args.ContextLines != nil {
+		if *args.ContextLines < 0 || *args.ContextLines > diffPathsMaxContextLines {
+			return nil, fmt.Errorf("contextLines must be between 0 and %d", diffPathsMaxContextLines)
+		}
+		d.opts.Context = *args.ContextLines
+	}
+	if args.MaxFileBytes < 0 || args.MaxFileBytes > toolutil.MaxTextProcessingBytes {
+		return nil, fmt.Errorf("maxFileBytes must be between 1 and %d, or 0 for the default", toolutil.MaxTextProcessingBytes)
+	}
+	if args.MaxFileBytes > 0 {
+		d.maxFileBytes = args.MaxFileBytes
+	}
+	if args.MaxOutputBytes < 0 || args.MaxOutputBytes > toolutil.MaxFileReadBytes {
+		return nil, fmt.Errorf("maxOutputBytes must be between 1 and %d, or 0 for the default", toolutil.MaxFileReadBytes)
+	}
+	if args.MaxOutputBytes > 0 {
+		d.budget = args.MaxOutputBytes
+	}
+
+	oldAbs, oldIsDir, err := resolveExistingFileOrDir(p, args.OldPath)
+	if err != nil {
+		return nil, err
+	}
+	newAbs, newIsDir, err := resolveExistingFileOrDir(p, args.NewPath)
+	if err != nil {
+		return nil, err
+	}
+	if oldIsDir != newIsDir {
+		return nil, fmt.Errorf("cannot compare a file with a directory: %s, %s", oldAbs, newAbs)
+	}
+
+	out := &DiffPathsOut{OldPath: oldAbs, NewPath: newAbs, IsDir: oldIsDir}
+	if oldIsDir {
+		err = d.diffTrees(ctx, p, oldAbs, newAbs, out)
+	} else {
+		err = d.diffFilePair(ctx, oldAbs, newAbs, out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out.Identical = len(out.Entries) == 0
+	out.Truncated = d.truncated
+	return out, nil
+}
+
+// resolveExistingFileOrDir resolves path and policy-checks it as an existing regular file or directory.
+// A symlink is followed only when the policy allows symlinks.
+func resolveExistingFileOrDir(p fspolicy.FSPolicy, path string) (abs string, isDir bool, err error) {
+	abs, err = p.ResolvePath(path, "")
+	if err != nil {
+		return "", false, err
+	}
+	if p.BlockSymlinks() {
+		if err := p.VerifyDirResolved(filepath.Dir(abs)); err != nil {
+			return "", false, err
+		}
+	}
+	st, err := p.FS().Lstat(abs)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", false, fmt.Errorf("path does not exist: %s", abs)
+		}
+		return "", false, err
+	}
+	if (st.Mode() & os.ModeSymlink) != 0 {
+		if p.BlockSymlinks() {
+			return "", false, fmt.Errorf("%w: refusing to operate on symlink: %s", fspolicy.ErrSymlinkDisallowed, abs)
+		}
+		if st, err = p.FS().Stat(abs); err != nil {
+			return "", false, err
+		}
+	}
+	if st.IsDir() {
+		return abs, true, nil
+	}
+	if _, err := p.RequireExistingRegularFileResolved(abs); err != nil {
+		return "", false, err
+	}
+	return abs, false, nil
+}
+
+// pathDiffer carries the diff options and the remaining output budget across files.
+type pathDiffer struct {
+	fsys         vfs.FS
+	opts         diffutil.DiffOptions
+	maxFileBytes int64
+	budget       int64
+	truncated    bool
+}
+
+func (d *pathDiffer) diffFilePair(ctx context.Context, oldAbs, newAbs string, out *DiffPathsOut) error {
+	same, err := ioutil.SameFileContent(ctx, d.fsys, oldAbs, newAbs)
+	if err != nil || same {
+		return err
+	}
+	e := DiffPathsEntry{
+		Path:    newAbs,
+		Status:  DiffPathsChanged,
+		OldKind: ioutil.TreeEntryFile,
+		NewKind: ioutil.TreeEntryFile,
+	}
+	equal, err := d.fillDiff(&e, oldAbs, newAbs, oldAbs, newAbs)
+	if err != nil || equal {
+		return err
+	}
+	out.Entries = append(out.Entries, e)
+	out.Changed++
+	return nil
+}
+
+func (d *pathDiffer) diffTrees(ctx context.Context, p fspolicy.FSPolicy, oldRoot, newRoot string, out *DiffPathsOut) error {
+	oldEntries, err := ioutil.ListTreeEntries(ctx, p, oldRoot, toolutil.MaxTreeEntries)
+	if err != nil {
+		return err
+	}
+	newEntries, err := ioutil.ListTreeEntries(ctx, p, newRoot, toolutil.MaxTreeEntries)
+	if err != nil {
+		return err
+	}
+	oldByPath := make(map[string]ioutil.TreeEntry, len(oldEntries))
+	paths := make([]string, 0, len(oldEntries)+len(newEntries))
+	for _, e := range oldEntries {
+		oldByPath[e.RelPath] = e
+		paths = append(paths, e.RelPath)
+	}
+	newByPath := make(map[string]ioutil.TreeEntry, len(newEntries))
+	for _, e := range newEntries {
+		newByPath[e.RelPath] = e
+		if _, ok := oldByPath[e.RelPath]; !ok {
+			paths = append(paths, e.RelPath)
+		}
+	}
+	slices.Sort(paths)
+
+	for _, rel := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		oe, inOld := oldByPath[rel]
+		ne, inNew := newByPath[rel]
+		oldAbs := filepath.Join(oldRoot, filepath.FromSlash(rel))
+		newAbs := filepath.Join(newRoot, filepath.FromSlash(rel))
+		e := DiffPathsEntry{Path: rel, OldKind: oe.Kind, NewKind: ne.Kind, OldTarget: oe.LinkTarget, NewTarget: ne.LinkTarget}
+
+		switch {
+		case !inNew:
+			e.Status = DiffPathsRemoved
+			out.Removed++
+			if oe.Kind == ioutil.TreeEntryFile {
+				if _, err := d.fillDiff(&e, oldAbs, "", "a/"+rel, diffutil.DevNull); err != nil {
+					return err
+				}
+			}
+		case !inOld:
+			e.Status = DiffPathsAdded
+			out.Added++
+			if ne.Kind == ioutil.TreeEntryFile {
+				if _, err := d.fillDiff(&e, "", newAbs, diffutil.DevNull, "b/"+rel); err != nil {
+					return err
+				}
+			}
+		case oe.Kind != ne.Kind:
+			e.Status = DiffPathsTypeChanged
+			out.Changed++
+		case oe.Kind == ioutil.TreeEntryDir:
+			continue
+		case oe.Kind == ioutil.TreeEntrySymlink:
+			if oe.LinkTarget == ne.LinkTarget {
+				out.Unchanged++
+				continue
+			}
+			e.Status = DiffPathsChanged
+			out.Changed++
+		default:
+			same, err := ioutil.SameFileContent(ctx, p.FS(), oldAbs, newAbs)
+			if err != nil {
+				return err
+			}
+			if same {
+				out.Unchanged++
+				continue
+			}
+			equal, err := d.fillDiff(&e, oldAbs, newAbs, "a/"+rel, "b/"+rel)
+			if err != nil {
+				return err
+			}
+			if equal {
+				out.Unchanged++
+				continue
+			}
+			e.Status = DiffPathsChanged
+			out.Changed++
+		}
+		out.Entries = append(out.Entries, e)
+	}
+	return nil
+}
+
+// fillDiff sets the unified diff (or the reason it was omitted) on e. An empty path stands for a missing side.
+// equal reports that two existing files differ only in ways the diff options ignore (e.g. whitespace).
+func (d *pathDiffer) fillDiff(e *DiffPathsEntry, oldAbs, newAbs, oldName, newName string) (equal bool, err error) {
+	oldText, reason, err := d.readDiffSide(oldAbs)
+	if err != nil || reason != "" {
+		e.DiffOmitted = reason
+		return false, err
+	}
+	newText, reason, err := d.readDiffSide(newAbs)
+	if err != nil || reason != "" {
+		e.DiffOmitted = reason
+		return false, err
+	}
+
+	a, aFinal := diffutil.SplitLines(oldText)
+	b, bFinal := diffutil.SplitLines(newText)
+	text, added, removed := diffutil.Unified(oldName, newName, a, b, aFinal, bFinal, d.opts)
+	if text == "" && oldAbs != "" && newAbs != "" {
+		return true, nil
+	}
+	e.LinesAdded, e.LinesRemoved = added, removed
+	if int64(len(text)) > d.budget {
+		e.DiffOmitted = DiffPathsOmitOutputLimit
+		d.truncated = true
+		return false, nil
+	}
+	d.budget -= int64(len(text))
+	e.Diff = text
+	return false, nil
+}
+
+func (d *pathDiffer) readDiffSide(path string) (string, DiffPathsOmitReason, error) {
+	if path == "" {
+		return "", "", nil
+	}
+	st, err := d.fsys.Stat(path)
+	if err != nil {
+		return "", "", err
+	}
+	if st.Size() > d.maxFileBytes {
+		return "", DiffPathsOmitTooLarge, nil
+	}
+	data, err := vfs.ReadFile(d.fsys, path)
+	if err != nil {
+		return "", "", err
+	}
+	if int64(len(data)) > d.maxFileBytes {
+		return "", DiffPathsOmitTooLarge, nil
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", DiffPathsOmitBinary, nil
+	}
+	return string(data), "", nil
+}
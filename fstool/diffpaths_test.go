@@ -0,0 +1,256 @@
+package fstool
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffPaths(t *testing.T) {
+	ptrInt := func(v int) *int { return &v }
+
+	// setupTrees creates old/ and new/ under tmp with a mix of unchanged, changed, added,
+	// removed, binary and type-changed entries.
+	setupTrees := func(t *testing.T, tmp string) {
+		t.Helper()
+		for _, d := range []string{"old/sub", "new/sub", "old/gone", "new/kind"} {
+			mustMkdirAll(t, filepath.Join(tmp, d))
+		}
+		mustWriteFile(t, filepath.Join(tmp, "old", "same.txt"), []byte("same\n"))
+		mustWriteFile(t, filepath.Join(tmp, "new", "same.txt"), []byte("same\n"))
+		mustWriteFile(t, filepath.Join(tmp, "old", "sub", "edit.txt"), []byte("a\nb\nc\n"))
+		mustWriteFile(t, filepath.Join(tmp, "new", "sub", "edit.txt"), []byte("a\nB\nc\n"))
+		mustWriteFile(t, filepath.Join(tmp, "old", "gone", "x.txt"), []byte("x\n"))
+		mustWriteFile(t, filepath.Join(tmp, "new", "added.txt"), []byte("new\n"))
+		mustWriteFile(t, filepath.Join(tmp, "old", "bin.dat"), []byte{0, 1, 2})
+		mustWriteFile(t, filepath.Join(tmp, "new", "bin.dat"), []byte{0, 1, 3})
+		mustWriteFile(t, filepath.Join(tmp, "old", "kind"), []byte("file\n"))
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, tmp string)
+		ctx     func(t *testing.T) context.Context
+		args    DiffPathsArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, out *DiffPathsOut)
+	}{
+		{
+			name:    "context_canceled",
+			ctx:     canceledContext,
+			args:    DiffPathsArgs{OldPath: "a", NewPath: "b"},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name: "identical_files",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(tmp, "a.txt"), []byte("x\n"))
+				mustWriteFile(t, filepath.Join(tmp, "b.txt"), []byte("x\n"))
+			},
+			args:    DiffPathsArgs{OldPath: "a.txt", NewPath: "b.txt"},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *DiffPathsOut) {
+				t.Helper()
+				if !out.Identical || len(out.Entries) != 0 || out.IsDir {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "changed_files_unified_diff",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(tmp, "a.txt"), []byte("1\n2\n3\n"))
+				mustWriteFile(t, filepath.Join(tmp, "b.txt"), []byte("1\ntwo\n3\n"))
+			},
+			args:    DiffPathsArgs{OldPath: "a.txt", NewPath: "b.txt", ContextLines: ptrInt(0)},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *DiffPathsOut) {
+				t.Helper()
+				if out.Identical || len(out.Entries) != 1 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				e := out.Entries[0]
+				want := "--- " + out.OldPath + "\n+++ " + out.NewPath + "\n@@ -2 +2 @@\n-2\n+two\n"
+				if e.Status != DiffPathsChanged || e.Diff != want || e.LinesAdded != 1 || e.LinesRemoved != 1 {
+					t.Fatalf("unexpected entry: %+v\nwant diff %q", e, want)
+				}
+			},
+		},
+		{
+			name: "ignore_whitespace",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(tmp, "a.txt"), []byte("x  =  1\n"))
+				mustWriteFile(t, filepath.Join(tmp, "b.txt"), []byte("x = 1\n"))
+			},
+			args:    DiffPathsArgs{OldPath: "a.txt", NewPath: "b.txt", IgnoreWhitespace: true},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *DiffPathsOut) {
+				t.Helper()
+				// Bytes differ, but nothing remains once whitespace is ignored.
+				if !out.Identical || len(out.Entries) != 0 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "ignore_whitespace_trees",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(tmp, "old"))
+				mustMkdirAll(t, filepath.Join(tmp, "new"))
+				mustWriteFile(t, filepath.Join(tmp, "old", "a.txt"), []byte("x  =  1\n"))
+				mustWriteFile(t, filepath.Join(tmp, "new", "a.txt"), []byte("x = 1\n"))
+			},
+			args:    DiffPathsArgs{OldPath: "old", NewPath: "new", IgnoreWhitespace: true},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *DiffPathsOut) {
+				t.Helper()
+				if !out.Identical || len(out.Entries) != 0 || out.Unchanged != 1 || out.Changed != 0 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "file_over_max_file_bytes_not_diffed",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(tmp, "a.txt"), []byte("0123456789\n"))
+				mustWriteFile(t, filepath.Join(tmp, "b.txt"), []byte("0123456789!\n"))
+			},
+			args:    DiffPathsArgs{OldPath: "a.txt", NewPath: "b.txt", MaxFileBytes: 5},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *DiffPathsOut) {
+				t.Helper()
+				if e := out.Entries[0]; e.Diff != "" || e.DiffOmitted != DiffPathsOmitTooLarge {
+					t.Fatalf("unexpected entry: %+v", e)
+				}
+			},
+		},
+		{
+			name: "file_vs_directory_errors",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(tmp, "a.txt"), []byte("x\n"))
+				mustMkdirAll(t, filepath.Join(tmp, "d"))
+			},
+			args:    DiffPathsArgs{OldPath: "a.txt", NewPath: "d"},
+			wantErr: wantErrContains("cannot compare a file with a directory"),
+		},
+		{
+			name:    "missing_path_errors",
+			args:    DiffPathsArgs{OldPath: "nope.txt", NewPath: "nope2.txt"},
+			wantErr: wantErrContains("path does not exist"),
+		},
+		{
+			name:    "invalid_context_lines",
+			args:    DiffPathsArgs{OldPath: "a", NewPath: "b", ContextLines: ptrInt(101)},
+			wantErr: wantErrContains("contextLines must be between"),
+		},
+		{
+			name:    "negative_max_file_bytes_mentions_default",
+			args:    DiffPathsArgs{OldPath: "a", NewPath: "b", MaxFileBytes: -1},
+			wantErr: wantErrContains("or 0 for the default"),
+		},
+		{
+			name:    "negative_max_output_bytes_mentions_default",
+			args:    DiffPathsArgs{OldPath: "a", NewPath: "b", MaxOutputBytes: -1},
+			wantErr: wantErrContains("or 0 for the default"),
+		},
+		{
+			name:    "directories_recursive",
+			setup:   setupTrees,
+			args:    DiffPathsArgs{OldPath: "old", NewPath: "new"},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *DiffPathsOut) {
+				t.Helper()
+				if !out.IsDir || out.Identical {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				type want struct {
+					status  DiffPathsStatus
+					omitted DiffPathsOmitReason
+					diff    string
+				}
+				wants := map[string]want{
+					"added.txt":    {status: DiffPathsAdded, diff: "--- /dev/null\n+++ b/added.txt\n@@ -0,0 +1 @@\n+new\n"},
+					"bin.dat":      {status: DiffPathsChanged, omitted: DiffPathsOmitBinary},
+					"gone":         {status: DiffPathsRemoved},
+					"gone/x.txt":   {status: DiffPathsRemoved, diff: "--- a/gone/x.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-x\n"},
+					"kind":         {status: DiffPathsTypeChanged},
+					"sub/edit.txt": {status: DiffPathsChanged, diff: "--- a/sub/edit.txt\n+++ b/sub/edit.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+				}
+				if len(out.Entries) != len(wants) {
+					t.Fatalf("got %d entries, want %d: %+v", len(out.Entries), len(wants), out.Entries)
+				}
+				for _, e := range out.Entries {
+					w, ok := wants[e.Path]
+					if !ok || e.Status != w.status || e.DiffOmitted != w.omitted || e.Diff != w.diff {
+						t.Fatalf("unexpected entry %+v (want %+v)", e, w)
+					}
+				}
+				if out.Added != 1 || out.Removed != 2 || out.Changed != 3 || out.Unchanged != 1 {
+					t.Fatalf("unexpected counts: %+v", out)
+				}
+			},
+		},
+		{
+			name:    "output_budget_truncates",
+			setup:   setupTrees,
+			args:    DiffPathsArgs{OldPath: "old", NewPath: "new", MaxOutputBytes: 60},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *DiffPathsOut) {
+				t.Helper()
+				if !out.Truncated {
+					t.Fatalf("expected truncated: %+v", out)
+				}
+				omitted := 0
+				for _, e := range out.Entries {
+					if e.DiffOmitted == DiffPathsOmitOutputLimit {
+						omitted++
+						if e.Diff != "" || e.LinesAdded+e.LinesRemoved == 0 {
+							t.Fatalf("unexpected entry: %+v", e)
+						}
+					}
+				}
+				if omitted == 0 {
+					t.Fatalf("expected some diffs to be omitted: %+v", out.Entries)
+				}
+			},
+		},
+		{
+			name: "symlink_in_tree_blocked",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(tmp, "old"))
+				mustMkdirAll(t, filepath.Join(tmp, "new"))
+				mustSymlinkOrSkip(t, "target", filepath.Join(tmp, "new", "link"))
+			},
+			args:    DiffPathsArgs{OldPath: "old", NewPath: "new"},
+			wantErr: wantErrContains("symlink"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			if tc.setup != nil {
+				tc.setup(t, tmp)
+			}
+			ft := mustNewFSTool(t, WithWorkBaseDir(tmp), WithBlockSymlinks(true))
+			ctx := t.Context()
+			if tc.ctx != nil {
+				ctx = tc.ctx(t)
+			}
+			out, err := ft.DiffPaths(ctx, tc.args)
+			if !tc.wantErr(err) {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if err == nil && tc.check != nil {
+				tc.check(t, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/archiveutil"
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const extractArchiveFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/extractarchive.ExtractArchive"
+
+var extractArchiveTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f3e-6141-751e-aba1-e5caafbfcf2e",
+	Slug:          "extractarchive",
+	Version:       "v1.0.0",
+	DisplayName:   "Extract archive",
+	Description:   "Extract a zip, tar, tar.gz or tar.zst archive into a directory, or list its entries without extracting (listOnly). Entries escaping the destination are refused, and entry count and uncompressed size are capped.",
+	Tags:          []string{"fs", "archive"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"archivePath": {
+		"type": "string",
+		"description": "Archive file to read."
+	},
+	"destinationPath": {
+		"type": "string",
+		"description": "Directory to extract into. Created if missing. Required unless listOnly is true."
+	},
+	"format": {
+		"type": "string",
+		"enum": ["zip", "tar", "tar.gz", "tar.zst"],
+		"description": "Archive format. Default: inferred from the file extension, then from the content."
+	},
+	"listOnly": {
+		"type": "boolean",
+		"description": "If true, return the archive's entries and write nothing.",
+		"default": false
+	},
+	"overwrite": {
+		"type": "boolean",
+		"description": "If false and any extracted path already exists in the destination, return an error before writing anything. If true, existing files are replaced.",
+		"default": false
+	},
+	"createParents": {
+		"type": "boolean",
+		"description": "If true, create missing parent directories of the destination. Max new directories created is 8.",
+		"default": false
+	},
+	"maxEntries": {
+		"type": "integer",
+		"minimum": 1,
+		"description": "Fail if the archive has more entries than this. Default and maximum 10000."
+	},
+	"maxBytes": {
+		"type": "integer",
+		"minimum": 1,
+		"description": "Fail if extraction would write more uncompressed bytes than this. Default and maximum 268435456 (256 MiB)."
+	}
+},
+"required": ["archivePath"],
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: extractArchiveFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type ExtractArchiveArgs struct {
+	ArchivePath     string `json:"archivePath"`
+	DestinationPath string `json:"destinationPath,omitempty"`
+	Format          string `json:"format,omitempty"`
+	ListOnly        bool   `json:"listOnly,omitempty"`
+	Overwrite       bool   `json:"overwrite,omitempty"`
+	CreateParents   bool   `json:"createParents,omitempty"`
+	MaxEntries      int    `json:"maxEntries,omitempty"`
+	MaxBytes        int64  `json:"maxBytes,omitempty"`
+}
+
+type ExtractArchiveOut struct {
+	ArchivePath     string              `json:"archivePath"`
+	Format          archiveutil.Format  `json:"format"`
+	DestinationPath string              `json:"destinationPath,omitempty"`
+	Entries         []archiveutil.Entry `json:"entries,omitempty"` // listOnly
+	archiveutil.Stats
+}
+
+// extractArchive lists or extracts an archive.
+//
+// Behavior notes (entry point):
+//   - Entry names are sanitized: absolute names, drive letters and ".." components are refused
+//     ("zip-slip"), and nothing is written through a symlink.
+//   - Symlink and hardlink entries fail the whole extraction when the policy blocks symlinks;
+//     otherwise their targets must stay inside the destination.
+//   - Entry count and actual uncompressed bytes are capped (toolutil.MaxTreeEntries/MaxTreeBytes, or lower
+//     via args), so a compression bomb fails instead of filling the disk.
+//   - The archive is staged next to the destination and moved into place only after every entry succeeded.
+func extractArchive(ctx context.Context, args ExtractArchiveArgs, p fspolicy.FSPolicy) (*ExtractArchiveOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	limits, err := archiveLimits(args.MaxEntries, args.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	src, err := p.ResolvePath(args.ArchivePath, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.RequireExistingRegularFileResolved(src); err != nil {
+		return nil, err
+	}
+	format, err := archiveFormat(p.FS(), args.Format, src, true)
+	if err != nil {
+		return nil, err
+	}
+	out := &ExtractArchiveOut{ArchivePath: src, Format: format}
+
+	if args.ListOnly {
+		entries, stats, err := archiveutil.List(ctx, p.FS(), src, format, limits)
+		if err != nil {
+			return nil, err
+		}
+		out.Entries, out.Stats = entries, stats
+		return out, nil
+	}
+
+	if strings.TrimSpace(args.DestinationPath) == "" {
+		return nil, errors.New("destinationPath is required unless listOnly is true")
+	}
+	dst, err := resolveArchiveOutputPath(p, args.DestinationPath, args.CreateParents)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := archiveutil.Extract(ctx, p, src, dst, format, archiveutil.ExtractOptions{
+		Overwrite: args.Overwrite,
+		Limits:    limits,
+	})
+	if err != nil {
+		return nil, wrapExistErr(err, dst, args.Overwrite)
+	}
+	out.DestinationPath, out.Stats = dst, stats
+	return out, nil
+}
+
+// archiveLimits applies caller-requested caps, which may only lower the tree defaults.
+func archiveLimits(maxEntries int, maxBytes int64) (archiveutil.Limits, error) {
+	l := archiveutil.Limits{MaxEntries: toolutil.MaxTreeEntries, MaxBytes: toolutil.MaxTreeBytes}
+	if maxEntries < 0 || maxBytes < 0 {
+		return l, errors.New("maxEntries and maxBytes must be positive")
+	}
+	if maxEntries > 0 {
+		l.MaxEntries = min(maxEntries, l.MaxEntries)
+	}
+	if maxBytes > 0 {
+		l.MaxBytes = min(maxBytes, l.MaxBytes)
+	}
+	return l, nil
+}
+
+// archiveFormat returns the explicit format, or infers it from the path (and, for existing archives, the content).
+func archiveFormat(fsys vfs.FS, explicit, path string, exists bool) (archiveutil.Format, error) {
+	if strings.TrimSpace(explicit) != "" {
+		return archiveutil.ParseFormat(explicit)
+	}
+	if exists {
+		return archiveutil.DetectFormat(fsys, path)
+	}
+	if f, ok := archiveutil.FormatFromName(path); ok {
+		return f, nil
+	}
+	return "", fmt.Errorf("cannot infer archive format from %s; pass format explicitly", path)
+}
+
+// resolveArchiveOutputPath resolves an archive output path and verifies (or creates, bounded) its parent.
+func resolveArchiveOutputPath(p fspolicy.FSPolicy, path string, createParents bool) (string, error) {
+	abs, err := p.ResolvePath(path, "")
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Dir(abs)
+	if createParents {
+		if _, err := p.EnsureDirResolved(parent, 8); err != nil {
+			return "", err
+		}
+	} else if err := p.VerifyDirResolved(parent); err != nil {
+		return "", err
+	}
+	return abs, nil
+}
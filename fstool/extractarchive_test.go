@@ -0,0 +1,204 @@
+package fstool
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/archiveutil"
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+)
+
+func mustWriteZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip Create: %v", err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("zip Write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip Close: %v", err)
+	}
+	mustWriteFile(t, path, buf.Bytes())
+}
+
+func TestExtractArchive(t *testing.T) {
+	setupZip := func(t *testing.T, tmp string) {
+		t.Helper()
+		mustWriteZip(t, filepath.Join(tmp, "a.zip"), map[string]string{"d/x.txt": "x", "y.txt": "yy"})
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, tmp string)
+		ctx     func(t *testing.T) context.Context
+		args    ExtractArchiveArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, tmp string, out *ExtractArchiveOut)
+	}{
+		{
+			name:    "context_canceled",
+			setup:   setupZip,
+			ctx:     canceledContext,
+			args:    ExtractArchiveArgs{ArchivePath: "a.zip", DestinationPath: "out"},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name:    "list_only",
+			setup:   setupZip,
+			args:    ExtractArchiveArgs{ArchivePath: "a.zip", ListOnly: true},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, tmp string, out *ExtractArchiveOut) {
+				t.Helper()
+				if out.Format != archiveutil.FormatZip || out.Files != 2 || out.Bytes != 3 || len(out.Entries) != 2 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if out.DestinationPath != "" {
+					t.Fatalf("listOnly must not extract: %+v", out)
+				}
+			},
+		},
+		{
+			name:    "extract_creates_destination",
+			setup:   setupZip,
+			args:    ExtractArchiveArgs{ArchivePath: "a.zip", DestinationPath: "new/out", CreateParents: true},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, tmp string, out *ExtractArchiveOut) {
+				t.Helper()
+				if got := string(mustReadFile(t, filepath.Join(tmp, "new", "out", "d", "x.txt"))); got != "x" {
+					t.Fatalf("got %q", got)
+				}
+				if out.Files != 2 || out.Dirs != 1 {
+					t.Fatalf("unexpected stats: %+v", out.Stats)
+				}
+			},
+		},
+		{
+			name:    "missing_destination_errors",
+			setup:   setupZip,
+			args:    ExtractArchiveArgs{ArchivePath: "a.zip"},
+			wantErr: wantErrContains("destinationPath is required"),
+		},
+		{
+			name: "zip_slip_refused",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				mustWriteZip(t, filepath.Join(tmp, "evil.zip"), map[string]string{"../../escape.txt": "evil"})
+			},
+			args:    ExtractArchiveArgs{ArchivePath: "evil.zip", DestinationPath: "out"},
+			wantErr: wantErrContains("outside the destination"),
+		},
+		{
+			name: "conflict_without_overwrite",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				setupZip(t, tmp)
+				mustMkdirAll(t, filepath.Join(tmp, "out"))
+				mustWriteFile(t, filepath.Join(tmp, "out", "y.txt"), []byte("keep"))
+			},
+			args:    ExtractArchiveArgs{ArchivePath: "a.zip", DestinationPath: "out"},
+			wantErr: wantErrContains("overwrite=false"),
+		},
+		{
+			name: "overwrite_merges",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				setupZip(t, tmp)
+				mustMkdirAll(t, filepath.Join(tmp, "out"))
+				mustWriteFile(t, filepath.Join(tmp, "out", "y.txt"), []byte("old"))
+				mustWriteFile(t, filepath.Join(tmp, "out", "keep.txt"), []byte("keep"))
+			},
+			args:    ExtractArchiveArgs{ArchivePath: "a.zip", DestinationPath: "out", Overwrite: true},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, tmp string, out *ExtractArchiveOut) {
+				t.Helper()
+				if got := string(mustReadFile(t, filepath.Join(tmp, "out", "y.txt"))); got != "yy" {
+					t.Fatalf("y.txt = %q", got)
+				}
+				if got := string(mustReadFile(t, filepath.Join(tmp, "out", "keep.txt"))); got != "keep" {
+					t.Fatalf("keep.txt = %q", got)
+				}
+			},
+		},
+		{
+			name:    "max_bytes_cap",
+			setup:   setupZip,
+			args:    ExtractArchiveArgs{ArchivePath: "a.zip", DestinationPath: "out", MaxBytes: 2},
+			wantErr: wantErrIs(archiveutil.ErrLimitExceeded),
+		},
+		{
+			name:    "max_entries_cap_in_list",
+			setup:   setupZip,
+			args:    ExtractArchiveArgs{ArchivePath: "a.zip", ListOnly: true, MaxEntries: 1},
+			wantErr: wantErrIs(archiveutil.ErrLimitExceeded),
+		},
+		{
+			name: "unknown_format_errors",
+			setup: func(t *testing.T, tmp string) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(tmp, "blob"), []byte("plain text"))
+			},
+			args:    ExtractArchiveArgs{ArchivePath: "blob", ListOnly: true},
+			wantErr: wantErrContains("cannot determine archive format"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			if tc.setup != nil {
+				tc.setup(t, tmp)
+			}
+			ft := mustNewFSTool(t, WithWorkBaseDir(tmp), WithBlockSymlinks(true))
+			ctx := t.Context()
+			if tc.ctx != nil {
+				ctx = tc.ctx(t)
+			}
+			out, err := ft.ExtractArchive(ctx, tc.args)
+			if !tc.wantErr(err) {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if err == nil && tc.check != nil {
+				tc.check(t, tmp, out)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveSymlinkEntryBlocked(t *testing.T) {
+	tmp := t.TempDir()
+	src := filepath.Join(tmp, "src")
+	mustMkdirAll(t, src)
+	mustWriteFile(t, filepath.Join(src, "a.txt"), []byte("a"))
+	mustSymlinkOrSkip(t, "a.txt", filepath.Join(src, "link"))
+
+	// Symlinks allowed: archive and round-trip the link.
+	open := mustNewFSTool(t, WithWorkBaseDir(tmp), WithBlockSymlinks(false))
+	if _, err := open.CreateArchive(t.Context(), CreateArchiveArgs{ArchivePath: "s.tar.gz", Sources: []string{"src"}}); err != nil {
+		t.Fatalf("CreateArchive: %v", err)
+	}
+	out, err := open.ExtractArchive(t.Context(), ExtractArchiveArgs{ArchivePath: "s.tar.gz", DestinationPath: "ok"})
+	if err != nil || out.Symlinks != 1 {
+		t.Fatalf("extract with symlinks allowed: %+v, %v", out, err)
+	}
+
+	// Symlinks blocked: the same archive is refused and nothing is written.
+	closed := mustNewFSTool(t, WithWorkBaseDir(tmp), WithBlockSymlinks(true))
+	_, err = closed.ExtractArchive(t.Context(), ExtractArchiveArgs{ArchivePath: "s.tar.gz", DestinationPath: "blocked"})
+	if !wantErrIs(fspolicy.ErrSymlinkDisallowed)(err) {
+		t.Fatalf("expected ErrSymlinkDisallowed, got %v", err)
+	}
+	if _, statErr := os.Lstat(filepath.Join(tmp, "blocked")); !errors.Is(statErr, os.ErrNotExist) {
+		t.Fatalf("blocked destination should not exist: %v", statErr)
+	}
+}
@@ -2,17 +2,36 @@ package fstool
 
 import (
 	"context"
+	"fmt"
+	"io/fs"
 	"sync"
+	"time"
 
+	"github.com/flexigpt/llmtools-go/checkpoint"
+	"github.com/flexigpt/llmtools-go/internal/filelock"
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/mutation"
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/quota"
 	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
+// ErrStaleVersion is returned (wrapped) by mutating tools when expectedVersion no longer matches the file.
+var ErrStaleVersion = ioutil.ErrStaleVersion
+
+// ErrLockTimeout is returned (wrapped) by mutating tools when another call holds a path for longer
+// than the lock timeout.
+var ErrLockTimeout = filelock.ErrTimeout
+
 type fsToolConfig struct {
-	allowedRoots  []string
-	workBaseDir   string
-	blockSymlinks bool
+	fsys            vfs.FS
+	allowedRoots    []string
+	workBaseDir     string
+	blockSymlinks   bool
+	allowedPermMask fs.FileMode
+	mutation        mutation.Config
 }
 
 // FSTool is an instance-owned filesystem tool runner.
@@ -46,6 +65,16 @@ func WithWorkBaseDir(base string) FSToolOption {
 	}
 }
 
+// WithFS sets the filesystem the tools operate on (default: the OS filesystem), e.g. a vfs.MemFS.
+// Paths stay native absolute paths; with a non-OS filesystem, WithWorkBaseDir or WithAllowedRoots
+// must name an absolute directory that exists in it.
+func WithFS(fsys vfs.FS) FSToolOption {
+	return func(ft *FSTool) error {
+		ft.cfg.fsys = fsys
+		return nil
+	}
+}
+
 // WithBlockSymlinks configures whether symlink traversal should be blocked (if supported downstream).
 func WithBlockSymlinks(block bool) FSToolOption {
 	return func(ft *FSTool) error {
@@ -54,12 +83,65 @@ func WithBlockSymlinks(block bool) FSToolOption {
 	}
 }
 
+// WithAllowedPermMask sets the mode bits setattributes may turn on (default DefaultAllowedPermMask).
+// Include fs.ModeSetuid, fs.ModeSetgid or fs.ModeSticky to allow those bits.
+func WithAllowedPermMask(mask fs.FileMode) FSToolOption {
+	return func(ft *FSTool) error {
+		if mask&^(fs.ModePerm|fs.ModeSetuid|fs.ModeSetgid|fs.ModeSticky) != 0 {
+			return fmt.Errorf("invalid permission mask %v: only permission, setuid, setgid and sticky bits are allowed", mask)
+		}
+		ft.cfg.allowedPermMask = mask
+		return nil
+	}
+}
+
+// WithLockTimeout bounds how long a mutating tool waits for another call editing the same path
+// (default filelock.DefaultTimeout, 30s); d <= 0 waits until the call's context is done.
+func WithLockTimeout(d time.Duration) FSToolOption {
+	return func(ft *FSTool) error {
+		ft.cfg.mutation.Locks.Timeout = d
+		return nil
+	}
+}
+
+// WithOSFileLocks also takes OS advisory locks (flock, LockFileEx) around each read-modify-write, so
+// tools in other processes configured with the same lock directory are excluded too. Lock files are
+// kept in dir, or in llmtools-go-locks under the OS temp dir if dir is empty. Only the OS filesystem
+// is locked this way; in-process locking always applies.
+func WithOSFileLocks(dir string) FSToolOption {
+	return func(ft *FSTool) error {
+		return ft.cfg.mutation.SetOSLocks(dir)
+	}
+}
+
+// WithQuota charges the writes of writefile, copypath, extractarchive and createarchive to q, which
+// bounds the bytes written, files created, single-file size and mutating calls per minute. Share one
+// Quota between the tools of a session; q.Usage reports what remains.
+func WithQuota(q *quota.Quota) FSToolOption {
+	return func(ft *FSTool) error {
+		ft.cfg.mutation.Quota = q
+		return nil
+	}
+}
+
+// WithCheckpoints records the prior state of the paths writefile and deletefile change in m, so the
+// host (or the model, via the checkpoint tools) can roll them back. m must use the same filesystem as
+// the tools (see WithFS).
+func WithCheckpoints(m *checkpoint.Manager) FSToolOption {
+	return func(ft *FSTool) error {
+		ft.cfg.mutation.Checkpoints = m
+		return nil
+	}
+}
+
 func NewFSTool(opts ...FSToolOption) (*FSTool, error) {
 	ft := &FSTool{
 		cfg: fsToolConfig{
-			allowedRoots:  nil,
-			workBaseDir:   "",
-			blockSymlinks: false,
+			allowedRoots:    nil,
+			workBaseDir:     "",
+			blockSymlinks:   false,
+			allowedPermMask: DefaultAllowedPermMask,
+			mutation:        mutation.DefaultConfig(),
 		},
 	}
 
@@ -72,7 +154,11 @@ func NewFSTool(opts ...FSToolOption) (*FSTool, error) {
 		}
 	}
 
-	pol, err := fspolicy.New(ft.cfg.workBaseDir, ft.cfg.allowedRoots, ft.cfg.blockSymlinks)
+	if err := ft.cfg.mutation.Validate(ft.cfg.fsys); err != nil {
+		return nil, err
+	}
+
+	pol, err := fspolicy.NewWithFS(ft.cfg.fsys, ft.cfg.workBaseDir, ft.cfg.allowedRoots, ft.cfg.blockSymlinks)
 	if err != nil {
 		return nil, err
 	}
@@ -81,22 +167,100 @@ func NewFSTool(opts ...FSToolOption) (*FSTool, error) {
 	return ft, nil
 }
 
+func (ft *FSTool) CopyPathTool() spec.Tool         { return toolutil.CloneTool(copyPathTool) }
+func (ft *FSTool) CreateArchiveTool() spec.Tool    { return toolutil.CloneTool(createArchiveTool) }
+func (ft *FSTool) CreateDirectoryTool() spec.Tool  { return toolutil.CloneTool(createDirectoryTool) }
 func (ft *FSTool) DeleteFileTool() spec.Tool       { return toolutil.CloneTool(deleteFileTool) }
+func (ft *FSTool) DiffPathsTool() spec.Tool        { return toolutil.CloneTool(diffPathsTool) }
+func (ft *FSTool) ExtractArchiveTool() spec.Tool   { return toolutil.CloneTool(extractArchiveTool) }
+func (ft *FSTool) HashFileTool() spec.Tool         { return toolutil.CloneTool(hashFileTool) }
 func (ft *FSTool) ListDirectoryTool() spec.Tool    { return toolutil.CloneTool(listDirectoryTool) }
+func (ft *FSTool) ListTrashTool() spec.Tool        { return toolutil.CloneTool(listTrashTool) }
 func (ft *FSTool) MIMEForExtensionTool() spec.Tool { return toolutil.CloneTool(mimeForExtensionTool) }
 func (ft *FSTool) MIMEForPathTool() spec.Tool      { return toolutil.CloneTool(mimeForPathTool) }
+func (ft *FSTool) MovePathTool() spec.Tool         { return toolutil.CloneTool(movePathTool) }
+func (ft *FSTool) PreviewDataTool() spec.Tool      { return toolutil.CloneTool(previewDataTool) }
+func (ft *FSTool) PurgeTrashTool() spec.Tool       { return toolutil.CloneTool(purgeTrashTool) }
 func (ft *FSTool) ReadFileTool() spec.Tool         { return toolutil.CloneTool(readFileTool) }
+func (ft *FSTool) RestoreFromTrashTool() spec.Tool { return toolutil.CloneTool(restoreFromTrashTool) }
 func (ft *FSTool) SearchFilesTool() spec.Tool      { return toolutil.CloneTool(searchFilesTool) }
+func (ft *FSTool) SetAttributesTool() spec.Tool    { return toolutil.CloneTool(setAttributesTool) }
 func (ft *FSTool) StatPathTool() spec.Tool         { return toolutil.CloneTool(statPathTool) }
+func (ft *FSTool) TailFileTool() spec.Tool         { return toolutil.CloneTool(tailFileTool) }
 func (ft *FSTool) WriteFileTool() spec.Tool        { return toolutil.CloneTool(writeFileTool) }
 
+func (ft *FSTool) CopyPath(ctx context.Context, args CopyPathArgs) (*CopyPathOut, error) {
+	return toolutil.WithRecoveryResp(func() (*CopyPathOut, error) {
+		p, err := ft.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		return copyPath(ctx, args, p)
+	})
+}
+
+func (ft *FSTool) CreateArchive(ctx context.Context, args CreateArchiveArgs) (*CreateArchiveOut, error) {
+	return toolutil.WithRecoveryResp(func() (*CreateArchiveOut, error) {
+		p, err := ft.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		return createArchive(ctx, args, p)
+	})
+}
+
+func (ft *FSTool) CreateDirectory(ctx context.Context, args CreateDirectoryArgs) (*CreateDirectoryOut, error) {
+	return toolutil.WithRecoveryResp(func() (*CreateDirectoryOut, error) {
+		p, err := ft.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		return createDirectory(ctx, args, p)
+	})
+}
+
 func (ft *FSTool) DeleteFile(ctx context.Context, args DeleteFileArgs) (*DeleteFileOut, error) {
 	return toolutil.WithRecoveryResp(func() (*DeleteFileOut, error) {
-		p := ft.snapshotPolicy()
+		p, err := ft.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		done, err := ft.beginMutation(ctx, p, deleteFileTool.Slug, args.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
 		return deleteFile(ctx, args, p)
 	})
 }
 
+func (ft *FSTool) DiffPaths(ctx context.Context, args DiffPathsArgs) (*DiffPathsOut, error) {
+	return toolutil.WithRecoveryResp(func() (*DiffPathsOut, error) {
+		p := ft.snapshotPolicy()
+		return diffPaths(ctx, args, p)
+	})
+}
+
+func (ft *FSTool) ExtractArchive(ctx context.Context, args ExtractArchiveArgs) (*ExtractArchiveOut, error) {
+	return toolutil.WithRecoveryResp(func() (*ExtractArchiveOut, error) {
+		p := ft.snapshotPolicy()
+		if !args.ListOnly {
+			var err error
+			if p, err = ft.meteredPolicy(); err != nil {
+				return nil, err
+			}
+		}
+		return extractArchive(ctx, args, p)
+	})
+}
+
+func (ft *FSTool) HashFile(ctx context.Context, args HashFileArgs) (*HashFileOut, error) {
+	return toolutil.WithRecoveryResp(func() (*HashFileOut, error) {
+		p := ft.snapshotPolicy()
+		return hashFile(ctx, args, p)
+	})
+}
+
 func (ft *FSTool) ListDirectory(ctx context.Context, args ListDirectoryArgs) (*ListDirectoryOut, error) {
 	return toolutil.WithRecoveryResp(func() (*ListDirectoryOut, error) {
 		p := ft.snapshotPolicy()
@@ -104,6 +268,13 @@ func (ft *FSTool) ListDirectory(ctx context.Context, args ListDirectoryArgs) (*L
 	})
 }
 
+func (ft *FSTool) ListTrash(ctx context.Context, args ListTrashArgs) (*ListTrashOut, error) {
+	return toolutil.WithRecoveryResp(func() (*ListTrashOut, error) {
+		p := ft.snapshotPolicy()
+		return listTrash(ctx, args, p)
+	})
+}
+
 func (ft *FSTool) MIMEForExtension(ctx context.Context, args MIMEForExtensionArgs) (*MIMEForExtensionOut, error) {
 	return toolutil.WithRecoveryResp(func() (*MIMEForExtensionOut, error) {
 		p := ft.snapshotPolicy()
@@ -118,6 +289,33 @@ func (ft *FSTool) MIMEForPath(ctx context.Context, args MIMEForPathArgs) (*MIMEF
 	})
 }
 
+func (ft *FSTool) MovePath(ctx context.Context, args MovePathArgs) (*MovePathOut, error) {
+	return toolutil.WithRecoveryResp(func() (*MovePathOut, error) {
+		p, err := ft.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		return movePath(ctx, args, p)
+	})
+}
+
+func (ft *FSTool) PreviewData(ctx context.Context, args PreviewDataArgs) (*PreviewDataOut, error) {
+	return toolutil.WithRecoveryResp(func() (*PreviewDataOut, error) {
+		p := ft.snapshotPolicy()
+		return previewData(ctx, args, p)
+	})
+}
+
+func (ft *FSTool) PurgeTrash(ctx context.Context, args PurgeTrashArgs) (*PurgeTrashOut, error) {
+	return toolutil.WithRecoveryResp(func() (*PurgeTrashOut, error) {
+		p, err := ft.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		return purgeTrash(ctx, args, p)
+	})
+}
+
 func (ft *FSTool) ReadFile(
 	ctx context.Context,
 	args ReadFileArgs,
@@ -128,6 +326,16 @@ func (ft *FSTool) ReadFile(
 	})
 }
 
+func (ft *FSTool) RestoreFromTrash(ctx context.Context, args RestoreFromTrashArgs) (*RestoreFromTrashOut, error) {
+	return toolutil.WithRecoveryResp(func() (*RestoreFromTrashOut, error) {
+		p, err := ft.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		return restoreFromTrash(ctx, args, p)
+	})
+}
+
 func (ft *FSTool) SearchFiles(ctx context.Context, args SearchFilesArgs) (*SearchFilesOut, error) {
 	return toolutil.WithRecoveryResp(func() (*SearchFilesOut, error) {
 		p := ft.snapshotPolicy()
@@ -135,6 +343,16 @@ func (ft *FSTool) SearchFiles(ctx context.Context, args SearchFilesArgs) (*Searc
 	})
 }
 
+func (ft *FSTool) SetAttributes(ctx context.Context, args SetAttributesArgs) (*SetAttributesOut, error) {
+	return toolutil.WithRecoveryResp(func() (*SetAttributesOut, error) {
+		p, err := ft.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		return setAttributes(ctx, args, p, ft.cfg.allowedPermMask)
+	})
+}
+
 func (ft *FSTool) StatPath(ctx context.Context, args StatPathArgs) (*StatPathOut, error) {
 	return toolutil.WithRecoveryResp(func() (*StatPathOut, error) {
 		p := ft.snapshotPolicy()
@@ -142,16 +360,55 @@ func (ft *FSTool) StatPath(ctx context.Context, args StatPathArgs) (*StatPathOut
 	})
 }
 
+func (ft *FSTool) TailFile(ctx context.Context, args TailFileArgs) (*TailFileOut, error) {
+	return toolutil.WithRecoveryResp(func() (*TailFileOut, error) {
+		p := ft.snapshotPolicy()
+		return tailFile(ctx, args, p)
+	})
+}
+
 func (ft *FSTool) WriteFile(ctx context.Context, args WriteFileArgs) (*WriteFileOut, error) {
 	return toolutil.WithRecoveryResp(func() (*WriteFileOut, error) {
-		p := ft.snapshotPolicy()
+		p, err := ft.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		done, err := ft.beginMutation(ctx, p, writeFileTool.Slug, args.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
 		return writeFile(ctx, args, p)
 	})
 }
 
+// meteredPolicy returns the policy for a call that writes content: the call is counted against the
+// rate limit of the write quota (see WithQuota), and the policy charges the quota for what it writes.
+func (ft *FSTool) meteredPolicy() (fspolicy.FSPolicy, error) {
+	ft.mu.RLock()
+	p := ft.policy
+	m := ft.cfg.mutation
+	ft.mu.RUnlock()
+	return m.Metered(p)
+}
+
 func (ft *FSTool) snapshotPolicy() fspolicy.FSPolicy {
 	ft.mu.RLock()
 	p := ft.policy
 	ft.mu.RUnlock()
 	return p
 }
+
+// beginMutation locks the paths a tool is about to change (see WithLockTimeout and WithOSFileLocks)
+// and snapshots them for WithCheckpoints. The returned function releases the locks.
+func (ft *FSTool) beginMutation(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	tool string,
+	paths ...string,
+) (func(), error) {
+	ft.mu.RLock()
+	m := ft.cfg.mutation
+	ft.mu.RUnlock()
+	return m.Begin(ctx, p, tool, paths...)
+}
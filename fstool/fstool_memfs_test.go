@@ -0,0 +1,372 @@
+package fstool
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/checkpoint"
+	"github.com/flexigpt/llmtools-go/quota"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestFSTool_MemFS(t *testing.T) {
+	t.Parallel()
+
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := vfs.NewMemFS()
+	if err := m.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ft := mustNewFSTool(t, WithFS(m), WithWorkBaseDir(root), WithAllowedRoots([]string{root}))
+	ctx := t.Context()
+
+	_, err = ft.WriteFile(ctx, WriteFileArgs{Path: "dir/a.txt", Content: "alpha\nbeta\n", CreateParents: true})
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	b, err := vfs.ReadFile(m, filepath.Join(root, "dir", "a.txt"))
+	if err != nil || string(b) != "alpha\nbeta\n" {
+		t.Fatalf("memfs content = %q, %v", b, err)
+	}
+
+	outs, err := ft.ReadFile(ctx, ReadFileArgs{Path: "dir/a.txt"})
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if len(outs) == 0 || outs[len(outs)-1].TextItem == nil ||
+		!strings.Contains(outs[len(outs)-1].TextItem.Text, "beta") {
+		t.Fatalf("ReadFile outs = %#v", outs)
+	}
+
+	if _, err := ft.CopyPath(ctx, CopyPathArgs{SourcePath: "dir", DestinationPath: "copy"}); err != nil {
+		t.Fatalf("CopyPath: %v", err)
+	}
+	ls, err := ft.ListDirectory(ctx, ListDirectoryArgs{Path: "copy"})
+	if err != nil || len(ls.Entries) != 1 || ls.Entries[0] != "a.txt" {
+		t.Fatalf("ListDirectory = %#v, %v", ls, err)
+	}
+
+	sr, err := ft.SearchFiles(ctx, SearchFilesArgs{Pattern: "beta"})
+	if err != nil || sr.MatchCount != 2 {
+		t.Fatalf("SearchFiles = %#v, %v", sr, err)
+	}
+
+	del, err := ft.DeleteFile(ctx, DeleteFileArgs{Path: "copy/a.txt"})
+	if err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if !strings.HasPrefix(del.TrashedPath, root) {
+		t.Fatalf("trashed outside the memfs workspace: %q", del.TrashedPath)
+	}
+	if _, err := m.Stat(del.TrashedPath); err != nil {
+		t.Fatalf("trashed file missing: %v", err)
+	}
+
+	// Paths outside the workspace stay rejected on the in-memory backend too.
+	outside := filepath.Join(filepath.Dir(root), "x.txt")
+	if _, err := ft.WriteFile(ctx, WriteFileArgs{Path: outside, Content: "x"}); err == nil {
+		t.Fatal("expected write outside the workspace to fail")
+	}
+}
+
+func TestFSTool_Checkpoints(t *testing.T) {
+	t.Parallel()
+
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := vfs.NewMemFS()
+	if err := m.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(root, "a.txt")
+	if err := vfs.WriteFile(m, a, []byte("alpha\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cm, err := checkpoint.New(t.TempDir(), checkpoint.WithFS(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFSTool(WithWorkBaseDir(t.TempDir()), WithCheckpoints(cm)); err == nil {
+		t.Fatal("expected error for a checkpoint manager on another filesystem")
+	}
+	ft := mustNewFSTool(t, WithFS(m), WithWorkBaseDir(root), WithAllowedRoots([]string{root}), WithCheckpoints(cm))
+
+	ctx := checkpoint.WithCallID(t.Context(), "call-write")
+	if _, err := ft.WriteFile(ctx, WriteFileArgs{Path: "a.txt", Content: "changed\n", Overwrite: true}); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	_, err = ft.WriteFile(t.Context(), WriteFileArgs{Path: "new/b.txt", Content: "b\n", CreateParents: true})
+	if err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := ft.DeleteFile(t.Context(), DeleteFileArgs{Path: "a.txt"}); err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if got := len(cm.Entries()); got != 3 {
+		t.Fatalf("entries = %d, want 3", got)
+	}
+
+	if _, err := cm.RollbackTo(t.Context(), "call-write"); err != nil {
+		t.Fatalf("RollbackTo: %v", err)
+	}
+	b, err := vfs.ReadFile(m, a)
+	if err != nil || string(b) != "alpha\n" {
+		t.Fatalf("a.txt = %q, %v", b, err)
+	}
+	if _, err := m.Lstat(filepath.Join(root, "new")); err == nil {
+		t.Fatal("created directory survived the rollback")
+	}
+}
+
+func TestFSTool_Quota(t *testing.T) {
+	t.Parallel()
+
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := vfs.NewMemFS()
+	if err := m.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	newTool := func(q *quota.Quota) *FSTool {
+		return mustNewFSTool(t, WithFS(m), WithWorkBaseDir(root), WithAllowedRoots([]string{root}), WithQuota(q))
+	}
+	q, err := quota.New(quota.Limits{MaxBytesWritten: 100, MaxFilesCreated: 3, MaxFileBytes: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ft := newTool(q)
+	ctx := t.Context()
+
+	steps := []struct {
+		name    string
+		run     func() error
+		wantErr bool
+		want    quota.Usage // bytes and files after the step
+	}{
+		{
+			name: "file over the size cap is refused",
+			run: func() error {
+				_, err := ft.WriteFile(ctx, WriteFileArgs{Path: "big.txt", Content: strings.Repeat("x", 41)})
+				return err
+			},
+			wantErr: true,
+		},
+		{
+			name: "write creates a file",
+			run: func() error {
+				_, err := ft.WriteFile(ctx, WriteFileArgs{Path: "a.txt", Content: strings.Repeat("a", 20)})
+				return err
+			},
+			want: quota.Usage{BytesWritten: 20, FilesCreated: 1},
+		},
+		{
+			name: "append past the size cap is refused",
+			run: func() error {
+				_, err := ft.WriteFile(ctx, WriteFileArgs{Path: "a.txt", Content: strings.Repeat("a", 21), Mode: "append"})
+				return err
+			},
+			wantErr: true,
+			want:    quota.Usage{BytesWritten: 20, FilesCreated: 1},
+		},
+		{
+			name: "copy is charged",
+			run: func() error {
+				_, err := ft.CopyPath(ctx, CopyPathArgs{SourcePath: "a.txt", DestinationPath: "b.txt"})
+				return err
+			},
+			want: quota.Usage{BytesWritten: 40, FilesCreated: 2},
+		},
+		{
+			name: "overwrite does not create a file",
+			run: func() error {
+				_, err := ft.WriteFile(ctx, WriteFileArgs{Path: "b.txt", Content: strings.Repeat("b", 40), Overwrite: true})
+				return err
+			},
+			want: quota.Usage{BytesWritten: 80, FilesCreated: 2},
+		},
+		{
+			name: "total bytes cap",
+			run: func() error {
+				_, err := ft.WriteFile(ctx, WriteFileArgs{Path: "c.txt", Content: strings.Repeat("c", 21)})
+				return err
+			},
+			wantErr: true,
+			want:    quota.Usage{BytesWritten: 80, FilesCreated: 2},
+		},
+	}
+	for _, s := range steps {
+		err := s.run()
+		if s.wantErr != (err != nil) || (err != nil && !errors.Is(err, quota.ErrExceeded)) {
+			t.Fatalf("%s: err = %v, wantErr %v", s.name, err, s.wantErr)
+		}
+		if u := q.Usage(); u.BytesWritten != s.want.BytesWritten || u.FilesCreated != s.want.FilesCreated {
+			t.Fatalf("%s: usage = %+v, want %+v", s.name, u, s.want)
+		}
+	}
+	if _, err := m.Lstat(filepath.Join(root, "big.txt")); err == nil {
+		t.Fatal("refused write left a file behind")
+	}
+
+	// Extraction counts every file; the unmetered tool builds the archive.
+	_, err = newTool(nil).CreateArchive(ctx, CreateArchiveArgs{ArchivePath: "ab.zip", Sources: []string{"a.txt", "b.txt"}})
+	if err != nil {
+		t.Fatalf("CreateArchive: %v", err)
+	}
+	_, err = ft.ExtractArchive(ctx, ExtractArchiveArgs{ArchivePath: "ab.zip", DestinationPath: "out"})
+	if !errors.Is(err, quota.ErrExceeded) {
+		t.Fatalf("ExtractArchive err = %v, want quota.ErrExceeded", err)
+	}
+	if _, err := m.Lstat(filepath.Join(root, "out")); err == nil {
+		t.Fatal("refused extraction left a destination behind")
+	}
+	if u := q.Usage(); u.BytesWritten != 80 || u.FilesCreated != 2 {
+		t.Fatalf("refused extraction was not refunded: usage = %+v", u)
+	}
+
+	rq, err := quota.New(quota.Limits{MaxCallsPerMinute: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := newTool(rq)
+	if _, err := rt.WriteFile(ctx, WriteFileArgs{Path: "r.txt", Content: "1"}); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if _, err := rt.ReadFile(ctx, ReadFileArgs{Path: "r.txt"}); err != nil {
+		t.Fatalf("read-only calls are not rate limited: %v", err)
+	}
+	_, err = rt.WriteFile(ctx, WriteFileArgs{Path: "r.txt", Content: "2", Overwrite: true})
+	if !errors.Is(err, quota.ErrExceeded) {
+		t.Fatalf("second call err = %v, want quota.ErrExceeded", err)
+	}
+	mutating := map[string]func() error{
+		"createdirectory": func() error {
+			_, err := rt.CreateDirectory(ctx, CreateDirectoryArgs{Path: "d"})
+			return err
+		},
+		"deletefile": func() error {
+			_, err := rt.DeleteFile(ctx, DeleteFileArgs{Path: "r.txt"})
+			return err
+		},
+		"movepath": func() error {
+			_, err := rt.MovePath(ctx, MovePathArgs{SourcePath: "r.txt", DestinationPath: "s.txt"})
+			return err
+		},
+		"setattributes": func() error {
+			_, err := rt.SetAttributes(ctx, SetAttributesArgs{Path: "r.txt", Mode: "0600"})
+			return err
+		},
+		"restorefromtrash": func() error {
+			_, err := rt.RestoreFromTrash(ctx, RestoreFromTrashArgs{Name: "x"})
+			return err
+		},
+		"purgetrash": func() error {
+			_, err := rt.PurgeTrash(ctx, PurgeTrashArgs{DryRun: true})
+			return err
+		},
+	}
+	for name, call := range mutating {
+		if err := call(); !errors.Is(err, quota.ErrExceeded) {
+			t.Fatalf("%s err = %v, want quota.ErrExceeded", name, err)
+		}
+	}
+}
+
+func TestFSTool_AutoTrash(t *testing.T) {
+	t.Parallel()
+
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := vfs.NewMemFS()
+	if err := m.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", trashInfoSidecarDir} {
+		if err := vfs.WriteFile(m, filepath.Join(root, "sub", name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ft := mustNewFSTool(t, WithFS(m), WithWorkBaseDir(root), WithAllowedRoots([]string{root}))
+	ctx := t.Context()
+
+	// The auto fallback is the root's .trash, which the other auto trash tools scan.
+	trash := filepath.Join(root, localTrashDirName)
+	for _, name := range []string{"a.txt", trashInfoSidecarDir} {
+		del, err := ft.DeleteFile(ctx, DeleteFileArgs{Path: "sub/" + name})
+		if err != nil {
+			t.Fatalf("DeleteFile %s: %v", name, err)
+		}
+		if filepath.Dir(del.TrashedPath) != trash || del.InfoPath == "" {
+			t.Fatalf("DeleteFile %s = %+v", name, del)
+		}
+	}
+	ls, err := ft.ListTrash(ctx, ListTrashArgs{})
+	if err != nil || ls.TrashDir != trash || len(ls.Items) != 2 {
+		t.Fatalf("ListTrash = %+v, %v", ls, err)
+	}
+	for _, it := range ls.Items {
+		if it.Name == trashInfoSidecarDir || it.OriginalPath == "" {
+			t.Fatalf("unexpected item %+v", it)
+		}
+		if _, err := ft.RestoreFromTrash(ctx, RestoreFromTrashArgs{Name: it.Name}); err != nil {
+			t.Fatalf("RestoreFromTrash %s: %v", it.Name, err)
+		}
+	}
+	b, err := vfs.ReadFile(m, filepath.Join(root, "sub", trashInfoSidecarDir))
+	if err != nil || string(b) != trashInfoSidecarDir {
+		t.Fatalf("restored sidecar-named file = %q, %v", b, err)
+	}
+}
+
+func TestFSTool_AutoTrashOutsideWorkBaseDir(t *testing.T) {
+	t.Parallel()
+
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(filepath.Dir(root), "other")
+	m := vfs.NewMemFS()
+	for _, dir := range []string{root, other} {
+		if err := m.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := filepath.Join(other, "f.txt")
+	if err := vfs.WriteFile(m, src, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Without allowed roots, a file outside the work base dir is still trashed where the auto trash
+	// tools look: the work base dir's .trash.
+	ft := mustNewFSTool(t, WithFS(m), WithWorkBaseDir(root))
+	ctx := t.Context()
+
+	del, err := ft.DeleteFile(ctx, DeleteFileArgs{Path: src})
+	if err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if want := filepath.Join(root, localTrashDirName); filepath.Dir(del.TrashedPath) != want {
+		t.Fatalf("trashed into %q, want %q", filepath.Dir(del.TrashedPath), want)
+	}
+	ls, err := ft.ListTrash(ctx, ListTrashArgs{})
+	if err != nil || len(ls.Items) != 1 || ls.Items[0].OriginalPath != src {
+		t.Fatalf("ListTrash = %+v, %v", ls, err)
+	}
+	res, err := ft.RestoreFromTrash(ctx, RestoreFromTrashArgs{Name: ls.Items[0].Name})
+	if err != nil || res.RestoredPath != src {
+		t.Fatalf("RestoreFromTrash = %+v, %v", res, err)
+	}
+	if b, err := vfs.ReadFile(m, src); err != nil || string(b) != "data" {
+		t.Fatalf("restored content = %q, %v", b, err)
+	}
+}
@@ -1,11 +1,14 @@
 package fstool
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
+	"github.com/flexigpt/llmtools-go/internal/filelock"
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
 )
 
@@ -98,3 +101,24 @@ func TestNewFSTool_PolicyInitializationAndDefaults(t *testing.T) {
 		})
 	}
 }
+
+func TestFSTool_WriteFileLockTimeout(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	ft := mustNewFSTool(t, WithWorkBaseDir(dir), WithLockTimeout(20*time.Millisecond))
+
+	unlock, err := filelock.Lock(t.Context(), nil, filelock.Config{}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ft.WriteFile(t.Context(), WriteFileArgs{Path: "a.txt", Content: "x"})
+	unlock()
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("WriteFile error = %v, want ErrLockTimeout", err)
+	}
+	if _, err := ft.WriteFile(t.Context(), WriteFileArgs{Path: "a.txt", Content: "x"}); err != nil {
+		t.Fatalf("WriteFile after unlock: %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package fstool
+
+import (
+	"context"
+	"crypto/md5"  //nolint:gosec // offered for checksum verification, not security
+	"crypto/sha1" //nolint:gosec // offered for checksum verification, not security
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+	"golang.org/x/crypto/blake2b"
+)
+
+const hashFileFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/hashfile.HashFile"
+
+var hashFileTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f32-4258-787d-8c89-3549abc947f6",
+	Slug:          "hashfile",
+	Version:       "v1.0.0",
+	DisplayName:   "Hash file or directory",
+	Description:   "Compute a SHA-256/SHA-1/MD5/BLAKE2b digest of a file, or a Merkle-style digest of a directory tree with per-entry digests. Files are streamed, never loaded into memory. Optionally verify against expected digests.",
+	Tags:          []string{"fs", "hash"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"path": {
+		"type": "string",
+		"description": "File or directory to hash."
+	},
+	"algorithm": {
+		"type": "string",
+		"enum": ["sha256", "sha1", "md5", "blake2b"],
+		"description": "Digest algorithm (blake2b is BLAKE2b-256). Default sha256.",
+		"default": "sha256"
+	},
+	"expected": {
+		"type": "string",
+		"description": "Expected hex digest of the file, or of the directory root. May be prefixed with \"<algorithm>:\"."
+	},
+	"expectedFiles": {
+		"type": "object",
+		"additionalProperties": {"type": "string"},
+		"description": "Directories only: expected hex digests keyed by slash-separated path relative to the directory. Missing, unexpected and changed files are reported."
+	}
+},
+"required": ["path"],
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: hashFileFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type HashAlgorithm string
+
+const (
+	HashSHA256  HashAlgorithm = "sha256"
+	HashSHA1    HashAlgorithm = "sha1"
+	HashMD5     HashAlgorithm = "md5"
+	HashBLAKE2b HashAlgorithm = "blake2b"
+)
+
+type HashFileArgs struct {
+	Path          string            `json:"path"`
+	Algorithm     HashAlgorithm     `json:"algorithm,omitempty"` // default sha256
+	Expected      string            `json:"expected,omitempty"`
+	ExpectedFiles map[string]string `json:"expectedFiles,omitempty"`
+}
+
+type HashFileEntry struct {
+	Path      string               `json:"path"` // slash-separated, relative to the hashed directory
+	Kind      ioutil.TreeEntryKind `json:"kind"`
+	Digest    string               `json:"digest"`
+	SizeBytes int64                `json:"sizeBytes,omitempty"` // files only
+}
+
+type HashMismatchReason string
+
+const (
+	HashMismatchChanged    HashMismatchReason = "changed"
+	HashMismatchMissing    HashMismatchReason = "missing"
+	HashMismatchUnexpected HashMismatchReason = "unexpected"
+)
+
+type HashFileMismatch struct {
+	Path     string             `json:"path"`
+	Reason   HashMismatchReason `json:"reason"`
+	Expected string             `json:"expected,omitempty"`
+	Actual   string             `json:"actual,omitempty"`
+}
+
+type HashFileOut struct {
+	Path       string             `json:"path"`
+	Algorithm  HashAlgorithm      `json:"algorithm"`
+	IsDir      bool               `json:"isDir"`
+	Digest     string             `json:"digest"` // file digest, or directory root digest
+	SizeBytes  int64              `json:"sizeBytes"`
+	FileCount  int                `json:"fileCount,omitempty"` // directories only
+	Entries    []HashFileEntry    `json:"entries,omitempty"`   // directories only, in path order
+	Match      *bool              `json:"match,omitempty"`     // set when expected or expectedFiles is given
+	Mismatches []HashFileMismatch `json:"mismatches,omitempty"`
+}
+
+// hashFile computes digests for a file or directory tree.
+//
+// Behavior notes (entry point):
+//   - Files are streamed through the hash; there is no size cap, but the context is checked between reads.
+//   - Directory trees are walked without following symlinks and capped at toolutil.MaxTreeEntries entries.
+//     Each directory's digest is the hash of its children, one "<kind> <hex digest> <name>\n" line per
+//     child in name order (kind is file, dir or symlink; a symlink's digest is the hash of its target),
+//     so any change below a directory changes every digest up to the root.
+//   - Expected digests are compared case-insensitively.
+func hashFile(ctx context.Context, args HashFileArgs, p fspolicy.FSPolicy) (*HashFileOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	algo := HashAlgorithm(strings.ToLower(strings.TrimSpace(string(args.Algorithm))))
+	if algo == "" {
+		algo = HashSHA256
+	}
+	h, err := newHash(algo)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := normalizeExpectedDigest(args.Expected, algo, h.Size())
+	if err != nil {
+		return nil, err
+	}
+	expectedFiles := make(map[string]string, len(args.ExpectedFiles))
+	for rel, d := range args.ExpectedFiles {
+		nd, err := normalizeExpectedDigest(d, algo, h.Size())
+		if err != nil || nd == "" {
+			return nil, fmt.Errorf("expectedFiles[%q]: invalid digest %q", rel, d)
+		}
+		expectedFiles[path.Clean(filepath.ToSlash(rel))] = nd
+	}
+
+	abs, isDir, err := resolveExistingFileOrDir(p, args.Path)
+	if err != nil {
+		return nil, err
+	}
+	out := &HashFileOut{Path: abs, Algorithm: algo, IsDir: isDir}
+	if !isDir {
+		if len(expectedFiles) > 0 {
+			return nil, errors.New("expectedFiles requires path to be a directory")
+		}
+		sum, n, err := ioutil.HashFile(ctx, p.FS(), abs, h)
+		if err != nil {
+			return nil, err
+		}
+		out.Digest, out.SizeBytes = hex.EncodeToString(sum), n
+	} else if err := hashTree(ctx, p, abs, h, out); err != nil {
+		return nil, err
+	}
+
+	if expected != "" || len(args.ExpectedFiles) > 0 {
+		match := expected == "" || expected == out.Digest
+		if len(args.ExpectedFiles) > 0 {
+			out.Mismatches = compareHashManifest(out.Entries, expectedFiles)
+			match = match && len(out.Mismatches) == 0
+		}
+		out.Match = &match
+	}
+	return out, nil
+}
+
+func newHash(algo HashAlgorithm) (hash.Hash, error) {
+	switch algo {
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA1:
+		return sha1.New(), nil //nolint:gosec // checksum verification
+	case HashMD5:
+		return md5.New(), nil //nolint:gosec // checksum verification
+	case HashBLAKE2b:
+		return blake2b.New256(nil)
+	default:
+		return nil, fmt.Errorf(`unsupported algorithm %q (use "sha256", "sha1", "md5" or "blake2b")`, algo)
+	}
+}
+
+// normalizeExpectedDigest lowercases a hex digest, strips an optional "<algorithm>:" prefix and checks its length.
+func normalizeExpectedDigest(s string, algo HashAlgorithm, size int) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	if prefix, rest, ok := strings.Cut(s, ":"); ok {
+		if HashAlgorithm(prefix) != algo {
+			return "", fmt.Errorf("expected digest is for %q but algorithm is %q", prefix, algo)
+		}
+		s = rest
+	}
+	if _, err := hex.DecodeString(s); err != nil || len(s) != 2*size {
+		return "", fmt.Errorf("invalid expected digest %q: want %d hex characters", s, 2*size)
+	}
+	return s, nil
+}
+
+// hashTree fills out with the Merkle-style digests of the tree at root.
+func hashTree(ctx context.Context, p fspolicy.FSPolicy, root string, h hash.Hash, out *HashFileOut) error {
+	entries, err := ioutil.ListTreeEntries(ctx, p, root, toolutil.MaxTreeEntries)
+	if err != nil {
+		return err
+	}
+
+	// WalkDir visits each directory's children in name order, so appending keeps children sorted.
+	children := make(map[string][]int, len(entries))
+	for i, e := range entries {
+		parent := path.Dir(e.RelPath)
+		children[parent] = append(children[parent], i)
+	}
+
+	out.Entries = make([]HashFileEntry, len(entries))
+	var dirDigest func(rel string) (string, error)
+	dirDigest = func(rel string) (string, error) {
+		var manifest strings.Builder
+		for _, i := range children[rel] {
+			e := entries[i]
+			he := HashFileEntry{Path: e.RelPath, Kind: e.Kind}
+			switch e.Kind {
+			case ioutil.TreeEntryDir:
+				d, err := dirDigest(e.RelPath)
+				if err != nil {
+					return "", err
+				}
+				he.Digest = d
+			case ioutil.TreeEntrySymlink:
+				h.Reset()
+				h.Write([]byte(e.LinkTarget))
+				he.Digest = hex.EncodeToString(h.Sum(nil))
+			default:
+				sum, n, err := ioutil.HashFile(ctx, p.FS(), filepath.Join(root, filepath.FromSlash(e.RelPath)), h)
+				if err != nil {
+					return "", err
+				}
+				he.Digest, he.SizeBytes = hex.EncodeToString(sum), n
+				out.SizeBytes += n
+				out.FileCount++
+			}
+			out.Entries[i] = he
+			fmt.Fprintf(&manifest, "%s %s %s\n", e.Kind, he.Digest, path.Base(e.RelPath))
+		}
+		h.Reset()
+		h.Write([]byte(manifest.String()))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	rootDigest, err := dirDigest(".")
+	if err != nil {
+		return err
+	}
+	out.Digest = rootDigest
+	slices.SortFunc(out.Entries, func(a, b HashFileEntry) int { return strings.Compare(a.Path, b.Path) })
+	return nil
+}
+
+// compareHashManifest compares the file entries of a tree against expected digests.
+func compareHashManifest(entries []HashFileEntry, expected map[string]string) []HashFileMismatch {
+	var mismatches []HashFileMismatch
+	seen := make(map[string]bool, len(expected))
+	for _, e := range entries {
+		if e.Kind != ioutil.TreeEntryFile {
+			continue
+		}
+		want, ok := expected[e.Path]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, HashFileMismatch{Path: e.Path, Reason: HashMismatchUnexpected, Actual: e.Digest})
+		case want != e.Digest:
+			mismatches = append(mismatches, HashFileMismatch{Path: e.Path, Reason: HashMismatchChanged, Expected: want, Actual: e.Digest})
+		}
+		seen[e.Path] = true
+	}
+	var missing []string
+	for rel := range expected {
+		if !seen[rel] {
+			missing = append(missing, rel)
+		}
+	}
+	slices.Sort(missing)
+	for _, rel := range missing {
+		mismatches = append(mismatches, HashFileMismatch{Path: rel, Reason: HashMismatchMissing, Expected: expected[rel]})
+	}
+	return mismatches
+}
@@ -0,0 +1,214 @@
+package fstool
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // Test.
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestHashFile(t *testing.T) {
+	content := []byte(strings.Repeat("hash me\n", 50000))
+	sha := sha256.Sum256(content)
+	shaHex := hex.EncodeToString(sha[:])
+	md := md5.Sum(content) //nolint:gosec // Test.
+	b2 := blake2b.Sum256(content)
+
+	setupFile := func(t *testing.T, tmp string) {
+		t.Helper()
+		mustWriteFile(t, filepath.Join(tmp, "f.bin"), content)
+	}
+	setupTree := func(t *testing.T, tmp string) {
+		t.Helper()
+		mustMkdirAll(t, filepath.Join(tmp, "d", "sub"))
+		mustMkdirAll(t, filepath.Join(tmp, "d", "empty"))
+		mustWriteFile(t, filepath.Join(tmp, "d", "a.txt"), []byte("a\n"))
+		mustWriteFile(t, filepath.Join(tmp, "d", "sub", "b.txt"), []byte("b\n"))
+	}
+	hexSHA := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, tmp string)
+		ctx     func(t *testing.T) context.Context
+		args    HashFileArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, out *HashFileOut)
+	}{
+		{
+			name:    "context_canceled",
+			setup:   setupFile,
+			ctx:     canceledContext,
+			args:    HashFileArgs{Path: "f.bin"},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name:    "sha256_default",
+			setup:   setupFile,
+			args:    HashFileArgs{Path: "f.bin"},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *HashFileOut) {
+				t.Helper()
+				if out.Algorithm != HashSHA256 || out.Digest != shaHex || out.SizeBytes != int64(len(content)) || out.Match != nil {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name:    "md5",
+			setup:   setupFile,
+			args:    HashFileArgs{Path: "f.bin", Algorithm: HashMD5},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *HashFileOut) {
+				t.Helper()
+				if out.Digest != hex.EncodeToString(md[:]) {
+					t.Fatalf("unexpected digest: %s", out.Digest)
+				}
+			},
+		},
+		{
+			name:    "blake2b_expected_match_with_prefix_and_case",
+			setup:   setupFile,
+			args:    HashFileArgs{Path: "f.bin", Algorithm: "BLAKE2B", Expected: "blake2b:" + strings.ToUpper(hex.EncodeToString(b2[:]))},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *HashFileOut) {
+				t.Helper()
+				if out.Match == nil || !*out.Match {
+					t.Fatalf("expected match: %+v", out)
+				}
+			},
+		},
+		{
+			name:    "expected_mismatch",
+			setup:   setupFile,
+			args:    HashFileArgs{Path: "f.bin", Expected: strings.Repeat("0", 64)},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *HashFileOut) {
+				t.Helper()
+				if out.Match == nil || *out.Match {
+					t.Fatalf("expected mismatch: %+v", out)
+				}
+			},
+		},
+		{
+			name:    "expected_wrong_length_errors",
+			setup:   setupFile,
+			args:    HashFileArgs{Path: "f.bin", Expected: "abcd"},
+			wantErr: wantErrContains("invalid expected digest"),
+		},
+		{
+			name:    "expected_other_algorithm_prefix_errors",
+			setup:   setupFile,
+			args:    HashFileArgs{Path: "f.bin", Expected: "md5:" + shaHex},
+			wantErr: wantErrContains("but algorithm is"),
+		},
+		{
+			name:    "unsupported_algorithm_errors",
+			setup:   setupFile,
+			args:    HashFileArgs{Path: "f.bin", Algorithm: "crc32"},
+			wantErr: wantErrContains("unsupported algorithm"),
+		},
+		{
+			name:    "expected_files_on_file_errors",
+			setup:   setupFile,
+			args:    HashFileArgs{Path: "f.bin", ExpectedFiles: map[string]string{"x": shaHex}},
+			wantErr: wantErrContains("requires path to be a directory"),
+		},
+		{
+			name:    "directory_merkle_manifest",
+			setup:   setupTree,
+			args:    HashFileArgs{Path: "d"},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *HashFileOut) {
+				t.Helper()
+				emptyDir := hexSHA("")
+				sub := hexSHA("file " + hexSHA("b\n") + " b.txt\n")
+				root := hexSHA("file " + hexSHA("a\n") + " a.txt\n" + "dir " + emptyDir + " empty\n" + "dir " + sub + " sub\n")
+				want := []HashFileEntry{
+					{Path: "a.txt", Kind: "file", Digest: hexSHA("a\n"), SizeBytes: 2},
+					{Path: "empty", Kind: "dir", Digest: emptyDir},
+					{Path: "sub", Kind: "dir", Digest: sub},
+					{Path: "sub/b.txt", Kind: "file", Digest: hexSHA("b\n"), SizeBytes: 2},
+				}
+				if !out.IsDir || out.Digest != root || out.FileCount != 2 || out.SizeBytes != 4 || len(out.Entries) != len(want) {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				for i := range want {
+					if out.Entries[i] != want[i] {
+						t.Fatalf("entry %d: got %+v want %+v", i, out.Entries[i], want[i])
+					}
+				}
+			},
+		},
+		{
+			name:  "directory_expected_files_mismatches",
+			setup: setupTree,
+			args: HashFileArgs{Path: "d", ExpectedFiles: map[string]string{
+				"a.txt":     hexSHA("a\n"),
+				"sub/b.txt": hexSHA("changed\n"),
+				"gone.txt":  hexSHA("gone\n"),
+			}},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *HashFileOut) {
+				t.Helper()
+				if out.Match == nil || *out.Match || len(out.Mismatches) != 2 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if m := out.Mismatches[0]; m.Path != "sub/b.txt" || m.Reason != HashMismatchChanged {
+					t.Fatalf("unexpected mismatch: %+v", m)
+				}
+				if m := out.Mismatches[1]; m.Path != "gone.txt" || m.Reason != HashMismatchMissing {
+					t.Fatalf("unexpected mismatch: %+v", m)
+				}
+			},
+		},
+		{
+			name:  "directory_unexpected_file",
+			setup: setupTree,
+			args: HashFileArgs{Path: "d", ExpectedFiles: map[string]string{
+				"a.txt": hexSHA("a\n"),
+			}},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *HashFileOut) {
+				t.Helper()
+				if len(out.Mismatches) != 1 || out.Mismatches[0].Reason != HashMismatchUnexpected || out.Mismatches[0].Path != "sub/b.txt" {
+					t.Fatalf("unexpected mismatches: %+v", out.Mismatches)
+				}
+			},
+		},
+		{
+			name:    "missing_path_errors",
+			args:    HashFileArgs{Path: "nope"},
+			wantErr: wantErrContains("path does not exist"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			if tc.setup != nil {
+				tc.setup(t, tmp)
+			}
+			ft := mustNewFSTool(t, WithWorkBaseDir(tmp), WithBlockSymlinks(true))
+			ctx := t.Context()
+			if tc.ctx != nil {
+				ctx = tc.ctx(t)
+			}
+			out, err := ft.HashFile(ctx, tc.args)
+			if !tc.wantErr(err) {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if err == nil && tc.check != nil {
+				tc.check(t, out)
+			}
+		})
+	}
+}
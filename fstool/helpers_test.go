@@ -36,6 +36,15 @@ func mustWriteFile(t *testing.T, path string, data []byte) {
 	}
 }
 
+// mustTruncateFile creates a (sparse, where supported) zero-filled file of size bytes.
+func mustTruncateFile(t *testing.T, path string, size int64) {
+	t.Helper()
+	mustWriteFile(t, path, nil)
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatalf("Truncate(%q): %v", path, err)
+	}
+}
+
 func mustReadFile(t *testing.T, path string) []byte {
 	t.Helper()
 	b, err := os.ReadFile(path)
@@ -167,3 +176,4 @@ func wantErrNone(err error) bool { return err == nil }
 
 func ptrInt64(v int64) *int64 { return &v }
 func ptrBool(v bool) *bool    { return &v }
+func ptrInt(v int) *int       { return &v }
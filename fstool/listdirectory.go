@@ -69,7 +69,7 @@ func listDirectory(
 		return nil, err
 	}
 
-	entries, err := ioutil.ListDirectoryNormalized(dir, args.Pattern)
+	entries, err := ioutil.ListDirectoryNormalized(p.FS(), dir, args.Pattern)
 	if err != nil {
 		return nil, err
 	}
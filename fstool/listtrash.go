@@ -0,0 +1,167 @@
+package fstool
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const listTrashFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/listtrash.ListTrash"
+
+// maxTrashListItems caps the number of items returned by listtrash.
+const maxTrashListItems = 1000
+
+var listTrashTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f1b-b386-759c-9e11-c87a91c5a34b",
+	Slug:          "listtrash",
+	Version:       "v1.0.0",
+	DisplayName:   "List trash",
+	Description:   "List items in a trash directory with their original path and deletion time when known (newest first).",
+	Tags:          []string{"fs", "trash"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"trashDir": {
+		"type": "string",
+		"default": "auto",
+		"description": "Trash directory to list (as used by deletefile). \"auto\" lists the system trash and the local .trash directories deletefile falls back to."
+	}
+},
+"required": [],
+"additionalProperties": false
+}`),
+
+	GoImpl: spec.GoToolImpl{FuncID: listTrashFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type ListTrashArgs struct {
+	TrashDir string `json:"trashDir,omitempty"` // "auto" default
+}
+
+type TrashItem struct {
+	Name         string `json:"name"`
+	TrashedPath  string `json:"trashedPath"`
+	OriginalPath string `json:"originalPath,omitempty"`
+	// DeletedAt is nil when no (valid) metadata exists for the item.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	IsDir     bool       `json:"isDir"`
+	SizeBytes int64      `json:"sizeBytes,omitempty"`
+}
+
+type ListTrashOut struct {
+	// TrashDir is the first trash directory listed (empty when "auto" found none).
+	TrashDir string `json:"trashDir"`
+	// TrashDirs lists every trash directory scanned when "auto" scans several.
+	TrashDirs []string    `json:"trashDirs,omitempty"`
+	Items     []TrashItem `json:"items"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// listTrash lists entries of a trash directory.
+//
+// Behavior notes (entry point):
+//   - "auto" lists the system trash and the local .trash of the work base dir and allowed roots.
+//   - Metadata comes from .trashinfo files; items without metadata are still listed.
+//   - Items are sorted newest first; items without a deletion time come last (by name).
+//   - At most maxTrashListItems items are returned.
+func listTrash(
+	ctx context.Context,
+	args ListTrashArgs,
+	p fspolicy.FSPolicy,
+) (*ListTrashOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dirs, err := resolveTrashDirs(p, args.TrashDir)
+	if err != nil {
+		return nil, err
+	}
+	items, err := readTrashDirs(ctx, p.FS(), dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].DeletedAt, items[j].DeletedAt
+		switch {
+		case a != nil && b != nil && !a.Equal(*b):
+			return a.After(*b)
+		case a != nil && b == nil:
+			return true
+		case a == nil && b != nil:
+			return false
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	out := &ListTrashOut{Items: items}
+	if len(dirs) > 0 {
+		out.TrashDir = dirs[0]
+	}
+	if len(dirs) > 1 {
+		out.TrashDirs = dirs
+	}
+	if len(out.Items) > maxTrashListItems {
+		out.Items = out.Items[:maxTrashListItems]
+		out.Truncated = true
+	}
+	return out, nil
+}
+
+// readTrashDirs reads the entries of every trash dir in dirs.
+func readTrashDirs(ctx context.Context, fsys vfs.FS, dirs []string) ([]TrashItem, error) {
+	items := []TrashItem{}
+	for _, td := range dirs {
+		more, err := readTrashItems(ctx, fsys, td)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, more...)
+	}
+	return items, nil
+}
+
+// readTrashItems reads all entries (with metadata when present) of an already-resolved trash dir.
+func readTrashItems(ctx context.Context, fsys vfs.FS, trashDir string) ([]TrashItem, error) {
+	entries, err := fsys.ReadDir(trashDir)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]TrashItem, 0, len(entries))
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, err := trashEntryPath(fsys, trashDir, e.Name()); err != nil {
+			continue // sidecar metadata dir
+		}
+		item := TrashItem{
+			Name:        e.Name(),
+			TrashedPath: filepath.Join(trashDir, e.Name()),
+			IsDir:       e.IsDir(),
+		}
+		if fi, err := e.Info(); err == nil && fi.Mode().IsRegular() {
+			item.SizeBytes = fi.Size()
+		}
+		if info, err := readTrashInfo(fsys, trashInfoPathFor(fsys, trashDir, e.Name())); err == nil {
+			item.OriginalPath = info.OriginalPath
+			if !info.DeletedAt.IsZero() {
+				t := info.DeletedAt
+				item.DeletedAt = &t
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,112 @@
+package fstool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestListTrash(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     func(t *testing.T) context.Context
+		setup   func(t *testing.T, ft *FSTool, root string) ListTrashArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, root string, out *ListTrashOut)
+	}{
+		{
+			name: "context_canceled",
+			ctx:  canceledContext,
+			setup: func(t *testing.T, ft *FSTool, root string) ListTrashArgs {
+				t.Helper()
+				return ListTrashArgs{TrashDir: root}
+			},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name: "missing_trash_dir_errors",
+			setup: func(t *testing.T, ft *FSTool, root string) ListTrashArgs {
+				t.Helper()
+				return ListTrashArgs{TrashDir: filepath.Join(root, "nope")}
+			},
+			wantErr: wantErrAny,
+		},
+		{
+			name: "lists_deleted_items_newest_first_with_metadata",
+			setup: func(t *testing.T, ft *FSTool, root string) ListTrashArgs {
+				t.Helper()
+				trash := filepath.Join(root, "trash")
+				mustWriteFile(t, filepath.Join(root, "a.txt"), []byte("aaa"))
+				mustMkdirAll(t, filepath.Join(root, "d"))
+				if _, err := ft.DeleteFile(t.Context(), DeleteFileArgs{Path: "a.txt", TrashDir: trash}); err != nil {
+					t.Fatalf("delete a: %v", err)
+				}
+				if _, err := ft.DeleteFile(t.Context(), DeleteFileArgs{Path: "d", TrashDir: trash, Recursive: true}); err != nil {
+					t.Fatalf("delete d: %v", err)
+				}
+				// Make "a.txt" clearly older.
+				old := time.Now().Add(-48 * time.Hour)
+				mustWriteFile(t, trashInfoPathFor(vfs.OS(), trash, "a.txt"), encodeTrashInfo(filepath.Join(root, "a.txt"), old))
+				// An item without metadata.
+				mustWriteFile(t, filepath.Join(trash, "orphan"), []byte("o"))
+				return ListTrashArgs{TrashDir: "trash"}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, root string, out *ListTrashOut) {
+				t.Helper()
+				if len(out.Items) != 3 {
+					t.Fatalf("items=%+v", out.Items)
+				}
+				if out.Items[0].Name != "d" || !out.Items[0].IsDir || out.Items[0].OriginalPath != filepath.Join(root, "d") {
+					t.Fatalf("first item=%+v", out.Items[0])
+				}
+				if out.Items[1].Name != "a.txt" || out.Items[1].SizeBytes != 3 || out.Items[1].DeletedAt == nil {
+					t.Fatalf("second item=%+v", out.Items[1])
+				}
+				if out.Items[2].Name != "orphan" || out.Items[2].DeletedAt != nil || out.Items[2].OriginalPath != "" {
+					t.Fatalf("third item=%+v", out.Items[2])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			ft := mustNewFSTool(t, WithWorkBaseDir(root), WithAllowedRoots([]string{root}))
+			args := tt.setup(t, ft, root)
+			ctx := t.Context()
+			if tt.ctx != nil {
+				ctx = tt.ctx(t)
+			}
+			out, err := ft.ListTrash(ctx, args)
+			if !tt.wantErr(err) {
+				t.Fatalf("err=%v did not match expectation", err)
+			}
+			if err == nil && tt.check != nil {
+				tt.check(t, root, out)
+			}
+		})
+	}
+}
+
+func TestListTrashSkipsSidecar(t *testing.T) {
+	root := t.TempDir()
+	trash := filepath.Join(root, "trash")
+	mustMkdirAll(t, filepath.Join(trash, trashInfoSidecarDir))
+	ft := mustNewFSTool(t, WithWorkBaseDir(root))
+	out, err := ft.ListTrash(t.Context(), ListTrashArgs{TrashDir: trash})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out.Items) != 0 {
+		t.Fatalf("expected sidecar to be hidden, got %+v", out.Items)
+	}
+	if _, err := os.Stat(filepath.Join(trash, trashInfoSidecarDir)); err != nil {
+		t.Fatalf("sidecar should remain: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package fstool
+
+import (
+	"context"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+)
+
+const movePathFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/movepath.MovePath"
+
+var movePathTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f13-c841-7537-8e2d-f3d864d4fc84",
+	Slug:          "movepath",
+	Version:       "v1.0.0",
+	DisplayName:   "Move or rename path",
+	Description:   "Move or rename a file or a directory tree. Uses an atomic rename when possible and falls back to copy-then-remove across devices (bounded for directories).",
+	Tags:          []string{"fs"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"sourcePath": {
+		"type": "string",
+		"description": "Path of the file or directory to move."
+	},
+	"destinationPath": {
+		"type": "string",
+		"description": "Destination path (the full new path, not a parent directory)."
+	},
+	"overwrite": {
+		"type": "boolean",
+		"description": "If false and the destination exists, return an error. If true, files are replaced and directories are merged.",
+		"default": false
+	},
+	"createParents": {
+		"type": "boolean",
+		"description": "If true, create missing parent directories of the destination. Max new directories created is 8.",
+		"default": false
+	}
+},
+"required": ["sourcePath", "destinationPath"],
+"additionalProperties": false
+}`),
+
+	GoImpl: spec.GoToolImpl{FuncID: movePathFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type MovePathArgs struct {
+	SourcePath      string `json:"sourcePath"`
+	DestinationPath string `json:"destinationPath"`
+	Overwrite       bool   `json:"overwrite,omitempty"`
+	CreateParents   bool   `json:"createParents,omitempty"`
+}
+
+type MovePathMethod string
+
+const (
+	MovePathMethodRename        MovePathMethod = "rename"
+	MovePathMethodCopyAndRemove MovePathMethod = "copyAndRemove"
+	MovePathMethodMerge         MovePathMethod = "merge"
+)
+
+type MovePathOut struct {
+	SourcePath      string         `json:"sourcePath"`
+	DestinationPath string         `json:"destinationPath"`
+	IsDir           bool           `json:"isDir"`
+	Method          MovePathMethod `json:"method"`
+	FilesMoved      int            `json:"filesMoved"`
+	DirsMoved       int            `json:"dirsMoved,omitempty"`
+	SymlinksMoved   int            `json:"symlinksMoved,omitempty"`
+	BytesMoved      int64          `json:"bytesMoved"`
+}
+
+// movePath moves/renames a file or directory tree.
+//
+// Behavior notes (entry point):
+//   - Source and destination are both resolved and checked via policy.
+//   - Without overwrite, file moves never clobber an existing destination (no-clobber rename).
+//   - Directory trees are bounded by toolutil.MaxTreeEntries/MaxTreeBytes.
+//   - Symlinks are moved as links (never followed) and refused when the policy blocks symlinks.
+func movePath(
+	ctx context.Context,
+	args MovePathArgs,
+	p fspolicy.FSPolicy,
+) (*MovePathOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	src, dst, srcInfo, err := resolveSourceAndDestination(p, args.SourcePath, args.DestinationPath, args.CreateParents)
+	if err != nil {
+		return nil, err
+	}
+
+	method, stats, err := ioutil.MovePathResolved(ctx, p, src, dst, args.Overwrite, ioutil.TreeLimits{
+		MaxEntries: toolutil.MaxTreeEntries,
+		MaxBytes:   toolutil.MaxTreeBytes,
+	})
+	if err != nil {
+		return nil, wrapExistErr(err, dst, args.Overwrite)
+	}
+
+	return &MovePathOut{
+		SourcePath:      src,
+		DestinationPath: dst,
+		IsDir:           srcInfo.IsDir(),
+		Method:          MovePathMethod(method),
+		FilesMoved:      stats.Files,
+		DirsMoved:       stats.Dirs,
+		SymlinksMoved:   stats.Symlinks,
+		BytesMoved:      stats.Bytes,
+	}, nil
+}
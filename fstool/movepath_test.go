@@ -0,0 +1,253 @@
+package fstool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+)
+
+func TestMovePath(t *testing.T) {
+	type cfg struct {
+		workBaseDir   string
+		allowedRoots  []string
+		blockSymlinks bool
+	}
+
+	makeTool := func(t *testing.T, c cfg) *FSTool {
+		t.Helper()
+		opts := []FSToolOption{WithWorkBaseDir(c.workBaseDir), WithBlockSymlinks(c.blockSymlinks)}
+		if c.allowedRoots != nil {
+			opts = append(opts, WithAllowedRoots(c.allowedRoots))
+		}
+		return mustNewFSTool(t, opts...)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     func(t *testing.T) cfg
+		ctx     func(t *testing.T) context.Context
+		args    func(t *testing.T, c cfg) MovePathArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, c cfg, out *MovePathOut)
+	}{
+		{
+			name: "context_canceled",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			ctx: canceledContext,
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				return MovePathArgs{SourcePath: "a.txt", DestinationPath: "b.txt"}
+			},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name: "renames_file",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("hello"))
+				return MovePathArgs{SourcePath: "a.txt", DestinationPath: "b.txt"}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *MovePathOut) {
+				t.Helper()
+				if out.Method != MovePathMethodRename || out.FilesMoved != 1 || out.BytesMoved != 5 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if _, err := os.Lstat(filepath.Join(c.workBaseDir, "a.txt")); !os.IsNotExist(err) {
+					t.Fatalf("expected source removed, stat err=%v", err)
+				}
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "b.txt"))); got != "hello" {
+					t.Fatalf("dst content=%q", got)
+				}
+			},
+		},
+		{
+			name: "missing_source_preserves_isnotexist",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				return MovePathArgs{SourcePath: "missing.txt", DestinationPath: "b.txt"}
+			},
+			wantErr: func(err error) bool { return err != nil && os.IsNotExist(err) },
+		},
+		{
+			name: "file_exists_overwrite_false_keeps_both",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("new"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "b.txt"), []byte("old"))
+				return MovePathArgs{SourcePath: "a.txt", DestinationPath: "b.txt"}
+			},
+			wantErr: wantErrContains("overwrite=false"),
+			check: func(t *testing.T, c cfg, out *MovePathOut) {
+				t.Helper()
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "a.txt"))); got != "new" {
+					t.Fatalf("src content=%q", got)
+				}
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "b.txt"))); got != "old" {
+					t.Fatalf("dst content=%q", got)
+				}
+			},
+		},
+		{
+			name: "file_overwrite_true_replaces",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("new"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "b.txt"), []byte("old"))
+				return MovePathArgs{SourcePath: "a.txt", DestinationPath: "b.txt", Overwrite: true}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *MovePathOut) {
+				t.Helper()
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "b.txt"))); got != "new" {
+					t.Fatalf("dst content=%q", got)
+				}
+			},
+		},
+		{
+			name: "moves_directory_with_create_parents",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "src", "sub"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "src", "sub", "a.txt"), []byte("a"))
+				return MovePathArgs{SourcePath: "src", DestinationPath: "p/dst", CreateParents: true}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *MovePathOut) {
+				t.Helper()
+				if !out.IsDir || out.FilesMoved != 1 || out.DirsMoved != 1 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "p", "dst", "sub", "a.txt"))); got != "a" {
+					t.Fatalf("moved content=%q", got)
+				}
+				if _, err := os.Lstat(filepath.Join(c.workBaseDir, "src")); !os.IsNotExist(err) {
+					t.Fatalf("expected source dir removed, stat err=%v", err)
+				}
+			},
+		},
+		{
+			name: "directory_merge_with_overwrite",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "src", "sub"))
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "dst", "sub"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "src", "sub", "a.txt"), []byte("new"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "dst", "sub", "a.txt"), []byte("old"))
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "dst", "keep.txt"), []byte("keep"))
+				return MovePathArgs{SourcePath: "src", DestinationPath: "dst", Overwrite: true}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *MovePathOut) {
+				t.Helper()
+				if out.Method != MovePathMethodMerge {
+					t.Fatalf("method=%q want merge", out.Method)
+				}
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "dst", "sub", "a.txt"))); got != "new" {
+					t.Fatalf("merged content=%q", got)
+				}
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "dst", "keep.txt"))); got != "keep" {
+					t.Fatalf("kept content=%q", got)
+				}
+				if _, err := os.Lstat(filepath.Join(c.workBaseDir, "src")); !os.IsNotExist(err) {
+					t.Fatalf("expected source dir removed, stat err=%v", err)
+				}
+			},
+		},
+		{
+			name: "directory_into_itself_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(c.workBaseDir, "src"))
+				return MovePathArgs{SourcePath: "src", DestinationPath: "src/inner"}
+			},
+			wantErr: wantErrContains("inside source"),
+		},
+		{
+			name: "source_outside_allowed_roots_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				root := t.TempDir()
+				return cfg{workBaseDir: root, allowedRoots: []string{root}}
+			},
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				outside := filepath.Join(t.TempDir(), "x.txt")
+				mustWriteFile(t, outside, []byte("x"))
+				return MovePathArgs{SourcePath: outside, DestinationPath: "x.txt"}
+			},
+			wantErr: wantErrContains("outside allowed roots"),
+		},
+		{
+			name: "symlink_refused_when_blockSymlinks_true",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				if runtime.GOOS == toolutil.GOOSWindows {
+					t.Skip("symlink tests are unreliable on Windows CI")
+				}
+				return cfg{workBaseDir: t.TempDir(), blockSymlinks: true}
+			},
+			args: func(t *testing.T, c cfg) MovePathArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "target.txt"), []byte("t"))
+				mustSymlinkOrSkip(t, filepath.Join(c.workBaseDir, "target.txt"), filepath.Join(c.workBaseDir, "l"))
+				return MovePathArgs{SourcePath: "l", DestinationPath: "l2"}
+			},
+			wantErr: wantErrIs(fspolicy.ErrSymlinkDisallowed),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.cfg(t)
+			ft := makeTool(t, c)
+			ctx := t.Context()
+			if tt.ctx != nil {
+				ctx = tt.ctx(t)
+			}
+			out, err := ft.MovePath(ctx, tt.args(t, c))
+			if !tt.wantErr(err) {
+				t.Fatalf("err=%v did not match expectation", err)
+			}
+			if tt.check != nil {
+				tt.check(t, c, out)
+			}
+		})
+	}
+}
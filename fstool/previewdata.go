@@ -0,0 +1,205 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flexigpt/llmtools-go/internal/datautil"
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const previewDataFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/previewdata.PreviewData"
+
+const (
+	previewDataDefaultHeadRows   = 10
+	previewDataDefaultSampleRows = 10
+)
+
+var previewDataTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f7f-6ac1-79ca-898c-4ac5568ff587",
+	Slug:          "previewdata",
+	Version:       "v1.0.0",
+	DisplayName:   "Preview data file",
+	Description:   "Summarize a CSV, TSV, JSON, JSONL or Parquet file without loading it: column names, inferred types, null counts, row count (exact, or estimated past 256MB), the first rows and a random sample (drawn from the first 256MB for text formats). Use this instead of readfile for large data files. Parquet reports schema, row count and null counts from the footer and decodes rows from the first row groups.",
+	Tags:          []string{"fs", "data"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"path": {
+		"type": "string",
+		"description": "Data file to preview."
+	},
+	"format": {
+		"type": "string",
+		"enum": ["auto", "csv", "tsv", "json", "jsonl", "parquet"],
+		"description": "Data format. Default auto: detected from the extension (.csv, .tsv, .json, .jsonl/.ndjson, .parquet), else from the content.",
+		"default": "auto"
+	},
+	"headRows": {
+		"type": "integer",
+		"minimum": 0,
+		"maximum": 100,
+		"description": "Rows returned from the start of the file. Default 10.",
+		"default": 10
+	},
+	"sampleRows": {
+		"type": "integer",
+		"minimum": 0,
+		"maximum": 100,
+		"description": "Rows returned as a random sample of the scanned rows: the first 256MB of CSV/TSV/JSON/JSONL, the first row groups of Parquet (omitted when head already covers every scanned row). Default 10.",
+		"default": 10
+	},
+	"hasHeader": {
+		"type": "boolean",
+		"description": "CSV/TSV: whether the first record names the columns. Default true.",
+		"default": true
+	},
+	"delimiter": {
+		"type": "string",
+		"minLength": 1,
+		"maxLength": 1,
+		"description": "CSV/TSV: field delimiter overriding the format default (\",\" or tab), e.g. \";\" or \"|\"."
+	}
+},
+"required": ["path"],
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: previewDataFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type PreviewDataArgs struct {
+	Path       string `json:"path"`
+	Format     string `json:"format,omitempty"`     // "auto" (default) | "csv" | "tsv" | "json" | "jsonl" | "parquet"
+	HeadRows   *int   `json:"headRows,omitempty"`   // default 10
+	SampleRows *int   `json:"sampleRows,omitempty"` // default 10
+	HasHeader  *bool  `json:"hasHeader,omitempty"`  // default true
+	Delimiter  string `json:"delimiter,omitempty"`
+}
+
+type PreviewDataOut struct {
+	Path string `json:"path"`
+	datautil.Preview
+}
+
+// previewData summarizes a tabular data file.
+//
+// Behavior notes (entry point):
+//   - The file is streamed once; memory use is bounded by the preview rows, not the file size.
+//   - Statistics (types, null counts, sample) cover the first 256MB; past that the row count is
+//     extrapolated, rowCountExact is false and the sample is a prefix sample, not one of the whole file.
+//   - Parquet schema, exact row count and null counts (-1 when absent) come from the file footer; head and
+//     sample rows are decoded from the first row group plus any further groups within 256MB.
+//   - The sample uses a fixed seed, so previews of an unchanged file are repeatable.
+func previewData(ctx context.Context, args PreviewDataArgs, p fspolicy.FSPolicy) (*PreviewDataOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	abs, err := p.ResolvePath(args.Path, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.RequireExistingRegularFileResolved(abs); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("path does not exist: %s", abs)
+		}
+		return nil, err
+	}
+
+	opts := datautil.Options{
+		HeadRows:   previewDataDefaultHeadRows,
+		SampleRows: previewDataDefaultSampleRows,
+		NoHeader:   args.HasHeader != nil && !*args.HasHeader,
+	}
+	if args.HeadRows != nil {
+		opts.HeadRows = *args.HeadRows
+	}
+	if args.SampleRows != nil {
+		opts.SampleRows = *args.SampleRows
+	}
+	if opts.HeadRows < 0 || opts.HeadRows > datautil.MaxPreviewRows ||
+		opts.SampleRows < 0 || opts.SampleRows > datautil.MaxPreviewRows {
+		return nil, fmt.Errorf("headRows and sampleRows must be between 0 and %d", datautil.MaxPreviewRows)
+	}
+	if args.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(args.Delimiter)
+		if size != len(args.Delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return nil, fmt.Errorf(
+				"invalid delimiter %q: must be a single character other than a quote or newline", args.Delimiter,
+			)
+		}
+		opts.Delimiter = r
+	}
+
+	opts.Format, err = detectDataFormat(p.FS(), abs, args.Format)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := datautil.PreviewSafe(ctx, p.FS(), abs, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PreviewDataOut{Path: abs, Preview: *prev}, nil
+}
+
+// detectDataFormat validates an explicit format, or picks one from the file's extension MIME type and,
+// failing that, its first bytes.
+func detectDataFormat(fsys vfs.FS, abs, format string) (datautil.Format, error) {
+	switch f := datautil.Format(strings.ToLower(strings.TrimSpace(format))); f {
+	case datautil.FormatCSV, datautil.FormatTSV, datautil.FormatJSON, datautil.FormatJSONL, datautil.FormatParquet:
+		return f, nil
+	case "", "auto":
+	default:
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+
+	if mt, err := ioutil.MIMEFromExtensionString(filepath.Ext(abs)); err == nil {
+		switch ioutil.GetBaseMIME(mt) {
+		case ioutil.GetBaseMIME(ioutil.MIMETextCSV):
+			return datautil.FormatCSV, nil
+		case ioutil.GetBaseMIME(ioutil.MIMETextTSV):
+			return datautil.FormatTSV, nil
+		case ioutil.GetBaseMIME(ioutil.MIMEApplicationJSON):
+			return datautil.FormatJSON, nil
+		case ioutil.GetBaseMIME(ioutil.MIMEApplicationJSONL):
+			return datautil.FormatJSONL, nil
+		case ioutil.GetBaseMIME(ioutil.MIMEApplicationParquet):
+			return datautil.FormatParquet, nil
+		}
+	}
+
+	f, err := fsys.Open(abs)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, _ := f.Read(buf)
+	head := strings.TrimLeft(strings.TrimPrefix(string(buf[:n]), "\ufeff"), " \t\r\n")
+	switch {
+	case strings.HasPrefix(head, "PAR1"):
+		return datautil.FormatParquet, nil
+	case strings.HasPrefix(head, "["):
+		return datautil.FormatJSON, nil
+	case strings.HasPrefix(head, "{"):
+		// One object per line is JSONL; a single multi-line object is JSON.
+		if line, _, ok := strings.Cut(head, "\n"); ok && strings.HasSuffix(strings.TrimSpace(line), "}") {
+			return datautil.FormatJSONL, nil
+		}
+		return datautil.FormatJSON, nil
+	}
+	return "", fmt.Errorf("cannot detect the data format of %q; pass format explicitly", abs)
+}
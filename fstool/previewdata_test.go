@@ -0,0 +1,112 @@
+package fstool
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/datautil"
+)
+
+func TestPreviewData(t *testing.T) {
+	setup := func(t *testing.T, tmp string) {
+		t.Helper()
+		mustWriteFile(t, filepath.Join(tmp, "rows.csv"), []byte("a,b\n1,x\n2,\n3,z\n"))
+		mustWriteFile(t, filepath.Join(tmp, "semi.txt"), []byte("a;b\n1;2\n"))
+		mustWriteFile(t, filepath.Join(tmp, "events.log"), []byte("{\"k\":1}\n{\"k\":2}\n"))
+		mustWriteFile(t, filepath.Join(tmp, "doc.dat"), []byte("{\n  \"k\": [1, 2]\n}\n"))
+		mustWriteFile(t, filepath.Join(tmp, "plain.dat"), []byte("hello"))
+	}
+
+	tests := []struct {
+		name    string
+		ctx     func(t *testing.T) context.Context
+		args    PreviewDataArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, out *PreviewDataOut)
+	}{
+		{
+			name:    "context_canceled",
+			ctx:     canceledContext,
+			args:    PreviewDataArgs{Path: "rows.csv"},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name:    "csv_by_extension",
+			args:    PreviewDataArgs{Path: "rows.csv", HeadRows: ptrInt(2)},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *PreviewDataOut) {
+				t.Helper()
+				if out.Format != datautil.FormatCSV || out.RowCount != 3 || len(out.Head) != 2 || len(out.Sample) != 3 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if out.Columns[0].Type != datautil.TypeInteger || out.Columns[1].NullCount != 1 {
+					t.Fatalf("unexpected columns: %+v", out.Columns)
+				}
+			},
+		},
+		{
+			name:    "explicit_format_and_delimiter",
+			args:    PreviewDataArgs{Path: "semi.txt", Format: "csv", Delimiter: ";", HasHeader: ptrBool(false)},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *PreviewDataOut) {
+				t.Helper()
+				if len(out.Columns) != 2 || out.Columns[0].Name != "column_1" || out.RowCount != 2 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name:    "sniffed_jsonl",
+			args:    PreviewDataArgs{Path: "events.log"},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *PreviewDataOut) {
+				t.Helper()
+				if out.Format != datautil.FormatJSONL || out.RowCount != 2 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name:    "sniffed_json_object",
+			args:    PreviewDataArgs{Path: "doc.dat"},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *PreviewDataOut) {
+				t.Helper()
+				if out.Format != datautil.FormatJSON || out.Columns[0].Type != datautil.TypeArray {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{name: "undetectable", args: PreviewDataArgs{Path: "plain.dat"}, wantErr: wantErrContains("cannot detect")},
+		{
+			name:    "bad_delimiter",
+			args:    PreviewDataArgs{Path: "rows.csv", Delimiter: "\""},
+			wantErr: wantErrContains("invalid delimiter"),
+		},
+		{
+			name:    "rows_out_of_range",
+			args:    PreviewDataArgs{Path: "rows.csv", SampleRows: ptrInt(101)},
+			wantErr: wantErrContains("between 0 and 100"),
+		},
+		{name: "missing", args: PreviewDataArgs{Path: "nope.csv"}, wantErr: wantErrContains("does not exist")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			setup(t, tmp)
+			ft := mustNewFSTool(t, WithWorkBaseDir(tmp))
+			ctx := t.Context()
+			if tt.ctx != nil {
+				ctx = tt.ctx(t)
+			}
+			out, err := ft.PreviewData(ctx, tt.args)
+			if !tt.wantErr(err) {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if err == nil && tt.check != nil {
+				tt.check(t, out)
+			}
+		})
+	}
+}
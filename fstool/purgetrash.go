@@ -0,0 +1,132 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/spec"
+)
+
+const purgeTrashFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/purgetrash.PurgeTrash"
+
+var purgeTrashTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f1b-b3d0-77d6-b5f2-28103cd56744",
+	Slug:          "purgetrash",
+	Version:       "v1.0.0",
+	DisplayName:   "Purge trash",
+	Description:   "Permanently delete trash items whose recorded deletion time is older than a threshold. Items without trash metadata are never purged.",
+	Tags:          []string{"fs", "trash"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"trashDir": {
+		"type": "string",
+		"default": "auto",
+		"description": "Trash directory to purge (as used by deletefile). \"auto\" purges the system trash and the local .trash directories deletefile falls back to."
+	},
+	"olderThanDays": {
+		"type": "integer",
+		"minimum": 0,
+		"description": "Purge items deleted more than this many days ago. 0 purges all items with metadata."
+	},
+	"dryRun": {
+		"type": "boolean",
+		"default": false,
+		"description": "If true, only report what would be purged."
+	}
+},
+"required": ["olderThanDays"],
+"additionalProperties": false
+}`),
+
+	GoImpl: spec.GoToolImpl{FuncID: purgeTrashFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type PurgeTrashArgs struct {
+	TrashDir      string `json:"trashDir,omitempty"` // "auto" default
+	OlderThanDays int    `json:"olderThanDays"`
+	DryRun        bool   `json:"dryRun,omitempty"`
+}
+
+type PurgeTrashOut struct {
+	// TrashDir is the first trash directory purged (empty when "auto" found none).
+	TrashDir string `json:"trashDir"`
+	// TrashDirs lists every trash directory scanned when "auto" scans several.
+	TrashDirs             []string    `json:"trashDirs,omitempty"`
+	DryRun                bool        `json:"dryRun,omitempty"`
+	Purged                []TrashItem `json:"purged"`
+	SkippedNoMetadata     int         `json:"skippedNoMetadata,omitempty"`
+	RemainingWithMetadata int         `json:"remainingWithMetadata"`
+	Truncated             bool        `json:"truncated,omitempty"`
+}
+
+// purgeTrash permanently removes old trash entries.
+//
+// Behavior notes (entry point):
+//   - "auto" purges the system trash and the local .trash of the work base dir and allowed roots.
+//   - Age is taken only from .trashinfo DeletionDate; entries without metadata are skipped.
+//   - Removal is permanent (entry and its metadata). At most maxTrashListItems entries are purged per call.
+//   - DryRun reports candidates without removing anything.
+func purgeTrash(
+	ctx context.Context,
+	args PurgeTrashArgs,
+	p fspolicy.FSPolicy,
+) (*PurgeTrashOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if args.OlderThanDays < 0 {
+		return nil, errors.New("olderThanDays must be >= 0")
+	}
+	dirs, err := resolveTrashDirs(p, args.TrashDir)
+	if err != nil {
+		return nil, err
+	}
+	items, err := readTrashDirs(ctx, p.FS(), dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-time.Duration(args.OlderThanDays) * 24 * time.Hour)
+	out := &PurgeTrashOut{DryRun: args.DryRun, Purged: []TrashItem{}}
+	if len(dirs) > 0 {
+		out.TrashDir = dirs[0]
+	}
+	if len(dirs) > 1 {
+		out.TrashDirs = dirs
+	}
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if item.DeletedAt == nil {
+			out.SkippedNoMetadata++
+			continue
+		}
+		if !item.DeletedAt.Before(cutoff) || len(out.Purged) >= maxTrashListItems {
+			if item.DeletedAt.Before(cutoff) {
+				out.Truncated = true
+			}
+			out.RemainingWithMetadata++
+			continue
+		}
+		if !args.DryRun {
+			if err := p.FS().RemoveAll(item.TrashedPath); err != nil {
+				return nil, fmt.Errorf("purge %s: %w", item.TrashedPath, err)
+			}
+			_ = p.FS().Remove(trashInfoPathFor(p.FS(), filepath.Dir(item.TrashedPath), item.Name))
+		}
+		out.Purged = append(out.Purged, item)
+	}
+	return out, nil
+}
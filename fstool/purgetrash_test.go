@@ -0,0 +1,115 @@
+package fstool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestPurgeTrash(t *testing.T) {
+	// Seeds trash with: "old.txt" (10 days), "new.txt" (1 hour), "olddir" (10 days), "orphan" (no metadata).
+	seed := func(t *testing.T, root string) string {
+		t.Helper()
+		trash := filepath.Join(root, "trash")
+		mustMkdirAll(t, trashInfoDirFor(vfs.OS(), trash))
+		mustMkdirAll(t, filepath.Join(trash, "olddir", "sub"))
+		mustWriteFile(t, filepath.Join(trash, "olddir", "sub", "f"), []byte("f"))
+		for name, age := range map[string]time.Duration{
+			"old.txt": 10 * 24 * time.Hour,
+			"new.txt": time.Hour,
+			"olddir":  10 * 24 * time.Hour,
+		} {
+			if name != "olddir" {
+				mustWriteFile(t, filepath.Join(trash, name), []byte(name))
+			}
+			info := encodeTrashInfo(filepath.Join(root, name), time.Now().Add(-age))
+			mustWriteFile(t, trashInfoPathFor(vfs.OS(), trash, name), info)
+		}
+		mustWriteFile(t, filepath.Join(trash, "orphan"), []byte("o"))
+		return trash
+	}
+	exists := func(t *testing.T, path string) bool {
+		t.Helper()
+		_, err := os.Lstat(path)
+		return err == nil
+	}
+
+	tests := []struct {
+		name    string
+		ctx     func(t *testing.T) context.Context
+		args    PurgeTrashArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, trash string, out *PurgeTrashOut)
+	}{
+		{
+			name:    "context_canceled",
+			ctx:     canceledContext,
+			args:    PurgeTrashArgs{TrashDir: "trash", OlderThanDays: 1},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name:    "negative_threshold_errors",
+			args:    PurgeTrashArgs{TrashDir: "trash", OlderThanDays: -1},
+			wantErr: wantErrContains("olderThanDays"),
+		},
+		{
+			name:    "purges_only_old_items_with_metadata",
+			args:    PurgeTrashArgs{TrashDir: "trash", OlderThanDays: 7},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, trash string, out *PurgeTrashOut) {
+				t.Helper()
+				if len(out.Purged) != 2 || out.SkippedNoMetadata != 1 || out.RemainingWithMetadata != 1 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if exists(t, filepath.Join(trash, "old.txt")) || exists(t, filepath.Join(trash, "olddir")) {
+					t.Fatalf("expected old items removed")
+				}
+				if exists(t, trashInfoPathFor(vfs.OS(), trash, "old.txt")) {
+					t.Fatalf("expected old metadata removed")
+				}
+				if !exists(t, filepath.Join(trash, "new.txt")) || !exists(t, filepath.Join(trash, "orphan")) {
+					t.Fatalf("expected new and orphan items to remain")
+				}
+			},
+		},
+		{
+			name:    "dry_run_removes_nothing",
+			args:    PurgeTrashArgs{TrashDir: "trash", OlderThanDays: 0, DryRun: true},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, trash string, out *PurgeTrashOut) {
+				t.Helper()
+				if !out.DryRun || len(out.Purged) != 3 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				for _, name := range []string{"old.txt", "new.txt", "olddir", "orphan"} {
+					if !exists(t, filepath.Join(trash, name)) {
+						t.Fatalf("dry run removed %s", name)
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			trash := seed(t, root)
+			ft := mustNewFSTool(t, WithWorkBaseDir(root), WithAllowedRoots([]string{root}))
+			ctx := t.Context()
+			if tt.ctx != nil {
+				ctx = tt.ctx(t)
+			}
+			out, err := ft.PurgeTrash(ctx, tt.args)
+			if !tt.wantErr(err) {
+				t.Fatalf("err=%v did not match expectation", err)
+			}
+			if err == nil && tt.check != nil {
+				tt.check(t, trash, out)
+			}
+		})
+	}
+}
@@ -2,19 +2,23 @@ package fstool
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"mime"
 	"os"
 	"path/filepath"
 	"strings"
-	"unicode/utf8"
 
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
 	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/markdownutil"
+	"github.com/flexigpt/llmtools-go/internal/officeutil"
 	"github.com/flexigpt/llmtools-go/internal/pdfutil"
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
 	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 const readFileFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/readfile.ReadFile"
@@ -25,7 +29,7 @@ var readFileTool = spec.Tool{
 	Slug:          "readfile",
 	Version:       "v1.0.0",
 	DisplayName:   "Read file",
-	Description:   "Read a local file from disk and return its contents (text or base64).",
+	Description:   "Read a local file from disk and return its contents (text or base64). Many files can be read at once with paths (globs allowed). Large files can be paged with offset/length (bytes) or startLine/lineCount (text); chunked reads start with a JSON header containing totalSize, totalLines and a nextOffset cursor. PDFs can be read by page with pages; the header then reports pageCount, document metadata and outline. Use format \"markdown\" to read HTML and RTF as readable markdown; EPUB is always converted.",
 	Tags:          []string{"fs", "read"},
 
 	ArgSchema: spec.JSONSchema(`{
@@ -36,14 +40,59 @@ var readFileTool = spec.Tool{
 		"type": "string",
 		"description": "Path of the file to read."
 	},
+	"paths": {
+		"type": "array",
+		"items": { "type": "string" },
+		"minItems": 1,
+		"maxItems": 64,
+		"description": "Batch form: files or glob patterns (e.g. \"src/*.go\"; * ? [..] within one path segment, no **) to read in one call, up to 64 files and 16MB of output in total. Each file becomes one output headed by its path (text prefixed with \"==> path <==\"); a failing file gets an error item instead of failing the call. Only encoding and format apply per file."
+	},
 	"encoding": {
 		"type": "string",
 		"enum": ["text", "binary"],
-		"description": "Return mode: \"text\" reads file as UTF-8, \"binary\" returns base64 string.",
+		"description": "Return mode: \"text\" reads file as UTF-8, transcoding UTF-16 (BOM) and legacy encodings such as Windows-1252, Latin-1 and Shift_JIS and appending a JSON item {path, encoding} when it does (PDF, docx, xlsx, pptx, odt, ods and epub files are converted to plain text), \"binary\" returns base64 string.",
 		"default": "text"
+	},
+	"format": {
+		"type": "string",
+		"enum": ["raw", "markdown"],
+		"description": "Text mode only. \"raw\" returns the file as is; \"markdown\" converts HTML (.html/.htm/.xhtml), EPUB and RTF to readable markdown, dropping scripts, styles and markup and keeping headings, lists, links and tables. Cannot be combined with chunked reads.",
+		"default": "raw"
+	},
+	"offset": {
+		"type": "integer",
+		"minimum": 0,
+		"description": "Byte offset to start reading at (chunked read). In text mode the start is moved forward to the next character boundary (of the detected encoding, e.g. UTF-8, UTF-16 or Shift_JIS) if needed."
+	},
+	"length": {
+		"type": "integer",
+		"minimum": 1,
+		"description": "Max bytes to return for a chunked read. Default and max is 16MB."
+	},
+	"startLine": {
+		"type": "integer",
+		"minimum": 1,
+		"description": "1-based line to start reading at (text mode only). Cannot be combined with offset/length."
+	},
+	"lineCount": {
+		"type": "integer",
+		"minimum": 1,
+		"description": "Number of lines to return from startLine (text mode only). Defaults to as many lines as fit in 16MB."
+	},
+	"pages": {
+		"type": "string",
+		"description": "PDF text mode only: pages to extract, 1-based, e.g. \"1-5\", \"2,7,10-12\" or \"20-\" (to the end). Output starts with a JSON header {pageCount, title, author, creationDate, outline, pages, nextPage, truncated}, then the text with a \"--- Page N of M ---\" marker before each page. Cannot be combined with offset/length or startLine/lineCount."
+	},
+	"includeVersion": {
+		"type": "boolean",
+		"description": "For whole-file reads, append a JSON item {path, version} whose version can be passed as expectedVersion to writefile and the text edit tools. Chunked reads always report version in their header (files up to 64MB).",
+		"default": false
 	}
 },
-"required": ["path"],
+"oneOf": [
+	{ "required": ["path"], "not": { "required": ["paths"] } },
+	{ "required": ["paths"], "not": { "required": ["path"] } }
+],
 "additionalProperties": false
 }`),
 	GoImpl: spec.GoToolImpl{FuncID: readFileFuncID},
@@ -53,17 +102,93 @@ var readFileTool = spec.Tool{
 }
 
 type ReadFileArgs struct {
-	Path     string `json:"path"`               // required
-	Encoding string `json:"encoding,omitempty"` // "text" (default) | "binary"
+	Path     string   `json:"path,omitempty"`     // required unless Paths is set
+	Paths    []string `json:"paths,omitempty"`    // batch form; see readFileBatch
+	Encoding string   `json:"encoding,omitempty"` // "text" (default) | "binary"
+	Format   string   `json:"format,omitempty"`   // "raw" (default) | "markdown"; text mode only
+
+	// Chunked reads: either a byte window (Offset/Length) or a line window (StartLine/LineCount).
+	Offset    int64 `json:"offset,omitempty"`
+	Length    int64 `json:"length,omitempty"`
+	StartLine int   `json:"startLine,omitempty"`
+	LineCount int   `json:"lineCount,omitempty"`
+
+	// PDF page window, e.g. "1-5,8" (text mode only).
+	Pages string `json:"pages,omitempty"`
+
+	IncludeVersion bool `json:"includeVersion,omitempty"`
+}
+
+// ReadFileVersionInfo is the JSON item appended to a whole-file readfile result when IncludeVersion is set.
+type ReadFileVersionInfo struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+// ReadFileEncodingInfo is the JSON item appended to a whole-file text read when the file was not plain
+// UTF-8 and was transcoded (or had a byte order mark stripped). The text edit tools keep this encoding
+// when writing the file back.
+type ReadFileEncodingInfo struct {
+	Path     string `json:"path"`
+	Encoding string `json:"encoding"` // e.g. "utf-16le", "windows-1252", "shift_jis"
+	BOM      bool   `json:"bom,omitempty"`
+}
+
+// ReadFileChunkInfo is the JSON header (first text output) of a chunked readfile result.
+type ReadFileChunkInfo struct {
+	Path       string `json:"path"`
+	Offset     int64  `json:"offset"`
+	Length     int64  `json:"length"`
+	TotalSize  int64  `json:"totalSize"`
+	TotalLines *int   `json:"totalLines,omitempty"` // only when cheap to compute
+	NextOffset int64  `json:"nextOffset"`
+	EOF        bool   `json:"eof"`
+
+	// Line mode only.
+	StartLine int `json:"startLine,omitempty"`
+	EndLine   int `json:"endLine,omitempty"`
+	NextLine  int `json:"nextLine,omitempty"`
+
+	Version string `json:"version,omitempty"` // whole-file version; only when cheap to compute
+
+	// Text mode only: set when the file is not plain UTF-8 and the chunk was transcoded. Offsets and
+	// lengths still count bytes of the file.
+	Encoding string `json:"encoding,omitempty"`
+	BOM      bool   `json:"bom,omitempty"`
+}
+
+// ReadFilePDFPagesInfo is the JSON header (first text output) of a readfile result for a PDF page range.
+type ReadFilePDFPagesInfo struct {
+	Path string `json:"path"`
+	pdfutil.PDFInfo
+
+	Pages     []int `json:"pages"`              // pages included in the text, in order
+	NextPage  int   `json:"nextPage,omitempty"` // set when the size limit cut the range short
+	Truncated bool  `json:"truncated"`
+}
+
+// readFileScanMaxBytes bounds the full-file scans used to report TotalLines and Version for chunked reads.
+const readFileScanMaxBytes = 4 * toolutil.MaxFileReadBytes
+
+func (a ReadFileArgs) isChunked() bool {
+	return a.Offset != 0 || a.Length != 0 || a.StartLine != 0 || a.LineCount != 0
+}
+
+func (a ReadFileArgs) isLineMode() bool {
+	return a.StartLine != 0 || a.LineCount != 0
 }
 
 // readFile reads a file from disk and returns its contents.
 // If Encoding == "binary" the output is base64-encoded.
+// If any chunk argument is set, a bounded window is returned instead (see readFileChunk).
+// If Paths is set, several files are read at once (see readFileBatch).
+// Version tokens are computed before the content is read, so a concurrent change makes them stale
+// rather than describing content the caller never saw.
 func readFile(
 	ctx context.Context,
 	args ReadFileArgs,
 	p fspolicy.FSPolicy,
-) ([]spec.ToolOutputUnion, error) {
+) (outs []spec.ToolOutputUnion, retErr error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
@@ -77,6 +202,17 @@ func readFile(
 		return nil, errors.New(`encoding must be "text" or "binary"`)
 	}
 
+	if len(args.Paths) > 0 {
+		return readFileBatch(ctx, args, p)
+	}
+	if err := validateReadFileChunkArgs(args, enc); err != nil {
+		return nil, err
+	}
+	markdown, err := readFileMarkdownFormat(args, enc)
+	if err != nil {
+		return nil, err
+	}
+
 	abs, err := p.ResolvePath(args.Path, "")
 	if err != nil {
 		return nil, err
@@ -90,26 +226,86 @@ func readFile(
 		return nil, err
 	}
 
+	if args.IncludeVersion && !args.isChunked() {
+		version, err := ioutil.FileVersion(ctx, p.FS(), abs)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if retErr != nil {
+				return
+			}
+			item, err := json.Marshal(ReadFileVersionInfo{Path: abs, Version: version})
+			if err != nil {
+				outs, retErr = nil, err
+				return
+			}
+			outs = append(outs, spec.ToolOutputUnion{
+				Kind:     spec.ToolOutputKindText,
+				TextItem: &spec.ToolOutputText{Text: string(item)},
+			})
+		}()
+	}
+
 	// Detect MIME / extension where possible.
-	mimeType, extMode, _, mimeErr := ioutil.MIMEForLocalFile(abs)
+	mimeType, extMode, _, mimeErr := ioutil.MIMEForLocalFile(p.FS(), abs)
 	ext := strings.ToLower(filepath.Ext(abs))
 
 	isPDFByExt := ext == string(ioutil.ExtPDF)
 	isPDFByMime := mimeErr == nil && mimeType == ioutil.MIMEApplicationPDF
 	isPDF := isPDFByExt || isPDFByMime
+	isOffice := officeutil.IsOfficeExt(ext)
+	// EPUB has no useful raw text form, so it is always converted.
+	isMarkup := markdownutil.IsMarkdownExt(ext) && (markdown || ext == ".epub")
 
 	if enc == ioutil.ReadEncodingText {
-		// For non-PDFs, fail if MIME detection fails (conservative).
-		// For PDFs, allow text extraction even if MIME sniffing fails,
-		// as long as the extension is .pdf.
-		if !isPDF && mimeErr != nil {
+		if args.Pages != "" && !isPDF {
+			return nil, fmt.Errorf("pages is only supported for PDF files, not %q", abs)
+		}
+		if markdown && !isMarkup && !isPDF && !isOffice {
+			return nil, fmt.Errorf(
+				"format \"markdown\" supports HTML, EPUB and RTF files (PDF and office documents are always converted), not %q",
+				abs,
+			)
+		}
+		if isMarkup {
+			// HTML, EPUB and RTF: convert the whole document, limited to toolutil.MaxFileReadBytes.
+			if args.isChunked() {
+				return nil, errors.New("offset/length and startLine/lineCount are not supported for markdown conversion")
+			}
+			text, err := markdownutil.ConvertToMarkdownSafe(ctx, p.FS(), abs, toolutil.MaxFileReadBytes)
+			if err != nil {
+				return nil, err
+			}
+			return []spec.ToolOutputUnion{
+				{
+					Kind:     spec.ToolOutputKindText,
+					TextItem: &spec.ToolOutputText{Text: text},
+				},
+			}, nil
+		}
+
+		// For non-documents, fail if MIME detection fails (conservative).
+		// For PDFs and office documents, allow text extraction even if MIME sniffing fails,
+		// as long as the extension matches.
+		if !isPDF && !isOffice && mimeErr != nil {
 			return nil, fmt.Errorf("cannot read %q as text (MIME detection failed: %w)", abs, mimeErr)
 		}
 
-		if isPDF {
-			// PDF: use the same extraction logic as attachments.
-			// Extraction itself is limited to toolutil.MaxFileReadBytes via LimitedReader.
-			text, err := pdfutil.ExtractPDFTextSafe(ctx, abs, toolutil.MaxFileReadBytes)
+		if isPDF && args.Pages != "" {
+			return readFilePDFPages(ctx, p.FS(), args, abs)
+		}
+		if (isPDF || isOffice) && args.isChunked() {
+			return nil, errors.New("offset/length and startLine/lineCount are not supported for PDF and office document text extraction")
+		}
+		if isPDF || isOffice {
+			// PDF and office documents: use the same extraction logic as attachments.
+			// Extraction itself is limited to toolutil.MaxFileReadBytes.
+			extract := pdfutil.ExtractPDFTextSafe
+			if isOffice {
+				extract = officeutil.ExtractOfficeTextSafe
+			}
+			text, err := extract(ctx, p.FS(), abs, toolutil.MaxFileReadBytes)
 			if err != nil {
 				return nil, err
 			}
@@ -132,32 +328,52 @@ func readFile(
 			)
 		}
 
-		// Normal text file: read and validate UTF‑8.
-		data, err := ioutil.ReadFile(abs, ioutil.ReadEncodingText, toolutil.MaxFileReadBytes)
+		if args.isChunked() {
+			return readFileChunk(ctx, p.FS(), args, abs, enc)
+		}
+
+		// Normal text file: read and decode to UTF‑8 (BOM and charset detection).
+		data, err := ioutil.ReadFile(p.FS(), abs, ioutil.ReadEncodingText, toolutil.MaxFileReadBytes)
 		if err != nil {
-			return nil, err
+			return nil, withChunkHint(err)
 		}
-		if !utf8.ValidString(data) {
+		dec, err := ioutil.DecodeText([]byte(data))
+		if err != nil {
 			return nil, fmt.Errorf(
-				"file %q is not valid UTF-8 text; use encoding \"binary\" instead",
+				"file %q is not valid UTF-8 or a detectable text encoding; use encoding \"binary\" instead",
 				abs,
 			)
 		}
 
-		return []spec.ToolOutputUnion{
+		res := []spec.ToolOutputUnion{
 			{
 				Kind: spec.ToolOutputKindText,
 				TextItem: &spec.ToolOutputText{
-					Text: data,
+					Text: dec.Text,
 				},
 			},
-		}, nil
+		}
+		if dec.Encoding != ioutil.TextEncodingUTF8 || dec.HasBOM {
+			item, err := json.Marshal(ReadFileEncodingInfo{Path: abs, Encoding: string(dec.Encoding), BOM: dec.HasBOM})
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, spec.ToolOutputUnion{
+				Kind:     spec.ToolOutputKindText,
+				TextItem: &spec.ToolOutputText{Text: string(item)},
+			})
+		}
+		return res, nil
+	}
+
+	if args.isChunked() {
+		return readFileChunk(ctx, p.FS(), args, abs, enc)
 	}
 
 	// Binary mode: base64-encode and return, like before.
-	data, err := ioutil.ReadFile(abs, ioutil.ReadEncodingBinary, toolutil.MaxFileReadBytes)
+	data, err := ioutil.ReadFile(p.FS(), abs, ioutil.ReadEncodingBinary, toolutil.MaxFileReadBytes)
 	if err != nil {
-		return nil, err
+		return nil, withChunkHint(err)
 	}
 
 	baseName := filepath.Base(abs)
@@ -205,3 +421,190 @@ func readFile(
 		},
 	}, nil
 }
+
+// readFileMarkdownFormat validates args.Format and reports whether markdown conversion was requested.
+func readFileMarkdownFormat(args ReadFileArgs, enc ioutil.ReadEncoding) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(args.Format)) {
+	case "", "raw":
+		return false, nil
+	case "markdown":
+		if enc != ioutil.ReadEncodingText {
+			return false, errors.New(`format "markdown" requires encoding "text"`)
+		}
+		if args.Pages != "" {
+			return false, errors.New(`format "markdown" cannot be combined with pages`)
+		}
+		return true, nil
+	}
+	return false, errors.New(`format must be "raw" or "markdown"`)
+}
+
+func validateReadFileChunkArgs(args ReadFileArgs, enc ioutil.ReadEncoding) error {
+	if args.Offset < 0 || args.Length < 0 || args.StartLine < 0 || args.LineCount < 0 {
+		return errors.New("offset, length, startLine and lineCount must not be negative")
+	}
+	if args.Pages != "" {
+		if args.isChunked() {
+			return errors.New("pages cannot be combined with offset/length or startLine/lineCount")
+		}
+		if enc != ioutil.ReadEncodingText {
+			return errors.New(`pages requires encoding "text"`)
+		}
+	}
+	if args.isLineMode() {
+		if args.Offset != 0 || args.Length != 0 {
+			return errors.New("use either offset/length or startLine/lineCount, not both")
+		}
+		if enc != ioutil.ReadEncodingText {
+			return errors.New(`startLine/lineCount require encoding "text"`)
+		}
+	}
+	return nil
+}
+
+// readFilePDFPages returns the text of the requested PDF pages, preceded by a JSON ReadFilePDFPagesInfo
+// header carrying the page count and document metadata.
+func readFilePDFPages(ctx context.Context, fsys vfs.FS, args ReadFileArgs, abs string) ([]spec.ToolOutputUnion, error) {
+	ranges, err := pdfutil.ParsePageRanges(args.Pages)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := pdfutil.ExtractPDFPagesSafe(ctx, fsys, abs, ranges, toolutil.MaxFileReadBytes)
+	if err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(ReadFilePDFPagesInfo{
+		Path:      abs,
+		PDFInfo:   pages.Info,
+		Pages:     pages.Pages,
+		NextPage:  pages.NextPage,
+		Truncated: pages.Truncated,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []spec.ToolOutputUnion{
+		{Kind: spec.ToolOutputKindText, TextItem: &spec.ToolOutputText{Text: string(header)}},
+		{Kind: spec.ToolOutputKindText, TextItem: &spec.ToolOutputText{Text: pages.Text}},
+	}, nil
+}
+
+// readFileChunk returns a bounded window of abs: a JSON ReadFileChunkInfo header, then the data
+// (text item in text mode, file item in binary mode since a partial file has no meaningful MIME type).
+// Text mode detects the file's encoding from its head and transcodes the window, which is aligned to
+// that encoding's character boundaries.
+func readFileChunk(
+	ctx context.Context,
+	fsys vfs.FS,
+	args ReadFileArgs,
+	abs string,
+	enc ioutil.ReadEncoding,
+) ([]spec.ToolOutputUnion, error) {
+	var version string
+	if st, err := fsys.Stat(abs); err == nil && st.Size() <= readFileScanMaxBytes {
+		if v, err := ioutil.FileVersion(ctx, fsys, abs); err == nil {
+			version = v
+		}
+	}
+
+	var (
+		textEnc ioutil.TextEncoding
+		bom     bool
+	)
+	if enc == ioutil.ReadEncodingText {
+		var err error
+		if textEnc, bom, err = ioutil.DetectFileTextEncoding(fsys, abs); err != nil {
+			return nil, fmt.Errorf(
+				"file %q is not valid UTF-8 or a detectable text encoding; use encoding \"binary\" instead",
+				abs,
+			)
+		}
+	}
+
+	var (
+		chunk *ioutil.FileChunk
+		err   error
+	)
+	if args.isLineMode() {
+		startLine := max(args.StartLine, 1)
+		chunk, err = ioutil.ReadTextLines(ctx, fsys, abs, startLine, args.LineCount, toolutil.MaxFileReadBytes, textEnc)
+	} else {
+		length := args.Length
+		if length <= 0 || length > toolutil.MaxFileReadBytes {
+			length = toolutil.MaxFileReadBytes
+		}
+		if enc == ioutil.ReadEncodingText {
+			chunk, err = ioutil.ReadTextChunk(ctx, fsys, abs, args.Offset, length, textEnc)
+		} else {
+			chunk, err = ioutil.ReadFileChunk(ctx, fsys, abs, args.Offset, length, false)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := ReadFileChunkInfo{
+		Path:       abs,
+		Offset:     chunk.Offset,
+		Length:     int64(len(chunk.Data)),
+		TotalSize:  chunk.TotalSize,
+		NextOffset: chunk.NextOffset,
+		EOF:        chunk.EOF,
+		StartLine:  chunk.StartLine,
+		EndLine:    chunk.EndLine,
+		Version:    version,
+	}
+	if chunk.EndLine > 0 && !chunk.EOF {
+		info.NextLine = chunk.EndLine + 1
+	}
+
+	var body spec.ToolOutputUnion
+	if enc == ioutil.ReadEncodingText {
+		data := chunk.Data
+		if bom && chunk.Offset == 0 {
+			data = data[min(ioutil.TextBOMLen(textEnc), len(data)):]
+		}
+		text, err := ioutil.DecodeTextAs(data, textEnc)
+		if err != nil {
+			return nil, fmt.Errorf("file %q is not valid %s text in the requested range; use encoding \"binary\" instead", abs, textEnc)
+		}
+		if textEnc != ioutil.TextEncodingUTF8 || bom {
+			info.Encoding = string(textEnc)
+			info.BOM = bom
+		}
+		if chunk.TotalSize <= readFileScanMaxBytes {
+			if n, err := ioutil.CountTextLines(ctx, fsys, abs, textEnc); err == nil {
+				info.TotalLines = &n
+			}
+		}
+		body = spec.ToolOutputUnion{
+			Kind:     spec.ToolOutputKindText,
+			TextItem: &spec.ToolOutputText{Text: text},
+		}
+	} else {
+		body = spec.ToolOutputUnion{
+			Kind: spec.ToolOutputKindFile,
+			FileItem: &spec.ToolOutputFile{
+				FileName: filepath.Base(abs),
+				FileMIME: "application/octet-stream",
+				FileData: base64.StdEncoding.EncodeToString(chunk.Data),
+			},
+		}
+	}
+
+	header, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	return []spec.ToolOutputUnion{
+		{Kind: spec.ToolOutputKindText, TextItem: &spec.ToolOutputText{Text: string(header)}},
+		body,
+	}, nil
+}
+
+func withChunkHint(err error) error {
+	if errors.Is(err, ioutil.ErrFileExceedsMaxSize) {
+		return fmt.Errorf("%w; use offset/length or startLine/lineCount to read it in chunks", err)
+	}
+	return err
+}
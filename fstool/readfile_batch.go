@@ -0,0 +1,212 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const (
+	// readFileMaxBatchFiles caps the number of files (after glob expansion) in one batch read.
+	readFileMaxBatchFiles = 64
+	// readFileMaxBatchBytes caps the output of a batch read across all files.
+	readFileMaxBatchBytes = toolutil.MaxFileReadBytes
+)
+
+// readFileBatch reads several files in one call.
+//
+// Behavior notes (entry point):
+//   - Each entry of args.Paths is a file path or a glob pattern (* ? [...] within a path segment; ** is
+//     not supported). Patterns expand to the regular files they match, in lexical order; duplicates are
+//     read once.
+//   - The result has one output per file. Text is prefixed with a "==> path <==" header line; images and
+//     other binary files carry the absolute path as their name.
+//   - A file that cannot be read yields an error text item under its header instead of failing the call.
+//   - At most readFileMaxBatchFiles files and readFileMaxBatchBytes of output are returned; files past
+//     either limit are listed as skipped.
+//   - Only Encoding and Format apply; chunk, pages and version arguments are rejected.
+func readFileBatch(
+	ctx context.Context,
+	args ReadFileArgs,
+	p fspolicy.FSPolicy,
+) ([]spec.ToolOutputUnion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Path) != "" {
+		return nil, errors.New("provide either path or paths, not both")
+	}
+	if args.isChunked() || args.Pages != "" || args.IncludeVersion {
+		return nil, errors.New(
+			"offset/length, startLine/lineCount, pages and includeVersion are not supported with paths; read the file singly",
+		)
+	}
+	if len(args.Paths) > readFileMaxBatchFiles {
+		return nil, fmt.Errorf("too many paths (%d); max %d", len(args.Paths), readFileMaxBatchFiles)
+	}
+
+	files, outs := expandReadFilePaths(args.Paths, p)
+	budget := readFileMaxBatchBytes
+	for i, abs := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if i >= readFileMaxBatchFiles {
+			err := fmt.Errorf("skipped: batch limit of %d files reached", readFileMaxBatchFiles)
+			outs = append(outs, readFileBatchError(abs, err))
+			continue
+		}
+		if budget <= 0 {
+			err := fmt.Errorf("skipped: batch output limit of %d bytes reached", readFileMaxBatchBytes)
+			outs = append(outs, readFileBatchError(abs, err))
+			continue
+		}
+		res, err := readFile(ctx, ReadFileArgs{Path: abs, Encoding: args.Encoding, Format: args.Format}, p)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			outs = append(outs, readFileBatchError(abs, err))
+			continue
+		}
+		item := readFileBatchItem(abs, res)
+		n := outputSize(item)
+		if n > budget {
+			outs = append(outs, readFileBatchError(abs, fmt.Errorf(
+				"skipped: output of %d bytes exceeds the remaining batch budget of %d bytes; read it singly", n, budget,
+			)))
+			continue
+		}
+		budget -= n
+		outs = append(outs, item)
+	}
+	return outs, nil
+}
+
+// expandReadFilePaths resolves plain paths and expands glob patterns into a deduplicated list of
+// absolute paths. Patterns that fail or match nothing produce error items.
+func expandReadFilePaths(paths []string, p fspolicy.FSPolicy) (files []string, errs []spec.ToolOutputUnion) {
+	seen := make(map[string]bool)
+	add := func(abs string) {
+		if !seen[abs] {
+			seen[abs] = true
+			files = append(files, abs)
+		}
+	}
+	for _, raw := range paths {
+		if !hasGlobMeta(raw) {
+			abs, err := p.ResolvePath(raw, "")
+			if err != nil {
+				errs = append(errs, readFileBatchError(raw, err))
+				continue
+			}
+			add(abs)
+			continue
+		}
+		matches, err := globReadFilePattern(raw, p)
+		if err != nil {
+			errs = append(errs, readFileBatchError(raw, err))
+			continue
+		}
+		if len(matches) == 0 {
+			errs = append(errs, readFileBatchError(raw, errors.New("pattern matched no files")))
+			continue
+		}
+		for _, m := range matches {
+			add(m)
+		}
+	}
+	return files, errs
+}
+
+// globReadFilePattern expands pattern to the regular files it matches. The directory part before the
+// first wildcard is resolved and checked against the policy; the wildcard part may not contain "..".
+func globReadFilePattern(pattern string, p fspolicy.FSPolicy) ([]string, error) {
+	clean := filepath.ToSlash(strings.TrimSpace(pattern))
+	if strings.Contains(clean, "**") {
+		return nil, errors.New("recursive ** patterns are not supported")
+	}
+	segs := strings.Split(clean, "/")
+	first := slices.IndexFunc(segs, hasGlobMeta)
+	if slices.Contains(segs[first:], "..") {
+		return nil, errors.New(`".." is not allowed after a wildcard`)
+	}
+	dirPart := strings.Join(segs[:first], "/")
+	if dirPart == "" && strings.HasPrefix(clean, "/") || strings.HasSuffix(dirPart, ":") {
+		dirPart += "/" // filesystem or drive root
+	}
+	dir, err := p.ResolvePath(filepath.FromSlash(dirPart), ".")
+	if err != nil {
+		return nil, err
+	}
+	if err := p.VerifyDirResolved(dir); err != nil {
+		return nil, err
+	}
+	matches, err := vfs.Glob(p.FS(), filepath.Join(dir, filepath.FromSlash(strings.Join(segs[first:], "/"))))
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern: %w", err)
+	}
+	files := matches[:0]
+	for _, m := range matches {
+		if st, err := p.FS().Lstat(m); err == nil && st.Mode().IsRegular() {
+			files = append(files, m)
+		}
+	}
+	return files, nil
+}
+
+func hasGlobMeta(s string) bool { return strings.ContainsAny(s, "*?[") }
+
+// readFileBatchItem merges the outputs of a single-file read into one item labeled with path.
+func readFileBatchItem(path string, res []spec.ToolOutputUnion) spec.ToolOutputUnion {
+	if len(res) == 1 {
+		switch it := res[0]; it.Kind {
+		case spec.ToolOutputKindImage:
+			img := *it.ImageItem
+			img.ImageName = path
+			return spec.ToolOutputUnion{Kind: spec.ToolOutputKindImage, ImageItem: &img}
+		case spec.ToolOutputKindFile:
+			f := *it.FileItem
+			f.FileName = path
+			return spec.ToolOutputUnion{Kind: spec.ToolOutputKindFile, FileItem: &f}
+		}
+	}
+	texts := make([]string, 0, len(res))
+	for _, it := range res {
+		if it.TextItem != nil {
+			texts = append(texts, it.TextItem.Text)
+		}
+	}
+	return readFileBatchText(path, strings.Join(texts, "\n\n"))
+}
+
+func readFileBatchError(path string, err error) spec.ToolOutputUnion {
+	return readFileBatchText(path, "error: "+err.Error())
+}
+
+func readFileBatchText(path, body string) spec.ToolOutputUnion {
+	return spec.ToolOutputUnion{
+		Kind:     spec.ToolOutputKindText,
+		TextItem: &spec.ToolOutputText{Text: "==> " + path + " <==\n" + body},
+	}
+}
+
+func outputSize(it spec.ToolOutputUnion) int {
+	switch {
+	case it.TextItem != nil:
+		return len(it.TextItem.Text)
+	case it.ImageItem != nil:
+		return len(it.ImageItem.ImageData)
+	case it.FileItem != nil:
+		return len(it.FileItem.FileData)
+	}
+	return 0
+}
@@ -0,0 +1,136 @@
+package fstool
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/spec"
+)
+
+func TestReadFile_Batch(t *testing.T) {
+	root := t.TempDir()
+	mustMkdirAll(t, filepath.Join(root, "src", "dir.go"))
+	mustWriteFile(t, filepath.Join(root, "src", "a.go"), []byte("package a\n"))
+	mustWriteFile(t, filepath.Join(root, "src", "b.go"), []byte("package b\n"))
+	mustWriteFile(t, filepath.Join(root, "notes.txt"), []byte("hello"))
+	mustWriteFile(t, filepath.Join(root, "blob.bin"), []byte{0x00, 0x01, 0x02})
+
+	abs := func(rel string) string { return filepath.Join(root, filepath.FromSlash(rel)) }
+	header := func(p string) string { return "==> " + p + " <==\n" }
+
+	tests := []struct {
+		name    string
+		args    ReadFileArgs
+		want    []string // expected text of each output; "" for a non-text item
+		wantErr func(error) bool
+	}{
+		{
+			name: "mixed_success_and_errors",
+			args: ReadFileArgs{Paths: []string{"notes.txt", "missing.txt", "blob.bin"}},
+			want: []string{
+				header(abs("notes.txt")) + "hello",
+				header(abs("missing.txt")) + "error: path does not exist",
+				header(abs("blob.bin")) + "error: cannot read non-text file",
+			},
+			wantErr: wantErrNone,
+		},
+		{
+			name: "glob_expands_files_only_and_dedupes",
+			args: ReadFileArgs{Paths: []string{"src/a.go", "src/*.go"}},
+			want: []string{
+				header(abs("src/a.go")) + "package a\n",
+				header(abs("src/b.go")) + "package b\n",
+			},
+			wantErr: wantErrNone,
+		},
+		{
+			name: "pattern_errors_are_items",
+			args: ReadFileArgs{Paths: []string{"*.md", "src/**/*.go", "src/*/../x", "notes.txt"}},
+			want: []string{
+				header("*.md") + "error: pattern matched no files",
+				header("src/**/*.go") + "error: recursive ** patterns are not supported",
+				header("src/*/../x") + `error: ".." is not allowed after a wildcard`,
+				header(abs("notes.txt")) + "hello",
+			},
+			wantErr: wantErrNone,
+		},
+		{
+			name:    "binary_items_named_by_path",
+			args:    ReadFileArgs{Paths: []string{"blob.bin"}, Encoding: "binary"},
+			want:    []string{""},
+			wantErr: wantErrNone,
+		},
+		{
+			name:    "path_and_paths_conflict",
+			args:    ReadFileArgs{Path: "notes.txt", Paths: []string{"notes.txt"}},
+			wantErr: wantErrContains("not both"),
+		},
+		{
+			name:    "chunk_args_rejected",
+			args:    ReadFileArgs{Paths: []string{"notes.txt"}, Length: 2},
+			wantErr: wantErrContains("not supported with paths"),
+		},
+		{
+			name:    "too_many_paths",
+			args:    ReadFileArgs{Paths: make([]string, readFileMaxBatchFiles+1)},
+			wantErr: wantErrContains("too many paths"),
+		},
+	}
+
+	ft := mustNewFSTool(t, WithWorkBaseDir(root))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outs, err := ft.ReadFile(t.Context(), tt.args)
+			if !tt.wantErr(err) {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if len(outs) != len(tt.want) {
+				t.Fatalf("got %d outputs, want %d: %+v", len(outs), len(tt.want), outs)
+			}
+			for i, want := range tt.want {
+				if want == "" {
+					if outs[i].Kind != spec.ToolOutputKindFile || outs[i].FileItem.FileName != abs("blob.bin") {
+						t.Fatalf("output %d: want file item named by path, got %+v", i, outs[i])
+					}
+					continue
+				}
+				if outs[i].TextItem == nil || !strings.HasPrefix(outs[i].TextItem.Text, want) {
+					t.Fatalf("output %d = %+v, want prefix %q", i, outs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadFile_BatchByteBudget(t *testing.T) {
+	root := t.TempDir()
+	big := bytes.Repeat([]byte("x"), readFileMaxBatchBytes/2+1)
+	for _, name := range []string{"1.txt", "2.txt", "3.txt"} {
+		mustWriteFile(t, filepath.Join(root, name), big)
+	}
+	mustWriteFile(t, filepath.Join(root, "4.txt"), []byte("small"))
+
+	ft := mustNewFSTool(t, WithWorkBaseDir(root))
+	outs, err := ft.ReadFile(t.Context(), ReadFileArgs{Paths: []string{"*.txt"}})
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if len(outs) != 4 {
+		t.Fatalf("got %d outputs, want 4", len(outs))
+	}
+	for i, wantSkipped := range []bool{false, true, true, false} {
+		text := outs[i].TextItem.Text
+		if got := strings.Contains(text[:min(len(text), 200)], "error: skipped"); got != wantSkipped {
+			t.Fatalf("output %d skipped=%v, want %v", i, got, wantSkipped)
+		}
+	}
+	if want := fmt.Sprintf("==> %s <==\nsmall", filepath.Join(root, "4.txt")); outs[3].TextItem.Text != want {
+		t.Fatalf("small file output = %q, want %q", outs[3].TextItem.Text, want)
+	}
+}
@@ -3,10 +3,14 @@ package fstool
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"unicode/utf16"
 
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
 )
@@ -181,7 +185,7 @@ func TestReadFile(t *testing.T) {
 			cfg: func(t *testing.T) cfg {
 				t.Helper()
 				tmp := t.TempDir()
-				mustWriteFile(t, filepath.Join(tmp, "bad.txt"), []byte{0xff, 0xfe})
+				mustWriteFile(t, filepath.Join(tmp, "bad.txt"), []byte{0x00, 0xff, 0x01})
 				return cfg{workBaseDir: tmp}
 			},
 			args: func(t *testing.T, c cfg) ReadFileArgs {
@@ -226,6 +230,98 @@ func TestReadFile(t *testing.T) {
 			},
 			wantErr: wantErrContains("outside allowed roots"),
 		},
+		{
+			name: "docx_text_extracted",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) ReadFileArgs {
+				t.Helper()
+				mustWriteZip(t, filepath.Join(c.workBaseDir, "doc.docx"), map[string]string{
+					"word/document.xml": `<w:document xmlns:w="w"><w:body>` +
+						`<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Title</w:t></w:r></w:p>` +
+						`<w:p><w:r><w:t>Body text</w:t></w:r></w:p></w:body></w:document>`,
+				})
+				return ReadFileArgs{Path: "doc.docx"}
+			},
+			wantErr:  wantErrNone,
+			wantKind: "text",
+			wantText: "# Title\nBody text",
+		},
+		{
+			name: "office_document_chunked_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) ReadFileArgs {
+				t.Helper()
+				mustWriteZip(t, filepath.Join(c.workBaseDir, "s.xlsx"), map[string]string{"xl/workbook.xml": "<workbook/>"})
+				return ReadFileArgs{Path: "s.xlsx", StartLine: 1}
+			},
+			wantErr: wantErrContains("not supported for PDF and office document"),
+		},
+		{
+			name: "html_markdown_format",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) ReadFileArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "p.html"), []byte(
+					`<html><head><script>x()</script></head><body><h2>Hi</h2><p>See <a href="/d">docs</a></p></body></html>`))
+				return ReadFileArgs{Path: "p.html", Format: "markdown"}
+			},
+			wantKind: "text",
+			wantText: "## Hi\n\nSee [docs](/d)",
+		},
+		{
+			name: "epub_converted_without_format",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) ReadFileArgs {
+				t.Helper()
+				mustWriteZip(t, filepath.Join(c.workBaseDir, "b.epub"), map[string]string{
+					"META-INF/container.xml": `<container><rootfiles><rootfile full-path="c.opf"/></rootfiles></container>`,
+					"c.opf": `<package><manifest><item id="a" href="a.xhtml" media-type="application/xhtml+xml"/></manifest>` +
+						`<spine><itemref idref="a"/></spine></package>`,
+					"a.xhtml": `<html><body><p>Chapter text</p></body></html>`,
+				})
+				return ReadFileArgs{Path: "b.epub"}
+			},
+			wantKind: "text",
+			wantText: "Chapter text",
+		},
+		{
+			name: "markdown_format_on_plain_text_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) ReadFileArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "a.txt"), []byte("x"))
+				return ReadFileArgs{Path: "a.txt", Format: "markdown"}
+			},
+			wantErr: wantErrContains("supports HTML, EPUB and RTF"),
+		},
+		{
+			name: "markdown_format_with_binary_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) ReadFileArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "p.html"), []byte("<p>x</p>"))
+				return ReadFileArgs{Path: "p.html", Format: "markdown", Encoding: "binary"}
+			},
+			wantErr: wantErrContains(`requires encoding "text"`),
+		},
 	}
 
 	for _, tt := range tests {
@@ -303,3 +399,323 @@ func TestReadFile(t *testing.T) {
 		})
 	}
 }
+
+func TestReadFileChunked(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string][]byte
+		args     ReadFileArgs
+		wantErr  func(error) bool
+		wantInfo ReadFileChunkInfo
+		wantText string
+		wantRaw  []byte
+	}{
+		{
+			name:     "byte_window_text",
+			files:    map[string][]byte{"a.txt": []byte("hello\nworld\n")},
+			args:     ReadFileArgs{Path: "a.txt", Offset: 6, Length: 3},
+			wantErr:  wantErrNone,
+			wantInfo: ReadFileChunkInfo{Offset: 6, Length: 3, TotalSize: 12, NextOffset: 9},
+			wantText: "wor",
+		},
+		{
+			name:     "byte_window_text_aligns_to_utf8",
+			files:    map[string][]byte{"u.txt": []byte("é日")},
+			args:     ReadFileArgs{Path: "u.txt", Offset: 1, Length: 4},
+			wantErr:  wantErrNone,
+			wantInfo: ReadFileChunkInfo{Offset: 2, Length: 3, TotalSize: 5, NextOffset: 5, EOF: true},
+			wantText: "日",
+		},
+		{
+			name:     "line_window",
+			files:    map[string][]byte{"l.txt": []byte("1\n2\n3\n4\n")},
+			args:     ReadFileArgs{Path: "l.txt", StartLine: 2, LineCount: 2},
+			wantErr:  wantErrNone,
+			wantInfo: ReadFileChunkInfo{Offset: 2, Length: 4, TotalSize: 8, NextOffset: 6, StartLine: 2, EndLine: 3, NextLine: 4},
+			wantText: "2\n3\n",
+		},
+		{
+			name:     "binary_window_is_file_item",
+			files:    map[string][]byte{"b.bin": {0, 1, 2, 3, 4, 5}},
+			args:     ReadFileArgs{Path: "b.bin", Encoding: "binary", Offset: 4},
+			wantErr:  wantErrNone,
+			wantInfo: ReadFileChunkInfo{Offset: 4, Length: 2, TotalSize: 6, NextOffset: 6, EOF: true},
+			wantRaw:  []byte{4, 5},
+		},
+		{
+			name:     "utf16_line_window_is_transcoded",
+			files:    map[string][]byte{"w.txt": utf16LEWithBOM("a\nhé\n😀\n")},
+			args:     ReadFileArgs{Path: "w.txt", StartLine: 2, LineCount: 1},
+			wantErr:  wantErrNone,
+			wantInfo: ReadFileChunkInfo{Offset: 6, Length: 6, TotalSize: 18, NextOffset: 12, StartLine: 2, EndLine: 2, NextLine: 3, Encoding: "utf-16le", BOM: true},
+			wantText: "hé\n",
+		},
+		{
+			name:     "utf16_byte_window_keeps_surrogate_pairs_whole",
+			files:    map[string][]byte{"w.txt": utf16LEWithBOM("a\nhé\n😀\n")},
+			args:     ReadFileArgs{Path: "w.txt", Offset: 9, Length: 4},
+			wantErr:  wantErrNone,
+			wantInfo: ReadFileChunkInfo{Offset: 10, Length: 2, TotalSize: 18, NextOffset: 12, Encoding: "utf-16le", BOM: true},
+			wantText: "\n",
+		},
+		{
+			name: "shift_jis_byte_window_aligns_to_characters",
+			// "こんにちは\n世界\n"
+			files:    map[string][]byte{"j.txt": []byte("\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\n\x90\xa2\x8a\x45\n")},
+			args:     ReadFileArgs{Path: "j.txt", Offset: 3, Length: 5},
+			wantErr:  wantErrNone,
+			wantInfo: ReadFileChunkInfo{Offset: 4, Length: 4, TotalSize: 16, NextOffset: 8, Encoding: "shift_jis"},
+			wantText: "にち",
+		},
+		{
+			name:     "shift_jis_line_window",
+			files:    map[string][]byte{"j.txt": []byte("\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\n\x90\xa2\x8a\x45\n")},
+			args:     ReadFileArgs{Path: "j.txt", StartLine: 2},
+			wantErr:  wantErrNone,
+			wantInfo: ReadFileChunkInfo{Offset: 11, Length: 5, TotalSize: 16, NextOffset: 16, EOF: true, StartLine: 2, EndLine: 2, Encoding: "shift_jis"},
+			wantText: "世界\n",
+		},
+		{
+			name:    "mixed_modes_error",
+			files:   map[string][]byte{"a.txt": []byte("x")},
+			args:    ReadFileArgs{Path: "a.txt", Offset: 1, StartLine: 1},
+			wantErr: wantErrContains("not both"),
+		},
+		{
+			name:    "line_mode_binary_errors",
+			files:   map[string][]byte{"a.txt": []byte("x")},
+			args:    ReadFileArgs{Path: "a.txt", Encoding: "binary", StartLine: 1},
+			wantErr: wantErrContains(`require encoding "text"`),
+		},
+		{
+			name:    "negative_offset_errors",
+			files:   map[string][]byte{"a.txt": []byte("x")},
+			args:    ReadFileArgs{Path: "a.txt", Offset: -1},
+			wantErr: wantErrContains("negative"),
+		},
+		{
+			name:    "offset_beyond_eof_errors",
+			files:   map[string][]byte{"a.txt": []byte("x")},
+			args:    ReadFileArgs{Path: "a.txt", Offset: 10},
+			wantErr: wantErrContains("beyond end of file"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, data := range tt.files {
+				mustWriteFile(t, filepath.Join(root, name), data)
+			}
+			ft := mustNewFSTool(t, WithWorkBaseDir(root))
+			outs, err := ft.ReadFile(t.Context(), tt.args)
+			if !tt.wantErr(err) {
+				t.Fatalf("err=%v did not match expectation", err)
+			}
+			if err != nil {
+				return
+			}
+			if len(outs) != 2 || outs[0].TextItem == nil {
+				t.Fatalf("expected header + body, got %#v", outs)
+			}
+			var info ReadFileChunkInfo
+			if err := json.Unmarshal([]byte(outs[0].TextItem.Text), &info); err != nil {
+				t.Fatalf("header is not JSON: %v", err)
+			}
+			want := tt.wantInfo
+			want.Path = filepath.Join(root, tt.args.Path)
+			info.TotalLines = nil
+			if !strings.HasPrefix(info.Version, "sha256:") {
+				t.Fatalf("expected version in header, got %q", info.Version)
+			}
+			info.Version = ""
+			if info != want {
+				t.Fatalf("info=%+v want=%+v", info, want)
+			}
+			if tt.wantRaw != nil {
+				if outs[1].FileItem == nil {
+					t.Fatalf("expected file item, got %#v", outs[1])
+				}
+				if raw := decodeBase64OrFail(t, outs[1].FileItem.FileData); !bytes.Equal(raw, tt.wantRaw) {
+					t.Fatalf("raw=%v want=%v", raw, tt.wantRaw)
+				}
+				return
+			}
+			if outs[1].TextItem == nil || outs[1].TextItem.Text != tt.wantText {
+				t.Fatalf("body=%#v want text %q", outs[1], tt.wantText)
+			}
+		})
+	}
+}
+
+func TestReadFileChunkedReportsTotalLines(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "a.txt"), []byte("1\n2\n3"))
+	ft := mustNewFSTool(t, WithWorkBaseDir(root))
+	outs, err := ft.ReadFile(t.Context(), ReadFileArgs{Path: "a.txt", Length: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var info ReadFileChunkInfo
+	if err := json.Unmarshal([]byte(outs[0].TextItem.Text), &info); err != nil {
+		t.Fatalf("header is not JSON: %v", err)
+	}
+	if info.TotalLines == nil || *info.TotalLines != 3 {
+		t.Fatalf("totalLines=%v want 3", info.TotalLines)
+	}
+}
+
+func TestReadFilePDFPages(t *testing.T) {
+	root := t.TempDir()
+	mustWritePDF(t, filepath.Join(root, "doc.pdf"), []string{"alpha", "beta", "gamma"})
+	mustWriteFile(t, filepath.Join(root, "a.txt"), []byte("text"))
+	ft := mustNewFSTool(t, WithWorkBaseDir(root))
+
+	tests := []struct {
+		name      string
+		args      ReadFileArgs
+		wantErr   func(error) bool
+		wantPages []int
+		wantText  string
+	}{
+		{
+			name:      "page_range_with_markers",
+			args:      ReadFileArgs{Path: "doc.pdf", Pages: "2-"},
+			wantPages: []int{2, 3},
+			wantText:  "--- Page 2 of 3 ---\nbeta\n--- Page 3 of 3 ---\ngamma",
+		},
+		{
+			name:    "out_of_range_reports_total",
+			args:    ReadFileArgs{Path: "doc.pdf", Pages: "5"},
+			wantErr: wantErrContains("document has 3 pages"),
+		},
+		{name: "invalid_spec", args: ReadFileArgs{Path: "doc.pdf", Pages: "x"}, wantErr: wantErrContains("invalid page range")},
+		{name: "not_pdf", args: ReadFileArgs{Path: "a.txt", Pages: "1"}, wantErr: wantErrContains("only supported for PDF")},
+		{
+			name:    "with_chunk_args",
+			args:    ReadFileArgs{Path: "doc.pdf", Pages: "1", StartLine: 1},
+			wantErr: wantErrContains("cannot be combined"),
+		},
+		{
+			name:    "binary_encoding",
+			args:    ReadFileArgs{Path: "doc.pdf", Pages: "1", Encoding: "binary"},
+			wantErr: wantErrContains(`requires encoding "text"`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outs, err := ft.ReadFile(t.Context(), tt.args)
+			if tt.wantErr == nil {
+				tt.wantErr = wantErrNone
+			}
+			if !tt.wantErr(err) {
+				t.Fatalf("err=%v did not match expectation", err)
+			}
+			if err != nil {
+				return
+			}
+			if len(outs) != 2 || outs[0].TextItem == nil || outs[1].TextItem == nil {
+				t.Fatalf("expected header and text outputs, got %#v", outs)
+			}
+			var info ReadFilePDFPagesInfo
+			if err := json.Unmarshal([]byte(outs[0].TextItem.Text), &info); err != nil {
+				t.Fatalf("header is not JSON: %v", err)
+			}
+			if info.PageCount != 3 || info.Truncated || !reflect.DeepEqual(info.Pages, tt.wantPages) {
+				t.Fatalf("unexpected header: %+v", info)
+			}
+			if outs[1].TextItem.Text != tt.wantText {
+				t.Fatalf("Text=%q want=%q", outs[1].TextItem.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+// mustWritePDF writes a minimal PDF with one page per text.
+func mustWritePDF(t *testing.T, path string, texts []string) {
+	t.Helper()
+	objs := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // page tree, filled below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var kids []string
+	for _, text := range texts {
+		id := len(objs) + 1
+		content := "BT\n/F1 24 Tf\n72 120 Td\n(" + text + ") Tj\nET\n"
+		objs = append(objs,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Contents %d 0 R "+
+				"/Resources << /Font << /F1 3 0 R >> >> >>", id+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+		)
+		kids = append(kids, fmt.Sprintf("%d 0 R", id))
+	}
+	objs[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(texts))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, o := range objs {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	mustWriteFile(t, path, b.Bytes())
+}
+
+func TestReadFileDecodesLegacyEncodings(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "latin.txt"), []byte("\x93caf\xe9\x94\n"))
+	mustWriteFile(t, filepath.Join(root, "plain.txt"), []byte("plain\n"))
+	ft := mustNewFSTool(t, WithWorkBaseDir(root))
+
+	tests := []struct {
+		name     string
+		path     string
+		wantText string
+		wantEnc  string // "" means no encoding item
+	}{
+		{name: "windows1252", path: "latin.txt", wantText: "“café”\n", wantEnc: "windows-1252"},
+		{name: "utf8_unreported", path: "plain.txt", wantText: "plain\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outs, err := ft.ReadFile(t.Context(), ReadFileArgs{Path: tt.path})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if outs[0].TextItem == nil || outs[0].TextItem.Text != tt.wantText {
+				t.Fatalf("text=%#v want %q", outs[0], tt.wantText)
+			}
+			if tt.wantEnc == "" {
+				if len(outs) != 1 {
+					t.Fatalf("expected 1 output, got %d", len(outs))
+				}
+				return
+			}
+			if len(outs) != 2 {
+				t.Fatalf("expected text and encoding outputs, got %d", len(outs))
+			}
+			var info ReadFileEncodingInfo
+			if err := json.Unmarshal([]byte(outs[1].TextItem.Text), &info); err != nil {
+				t.Fatalf("encoding item is not JSON: %v", err)
+			}
+			if info.Encoding != tt.wantEnc {
+				t.Fatalf("encoding=%q want %q", info.Encoding, tt.wantEnc)
+			}
+		})
+	}
+}
+
+func utf16LEWithBOM(s string) []byte {
+	out := []byte{0xff, 0xfe}
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
@@ -0,0 +1,162 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const restoreFromTrashFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/restorefromtrash.RestoreFromTrash"
+
+var restoreFromTrashTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f1b-b3ab-7c31-bc9f-3a75e17a24da",
+	Slug:          "restorefromtrash",
+	Version:       "v1.0.0",
+	DisplayName:   "Restore from trash",
+	Description:   "Restore a trashed file or directory to its original path (from trash metadata) or to a new path. Never overwrites an existing destination.",
+	Tags:          []string{"fs", "trash"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"trashDir": {
+		"type": "string",
+		"default": "auto",
+		"description": "Trash directory holding the item (as used by deletefile). \"auto\" searches the system trash, then the local .trash directories deletefile falls back to."
+	},
+	"name": {
+		"type": "string",
+		"description": "Name of the item inside the trash directory (as returned by listtrash)."
+	},
+	"destinationPath": {
+		"type": "string",
+		"description": "Optional restore path. Defaults to the original path recorded at delete time."
+	},
+	"createParents": {
+		"type": "boolean",
+		"description": "If true, create missing parent directories of the destination. Max new directories created is 8.",
+		"default": false
+	}
+},
+"required": ["name"],
+"additionalProperties": false
+}`),
+
+	GoImpl: spec.GoToolImpl{FuncID: restoreFromTrashFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type RestoreFromTrashArgs struct {
+	TrashDir        string `json:"trashDir,omitempty"` // "auto" default
+	Name            string `json:"name"`
+	DestinationPath string `json:"destinationPath,omitempty"`
+	CreateParents   bool   `json:"createParents,omitempty"`
+}
+
+type RestoreFromTrashOut struct {
+	TrashedPath  string         `json:"trashedPath"`
+	RestoredPath string         `json:"restoredPath"`
+	IsDir        bool           `json:"isDir"`
+	Method       MovePathMethod `json:"method"`
+}
+
+// restoreFromTrash moves a trash entry back out of the trash.
+//
+// Behavior notes (entry point):
+//   - "auto" restores from the first trash dir holding Name (system trash first, then local .trash dirs).
+//   - Without DestinationPath, the original path is read from the entry's .trashinfo metadata.
+//   - The destination is policy-checked like any other write; it is never overwritten.
+//   - On success the metadata file is removed (best-effort).
+func restoreFromTrash(
+	ctx context.Context,
+	args RestoreFromTrashArgs,
+	p fspolicy.FSPolicy,
+) (*RestoreFromTrashOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	dirs, err := resolveTrashDirs(p, args.TrashDir)
+	if err != nil {
+		return nil, err
+	}
+	td, err := findTrashEntry(p.FS(), dirs, args.Name)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := trashEntryPath(p.FS(), td, args.Name)
+	if err != nil {
+		return nil, err
+	}
+	infoPath := trashInfoPathFor(p.FS(), td, args.Name)
+
+	dstIn := strings.TrimSpace(args.DestinationPath)
+	if dstIn == "" {
+		info, err := readTrashInfo(p.FS(), infoPath)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("no original path recorded for %q; pass destinationPath", args.Name)
+			}
+			return nil, err
+		}
+		dstIn = info.OriginalPath
+	}
+
+	src, dst, srcInfo, err := resolveSourceAndDestination(p, entry, dstIn, args.CreateParents)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.FS().Lstat(dst); err == nil {
+		return nil, fmt.Errorf("restore destination already exists: %s: %w", dst, os.ErrExist)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	method, _, err := ioutil.MovePathResolved(ctx, p, src, dst, false, ioutil.TreeLimits{
+		MaxEntries: toolutil.MaxTreeEntries,
+		MaxBytes:   toolutil.MaxTreeBytes,
+	})
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("restore destination already exists: %s: %w", dst, err)
+		}
+		return nil, err
+	}
+	_ = p.FS().Remove(infoPath)
+
+	return &RestoreFromTrashOut{
+		TrashedPath:  src,
+		RestoredPath: dst,
+		IsDir:        srcInfo.IsDir(),
+		Method:       MovePathMethod(method),
+	}, nil
+}
+
+// findTrashEntry returns the first of dirs holding an entry called name; with a single dir it is
+// returned as is, so a missing entry is reported by the move.
+func findTrashEntry(fsys vfs.FS, dirs []string, name string) (string, error) {
+	if len(dirs) == 1 {
+		return dirs[0], nil
+	}
+	for _, td := range dirs {
+		entry, err := trashEntryPath(fsys, td, name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := fsys.Lstat(entry); err == nil {
+			return td, nil
+		}
+	}
+	return "", fmt.Errorf("trash entry %q not found in %s: %w", name, strings.Join(dirs, ", "), os.ErrNotExist)
+}
@@ -0,0 +1,158 @@
+package fstool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestRestoreFromTrash(t *testing.T) {
+	trashIt := func(t *testing.T, ft *FSTool, args DeleteFileArgs) *DeleteFileOut {
+		t.Helper()
+		out, err := ft.DeleteFile(t.Context(), args)
+		if err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		ctx     func(t *testing.T) context.Context
+		setup   func(t *testing.T, ft *FSTool, root string) RestoreFromTrashArgs
+		wantErr func(error) bool
+		check   func(t *testing.T, root string, out *RestoreFromTrashOut)
+	}{
+		{
+			name: "context_canceled",
+			ctx:  canceledContext,
+			setup: func(t *testing.T, ft *FSTool, root string) RestoreFromTrashArgs {
+				t.Helper()
+				return RestoreFromTrashArgs{TrashDir: root, Name: "x"}
+			},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name: "restores_file_to_original_path_and_removes_metadata",
+			setup: func(t *testing.T, ft *FSTool, root string) RestoreFromTrashArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(root, "a.txt"), []byte("hello"))
+				out := trashIt(t, ft, DeleteFileArgs{Path: "a.txt", TrashDir: "trash"})
+				if out.InfoPath == "" {
+					t.Fatalf("expected trash metadata to be written")
+				}
+				return RestoreFromTrashArgs{TrashDir: "trash", Name: filepath.Base(out.TrashedPath)}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, root string, out *RestoreFromTrashOut) {
+				t.Helper()
+				if out.RestoredPath != filepath.Join(root, "a.txt") {
+					t.Fatalf("restored to %q", out.RestoredPath)
+				}
+				if got := string(mustReadFile(t, out.RestoredPath)); got != "hello" {
+					t.Fatalf("content=%q", got)
+				}
+				if _, err := os.Lstat(trashInfoPathFor(vfs.OS(), filepath.Join(root, "trash"), "a.txt")); !os.IsNotExist(err) {
+					t.Fatalf("expected metadata removed, stat err=%v", err)
+				}
+			},
+		},
+		{
+			name: "restores_directory_with_create_parents_to_new_path",
+			setup: func(t *testing.T, ft *FSTool, root string) RestoreFromTrashArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(root, "d", "sub"))
+				mustWriteFile(t, filepath.Join(root, "d", "sub", "f.txt"), []byte("f"))
+				out := trashIt(t, ft, DeleteFileArgs{Path: "d", TrashDir: "trash", Recursive: true})
+				return RestoreFromTrashArgs{
+					TrashDir:        "trash",
+					Name:            filepath.Base(out.TrashedPath),
+					DestinationPath: "x/y/d2",
+					CreateParents:   true,
+				}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, root string, out *RestoreFromTrashOut) {
+				t.Helper()
+				if !out.IsDir {
+					t.Fatalf("expected dir restore: %+v", out)
+				}
+				if got := string(mustReadFile(t, filepath.Join(root, "x", "y", "d2", "sub", "f.txt"))); got != "f" {
+					t.Fatalf("content=%q", got)
+				}
+			},
+		},
+		{
+			name: "existing_destination_is_not_overwritten",
+			setup: func(t *testing.T, ft *FSTool, root string) RestoreFromTrashArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(root, "a.txt"), []byte("old"))
+				out := trashIt(t, ft, DeleteFileArgs{Path: "a.txt", TrashDir: "trash"})
+				mustWriteFile(t, filepath.Join(root, "a.txt"), []byte("new"))
+				return RestoreFromTrashArgs{TrashDir: "trash", Name: filepath.Base(out.TrashedPath)}
+			},
+			wantErr: func(err error) bool { return wantErrContains("already exists")(err) && wantErrIs(os.ErrExist)(err) },
+			check: func(t *testing.T, root string, out *RestoreFromTrashOut) {
+				t.Helper()
+				if got := string(mustReadFile(t, filepath.Join(root, "a.txt"))); got != "new" {
+					t.Fatalf("destination clobbered: %q", got)
+				}
+			},
+		},
+		{
+			name: "no_metadata_requires_destination",
+			setup: func(t *testing.T, ft *FSTool, root string) RestoreFromTrashArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(root, "trash"))
+				mustWriteFile(t, filepath.Join(root, "trash", "orphan"), []byte("o"))
+				return RestoreFromTrashArgs{TrashDir: "trash", Name: "orphan"}
+			},
+			wantErr: wantErrContains("destinationPath"),
+		},
+		{
+			name: "name_with_separator_rejected",
+			setup: func(t *testing.T, ft *FSTool, root string) RestoreFromTrashArgs {
+				t.Helper()
+				mustMkdirAll(t, filepath.Join(root, "trash"))
+				return RestoreFromTrashArgs{TrashDir: "trash", Name: "../a.txt", DestinationPath: "b.txt"}
+			},
+			wantErr: wantErrContains("invalid trash entry name"),
+		},
+		{
+			name: "original_path_outside_allowed_roots_refused",
+			setup: func(t *testing.T, ft *FSTool, root string) RestoreFromTrashArgs {
+				t.Helper()
+				trash := filepath.Join(root, "trash")
+				mustMkdirAll(t, trashInfoDirFor(vfs.OS(), trash))
+				mustWriteFile(t, filepath.Join(trash, "a.txt"), []byte("a"))
+				outside := filepath.Join(t.TempDir(), "a.txt")
+				mustWriteFile(t, trashInfoPathFor(vfs.OS(), trash, "a.txt"), encodeTrashInfo(outside, time.Now()))
+				return RestoreFromTrashArgs{TrashDir: "trash", Name: "a.txt"}
+			},
+			wantErr: wantErrContains("outside allowed roots"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			ft := mustNewFSTool(t, WithWorkBaseDir(root), WithAllowedRoots([]string{root}))
+			args := tt.setup(t, ft, root)
+			ctx := t.Context()
+			if tt.ctx != nil {
+				ctx = tt.ctx(t)
+			}
+			out, err := ft.RestoreFromTrash(ctx, args)
+			if !tt.wantErr(err) {
+				t.Fatalf("err=%v did not match expectation", err)
+			}
+			if tt.check != nil {
+				tt.check(t, root, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/spec"
+)
+
+const setAttributesFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/setattributes.SetAttributes"
+
+// DefaultAllowedPermMask is the default set of mode bits setattributes may turn on:
+// rwx for user, group and other, but never setuid, setgid or sticky.
+const DefaultAllowedPermMask fs.FileMode = 0o777
+
+var setAttributesTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f37-4b06-7c0c-a76b-a1334a0557ae",
+	Slug:          "setattributes",
+	Version:       "v1.0.0",
+	DisplayName:   "Set file attributes",
+	Description:   "Change permission bits (octal or chmod-style symbolic, e.g. \"u+x\"), the read-only flag, and modification/access times of an existing file or directory.",
+	Tags:          []string{"fs", "write"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"path": {
+		"type": "string",
+		"description": "File or directory to modify. Must exist."
+	},
+	"mode": {
+		"type": "string",
+		"description": "Permission bits: octal (\"755\", \"0644\") or symbolic clauses separated by commas (\"u+x\", \"go-w\", \"a=r\"). On Windows only the owner write bit has an effect (it maps to the read-only attribute)."
+	},
+	"readOnly": {
+		"type": "boolean",
+		"description": "true removes all write bits (sets the Windows read-only attribute); false adds the owner write bit (clears it)."
+	},
+	"modTime": {
+		"type": "string",
+		"description": "New modification time as RFC3339 (e.g. 2024-05-01T12:00:00Z), or \"now\"."
+	},
+	"accessTime": {
+		"type": "string",
+		"description": "New access time as RFC3339, or \"now\"."
+	}
+},
+"required": ["path"],
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: setAttributesFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type SetAttributesArgs struct {
+	Path       string `json:"path"`
+	Mode       string `json:"mode,omitempty"`
+	ReadOnly   *bool  `json:"readOnly,omitempty"`
+	ModTime    string `json:"modTime,omitempty"`
+	AccessTime string `json:"accessTime,omitempty"`
+}
+
+type SetAttributesOut struct {
+	Path    string    `json:"path"`
+	Mode    string    `json:"mode"` // resulting mode, e.g. "-rwxr-xr-x"
+	Perm    string    `json:"perm"` // resulting permission bits in octal, e.g. "0755"
+	ModTime time.Time `json:"modTime"`
+	Changed []string  `json:"changed,omitempty"` // "mode", "modTime", "accessTime"
+}
+
+// setAttributes changes the permissions and/or timestamps of an existing path.
+//
+// Behavior notes (entry point):
+//   - Mode bits that would be newly turned on must be within allowedMask (bits already set are
+//     kept). The mask is host configuration (WithAllowedPermMask), never a tool argument.
+//   - mode is applied first, then readOnly adjusts the write bits of the result.
+//   - Symlinks are refused when the policy blocks them; otherwise the link target is modified and
+//     must itself be within the policy's allowed roots.
+func setAttributes(
+	ctx context.Context,
+	args SetAttributesArgs,
+	p fspolicy.FSPolicy,
+	allowedMask fs.FileMode,
+) (*SetAttributesOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Mode) == "" && args.ReadOnly == nil &&
+		strings.TrimSpace(args.ModTime) == "" && strings.TrimSpace(args.AccessTime) == "" {
+		return nil, errors.New("nothing to change: provide mode, readOnly, modTime and/or accessTime")
+	}
+	now := time.Now()
+	mtime, err := parseAttrTime("modTime", args.ModTime, now)
+	if err != nil {
+		return nil, err
+	}
+	atime, err := parseAttrTime("accessTime", args.AccessTime, now)
+	if err != nil {
+		return nil, err
+	}
+
+	abs, err := resolveAttributesTarget(p, args.Path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := p.FS().Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !st.Mode().IsRegular() && !st.IsDir() {
+		return nil, fmt.Errorf("refusing to modify non-regular file: %s", abs)
+	}
+
+	oldMode := st.Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	newMode := oldMode
+	if strings.TrimSpace(args.Mode) != "" {
+		if newMode, err = applyModeSpec(oldMode, args.Mode); err != nil {
+			return nil, err
+		}
+	}
+	if args.ReadOnly != nil {
+		if *args.ReadOnly {
+			newMode &^= 0o222
+		} else {
+			newMode |= 0o200
+		}
+	}
+	if added := newMode &^ oldMode &^ allowedMask; added != 0 {
+		return nil, fmt.Errorf("mode %s would set bits outside the allowed mask %s", fmtUnixMode(newMode), fmtUnixMode(allowedMask))
+	}
+
+	var changed []string
+	if newMode != oldMode {
+		if err := p.FS().Chmod(abs, newMode); err != nil {
+			return nil, err
+		}
+		changed = append(changed, "mode")
+	}
+	if !mtime.IsZero() || !atime.IsZero() {
+		// Zero times are left unchanged by os.Chtimes.
+		if err := p.FS().Chtimes(abs, atime, mtime); err != nil {
+			return nil, err
+		}
+		if !mtime.IsZero() {
+			changed = append(changed, "modTime")
+		}
+		if !atime.IsZero() {
+			changed = append(changed, "accessTime")
+		}
+	}
+
+	st, err = p.FS().Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	return &SetAttributesOut{
+		Path:    abs,
+		Mode:    st.Mode().String(),
+		Perm:    fmtUnixMode(st.Mode()),
+		ModTime: st.ModTime().UTC(),
+		Changed: changed,
+	}, nil
+}
+
+// resolveAttributesTarget resolves path to the existing file or directory whose attributes change.
+// os.Chmod/os.Chtimes follow symlinks, so an allowed symlink's target is policy-checked too.
+func resolveAttributesTarget(p fspolicy.FSPolicy, path string) (string, error) {
+	abs, err := p.ResolvePath(path, "")
+	if err != nil {
+		return "", err
+	}
+	if p.BlockSymlinks() {
+		if err := p.VerifyDirResolved(filepath.Dir(abs)); err != nil {
+			return "", err
+		}
+	}
+	st, err := p.FS().Lstat(abs)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("path does not exist: %s", abs)
+		}
+		return "", err
+	}
+	if (st.Mode() & os.ModeSymlink) == 0 {
+		return abs, nil
+	}
+	if p.BlockSymlinks() {
+		return "", fmt.Errorf("%w: refusing to operate on symlink: %s", fspolicy.ErrSymlinkDisallowed, abs)
+	}
+	target, err := p.FS().EvalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	return p.ResolvePath(target, "")
+}
+
+func parseAttrTime(name, s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return time.Time{}, nil
+	case strings.EqualFold(s, "now"):
+		return now, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: want RFC3339 or \"now\"", name, s)
+	}
+	return t, nil
+}
+
+// applyModeSpec applies an octal ("755", "4755") or symbolic ("u+x,go-w", "a=r") mode to cur.
+func applyModeSpec(cur fs.FileMode, modeSpec string) (fs.FileMode, error) {
+	modeSpec = strings.TrimSpace(modeSpec)
+	if modeSpec[0] >= '0' && modeSpec[0] <= '7' {
+		n, err := strconv.ParseUint(modeSpec, 8, 32)
+		if err != nil || n > 0o7777 {
+			return 0, fmt.Errorf("invalid mode %q: octal modes go up to 7777", modeSpec)
+		}
+		m := fs.FileMode(n & 0o777)
+		if n&0o4000 != 0 {
+			m |= fs.ModeSetuid
+		}
+		if n&0o2000 != 0 {
+			m |= fs.ModeSetgid
+		}
+		if n&0o1000 != 0 {
+			m |= fs.ModeSticky
+		}
+		return m, nil
+	}
+
+	m := cur
+	for clause := range strings.SplitSeq(modeSpec, ",") {
+		i := strings.IndexAny(clause, "+-=")
+		if i < 0 || (i == len(clause)-1 && clause[i] != '=') {
+			return 0, fmt.Errorf("invalid mode clause %q: want e.g. u+x, go-w, a=r", clause)
+		}
+		who, op, perms := clause[:i], clause[i], clause[i+1:]
+		if who == "" {
+			who = "a"
+		}
+		var whoMask fs.FileMode
+		for _, c := range who {
+			switch c {
+			case 'u':
+				whoMask |= 0o700
+			case 'g':
+				whoMask |= 0o070
+			case 'o':
+				whoMask |= 0o007
+			case 'a':
+				whoMask |= 0o777
+			default:
+				return 0, fmt.Errorf("invalid mode clause %q: unknown class %q", clause, c)
+			}
+		}
+		var bits fs.FileMode
+		for _, c := range perms {
+			switch c {
+			case 'r':
+				bits |= 0o444
+			case 'w':
+				bits |= 0o222
+			case 'x':
+				bits |= 0o111
+			default:
+				return 0, fmt.Errorf("invalid mode clause %q: unknown permission %q", clause, c)
+			}
+		}
+		bits &= whoMask
+		switch op {
+		case '+':
+			m |= bits
+		case '-':
+			m &^= bits
+		case '=':
+			m = m&^whoMask | bits
+		}
+	}
+	return m, nil
+}
+
+// fmtUnixMode formats permission and special bits as 4-digit octal, e.g. "0755" or "4755".
+func fmtUnixMode(m fs.FileMode) string {
+	n := uint32(m.Perm())
+	if m&fs.ModeSetuid != 0 {
+		n |= 0o4000
+	}
+	if m&fs.ModeSetgid != 0 {
+		n |= 0o2000
+	}
+	if m&fs.ModeSticky != 0 {
+		n |= 0o1000
+	}
+	return fmt.Sprintf("%04o", n)
+}
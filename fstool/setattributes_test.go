@@ -0,0 +1,213 @@
+package fstool
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+)
+
+func TestSetAttributes(t *testing.T) {
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	unixOnly := func(t *testing.T) {
+		t.Helper()
+		if runtime.GOOS == toolutil.GOOSWindows {
+			t.Skip("unix permission bits")
+		}
+	}
+
+	tests := []struct {
+		name        string
+		skip        func(t *testing.T)
+		mask        fs.FileMode
+		initialMode fs.FileMode
+		args        SetAttributesArgs
+		ctx         func(t *testing.T) context.Context
+		wantErr     func(error) bool
+		wantPerm    string
+		wantChanged []string
+		wantModTime *time.Time
+	}{
+		{
+			name:    "context_canceled",
+			ctx:     canceledContext,
+			args:    SetAttributesArgs{Path: "f.sh", Mode: "755"},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name:    "nothing_to_change",
+			args:    SetAttributesArgs{Path: "f.sh"},
+			wantErr: wantErrContains("nothing to change"),
+		},
+		{
+			name:        "octal_mode",
+			skip:        unixOnly,
+			args:        SetAttributesArgs{Path: "f.sh", Mode: "0750"},
+			wantErr:     wantErrNone,
+			wantPerm:    "0750",
+			wantChanged: []string{"mode"},
+		},
+		{
+			name:        "symbolic_add_execute",
+			skip:        unixOnly,
+			args:        SetAttributesArgs{Path: "f.sh", Mode: "u+x,g+rx"},
+			wantErr:     wantErrNone,
+			wantPerm:    "0750",
+			wantChanged: []string{"mode"},
+		},
+		{
+			name:        "symbolic_equals_and_remove",
+			skip:        unixOnly,
+			initialMode: 0o777,
+			args:        SetAttributesArgs{Path: "f.sh", Mode: "o=,g-w"},
+			wantErr:     wantErrNone,
+			wantPerm:    "0750",
+			wantChanged: []string{"mode"},
+		},
+		{
+			name:    "setuid_refused_by_default_mask",
+			skip:    unixOnly,
+			args:    SetAttributesArgs{Path: "f.sh", Mode: "4755"},
+			wantErr: wantErrContains("outside the allowed mask"),
+		},
+		{
+			name:    "custom_mask_refuses_other_write",
+			skip:    unixOnly,
+			mask:    0o755,
+			args:    SetAttributesArgs{Path: "f.sh", Mode: "a+w"},
+			wantErr: wantErrContains("outside the allowed mask 0755"),
+		},
+		{
+			name:        "read_only_true",
+			args:        SetAttributesArgs{Path: "f.sh", ReadOnly: ptrBool(true)},
+			wantErr:     wantErrNone,
+			wantPerm:    "0400",
+			wantChanged: []string{"mode"},
+		},
+		{
+			name:        "read_only_false_restores_owner_write",
+			initialMode: 0o444,
+			args:        SetAttributesArgs{Path: "f.sh", ReadOnly: ptrBool(false)},
+			wantErr:     wantErrNone,
+			wantPerm:    "0644",
+			wantChanged: []string{"mode"},
+		},
+		{
+			name:        "times",
+			args:        SetAttributesArgs{Path: "f.sh", ModTime: fixed.Format(time.RFC3339), AccessTime: "now"},
+			wantErr:     wantErrNone,
+			wantChanged: []string{"modTime", "accessTime"},
+			wantModTime: &fixed,
+		},
+		{
+			name:    "invalid_time",
+			args:    SetAttributesArgs{Path: "f.sh", ModTime: "yesterday"},
+			wantErr: wantErrContains("invalid modTime"),
+		},
+		{
+			name:    "invalid_symbolic_mode",
+			args:    SetAttributesArgs{Path: "f.sh", Mode: "u+q"},
+			wantErr: wantErrContains("unknown permission"),
+		},
+		{
+			name:    "invalid_octal_mode",
+			args:    SetAttributesArgs{Path: "f.sh", Mode: "17777"},
+			wantErr: wantErrContains("octal modes go up to 7777"),
+		},
+		{
+			name:    "missing_path",
+			args:    SetAttributesArgs{Path: "nope", Mode: "644"},
+			wantErr: wantErrContains("path does not exist"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip != nil {
+				tc.skip(t)
+			}
+			tmp := t.TempDir()
+			path := filepath.Join(tmp, "f.sh")
+			mustWriteFile(t, path, []byte("#!/bin/sh\n"))
+			initial := tc.initialMode
+			if initial == 0 {
+				initial = 0o600
+			}
+			if err := os.Chmod(path, initial); err != nil {
+				t.Fatalf("chmod: %v", err)
+			}
+			t.Cleanup(func() { _ = os.Chmod(path, 0o600) })
+
+			opts := []FSToolOption{WithWorkBaseDir(tmp), WithBlockSymlinks(true)}
+			if tc.mask != 0 {
+				opts = append(opts, WithAllowedPermMask(tc.mask))
+			}
+			ft := mustNewFSTool(t, opts...)
+			ctx := t.Context()
+			if tc.ctx != nil {
+				ctx = tc.ctx(t)
+			}
+
+			out, err := ft.SetAttributes(ctx, tc.args)
+			if !tc.wantErr(err) {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if !slices.Equal(out.Changed, tc.wantChanged) {
+				t.Fatalf("Changed=%v want %v", out.Changed, tc.wantChanged)
+			}
+			if tc.wantPerm != "" && runtime.GOOS != toolutil.GOOSWindows && out.Perm != tc.wantPerm {
+				t.Fatalf("Perm=%q want %q", out.Perm, tc.wantPerm)
+			}
+			if tc.wantModTime != nil && !out.ModTime.Equal(*tc.wantModTime) {
+				t.Fatalf("ModTime=%v want %v", out.ModTime, *tc.wantModTime)
+			}
+		})
+	}
+}
+
+func TestSetAttributesSymlinks(t *testing.T) {
+	if runtime.GOOS == toolutil.GOOSWindows {
+		t.Skip("symlink tests are unreliable on Windows CI")
+	}
+	root := t.TempDir()
+	outside := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "target.sh"), []byte("x"))
+	mustWriteFile(t, filepath.Join(outside, "secret.sh"), []byte("x"))
+	mustSymlinkOrSkip(t, filepath.Join(root, "target.sh"), filepath.Join(root, "inside"))
+	mustSymlinkOrSkip(t, filepath.Join(outside, "secret.sh"), filepath.Join(root, "escape"))
+
+	tests := []struct {
+		name    string
+		block   bool
+		path    string
+		wantErr func(error) bool
+	}{
+		{name: "blocked", block: true, path: "inside", wantErr: wantErrContains("symlink")},
+		{name: "allowed_target_inside_root", block: false, path: "inside", wantErr: wantErrNone},
+		{name: "allowed_target_outside_root", block: false, path: "escape", wantErr: wantErrContains("outside allowed roots")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ft := mustNewFSTool(t, WithWorkBaseDir(root), WithAllowedRoots([]string{root}), WithBlockSymlinks(tc.block))
+			_, err := ft.SetAttributes(t.Context(), SetAttributesArgs{Path: tc.path, Mode: "u+x"})
+			if !tc.wantErr(err) {
+				t.Fatalf("unexpected err: %v", err)
+			}
+		})
+	}
+}
+
+func TestWithAllowedPermMaskRejectsTypeBits(t *testing.T) {
+	if _, err := NewFSTool(WithAllowedPermMask(fs.ModeDir | 0o755)); err == nil {
+		t.Fatal("expected error")
+	}
+}
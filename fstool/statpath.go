@@ -2,22 +2,28 @@ package fstool
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
 	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
 	"github.com/flexigpt/llmtools-go/spec"
 )
 
 const statPathFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/statpath.StatPath"
 
+const statPathMaxBatchPaths = 256
+
 var statPathTool = spec.Tool{
 	SchemaVersion: spec.SchemaVersion,
 	ID:            "018fe0f4-b8cd-7e55-82d5-9df0bd70e4bd",
 	Slug:          "statpath",
 	Version:       "v1.0.0",
 	DisplayName:   "Inspect path",
-	Description:   "Return size, timestamps, and basic metadata for a file-system path without modifying it.",
+	Description:   "Return size, timestamps, permissions, ownership, link info, MIME type and other metadata for one path, or for many paths at once with paths. For regular files up to 64MB, also returns a content version token usable as expectedVersion. Never modifies anything.",
 	Tags:          []string{"fs", "stat"},
 
 	ArgSchema: spec.JSONSchema(`{
@@ -27,9 +33,19 @@ var statPathTool = spec.Tool{
 	"path": {
 		"type": "string",
 		"description": "Path to inspect."
+	},
+	"paths": {
+		"type": "array",
+		"items": { "type": "string" },
+		"minItems": 1,
+		"maxItems": 256,
+		"description": "Batch form: paths to inspect. Results are returned as items in the same order; a failing path gets an error instead of failing the call."
 	}
 },
-"required": ["path"],
+"oneOf": [
+	{ "required": ["path"], "not": { "required": ["paths"] } },
+	{ "required": ["paths"], "not": { "required": ["path"] } }
+],
 "additionalProperties": false
 }`),
 	GoImpl: spec.GoToolImpl{FuncID: statPathFuncID},
@@ -39,34 +55,129 @@ var statPathTool = spec.Tool{
 }
 
 type StatPathArgs struct {
-	Path string `json:"path"`
+	Path  string   `json:"path,omitempty"`
+	Paths []string `json:"paths,omitempty"`
 }
 
-type StatPathOut struct {
+// StatPathInfo is the metadata of one path.
+type StatPathInfo struct {
 	Path      string     `json:"path"`
 	Name      string     `json:"name"`
 	Exists    bool       `json:"exists"`
 	IsDir     bool       `json:"isDir"`
 	SizeBytes int64      `json:"sizeBytes,omitempty"`
 	ModTime   *time.Time `json:"modTime,omitempty"`
+	Version   string     `json:"version,omitempty"` // regular files up to statPathVersionMaxBytes only
+
+	Mode       string `json:"mode,omitempty"` // e.g. "-rw-r--r--"
+	Perm       string `json:"perm,omitempty"` // e.g. "0644"
+	IsSymlink  bool   `json:"isSymlink,omitempty"`
+	LinkTarget string `json:"linkTarget,omitempty"`
+	MIMEType   string `json:"mimeType,omitempty"` // regular files only
+
+	// Platform-dependent; omitted where the OS does not report them.
+	UID        *int       `json:"uid,omitempty"`
+	GID        *int       `json:"gid,omitempty"`
+	OwnerSID   string     `json:"ownerSid,omitempty"` // Windows
+	HardLinks  uint64     `json:"hardLinks,omitempty"`
+	CreateTime *time.Time `json:"createTime,omitempty"`
+	AccessTime *time.Time `json:"accessTime,omitempty"`
+
+	Error string `json:"error,omitempty"` // batch items only: why this path could not be inspected
 }
 
-// statPath returns basic metadata for the supplied path without mutating the file system.
+// StatPathOut is the metadata of the single path, or, for the batch form, only Items (StatPathInfo
+// is nil then, so the JSON holds just the items).
+type StatPathOut struct {
+	*StatPathInfo
+
+	// Items holds the batch results, in the order of the requested paths.
+	Items []StatPathInfo `json:"items,omitempty"`
+}
+
+// statPathVersionMaxBytes bounds how much statpath hashes to report file versions (per call).
+const statPathVersionMaxBytes = 4 * toolutil.MaxFileReadBytes
+
+// statPath returns metadata for the supplied path(s) without mutating the file system.
+//
+// Behavior notes (entry point):
+//   - Symlinks are reported with isSymlink/linkTarget and otherwise described by their target
+//     (refused when the policy blocks symlinks).
+//   - Version hashing shares one statPathVersionMaxBytes budget across a batch; files past it get no version.
 func statPath(ctx context.Context, args StatPathArgs, p fspolicy.FSPolicy) (*StatPathOut, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	budget := int64(statPathVersionMaxBytes)
+	if len(args.Paths) == 0 {
+		info, err := statOnePath(ctx, args.Path, p, &budget)
+		if err != nil {
+			return nil, err
+		}
+		return &StatPathOut{StatPathInfo: info}, nil
+	}
 
-	pathInfo, err := ioutil.StatPath(p, args.Path)
+	if strings.TrimSpace(args.Path) != "" {
+		return nil, errors.New("provide either path or paths, not both")
+	}
+	if len(args.Paths) > statPathMaxBatchPaths {
+		return nil, fmt.Errorf("too many paths (%d); max %d", len(args.Paths), statPathMaxBatchPaths)
+	}
+	out := &StatPathOut{Items: make([]StatPathInfo, 0, len(args.Paths))}
+	for _, path := range args.Paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		info, err := statOnePath(ctx, path, p, &budget)
+		if err != nil {
+			out.Items = append(out.Items, StatPathInfo{Path: path, Error: err.Error()})
+			continue
+		}
+		out.Items = append(out.Items, *info)
+	}
+	return out, nil
+}
+
+func statOnePath(ctx context.Context, path string, p fspolicy.FSPolicy, versionBudget *int64) (*StatPathInfo, error) {
+	pathInfo, err := ioutil.StatPath(p, path)
 	if err != nil {
 		return nil, err
 	}
-	return &StatPathOut{
-		Path:      pathInfo.Path,
-		Name:      pathInfo.Name,
-		Exists:    pathInfo.Exists,
-		IsDir:     pathInfo.IsDir,
-		SizeBytes: pathInfo.Size,
-		ModTime:   pathInfo.ModTime,
-	}, nil
+	out := &StatPathInfo{
+		Path:       pathInfo.Path,
+		Name:       pathInfo.Name,
+		Exists:     pathInfo.Exists,
+		IsDir:      pathInfo.IsDir,
+		SizeBytes:  pathInfo.Size,
+		ModTime:    pathInfo.ModTime,
+		Mode:       pathInfo.Mode,
+		Perm:       pathInfo.Perm,
+		IsSymlink:  pathInfo.IsSymlink,
+		LinkTarget: pathInfo.LinkTarget,
+		UID:        pathInfo.UID,
+		GID:        pathInfo.GID,
+		OwnerSID:   pathInfo.OwnerSID,
+		HardLinks:  pathInfo.HardLinks,
+		CreateTime: pathInfo.CreateTime,
+		AccessTime: pathInfo.AccessTime,
+	}
+	if !pathInfo.Exists || pathInfo.IsDir {
+		return out, nil
+	}
+	// Only sniff/hash regular files: reading a FIFO or device could block or never end.
+	if st, err := p.FS().Stat(pathInfo.Path); err != nil || !st.Mode().IsRegular() {
+		return out, nil //nolint:nilerr // Metadata above is still valid.
+	}
+	if mt, _, _, err := ioutil.MIMEForLocalFile(p.FS(), pathInfo.Path); err == nil {
+		out.MIMEType = string(mt)
+	}
+	if pathInfo.Size <= *versionBudget {
+		version, err := ioutil.FileVersion(ctx, p.FS(), pathInfo.Path)
+		if err != nil {
+			return nil, err
+		}
+		out.Version = version
+		*versionBudget -= pathInfo.Size
+	}
+	return out, nil
 }
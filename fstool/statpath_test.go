@@ -2,8 +2,10 @@ package fstool
 
 import (
 	"context"
+	"encoding/json"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
@@ -37,6 +39,9 @@ func TestStatPath(t *testing.T) {
 		wantName    string
 		wantSize    *int64
 		wantModTime bool
+		wantSymlink bool
+		wantMIME    string
+		wantMode    string
 	}{
 		{
 			name: "context_canceled",
@@ -71,6 +76,8 @@ func TestStatPath(t *testing.T) {
 			wantName:    "sample.txt",
 			wantSize:    ptrInt64(2),
 			wantModTime: true,
+			wantMIME:    "text/plain",
+			wantMode:    "-rw-------",
 		},
 		{
 			name: "existing_dir",
@@ -131,9 +138,10 @@ func TestStatPath(t *testing.T) {
 				t.Helper()
 				return StatPathArgs{Path: "link.txt"}
 			},
-			wantErr:    wantErrNone,
-			wantExists: ptrBool(true),
-			wantIsDir:  ptrBool(false),
+			wantErr:     wantErrNone,
+			wantExists:  ptrBool(true),
+			wantIsDir:   ptrBool(false),
+			wantSymlink: true,
 		},
 		{
 			name: "symlink_path_refused_when_blockSymlinks_true",
@@ -224,6 +232,63 @@ func TestStatPath(t *testing.T) {
 			if tt.wantModTime && out.ModTime == nil {
 				t.Fatalf("expected ModTime set")
 			}
+			if out.IsSymlink != tt.wantSymlink || (tt.wantSymlink && out.LinkTarget == "") {
+				t.Fatalf("IsSymlink=%v LinkTarget=%q want symlink=%v", out.IsSymlink, out.LinkTarget, tt.wantSymlink)
+			}
+			if tt.wantMIME != "" && !strings.HasPrefix(out.MIMEType, tt.wantMIME) {
+				t.Fatalf("MIMEType=%q want prefix %q", out.MIMEType, tt.wantMIME)
+			}
+			if tt.wantMode != "" && runtime.GOOS != toolutil.GOOSWindows && out.Mode != tt.wantMode {
+				t.Fatalf("Mode=%q want %q", out.Mode, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestStatPathBatch(t *testing.T) {
+	tmp := t.TempDir()
+	mustWriteFile(t, filepath.Join(tmp, "a.txt"), []byte("hello"))
+	mustMkdirAll(t, filepath.Join(tmp, "dir"))
+	outside := t.TempDir()
+	ft := mustNewFSTool(t, WithWorkBaseDir(tmp), WithAllowedRoots([]string{tmp}))
+
+	out, err := ft.StatPath(t.Context(), StatPathArgs{Paths: []string{"a.txt", "dir", "missing", outside}})
+	if err != nil {
+		t.Fatalf("StatPath: %v", err)
+	}
+	if out.StatPathInfo != nil || len(out.Items) != 4 {
+		t.Fatalf("unexpected out: %+v", out)
+	}
+	if b, err := json.Marshal(out); err != nil || !strings.HasPrefix(string(b), `{"items":[`) {
+		t.Fatalf("batch JSON = %s, %v; want only items", b, err)
+	}
+	if it := out.Items[0]; !it.Exists || it.SizeBytes != 5 || it.Version == "" || it.Error != "" {
+		t.Fatalf("item 0: %+v", it)
+	}
+	if it := out.Items[1]; !it.Exists || !it.IsDir || it.Version != "" {
+		t.Fatalf("item 1: %+v", it)
+	}
+	if it := out.Items[2]; it.Exists || it.Error != "" {
+		t.Fatalf("item 2: %+v", it)
+	}
+	if it := out.Items[3]; it.Path != outside || !strings.Contains(it.Error, "outside allowed roots") {
+		t.Fatalf("item 3: %+v", it)
+	}
+
+	tests := []struct {
+		name    string
+		args    StatPathArgs
+		wantErr func(error) bool
+	}{
+		{name: "path_and_paths", args: StatPathArgs{Path: "a.txt", Paths: []string{"a.txt"}}, wantErr: wantErrContains("not both")},
+		{name: "too_many_paths", args: StatPathArgs{Paths: make([]string, statPathMaxBatchPaths+1)}, wantErr: wantErrContains("too many paths")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ft.StatPath(t.Context(), tc.args)
+			if !tc.wantErr(err) {
+				t.Fatalf("unexpected err: %v", err)
+			}
 		})
 	}
 }
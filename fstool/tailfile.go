@@ -0,0 +1,258 @@
+package fstool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const tailFileFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/fstool/tailfile.TailFile"
+
+const (
+	tailFileDefaultLines = 100
+	tailFileMaxLines     = 10000
+	// tailCursorFingerprintBytes is how much of the file head is fingerprinted to detect rotation.
+	tailCursorFingerprintBytes = 4096
+	tailCursorVersion          = "v2"
+)
+
+var tailFileTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f1f-ea90-7ba6-9d68-ae5fab7126b8",
+	Slug:          "tailfile",
+	Version:       "v1.0.0",
+	DisplayName:   "Tail file",
+	Description:   "Return the last N lines (or bytes) of a text file, e.g. a log. Pass the returned cursor as sinceCursor to get only content appended since the previous call; truncation and rotation are detected and reported.",
+	Tags:          []string{"fs", "read"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"path": {
+		"type": "string",
+		"description": "Path of the file to tail."
+	},
+	"lines": {
+		"type": "integer",
+		"minimum": 1,
+		"maximum": 10000,
+		"description": "Number of trailing lines to return. Default 100. Ignored when bytes is set."
+	},
+	"bytes": {
+		"type": "integer",
+		"minimum": 1,
+		"description": "Number of trailing bytes to return instead of lines (max 16MB)."
+	},
+	"sinceCursor": {
+		"type": "string",
+		"description": "Cursor from a previous tailfile call. Returns only content appended since then (up to 16MB per call). If the file was truncated or rotated, reset is true and a normal tail is returned."
+	}
+},
+"required": ["path"],
+"additionalProperties": false
+}`),
+
+	GoImpl: spec.GoToolImpl{FuncID: tailFileFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type TailFileArgs struct {
+	Path        string `json:"path"`
+	Lines       int    `json:"lines,omitempty"`
+	Bytes       int64  `json:"bytes,omitempty"`
+	SinceCursor string `json:"sinceCursor,omitempty"`
+}
+
+type TailFileResetReason string
+
+const (
+	TailFileResetTruncated TailFileResetReason = "truncated"
+	TailFileResetRotated   TailFileResetReason = "rotated"
+)
+
+type TailFileOut struct {
+	Path        string              `json:"path"`
+	Content     string              `json:"content"`
+	StartOffset int64               `json:"startOffset"`
+	EndOffset   int64               `json:"endOffset"`
+	TotalSize   int64               `json:"totalSize"`
+	Cursor      string              `json:"cursor"`
+	HasMore     bool                `json:"hasMore,omitempty"` // more appended content is available (sinceCursor only)
+	Reset       bool                `json:"reset,omitempty"`
+	ResetReason TailFileResetReason `json:"resetReason,omitempty"`
+}
+
+// tailFile returns the end of a text file, or what was appended since a cursor.
+//
+// Behavior notes (entry point):
+//   - Lines are found by reading backwards from EOF in blocks; output is capped at toolutil.MaxFileReadBytes.
+//   - The cursor encodes the end offset, the file identity (device/inode, or the Windows file ID) and a
+//     fingerprint of the file head. A different identity or a changed head is reported as rotated; a
+//     file smaller than the cursor offset as truncated. Both fall back to a normal tail.
+//   - Invalid UTF-8 in the output is replaced with U+FFFD (logs often contain stray bytes).
+func tailFile(
+	ctx context.Context,
+	args TailFileArgs,
+	p fspolicy.FSPolicy,
+) (*TailFileOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if args.Lines < 0 || args.Lines > tailFileMaxLines {
+		return nil, fmt.Errorf("lines must be between 1 and %d", tailFileMaxLines)
+	}
+	if args.Bytes < 0 {
+		return nil, errors.New("bytes must be > 0")
+	}
+
+	abs, err := p.ResolvePath(args.Path, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.RequireExistingRegularFileResolved(abs); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("path does not exist: %s", abs)
+		}
+		return nil, err
+	}
+	if _, mode, _, err := ioutil.MIMEForLocalFile(p.FS(), abs); err != nil || mode != ioutil.ExtensionModeText {
+		return nil, fmt.Errorf("cannot tail non-text file %q", abs)
+	}
+
+	out := &TailFileOut{Path: abs}
+	var chunk *ioutil.FileChunk
+	if strings.TrimSpace(args.SinceCursor) != "" {
+		cur, err := parseTailCursor(args.SinceCursor)
+		if err != nil {
+			return nil, err
+		}
+		reason, err := checkTailCursor(p.FS(), abs, cur)
+		if err != nil {
+			return nil, err
+		}
+		if reason == "" {
+			chunk, err = ioutil.ReadFileChunk(ctx, p.FS(), abs, cur.offset, toolutil.MaxFileReadBytes, true)
+			if err != nil {
+				return nil, err
+			}
+			out.HasMore = !chunk.EOF
+		} else {
+			out.Reset = true
+			out.ResetReason = reason
+		}
+	}
+
+	if chunk == nil {
+		if args.Bytes > 0 {
+			chunk, err = tailFileBytes(ctx, p.FS(), abs, min(args.Bytes, toolutil.MaxFileReadBytes))
+		} else {
+			lines := args.Lines
+			if lines == 0 {
+				lines = tailFileDefaultLines
+			}
+			chunk, err = ioutil.TailFileLines(ctx, p.FS(), abs, lines, toolutil.MaxFileReadBytes)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cursor, err := makeTailCursor(p.FS(), abs, chunk.NextOffset)
+	if err != nil {
+		return nil, err
+	}
+	out.Content = strings.ToValidUTF8(string(chunk.Data), "\uFFFD")
+	out.StartOffset = chunk.Offset
+	out.EndOffset = chunk.NextOffset
+	out.TotalSize = chunk.TotalSize
+	out.Cursor = cursor
+	return out, nil
+}
+
+func tailFileBytes(ctx context.Context, fsys vfs.FS, abs string, n int64) (*ioutil.FileChunk, error) {
+	st, err := fsys.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	start := max(st.Size()-n, 0)
+	return ioutil.ReadFileChunk(ctx, fsys, abs, start, max(st.Size()-start, 1), true)
+}
+
+// tailCursor is the decoded form of "v2:<offset>:<fingerprint>:<fileID>". The file ID is empty where
+// the FS exposes none; v1 cursors ("v1:<offset>:<fingerprint>") carry no file ID.
+type tailCursor struct {
+	offset      int64
+	fingerprint string
+	fileID      string
+}
+
+func makeTailCursor(fsys vfs.FS, abs string, offset int64) (string, error) {
+	fp, err := ioutil.FileHeadFingerprint(fsys, abs, min(offset, tailCursorFingerprintBytes))
+	if err != nil {
+		return "", err
+	}
+	id, err := ioutil.FileID(fsys, abs)
+	if err != nil {
+		return "", err
+	}
+	return tailCursorVersion + ":" + strconv.FormatInt(offset, 10) + ":" + fp + ":" + id, nil
+}
+
+func parseTailCursor(c string) (tailCursor, error) {
+	parts := strings.Split(strings.TrimSpace(c), ":")
+	valid := (len(parts) == 3 && parts[0] == "v1") || (len(parts) == 4 && parts[0] == tailCursorVersion)
+	if !valid || parts[2] == "" {
+		return tailCursor{}, fmt.Errorf("invalid sinceCursor: %q", c)
+	}
+	offset, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || offset < 0 {
+		return tailCursor{}, fmt.Errorf("invalid sinceCursor: %q", c)
+	}
+	cur := tailCursor{offset: offset, fingerprint: parts[2]}
+	if len(parts) == 4 {
+		cur.fileID = parts[3]
+	}
+	return cur, nil
+}
+
+// checkTailCursor reports why a cursor no longer applies to the file ("" if it still does). The file
+// identity is compared first: a replacement file with the same head and at least as many bytes is only
+// caught this way. The head fingerprint covers FSes without file identities.
+func checkTailCursor(fsys vfs.FS, abs string, cur tailCursor) (TailFileResetReason, error) {
+	if cur.fileID != "" {
+		id, err := ioutil.FileID(fsys, abs)
+		if err != nil {
+			return "", err
+		}
+		if id != "" && id != cur.fileID {
+			return TailFileResetRotated, nil
+		}
+	}
+	st, err := fsys.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if st.Size() < cur.offset {
+		return TailFileResetTruncated, nil
+	}
+	fp, err := ioutil.FileHeadFingerprint(fsys, abs, min(cur.offset, tailCursorFingerprintBytes))
+	if err != nil {
+		return "", err
+	}
+	if fp != cur.fingerprint {
+		return TailFileResetRotated, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,231 @@
+package fstool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTailFile(t *testing.T) {
+	appendFile := func(t *testing.T, path, s string) {
+		t.Helper()
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(s); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	tailOrFail := func(t *testing.T, ft *FSTool, args TailFileArgs) *TailFileOut {
+		t.Helper()
+		out, err := ft.TailFile(t.Context(), args)
+		if err != nil {
+			t.Fatalf("tail: %v", err)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		run     func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error)
+		wantErr func(error) bool
+		check   func(t *testing.T, out *TailFileOut)
+	}{
+		{
+			name: "context_canceled",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				return ft.TailFile(canceledContext(t), TailFileArgs{Path: "app.log"})
+			},
+			wantErr: wantErrIs(context.Canceled),
+		},
+		{
+			name: "last_lines",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(root, "app.log"), []byte("a\nb\nc\nd\n"))
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", Lines: 2})
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *TailFileOut) {
+				t.Helper()
+				if out.Content != "c\nd\n" || out.StartOffset != 4 || out.EndOffset != 8 || out.Cursor == "" {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "last_bytes",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(root, "app.log"), []byte("hello world"))
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", Bytes: 5})
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *TailFileOut) {
+				t.Helper()
+				if out.Content != "world" {
+					t.Fatalf("content=%q", out.Content)
+				}
+			},
+		},
+		{
+			name: "since_cursor_returns_only_appended",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				p := filepath.Join(root, "app.log")
+				mustWriteFile(t, p, []byte("a\nb\n"))
+				first := tailOrFail(t, ft, TailFileArgs{Path: "app.log"})
+				appendFile(t, p, "c\nd\n")
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", SinceCursor: first.Cursor})
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *TailFileOut) {
+				t.Helper()
+				if out.Content != "c\nd\n" || out.Reset || out.StartOffset != 4 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "since_cursor_nothing_new",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(root, "app.log"), []byte("a\n"))
+				first := tailOrFail(t, ft, TailFileArgs{Path: "app.log"})
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", SinceCursor: first.Cursor})
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *TailFileOut) {
+				t.Helper()
+				if out.Content != "" || out.Reset {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "truncation_detected",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				p := filepath.Join(root, "app.log")
+				mustWriteFile(t, p, []byte("line one\nline two\n"))
+				first := tailOrFail(t, ft, TailFileArgs{Path: "app.log"})
+				mustWriteFile(t, p, []byte("x\n"))
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", SinceCursor: first.Cursor})
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *TailFileOut) {
+				t.Helper()
+				if !out.Reset || out.ResetReason != TailFileResetTruncated || out.Content != "x\n" {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "rotation_detected",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				p := filepath.Join(root, "app.log")
+				mustWriteFile(t, p, []byte("old 1\n"))
+				first := tailOrFail(t, ft, TailFileArgs{Path: "app.log"})
+				if err := os.Rename(p, p+".1"); err != nil {
+					t.Fatalf("rotate: %v", err)
+				}
+				mustWriteFile(t, p, []byte("new 1\nnew 2\n"))
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", SinceCursor: first.Cursor})
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *TailFileOut) {
+				t.Helper()
+				if !out.Reset || out.ResetReason != TailFileResetRotated || out.Content != "new 1\nnew 2\n" {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "rotation_with_same_head_detected",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				p := filepath.Join(root, "app.log")
+				mustWriteFile(t, p, []byte("start\n"))
+				first := tailOrFail(t, ft, TailFileArgs{Path: "app.log"})
+				if err := os.Rename(p, p+".1"); err != nil {
+					t.Fatalf("rotate: %v", err)
+				}
+				mustWriteFile(t, p, []byte("start\nnext\n"))
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", SinceCursor: first.Cursor})
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *TailFileOut) {
+				t.Helper()
+				if !out.Reset || out.ResetReason != TailFileResetRotated || out.Content != "start\nnext\n" {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "v1_cursor_accepted",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				p := filepath.Join(root, "app.log")
+				mustWriteFile(t, p, []byte("a\n"))
+				first := tailOrFail(t, ft, TailFileArgs{Path: "app.log"})
+				appendFile(t, p, "b\n")
+				parts := strings.Split(first.Cursor, ":")
+				v1 := "v1:" + parts[1] + ":" + parts[2]
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", SinceCursor: v1})
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, out *TailFileOut) {
+				t.Helper()
+				if out.Reset || out.Content != "b\n" {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "invalid_cursor_errors",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(root, "app.log"), []byte("a\n"))
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", SinceCursor: "garbage"})
+			},
+			wantErr: wantErrContains("invalid sinceCursor"),
+		},
+		{
+			name: "binary_file_refused",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(root, "x.png"), []byte("\x89PNG\r\n\x1a\n"))
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "x.png"})
+			},
+			wantErr: wantErrContains("non-text"),
+		},
+		{
+			name: "too_many_lines_errors",
+			run: func(t *testing.T, ft *FSTool, root string) (*TailFileOut, error) {
+				t.Helper()
+				return ft.TailFile(t.Context(), TailFileArgs{Path: "app.log", Lines: tailFileMaxLines + 1})
+			},
+			wantErr: wantErrContains("lines must be"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			ft := mustNewFSTool(t, WithWorkBaseDir(root))
+			out, err := tt.run(t, ft, root)
+			if !tt.wantErr(err) {
+				t.Fatalf("err=%v did not match expectation", err)
+			}
+			if err == nil && tt.check != nil {
+				tt.check(t, out)
+			}
+		})
+	}
+}
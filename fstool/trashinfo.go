@@ -0,0 +1,231 @@
+package fstool
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// Trash metadata uses the freedesktop.org trash spec ".trashinfo" format:
+//
+//	[Trash Info]
+//	Path=/url/escaped/original/path
+//	DeletionDate=2006-01-02T15:04:05
+//
+// For a freedesktop trash (a "files" directory next to an "info" directory, or the detected system
+// trash), info files live in the sibling "info" directory. For any other trash dir (local .trash,
+// explicit dirs), they live in a hidden sidecar directory inside the trash dir, so trashed entries keep
+// their plain location; an entry named like the sidecar is trashed under an escaped name.
+const (
+	localTrashDirName     = ".trash"
+	trashInfoExt          = ".trashinfo"
+	trashInfoHeader       = "[Trash Info]"
+	trashInfoDateLayout   = "2006-01-02T15:04:05"
+	trashInfoSidecarDir   = ".trashinfo"
+	freedesktopFilesDir   = "files"
+	freedesktopInfoDir    = "info"
+	maxTrashInfoFileBytes = 64 * 1024
+)
+
+type trashInfo struct {
+	OriginalPath string
+	DeletedAt    time.Time
+}
+
+// resolveTrashDirs resolves a trashDir argument. "auto" (or empty) selects every existing trash dir
+// deletefile's "auto" can pick: the detected system trash, then the local .trash of the work base dir
+// and of each allowed root.
+func resolveTrashDirs(p fspolicy.FSPolicy, in string) ([]string, error) {
+	in = strings.TrimSpace(in)
+	if in != "" && in != "auto" {
+		td, err := p.ResolvePath(in, "")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.VerifyDirResolved(td); err != nil {
+			return nil, err
+		}
+		return []string{td}, nil
+	}
+
+	var candidates []string
+	if sys, ok := detectSystemTrashDir(); ok && vfs.IsOS(p.FS()) {
+		candidates = append(candidates, sys)
+	}
+	for _, root := range append([]string{p.WorkBaseDir()}, p.AllowedRoots()...) {
+		if root != "" {
+			candidates = append(candidates, filepath.Join(root, localTrashDirName))
+		}
+	}
+	var dirs []string
+	for _, c := range candidates {
+		td, err := p.ResolvePath(c, "")
+		if err != nil || slices.Contains(dirs, td) {
+			continue
+		}
+		if st, err := p.FS().Stat(td); err != nil || !st.IsDir() || p.VerifyDirResolved(td) != nil {
+			continue
+		}
+		dirs = append(dirs, td)
+	}
+	return dirs, nil
+}
+
+// localTrashDir returns the .trash fallback for src: the one in the innermost allowed root containing
+// src, else the one in the work base dir. Both are among the dirs the "auto" trash tools search, so
+// whatever deletefile trashes can be listed, restored and purged again.
+func localTrashDir(p fspolicy.FSPolicy, src string) string {
+	best := p.WorkBaseDir()
+	inside := isSameOrInside(best, src)
+	for _, root := range p.AllowedRoots() {
+		if isSameOrInside(root, src) && (!inside || len(root) > len(best)) {
+			best, inside = root, true
+		}
+	}
+	return filepath.Join(best, localTrashDirName)
+}
+
+// isFreedesktopTrash reports whether trashDir is the "files" directory of a freedesktop trash: it has
+// an "info" sibling directory, or it is the detected system trash (whose "info" may not exist yet).
+func isFreedesktopTrash(fsys vfs.FS, trashDir string) bool {
+	if filepath.Base(trashDir) != freedesktopFilesDir {
+		return false
+	}
+	if sys, ok := detectSystemTrashDir(); ok && vfs.IsOS(fsys) && filepath.Clean(sys) == filepath.Clean(trashDir) {
+		return true
+	}
+	st, err := fsys.Stat(filepath.Join(filepath.Dir(trashDir), freedesktopInfoDir))
+	return err == nil && st.IsDir()
+}
+
+// trashInfoDirFor returns the directory holding .trashinfo files for trashDir.
+func trashInfoDirFor(fsys vfs.FS, trashDir string) string {
+	if isFreedesktopTrash(fsys, trashDir) {
+		return filepath.Join(filepath.Dir(trashDir), freedesktopInfoDir)
+	}
+	return filepath.Join(trashDir, trashInfoSidecarDir)
+}
+
+func trashInfoPathFor(fsys vfs.FS, trashDir, name string) string {
+	return filepath.Join(trashInfoDirFor(fsys, trashDir), name+trashInfoExt)
+}
+
+// trashEntryBase returns the name to trash an entry named base under: the sidecar name is reserved.
+func trashEntryBase(fsys vfs.FS, trashDir, base string) string {
+	if base == trashInfoSidecarDir && !isFreedesktopTrash(fsys, trashDir) {
+		return "_" + base
+	}
+	return base
+}
+
+// writeTrashInfo records metadata for an entry that was moved into trashDir.
+func writeTrashInfo(p fspolicy.FSPolicy, trashDir, trashedPath, originalPath string, deletedAt time.Time) (string, error) {
+	infoDir := trashInfoDirFor(p.FS(), trashDir)
+	if _, err := p.EnsureDirResolved(infoDir, 1); err != nil {
+		return "", err
+	}
+	infoPath := trashInfoPathFor(p.FS(), trashDir, filepath.Base(trashedPath))
+	if err := ioutil.WriteFileAtomicBytesResolved(p, infoPath, encodeTrashInfo(originalPath, deletedAt), 0o600, true); err != nil {
+		return "", err
+	}
+	return infoPath, nil
+}
+
+func encodeTrashInfo(originalPath string, deletedAt time.Time) []byte {
+	u := url.URL{Path: filepath.ToSlash(originalPath)}
+	var b bytes.Buffer
+	b.WriteString(trashInfoHeader + "\n")
+	b.WriteString("Path=" + u.EscapedPath() + "\n")
+	b.WriteString("DeletionDate=" + deletedAt.Local().Format(trashInfoDateLayout) + "\n")
+	return b.Bytes()
+}
+
+// readTrashInfo parses a .trashinfo file. A missing file reports os.ErrNotExist.
+func readTrashInfo(fsys vfs.FS, infoPath string) (*trashInfo, error) {
+	st, err := fsys.Lstat(infoPath)
+	if err != nil {
+		return nil, err
+	}
+	if !st.Mode().IsRegular() {
+		return nil, fmt.Errorf("trash info is not a regular file: %s", infoPath)
+	}
+	if st.Size() > maxTrashInfoFileBytes {
+		return nil, fmt.Errorf("trash info file too large: %s", infoPath)
+	}
+	data, err := vfs.ReadFile(fsys, infoPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseTrashInfo(data)
+}
+
+func parseTrashInfo(data []byte) (*trashInfo, error) {
+	var (
+		info      trashInfo
+		inSection bool
+		havePath  bool
+	)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			inSection = line == trashInfoHeader
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Path":
+			raw, err := url.PathUnescape(strings.TrimSpace(val))
+			if err != nil {
+				return nil, fmt.Errorf("invalid trash info path: %w", err)
+			}
+			info.OriginalPath = filepath.FromSlash(raw)
+			havePath = true
+		case "DeletionDate":
+			t, err := time.ParseInLocation(trashInfoDateLayout, strings.TrimSpace(val), time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trash info deletion date: %w", err)
+			}
+			info.DeletedAt = t.UTC()
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !havePath || info.OriginalPath == "" {
+		return nil, errors.New("trash info has no Path entry")
+	}
+	return &info, nil
+}
+
+// trashEntryPath validates a trash entry name (a single path element) and joins it to trashDir.
+func trashEntryPath(fsys vfs.FS, trashDir, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name ||
+		strings.ContainsAny(name, `/\`) || strings.ContainsRune(name, 0) {
+		return "", fmt.Errorf("invalid trash entry name: %q", name)
+	}
+	if name == trashInfoSidecarDir && !isFreedesktopTrash(fsys, trashDir) {
+		return "", fmt.Errorf("invalid trash entry name: %q", name)
+	}
+	return filepath.Join(trashDir, name), nil
+}
@@ -0,0 +1,142 @@
+package fstool
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestTrashInfoRoundTrip(t *testing.T) {
+	deletedAt := time.Date(2024, 3, 5, 10, 11, 12, 0, time.Local)
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "plain", path: filepath.Join(string(filepath.Separator)+"tmp", "a.txt")},
+		{name: "spaces_and_percent", path: filepath.Join(string(filepath.Separator)+"tmp", "my dir", "100% done.txt")},
+		{name: "unicode", path: filepath.Join(string(filepath.Separator)+"tmp", "héllo", "日本.txt")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := encodeTrashInfo(tc.path, deletedAt)
+			if !strings.HasPrefix(string(data), trashInfoHeader+"\n") {
+				t.Fatalf("missing header: %q", string(data))
+			}
+			for line := range strings.SplitSeq(string(data), "\n") {
+				if strings.HasPrefix(line, "Path=") && strings.Contains(line, " ") {
+					t.Fatalf("expected URL-escaped path, got %q", line)
+				}
+			}
+			info, err := parseTrashInfo(data)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if info.OriginalPath != tc.path {
+				t.Fatalf("path=%q want=%q", info.OriginalPath, tc.path)
+			}
+			if !info.DeletedAt.Equal(deletedAt) {
+				t.Fatalf("deletedAt=%v want=%v", info.DeletedAt, deletedAt)
+			}
+		})
+	}
+}
+
+func TestParseTrashInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantErr  bool
+		wantPath string
+	}{
+		{
+			name:     "freedesktop_example",
+			data:     "[Trash Info]\nPath=/home/u/foo%20bar.txt\nDeletionDate=2004-08-31T22:32:08\n",
+			wantPath: filepath.FromSlash("/home/u/foo bar.txt"),
+		},
+		{
+			name:     "keys_outside_section_ignored",
+			data:     "Path=/wrong\n[Other]\nPath=/wrong2\n[Trash Info]\nPath=/right\n",
+			wantPath: filepath.FromSlash("/right"),
+		},
+		{name: "missing_path", data: "[Trash Info]\nDeletionDate=2004-08-31T22:32:08\n", wantErr: true},
+		{name: "bad_date", data: "[Trash Info]\nPath=/a\nDeletionDate=yesterday\n", wantErr: true},
+		{name: "bad_escape", data: "[Trash Info]\nPath=/a%zz\n", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := parseTrashInfo([]byte(tc.data))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if info.OriginalPath != tc.wantPath {
+				t.Fatalf("path=%q want=%q", info.OriginalPath, tc.wantPath)
+			}
+		})
+	}
+}
+
+func TestTrashInfoDirFor(t *testing.T) {
+	t.Parallel()
+
+	m := vfs.NewMemFS()
+	base := filepath.Join(string(filepath.Separator)+"x", "Trash")
+	other := filepath.Join(string(filepath.Separator)+"x", "backup")
+	local := filepath.Join(string(filepath.Separator)+"x", ".trash")
+	for _, d := range []string{
+		filepath.Join(base, "files"), filepath.Join(base, "info"), filepath.Join(other, "files"), local,
+	} {
+		if err := m.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		trashDir string
+		want     string
+	}{
+		{name: "freedesktop layout", trashDir: filepath.Join(base, "files"), want: filepath.Join(base, "info")},
+		{
+			name:     "files dir without info sibling",
+			trashDir: filepath.Join(other, "files"),
+			want:     filepath.Join(other, "files", trashInfoSidecarDir),
+		},
+		{name: "local trash", trashDir: local, want: filepath.Join(local, trashInfoSidecarDir)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := trashInfoDirFor(m, tc.trashDir); got != tc.want {
+				t.Fatalf("info dir=%q want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTrashEntryPath(t *testing.T) {
+	t.Parallel()
+
+	m := vfs.NewMemFS()
+	td := filepath.Join(string(filepath.Separator)+"x", ".trash")
+	for _, bad := range []string{"", ".", "..", "a/b", `a\b`, trashInfoSidecarDir} {
+		if _, err := trashEntryPath(m, td, bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+	got, err := trashEntryPath(m, td, "a.txt")
+	if err != nil || got != filepath.Join(td, "a.txt") {
+		t.Fatalf("got=%q err=%v", got, err)
+	}
+	if got := trashEntryBase(m, td, trashInfoSidecarDir); got == trashInfoSidecarDir {
+		t.Fatalf("sidecar name not escaped: %q", got)
+	}
+}
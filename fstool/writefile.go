@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"unicode/utf8"
 
@@ -23,7 +24,7 @@ var writeFileTool = spec.Tool{
 	Slug:          "writefile",
 	Version:       "v1.0.0",
 	DisplayName:   "Write file",
-	Description:   "Write a file to disk. encoding=text writes UTF-8; binary expects base64 string as input and writes raw bytes.",
+	Description:   "Write a file to disk. encoding=text writes UTF-8; binary expects base64 string as input and writes raw bytes. mode=append appends to the end of a file; mode=writeAt overwrites bytes at a given offset.",
 	Tags:          []string{"fs"},
 
 	ArgSchema: spec.JSONSchema(`{
@@ -44,15 +45,30 @@ var writeFileTool = spec.Tool{
 		"type": "string",
 		"description": "If encoding=text, UTF-8 content. If encoding=binary, base64-encoded bytes."
 	},
+	"mode": {
+		"type": "string",
+		"enum": ["write", "append", "writeAt"],
+		"description": "write: create or replace the whole file atomically. append: add content at the end (creates the file if missing). writeAt: overwrite bytes in place starting at offset (file must exist).",
+		"default": "write"
+	},
+	"offset": {
+		"type": "integer",
+		"minimum": 0,
+		"description": "Byte offset for mode=writeAt. Must not exceed the current file size."
+	},
 	"overwrite": {
 		"type": "boolean",
-		"description": "If false and the file exists, return an error.",
+		"description": "mode=write only. If false and the file exists, return an error.",
 		"default": false
 	},
 	"createParents": {
 		"type": "boolean",
 		"description": "If true, create missing parent directories. Max new directories created is 8.",
 		"default": false
+	},
+	"expectedVersion": {
+		"type": "string",
+		"description": "Version token from an earlier read (readfile, statpath, readtextrange, findtext). For mode=write it requires overwrite=true. If the file has changed since, the write fails with a stale-version error."
 	}
 },
 "required": ["path", "content"],
@@ -69,15 +85,41 @@ type WriteFileArgs struct {
 	Path          string `json:"path"`
 	Encoding      string `json:"encoding,omitempty"` // "text"(default) | "binary"
 	Content       string `json:"content"`
+	Mode          string `json:"mode,omitempty"`   // "write"(default) | "append" | "writeAt"
+	Offset        int64  `json:"offset,omitempty"` // mode=writeAt only
 	Overwrite     bool   `json:"overwrite,omitempty"`
 	CreateParents bool   `json:"createParents,omitempty"`
+
+	ExpectedVersion string `json:"expectedVersion,omitempty"`
 }
 
 type WriteFileOut struct {
 	Path         string `json:"path"`
 	BytesWritten int64  `json:"bytesWritten"`
+	Version      string `json:"version,omitempty"` // version token of the written file (mode=write only)
+
+	// mode=append/writeAt only.
+	Offset    int64 `json:"offset,omitempty"`    // where the content was written
+	SizeBytes int64 `json:"sizeBytes,omitempty"` // file size after the write
 }
 
+const (
+	writeFileModeWrite   = "write"
+	writeFileModeAppend  = "append"
+	writeFileModeWriteAt = "writeat" // compared lowercased
+)
+
+// writeFile writes content to a file.
+//
+// Behavior notes (entry point):
+//   - mode=write replaces the whole file atomically (temp file + rename).
+//   - mode=append uses a single O_APPEND write, so concurrent appenders on a local filesystem never
+//     clobber each other. A missing file is created (createParents applies).
+//   - mode=writeAt patches bytes in place (not atomic); offset may equal, but not exceed, the file size.
+//   - content is capped at toolutil.MaxFileWriteBytes per call in every mode; the size of the file
+//     it lands in is only limited by the quota's MaxFileBytes, so large logs can still be appended to.
+//   - If expectedVersion is set, the existing file must still match it (else ioutil.ErrStaleVersion).
+//     The check runs right before the write; it narrows but does not close the race window.
 func writeFile(
 	ctx context.Context,
 	args WriteFileArgs,
@@ -120,6 +162,40 @@ func writeFile(
 		return nil, fmt.Errorf("content too large (%d bytes; max %d)", len(data), toolutil.MaxFileWriteBytes)
 	}
 
+	mode := strings.ToLower(strings.TrimSpace(args.Mode))
+	if mode == "" {
+		mode = writeFileModeWrite
+	}
+	switch mode {
+	case writeFileModeWrite:
+		if args.Offset != 0 {
+			return nil, errors.New("offset is only valid with mode=writeAt")
+		}
+	case writeFileModeAppend, writeFileModeWriteAt:
+		if args.Overwrite {
+			return nil, errors.New("overwrite is only valid with mode=write")
+		}
+		if mode == writeFileModeAppend && args.Offset != 0 {
+			return nil, errors.New("offset is only valid with mode=writeAt")
+		}
+		return writeFileInPlace(ctx, args, mode, data, p)
+	default:
+		return nil, fmt.Errorf(`mode must be "write", "append" or "writeAt" (got %q)`, args.Mode)
+	}
+
+	if strings.TrimSpace(args.ExpectedVersion) != "" {
+		if !args.Overwrite {
+			return nil, errors.New("expectedVersion requires overwrite=true")
+		}
+		abs, err := p.ResolvePath(args.Path, "")
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.CheckFileVersion(ctx, p.FS(), abs, args.ExpectedVersion); err != nil {
+			return nil, err
+		}
+	}
+
 	dst, err := ioutil.WriteFileAtomicBytesWithParents(
 		p,
 		args.Path,
@@ -138,8 +214,56 @@ func writeFile(
 		}
 		return nil, err
 	}
+	version, err := ioutil.WrittenFileVersion(p.FS(), dst, data)
+	if err != nil {
+		return nil, err
+	}
 	return &WriteFileOut{
 		Path:         dst,
 		BytesWritten: int64(len(data)),
+		Version:      version,
 	}, nil
 }
+
+// writeFileInPlace handles mode=append and mode=writeAt (see writeFile).
+func writeFileInPlace(
+	ctx context.Context,
+	args WriteFileArgs,
+	mode string,
+	data []byte,
+	p fspolicy.FSPolicy,
+) (*WriteFileOut, error) {
+	if args.Offset < 0 {
+		return nil, errors.New("offset must be >= 0")
+	}
+	dst, err := p.ResolvePath(args.Path, "")
+	if err != nil {
+		return nil, err
+	}
+	parent := filepath.Dir(dst)
+	if mode == writeFileModeAppend && args.CreateParents {
+		if _, err := p.EnsureDirResolved(parent, 8); err != nil {
+			return nil, err
+		}
+	} else if err := p.VerifyDirResolved(parent); err != nil {
+		return nil, err
+	}
+	if err := ioutil.CheckFileVersion(ctx, p.FS(), dst, args.ExpectedVersion); err != nil {
+		return nil, err
+	}
+
+	out := &WriteFileOut{Path: dst, BytesWritten: int64(len(data))}
+	if mode == writeFileModeAppend {
+		out.Offset, out.SizeBytes, err = ioutil.AppendFileBytesResolved(p, dst, data, 0o600)
+	} else {
+		out.Offset = args.Offset
+		out.SizeBytes, err = ioutil.WriteFileAtResolved(p, dst, args.Offset, data)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("path does not exist (mode=writeAt requires an existing file): %s", dst)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
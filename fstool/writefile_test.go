@@ -120,6 +120,252 @@ func TestWriteFile(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "expected_version_matches_overwrites_and_returns_new_version",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "v.txt"), []byte("a"))
+				st, err := makeTool(t, c).StatPath(t.Context(), StatPathArgs{Path: "v.txt"})
+				if err != nil {
+					t.Fatalf("stat: %v", err)
+				}
+				return WriteFileArgs{Path: "v.txt", Content: "b", Overwrite: true, ExpectedVersion: st.Version}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *WriteFileOut) {
+				t.Helper()
+				st, err := makeTool(t, c).StatPath(t.Context(), StatPathArgs{Path: "v.txt"})
+				if err != nil {
+					t.Fatalf("stat: %v", err)
+				}
+				if out.Version == "" || out.Version != st.Version {
+					t.Fatalf("version=%q want %q", out.Version, st.Version)
+				}
+			},
+		},
+		{
+			name: "stale_expected_version_errors_and_preserves_file",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				p := filepath.Join(c.workBaseDir, "v.txt")
+				mustWriteFile(t, p, []byte("a"))
+				st, err := makeTool(t, c).StatPath(t.Context(), StatPathArgs{Path: "v.txt"})
+				if err != nil {
+					t.Fatalf("stat: %v", err)
+				}
+				mustWriteFile(t, p, []byte("changed by someone else"))
+				return WriteFileArgs{Path: "v.txt", Content: "b", Overwrite: true, ExpectedVersion: st.Version}
+			},
+			wantErr: wantErrIs(ErrStaleVersion),
+			check: func(t *testing.T, c cfg, out *WriteFileOut) {
+				t.Helper()
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "v.txt"))); got != "changed by someone else" {
+					t.Fatalf("content=%q; stale write must not modify the file", got)
+				}
+			},
+		},
+		{
+			name: "expected_version_requires_overwrite",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				return WriteFileArgs{Path: "v.txt", Content: "b", ExpectedVersion: "sha256:00@0"}
+			},
+			wantErr: wantErrContains("requires overwrite=true"),
+		},
+		{
+			name: "append_creates_then_appends",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				if _, err := makeTool(t, c).WriteFile(
+					t.Context(), WriteFileArgs{Path: "log.csv", Content: "a,b\n", Mode: "append"},
+				); err != nil {
+					t.Fatalf("first append: %v", err)
+				}
+				return WriteFileArgs{Path: "log.csv", Content: "1,2\n", Mode: "append"}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *WriteFileOut) {
+				t.Helper()
+				if out.Offset != 4 || out.SizeBytes != 8 || out.BytesWritten != 4 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if got := string(mustReadFile(t, filepath.Join(c.workBaseDir, "log.csv"))); got != "a,b\n1,2\n" {
+					t.Fatalf("content=%q", got)
+				}
+			},
+		},
+		{
+			name: "writeAt_patches_binary_in_place",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "b.bin"), []byte{0, 1, 2, 3, 4})
+				return WriteFileArgs{
+					Path:     "b.bin",
+					Encoding: "binary",
+					Content:  base64.StdEncoding.EncodeToString([]byte{9, 9}),
+					Mode:     "writeAt",
+					Offset:   1,
+				}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *WriteFileOut) {
+				t.Helper()
+				if out.Offset != 1 || out.SizeBytes != 5 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				if got := mustReadFile(t, filepath.Join(c.workBaseDir, "b.bin")); !bytes.Equal(got, []byte{0, 9, 9, 3, 4}) {
+					t.Fatalf("content=%v", got)
+				}
+			},
+		},
+		{
+			name: "append_to_file_larger_than_write_cap",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				mustTruncateFile(t, filepath.Join(c.workBaseDir, "big.log"), toolutil.MaxFileWriteBytes+3)
+				return WriteFileArgs{Path: "big.log", Content: "tail\n", Mode: "append"}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *WriteFileOut) {
+				t.Helper()
+				if out.Offset != toolutil.MaxFileWriteBytes+3 || out.SizeBytes != toolutil.MaxFileWriteBytes+8 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name: "writeAt_into_file_larger_than_write_cap",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				mustTruncateFile(t, filepath.Join(c.workBaseDir, "big.bin"), toolutil.MaxFileWriteBytes+3)
+				return WriteFileArgs{
+					Path:     "big.bin",
+					Encoding: "binary",
+					Content:  base64.StdEncoding.EncodeToString([]byte{7, 7}),
+					Mode:     "writeAt",
+					Offset:   toolutil.MaxFileWriteBytes,
+				}
+			},
+			wantErr: wantErrNone,
+			check: func(t *testing.T, c cfg, out *WriteFileOut) {
+				t.Helper()
+				if out.SizeBytes != toolutil.MaxFileWriteBytes+3 {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+				got := mustReadFile(t, filepath.Join(c.workBaseDir, "big.bin"))
+				if !bytes.Equal(got[toolutil.MaxFileWriteBytes-1:], []byte{0, 7, 7, 0}) {
+					t.Fatalf("tail=%v", got[toolutil.MaxFileWriteBytes-1:])
+				}
+			},
+		},
+		{
+			name: "writeAt_beyond_end_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				mustWriteFile(t, filepath.Join(c.workBaseDir, "b.txt"), []byte("abc"))
+				return WriteFileArgs{Path: "b.txt", Content: "x", Mode: "writeAt", Offset: 10}
+			},
+			wantErr: wantErrContains("beyond end of file"),
+		},
+		{
+			name: "writeAt_missing_file_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				return WriteFileArgs{Path: "nope.txt", Content: "x", Mode: "writeAt"}
+			},
+			wantErr: wantErrContains("requires an existing file"),
+		},
+		{
+			name: "append_rejects_overwrite_flag",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				return WriteFileArgs{Path: "a.txt", Content: "x", Mode: "append", Overwrite: true}
+			},
+			wantErr: wantErrContains("overwrite is only valid"),
+		},
+		{
+			name: "offset_without_writeAt_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				return WriteFileArgs{Path: "a.txt", Content: "x", Offset: 3}
+			},
+			wantErr: wantErrContains("offset is only valid"),
+		},
+		{
+			name: "append_stale_expected_version_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				p := filepath.Join(c.workBaseDir, "log.txt")
+				mustWriteFile(t, p, []byte("a\n"))
+				st, err := makeTool(t, c).StatPath(t.Context(), StatPathArgs{Path: "log.txt"})
+				if err != nil {
+					t.Fatalf("stat: %v", err)
+				}
+				mustWriteFile(t, p, []byte("a\nb\n"))
+				return WriteFileArgs{Path: "log.txt", Content: "c\n", Mode: "append", ExpectedVersion: st.Version}
+			},
+			wantErr: wantErrIs(ErrStaleVersion),
+		},
+		{
+			name: "unknown_mode_errors",
+			cfg: func(t *testing.T) cfg {
+				t.Helper()
+				return cfg{workBaseDir: t.TempDir()}
+			},
+			args: func(t *testing.T, c cfg) WriteFileArgs {
+				t.Helper()
+				return WriteFileArgs{Path: "a.txt", Content: "x", Mode: "prepend"}
+			},
+			wantErr: wantErrContains("mode must be"),
+		},
 		{
 			name: "writes_binary_base64_trimmed_case_insensitive_encoding",
 			cfg: func(t *testing.T) cfg {
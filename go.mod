@@ -3,3 +3,11 @@ module github.com/flexigpt/llmtools-go
 go 1.25
 
 require github.com/ledongthuc/pdf v0.0.0-20250511090121-5959a4027728
+
+require (
+	github.com/klauspost/compress v1.18.0
+	golang.org/x/crypto v0.45.0
+	golang.org/x/net v0.47.0
+	golang.org/x/sys v0.38.0
+	golang.org/x/text v0.31.0
+)
@@ -7,9 +7,11 @@ import (
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
 	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 type imageToolConfig struct {
+	fsys          vfs.FS
 	allowedRoots  []string
 	workBaseDir   string
 	blockSymlinks bool
@@ -42,6 +44,16 @@ func WithWorkBaseDir(base string) ImageToolOption {
 	}
 }
 
+// WithFS sets the filesystem the tools operate on (default: the OS filesystem), e.g. a vfs.MemFS.
+// Paths stay native absolute paths; with a non-OS filesystem, WithWorkBaseDir or WithAllowedRoots
+// must name an absolute directory that exists in it.
+func WithFS(fsys vfs.FS) ImageToolOption {
+	return func(it *ImageTool) error {
+		it.cfg.fsys = fsys
+		return nil
+	}
+}
+
 // WithBlockSymlinks configures whether symlink traversal should be blocked (if supported downstream).
 func WithBlockSymlinks(block bool) ImageToolOption {
 	return func(it *ImageTool) error {
@@ -68,7 +80,7 @@ func NewImageTool(opts ...ImageToolOption) (*ImageTool, error) {
 		}
 	}
 
-	pol, err := fspolicy.New(it.cfg.workBaseDir, it.cfg.allowedRoots, it.cfg.blockSymlinks)
+	pol, err := fspolicy.NewWithFS(it.cfg.fsys, it.cfg.workBaseDir, it.cfg.allowedRoots, it.cfg.blockSymlinks)
 	if err != nil {
 		return nil, err
 	}
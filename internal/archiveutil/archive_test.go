@@ -0,0 +1,447 @@
+package archiveutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+type testEntry struct {
+	name   string
+	typ    EntryType
+	body   string
+	target string
+}
+
+func writeTarArchive(t *testing.T, path string, entries []testEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		h := &tar.Header{Name: e.name, Mode: 0o644, Linkname: e.target}
+		switch e.typ {
+		case EntryDir:
+			h.Typeflag, h.Mode = tar.TypeDir, 0o755
+		case EntrySymlink:
+			h.Typeflag = tar.TypeSymlink
+		case EntryHardlink:
+			h.Typeflag = tar.TypeLink
+		default:
+			h.Typeflag, h.Size = tar.TypeReg, int64(len(e.body))
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func writeZipArchive(t *testing.T, path string, entries []testEntry) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		h.SetMode(0o644)
+		if e.typ == EntrySymlink {
+			h.SetMode(fs.ModeSymlink | 0o777)
+			e.body = e.target
+		}
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatalf("CreateHeader: %v", err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+}
+
+func mustPolicy(t *testing.T, dir string, blockSymlinks bool) fspolicy.FSPolicy {
+	t.Helper()
+	p, err := fspolicy.New(dir, nil, blockSymlinks)
+	if err != nil {
+		t.Fatalf("fspolicy.New: %v", err)
+	}
+	return p
+}
+
+func TestSanitizeEntryName(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "a/b.txt", want: "a/b.txt"},
+		{in: "./a//b/", want: "a/b"},
+		{in: "a/../b", want: "b"},
+		{in: "dir\\file.txt", want: "dir/file.txt"},
+		{in: ".", want: ""},
+		{in: "../evil", wantErr: true},
+		{in: "a/../../evil", wantErr: true},
+		{in: "..\\evil", wantErr: true},
+		{in: "/etc/passwd", wantErr: true},
+		{in: "\\\\server\\share", wantErr: true},
+		{in: "C:/Windows/x", wantErr: true},
+		{in: "c:evil", wantErr: true},
+		{in: "a\x00b", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := SanitizeEntryName(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err=%v wantErr=%v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Fatalf("got %q want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	dir := t.TempDir()
+	tarPath := filepath.Join(dir, "noext")
+	writeTarArchive(t, tarPath, []testEntry{{name: "a.txt", body: "a"}})
+	zipPath := filepath.Join(dir, "other")
+	writeZipArchive(t, zipPath, []testEntry{{name: "a.txt", body: "a"}})
+	junk := filepath.Join(dir, "junk")
+	if err := os.WriteFile(junk, []byte("not an archive"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    Format
+		wantErr bool
+	}{
+		{path: "x.TGZ", want: FormatTarGz},
+		{path: "x.tar.zst", want: FormatTarZst},
+		{path: "x.zip", want: FormatZip},
+		{path: tarPath, want: FormatTar},
+		{path: zipPath, want: FormatZip},
+		{path: junk, wantErr: true},
+	}
+	for _, tc := range tests {
+		got, err := DetectFormat(vfs.OS(), tc.path)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Fatalf("DetectFormat(%q) = %q, %v; want %q (err=%v)", tc.path, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	canSymlink := runtime.GOOS != "windows"
+
+	tests := []struct {
+		name          string
+		zip           bool
+		entries       []testEntry
+		blockSymlinks bool
+		limits        Limits
+		existing      map[string]string // files pre-created in dest
+		overwrite     bool
+		needSymlinks  bool
+		wantErr       string
+		wantErrIs     error
+		wantFiles     map[string]string
+		wantStats     *Stats
+	}{
+		{
+			name: "tar_files_and_dirs",
+			entries: []testEntry{
+				{name: "top/", typ: EntryDir},
+				{name: "top/a.txt", body: "alpha"},
+				{name: "top/sub/b.txt", body: "beta"},
+			},
+			wantFiles: map[string]string{"top/a.txt": "alpha", "top/sub/b.txt": "beta"},
+			wantStats: &Stats{Files: 2, Dirs: 2, Bytes: 9},
+		},
+		{
+			name:      "zip_files",
+			zip:       true,
+			entries:   []testEntry{{name: "d/x.txt", body: "x"}, {name: "y.txt", body: "yy"}},
+			wantFiles: map[string]string{"d/x.txt": "x", "y.txt": "yy"},
+		},
+		{
+			name:    "zip_slip_dotdot",
+			zip:     true,
+			entries: []testEntry{{name: "ok.txt", body: "ok"}, {name: "../evil.txt", body: "evil"}},
+			wantErr: "outside the destination",
+		},
+		{
+			name:    "tar_absolute_entry",
+			entries: []testEntry{{name: "/etc/evil", body: "evil"}},
+			wantErr: "absolute",
+		},
+		{
+			name:    "zip_backslash_dotdot",
+			zip:     true,
+			entries: []testEntry{{name: "..\\evil.txt", body: "evil"}},
+			wantErr: "outside the destination",
+		},
+		{
+			name:          "symlink_blocked",
+			entries:       []testEntry{{name: "a.txt", body: "a"}, {name: "l", typ: EntrySymlink, target: "a.txt"}},
+			blockSymlinks: true,
+			wantErrIs:     fspolicy.ErrSymlinkDisallowed,
+		},
+		{
+			name:          "hardlink_blocked",
+			entries:       []testEntry{{name: "a.txt", body: "a"}, {name: "h", typ: EntryHardlink, target: "a.txt"}},
+			blockSymlinks: true,
+			wantErrIs:     fspolicy.ErrSymlinkDisallowed,
+		},
+		{
+			name:         "symlink_inside_allowed",
+			zip:          true,
+			entries:      []testEntry{{name: "a.txt", body: "a"}, {name: "d/l", typ: EntrySymlink, target: "../a.txt"}},
+			needSymlinks: true,
+			wantFiles:    map[string]string{"a.txt": "a", "d/l": "a"},
+		},
+		{
+			name:         "symlink_escaping",
+			entries:      []testEntry{{name: "d/l", typ: EntrySymlink, target: "../../outside"}},
+			needSymlinks: true,
+			wantErr:      "pointing outside the destination",
+		},
+		{
+			name:         "symlink_absolute_target",
+			entries:      []testEntry{{name: "l", typ: EntrySymlink, target: "/etc/passwd"}},
+			needSymlinks: true,
+			wantErr:      "absolute target",
+		},
+		{
+			name: "write_through_symlink",
+			entries: []testEntry{
+				{name: "sub/", typ: EntryDir},
+				{name: "l", typ: EntrySymlink, target: "sub"},
+				{name: "l/x.txt", body: "x"},
+			},
+			needSymlinks: true,
+			wantErr:      "through symlink",
+		},
+		{
+			name:      "hardlink_allowed",
+			entries:   []testEntry{{name: "a.txt", body: "a"}, {name: "h", typ: EntryHardlink, target: "a.txt"}},
+			wantFiles: map[string]string{"a.txt": "a", "h": "a"},
+			wantStats: &Stats{Files: 1, Hardlinks: 1, Bytes: 1},
+		},
+		{
+			name:    "hardlink_escaping",
+			entries: []testEntry{{name: "h", typ: EntryHardlink, target: "../a.txt"}},
+			wantErr: "pointing outside the destination",
+		},
+		{
+			name:    "duplicate_entry",
+			entries: []testEntry{{name: "a.txt", body: "a"}, {name: "a.txt", body: "b"}},
+			wantErr: "duplicate archive entry",
+		},
+		{
+			name:      "entry_limit",
+			entries:   []testEntry{{name: "a", body: "a"}, {name: "b", body: "b"}, {name: "c", body: "c"}},
+			limits:    Limits{MaxEntries: 2},
+			wantErrIs: ErrLimitExceeded,
+		},
+		{
+			name:      "zip_bomb_byte_limit",
+			zip:       true,
+			entries:   []testEntry{{name: "bomb.txt", body: strings.Repeat("0", 1<<20)}},
+			limits:    Limits{MaxBytes: 1 << 10},
+			wantErrIs: ErrLimitExceeded,
+		},
+		{
+			name:      "existing_conflict_without_overwrite",
+			entries:   []testEntry{{name: "a.txt", body: "new"}, {name: "b.txt", body: "b"}},
+			existing:  map[string]string{"a.txt": "old"},
+			wantErrIs: os.ErrExist,
+		},
+		{
+			name:      "existing_merge_with_overwrite",
+			entries:   []testEntry{{name: "a.txt", body: "new"}, {name: "b.txt", body: "b"}},
+			existing:  map[string]string{"a.txt": "old", "keep.txt": "keep"},
+			overwrite: true,
+			wantFiles: map[string]string{"a.txt": "new", "b.txt": "b", "keep.txt": "keep"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.needSymlinks && !canSymlink {
+				t.Skip("symlinks not reliably available")
+			}
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "a.tar")
+			format := FormatTar
+			if tc.zip {
+				archive, format = filepath.Join(dir, "a.zip"), FormatZip
+				writeZipArchive(t, archive, tc.entries)
+			} else {
+				writeTarArchive(t, archive, tc.entries)
+			}
+			dest := filepath.Join(dir, "out")
+			for rel, body := range tc.existing {
+				if err := os.MkdirAll(dest, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dest, rel), []byte(body), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stats, err := Extract(t.Context(), mustPolicy(t, dir, tc.blockSymlinks), archive, dest, format,
+				ExtractOptions{Overwrite: tc.overwrite, Limits: tc.limits})
+
+			if tc.wantErr != "" || tc.wantErrIs != nil {
+				if err == nil {
+					t.Fatalf("expected error, got stats %+v", stats)
+				}
+				if tc.wantErr != "" && !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error %q does not contain %q", err, tc.wantErr)
+				}
+				if tc.wantErrIs != nil && !errors.Is(err, tc.wantErrIs) {
+					t.Fatalf("error %v is not %v", err, tc.wantErrIs)
+				}
+				// Nothing is left behind: no staging dir, and dest holds only pre-existing files.
+				items, _ := os.ReadDir(dir)
+				for _, it := range items {
+					if strings.HasPrefix(it.Name(), ".tmp-llmtools-") {
+						t.Fatalf("staging dir left behind: %s", it.Name())
+					}
+				}
+				if _, err := os.Stat(filepath.Join(dir, "evil.txt")); err == nil {
+					t.Fatalf("entry escaped the destination")
+				}
+				for rel, body := range tc.existing {
+					if got, _ := os.ReadFile(filepath.Join(dest, rel)); string(got) != body {
+						t.Fatalf("existing %s changed to %q", rel, got)
+					}
+				}
+				if len(tc.existing) == 0 {
+					if _, err := os.Stat(dest); !errors.Is(err, os.ErrNotExist) {
+						t.Fatalf("dest should not exist after failure: %v", err)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			for rel, want := range tc.wantFiles {
+				got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(rel)))
+				if err != nil || string(got) != want {
+					t.Fatalf("%s: got %q, %v; want %q", rel, got, err, want)
+				}
+			}
+			if tc.wantStats != nil && stats != *tc.wantStats {
+				t.Fatalf("stats = %+v, want %+v", stats, *tc.wantStats)
+			}
+		})
+	}
+}
+
+func TestCreateListExtractRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatZip, FormatTar, FormatTarGz, FormatTarZst} {
+		t.Run(string(format), func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src")
+			if err := os.MkdirAll(filepath.Join(src, "sub", "empty"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(src, "a.txt"), []byte("alpha"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("beta"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			single := filepath.Join(dir, "single.txt")
+			if err := os.WriteFile(single, []byte("one"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			p := mustPolicy(t, dir, true)
+
+			var buf bytes.Buffer
+			stats, err := Create(t.Context(), p, &buf, format,
+				[]Source{{AbsPath: src, Name: "src"}, {AbsPath: single, Name: "single.txt"}}, Limits{})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if want := (Stats{Files: 3, Dirs: 3, Bytes: 12}); stats != want {
+				t.Fatalf("create stats = %+v, want %+v", stats, want)
+			}
+			archive := filepath.Join(dir, "out."+string(format))
+			if err := os.WriteFile(archive, buf.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, lstats, err := List(t.Context(), vfs.OS(), archive, format, Limits{})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if lstats != stats || len(entries) != 6 {
+				t.Fatalf("list stats = %+v (%d entries), want %+v", lstats, len(entries), stats)
+			}
+			if _, _, err := List(t.Context(), vfs.OS(), archive, format, Limits{MaxEntries: 2}); !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("expected entry limit error, got %v", err)
+			}
+
+			dest := filepath.Join(dir, "dest")
+			if _, err := Extract(t.Context(), p, archive, dest, format, ExtractOptions{}); err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			for rel, want := range map[string]string{"src/a.txt": "alpha", "src/sub/b.txt": "beta", "single.txt": "one"} {
+				got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(rel)))
+				if err != nil || string(got) != want {
+					t.Fatalf("%s: got %q, %v", rel, got, err)
+				}
+			}
+			if st, err := os.Stat(filepath.Join(dest, "src", "sub", "empty")); err != nil || !st.IsDir() {
+				t.Fatalf("empty dir not restored: %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateLimits(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(src, n), []byte("0123456789"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := mustPolicy(t, dir, true)
+	for _, l := range []Limits{{MaxEntries: 2}, {MaxBytes: 15}} {
+		var buf bytes.Buffer
+		if _, err := Create(t.Context(), p, &buf, FormatTar, []Source{{AbsPath: src, Name: "src"}}, l); !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("limits %+v: expected ErrLimitExceeded, got %v", l, err)
+		}
+	}
+}
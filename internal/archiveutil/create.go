@@ -0,0 +1,256 @@
+package archiveutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Source is a file or directory to add to an archive.
+type Source struct {
+	AbsPath string // absolute, policy-resolved
+	Name    string // slash-separated name of the source inside the archive
+}
+
+// Create writes an archive of sources to w.
+//
+// Directory sources are walked without following symlinks (ioutil.ListTreeEntries rules: symlinks
+// are refused when the policy blocks them, otherwise stored as symlink entries). Entry count and
+// total file bytes are capped by limits.
+func Create(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	w io.Writer,
+	format Format,
+	sources []Source,
+	limits Limits,
+) (Stats, error) {
+	aw, err := newArchiveWriter(w, format)
+	if err != nil {
+		return Stats{}, err
+	}
+	c := &creator{ctx: ctx, fsys: p.FS(), aw: aw, limits: limits}
+	for _, src := range sources {
+		if err := c.addSource(p, src); err != nil {
+			_ = aw.Close()
+			return Stats{}, err
+		}
+	}
+	if err := aw.Close(); err != nil {
+		return Stats{}, err
+	}
+	return c.stats, nil
+}
+
+type creator struct {
+	ctx    context.Context
+	fsys   vfs.FS
+	aw     archiveWriter
+	limits Limits
+	stats  Stats
+}
+
+func (c *creator) addSource(p fspolicy.FSPolicy, src Source) error {
+	st, err := c.fsys.Lstat(src.AbsPath)
+	if err != nil {
+		return err
+	}
+	if st.Mode()&os.ModeSymlink != 0 {
+		if p.BlockSymlinks() {
+			return fmt.Errorf("%w: refusing to operate on symlink: %s", fspolicy.ErrSymlinkDisallowed, src.AbsPath)
+		}
+		target, err := c.fsys.Readlink(src.AbsPath)
+		if err != nil {
+			return err
+		}
+		return c.add(src.Name, src.AbsPath, st, target)
+	}
+	if !st.IsDir() {
+		return c.add(src.Name, src.AbsPath, st, "")
+	}
+	if err := c.add(src.Name, src.AbsPath, st, ""); err != nil {
+		return err
+	}
+
+	maxEntries := 0
+	if c.limits.MaxEntries > 0 {
+		maxEntries = c.limits.MaxEntries - c.stats.entries()
+	}
+	entries, err := ioutil.ListTreeEntries(c.ctx, p, src.AbsPath, maxEntries)
+	if err != nil {
+		if errors.Is(err, ioutil.ErrTreeLimitExceeded) {
+			return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, c.limits.MaxEntries)
+		}
+		return err
+	}
+	for _, e := range entries {
+		abs := filepath.Join(src.AbsPath, filepath.FromSlash(e.RelPath))
+		info, err := c.fsys.Lstat(abs)
+		if err != nil {
+			return err
+		}
+		if err := c.add(path.Join(src.Name, e.RelPath), abs, info, e.LinkTarget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *creator) add(name, abs string, info fs.FileInfo, linkTarget string) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	e := Entry{Name: name, Mode: fmt.Sprintf("%04o", info.Mode().Perm()), LinkTarget: linkTarget}
+	mt := info.ModTime().UTC()
+	e.ModTime = &mt
+	switch {
+	case linkTarget != "":
+		e.Type = EntrySymlink
+	case info.IsDir():
+		e.Type = EntryDir
+	case info.Mode().IsRegular():
+		e.Type, e.Size = EntryFile, info.Size()
+	default:
+		return fmt.Errorf("refusing to archive non-regular file: %s", abs)
+	}
+
+	countEntry(&c.stats, e.Type)
+	if c.limits.MaxEntries > 0 && c.stats.entries() > c.limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, c.limits.MaxEntries)
+	}
+	if e.Type != EntryFile {
+		return c.aw.add(e, info.Mode().Perm(), nil)
+	}
+	c.stats.Bytes += e.Size
+	if c.limits.MaxBytes > 0 && c.stats.Bytes > c.limits.MaxBytes {
+		return fmt.Errorf("%w: more than %d uncompressed bytes", ErrLimitExceeded, c.limits.MaxBytes)
+	}
+	f, err := c.fsys.Open(abs)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// Never write more than the header declares, even if the file grows meanwhile.
+	return c.aw.add(e, info.Mode().Perm(), io.LimitReader(f, e.Size))
+}
+
+type archiveWriter interface {
+	add(e Entry, perm fs.FileMode, r io.Reader) error
+	Close() error
+}
+
+func newArchiveWriter(w io.Writer, format Format) (archiveWriter, error) {
+	switch format {
+	case FormatZip:
+		return &zipArchiveWriter{zw: zip.NewWriter(w)}, nil
+	case FormatTar:
+		return &tarArchiveWriter{tw: tar.NewWriter(w)}, nil
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarArchiveWriter{tw: tar.NewWriter(gz), compressor: gz}, nil
+	case FormatTarZst:
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &tarArchiveWriter{tw: tar.NewWriter(zw), compressor: zw}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+}
+
+type tarArchiveWriter struct {
+	tw         *tar.Writer
+	compressor io.WriteCloser
+}
+
+func (t *tarArchiveWriter) add(e Entry, perm fs.FileMode, r io.Reader) error {
+	h := &tar.Header{
+		Name:    e.Name,
+		Mode:    int64(perm),
+		ModTime: *e.ModTime,
+		Format:  tar.FormatPAX,
+	}
+	switch e.Type {
+	case EntryDir:
+		h.Typeflag, h.Name = tar.TypeDir, e.Name+"/"
+	case EntrySymlink:
+		h.Typeflag, h.Linkname = tar.TypeSymlink, e.LinkTarget
+	default:
+		h.Typeflag, h.Size = tar.TypeReg, e.Size
+	}
+	if err := t.tw.WriteHeader(h); err != nil {
+		return err
+	}
+	if r != nil {
+		n, err := io.Copy(t.tw, r)
+		if err != nil {
+			return err
+		}
+		if n != e.Size {
+			return fmt.Errorf("file changed while archiving: %s", e.Name)
+		}
+	}
+	return nil
+}
+
+func (t *tarArchiveWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+	if t.compressor != nil {
+		return t.compressor.Close()
+	}
+	return nil
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipArchiveWriter) add(e Entry, perm fs.FileMode, r io.Reader) error {
+	h := &zip.FileHeader{Name: e.Name, Modified: *e.ModTime, Method: zip.Deflate}
+	switch e.Type {
+	case EntryDir:
+		h.Name, h.Method = e.Name+"/", zip.Store
+		h.SetMode(fs.ModeDir | perm)
+	case EntrySymlink:
+		h.Method = zip.Store
+		h.SetMode(fs.ModeSymlink | perm)
+	default:
+		h.SetMode(perm)
+	}
+	fw, err := z.zw.CreateHeader(h)
+	if err != nil {
+		return err
+	}
+	switch e.Type {
+	case EntrySymlink:
+		_, err = io.WriteString(fw, e.LinkTarget)
+		return err
+	case EntryFile:
+		n, err := io.Copy(fw, r)
+		if err != nil {
+			return err
+		}
+		if n != e.Size {
+			return fmt.Errorf("file changed while archiving: %s", e.Name)
+		}
+	}
+	return nil
+}
+
+func (z *zipArchiveWriter) Close() error { return z.zw.Close() }
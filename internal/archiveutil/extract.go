@@ -0,0 +1,323 @@
+package archiveutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/quota"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// ExtractOptions controls Extract.
+type ExtractOptions struct {
+	// Overwrite allows replacing existing files in dest. Without it, any conflict fails the
+	// extraction before dest is touched.
+	Overwrite bool
+	Limits    Limits
+}
+
+// Extract unpacks archivePath into dest (absolute, policy-resolved; its parent must exist).
+//
+// Hardening:
+//   - Entry names are sanitized (SanitizeEntryName); nothing is written outside dest.
+//   - Symlink and hardlink entries fail the extraction when the policy blocks symlinks. Otherwise
+//     their targets must stay inside dest, and no entry may be written through a symlink.
+//   - Entry count and actual (not declared) uncompressed bytes are capped by opts.Limits.
+//   - Setuid/setgid/sticky bits are dropped.
+//
+// The archive is unpacked into a staging directory next to dest first, so a failing archive leaves dest
+// untouched; the staged tree is then renamed into place (or merged into an existing dest).
+func Extract(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	archivePath, dest string,
+	format Format,
+	opts ExtractOptions,
+) (Stats, error) {
+	fsys := p.FS()
+	if st, err := fsys.Lstat(dest); err == nil && (!st.IsDir() || st.Mode()&os.ModeSymlink != 0) {
+		return Stats{}, fmt.Errorf("destination exists and is not a directory: %s", dest)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Stats{}, err
+	}
+
+	staging, err := fsys.MkdirTemp(filepath.Dir(dest), ".tmp-llmtools-extract-*")
+	if err != nil {
+		return Stats{}, err
+	}
+	x := &extractor{
+		fsys:       fsys,
+		staging:    staging,
+		allowLinks: !p.BlockSymlinks(),
+		limits:     opts.Limits,
+		quota:      p.Quota(),
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = fsys.RemoveAll(staging)
+			x.refund()
+		}
+	}()
+	if err := forEachEntry(ctx, fsys, archivePath, format, x.entry); err != nil {
+		return Stats{}, err
+	}
+
+	if _, err := fsys.Lstat(dest); errors.Is(err, os.ErrNotExist) {
+		if err := fsys.Rename(staging, dest); err != nil {
+			return Stats{}, err
+		}
+		committed = true
+		return x.stats, nil
+	}
+	if !opts.Overwrite {
+		if err := checkNoConflicts(ctx, fsys, staging, dest); err != nil {
+			return Stats{}, err
+		}
+	}
+	// Limits and the write quota were enforced while staging.
+	if _, _, err := ioutil.MovePathResolved(ctx, p.WithQuota(nil), staging, dest, true, ioutil.TreeLimits{}); err != nil {
+		return Stats{}, err
+	}
+	committed = true
+	return x.stats, nil
+}
+
+type extractor struct {
+	fsys       vfs.FS
+	staging    string
+	allowLinks bool
+	limits     Limits
+	quota      *quota.Quota
+	stats      Stats
+
+	// charged and created are what this extraction charged to quota, refunded if it fails.
+	charged int64
+	created int64
+}
+
+func (x *extractor) refund() {
+	x.quota.Refund(x.charged)
+	for ; x.created > 0; x.created-- {
+		x.quota.ReleaseFile()
+	}
+}
+
+func (x *extractor) entry(e Entry, r io.Reader) error {
+	rel, err := SanitizeEntryName(e.Name)
+	if err != nil {
+		return err
+	}
+	if rel == "" {
+		return nil // the root directory itself
+	}
+	if x.limits.MaxEntries > 0 && x.stats.entries()+1 > x.limits.MaxEntries {
+		return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, x.limits.MaxEntries)
+	}
+	dst := filepath.Join(x.staging, filepath.FromSlash(rel))
+
+	switch e.Type {
+	case EntryDir:
+		if err := x.mkdirAll(rel); err != nil {
+			return err
+		}
+	case EntryFile:
+		if err := x.mkdirAll(path.Dir(rel)); err != nil {
+			return err
+		}
+		if err := x.writeFile(dst, e, r); err != nil {
+			return err
+		}
+	case EntrySymlink:
+		if err := x.checkLink(e, rel); err != nil {
+			return err
+		}
+		target := filepath.FromSlash(e.LinkTarget)
+		if !filepath.IsLocal(filepath.Join(filepath.Dir(filepath.FromSlash(rel)), target)) {
+			return fmt.Errorf("refusing symlink %q pointing outside the destination: %q", e.Name, e.LinkTarget)
+		}
+		if err := x.mkdirAll(path.Dir(rel)); err != nil {
+			return err
+		}
+		if err := x.fsys.Symlink(target, dst); err != nil {
+			return err
+		}
+	case EntryHardlink:
+		if err := x.checkLink(e, rel); err != nil {
+			return err
+		}
+		targetRel, err := SanitizeEntryName(e.LinkTarget)
+		if err != nil || targetRel == "" {
+			return fmt.Errorf("refusing hardlink %q pointing outside the destination: %q", e.Name, e.LinkTarget)
+		}
+		if err := x.checkNoSymlinkComponents(targetRel); err != nil {
+			return err
+		}
+		target := filepath.Join(x.staging, filepath.FromSlash(targetRel))
+		if st, err := x.fsys.Lstat(target); err != nil || !st.Mode().IsRegular() {
+			return fmt.Errorf("hardlink %q must point to a regular file extracted earlier: %q", e.Name, e.LinkTarget)
+		}
+		if err := x.mkdirAll(path.Dir(rel)); err != nil {
+			return err
+		}
+		if err := x.fsys.Link(target, dst); err != nil {
+			return err
+		}
+	}
+	if e.Type != EntryDir { // mkdirAll counts directories, including implied parents
+		countEntry(&x.stats, e.Type)
+	}
+	return nil
+}
+
+func (x *extractor) checkLink(e Entry, rel string) error {
+	if !x.allowLinks {
+		return fmt.Errorf("%w: archive contains %s entry %q", fspolicy.ErrSymlinkDisallowed, e.Type, e.Name)
+	}
+	if e.LinkTarget == "" {
+		return fmt.Errorf("%s entry %q has no target", e.Type, e.Name)
+	}
+	if filepath.IsAbs(e.LinkTarget) || strings.HasPrefix(e.LinkTarget, "/") {
+		return fmt.Errorf("refusing %s %q with absolute target %q", e.Type, rel, e.LinkTarget)
+	}
+	return nil
+}
+
+func (x *extractor) writeFile(dst string, e Entry, r io.Reader) error {
+	remaining := int64(-1)
+	if x.limits.MaxBytes > 0 {
+		remaining = x.limits.MaxBytes - x.stats.Bytes
+		if e.Size > remaining {
+			return fmt.Errorf("%w: more than %d uncompressed bytes", ErrLimitExceeded, x.limits.MaxBytes)
+		}
+	}
+	perm := fs.FileMode(0o644)
+	if m, err := strconv.ParseUint(e.Mode, 8, 32); err == nil && m != 0 {
+		perm = fs.FileMode(m) & fs.ModePerm
+	}
+
+	// Every extracted file counts as created; the declared size is checked before writing.
+	if err := x.quota.Check(0, e.Size); err != nil {
+		return err
+	}
+	if err := x.quota.CreateFile(); err != nil {
+		return err
+	}
+	x.created++
+	// O_EXCL: the staging dir is fresh, so an existing path means a duplicate entry (or a link placed by one).
+	f, err := x.fsys.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm|0o200)
+	if err != nil {
+		x.quota.ReleaseFile()
+		x.created--
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("duplicate archive entry %q", e.Name)
+		}
+		return err
+	}
+	defer f.Close()
+
+	if remaining >= 0 {
+		r = io.LimitReader(r, remaining+1)
+	}
+	mr := ioutil.MeteredReader(r, x.quota, 0)
+	n, err := io.Copy(f, mr)
+	x.charged += mr.Charged()
+	x.stats.Bytes += n
+	if err != nil {
+		return err
+	}
+	if x.limits.MaxBytes > 0 && x.stats.Bytes > x.limits.MaxBytes {
+		return fmt.Errorf("%w: more than %d uncompressed bytes", ErrLimitExceeded, x.limits.MaxBytes)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if perm&0o200 == 0 {
+		return x.fsys.Chmod(dst, perm)
+	}
+	return nil
+}
+
+// mkdirAll creates rel (slash-separated, under staging) one component at a time, refusing to
+// traverse symlinks created by earlier entries.
+func (x *extractor) mkdirAll(rel string) error {
+	if rel == "." || rel == "" {
+		return nil
+	}
+	cur := x.staging
+	for part := range strings.SplitSeq(rel, "/") {
+		cur = filepath.Join(cur, part)
+		st, err := x.fsys.Lstat(cur)
+		switch {
+		case err == nil && st.Mode()&os.ModeSymlink != 0:
+			return fmt.Errorf("refusing to extract through symlink %q", part)
+		case err == nil && !st.IsDir():
+			return fmt.Errorf("archive entry conflicts with file %q", part)
+		case err == nil:
+			continue
+		case !errors.Is(err, os.ErrNotExist):
+			return err
+		}
+		if err := x.fsys.Mkdir(cur, 0o755); err != nil {
+			return err
+		}
+		x.stats.Dirs++
+	}
+	return nil
+}
+
+// checkNoSymlinkComponents verifies no component of rel (including the last) is a symlink.
+func (x *extractor) checkNoSymlinkComponents(rel string) error {
+	cur := x.staging
+	for part := range strings.SplitSeq(rel, "/") {
+		cur = filepath.Join(cur, part)
+		if st, err := x.fsys.Lstat(cur); err == nil && st.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("refusing to extract through symlink %q", part)
+		}
+	}
+	return nil
+}
+
+// checkNoConflicts fails if any staged non-directory already exists in dest, or a staged
+// directory exists there as a non-directory.
+func checkNoConflicts(ctx context.Context, fsys vfs.FS, staging, dest string) error {
+	return vfs.WalkDir(fsys, staging, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p == staging {
+			return nil
+		}
+		rel, err := filepath.Rel(staging, p)
+		if err != nil {
+			return err
+		}
+		st, err := fsys.Lstat(filepath.Join(dest, rel))
+		if errors.Is(err, os.ErrNotExist) {
+			if d.IsDir() {
+				return filepath.SkipDir // nothing below can conflict
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && st.IsDir() && st.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+		return fmt.Errorf("%s already exists in destination: %w", filepath.ToSlash(rel), os.ErrExist)
+	})
+}
@@ -0,0 +1,125 @@
+// Package archiveutil lists, extracts and creates zip and tar (plain, gzip, zstd) archives with
+// sandbox hardening: entry names are confined to the destination, links are policy-checked and
+// entry-count/size caps bound extraction.
+package archiveutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/flexigpt/llmtools-go/vfs"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrLimitExceeded indicates an archive has more entries or uncompressed bytes than allowed.
+var ErrLimitExceeded = errors.New("archive exceeds limits")
+
+type Format string
+
+const (
+	FormatZip    Format = "zip"
+	FormatTar    Format = "tar"
+	FormatTarGz  Format = "tar.gz"
+	FormatTarZst Format = "tar.zst"
+)
+
+// Limits bounds archive operations. Zero values mean "no limit".
+type Limits struct {
+	MaxEntries int
+	MaxBytes   int64 // total uncompressed bytes of file entries
+}
+
+type EntryType string
+
+const (
+	EntryFile     EntryType = "file"
+	EntryDir      EntryType = "dir"
+	EntrySymlink  EntryType = "symlink"
+	EntryHardlink EntryType = "hardlink"
+)
+
+// Entry describes one archive member.
+type Entry struct {
+	Name       string     `json:"name"` // as stored in the archive
+	Type       EntryType  `json:"type"`
+	Size       int64      `json:"size,omitempty"` // declared uncompressed size (files only)
+	Mode       string     `json:"mode,omitempty"` // permission bits in octal, e.g. "0644"
+	ModTime    *time.Time `json:"modTime,omitempty"`
+	LinkTarget string     `json:"linkTarget,omitempty"`
+}
+
+// Stats summarizes the entries of an archive operation.
+type Stats struct {
+	Files     int   `json:"files"`
+	Dirs      int   `json:"dirs"`
+	Symlinks  int   `json:"symlinks,omitempty"`
+	Hardlinks int   `json:"hardlinks,omitempty"`
+	Bytes     int64 `json:"bytes"` // uncompressed bytes of file entries
+}
+
+func (s Stats) entries() int { return s.Files + s.Dirs + s.Symlinks + s.Hardlinks }
+
+// ParseFormat parses a format name. Aliases: "tgz" for tar.gz and "tzst" for tar.zst.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "zip":
+		return FormatZip, nil
+	case "tar":
+		return FormatTar, nil
+	case "tar.gz", "tgz":
+		return FormatTarGz, nil
+	case "tar.zst", "tzst":
+		return FormatTarZst, nil
+	default:
+		return "", fmt.Errorf("unsupported archive format %q (use zip, tar, tar.gz or tar.zst)", s)
+	}
+}
+
+// FormatFromName infers the format from a file name's extension.
+func FormatFromName(name string) (Format, bool) {
+	n := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(n, ".zip"):
+		return FormatZip, true
+	case strings.HasSuffix(n, ".tar.gz"), strings.HasSuffix(n, ".tgz"):
+		return FormatTarGz, true
+	case strings.HasSuffix(n, ".tar.zst"), strings.HasSuffix(n, ".tzst"):
+		return FormatTarZst, true
+	case strings.HasSuffix(n, ".tar"):
+		return FormatTar, true
+	}
+	return "", false
+}
+
+// DetectFormat infers the format of an existing archive from its name, falling back to magic bytes.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func DetectFormat(fsys vfs.FS, path string) (Format, error) {
+	if f, ok := FormatFromName(path); ok {
+		return f, nil
+	}
+	fh, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(fh, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return FormatZip, nil
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return FormatTarGz, nil
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return FormatTarZst, nil
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return FormatTar, nil
+	}
+	return "", fmt.Errorf("cannot determine archive format of %s; pass format explicitly", path)
+}
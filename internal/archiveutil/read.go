@@ -0,0 +1,225 @@
+package archiveutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+	"github.com/klauspost/compress/zstd"
+)
+
+// entryFunc is called for each archive member. r yields the content of file entries.
+type entryFunc func(e Entry, r io.Reader) error
+
+// List returns the archive's entries without extracting anything.
+// Only MaxEntries is enforced; sizes are as declared by the archive.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func List(ctx context.Context, fsys vfs.FS, archivePath string, format Format, limits Limits) ([]Entry, Stats, error) {
+	var (
+		entries []Entry
+		stats   Stats
+	)
+	err := forEachEntry(ctx, fsys, archivePath, format, func(e Entry, _ io.Reader) error {
+		entries = append(entries, e)
+		countEntry(&stats, e.Type)
+		if e.Type == EntryFile {
+			stats.Bytes += e.Size
+		}
+		if limits.MaxEntries > 0 && stats.entries() > limits.MaxEntries {
+			return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, limits.MaxEntries)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, Stats{}, err
+	}
+	return entries, stats, nil
+}
+
+// SanitizeEntryName converts an archive member name into a slash-separated path that is local to
+// the extraction root. Backslashes are treated as separators. Absolute names, drive letters and
+// names escaping the root via ".." are rejected. The root itself ("." or "/"-only names after
+// cleaning) yields "".
+func SanitizeEntryName(name string) (string, error) {
+	n := strings.ReplaceAll(name, "\\", "/")
+	if strings.ContainsRune(n, 0) {
+		return "", fmt.Errorf("invalid archive entry name %q", name)
+	}
+	if strings.HasPrefix(n, "/") || (len(n) >= 2 && n[1] == ':') {
+		return "", fmt.Errorf("refusing absolute archive entry name %q", name)
+	}
+	n = path.Clean(n)
+	if n == "." {
+		return "", nil
+	}
+	if n == ".." || strings.HasPrefix(n, "../") || !filepath.IsLocal(filepath.FromSlash(n)) {
+		return "", fmt.Errorf("refusing archive entry outside the destination: %q", name)
+	}
+	return n, nil
+}
+
+func countEntry(s *Stats, t EntryType) {
+	switch t {
+	case EntryFile:
+		s.Files++
+	case EntryDir:
+		s.Dirs++
+	case EntrySymlink:
+		s.Symlinks++
+	case EntryHardlink:
+		s.Hardlinks++
+	}
+}
+
+func forEachEntry(ctx context.Context, fsys vfs.FS, archivePath string, format Format, fn entryFunc) error {
+	if format == FormatZip {
+		return forEachZipEntry(ctx, fsys, archivePath, fn)
+	}
+
+	f, err := fsys.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch format {
+	case FormatTar:
+	case FormatTarGz:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case FormatTarZst:
+		zr, err := zstd.NewReader(f, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return fmt.Errorf("unsupported archive format %q", format)
+	}
+	return forEachTarEntry(ctx, tar.NewReader(r), fn)
+}
+
+func forEachTarEntry(ctx context.Context, tr *tar.Reader, fn entryFunc) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e := Entry{
+			Name:       h.Name,
+			Mode:       fmt.Sprintf("%04o", h.FileInfo().Mode().Perm()),
+			LinkTarget: h.Linkname,
+		}
+		if !h.ModTime.IsZero() {
+			mt := h.ModTime.UTC()
+			e.ModTime = &mt
+		}
+		switch h.Typeflag {
+		case tar.TypeReg, tar.TypeRegA: //nolint:staticcheck // TypeRegA still appears in old archives.
+			e.Type, e.Size = EntryFile, h.Size
+		case tar.TypeDir:
+			e.Type = EntryDir
+		case tar.TypeSymlink:
+			e.Type = EntrySymlink
+		case tar.TypeLink:
+			e.Type = EntryHardlink
+		case tar.TypeXGlobalHeader:
+			continue
+		default:
+			return fmt.Errorf("unsupported tar entry type %q for %q", h.Typeflag, h.Name)
+		}
+		if err := fn(e, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func forEachZipEntry(ctx context.Context, fsys vfs.FS, archivePath string, fn entryFunc) error {
+	zr, closer, err := ioutil.OpenZip(fsys, archivePath)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	for _, zf := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		mode := zf.Mode()
+		e := Entry{Name: zf.Name, Mode: fmt.Sprintf("%04o", mode.Perm())}
+		if mt := zf.Modified; !mt.IsZero() {
+			mt = mt.UTC()
+			e.ModTime = &mt
+		}
+		switch {
+		case mode&fs.ModeSymlink != 0:
+			e.Type = EntrySymlink
+			target, err := readZipSymlink(zf)
+			if err != nil {
+				return err
+			}
+			e.LinkTarget = target
+		case mode.IsDir() || strings.HasSuffix(zf.Name, "/"):
+			e.Type = EntryDir
+		case mode.IsRegular():
+			e.Type, e.Size = EntryFile, int64(zf.UncompressedSize64) //nolint:gosec // Sizes are capped while copying.
+		default:
+			return fmt.Errorf("unsupported zip entry type %v for %q", mode.Type(), zf.Name)
+		}
+		if err := forZipEntryContent(zf, e, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func forZipEntryContent(zf *zip.File, e Entry, fn entryFunc) error {
+	if e.Type != EntryFile {
+		return fn(e, nil)
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return fn(e, rc)
+}
+
+// readZipSymlink reads a zip symlink's target, which is stored as the entry content.
+func readZipSymlink(zf *zip.File) (string, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, 4096+1))
+	if err != nil {
+		return "", err
+	}
+	if len(b) > 4096 {
+		return "", fmt.Errorf("symlink target too long in %q", zf.Name)
+	}
+	return string(b), nil
+}
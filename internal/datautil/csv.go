@@ -0,0 +1,82 @@
+package datautil
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// scanCSV streams CSV/TSV records into c. Records may have varying field counts; fields beyond the
+// header get generated names.
+func scanCSV(ctx context.Context, r io.Reader, opts Options, c *collector) error {
+	cr := csv.NewReader(r)
+	cr.Comma = ','
+	if opts.Format == FormatTSV {
+		cr.Comma = '\t'
+	}
+	if opts.Delimiter != 0 {
+		cr.Comma = opts.Delimiter
+	}
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+
+	var cols []int
+	header := !opts.NoHeader
+	values := make([]any, 0, 16)
+	for {
+		if c.rows%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", opts.Format, err)
+		}
+		if len(cols) == 0 && len(rec) > 0 {
+			rec[0] = strings.TrimPrefix(rec[0], "\ufeff")
+		}
+		if header {
+			header = false
+			cols = csvHeader(c, rec)
+			continue
+		}
+		for i := len(cols); i < len(rec); i++ {
+			cols = append(cols, c.column(generatedColumnName(i)))
+		}
+		values = values[:0]
+		for _, f := range rec {
+			values = append(values, f)
+		}
+		c.add(cols[:len(rec)], values, fieldType)
+	}
+}
+
+// csvHeader registers the header's column names, naming blank ones by position and suffixing duplicates.
+func csvHeader(c *collector, rec []string) []int {
+	cols := make([]int, len(rec))
+	used := make(map[string]bool, len(rec))
+	for i, name := range rec {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = generatedColumnName(i)
+		}
+		base := name
+		for n := 2; used[name]; n++ {
+			name = base + "_" + strconv.Itoa(n)
+		}
+		used[name] = true
+		cols[i] = c.column(name)
+	}
+	return cols
+}
+
+func generatedColumnName(i int) string { return "column_" + strconv.Itoa(i+1) }
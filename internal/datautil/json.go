@@ -0,0 +1,190 @@
+package datautil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// valueColumn holds rows that are not JSON objects.
+const valueColumn = "value"
+
+// scanJSONL streams one JSON value per line into c; blank lines are skipped.
+func scanJSONL(ctx context.Context, r io.Reader, c *collector) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for line := 1; sc.Scan(); line++ {
+		if c.rows%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		b := bytes.TrimSpace(sc.Bytes())
+		if line == 1 {
+			b = bytes.TrimPrefix(b, []byte("\ufeff"))
+		}
+		if len(b) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		if err := addJSONRow(dec, c); err != nil {
+			return fmt.Errorf("invalid JSON on line %d: %w", line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("JSONL line longer than %d bytes", maxLineBytes)
+		}
+		return err
+	}
+	return nil
+}
+
+// scanJSON streams the elements of a top-level JSON array into c. A top-level object is a single row.
+func scanJSON(ctx context.Context, r io.Reader, c *collector) error {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(3); err == nil && string(b) == "\ufeff" {
+		_, _ = br.Discard(3)
+	}
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	switch tok {
+	case json.Delim('['):
+		for dec.More() {
+			if c.rows%1024 == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			if err := addJSONRow(dec, c); err != nil {
+				return fmt.Errorf("invalid JSON in element %d: %w", c.rows+1, err)
+			}
+		}
+		return nil
+	case json.Delim('{'):
+		keys, values, err := decodeObjectBody(dec)
+		if err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		c.add(jsonColumns(c, keys), values, jsonType)
+		return nil
+	}
+	return errors.New("JSON data must be an array of records or an object")
+}
+
+// addJSONRow decodes the next value from dec as one row: objects map keys to columns, other values
+// go to the "value" column.
+func addJSONRow(dec *json.Decoder, c *collector) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == json.Delim('{') {
+		keys, values, err := decodeObjectBody(dec)
+		if err != nil {
+			return err
+		}
+		c.add(jsonColumns(c, keys), values, jsonType)
+		return nil
+	}
+	v, err := decodeAfterToken(dec, tok)
+	if err != nil {
+		return err
+	}
+	c.add([]int{c.column(valueColumn)}, []any{v}, jsonType)
+	return nil
+}
+
+// decodeObjectBody reads an object's members in document order; the opening brace is already consumed.
+func decodeObjectBody(dec *json.Decoder) (keys []string, values []any, err error) {
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, nil, errors.New("expected object key")
+		}
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, v)
+	}
+	if _, err := dec.Token(); err != nil { // closing brace
+		return nil, nil, err
+	}
+	return keys, values, nil
+}
+
+// decodeAfterToken completes a value whose first token was already read.
+func decodeAfterToken(dec *json.Decoder, tok json.Token) (any, error) {
+	if tok != json.Delim('[') {
+		return tok, nil
+	}
+	var arr []any
+	for dec.More() {
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if arr == nil {
+		arr = []any{}
+	}
+	return arr, nil
+}
+
+func jsonColumns(c *collector, keys []string) []int {
+	cols := make([]int, len(keys))
+	for i, k := range keys {
+		cols[i] = c.column(k)
+	}
+	return cols
+}
+
+// jsonType returns the type of a decoded JSON value ("" for null).
+func jsonType(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		return TypeBoolean
+	case json.Number:
+		if strings.ContainsAny(string(x), ".eE") {
+			return TypeNumber
+		}
+		return TypeInteger
+	case string:
+		return TypeString
+	case map[string]any:
+		return TypeObject
+	case []any:
+		return TypeArray
+	}
+	return TypeMixed
+}
+
+func compactJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,520 @@
+package datautil
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Parquet previews start from the footer: the FileMetaData structure (Thrift compact protocol) gives the
+// schema, the exact row count and per-column-chunk null counts. Head and sample rows are then decoded from
+// the column pages (see parquet_rows.go).
+
+const (
+	parquetMagic = "PAR1"
+	// maxParquetFooterBytes bounds the metadata read from the end of the file.
+	maxParquetFooterBytes = 64 * 1024 * 1024
+	// maxThriftDepth bounds struct/list nesting while parsing the footer.
+	maxThriftDepth = 64
+)
+
+// TypeBinary is reported for Parquet byte-array columns without a string or other logical annotation.
+const TypeBinary = "binary"
+
+var errBadParquet = errors.New("not a valid Parquet file")
+
+type parquetSchemaElement struct {
+	name          string
+	physical      int32 // -1 for groups
+	typeLength    int32 // FIXED_LEN_BYTE_ARRAY width
+	repetition    int32 // 0 required, 1 optional, 2 repeated
+	convertedType int32 // -1 when absent
+	logicalType   int16 // LogicalType union member id, 0 when absent
+	timeUnit      int16 // TIMESTAMP logical type unit: 1 millis, 2 micros, 3 nanos
+	scale         int32 // DECIMAL scale
+	numChildren   int32
+}
+
+type parquetMeta struct {
+	numRows int64
+	schema  []parquetSchemaElement
+	// nulls sums Statistics.null_count per dotted column path; unknown marks columns where any
+	// column chunk lacks the statistic.
+	nulls     map[string]int64
+	unknown   map[string]bool
+	rowGroups []parquetRowGroup
+}
+
+type parquetRowGroup struct {
+	numRows int64
+	chunks  []parquetChunk
+}
+
+// parquetChunk locates one column chunk (ColumnMetaData) of a row group.
+type parquetChunk struct {
+	path       string
+	codec      int32
+	numValues  int64
+	size       int64 // total_compressed_size, page headers included
+	dataOffset int64
+	dictOffset int64 // 0 when absent
+}
+
+func previewParquet(ctx context.Context, r io.ReaderAt, size int64, opts Options) (*Preview, error) {
+	if size < 12 {
+		return nil, errBadParquet
+	}
+	tail := make([]byte, 8)
+	if _, err := r.ReadAt(tail, size-8); err != nil {
+		return nil, err
+	}
+	head := make([]byte, 4)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return nil, err
+	}
+	if string(tail[4:]) == "PARE" {
+		return nil, fmt.Errorf("%w: encrypted Parquet footers are not supported", ErrUnsupportedFormat)
+	}
+	if string(head) != parquetMagic || string(tail[4:]) != parquetMagic {
+		return nil, errBadParquet
+	}
+	metaLen := int64(binary.LittleEndian.Uint32(tail[:4]))
+	if metaLen > size-12 || metaLen > maxParquetFooterBytes {
+		return nil, fmt.Errorf("%w: footer length %d out of range", errBadParquet, metaLen)
+	}
+	buf := make([]byte, metaLen)
+	if _, err := r.ReadAt(buf, size-8-metaLen); err != nil {
+		return nil, err
+	}
+
+	meta := &parquetMeta{nulls: make(map[string]int64), unknown: make(map[string]bool)}
+	tr := &thriftReader{b: buf}
+	if err := tr.readStruct(meta.field); err != nil {
+		return nil, fmt.Errorf("%w: %w", errBadParquet, err)
+	}
+	if len(meta.schema) == 0 {
+		return nil, fmt.Errorf("%w: missing schema", errBadParquet)
+	}
+
+	out := &Preview{
+		Format:        FormatParquet,
+		RowCount:      meta.numRows,
+		RowCountExact: true,
+		Notes:         []string{"nullCount is -1 where the file has no statistics."},
+	}
+	var leaves []parquetLeaf
+	idx := 1
+	var walk func(prefix string, n int32, depth, def int, repeated bool) error
+	walk = func(prefix string, n int32, depth, def int, repeated bool) error {
+		if depth > maxThriftDepth {
+			return fmt.Errorf("%w: schema nested too deeply", errBadParquet)
+		}
+		for range n {
+			if idx >= len(meta.schema) {
+				return fmt.Errorf("%w: schema children exceed elements", errBadParquet)
+			}
+			el := meta.schema[idx]
+			idx++
+			name := el.name
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			d, rep := def, repeated || el.repetition == 2
+			if el.repetition != 0 {
+				d++
+			}
+			if el.numChildren > 0 {
+				if err := walk(name, el.numChildren, depth+1, d, rep); err != nil {
+					return err
+				}
+				continue
+			}
+			if len(out.Columns) >= maxColumns {
+				continue
+			}
+			nulls := meta.nulls[name]
+			if meta.unknown[name] {
+				nulls = -1
+			}
+			out.Columns = append(out.Columns, ColumnInfo{Name: name, Type: el.valueType(), NullCount: nulls})
+			leaves = append(leaves, parquetLeaf{el: el, path: name, maxDef: d, repeated: rep})
+		}
+		return nil
+	}
+	if err := walk("", meta.schema[0].numChildren, 0, 0, false); err != nil {
+		return nil, err
+	}
+
+	// Rows are best-effort: the footer summary stands even when the pages cannot be decoded.
+	if err := decodeParquetRows(ctx, r, meta, leaves, opts, out); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		out.Notes = append(out.Notes, "rows could not be decoded: "+err.Error())
+	}
+	return out, nil
+}
+
+// valueType maps Parquet physical, converted and logical types onto the preview type names.
+func (e parquetSchemaElement) valueType() string {
+	switch e.logicalType {
+	case 1, 4, 12, 14: // STRING, ENUM, JSON, UUID
+		return TypeString
+	case 5: // DECIMAL
+		return TypeNumber
+	case 6: // DATE
+		return TypeDate
+	case 8: // TIMESTAMP
+		return TypeDateTime
+	case 10: // INTEGER
+		return TypeInteger
+	}
+	switch e.convertedType {
+	case 0, 4, 19: // UTF8, ENUM, JSON
+		return TypeString
+	case 5: // DECIMAL
+		return TypeNumber
+	case 6: // DATE
+		return TypeDate
+	case 9, 10: // TIMESTAMP_MILLIS, TIMESTAMP_MICROS
+		return TypeDateTime
+	}
+	switch e.physical {
+	case 0:
+		return TypeBoolean
+	case 1, 2:
+		return TypeInteger
+	case 3: // INT96, the legacy timestamp encoding
+		return TypeDateTime
+	case 4, 5:
+		return TypeNumber
+	}
+	return TypeBinary
+}
+
+// field handles one FileMetaData field.
+func (m *parquetMeta) field(tr *thriftReader, id int16, typ byte) error {
+	switch {
+	case id == 2 && typ == thriftList: // schema
+		return tr.readList(func(tr *thriftReader) error {
+			el := parquetSchemaElement{physical: -1, convertedType: -1}
+			err := tr.readStruct(func(tr *thriftReader, id int16, typ byte) error {
+				switch {
+				case id == 1 && typ == thriftI32:
+					el.physical = int32(tr.varint())
+				case id == 2 && typ == thriftI32:
+					el.typeLength = int32(tr.varint())
+				case id == 3 && typ == thriftI32:
+					el.repetition = int32(tr.varint())
+				case id == 4 && typ == thriftBinary:
+					el.name = string(tr.binary())
+				case id == 5 && typ == thriftI32:
+					el.numChildren = int32(tr.varint())
+				case id == 6 && typ == thriftI32:
+					el.convertedType = int32(tr.varint())
+				case id == 7 && typ == thriftI32:
+					el.scale = int32(tr.varint())
+				case id == 10 && typ == thriftStruct:
+					// LogicalType is a union: the id of its single set member names the type.
+					return tr.readStruct(func(tr *thriftReader, id int16, typ byte) error {
+						el.logicalType = id
+						if typ != thriftStruct || (id != 5 && id != 8) {
+							return tr.skip(typ)
+						}
+						// DECIMAL {1: scale} and TIMESTAMP {2: unit, a union of empty structs}.
+						return tr.readStruct(func(tr *thriftReader, fid int16, typ byte) error {
+							switch {
+							case id == 5 && fid == 1 && typ == thriftI32:
+								el.scale = int32(tr.varint())
+								return tr.err
+							case id == 8 && fid == 2 && typ == thriftStruct:
+								return tr.readStruct(func(tr *thriftReader, unit int16, typ byte) error {
+									el.timeUnit = unit
+									return tr.skip(typ)
+								})
+							}
+							return tr.skip(typ)
+						})
+					})
+				default:
+					return tr.skip(typ)
+				}
+				return tr.err
+			})
+			m.schema = append(m.schema, el)
+			return err
+		})
+	case id == 3 && typ == thriftI64:
+		m.numRows = tr.varint()
+		return tr.err
+	case id == 4 && typ == thriftList: // row_groups
+		return tr.readList(func(tr *thriftReader) error {
+			var rg parquetRowGroup
+			err := tr.readStruct(func(tr *thriftReader, id int16, typ byte) error {
+				switch {
+				case id == 1 && typ == thriftList: // columns
+					return tr.readList(func(tr *thriftReader) error {
+						ch, err := m.readColumnChunk(tr)
+						rg.chunks = append(rg.chunks, ch)
+						return err
+					})
+				case id == 3 && typ == thriftI64:
+					rg.numRows = tr.varint()
+					return tr.err
+				}
+				return tr.skip(typ)
+			})
+			m.rowGroups = append(m.rowGroups, rg)
+			return err
+		})
+	}
+	return tr.skip(typ)
+}
+
+// readColumnChunk reads the location of a column chunk and accumulates its
+// meta_data.statistics.null_count by column path.
+func (m *parquetMeta) readColumnChunk(tr *thriftReader) (parquetChunk, error) {
+	var ch parquetChunk
+	err := tr.readStruct(func(tr *thriftReader, id int16, typ byte) error {
+		if id != 3 || typ != thriftStruct { // meta_data
+			return tr.skip(typ)
+		}
+		var path []string
+		nulls, hasNulls := int64(0), false
+		err := tr.readStruct(func(tr *thriftReader, id int16, typ byte) error {
+			switch {
+			case id == 3 && typ == thriftList: // path_in_schema
+				return tr.readList(func(tr *thriftReader) error {
+					path = append(path, string(tr.binary()))
+					return tr.err
+				})
+			case id == 4 && typ == thriftI32:
+				ch.codec = int32(tr.varint())
+				return tr.err
+			case id == 5 && typ == thriftI64:
+				ch.numValues = tr.varint()
+				return tr.err
+			case id == 7 && typ == thriftI64:
+				ch.size = tr.varint()
+				return tr.err
+			case id == 9 && typ == thriftI64:
+				ch.dataOffset = tr.varint()
+				return tr.err
+			case id == 11 && typ == thriftI64:
+				ch.dictOffset = tr.varint()
+				return tr.err
+			case id == 12 && typ == thriftStruct: // statistics
+				return tr.readStruct(func(tr *thriftReader, id int16, typ byte) error {
+					if id == 3 && typ == thriftI64 {
+						nulls, hasNulls = tr.varint(), true
+						return tr.err
+					}
+					return tr.skip(typ)
+				})
+			}
+			return tr.skip(typ)
+		})
+		if err != nil {
+			return err
+		}
+		ch.path = strings.Join(path, ".")
+		if hasNulls {
+			m.nulls[ch.path] += nulls
+		} else {
+			m.unknown[ch.path] = true
+		}
+		return nil
+	})
+	return ch, err
+}
+
+// Thrift compact protocol type ids.
+const (
+	thriftBoolTrue  = 1
+	thriftBoolFalse = 2
+	thriftByte      = 3
+	thriftI16       = 4
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftDouble    = 7
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftSet       = 10
+	thriftMap       = 11
+	thriftStruct    = 12
+)
+
+var errThriftEOF = errors.New("truncated metadata")
+
+// thriftReader decodes the subset of the Thrift compact protocol needed for Parquet metadata.
+// The first decoding error is sticky: later reads return zero values and tr.err stays set.
+type thriftReader struct {
+	b     []byte
+	pos   int
+	depth int
+	err   error
+}
+
+func (tr *thriftReader) fail(err error) {
+	if tr.err == nil {
+		tr.err = err
+	}
+}
+
+func (tr *thriftReader) byte() byte {
+	if tr.err != nil || tr.pos >= len(tr.b) {
+		tr.fail(errThriftEOF)
+		return 0
+	}
+	c := tr.b[tr.pos]
+	tr.pos++
+	return c
+}
+
+func (tr *thriftReader) uvarint() uint64 {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		c := tr.byte()
+		v |= uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return v
+		}
+	}
+	tr.fail(errors.New("varint overflow"))
+	return 0
+}
+
+// varint reads a zigzag-encoded integer (i16, i32 and i64 share this encoding).
+func (tr *thriftReader) varint() int64 {
+	u := tr.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (tr *thriftReader) binary() []byte {
+	n := tr.uvarint()
+	if tr.err != nil {
+		return nil
+	}
+	if n > uint64(len(tr.b)-tr.pos) {
+		tr.fail(errThriftEOF)
+		return nil
+	}
+	b := tr.b[tr.pos : tr.pos+int(n)]
+	tr.pos += int(n)
+	return b
+}
+
+func (tr *thriftReader) enter() error {
+	tr.depth++
+	if tr.depth > maxThriftDepth {
+		tr.fail(errors.New("metadata nested too deeply"))
+	}
+	return tr.err
+}
+
+// readStruct calls fn for each field; fn must consume the field's value (tr.skip for unknown fields).
+func (tr *thriftReader) readStruct(fn func(tr *thriftReader, id int16, typ byte) error) error {
+	if err := tr.enter(); err != nil {
+		return err
+	}
+	defer func() { tr.depth-- }()
+	var last int16
+	for {
+		h := tr.byte()
+		if tr.err != nil {
+			return tr.err
+		}
+		if h == 0 { // stop
+			return nil
+		}
+		typ := h & 0x0f
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(tr.varint())
+		}
+		last = id
+		if err := fn(tr, id, typ); err != nil {
+			return err
+		}
+		if tr.err != nil {
+			return tr.err
+		}
+	}
+}
+
+// readList calls fn for each element of a list or set.
+func (tr *thriftReader) readList(fn func(tr *thriftReader) error) error {
+	if err := tr.enter(); err != nil {
+		return err
+	}
+	defer func() { tr.depth-- }()
+	h := tr.byte()
+	n := uint64(h >> 4)
+	if n == 15 {
+		n = tr.uvarint()
+	}
+	// Every element takes at least one byte.
+	if tr.err == nil && n > uint64(len(tr.b)-tr.pos) {
+		tr.fail(errThriftEOF)
+	}
+	for i := uint64(0); i < n && tr.err == nil; i++ {
+		if err := fn(tr); err != nil {
+			return err
+		}
+	}
+	return tr.err
+}
+
+// skip consumes a value of type typ. Booleans in struct fields carry their value in the type id and
+// take no bytes.
+func (tr *thriftReader) skip(typ byte) error {
+	switch typ {
+	case thriftBoolTrue, thriftBoolFalse:
+	case thriftByte:
+		tr.byte()
+	case thriftI16, thriftI32, thriftI64:
+		tr.uvarint()
+	case thriftDouble:
+		for range 8 {
+			tr.byte()
+		}
+	case thriftBinary:
+		tr.binary()
+	case thriftList, thriftSet:
+		pos := tr.pos
+		h := tr.byte()
+		tr.pos = pos
+		et := h & 0x0f
+		return tr.readList(func(tr *thriftReader) error { return tr.skipElem(et) })
+	case thriftMap:
+		n := tr.uvarint()
+		if n == 0 {
+			return tr.err
+		}
+		kv := tr.byte()
+		if tr.err == nil && n > uint64(len(tr.b)-tr.pos) {
+			tr.fail(errThriftEOF)
+		}
+		for i := uint64(0); i < n && tr.err == nil; i++ {
+			_ = tr.skipElem(kv >> 4)
+			_ = tr.skipElem(kv & 0x0f)
+		}
+	case thriftStruct:
+		return tr.readStruct(func(tr *thriftReader, _ int16, typ byte) error { return tr.skip(typ) })
+	default:
+		tr.fail(fmt.Errorf("unknown thrift type %d", typ))
+	}
+	return tr.err
+}
+
+// skipElem consumes a list, set or map element; unlike struct fields, boolean elements take one byte.
+func (tr *thriftReader) skipElem(typ byte) error {
+	if typ == thriftBoolTrue || typ == thriftBoolFalse {
+		tr.byte()
+		return tr.err
+	}
+	return tr.skip(typ)
+}
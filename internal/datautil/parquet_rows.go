@@ -0,0 +1,744 @@
+package datautil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/bits"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Parquet row decoding covers flat columns (no repeated field on the path) stored in data pages v1 or v2
+// with PLAIN, dictionary or (for booleans) RLE encoding, uncompressed or compressed with Snappy, gzip or
+// Zstandard. Columns it cannot decode stay null in the preview rows and are named in a note.
+//
+// Head and sample rows come from the first row group and from as many following row groups as fit in the
+// scan budget; pages holding none of the wanted rows are skipped without being decompressed.
+
+const (
+	// maxParquetPageBytes bounds a single page, compressed or decompressed.
+	maxParquetPageBytes = 64 * 1024 * 1024
+	// maxParquetPageHeaderBytes bounds a page header (large ones carry statistics).
+	maxParquetPageHeaderBytes = 16 * 1024 * 1024
+)
+
+// Parquet page types.
+const (
+	parquetDataPage       = 0
+	parquetDictionaryPage = 2
+	parquetDataPageV2     = 3
+)
+
+// Parquet encodings.
+const (
+	parquetPlain           = 0
+	parquetPlainDictionary = 2
+	parquetRLE             = 3
+	parquetRLEDictionary   = 8
+)
+
+var errBadParquetPage = errors.New("malformed Parquet page")
+
+// parquetLeaf is a leaf column of the schema together with its maximum definition level.
+type parquetLeaf struct {
+	el       parquetSchemaElement
+	path     string
+	maxDef   int
+	repeated bool // a repeated field on the path: values do not map one-to-one onto rows
+}
+
+type parquetPageHeader struct {
+	typ              int32
+	uncompressedSize int32
+	compressedSize   int32
+	numValues        int32
+	encoding         int32
+	defLevelsLen     int32 // v2: byte length of the uncompressed definition levels
+	repLevelsLen     int32 // v2: byte length of the uncompressed repetition levels
+	compressed       bool  // v2: whether the values section is compressed
+}
+
+// decodeParquetRows fills out.Head, out.Sample and out.ScannedRows.
+func decodeParquetRows(
+	ctx context.Context,
+	r io.ReaderAt,
+	meta *parquetMeta,
+	leaves []parquetLeaf,
+	opts Options,
+	out *Preview,
+) error {
+	scan := opts.ScanBytes
+	if scan <= 0 {
+		scan = defaultScanBytes
+	}
+	// Always decode the first row group; add more while their chunks fit in the scan budget.
+	groups, rows, scanned := 0, int64(0), int64(0)
+	for i, rg := range meta.rowGroups {
+		var size int64
+		for _, ch := range rg.chunks {
+			size += ch.size
+		}
+		if i > 0 && scanned+size > scan {
+			break
+		}
+		if rg.numRows < 0 {
+			return fmt.Errorf("%w: negative row count", errBadParquet)
+		}
+		scanned += size
+		rows += rg.numRows
+		groups++
+	}
+	out.ScannedRows = rows
+	if groups < len(meta.rowGroups) {
+		out.Notes = append(out.Notes, fmt.Sprintf(
+			"head and sample rows come from the first %d of %d row groups (%d rows)", groups, len(meta.rowGroups), rows,
+		))
+	}
+
+	head := min(int64(clampRows(opts.HeadRows)), rows)
+	var sample []int64
+	// The sample only adds information when rows were skipped.
+	if n := int64(clampRows(opts.SampleRows)); n > 0 && rows > head {
+		sample = sampleIndexes(rows, min(n, rows))
+	}
+	want := make([]int64, 0, head+int64(len(sample)))
+	for i := range head {
+		want = append(want, i)
+	}
+	want = append(want, sample...)
+	slices.Sort(want)
+	want = slices.Compact(want)
+	if len(want) == 0 {
+		return nil
+	}
+	values := make(map[int64][]any, len(want))
+	for _, row := range want {
+		values[row] = make([]any, len(leaves))
+	}
+
+	d := &parquetDecoder{r: r}
+	defer d.close()
+	skipped := make(map[string]string)
+	base := int64(0)
+	for _, rg := range meta.rowGroups[:groups] {
+		lo, _ := slices.BinarySearch(want, base)
+		hi, _ := slices.BinarySearch(want, base+rg.numRows)
+		if lo < hi {
+			chunks := make(map[string]parquetChunk, len(rg.chunks))
+			for _, ch := range rg.chunks {
+				chunks[ch.path] = ch
+			}
+			for col, leaf := range leaves {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if _, done := skipped[leaf.path]; done {
+					continue
+				}
+				ch, ok := chunks[leaf.path]
+				switch {
+				case leaf.repeated:
+					skipped[leaf.path] = "repeated field"
+					continue
+				case !ok:
+					skipped[leaf.path] = "no column chunk"
+					continue
+				}
+				err := d.column(ctx, leaf, ch, base, want[lo:hi], func(row int64, v any) {
+					values[row][col] = previewValue(parquetCell(leaf.el, v))
+				})
+				if err != nil {
+					if ctx.Err() != nil {
+						return err
+					}
+					skipped[leaf.path] = err.Error()
+				}
+			}
+		}
+		base += rg.numRows
+	}
+
+	for _, row := range want[:head] {
+		out.Head = append(out.Head, values[row])
+	}
+	for _, row := range sample {
+		out.Sample = append(out.Sample, values[row])
+	}
+	if len(skipped) == len(leaves) {
+		for _, leaf := range leaves {
+			return fmt.Errorf("%s: %s", leaf.path, skipped[leaf.path])
+		}
+	}
+	if len(skipped) > 0 {
+		var parts []string
+		for _, leaf := range leaves {
+			if reason, ok := skipped[leaf.path]; ok {
+				parts = append(parts, leaf.path+" ("+reason+")")
+			}
+		}
+		out.Notes = append(out.Notes, "columns left null in head and sample rows: "+strings.Join(parts, "; "))
+	}
+	return nil
+}
+
+// sampleIndexes picks k distinct row indexes below n (Floyd's algorithm) with a fixed seed, sorted.
+func sampleIndexes(n, k int64) []int64 {
+	rng := rand.New(rand.NewPCG(1, 2)) //nolint:gosec // Sampling, not security.
+	chosen := make(map[int64]bool, k)
+	out := make([]int64, 0, k)
+	for j := n - k; j < n; j++ {
+		t := rng.Int64N(j + 1)
+		if chosen[t] {
+			t = j
+		}
+		chosen[t] = true
+		out = append(out, t)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// parquetDecoder reads column chunks; it keeps a Zstandard decoder across pages.
+type parquetDecoder struct {
+	r    io.ReaderAt
+	zstd *zstd.Decoder
+}
+
+func (d *parquetDecoder) close() {
+	if d.zstd != nil {
+		d.zstd.Close()
+	}
+}
+
+// column decodes the values of the chunk's rows listed in want (sorted, relative to the file) and passes
+// each to set. Rows of the chunk start at base.
+func (d *parquetDecoder) column(
+	ctx context.Context,
+	leaf parquetLeaf,
+	ch parquetChunk,
+	base int64,
+	want []int64,
+	set func(row int64, v any),
+) error {
+	off := ch.dataOffset
+	if ch.dictOffset > 0 && ch.dictOffset < off {
+		off = ch.dictOffset
+	}
+	end := off + ch.size
+	if off < 4 || ch.size <= 0 || end < off {
+		return fmt.Errorf("%w: bad column chunk location", errBadParquetPage)
+	}
+	var dict []any
+	row := base
+	for len(want) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if off >= end {
+			return fmt.Errorf("%w: column chunk ends before row %d", errBadParquetPage, want[0])
+		}
+		h, n, err := d.pageHeader(off, end)
+		if err != nil {
+			return err
+		}
+		off += n
+		if h.compressedSize < 0 || h.compressedSize > maxParquetPageBytes ||
+			h.uncompressedSize < 0 || h.uncompressedSize > maxParquetPageBytes ||
+			int64(h.compressedSize) > end-off || h.numValues < 0 {
+			return fmt.Errorf("%w: page size out of range", errBadParquetPage)
+		}
+		body := off
+		off += int64(h.compressedSize)
+
+		switch h.typ {
+		case parquetDictionaryPage:
+			page, err := d.read(body, h.compressedSize)
+			if err != nil {
+				return err
+			}
+			if page, err = d.decompress(ch.codec, page, h.uncompressedSize); err != nil {
+				return err
+			}
+			pr := &plainReader{b: page, el: leaf.el}
+			dict = make([]any, 0, min(int(h.numValues), len(page)))
+			for range h.numValues {
+				v, err := pr.next()
+				if err != nil {
+					return err
+				}
+				dict = append(dict, v)
+			}
+			continue
+		case parquetDataPage, parquetDataPageV2:
+		default:
+			continue // index pages
+		}
+		// Without repetition levels every value is one row.
+		if want[0] >= row+int64(h.numValues) {
+			row += int64(h.numValues)
+			continue
+		}
+		page, err := d.read(body, h.compressedSize)
+		if err != nil {
+			return err
+		}
+		var defs *rleDecoder
+		var vals []byte
+		if h.typ == parquetDataPage {
+			if page, err = d.decompress(ch.codec, page, h.uncompressedSize); err != nil {
+				return err
+			}
+			if leaf.maxDef > 0 {
+				if len(page) < 4 {
+					return errBadParquetPage
+				}
+				n := binary.LittleEndian.Uint32(page)
+				if uint64(n) > uint64(len(page)-4) {
+					return errBadParquetPage
+				}
+				defs = &rleDecoder{b: page[4 : 4+n], width: bits.Len(uint(leaf.maxDef))}
+				page = page[4+n:]
+			}
+			vals = page
+		} else {
+			levels := int64(h.defLevelsLen) + int64(h.repLevelsLen)
+			if h.defLevelsLen < 0 || h.repLevelsLen < 0 || levels > int64(len(page)) {
+				return errBadParquetPage
+			}
+			if leaf.maxDef > 0 {
+				defs = &rleDecoder{b: page[h.repLevelsLen:levels], width: bits.Len(uint(leaf.maxDef))}
+			}
+			vals = page[levels:]
+			if h.compressed {
+				size := h.uncompressedSize - int32(levels)
+				if vals, err = d.decompress(ch.codec, vals, size); err != nil {
+					return err
+				}
+			}
+		}
+
+		next, err := valueReader(leaf.el, h.encoding, vals, dict)
+		if err != nil {
+			return err
+		}
+		for range h.numValues {
+			if len(want) == 0 {
+				break
+			}
+			null := false
+			if defs != nil {
+				def, err := defs.next()
+				if err != nil {
+					return err
+				}
+				null = def < uint64(leaf.maxDef)
+			}
+			var v any
+			if !null {
+				if v, err = next(); err != nil {
+					return err
+				}
+			}
+			if want[0] == row {
+				set(row, v)
+				want = want[1:]
+			}
+			row++
+		}
+	}
+	return nil
+}
+
+func (d *parquetDecoder) read(off int64, n int32) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := d.r.ReadAt(b, off); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// pageHeader parses the PageHeader at off, reading a growing window until the struct fits.
+func (d *parquetDecoder) pageHeader(off, end int64) (parquetPageHeader, int64, error) {
+	for window := int64(4096); ; window *= 8 {
+		n := min(window, end-off, maxParquetPageHeaderBytes)
+		buf := make([]byte, n)
+		if m, err := d.r.ReadAt(buf, off); err != nil && (!errors.Is(err, io.EOF) || int64(m) < n) {
+			return parquetPageHeader{}, 0, err
+		}
+		h, pos, err := parsePageHeader(buf)
+		if err == nil {
+			return h, int64(pos), nil
+		}
+		if !errors.Is(err, errThriftEOF) || n == end-off || n == maxParquetPageHeaderBytes {
+			return parquetPageHeader{}, 0, fmt.Errorf("%w: %w", errBadParquetPage, err)
+		}
+	}
+}
+
+func parsePageHeader(b []byte) (parquetPageHeader, int, error) {
+	h := parquetPageHeader{typ: -1, compressed: true}
+	tr := &thriftReader{b: b}
+	// DataPageHeader {1: num_values, 2: encoding}; DictionaryPageHeader {1: num_values, 2: encoding};
+	// DataPageHeaderV2 {1: num_values, 4: encoding, 5: def levels length, 6: rep levels length,
+	// 7: is_compressed}.
+	sub := func(v2 bool) error {
+		return tr.readStruct(func(tr *thriftReader, id int16, typ byte) error {
+			switch {
+			case id == 1 && typ == thriftI32:
+				h.numValues = int32(tr.varint())
+			case id == 2 && typ == thriftI32 && !v2, id == 4 && typ == thriftI32 && v2:
+				h.encoding = int32(tr.varint())
+			case id == 5 && typ == thriftI32 && v2:
+				h.defLevelsLen = int32(tr.varint())
+			case id == 6 && typ == thriftI32 && v2:
+				h.repLevelsLen = int32(tr.varint())
+			case id == 7 && v2 && (typ == thriftBoolTrue || typ == thriftBoolFalse):
+				h.compressed = typ == thriftBoolTrue
+			default:
+				return tr.skip(typ)
+			}
+			return tr.err
+		})
+	}
+	err := tr.readStruct(func(tr *thriftReader, id int16, typ byte) error {
+		switch {
+		case id == 1 && typ == thriftI32:
+			h.typ = int32(tr.varint())
+		case id == 2 && typ == thriftI32:
+			h.uncompressedSize = int32(tr.varint())
+		case id == 3 && typ == thriftI32:
+			h.compressedSize = int32(tr.varint())
+		case (id == 5 || id == 7) && typ == thriftStruct:
+			return sub(false)
+		case id == 8 && typ == thriftStruct:
+			return sub(true)
+		default:
+			return tr.skip(typ)
+		}
+		return tr.err
+	})
+	return h, tr.pos, err
+}
+
+func (d *parquetDecoder) decompress(codec int32, src []byte, size int32) ([]byte, error) {
+	if size < 0 || size > maxParquetPageBytes {
+		return nil, errBadParquetPage
+	}
+	switch codec {
+	case 0: // UNCOMPRESSED
+		return src, nil
+	case 1: // SNAPPY
+		n, err := snappy.DecodedLen(src)
+		if err != nil {
+			return nil, err
+		}
+		if n > maxParquetPageBytes {
+			return nil, errBadParquetPage
+		}
+		return snappy.Decode(nil, src)
+	case 2: // GZIP
+		zr, err := gzip.NewReader(bytes.NewReader(src))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		b, err := io.ReadAll(io.LimitReader(zr, maxParquetPageBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > maxParquetPageBytes {
+			return nil, errBadParquetPage
+		}
+		return b, nil
+	case 6: // ZSTD
+		if d.zstd == nil {
+			zd, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxParquetPageBytes))
+			if err != nil {
+				return nil, err
+			}
+			d.zstd = zd
+		}
+		return d.zstd.DecodeAll(src, make([]byte, 0, size))
+	}
+	name := map[int32]string{3: "LZO", 4: "BROTLI", 5: "LZ4", 7: "LZ4_RAW"}[codec]
+	if name == "" {
+		name = strconv.Itoa(int(codec))
+	}
+	return nil, fmt.Errorf("unsupported compression codec %s", name)
+}
+
+// valueReader returns a function yielding the page's non-null values in order.
+func valueReader(el parquetSchemaElement, encoding int32, b []byte, dict []any) (func() (any, error), error) {
+	switch encoding {
+	case parquetPlain:
+		return (&plainReader{b: b, el: el}).next, nil
+	case parquetPlainDictionary, parquetRLEDictionary:
+		if dict == nil {
+			return nil, fmt.Errorf("%w: dictionary page missing", errBadParquetPage)
+		}
+		if len(b) == 0 {
+			return nil, errBadParquetPage
+		}
+		idx := &rleDecoder{b: b[1:], width: int(b[0])}
+		return func() (any, error) {
+			i, err := idx.next()
+			if err != nil {
+				return nil, err
+			}
+			if i >= uint64(len(dict)) {
+				return nil, fmt.Errorf("%w: dictionary index out of range", errBadParquetPage)
+			}
+			return dict[i], nil
+		}, nil
+	case parquetRLE:
+		if el.physical != 0 {
+			break
+		}
+		// RLE-encoded booleans carry a 4-byte length prefix.
+		if len(b) < 4 || uint64(binary.LittleEndian.Uint32(b)) > uint64(len(b)-4) {
+			return nil, errBadParquetPage
+		}
+		dec := &rleDecoder{b: b[4 : 4+binary.LittleEndian.Uint32(b)], width: 1}
+		return func() (any, error) {
+			v, err := dec.next()
+			return v == 1, err
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %d", encoding)
+}
+
+// plainReader decodes PLAIN-encoded values into bool, int32, int64, float32, float64 or []byte.
+type plainReader struct {
+	b      []byte
+	pos    int
+	bitPos int // booleans are bit-packed, least significant bit first
+	el     parquetSchemaElement
+}
+
+func (p *plainReader) take(n int) ([]byte, error) {
+	if n < 0 || n > len(p.b)-p.pos {
+		return nil, fmt.Errorf("%w: values end early", errBadParquetPage)
+	}
+	b := p.b[p.pos : p.pos+n]
+	p.pos += n
+	return b, nil
+}
+
+func (p *plainReader) next() (any, error) {
+	switch p.el.physical {
+	case 0: // BOOLEAN
+		if p.bitPos/8 >= len(p.b) {
+			return nil, fmt.Errorf("%w: values end early", errBadParquetPage)
+		}
+		v := p.b[p.bitPos/8]>>(p.bitPos%8)&1 == 1
+		p.bitPos++
+		return v, nil
+	case 1: // INT32
+		b, err := p.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return int32(binary.LittleEndian.Uint32(b)), nil
+	case 2: // INT64
+		b, err := p.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return int64(binary.LittleEndian.Uint64(b)), nil
+	case 3: // INT96
+		return p.take(12)
+	case 4: // FLOAT
+		b, err := p.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case 5: // DOUBLE
+		b, err := p.take(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case 6: // BYTE_ARRAY
+		b, err := p.take(4)
+		if err != nil {
+			return nil, err
+		}
+		return p.take(int(binary.LittleEndian.Uint32(b)))
+	case 7: // FIXED_LEN_BYTE_ARRAY
+		return p.take(int(p.el.typeLength))
+	}
+	return nil, fmt.Errorf("unsupported physical type %d", p.el.physical)
+}
+
+// rleDecoder reads the RLE/bit-packing hybrid encoding used for levels and dictionary indexes.
+type rleDecoder struct {
+	b      []byte
+	pos    int
+	width  int
+	run    uint64 // values left in the current RLE run
+	val    uint64
+	packed int // values left in the current bit-packed run
+	bitPos int
+}
+
+func (d *rleDecoder) next() (uint64, error) {
+	if d.width < 0 || d.width > 32 {
+		return 0, fmt.Errorf("%w: bit width %d", errBadParquetPage, d.width)
+	}
+	for d.run == 0 && d.packed == 0 {
+		h, n := binary.Uvarint(d.b[d.pos:])
+		if n <= 0 {
+			return 0, fmt.Errorf("%w: levels end early", errBadParquetPage)
+		}
+		d.pos += n
+		if h&1 == 0 {
+			w := (d.width + 7) / 8
+			if w > len(d.b)-d.pos {
+				return 0, fmt.Errorf("%w: levels end early", errBadParquetPage)
+			}
+			d.run, d.val = h>>1, 0
+			for i := range w {
+				d.val |= uint64(d.b[d.pos+i]) << (8 * i)
+			}
+			d.pos += w
+			continue
+		}
+		// Groups of 8 values; the last group may be cut short at the end of the data.
+		groups := min(h>>1, uint64(len(d.b)))
+		d.packed = int(groups) * 8
+		d.bitPos = d.pos * 8
+		d.pos = min(d.pos+int(groups)*d.width, len(d.b))
+	}
+	if d.run > 0 {
+		d.run--
+		return d.val, nil
+	}
+	d.packed--
+	var v uint64
+	for i := range d.width {
+		bit := d.bitPos + i
+		if bit/8 >= len(d.b) {
+			return 0, fmt.Errorf("%w: levels end early", errBadParquetPage)
+		}
+		v |= uint64(d.b[bit/8]>>(bit%8)&1) << i
+	}
+	d.bitPos += d.width
+	return v, nil
+}
+
+// parquetCell converts a decoded physical value into a JSON-friendly preview value following the
+// column's logical or converted type.
+func parquetCell(el parquetSchemaElement, v any) any {
+	decimal := el.logicalType == 5 || el.convertedType == 5
+	switch x := v.(type) {
+	case int32:
+		switch {
+		case el.logicalType == 6 || el.convertedType == 6: // DATE: days since the epoch
+			return time.Unix(int64(x)*86400, 0).UTC().Format(time.DateOnly)
+		case decimal:
+			return decimalNumber(big.NewInt(int64(x)), el.scale)
+		case el.convertedType >= 11 && el.convertedType <= 13: // UINT_8, UINT_16, UINT_32
+			return int64(uint32(x))
+		}
+		return int64(x)
+	case int64:
+		switch {
+		case el.logicalType == 8 || el.convertedType == 9 || el.convertedType == 10:
+			unit := el.timeUnit
+			switch el.convertedType {
+			case 9:
+				unit = 1
+			case 10:
+				unit = 2
+			}
+			var t time.Time
+			switch unit {
+			case 1:
+				t = time.UnixMilli(x)
+			case 2:
+				t = time.UnixMicro(x)
+			case 3:
+				t = time.Unix(0, x)
+			default:
+				return x
+			}
+			return t.UTC().Format(time.RFC3339Nano)
+		case decimal:
+			return decimalNumber(big.NewInt(x), el.scale)
+		case el.convertedType == 14 && x < 0: // UINT_64
+			return json.Number(strconv.FormatUint(uint64(x), 10))
+		}
+		return x
+	case float32:
+		if f := float64(x); math.IsNaN(f) || math.IsInf(f, 0) {
+			return finiteFloat(f)
+		}
+		// Shortest representation at float32 precision: 0.1 rather than 0.10000000149011612.
+		return json.Number(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	case float64:
+		return finiteFloat(x)
+	case []byte:
+		switch {
+		case el.physical == 3 && len(x) == 12: // INT96: nanoseconds of the day, then the Julian day
+			nanos := int64(binary.LittleEndian.Uint64(x))
+			day := int64(int32(binary.LittleEndian.Uint32(x[8:])))
+			return time.Unix((day-2440588)*86400, nanos).UTC().Format(time.RFC3339Nano)
+		case decimal && len(x) <= 64:
+			n := new(big.Int).SetBytes(x)
+			if len(x) > 0 && x[0]&0x80 != 0 { // two's complement
+				n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(x))*8))
+			}
+			return decimalNumber(n, el.scale)
+		case el.logicalType == 14 && len(x) == 16: // UUID
+			h := hex.EncodeToString(x)
+			return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+		case el.valueType() == TypeString:
+			return string(x)
+		}
+		if len(x) > maxCellRunes/2 {
+			return "0x" + hex.EncodeToString(x[:maxCellRunes/2]) + "…"
+		}
+		return "0x" + hex.EncodeToString(x)
+	}
+	return v
+}
+
+// decimalNumber renders unscaled × 10^-scale exactly.
+func decimalNumber(unscaled *big.Int, scale int32) json.Number {
+	s := unscaled.String()
+	if scale <= 0 || scale > 1000 {
+		return json.Number(s)
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if pad := int(scale) + 1 - len(s); pad > 0 {
+		s = strings.Repeat("0", pad) + s
+	}
+	return json.Number(sign + s[:len(s)-int(scale)] + "." + s[len(s)-int(scale):])
+}
+
+// finiteFloat keeps NaN and infinities, which JSON cannot encode, as strings.
+func finiteFloat(f float64) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return f
+}
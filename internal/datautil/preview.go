@@ -0,0 +1,364 @@
+// Package datautil summarizes tabular data files (CSV/TSV, JSON, JSONL and Parquet) without loading them
+// into memory: rows are streamed once to infer column types, count nulls and rows, and keep the first rows
+// plus a reservoir sample.
+package datautil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// Format is a tabular data file format.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatTSV     Format = "tsv"
+	FormatJSON    Format = "json"
+	FormatJSONL   Format = "jsonl"
+	FormatParquet Format = "parquet"
+)
+
+// Column value types reported in ColumnInfo.Type.
+const (
+	TypeNull     = "null" // every value was null or empty
+	TypeBoolean  = "boolean"
+	TypeInteger  = "integer"
+	TypeNumber   = "number"
+	TypeDate     = "date"
+	TypeDateTime = "datetime"
+	TypeString   = "string"
+	TypeObject   = "object"
+	TypeArray    = "array"
+	TypeMixed    = "mixed"
+)
+
+const (
+	// MaxPreviewRows caps Options.HeadRows and Options.SampleRows.
+	MaxPreviewRows = 100
+	// maxColumns bounds the columns tracked; further JSON keys or CSV fields are ignored.
+	maxColumns = 256
+	// maxCellRunes bounds each value returned in preview rows.
+	maxCellRunes = 200
+	// maxLineBytes bounds a single JSONL line.
+	maxLineBytes = 8 * 1024 * 1024
+	// defaultScanBytes bounds the bytes read for statistics; beyond it the row count is estimated.
+	defaultScanBytes = 16 * toolutil.MaxFileReadBytes
+)
+
+// ErrUnsupportedFormat is returned when a file is not a recognized data format.
+var ErrUnsupportedFormat = errors.New("unsupported data format")
+
+// Options controls Preview.
+type Options struct {
+	Format     Format // required
+	HeadRows   int    // rows returned from the start of the file
+	SampleRows int    // rows returned as a uniform random sample of the scanned rows
+	NoHeader   bool   // CSV/TSV: the first record is data; columns are named column_1, column_2, ...
+	Delimiter  rune   // CSV/TSV: overrides the format's default delimiter
+	ScanBytes  int64  // bytes read for statistics; 0 means the default (256MB)
+}
+
+// ColumnInfo describes one column.
+type ColumnInfo struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	NullCount int64  `json:"nullCount"`
+}
+
+// Preview is a schema summary and row preview of a data file.
+type Preview struct {
+	Format        Format       `json:"format"`
+	SizeBytes     int64        `json:"sizeBytes"`
+	Columns       []ColumnInfo `json:"columns"`
+	RowCount      int64        `json:"rowCount"`
+	RowCountExact bool         `json:"rowCountExact"`
+	// ScannedRows is the number of rows the statistics cover (less than RowCount when estimated).
+	ScannedRows int64    `json:"scannedRows"`
+	Head        [][]any  `json:"head,omitempty"`
+	Sample      [][]any  `json:"sample,omitempty"`
+	Notes       []string `json:"notes,omitempty"`
+}
+
+// PreviewSafe summarizes the data file at path with panic recovery.
+//
+// Types are inferred per value: JSON values keep their JSON type (integers and fractional numbers are
+// distinguished); CSV fields are parsed as boolean, integer, number, date (2006-01-02) or RFC 3339 datetime,
+// else string. A column whose values have several types is "number" when they are all numeric, otherwise
+// "string" for CSV and "mixed" for JSON. Empty CSV fields, the literal "null"/"NULL", JSON null and missing
+// JSON keys count as null.
+//
+// Statistics cover at most Options.ScanBytes of input; for larger files RowCount is extrapolated from the
+// bytes per row seen so far and RowCountExact is false. The sample is a reservoir sample of the scanned
+// prefix only, not of the whole file. For Parquet, rows are decoded from the first row group and the
+// following ones that fit in Options.ScanBytes. The sample is drawn with a fixed seed, so repeated
+// previews of an unchanged file are identical.
+func PreviewSafe(ctx context.Context, fsys vfs.FS, path string, opts Options) (*Preview, error) {
+	return toolutil.WithRecoveryResp(func() (*Preview, error) {
+		return preview(ctx, fsys, path, opts)
+	})
+}
+
+func preview(ctx context.Context, fsys vfs.FS, path string, opts Options) (*Preview, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Format == FormatParquet {
+		out, err := previewParquet(ctx, f, st.Size(), opts)
+		if err != nil {
+			return nil, err
+		}
+		out.SizeBytes = st.Size()
+		return out, nil
+	}
+
+	scan := opts.ScanBytes
+	if scan <= 0 {
+		scan = defaultScanBytes
+	}
+	cr := &countingReader{r: io.LimitReader(f, scan)}
+	c := newCollector(opts)
+	switch opts.Format {
+	case FormatCSV, FormatTSV:
+		err = scanCSV(ctx, cr, opts, c)
+	case FormatJSONL:
+		err = scanJSONL(ctx, cr, c)
+	case FormatJSON:
+		err = scanJSON(ctx, cr, c)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, opts.Format)
+	}
+	// A record cut off by the scan limit is not an error.
+	truncated := cr.n >= scan && cr.n < st.Size()
+	if err != nil && (!truncated || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return nil, err
+	}
+
+	out := c.result(opts.Format)
+	out.SizeBytes = st.Size()
+	out.RowCountExact = !truncated
+	if truncated && c.rows > 0 {
+		out.RowCount = int64(float64(c.rows) * float64(st.Size()) / float64(cr.n))
+		out.Notes = append(out.Notes, fmt.Sprintf(
+			"statistics and the sample cover the first %d bytes (%d rows); rowCount is estimated", cr.n, c.rows,
+		))
+	}
+	if c.truncatedColumns {
+		out.Notes = append(out.Notes, fmt.Sprintf("only the first %d columns are reported", maxColumns))
+	}
+	return out, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// collector accumulates column statistics and preview rows.
+type collector struct {
+	head, sample int
+	jsonTypes    bool
+	rng          *rand.Rand
+
+	names            []string
+	index            map[string]int
+	types            []map[string]bool
+	nulls            []int64
+	truncatedColumns bool
+
+	rows       int64
+	headRows   [][]any
+	sampleRows [][]any
+}
+
+func newCollector(opts Options) *collector {
+	return &collector{
+		head:      clampRows(opts.HeadRows),
+		sample:    clampRows(opts.SampleRows),
+		jsonTypes: opts.Format == FormatJSON || opts.Format == FormatJSONL,
+		rng:       rand.New(rand.NewPCG(1, 2)), //nolint:gosec // Sampling, not security.
+		index:     make(map[string]int),
+	}
+}
+
+func clampRows(n int) int { return max(0, min(n, MaxPreviewRows)) }
+
+// column returns the index of column name, adding it if there is room (-1 otherwise).
+func (c *collector) column(name string) int {
+	if i, ok := c.index[name]; ok {
+		return i
+	}
+	if len(c.names) >= maxColumns {
+		c.truncatedColumns = true
+		return -1
+	}
+	c.index[name] = len(c.names)
+	c.names = append(c.names, name)
+	c.types = append(c.types, make(map[string]bool))
+	// Rows seen before this column appeared had no value for it.
+	c.nulls = append(c.nulls, c.rows)
+	return len(c.names) - 1
+}
+
+// add records one row; values[i] belongs to column cols[i] and typ(v) gives a value's type ("" for null).
+func (c *collector) add(cols []int, values []any, typ func(any) string) {
+	seen := make([]bool, len(c.names))
+	for i, col := range cols {
+		if col < 0 {
+			continue
+		}
+		seen[col] = true
+		if t := typ(values[i]); t == "" {
+			c.nulls[col]++
+		} else {
+			c.types[col][t] = true
+		}
+	}
+	for col, ok := range seen {
+		if !ok {
+			c.nulls[col]++
+		}
+	}
+	c.rows++
+
+	if len(c.headRows) < c.head || c.sample > 0 {
+		row := make([]any, len(c.names))
+		for i, col := range cols {
+			if col >= 0 {
+				row[col] = previewValue(values[i])
+			}
+		}
+		if len(c.headRows) < c.head {
+			c.headRows = append(c.headRows, row)
+		}
+		// Reservoir sampling (Algorithm R).
+		if len(c.sampleRows) < c.sample {
+			c.sampleRows = append(c.sampleRows, row)
+		} else if c.sample > 0 {
+			if j := c.rng.Int64N(c.rows); j < int64(c.sample) {
+				c.sampleRows[j] = row
+			}
+		}
+	}
+}
+
+func (c *collector) result(format Format) *Preview {
+	out := &Preview{
+		Format:      format,
+		Columns:     make([]ColumnInfo, len(c.names)),
+		RowCount:    c.rows,
+		ScannedRows: c.rows,
+		Head:        padRows(c.headRows, len(c.names)),
+	}
+	// The sample only adds information when rows were skipped.
+	if c.rows > int64(len(c.headRows)) {
+		out.Sample = padRows(c.sampleRows, len(c.names))
+	}
+	for i, name := range c.names {
+		out.Columns[i] = ColumnInfo{Name: name, Type: mergeTypes(c.types[i], c.jsonTypes), NullCount: c.nulls[i]}
+	}
+	return out
+}
+
+// padRows extends rows recorded before later columns appeared.
+func padRows(rows [][]any, n int) [][]any {
+	for i, r := range rows {
+		if len(r) < n {
+			rows[i] = append(r, make([]any, n-len(r))...)
+		}
+	}
+	return rows
+}
+
+func mergeTypes(seen map[string]bool, jsonTypes bool) string {
+	switch len(seen) {
+	case 0:
+		return TypeNull
+	case 1:
+		for t := range seen {
+			return t
+		}
+	}
+	numeric := true
+	for t := range seen {
+		if t != TypeInteger && t != TypeNumber {
+			numeric = false
+		}
+	}
+	switch {
+	case numeric:
+		return TypeNumber
+	case jsonTypes:
+		return TypeMixed
+	}
+	return TypeString
+}
+
+// previewValue shortens long strings and renders nested JSON compactly.
+func previewValue(v any) any {
+	switch x := v.(type) {
+	case string:
+		return truncateRunes(x)
+	case map[string]any, []any:
+		return truncateRunes(compactJSON(x))
+	}
+	return v
+}
+
+func truncateRunes(s string) string {
+	if utf8.RuneCountInString(s) <= maxCellRunes {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:maxCellRunes]) + "…"
+}
+
+// fieldType infers the type of a CSV field ("" for null).
+func fieldType(v any) string {
+	s := strings.TrimSpace(v.(string))
+	switch {
+	case s == "" || s == "null" || s == "NULL":
+		return ""
+	case s == "true" || s == "false" || s == "TRUE" || s == "FALSE" || s == "True" || s == "False":
+		return TypeBoolean
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return TypeInteger
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return TypeNumber
+	}
+	if _, err := time.Parse(time.DateOnly, s); err == nil {
+		return TypeDate
+	}
+	if _, err := time.Parse(time.RFC3339, s); err == nil {
+		return TypeDateTime
+	}
+	return TypeString
+}
@@ -0,0 +1,554 @@
+package datautil
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+func writeFile(t *testing.T, dir, name string, body []byte) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, body, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return p
+}
+
+func TestPreviewSafe(t *testing.T) {
+	dir := t.TempDir()
+
+	var many strings.Builder
+	many.WriteString("n,sq\n")
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&many, "%d,%d\n", i, i*i)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		opts    Options
+		wantErr string
+		check   func(t *testing.T, p *Preview)
+	}{
+		{
+			name: "csv_types_nulls_and_head",
+			body: "\ufeffid,name,score,active,day,,name\n" +
+				"1,ann,1.5,true,2024-01-02,x,a\n" +
+				"2,,2,false,2024-01-03,,b\n" +
+				"3,NULL,3,TRUE,2024-01-04T10:00:00Z,z,c,extra\n",
+			opts: Options{Format: FormatCSV, HeadRows: 2},
+			check: func(t *testing.T, p *Preview) {
+				t.Helper()
+				want := []ColumnInfo{
+					{Name: "id", Type: TypeInteger},
+					{Name: "name", Type: TypeString, NullCount: 2},
+					{Name: "score", Type: TypeNumber},
+					{Name: "active", Type: TypeBoolean},
+					{Name: "day", Type: TypeString},
+					{Name: "column_6", Type: TypeString, NullCount: 1},
+					{Name: "name_2", Type: TypeString},
+					{Name: "column_8", Type: TypeString, NullCount: 2},
+				}
+				if !reflect.DeepEqual(p.Columns, want) {
+					t.Fatalf("columns = %+v\nwant %+v", p.Columns, want)
+				}
+				if p.RowCount != 3 || !p.RowCountExact || len(p.Head) != 2 || p.Head[0][1] != "ann" || len(p.Head[0]) != 8 {
+					t.Fatalf("unexpected preview: %+v", p)
+				}
+			},
+		},
+		{
+			name: "tsv_without_header",
+			body: "a\t1\nb\t2\n",
+			opts: Options{Format: FormatTSV, NoHeader: true, HeadRows: 5},
+			check: func(t *testing.T, p *Preview) {
+				t.Helper()
+				if len(p.Columns) != 2 || p.Columns[0].Name != "column_1" || p.Columns[1].Type != TypeInteger || p.RowCount != 2 {
+					t.Fatalf("unexpected preview: %+v", p)
+				}
+				if p.Sample != nil {
+					t.Fatalf("sample should be omitted when head covers every row: %+v", p.Sample)
+				}
+			},
+		},
+		{
+			name: "sample_is_deterministic_subset",
+			body: many.String(),
+			opts: Options{Format: FormatCSV, HeadRows: 1, SampleRows: 5},
+			check: func(t *testing.T, p *Preview) {
+				t.Helper()
+				if p.RowCount != 1000 || len(p.Sample) != 5 || p.Columns[1].Type != TypeInteger {
+					t.Fatalf("unexpected preview: %+v", p)
+				}
+				again, err := PreviewSafe(t.Context(), vfs.OS(), filepath.Join(dir, "sample_is_deterministic_subset"), Options{
+					Format: FormatCSV, HeadRows: 1, SampleRows: 5,
+				})
+				if err != nil || !reflect.DeepEqual(again.Sample, p.Sample) {
+					t.Fatalf("sample not deterministic: %v %+v vs %+v", err, again, p.Sample)
+				}
+			},
+		},
+		{
+			name: "scan_limit_estimates_row_count",
+			body: many.String(),
+			opts: Options{Format: FormatCSV, ScanBytes: 1024},
+			check: func(t *testing.T, p *Preview) {
+				t.Helper()
+				if p.RowCountExact || p.ScannedRows >= 1000 || p.RowCount < 500 || p.RowCount > 2000 || len(p.Notes) == 0 {
+					t.Fatalf("unexpected estimate: %+v", p)
+				}
+			},
+		},
+		{
+			name: "jsonl_keys_in_order_with_late_columns",
+			body: `{"id":1,"tags":["a"],"meta":{"k":1}}` + "\n\n" +
+				`{"id":2.5,"extra":null}` + "\n" +
+				`{"id":"x","extra":true}` + "\n",
+			opts: Options{Format: FormatJSONL, HeadRows: 3},
+			check: func(t *testing.T, p *Preview) {
+				t.Helper()
+				want := []ColumnInfo{
+					{Name: "id", Type: TypeMixed},
+					{Name: "tags", Type: TypeArray, NullCount: 2},
+					{Name: "meta", Type: TypeObject, NullCount: 2},
+					{Name: "extra", Type: TypeBoolean, NullCount: 2},
+				}
+				if !reflect.DeepEqual(p.Columns, want) {
+					t.Fatalf("columns = %+v\nwant %+v", p.Columns, want)
+				}
+				if p.Head[0][2] != `{"k":1}` || len(p.Head[0]) != 4 {
+					t.Fatalf("unexpected head: %+v", p.Head)
+				}
+			},
+		},
+		{
+			name: "json_array_of_records_and_scalars",
+			body: `[{"a":1},{"a":2},3]`,
+			opts: Options{Format: FormatJSON},
+			check: func(t *testing.T, p *Preview) {
+				t.Helper()
+				if p.RowCount != 3 || len(p.Columns) != 2 || p.Columns[0].Type != TypeInteger || p.Columns[1].Name != "value" {
+					t.Fatalf("unexpected preview: %+v", p)
+				}
+			},
+		},
+		{
+			name: "json_single_object",
+			body: `{"a":1,"b":"x"}`,
+			opts: Options{Format: FormatJSON},
+			check: func(t *testing.T, p *Preview) {
+				t.Helper()
+				if p.RowCount != 1 || len(p.Columns) != 2 {
+					t.Fatalf("unexpected preview: %+v", p)
+				}
+			},
+		},
+		{name: "json_scalar_rejected", body: `42`, opts: Options{Format: FormatJSON}, wantErr: "array of records"},
+		{name: "jsonl_bad_line", body: "{}\n{bad\n", opts: Options{Format: FormatJSONL}, wantErr: "line 2"},
+		{name: "unknown_format", body: "x", opts: Options{Format: "xml"}, wantErr: "unsupported data format"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := writeFile(t, dir, tc.name, []byte(tc.body))
+			got, err := PreviewSafe(t.Context(), vfs.OS(), p, tc.opts)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got.SizeBytes != int64(len(tc.body)) {
+				t.Fatalf("sizeBytes = %d, want %d", got.SizeBytes, len(tc.body))
+			}
+			tc.check(t, got)
+		})
+	}
+}
+
+func TestPreviewSafe_Canceled(t *testing.T) {
+	p := writeFile(t, t.TempDir(), "a.csv", []byte("a\n1\n"))
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := PreviewSafe(ctx, vfs.OS(), p, Options{Format: FormatCSV}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+// thriftWriter encodes Thrift compact protocol for building Parquet footers in tests.
+type thriftWriter struct {
+	b    []byte
+	last []int16
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := w.last[len(w.last)-1]
+	if d := id - last; d > 0 && d <= 15 {
+		w.b = append(w.b, byte(d)<<4|typ)
+	} else {
+		w.b = append(w.b, typ)
+		w.i64(int64(id))
+	}
+	w.last[len(w.last)-1] = id
+}
+
+func (w *thriftWriter) i64(v int64) { w.b = binary.AppendUvarint(w.b, uint64(v<<1^v>>63)) }
+
+func (w *thriftWriter) str(s string) {
+	w.b = binary.AppendUvarint(w.b, uint64(len(s)))
+	w.b = append(w.b, s...)
+}
+
+func (w *thriftWriter) list(n int, et byte) { w.b = append(w.b, byte(n)<<4|et) }
+
+func (w *thriftWriter) begin() { w.last = append(w.last, 0) }
+
+func (w *thriftWriter) end() {
+	w.b = append(w.b, 0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+// parquetTestRow is row r of the file built by buildParquet: id, name and loc.lat.
+func parquetTestRow(r int) []any {
+	row := []any{int64(r + 1), []string{"ann", "bob", "cy"}[r%3], float64(r) / 2}
+	if r%10 == 3 {
+		row[1] = nil
+	}
+	if r%7 == 5 {
+		row[2] = nil
+	}
+	return row
+}
+
+// hybrid encodes levels or dictionary indexes as one RLE run when they are all equal, else bit-packed.
+func hybrid(vals []uint64, width int) []byte {
+	if len(vals) > 0 && !slices.ContainsFunc(vals, func(v uint64) bool { return v != vals[0] }) {
+		b := binary.AppendUvarint(nil, uint64(len(vals))<<1)
+		for i := range (width + 7) / 8 {
+			b = append(b, byte(vals[0]>>(8*i)))
+		}
+		return b
+	}
+	groups := (len(vals) + 7) / 8
+	b := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	packed := make([]byte, groups*width)
+	for i, v := range vals {
+		for j := range width {
+			bit := i*width + j
+			packed[bit/8] |= byte(v>>j&1) << (bit % 8)
+		}
+	}
+	return append(b, packed...)
+}
+
+func appendPage(b []byte, typ int64, sub int16, subFields [][2]int64, page, stored []byte) []byte {
+	w := &thriftWriter{}
+	w.begin()
+	w.field(1, thriftI32)
+	w.i64(typ)
+	w.field(2, thriftI32)
+	w.i64(int64(len(page)))
+	w.field(3, thriftI32)
+	w.i64(int64(len(stored)))
+	w.field(sub, thriftStruct)
+	w.begin()
+	for _, f := range subFields {
+		if f[0] == 7 { // DataPageHeaderV2.is_compressed
+			w.field(7, thriftBoolFalse)
+			continue
+		}
+		w.field(int16(f[0]), thriftI32)
+		w.i64(f[1])
+	}
+	w.end()
+	w.end()
+	return append(append(b, w.b...), stored...)
+}
+
+func compressPage(t *testing.T, codec int64, b []byte) []byte {
+	t.Helper()
+	switch codec {
+	case 1:
+		return snappy.Encode(nil, b)
+	case 6:
+		zw, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Fatalf("zstd: %v", err)
+		}
+		defer zw.Close()
+		return zw.EncodeAll(b, nil)
+	}
+	return b
+}
+
+// buildParquet writes a 42-row file in two row groups. Columns (all optional): id INT64 in PLAIN v1
+// pages; name UTF8 with a dictionary page and RLE_DICTIONARY indexes; loc.lat DOUBLE in two v2
+// pages per row group, Snappy-compressed in the first group and Zstandard in the second.
+func buildParquet(t *testing.T) []byte {
+	t.Helper()
+	const groupRows = 21
+	data := []byte(parquetMagic)
+	type chunk struct{ codec, offset, dictOffset, size int64 }
+	var chunks [2][3]chunk
+	for g := range 2 {
+		rows := make([][]any, groupRows)
+		for i := range rows {
+			rows[i] = parquetTestRow(g*groupRows + i)
+		}
+
+		// id: a v1 PLAIN page whose definition levels are one RLE run.
+		start := int64(len(data))
+		var defs []uint64
+		var vals []byte
+		for _, row := range rows {
+			defs = append(defs, 1)
+			vals = binary.LittleEndian.AppendUint64(vals, uint64(row[0].(int64)))
+		}
+		lv := hybrid(defs, 1)
+		page := append(append(binary.LittleEndian.AppendUint32(nil, uint32(len(lv))), lv...), vals...)
+		data = appendPage(data, 0, 5, [][2]int64{{1, groupRows}, {2, parquetPlain}, {3, parquetRLE}}, page, page)
+		chunks[g][0] = chunk{offset: start, size: int64(len(data)) - start}
+
+		// name: a dictionary page, then a v1 page of bit-packed levels and RLE_DICTIONARY indexes.
+		start = int64(len(data))
+		var dict []byte
+		for _, s := range []string{"ann", "bob", "cy"} {
+			dict = binary.LittleEndian.AppendUint32(dict, uint32(len(s)))
+			dict = append(dict, s...)
+		}
+		data = appendPage(data, 2, 7, [][2]int64{{1, 3}, {2, parquetPlain}}, dict, dict)
+		dataStart := int64(len(data))
+		defs = defs[:0]
+		var idx []uint64
+		for i, row := range rows {
+			if row[1] == nil {
+				defs = append(defs, 0)
+				continue
+			}
+			defs = append(defs, 1)
+			idx = append(idx, uint64((g*groupRows+i)%3))
+		}
+		lv = hybrid(defs, 1)
+		page = append(binary.LittleEndian.AppendUint32(nil, uint32(len(lv))), lv...)
+		page = append(append(page, 2), hybrid(idx, 2)...)
+		data = appendPage(data, 0, 5, [][2]int64{{1, groupRows}, {2, parquetRLEDictionary}, {3, parquetRLE}}, page, page)
+		chunks[g][1] = chunk{offset: dataStart, dictOffset: start, size: int64(len(data)) - start}
+
+		// loc.lat: v2 pages of 11 and 10 rows with uncompressed levels and compressed values.
+		codec := []int64{1, 6}[g]
+		start = int64(len(data))
+		for _, part := range [][][]any{rows[:11], rows[11:]} {
+			defs, vals = defs[:0], vals[:0]
+			nulls := int64(0)
+			for _, row := range part {
+				if row[2] == nil {
+					defs = append(defs, 0)
+					nulls++
+					continue
+				}
+				defs = append(defs, 2)
+				vals = binary.LittleEndian.AppendUint64(vals, math.Float64bits(row[2].(float64)))
+			}
+			lv = hybrid(defs, 2)
+			stored := append(slices.Clone(lv), compressPage(t, codec, vals)...)
+			page = append(slices.Clone(lv), vals...)
+			n := int64(len(part))
+			data = appendPage(data, 3, 8, [][2]int64{
+				{1, n}, {2, nulls}, {3, n}, {4, parquetPlain}, {5, int64(len(lv))}, {6, 0},
+			}, page, stored)
+		}
+		chunks[g][2] = chunk{codec: codec, offset: start, size: int64(len(data)) - start}
+	}
+
+	w := &thriftWriter{}
+	w.begin() // FileMetaData
+	w.field(1, thriftI32)
+	w.i64(1)
+
+	type el struct {
+		name                  string
+		physical, conv, child int64
+	}
+	schema := []el{
+		{name: "schema", physical: -1, conv: -1, child: 3},
+		{name: "id", physical: 2, conv: -1},
+		{name: "name", physical: 6, conv: 0},
+		{name: "loc", physical: -1, conv: -1, child: 1},
+		{name: "lat", physical: 5, conv: -1},
+	}
+	w.field(2, thriftList)
+	w.list(len(schema), thriftStruct)
+	for _, e := range schema {
+		w.begin()
+		if e.physical >= 0 {
+			w.field(1, thriftI32)
+			w.i64(e.physical)
+		}
+		w.field(3, thriftI32)
+		w.i64(1)
+		w.field(4, thriftBinary)
+		w.str(e.name)
+		if e.child > 0 {
+			w.field(5, thriftI32)
+			w.i64(e.child)
+		}
+		if e.conv >= 0 {
+			w.field(6, thriftI32)
+			w.i64(e.conv)
+		}
+		w.end()
+	}
+	w.field(3, thriftI64)
+	w.i64(2 * groupRows)
+
+	// Two row groups; the second has no statistics for "name".
+	nulls := [][]int64{{0, 2, 3}, {0, -1, 3}}
+	paths := [][]string{{"id"}, {"name"}, {"loc", "lat"}}
+	w.field(4, thriftList)
+	w.list(len(nulls), thriftStruct)
+	for g, rg := range nulls {
+		w.begin()
+		w.field(1, thriftList)
+		w.list(len(paths), thriftStruct)
+		for i, path := range paths {
+			ch := chunks[g][i]
+			w.begin() // ColumnChunk
+			w.field(2, thriftI64)
+			w.i64(ch.offset)
+			w.field(3, thriftStruct)
+			w.begin() // ColumnMetaData
+			w.field(2, thriftList)
+			w.list(2, thriftI32)
+			w.i64(0)
+			w.i64(3)
+			w.field(3, thriftList)
+			w.list(len(path), thriftBinary)
+			for _, s := range path {
+				w.str(s)
+			}
+			w.field(4, thriftI32)
+			w.i64(ch.codec)
+			w.field(7, thriftI64)
+			w.i64(ch.size)
+			w.field(9, thriftI64)
+			w.i64(ch.offset)
+			if ch.dictOffset > 0 {
+				w.field(11, thriftI64)
+				w.i64(ch.dictOffset)
+			}
+			if rg[i] >= 0 {
+				w.field(12, thriftStruct)
+				w.begin()
+				w.field(1, thriftBinary)
+				w.str("max")
+				w.field(3, thriftI64)
+				w.i64(rg[i])
+				w.end()
+			}
+			w.end()
+			w.end()
+		}
+		w.field(3, thriftI64)
+		w.i64(groupRows)
+		w.field(100, thriftBoolTrue) // unknown field with a long id delta
+		w.end()
+	}
+	w.field(6, thriftBinary)
+	w.str("test writer")
+	w.end()
+
+	out := append(data, w.b...)
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(w.b)))
+	return append(out, parquetMagic...)
+}
+
+func TestPreviewSafe_Parquet(t *testing.T) {
+	dir := t.TempDir()
+	good := buildParquet(t)
+
+	got, err := PreviewSafe(t.Context(), vfs.OS(), writeFile(t, dir, "x.parquet", good), Options{Format: FormatParquet})
+	if err != nil {
+		t.Fatalf("PreviewSafe: %v", err)
+	}
+	want := []ColumnInfo{
+		{Name: "id", Type: TypeInteger},
+		{Name: "name", Type: TypeString, NullCount: -1},
+		{Name: "loc.lat", Type: TypeNumber, NullCount: 6},
+	}
+	if !reflect.DeepEqual(got.Columns, want) {
+		t.Fatalf("columns = %+v\nwant %+v", got.Columns, want)
+	}
+	if got.RowCount != 42 || !got.RowCountExact || got.Head != nil || got.SizeBytes != int64(len(good)) {
+		t.Fatalf("unexpected preview: %+v", got)
+	}
+
+	rows := []struct {
+		name        string
+		opts        Options
+		scanned     int64
+		sampleBelow int64
+	}{
+		{name: "all_row_groups", opts: Options{HeadRows: 6, SampleRows: 8}, scanned: 42, sampleBelow: 42},
+		{name: "first_row_group", opts: Options{HeadRows: 6, SampleRows: 8, ScanBytes: 1}, scanned: 21, sampleBelow: 21},
+	}
+	for _, tc := range rows {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Format = FormatParquet
+			got, err := PreviewSafe(t.Context(), vfs.OS(), writeFile(t, dir, tc.name+".parquet", good), tc.opts)
+			if err != nil {
+				t.Fatalf("PreviewSafe: %v", err)
+			}
+			if got.ScannedRows != tc.scanned || len(got.Head) != 6 || len(got.Sample) != 8 {
+				t.Fatalf("scanned=%d head=%d sample=%d; notes %q",
+					got.ScannedRows, len(got.Head), len(got.Sample), got.Notes)
+			}
+			for i, row := range got.Head {
+				if !reflect.DeepEqual(row, parquetTestRow(i)) {
+					t.Fatalf("head[%d] = %v, want %v", i, row, parquetTestRow(i))
+				}
+			}
+			seen := map[int64]bool{}
+			for _, row := range got.Sample {
+				id, ok := row[0].(int64)
+				if !ok || id < 1 || id > tc.sampleBelow || seen[id] {
+					t.Fatalf("bad sample row %v", row)
+				}
+				seen[id] = true
+				if want := parquetTestRow(int(id - 1)); !reflect.DeepEqual(row, want) {
+					t.Fatalf("sample row = %v, want %v", row, want)
+				}
+			}
+		})
+	}
+
+	bad := []struct {
+		name string
+		body []byte
+	}{
+		{name: "not_parquet", body: []byte("hello, world!")},
+		{name: "truncated_footer", body: append(append([]byte(parquetMagic), good[4:len(good)-40]...), good[len(good)-8:]...)},
+	}
+	for _, tc := range bad {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := PreviewSafe(t.Context(), vfs.OS(), writeFile(t, dir, tc.name, tc.body), Options{Format: FormatParquet})
+			if !errors.Is(err, errBadParquet) {
+				t.Fatalf("err = %v, want errBadParquet", err)
+			}
+		})
+	}
+}
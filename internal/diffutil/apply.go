@@ -0,0 +1,230 @@
+package diffutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxFuzz bounds ApplyOptions.Fuzz.
+const MaxFuzz = 3
+
+// ApplyOptions controls how tolerant hunk matching is.
+type ApplyOptions struct {
+	// Fuzz is how many context lines may be ignored at each end of a hunk when it does not match
+	// exactly (like patch -F). Changed lines always have to match.
+	Fuzz int
+	// IgnoreWhitespace compares lines with runs of whitespace collapsed and leading/trailing
+	// whitespace removed.
+	IgnoreWhitespace bool
+}
+
+func (o ApplyOptions) lineEqual() func(a, b string) bool {
+	if !o.IgnoreWhitespace {
+		return func(a, b string) bool { return a == b }
+	}
+	return func(a, b string) bool {
+		return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+	}
+}
+
+// HunkResult reports where a hunk was applied.
+type HunkResult struct {
+	Hunk   int    // 1-based index within the file patch
+	Header string // "@@ ... @@" line
+	Line   int    // 1-based line in the patched file where the hunk's lines start
+	Offset int    // lines between where the header said the hunk would be and where it matched
+	Fuzz   int    // context lines ignored at each end to make the hunk match
+}
+
+// HunkError describes a hunk that could not be applied.
+type HunkError struct {
+	Hunk   int
+	Header string
+	Reason string
+}
+
+func (e *HunkError) Error() string {
+	return fmt.Sprintf("hunk %d (%s): %s", e.Hunk, e.Header, e.Reason)
+}
+
+// ApplyHunks applies hunks in order to lines (without terminators) and returns the patched lines.
+// finalNewline is whether the input ends with a newline; the returned value reflects
+// "\ No newline at end of file" markers on hunks that reach the end of the file.
+//
+// Each hunk is searched for nearest-first around its expected position (header line adjusted for
+// earlier hunks), never before the end of the previous hunk. If no hunk fails, errs is empty; otherwise
+// out is meaningless and errs has one *HunkError per failed hunk.
+func ApplyHunks(
+	lines []string,
+	finalNewline bool,
+	hunks []Hunk,
+	opts ApplyOptions,
+) (out []string, outFinalNewline bool, results []HunkResult, errs []error) {
+	fuzz := min(max(opts.Fuzz, 0), MaxFuzz)
+	eq := opts.lineEqual()
+
+	work := append([]string(nil), lines...)
+	outFinalNewline = finalNewline
+	minPos := 0
+	delta := 0 // net lines added by earlier hunks
+	carry := 0 // offset of the previous hunk, assumed to persist
+
+	for hi, h := range hunks {
+		oldSeq, newSeq, lead, trail := splitHunk(h)
+		declared := h.OldStart - 1
+		if len(oldSeq) == 0 {
+			declared = h.OldStart // insertion after line OldStart
+		}
+		declared += delta
+
+		var (
+			pos, usedFuzz, trimmedLead int
+			found                      bool
+		)
+		for f := 0; f <= fuzz && !found; f++ {
+			trimLead, trimTrail := min(f, lead), min(f, trail)
+			pattern := oldSeq[trimLead : len(oldSeq)-trimTrail]
+			if len(pattern) == 0 && len(oldSeq) > 0 {
+				break // nothing left to anchor on
+			}
+			if p, ok := findNearest(work, pattern, declared+carry+trimLead, minPos, eq); ok {
+				pos, usedFuzz, trimmedLead, found = p, f, trimLead, true
+				oldSeq = oldSeq[trimLead : len(oldSeq)-trimTrail]
+				newSeq = newSeq[trimLead : len(newSeq)-trimTrail]
+			}
+		}
+		if !found {
+			errs = append(errs, &HunkError{Hunk: hi + 1, Header: h.Header, Reason: "context not found"})
+			continue
+		}
+
+		// Keep the file's own text for context lines (they may differ in whitespace).
+		repl := make([]string, 0, len(newSeq))
+		oi := 0
+		for _, l := range newSeq {
+			if l.Kind == LineContext {
+				for oi < len(oldSeq) && oldSeq[oi].Kind != LineContext {
+					oi++
+				}
+				repl = append(repl, work[pos+oi])
+				oi++
+				continue
+			}
+			repl = append(repl, l.Text)
+		}
+
+		end := pos + len(oldSeq)
+		reachesEOF := end == len(work)
+		work = append(work[:pos], append(repl, work[end:]...)...)
+		if reachesEOF {
+			switch {
+			case h.NewNoFinalNewline:
+				outFinalNewline = false
+			case h.OldNoFinalNewline:
+				outFinalNewline = true
+			}
+		}
+
+		carry = pos - trimmedLead - declared
+		results = append(results, HunkResult{
+			Hunk:   hi + 1,
+			Header: h.Header,
+			Line:   pos + 1,
+			Offset: carry,
+			Fuzz:   usedFuzz,
+		})
+		delta += len(repl) - len(oldSeq)
+		minPos = pos + len(repl)
+	}
+	if len(errs) > 0 {
+		return nil, finalNewline, results, errs
+	}
+	return work, outFinalNewline, results, nil
+}
+
+// NewFileContent returns the lines of a file created by a patch (all hunks must be pure additions).
+func NewFileContent(fp FilePatch) (lines []string, finalNewline bool, err error) {
+	finalNewline = true
+	for hi, h := range fp.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind != LineAdd {
+				return nil, false, &HunkError{Hunk: hi + 1, Header: h.Header, Reason: "new-file hunk contains context or deleted lines"}
+			}
+			lines = append(lines, l.Text)
+		}
+		if h.NewNoFinalNewline {
+			finalNewline = false
+		}
+	}
+	return lines, finalNewline, nil
+}
+
+// VerifyDeletedContent checks that a delete-file patch removes exactly the given lines.
+func VerifyDeletedContent(fp FilePatch, lines []string, opts ApplyOptions) error {
+	var old []string
+	for hi, h := range fp.Hunks {
+		for _, l := range h.Lines {
+			if l.Kind == LineAdd {
+				return &HunkError{Hunk: hi + 1, Header: h.Header, Reason: "delete-file hunk contains added lines"}
+			}
+			old = append(old, l.Text)
+		}
+	}
+	if len(old) != len(lines) {
+		return fmt.Errorf("file has %d lines but the patch deletes %d", len(lines), len(old))
+	}
+	eq := opts.lineEqual()
+	for i := range old {
+		if !eq(old[i], lines[i]) {
+			return fmt.Errorf("line %d differs from the patch; refusing to delete", i+1)
+		}
+	}
+	return nil
+}
+
+// splitHunk returns the old-side (context+delete) and new-side (context+add) sequences and the
+// number of leading/trailing context lines.
+func splitHunk(h Hunk) (oldSeq, newSeq []HunkLine, lead, trail int) {
+	for _, l := range h.Lines {
+		if l.Kind != LineAdd {
+			oldSeq = append(oldSeq, l)
+		}
+		if l.Kind != LineDelete {
+			newSeq = append(newSeq, l)
+		}
+	}
+	for lead < len(h.Lines) && h.Lines[lead].Kind == LineContext {
+		lead++
+	}
+	for trail < len(h.Lines)-lead && h.Lines[len(h.Lines)-1-trail].Kind == LineContext {
+		trail++
+	}
+	return oldSeq, newSeq, lead, trail
+}
+
+// findNearest finds pattern in lines at the start position closest to want (ties go to the earlier one),
+// considering only starts >= minPos.
+func findNearest(lines []string, pattern []HunkLine, want, minPos int, eq func(a, b string) bool) (int, bool) {
+	last := len(lines) - len(pattern)
+	if last < minPos {
+		return 0, false
+	}
+	want = min(max(want, minPos), last)
+	matchAt := func(pos int) bool {
+		for i, l := range pattern {
+			if !eq(lines[pos+i], l.Text) {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; want-d >= minPos || want+d <= last; d++ {
+		if p := want - d; p >= minPos && matchAt(p) {
+			return p, true
+		}
+		if p := want + d; d > 0 && p <= last && matchAt(p) {
+			return p, true
+		}
+	}
+	return 0, false
+}
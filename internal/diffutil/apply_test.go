@@ -0,0 +1,145 @@
+package diffutil
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestApplyHunks(t *testing.T) {
+	file := []string{"package x", "", "func a() {", "\treturn 1", "}", "", "func b() {", "\treturn 2", "}"}
+
+	tests := []struct {
+		name        string
+		lines       []string
+		final       bool
+		patch       string
+		opts        ApplyOptions
+		want        []string
+		wantFinal   bool
+		wantResults []HunkResult
+		wantErr     string
+	}{
+		{
+			name:  "exact_match",
+			lines: file, final: true,
+			patch:       "@@ -3,3 +3,3 @@\n func a() {\n-\treturn 1\n+\treturn 10\n }\n",
+			want:        []string{"package x", "", "func a() {", "\treturn 10", "}", "", "func b() {", "\treturn 2", "}"},
+			wantFinal:   true,
+			wantResults: []HunkResult{{Hunk: 1, Header: "@@ -3,3 +3,3 @@", Line: 3}},
+		},
+		{
+			name:  "wrong_line_numbers_use_offset",
+			lines: file, final: true,
+			patch:       "@@ -1,3 +1,3 @@\n func b() {\n-\treturn 2\n+\treturn 20\n }\n",
+			want:        []string{"package x", "", "func a() {", "\treturn 1", "}", "", "func b() {", "\treturn 20", "}"},
+			wantFinal:   true,
+			wantResults: []HunkResult{{Hunk: 1, Header: "@@ -1,3 +1,3 @@", Line: 7, Offset: 6}},
+		},
+		{
+			name:  "second_hunk_accounts_for_first_hunk_growth",
+			lines: file, final: true,
+			patch: "@@ -1,2 +1,3 @@\n package x\n+// doc\n \n" +
+				"@@ -7,3 +8,3 @@\n func b() {\n-\treturn 2\n+\treturn 3\n }\n",
+			want:      []string{"package x", "// doc", "", "func a() {", "\treturn 1", "}", "", "func b() {", "\treturn 3", "}"},
+			wantFinal: true,
+			wantResults: []HunkResult{
+				{Hunk: 1, Header: "@@ -1,2 +1,3 @@", Line: 1},
+				{Hunk: 2, Header: "@@ -7,3 +8,3 @@", Line: 8},
+			},
+		},
+		{
+			name:  "mismatched_context_fails_without_fuzz",
+			lines: file, final: true,
+			patch:   "@@ -3,3 +3,3 @@\n func aa() {\n-\treturn 1\n+\treturn 10\n }\n",
+			wantErr: "hunk 1 (@@ -3,3 +3,3 @@): context not found",
+		},
+		{
+			name:  "fuzz_ignores_edge_context",
+			lines: file, final: true,
+			patch:       "@@ -3,3 +3,3 @@\n func aa() {\n-\treturn 1\n+\treturn 10\n }\n",
+			opts:        ApplyOptions{Fuzz: 1},
+			want:        []string{"package x", "", "func a() {", "\treturn 10", "}", "", "func b() {", "\treturn 2", "}"},
+			wantFinal:   true,
+			wantResults: []HunkResult{{Hunk: 1, Header: "@@ -3,3 +3,3 @@", Line: 4, Fuzz: 1}},
+		},
+		{
+			name:  "ignore_whitespace_keeps_file_context_text",
+			lines: []string{"if x {", "    y()", "}"}, final: true,
+			patch:       "@@ -1,3 +1,3 @@\n if  x {\n-  y()\n+  z()\n }\n",
+			opts:        ApplyOptions{IgnoreWhitespace: true},
+			want:        []string{"if x {", "  z()", "}"},
+			wantFinal:   true,
+			wantResults: []HunkResult{{Hunk: 1, Header: "@@ -1,3 +1,3 @@", Line: 1}},
+		},
+		{
+			name:  "pure_insertion_after_line",
+			lines: []string{"a", "b"}, final: true,
+			patch:       "@@ -1,0 +2 @@\n+x\n",
+			want:        []string{"a", "x", "b"},
+			wantFinal:   true,
+			wantResults: []HunkResult{{Hunk: 1, Header: "@@ -1,0 +2 @@", Line: 2}},
+		},
+		{
+			name:  "no_newline_markers",
+			lines: []string{"a", "b"}, final: false,
+			patch:       "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n",
+			want:        []string{"a", "c"},
+			wantFinal:   true,
+			wantResults: []HunkResult{{Hunk: 1, Header: "@@ -1,2 +1,2 @@", Line: 1}},
+		},
+		{
+			name:  "reports_every_failed_hunk",
+			lines: file, final: true,
+			patch:   "@@ -1 +1 @@\n-nope\n+x\n@@ -3 +3 @@\n-func a() {\n+func A() {\n@@ -8 +8 @@\n-nada\n+y\n",
+			wantErr: "hunk 1 (@@ -1 +1 @@): context not found\nhunk 3 (@@ -8 +8 @@): context not found",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			files, err := ParseUnified("--- a/f\n+++ b/f\n" + tc.patch)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			got, final, results, errs := ApplyHunks(tc.lines, tc.final, files[0].Hunks, tc.opts)
+			if tc.wantErr != "" {
+				if err := errors.Join(errs...); err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("errs=%v want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if len(errs) != 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			if !slices.Equal(got, tc.want) || final != tc.wantFinal {
+				t.Fatalf("got=%q final=%v\nwant=%q final=%v", got, final, tc.want, tc.wantFinal)
+			}
+			if !slices.Equal(results, tc.wantResults) {
+				t.Fatalf("results=%+v want %+v", results, tc.wantResults)
+			}
+		})
+	}
+}
+
+func TestNewFileContentAndVerifyDeleted(t *testing.T) {
+	files, err := ParseUnified("--- /dev/null\n+++ b/n\n@@ -0,0 +1,2 @@\n+a\n+b\n\\ No newline at end of file\n" +
+		"--- a/d\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-x\n-y\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	lines, final, err := NewFileContent(files[0])
+	if err != nil || !slices.Equal(lines, []string{"a", "b"}) || final {
+		t.Fatalf("NewFileContent=%q,%v,%v", lines, final, err)
+	}
+	if err := VerifyDeletedContent(files[1], []string{"x", "y"}, ApplyOptions{}); err != nil {
+		t.Fatalf("VerifyDeletedContent(match): %v", err)
+	}
+	if err := VerifyDeletedContent(files[1], []string{"x", "z"}, ApplyOptions{}); err == nil ||
+		!strings.Contains(err.Error(), "refusing to delete") {
+		t.Fatalf("VerifyDeletedContent(mismatch)=%v", err)
+	}
+	if err := VerifyDeletedContent(files[1], []string{"x", "y", "z"}, ApplyOptions{}); err == nil {
+		t.Fatalf("expected error for extra lines")
+	}
+}
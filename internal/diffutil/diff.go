@@ -0,0 +1,358 @@
+package diffutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultContext is the number of context lines around changes in unified diffs (like diff -u).
+const DefaultContext = 3
+
+// maxEditDistance bounds each Myers search (and so the time spent). Ranges that differ by more lines
+// than this still get a correct diff, just not a minimal one: the range is replaced wholesale.
+const maxEditDistance = 4000
+
+// DiffOptions controls diff generation.
+type DiffOptions struct {
+	Context          int // context lines around each change; negative means DefaultContext
+	IgnoreWhitespace bool
+}
+
+// Op is one line of an edit script. A and B are the 0-based line indexes in the old and new input
+// at that point of the script (A is meaningful for context/delete lines, B for context/add lines).
+type Op struct {
+	Kind LineKind
+	A, B int
+}
+
+// SplitLines splits text into lines without terminators and reports whether it ends with "\n".
+// Empty text has no lines and counts as newline-terminated.
+func SplitLines(s string) (lines []string, finalNewline bool) {
+	if s == "" {
+		return nil, true
+	}
+	finalNewline = strings.HasSuffix(s, "\n")
+	s = strings.TrimSuffix(s, "\n")
+	return strings.Split(s, "\n"), finalNewline
+}
+
+// DiffLines returns an edit script turning a into b. Within each run of changes, deletions come before additions.
+func DiffLines(a, b []string, aFinalNewline, bFinalNewline bool, ignoreWhitespace bool) []Op {
+	ka, kb := lineKeys(a, b, aFinalNewline, bFinalNewline, ignoreWhitespace)
+
+	// Common prefix and suffix are cheap to strip and keep the Myers search small.
+	pre := 0
+	for pre < len(ka) && pre < len(kb) && ka[pre] == kb[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(ka)-pre && suf < len(kb)-pre && ka[len(ka)-1-suf] == kb[len(kb)-1-suf] {
+		suf++
+	}
+
+	ops := make([]Op, 0, len(a)+len(b))
+	for i := range pre {
+		ops = append(ops, Op{Kind: LineContext, A: i, B: i})
+	}
+	mid := myers(ka[pre:len(ka)-suf], kb[pre:len(kb)-suf], maxEditDistance)
+	for _, op := range mid {
+		op.A += pre
+		op.B += pre
+		ops = append(ops, op)
+	}
+	for i := range suf {
+		ops = append(ops, Op{Kind: LineContext, A: len(ka) - suf + i, B: len(kb) - suf + i})
+	}
+	return deletesFirst(ops)
+}
+
+// Unified renders a unified diff between a and b with "--- oldName" / "+++ newName" headers.
+// It returns "" when the inputs are equal (under opts). The output parses with ParseUnified.
+func Unified(
+	oldName, newName string,
+	a, b []string,
+	aFinalNewline, bFinalNewline bool,
+	opts DiffOptions,
+) (text string, added, removed int) {
+	ctxLines := opts.Context
+	if ctxLines < 0 {
+		ctxLines = DefaultContext
+	}
+	ops := DiffLines(a, b, aFinalNewline, bFinalNewline, opts.IgnoreWhitespace)
+
+	var sb strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change and extend the hunk while changes are within 2*context lines of each other.
+		first := start
+		for first < len(ops) && ops[first].Kind == LineContext {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+		last := first
+		for i := first + 1; i < len(ops); i++ {
+			if ops[i].Kind == LineContext {
+				continue
+			}
+			if i-last-1 > 2*ctxLines {
+				break
+			}
+			last = i
+		}
+		lo := max(first-ctxLines, start)
+		hi := min(last+ctxLines+1, len(ops))
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		writeHunk(&sb, ops[lo:hi], a, b, aFinalNewline, bFinalNewline)
+		for _, op := range ops[lo:hi] {
+			switch op.Kind {
+			case LineAdd:
+				added++
+			case LineDelete:
+				removed++
+			}
+		}
+		start = hi
+	}
+	return sb.String(), added, removed
+}
+
+func writeHunk(sb *strings.Builder, ops []Op, a, b []string, aFinalNewline, bFinalNewline bool) {
+	oldCount, newCount := 0, 0
+	for _, op := range ops {
+		if op.Kind != LineAdd {
+			oldCount++
+		}
+		if op.Kind != LineDelete {
+			newCount++
+		}
+	}
+	// For an empty range the start is the line before it, as in diff -u.
+	oldStart, newStart := ops[0].A+1, ops[0].B+1
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", formatRange(oldStart, oldCount), formatRange(newStart, newCount))
+
+	for _, op := range ops {
+		var (
+			text   string
+			atEOF  bool
+			noEOFN bool
+		)
+		switch op.Kind {
+		case LineAdd:
+			text, atEOF, noEOFN = b[op.B], op.B == len(b)-1, !bFinalNewline
+		default:
+			text, atEOF, noEOFN = a[op.A], op.A == len(a)-1, !aFinalNewline
+		}
+		sb.WriteByte(byte(op.Kind))
+		sb.WriteString(text)
+		sb.WriteByte('\n')
+		if atEOF && noEOFN {
+			sb.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
+
+func formatRange(start, count int) string {
+	if count == 1 {
+		return strconv.Itoa(start)
+	}
+	return strconv.Itoa(start) + "," + strconv.Itoa(count)
+}
+
+// lineKeys maps lines to integers so equal (or whitespace-equal) lines compare cheaply.
+// A last line without a trailing newline never equals one with it, so such changes show up in the diff.
+func lineKeys(a, b []string, aFinalNewline, bFinalNewline, ignoreWhitespace bool) (ka, kb []int) {
+	ids := make(map[string]int, len(a)+len(b))
+	key := func(lines []string, final bool) []int {
+		out := make([]int, len(lines))
+		for i, l := range lines {
+			if ignoreWhitespace {
+				l = strings.Join(strings.Fields(l), " ")
+			}
+			if i == len(lines)-1 && !final {
+				l += "\x00noeol"
+			}
+			id, ok := ids[l]
+			if !ok {
+				id = len(ids)
+				ids[l] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	return key(a, aFinalNewline), key(b, bFinalNewline)
+}
+
+// myers computes a shortest edit script with the linear-space variant of Myers' O(ND) algorithm: it
+// finds the middle snake of a script and recurses on both halves, so it keeps two frontiers of
+// O(maxD) ints instead of one per edit. A range whose middle snake needs more than maxD edits is
+// replaced wholesale, which is correct but not minimal.
+func myers(a, b []int, maxD int) []Op {
+	s := &linearMyers{a: a, b: b, maxD: maxD}
+	s.diff(0, len(a), 0, len(b))
+	return s.ops
+}
+
+type linearMyers struct {
+	a, b   []int
+	maxD   int
+	ops    []Op
+	vf, vb []int // forward and backward frontiers, reused across ranges
+}
+
+func (s *linearMyers) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && s.a[a0] == s.b[b0] {
+		s.ops = append(s.ops, Op{Kind: LineContext, A: a0, B: b0})
+		a0++
+		b0++
+	}
+	suf := 0
+	for a1-suf > a0 && b1-suf > b0 && s.a[a1-1-suf] == s.b[b1-1-suf] {
+		suf++
+	}
+	a1, b1 = a1-suf, b1-suf
+
+	if a0 < a1 && b0 < b1 {
+		x, y, ok := s.middle(a0, a1, b0, b1)
+		if ok && (x > a0 || y > b0) && (x < a1 || y < b1) {
+			s.diff(a0, x, b0, y)
+			s.diff(x, a1, y, b1)
+		} else {
+			s.replace(a0, a1, b0, b1)
+		}
+	} else {
+		s.replace(a0, a1, b0, b1)
+	}
+	for i := range suf {
+		s.ops = append(s.ops, Op{Kind: LineContext, A: a1 + i, B: b1 + i})
+	}
+}
+
+func (s *linearMyers) replace(a0, a1, b0, b1 int) {
+	for i := a0; i < a1; i++ {
+		s.ops = append(s.ops, Op{Kind: LineDelete, A: i, B: b0})
+	}
+	for j := b0; j < b1; j++ {
+		s.ops = append(s.ops, Op{Kind: LineAdd, A: a1, B: j})
+	}
+}
+
+// middle returns a point on a shortest edit path of a[a0:a1] and b[b0:b1], found where the forward
+// and backward searches overlap, or ok=false past maxD edits.
+func (s *linearMyers) middle(a0, a1, b0, b1 int) (x, y int, ok bool) {
+	a, b := s.a[a0:a1], s.b[b0:b1]
+	n, m := len(a), len(b)
+	maxD := min((n+m+1)/2, s.maxD)
+	off := maxD + 1
+	size := 2*maxD + 3
+	if len(s.vf) < size {
+		s.vf, s.vb = make([]int, size), make([]int, size)
+	}
+	vf, vb := s.vf[:size], s.vb[:size]
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[off+1], vb[off+1] = 0, 0
+
+	delta := n - m
+	odd := delta%2 != 0
+	// Diagonals that ran off the grid are skipped in later rounds.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for d := 0; d <= maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if i := off + delta - k; i >= 0 && i < size && vb[i] != -1 && x >= n-vb[i] {
+					return a0 + x, b0 + y, true
+				}
+			}
+		}
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && vb[off+k-1] < vb[off+k+1]) {
+				x = vb[off+k+1]
+			} else {
+				x = vb[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			vb[off+k] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				if i := off + delta - k; i >= 0 && i < size && vf[i] != -1 && vf[i] >= n-x {
+					fx := vf[i]
+					return a0 + fx, b0 + fx - (delta - k), true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// deletesFirst reorders each run of changes so deletions precede additions and fixes up the
+// positions of the moved lines.
+func deletesFirst(ops []Op) []Op {
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == LineContext {
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].Kind != LineContext {
+			j++
+		}
+		a0, b0 := ops[i].A, ops[i].B
+		run := make([]Op, 0, j-i)
+		nDel := 0
+		for _, op := range ops[i:j] {
+			if op.Kind == LineDelete {
+				run = append(run, Op{Kind: LineDelete, A: a0 + nDel, B: b0})
+				nDel++
+			}
+		}
+		nAdd := 0
+		for _, op := range ops[i:j] {
+			if op.Kind == LineAdd {
+				run = append(run, Op{Kind: LineAdd, A: a0 + nDel, B: b0 + nAdd})
+				nAdd++
+			}
+		}
+		copy(ops[i:j], run)
+		i = j
+	}
+	return ops
+}
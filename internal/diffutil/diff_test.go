@@ -0,0 +1,191 @@
+package diffutil
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        string
+		opts        DiffOptions
+		want        string
+		wantAdded   int
+		wantRemoved int
+	}{
+		{
+			name: "equal",
+			a:    "x\ny\n", b: "x\ny\n",
+			opts: DiffOptions{Context: -1},
+			want: "",
+		},
+		{
+			name: "single_change_default_context",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n", b: "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			opts:      DiffOptions{Context: -1},
+			want:      "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+			wantAdded: 1, wantRemoved: 1,
+		},
+		{
+			name: "separate_hunks_with_zero_context",
+			a:    "1\n2\n3\n4\n", b: "one\n2\n3\n4\nfive\n",
+			opts:      DiffOptions{Context: 0},
+			want:      "--- a\n+++ b\n@@ -1 +1 @@\n-1\n+one\n@@ -4,0 +5 @@\n+five\n",
+			wantAdded: 2, wantRemoved: 1,
+		},
+		{
+			name: "missing_final_newline",
+			a:    "x\ny\n", b: "x\ny",
+			opts:      DiffOptions{Context: 1},
+			want:      "--- a\n+++ b\n@@ -1,2 +1,2 @@\n x\n-y\n+y\n\\ No newline at end of file\n",
+			wantAdded: 1, wantRemoved: 1,
+		},
+		{
+			name: "create_from_empty",
+			a:    "", b: "x\n",
+			opts:      DiffOptions{Context: 3},
+			want:      "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n",
+			wantAdded: 1,
+		},
+		{
+			name: "ignore_whitespace",
+			a:    "if x {\n\treturn  1\n}\n", b: "if x {\n    return 1\n}\n",
+			opts: DiffOptions{Context: 3, IgnoreWhitespace: true},
+			want: "",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a, af := SplitLines(tc.a)
+			b, bf := SplitLines(tc.b)
+			got, added, removed := Unified("a", "b", a, b, af, bf, tc.opts)
+			if got != tc.want || added != tc.wantAdded || removed != tc.wantRemoved {
+				t.Fatalf("got (+%d -%d)\n%s\nwant (+%d -%d)\n%s", added, removed, got, tc.wantAdded, tc.wantRemoved, tc.want)
+			}
+		})
+	}
+}
+
+// TestUnifiedRoundTrip checks that generated diffs apply cleanly and reproduce the new text.
+func TestUnifiedRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	words := []string{"a", "b", "c", "d", "", "e"}
+	randText := func() string {
+		n := r.IntN(30)
+		s := ""
+		for range n {
+			s += words[r.IntN(len(words))] + "\n"
+		}
+		if s != "" && r.IntN(4) == 0 {
+			s = s[:len(s)-1]
+		}
+		return s
+	}
+	for i := range 500 {
+		at, bt := randText(), randText()
+		ctx := r.IntN(4)
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			a, af := SplitLines(at)
+			b, bf := SplitLines(bt)
+			text, _, _ := Unified("a", "b", a, b, af, bf, DiffOptions{Context: ctx})
+			if text == "" {
+				if at != bt {
+					t.Fatalf("empty diff for different inputs %q %q", at, bt)
+				}
+				return
+			}
+			fps, err := ParseUnified(text)
+			if err != nil {
+				t.Fatalf("parse: %v\n%s", err, text)
+			}
+			out, of, _, errs := ApplyHunks(a, af, fps[0].Hunks, ApplyOptions{})
+			if len(errs) > 0 {
+				t.Fatalf("apply: %v\n%s", errs, text)
+			}
+			if !slices.Equal(out, b) || (len(b) > 0 && of != bf) {
+				t.Fatalf("round trip mismatch\na=%q\nb=%q\ngot=%q final=%v\n%s", at, bt, out, of, text)
+			}
+		})
+	}
+}
+
+func TestMyersLinearSpace(t *testing.T) {
+	t.Parallel()
+
+	// applyOps rebuilds b from a and the script, and counts the edits.
+	applyOps := func(a, b []int, ops []Op) (out []int, edits int) {
+		i := 0
+		for _, op := range ops {
+			switch op.Kind {
+			case LineContext:
+				if op.A != i || a[op.A] != b[op.B] {
+					t.Fatalf("bad context op %+v", op)
+				}
+				out = append(out, a[i])
+				i++
+			case LineDelete:
+				if op.A != i {
+					t.Fatalf("bad delete op %+v", op)
+				}
+				i++
+				edits++
+			case LineAdd:
+				out = append(out, b[op.B])
+				edits++
+			}
+		}
+		if i != len(a) {
+			t.Fatalf("script consumed %d of %d lines", i, len(a))
+		}
+		return out, edits
+	}
+	lcs := func(a, b []int) int {
+		dp := make([][]int, len(a)+1)
+		for i := range dp {
+			dp[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					dp[i][j] = dp[i+1][j+1] + 1
+				} else {
+					dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+				}
+			}
+		}
+		return dp[0][0]
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 500 {
+		a := make([]int, rng.IntN(30))
+		b := make([]int, rng.IntN(30))
+		for i := range a {
+			a[i] = rng.IntN(4)
+		}
+		for i := range b {
+			b[i] = rng.IntN(4)
+		}
+		out, edits := applyOps(a, b, myers(a, b, maxEditDistance))
+		if !slices.Equal(out, b) {
+			t.Fatalf("a=%v b=%v: got %v", a, b, out)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("a=%v b=%v: %d edits, want %d", a, b, edits, want)
+		}
+	}
+
+	// Past the bound the range is replaced wholesale, which is still a valid script.
+	a := make([]int, 50)
+	b := make([]int, 50)
+	for i := range a {
+		a[i], b[i] = i, 100+i
+	}
+	out, edits := applyOps(a, b, myers(a, b, 10))
+	if !slices.Equal(out, b) || edits != 100 {
+		t.Fatalf("fallback: edits=%d out=%v", edits, out)
+	}
+}
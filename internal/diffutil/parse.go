@@ -0,0 +1,283 @@
+package diffutil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DevNull is the path unified diffs use for the missing side of a created or deleted file.
+const DevNull = "/dev/null"
+
+// LineKind is the first column of a hunk body line.
+type LineKind byte
+
+const (
+	LineContext LineKind = ' '
+	LineDelete  LineKind = '-'
+	LineAdd     LineKind = '+'
+)
+
+// HunkLine is one body line of a hunk, without its kind prefix and line terminator.
+type HunkLine struct {
+	Kind LineKind
+	Text string
+}
+
+// Hunk is one "@@ -a,b +c,d @@" section of a file patch.
+type Hunk struct {
+	Header   string // the "@@ ... @@" line, for messages
+	OldStart int    // 1-based; for an empty old range, the line after which the new lines go
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []HunkLine
+
+	// Set by "\ No newline at end of file" markers.
+	OldNoFinalNewline bool
+	NewNoFinalNewline bool
+}
+
+// FilePatch is the set of hunks for one file.
+type FilePatch struct {
+	OldPath string // DevNull for a created file
+	NewPath string // DevNull for a deleted file
+	Hunks   []Hunk
+}
+
+func (fp FilePatch) IsCreate() bool { return fp.OldPath == DevNull }
+func (fp FilePatch) IsDelete() bool { return fp.NewPath == DevNull }
+
+// Path is the path the patch applies to (the new path, or the old one for deletions).
+func (fp FilePatch) Path() string {
+	if fp.IsDelete() {
+		return fp.OldPath
+	}
+	return fp.NewPath
+}
+
+// ParseUnified parses a unified diff that may touch several files.
+//
+// Parsing is lenient in the ways generated diffs tend to be wrong:
+//   - Line counts in "@@" headers are not trusted to end a hunk; a body ends at the next hunk header,
+//     file header, or a line that is not a hunk line. Empty lines inside a hunk are read as empty context
+//     lines. The counts only decide whether a "--- "/"+++ " pair inside a hunk is a file header or a
+//     removed "-- ..." line followed by an added "++ ..." line.
+//   - Text outside file sections (commit messages, "diff --git"/"index" lines, code fences) is skipped.
+//   - Git "a/" and "b/" path prefixes are stripped, as are trailing timestamps after a tab.
+//
+// CRLF line endings in the patch text are accepted.
+func ParseUnified(patch string) ([]FilePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var (
+		files []FilePatch
+		cur   *FilePatch
+	)
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isFileHeaderAt(lines, i):
+			oldPath := parseHeaderPath(strings.TrimPrefix(line, "--- "))
+			newPath := parseHeaderPath(strings.TrimPrefix(lines[i+1], "+++ "))
+			if oldPath == "" || newPath == "" {
+				return nil, fmt.Errorf("line %d: missing file path in diff header", i+1)
+			}
+			if oldPath == DevNull && newPath == DevNull {
+				return nil, fmt.Errorf("line %d: both sides of the diff are %s", i+1, DevNull)
+			}
+			oldPath, newPath = stripGitPrefixes(oldPath, newPath)
+			files = append(files, FilePatch{OldPath: oldPath, NewPath: newPath})
+			cur = &files[len(files)-1]
+			i += 2
+
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk before any ---/+++ file header", i+1)
+			}
+			h, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			cur.Hunks = append(cur.Hunks, h)
+			i = next
+
+		case strings.HasPrefix(line, "GIT binary patch") || strings.HasPrefix(line, "Binary files "):
+			return nil, fmt.Errorf("line %d: binary patches are not supported", i+1)
+
+		default:
+			i++
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no file headers (---/+++) found in patch")
+	}
+	for _, f := range files {
+		if len(f.Hunks) == 0 {
+			return nil, fmt.Errorf("%s: file header without hunks", f.Path())
+		}
+	}
+	return files, nil
+}
+
+func isFileHeaderAt(lines []string, i int) bool {
+	return strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// parseHunk parses the hunk whose header is lines[start] and returns the index of the first line after it.
+func parseHunk(lines []string, start int) (Hunk, int, error) {
+	h := Hunk{Header: strings.TrimSpace(lines[start])}
+	if err := parseHunkHeader(lines[start], &h); err != nil {
+		return h, 0, fmt.Errorf("line %d: %w", start+1, err)
+	}
+
+	i := start + 1
+	lastKind := LineKind(0)
+	remOld, remNew := h.OldLines, h.NewLines // still expected by the header
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "diff ") ||
+			(isFileHeaderAt(lines, i) && !bodyPairAt(lines, i, remOld, remNew)) {
+			break
+		}
+		if line == "" {
+			h.Lines = append(h.Lines, HunkLine{Kind: LineContext})
+			lastKind = LineContext
+			remOld, remNew = remOld-1, remNew-1
+			continue
+		}
+		kind := LineKind(line[0])
+		switch kind {
+		case LineContext, LineDelete, LineAdd:
+			h.Lines = append(h.Lines, HunkLine{Kind: kind, Text: line[1:]})
+			lastKind = kind
+			if kind != LineAdd {
+				remOld--
+			}
+			if kind != LineDelete {
+				remNew--
+			}
+			continue
+		case '\\':
+			// "\ No newline at end of file" applies to the line right before it.
+			switch lastKind {
+			case LineDelete:
+				h.OldNoFinalNewline = true
+			case LineAdd:
+				h.NewNoFinalNewline = true
+			case LineContext:
+				h.OldNoFinalNewline = true
+				h.NewNoFinalNewline = true
+			}
+			continue
+		}
+		break
+	}
+
+	// Blank lines between the hunk and whatever follows are separators, not context, unless the
+	// header's line count says otherwise.
+	for len(h.Lines) > 0 {
+		last := h.Lines[len(h.Lines)-1]
+		if last.Kind != LineContext || last.Text != "" || h.countOld() == h.OldLines {
+			break
+		}
+		h.Lines = h.Lines[:len(h.Lines)-1]
+	}
+	if len(h.Lines) == 0 {
+		return h, 0, fmt.Errorf("line %d: empty hunk", start+1)
+	}
+	return h, i, nil
+}
+
+// bodyPairAt reports whether the "--- "/"+++ " pair at lines[i] belongs to the hunk body: the header
+// still expects a removed line, and the pair is not followed by a hunk header unless it is exactly the
+// last removed and added line expected.
+func bodyPairAt(lines []string, i, remOld, remNew int) bool {
+	if remOld <= 0 {
+		return false
+	}
+	if i+2 < len(lines) && strings.HasPrefix(lines[i+2], "@@") {
+		return remOld == 1 && remNew == 1
+	}
+	return true
+}
+
+func (h Hunk) countOld() int {
+	n := 0
+	for _, l := range h.Lines {
+		if l.Kind != LineAdd {
+			n++
+		}
+	}
+	return n
+}
+
+// parseHunkHeader parses "@@ -a[,b] +c[,d] @@[ section]".
+func parseHunkHeader(line string, h *Hunk) error {
+	rest, ok := strings.CutPrefix(line, "@@ ")
+	if !ok {
+		return fmt.Errorf("malformed hunk header %q", line)
+	}
+	ranges, _, ok := strings.Cut(rest, " @@")
+	if !ok {
+		return fmt.Errorf("malformed hunk header %q", line)
+	}
+	fields := strings.Fields(ranges)
+	if len(fields) != 2 || !strings.HasPrefix(fields[0], "-") || !strings.HasPrefix(fields[1], "+") {
+		return fmt.Errorf("malformed hunk header %q", line)
+	}
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(fields[0][1:]); err != nil {
+		return fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+	if h.NewStart, h.NewLines, err = parseRange(fields[1][1:]); err != nil {
+		return fmt.Errorf("malformed hunk header %q: %w", line, err)
+	}
+	return nil
+}
+
+func parseRange(s string) (start, count int, err error) {
+	a, b, hasCount := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(a); err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(b); err != nil || count < 0 {
+			return 0, 0, fmt.Errorf("invalid range %q", s)
+		}
+	}
+	return start, count, nil
+}
+
+// parseHeaderPath extracts the path from a ---/+++ header value, dropping a tab-separated timestamp
+// and surrounding quotes.
+func parseHeaderPath(s string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			s = u
+		}
+	}
+	return s
+}
+
+// stripGitPrefixes removes "a/" and "b/" when the header uses git's prefix convention.
+func stripGitPrefixes(oldPath, newPath string) (o, n string) {
+	oldOK := oldPath == DevNull || strings.HasPrefix(oldPath, "a/")
+	newOK := newPath == DevNull || strings.HasPrefix(newPath, "b/")
+	if !oldOK || !newOK {
+		return oldPath, newPath
+	}
+	if oldPath != DevNull {
+		oldPath = oldPath[2:]
+	}
+	if newPath != DevNull {
+		newPath = newPath[2:]
+	}
+	return oldPath, newPath
+}
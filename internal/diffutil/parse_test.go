@@ -0,0 +1,121 @@
+package diffutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUnified(t *testing.T) {
+	tests := []struct {
+		name      string
+		patch     string
+		wantFiles []FilePatch
+		wantErr   string
+	}{
+		{
+			name: "git_multi_file_with_create_and_delete",
+			patch: "diff --git a/x.go b/x.go\nindex 1..2 100644\n--- a/x.go\n+++ b/x.go\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n" +
+				"diff --git a/new.txt b/new.txt\nnew file mode 100644\n--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hello\n\\ No newline at end of file\n" +
+				"--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n",
+			wantFiles: []FilePatch{
+				{OldPath: "x.go", NewPath: "x.go", Hunks: []Hunk{{
+					Header: "@@ -1,2 +1,2 @@", OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2,
+					Lines: []HunkLine{{LineContext, "a"}, {LineDelete, "b"}, {LineAdd, "c"}},
+				}}},
+				{OldPath: DevNull, NewPath: "new.txt", Hunks: []Hunk{{
+					Header: "@@ -0,0 +1 @@", OldStart: 0, OldLines: 0, NewStart: 1, NewLines: 1,
+					Lines: []HunkLine{{LineAdd, "hello"}}, NewNoFinalNewline: true,
+				}}},
+				{OldPath: "old.txt", NewPath: DevNull, Hunks: []Hunk{{
+					Header: "@@ -1 +0,0 @@", OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0,
+					Lines: []HunkLine{{LineDelete, "bye"}},
+				}}},
+			},
+		},
+		{
+			name:  "plain_paths_timestamps_crlf_and_wrong_counts",
+			patch: "--- f.txt\t2024-01-01 00:00:00\r\n+++ f.txt\t2024-01-02 00:00:00\r\n@@ -3,9 +3,9 @@ func x\r\n ctx\r\n-old\r\n+new\r\n",
+			wantFiles: []FilePatch{{OldPath: "f.txt", NewPath: "f.txt", Hunks: []Hunk{{
+				Header: "@@ -3,9 +3,9 @@ func x", OldStart: 3, OldLines: 9, NewStart: 3, NewLines: 9,
+				Lines: []HunkLine{{LineContext, "ctx"}, {LineDelete, "old"}, {LineAdd, "new"}},
+			}}}},
+		},
+		{
+			name:  "blank_line_is_context_but_trailing_separator_dropped",
+			patch: "--- a/f\n+++ b/f\n@@ -1,3 +1,3 @@\n a\n\n-b\n+c\n\n\nsome trailing prose\n",
+			wantFiles: []FilePatch{{OldPath: "f", NewPath: "f", Hunks: []Hunk{{
+				Header: "@@ -1,3 +1,3 @@", OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3,
+				Lines: []HunkLine{{LineContext, "a"}, {LineContext, ""}, {LineDelete, "b"}, {LineAdd, "c"}},
+			}}}},
+		},
+		{
+			name: "counts_keep_header_like_lines_in_the_body",
+			patch: "--- a/q.sql\n+++ b/q.sql\n@@ -1,2 +1,2 @@\n select 1;\n--- old comment\n+++ new counter\n" +
+				"@@ -9,2 +9,1 @@\n x\n--- last\n--- a/r.txt\n+++ b/r.txt\n@@ -1 +1 @@\n-r\n+s\n",
+			wantFiles: []FilePatch{
+				{OldPath: "q.sql", NewPath: "q.sql", Hunks: []Hunk{
+					{
+						Header: "@@ -1,2 +1,2 @@", OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 2,
+						Lines: []HunkLine{{LineContext, "select 1;"}, {LineDelete, "-- old comment"}, {LineAdd, "++ new counter"}},
+					},
+					{
+						Header: "@@ -9,2 +9,1 @@", OldStart: 9, OldLines: 2, NewStart: 9, NewLines: 1,
+						Lines: []HunkLine{{LineContext, "x"}, {LineDelete, "-- last"}},
+					},
+				}},
+				{OldPath: "r.txt", NewPath: "r.txt", Hunks: []Hunk{{
+					Header: "@@ -1 +1 @@", OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1,
+					Lines: []HunkLine{{LineDelete, "r"}, {LineAdd, "s"}},
+				}}},
+			},
+		},
+		{name: "no_headers_errors", patch: "just text\n", wantErr: "no file headers"},
+		{name: "hunk_before_header_errors", patch: "@@ -1 +1 @@\n-a\n+b\n", wantErr: "before any"},
+		{name: "header_without_hunks_errors", patch: "--- a/f\n+++ b/f\n", wantErr: "without hunks"},
+		{name: "malformed_hunk_header_errors", patch: "--- a/f\n+++ b/f\n@@ -x +1 @@\n-a\n", wantErr: "malformed hunk header"},
+		{name: "binary_patch_errors", patch: "--- a/f\n+++ b/f\nGIT binary patch\n", wantErr: "binary"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseUnified(tc.patch)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err=%v want substring %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tc.wantFiles) {
+				t.Fatalf("got %d files, want %d: %+v", len(got), len(tc.wantFiles), got)
+			}
+			for i := range got {
+				if !equalFilePatch(got[i], tc.wantFiles[i]) {
+					t.Fatalf("file %d:\n got=%+v\nwant=%+v", i, got[i], tc.wantFiles[i])
+				}
+			}
+		})
+	}
+}
+
+func equalFilePatch(a, b FilePatch) bool {
+	if a.OldPath != b.OldPath || a.NewPath != b.NewPath || len(a.Hunks) != len(b.Hunks) {
+		return false
+	}
+	for i := range a.Hunks {
+		ha, hb := a.Hunks[i], b.Hunks[i]
+		if ha.Header != hb.Header || ha.OldStart != hb.OldStart || ha.OldLines != hb.OldLines ||
+			ha.NewStart != hb.NewStart || ha.NewLines != hb.NewLines ||
+			ha.OldNoFinalNewline != hb.OldNoFinalNewline || ha.NewNoFinalNewline != hb.NewNoFinalNewline ||
+			len(ha.Lines) != len(hb.Lines) {
+			return false
+		}
+		for j := range ha.Lines {
+			if ha.Lines[j] != hb.Lines[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
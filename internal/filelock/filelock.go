@@ -0,0 +1,225 @@
+// Package filelock serializes read-modify-write cycles on files across concurrent tool calls.
+//
+// Every lock is an in-process lock keyed by the filesystem and canonical path, shared by all tool
+// instances in the process; a directory lock also covers the paths beneath it. Optionally, an OS
+// advisory lock (flock on unix, LockFileEx on Windows) is taken as well, on a lock file named after
+// the canonical path in a shared lock directory, so other processes using the same directory are
+// excluded too. Target files are never locked directly: the tools replace them by rename, which
+// would orphan a lock held on the old file.
+package filelock
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// DefaultTimeout bounds how long a tool call waits for a lock.
+const DefaultTimeout = 30 * time.Second
+
+// ErrTimeout is returned (wrapped) when a lock is not acquired within the timeout.
+var ErrTimeout = errors.New("timed out waiting for file lock")
+
+// Config controls how paths are locked.
+type Config struct {
+	// Timeout bounds the wait for each lock; <= 0 waits until ctx is done.
+	Timeout time.Duration
+	// OSLocks additionally takes OS advisory locks (OS filesystem only).
+	OSLocks bool
+	// Dir holds the OS lock files; empty means DefaultDir().
+	Dir string
+}
+
+// DefaultDir is the lock directory used when Config.Dir is empty.
+func DefaultDir() string {
+	return filepath.Join(os.TempDir(), "llmtools-go-locks")
+}
+
+type key struct {
+	fsys any // see fsKey
+	path string
+}
+
+var (
+	tableMu sync.Mutex
+	held    = map[key]int{}       // locked paths and their holder counts
+	changed = make(chan struct{}) // closed and replaced whenever a lock is released
+)
+
+// Lock locks the absolute paths of fsys and returns a function releasing them. A lock on a directory
+// also excludes locks on paths beneath it (and the other way round), so a recursive delete or move
+// does not race edits inside the tree. All paths are taken at once, so calls locking overlapping sets
+// cannot deadlock. Duplicate paths are locked once.
+//
+// OS advisory locks are per path only: another process is excluded from the same path, not from
+// paths beneath a locked directory.
+func Lock(ctx context.Context, fsys vfs.FS, cfg Config, paths ...string) (unlock func(), err error) {
+	fsys = vfs.OrOS(fsys)
+	canon := make([]string, 0, len(paths))
+	for _, p := range paths {
+		canon = append(canon, canonical(fsys, p))
+	}
+	slices.Sort(canon)
+	canon = slices.Compact(canon)
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, cfg.Timeout, ErrTimeout)
+		defer cancel()
+	}
+	keys := make([]key, 0, len(canon))
+	for _, p := range canon {
+		keys = append(keys, key{fsys: fsKey(fsys), path: p})
+	}
+	if err := lockKeys(ctx, keys); err != nil {
+		return nil, fmt.Errorf("lock %s: %w", strings.Join(canon, ", "), err)
+	}
+	releases := []func(){func() { unlockKeys(keys) }}
+	release := func() {
+		for _, u := range slices.Backward(releases) {
+			u()
+		}
+	}
+
+	if !cfg.OSLocks || !vfs.IsOS(fsys) {
+		return release, nil
+	}
+	for _, p := range canon {
+		u, err := lockOSFile(ctx, cfg.Dir, p)
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("lock %s: %w", p, err)
+		}
+		releases = append(releases, u)
+	}
+	return release, nil
+}
+
+// lockKeys waits until no key conflicts with a held lock, then holds all of them.
+func lockKeys(ctx context.Context, keys []key) error {
+	for {
+		tableMu.Lock()
+		if !slices.ContainsFunc(keys, conflicts) {
+			for _, k := range keys {
+				held[k]++
+			}
+			tableMu.Unlock()
+			return nil
+		}
+		wait := changed
+		tableMu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+}
+
+func unlockKeys(keys []key) {
+	tableMu.Lock()
+	defer tableMu.Unlock()
+	for _, k := range keys {
+		if held[k]--; held[k] == 0 {
+			delete(held, k)
+		}
+	}
+	close(changed)
+	changed = make(chan struct{})
+}
+
+// conflicts reports whether k is held, or lies inside or contains a held path. tableMu must be held.
+func conflicts(k key) bool {
+	for h := range held {
+		if h.fsys == k.fsys && (within(h.path, k.path) || within(k.path, h.path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// within reports whether path is dir or lies beneath it.
+func within(dir, path string) bool {
+	if path == dir {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
+
+// fsKey returns a comparable identity for fsys. Most filesystems (pointers, empty structs) are their
+// own identity; one whose value is not comparable, and would panic as a map key, is identified by its
+// type, so all its instances share locks.
+func fsKey(fsys vfs.FS) any {
+	if v := reflect.ValueOf(fsys); !v.Comparable() {
+		return v.Type()
+	}
+	return fsys
+}
+
+// lockOSFile takes an exclusive advisory lock on the lock file for path, polling until it is free.
+func lockOSFile(ctx context.Context, dir, path string) (func(), error) {
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(path))
+	f, err := os.OpenFile(filepath.Join(dir, hex.EncodeToString(sum[:16])+".lock"), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	for wait := time.Millisecond; ; wait = min(2*wait, 50*time.Millisecond) {
+		ok, err := tryLock(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		if ok {
+			return func() {
+				_ = unlockFile(f)
+				_ = f.Close()
+			}, nil
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			_ = f.Close()
+			return nil, context.Cause(ctx)
+		}
+	}
+}
+
+// canonical returns the key for path: symlinks in its existing prefix are resolved (so links to one
+// file share a lock), and case is folded on the Windows OS filesystem.
+func canonical(fsys vfs.FS, path string) string {
+	p := filepath.Clean(path)
+	if r, err := fsys.EvalSymlinks(p); err == nil {
+		p = r
+	} else if r, err := fsys.EvalSymlinks(filepath.Dir(p)); err == nil {
+		p = filepath.Join(r, filepath.Base(p))
+	}
+	if vfs.IsOS(fsys) && runtime.GOOS == toolutil.GOOSWindows {
+		p = strings.ToLower(p)
+	}
+	return p
+}
@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package filelock
+
+import (
+	"errors"
+	"os"
+)
+
+func tryLock(*os.File) (bool, error) {
+	return false, errors.ErrUnsupported
+}
+
+func unlockFile(*os.File) error { return nil }
@@ -0,0 +1,192 @@
+package filelock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestLock(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	sub := filepath.Join(dir, "sub")
+	subFile := filepath.Join(sub, "x.txt")
+	short := Config{Timeout: 20 * time.Millisecond}
+
+	tests := []struct {
+		name    string
+		fsys    vfs.FS
+		cfg     Config
+		held    []string
+		lock    []string
+		wantErr error
+	}{
+		{name: "same path times out", cfg: short, held: []string{a}, lock: []string{a}, wantErr: ErrTimeout},
+		{name: "other path is free", cfg: short, held: []string{a}, lock: []string{b}},
+		{name: "any overlap times out", cfg: short, held: []string{b}, lock: []string{a, b}, wantErr: ErrTimeout},
+		{name: "duplicates lock once", cfg: short, lock: []string{a, a, filepath.Join(dir, ".", "a.txt")}},
+		{name: "directory lock covers children", cfg: short, held: []string{sub}, lock: []string{subFile}, wantErr: ErrTimeout},
+		{name: "child lock blocks directory", cfg: short, held: []string{subFile}, lock: []string{sub}, wantErr: ErrTimeout},
+		{name: "sibling with shared prefix is free", cfg: short, held: []string{sub}, lock: []string{sub + "x"}},
+		{name: "other filesystem is free", fsys: vfs.NewMemFS(), cfg: short, held: []string{a}, lock: []string{a}},
+		{
+			name:    "os lock held elsewhere times out",
+			cfg:     Config{Timeout: 20 * time.Millisecond, OSLocks: true, Dir: filepath.Join(dir, "locks")},
+			held:    []string{"os:" + a},
+			lock:    []string{a},
+			wantErr: ErrTimeout,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Not parallel: subtests share paths in the process-wide lock table.
+			for _, p := range tc.held {
+				var unlock func()
+				var err error
+				if rest, ok := strings.CutPrefix(p, "os:"); ok {
+					// A lock file held through another descriptor stands in for another process.
+					unlock, err = lockOSFile(t.Context(), tc.cfg.Dir, canonical(vfs.OS(), rest))
+				} else {
+					unlock, err = Lock(t.Context(), nil, Config{}, p)
+				}
+				if err != nil {
+					t.Fatalf("hold %s: %v", p, err)
+				}
+				t.Cleanup(unlock)
+			}
+
+			unlock, err := Lock(t.Context(), tc.fsys, tc.cfg, tc.lock...)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("Lock error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lock: %v", err)
+			}
+			unlock()
+		})
+	}
+}
+
+func TestLock_Serializes(t *testing.T) {
+	t.Parallel()
+
+	p := filepath.Join(t.TempDir(), "counter")
+	cfg := Config{Timeout: 10 * time.Second, OSLocks: true, Dir: t.TempDir()}
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		inside  int
+		overlap bool
+	)
+	for range 8 {
+		wg.Go(func() {
+			unlock, err := Lock(t.Context(), nil, cfg, p)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			inside++
+			overlap = overlap || inside > 1
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			inside--
+			mu.Unlock()
+			unlock()
+		})
+	}
+	wg.Wait()
+	if overlap {
+		t.Fatal("two holders were inside the lock at once")
+	}
+}
+
+func TestLock_SymlinkSharesLock(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == toolutil.GOOSWindows {
+		t.Skip("symlinks need privileges on windows")
+	}
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(target, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.txt")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := Lock(t.Context(), nil, Config{}, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := Lock(ctx, nil, Config{}, link); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Lock via symlink error = %v, want deadline exceeded", err)
+	}
+}
+
+// sliceFS is a filesystem whose values are not comparable.
+type sliceFS struct {
+	vfs.FS
+	tags []string
+}
+
+func TestLock_MemFS(t *testing.T) {
+	t.Parallel()
+
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := vfs.NewMemFS()
+	if err := m.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(root, "target.txt")
+	if err := vfs.WriteFile(m, target, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link.txt")
+	if err := m.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+	short := Config{Timeout: 20 * time.Millisecond}
+
+	unlock, err := Lock(t.Context(), m, Config{}, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	if _, err := Lock(t.Context(), m, short, link); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Lock via memfs symlink error = %v, want ErrTimeout", err)
+	}
+
+	// A non-comparable filesystem must not panic as a lock key.
+	fsys := sliceFS{FS: vfs.NewMemFS(), tags: []string{"a"}}
+	u, err := Lock(t.Context(), fsys, short, target)
+	if err != nil {
+		t.Fatalf("Lock on non-comparable fs: %v", err)
+	}
+	u()
+}
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 func mkdirAll(t *testing.T, p string) string {
@@ -73,6 +75,6 @@ func pathAbs(t *testing.T, p string) string {
 		t.Fatalf("Abs(%q): %v", p, err)
 	}
 	abs = applySystemRootAliases(abs)
-	abs = evalSymlinksBestEffort(abs)
+	abs = evalSymlinksBestEffort(vfs.OS(), abs)
 	return abs
 }
@@ -6,12 +6,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 var errPathMustBeAbsolute = errors.New("path must be absolute")
 
-func ensureDirExists(p string) error {
-	st, err := os.Stat(p)
+func ensureDirExists(fsys vfs.FS, p string) error {
+	st, err := fsys.Stat(p)
 	if err != nil {
 		return errors.Join(err, errors.New("no such dir"))
 	}
@@ -68,16 +70,16 @@ func normalizePath(p string) (string, error) {
 	return filepath.Clean(p), nil
 }
 
-// evalSymlinksBestEffort tries filepath.EvalSymlinks on p. If p doesn't exist,
+// evalSymlinksBestEffort tries fsys.EvalSymlinks on p. If p doesn't exist,
 // it walks up to the nearest existing parent, resolves that, then joins the
 // remainder back on.
-func evalSymlinksBestEffort(p string) string {
+func evalSymlinksBestEffort(fsys vfs.FS, p string) string {
 	p = filepath.Clean(p)
 	tried := p
 	remainder := ""
 
 	for range 64 {
-		if resolved, err := filepath.EvalSymlinks(tried); err == nil && resolved != "" {
+		if resolved, err := fsys.EvalSymlinks(tried); err == nil && resolved != "" {
 			resolved = filepath.Clean(resolved)
 			if remainder == "" {
 				return resolved
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 func TestNormalizePath(t *testing.T) {
@@ -215,7 +217,7 @@ func TestEvalSymlinksBestEffort(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
-			got := evalSymlinksBestEffort(c.in)
+			got := evalSymlinksBestEffort(vfs.OS(), c.in)
 			if got != c.want {
 				t.Fatalf("evalSymlinksBestEffort(%q)=%q, want %q", c.in, got, c.want)
 			}
@@ -304,7 +306,7 @@ func TestCanonicalizeExistingDir_Errors(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			t.Parallel()
-			_, err := canonicalizeExistingDir(c.in)
+			_, err := canonicalizeExistingDir(vfs.OS(), c.in)
 			if err == nil {
 				t.Fatalf("expected error, got nil")
 			}
@@ -8,6 +8,9 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/flexigpt/llmtools-go/quota"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 var (
@@ -32,10 +35,14 @@ var (
 //     detect symlink inputs.
 //   - If blockSymlinks is true, directory traversal refuses symlink components and
 //     file operations can refuse symlink files (depending on caller and method).
+//   - Every filesystem access goes through the policy's vfs.FS (the OS by default), so the
+//     same rules apply to in-memory and other backends.
 type FSPolicy struct {
+	fsys          vfs.FS
 	allowedRoots  []string
 	workBaseDir   string
 	blockSymlinks bool
+	quota         *quota.Quota
 }
 
 // New initializes a hardened filesystem policy.
@@ -44,9 +51,18 @@ type FSPolicy struct {
 //   - if allowedRoots is set => defaults to allowedRoots[0]
 //   - else => defaults to process CWD
 func New(workBaseDir string, allowedRoots []string, blockSymlinks bool) (FSPolicy, error) {
+	return NewWithFS(vfs.OS(), workBaseDir, allowedRoots, blockSymlinks)
+}
+
+// NewWithFS is New over the given filesystem (nil => OS).
+// For filesystems other than the OS, workBaseDir or allowedRoots must be set and absolute, since
+// the process CWD means nothing there.
+func NewWithFS(fsys vfs.FS, workBaseDir string, allowedRoots []string, blockSymlinks bool) (FSPolicy, error) {
+	fsys = vfs.OrOS(fsys)
 	// Defense-in-depth: if symlinks are blocked, require that configured roots/base
 	// contain no symlink components (and allow only explicit system symlinks via allowSystemSymlink).
 	tmpPolicy := FSPolicy{
+		fsys:          fsys,
 		allowedRoots:  allowedRoots,
 		workBaseDir:   workBaseDir,
 		blockSymlinks: blockSymlinks,
@@ -68,7 +84,7 @@ func New(workBaseDir string, allowedRoots []string, blockSymlinks bool) (FSPolic
 			}
 		}
 	}
-	roots, err := canonicalizeAllowedRoots(allowedRoots)
+	roots, err := canonicalizeAllowedRoots(fsys, allowedRoots)
 	if err != nil {
 		return FSPolicy{}, err
 	}
@@ -78,6 +94,9 @@ func New(workBaseDir string, allowedRoots []string, blockSymlinks bool) (FSPolic
 		if len(roots) > 0 {
 			base = roots[0]
 		} else {
+			if !vfs.IsOS(fsys) {
+				return FSPolicy{}, errors.New("work base dir or allowed roots are required for a non-OS filesystem")
+			}
 			cwd, e := os.Getwd()
 			if e != nil {
 				return FSPolicy{}, e
@@ -86,7 +105,10 @@ func New(workBaseDir string, allowedRoots []string, blockSymlinks bool) (FSPolic
 		}
 	}
 
-	baseCanon, err := canonicalizeExistingDir(base)
+	if !vfs.IsOS(fsys) && !filepath.IsAbs(filepath.FromSlash(base)) {
+		return FSPolicy{}, fmt.Errorf("invalid work base dir %q: %w", workBaseDir, errPathMustBeAbsolute)
+	}
+	baseCanon, err := canonicalizeExistingDir(fsys, base)
 	if err != nil {
 		return FSPolicy{}, fmt.Errorf("invalid work base dir %q: %w", workBaseDir, err)
 	}
@@ -96,6 +118,7 @@ func New(workBaseDir string, allowedRoots []string, blockSymlinks bool) (FSPolic
 	}
 
 	p := FSPolicy{
+		fsys:          fsys,
 		allowedRoots:  roots,
 		workBaseDir:   baseCanon,
 		blockSymlinks: blockSymlinks,
@@ -104,6 +127,18 @@ func New(workBaseDir string, allowedRoots []string, blockSymlinks bool) (FSPolic
 	return p, nil
 }
 
+// FS returns the filesystem the policy operates on; the zero FSPolicy uses the OS.
+func (p FSPolicy) FS() vfs.FS { return vfs.OrOS(p.fsys) }
+
+// WithQuota returns a copy of p whose writes are charged to q (nil: unmetered).
+func (p FSPolicy) WithQuota(q *quota.Quota) FSPolicy {
+	p.quota = q
+	return p
+}
+
+// Quota returns the write quota, or nil if writes are unmetered.
+func (p FSPolicy) Quota() *quota.Quota { return p.quota }
+
 func (p FSPolicy) WorkBaseDir() string { return p.workBaseDir }
 func (p FSPolicy) BlockSymlinks() bool { return p.blockSymlinks }
 func (p FSPolicy) HasAllowedRoots() bool {
@@ -137,10 +172,10 @@ func (p FSPolicy) VerifyDirResolved(absDir string) error {
 		return errPathMustBeAbsolute
 	}
 	d = filepath.Clean(d)
-	d = applySystemRootAliases(d)
+	d = p.applySystemRootAliases(d)
 
 	if !p.blockSymlinks {
-		st, err := os.Stat(d)
+		st, err := p.FS().Stat(d)
 		if err != nil {
 			return fmt.Errorf("stat dir error: %w", err)
 		}
@@ -170,12 +205,12 @@ func (p FSPolicy) EnsureDirResolved(absDir string, maxNewDirs int) (created int,
 		return 0, errPathMustBeAbsolute
 	}
 	d = filepath.Clean(d)
-	d = applySystemRootAliases(d)
+	d = p.applySystemRootAliases(d)
 
 	if !p.blockSymlinks {
 		// If symlinks are allowed, we intentionally do not try to count created dirs.
 		// (Counting accurately would require additional TOCTOU-prone stat logic.)
-		return 0, os.MkdirAll(d, 0o755)
+		return 0, p.FS().MkdirAll(d, 0o755)
 	}
 	return p.walkDirNoSymlinkAbs(d, true, maxNewDirs)
 }
@@ -197,7 +232,7 @@ func (p FSPolicy) RequireExistingRegularFileResolved(absPath string) (fs.FileInf
 		return nil, errPathMustBeAbsolute
 	}
 	ap = filepath.Clean(ap)
-	ap = applySystemRootAliases(ap)
+	ap = p.applySystemRootAliases(ap)
 	return p.requireExistingRegularFileAbs(ap)
 }
 
@@ -244,10 +279,10 @@ func (p FSPolicy) resolvePathWithCheck(inputPath, defaultIfEmpty string) (absLex
 	absLex = filepath.Clean(absLex)
 
 	// Keep system paths coherent with canonicalization.
-	absLex = applySystemRootAliases(absLex)
+	absLex = p.applySystemRootAliases(absLex)
 
 	// Canonicalize for allowed-root comparisons (symlinks/junctions/8.3 names).
-	absCheck = evalSymlinksBestEffort(absLex)
+	absCheck = evalSymlinksBestEffort(p.FS(), absLex)
 
 	if err := ensureWithinRoots(absCheck, p.allowedRoots); err != nil {
 		return "", "", fmt.Errorf("path %q (resolved to %q): %w", absLex, absCheck, err)
@@ -269,7 +304,7 @@ func (p FSPolicy) requireExistingRegularFileAbs(absPath string) (fs.FileInfo, er
 			}
 		}
 
-		st, err := os.Lstat(absPath)
+		st, err := p.FS().Lstat(absPath)
 		if err != nil {
 			return nil, fmt.Errorf("got stat file error: %w", err)
 		}
@@ -286,7 +321,7 @@ func (p FSPolicy) requireExistingRegularFileAbs(absPath string) (fs.FileInfo, er
 	}
 
 	// Symlinks allowed: Stat follows symlinks.
-	st, err := os.Stat(absPath)
+	st, err := p.FS().Stat(absPath)
 	if err != nil {
 		return nil, fmt.Errorf("stat file error: %w", err)
 	}
@@ -343,11 +378,11 @@ func (p FSPolicy) walkDirNoSymlinkAbs(dir string, createMissing bool, maxNewDirs
 	for _, part := range parts {
 		cur = filepath.Join(cur, part)
 
-		st, err := os.Lstat(cur)
+		st, err := p.FS().Lstat(cur)
 		if err == nil {
 			if (st.Mode() & os.ModeSymlink) != 0 {
 				// Allow explicit system symlinks via platform helper.
-				if resolved, ok, aerr := allowSystemSymlink(cur); aerr != nil {
+				if resolved, ok, aerr := p.allowSystemSymlink(cur); aerr != nil {
 					return created, aerr
 				} else if ok {
 					cur = resolved
@@ -375,7 +410,7 @@ func (p FSPolicy) walkDirNoSymlinkAbs(dir string, createMissing bool, maxNewDirs
 		if maxNewDirs > 0 && created >= maxNewDirs {
 			return created, fmt.Errorf("too many parent directories to create (max %d)", maxNewDirs)
 		}
-		if err := os.Mkdir(cur, 0o755); err != nil {
+		if err := p.FS().Mkdir(cur, 0o755); err != nil {
 			return created, fmt.Errorf("could not make dir:%w", err)
 		}
 		created++
@@ -383,7 +418,7 @@ func (p FSPolicy) walkDirNoSymlinkAbs(dir string, createMissing bool, maxNewDirs
 
 	// Verify final is a directory if it already existed and we weren't creating.
 	if !createMissing {
-		st, err := os.Stat(d)
+		st, err := p.FS().Stat(d)
 		if err != nil {
 			return created, fmt.Errorf("stat error: %w", err)
 		}
@@ -395,7 +430,7 @@ func (p FSPolicy) walkDirNoSymlinkAbs(dir string, createMissing bool, maxNewDirs
 	return created, nil
 }
 
-func canonicalizeAllowedRoots(roots []string) ([]string, error) {
+func canonicalizeAllowedRoots(fsys vfs.FS, roots []string) ([]string, error) {
 	if len(roots) == 0 {
 		return nil, nil
 	}
@@ -406,7 +441,10 @@ func canonicalizeAllowedRoots(roots []string) ([]string, error) {
 		if r == "" {
 			continue
 		}
-		cr, err := canonicalizeExistingDir(r)
+		if !vfs.IsOS(fsys) && !filepath.IsAbs(filepath.FromSlash(r)) {
+			return nil, fmt.Errorf("invalid allowed root %q: %w", r, errPathMustBeAbsolute)
+		}
+		cr, err := canonicalizeExistingDir(fsys, r)
 		if err != nil {
 			return nil, fmt.Errorf("invalid allowed root %q: %w", r, err)
 		}
@@ -418,18 +456,18 @@ func canonicalizeAllowedRoots(roots []string) ([]string, error) {
 	return out, nil
 }
 
-func canonicalizeExistingDir(p string) (string, error) {
-	abs, err := canonicalizeDir(p)
+func canonicalizeExistingDir(fsys vfs.FS, p string) (string, error) {
+	abs, err := canonicalizeDir(fsys, p)
 	if err != nil {
 		return "", err
 	}
-	if err := ensureDirExists(abs); err != nil {
+	if err := ensureDirExists(fsys, abs); err != nil {
 		return "", err
 	}
 	return abs, nil
 }
 
-func canonicalizeDir(p string) (string, error) {
+func canonicalizeDir(fsys vfs.FS, p string) (string, error) {
 	if strings.ContainsRune(p, '\x00') {
 		return "", errors.New("path contains NUL byte")
 	}
@@ -440,7 +478,25 @@ func canonicalizeDir(p string) (string, error) {
 		return "", err
 	}
 
-	abs = applySystemRootAliases(abs)
-	abs = evalSymlinksBestEffort(abs)
+	if vfs.IsOS(fsys) {
+		abs = applySystemRootAliases(abs)
+	}
+	abs = evalSymlinksBestEffort(fsys, abs)
 	return abs, nil
 }
+
+// applySystemRootAliases applies the platform's system root aliases on the OS filesystem only;
+// other filesystems have no such compatibility symlinks.
+func (p FSPolicy) applySystemRootAliases(s string) string {
+	if !vfs.IsOS(p.FS()) {
+		return filepath.Clean(s)
+	}
+	return applySystemRootAliases(s)
+}
+
+func (p FSPolicy) allowSystemSymlink(cur string) (resolved string, ok bool, err error) {
+	if !vfs.IsOS(p.FS()) {
+		return "", false, nil
+	}
+	return allowSystemSymlink(cur)
+}
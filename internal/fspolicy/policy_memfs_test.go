@@ -0,0 +1,146 @@
+package fspolicy
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// memAbs returns an absolute path under the filesystem root ("/a/b" or `C:\a\b`).
+func memAbs(elem ...string) string {
+	p, _ := filepath.Abs(filepath.Join(append([]string{string(filepath.Separator)}, elem...)...))
+	return p
+}
+
+func TestNewWithFS_MemFS(t *testing.T) {
+	t.Parallel()
+
+	root := memAbs("ws")
+	outside := memAbs("outside")
+	newFS := func(t *testing.T) *vfs.MemFS {
+		t.Helper()
+		m := vfs.NewMemFS()
+		for _, d := range []string{filepath.Join(root, "real"), outside} {
+			if err := m.MkdirAll(d, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := vfs.WriteFile(m, filepath.Join(root, "real", "f.txt"), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := vfs.WriteFile(m, filepath.Join(outside, "secret"), []byte("s"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// An in-root symlink, and one escaping the root.
+		if err := m.Symlink(filepath.Join(root, "real"), filepath.Join(root, "link")); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, m *vfs.MemFS)
+	}{
+		{
+			name: "relative_paths_resolve_against_base",
+			run: func(t *testing.T, m *vfs.MemFS) {
+				t.Helper()
+				p, err := NewWithFS(m, "", []string{root}, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := p.ResolvePath("real/f.txt", "")
+				if err != nil || got != filepath.Join(root, "real", "f.txt") {
+					t.Fatalf("ResolvePath = %q, %v", got, err)
+				}
+				if _, err := p.RequireExistingRegularFileResolved(got); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "roots_enforced_through_symlinks",
+			run: func(t *testing.T, m *vfs.MemFS) {
+				t.Helper()
+				p, err := NewWithFS(m, root, []string{root}, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := p.ResolvePath(filepath.Join(outside, "secret"), ""); !errors.Is(err, ErrOutsideAllowedRoots) {
+					t.Fatalf("outside path err = %v", err)
+				}
+				if _, err := p.ResolvePath("escape/secret", ""); !errors.Is(err, ErrOutsideAllowedRoots) {
+					t.Fatalf("symlink escape err = %v", err)
+				}
+				if _, err := p.ResolvePath("link/f.txt", ""); err != nil {
+					t.Fatalf("in-root symlink: %v", err)
+				}
+			},
+		},
+		{
+			name: "block_symlinks",
+			run: func(t *testing.T, m *vfs.MemFS) {
+				t.Helper()
+				p, err := NewWithFS(m, root, []string{root}, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := p.VerifyDirResolved(filepath.Join(root, "link")); !errors.Is(err, ErrSymlinkDisallowed) {
+					t.Fatalf("VerifyDirResolved err = %v", err)
+				}
+				if _, err := p.RequireExistingRegularFileResolved(filepath.Join(root, "link", "f.txt")); !errors.Is(
+					err, ErrSymlinkDisallowed,
+				) {
+					t.Fatalf("RequireExistingRegularFileResolved err = %v", err)
+				}
+				if _, err := NewWithFS(m, filepath.Join(root, "link"), nil, true); !errors.Is(err, ErrSymlinkDisallowed) {
+					t.Fatalf("symlinked base err = %v", err)
+				}
+			},
+		},
+		{
+			name: "ensure_dir_counts_created",
+			run: func(t *testing.T, m *vfs.MemFS) {
+				t.Helper()
+				p, err := NewWithFS(m, root, []string{root}, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+				created, err := p.EnsureDirResolved(filepath.Join(root, "a", "b"), 0)
+				if err != nil || created != 2 {
+					t.Fatalf("EnsureDirResolved = %d, %v", created, err)
+				}
+				if _, err := p.EnsureDirResolved(filepath.Join(root, "c", "d", "e"), 2); err == nil {
+					t.Fatal("maxNewDirs not enforced")
+				}
+			},
+		},
+		{
+			name: "base_required_and_absolute",
+			run: func(t *testing.T, m *vfs.MemFS) {
+				t.Helper()
+				if _, err := NewWithFS(m, "", nil, false); err == nil {
+					t.Fatal("expected error without base or roots")
+				}
+				if _, err := NewWithFS(m, "ws", nil, false); !errors.Is(err, errPathMustBeAbsolute) {
+					t.Fatalf("relative base err = %v", err)
+				}
+				if _, err := NewWithFS(m, memAbs("missing"), nil, false); err == nil {
+					t.Fatal("expected error for a missing base dir")
+				}
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tc.run(t, newFS(t))
+		})
+	}
+}
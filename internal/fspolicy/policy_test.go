@@ -9,6 +9,8 @@ import (
 	"strings"
 	"sync"
 	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 func TestNew_CanonicalizesSortsAndDefaultsBase(t *testing.T) {
@@ -431,7 +433,7 @@ func TestConcurrency_ResolvePathAndEnsureDir(t *testing.T) {
 			}
 			checkPath := applySystemRootAliases(shared + string(os.PathSeparator))
 			// Canonicalize for allowed-root comparisons (symlinks/junctions/8.3 names).
-			checkPath = evalSymlinksBestEffort(checkPath)
+			checkPath = evalSymlinksBestEffort(vfs.OS(), checkPath)
 			if !strings.HasPrefix(abs, checkPath) && abs != shared {
 				errCh <- fmt.Errorf("resolved path not under shared: %q", abs)
 				return
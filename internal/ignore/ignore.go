@@ -0,0 +1,124 @@
+// Package ignore matches slash-separated relative paths against gitignore-style rules and globs.
+//
+// Supported rule syntax (a subset of gitignore):
+//   - blank lines and lines starting with # are skipped;
+//   - a leading ! negates a rule (the last matching rule wins);
+//   - a trailing / matches directories only;
+//   - a pattern without a slash (other than a trailing one) matches the name at any depth, otherwise
+//     it is anchored at the root (a leading / is optional);
+//   - * and ? match within a path segment, [...] matches a character class, and ** matches any number
+//     of segments.
+package ignore
+
+import (
+	"path"
+	"strings"
+)
+
+type rule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// Rules is an ordered set of ignore rules. The zero value ignores nothing.
+type Rules struct {
+	rules []rule
+}
+
+// New returns rules parsed from lines (e.g. the lines of a .gitignore file).
+func New(lines ...string) *Rules {
+	r := &Rules{}
+	r.Add(lines...)
+	return r
+}
+
+// Add appends rules parsed from lines.
+func (r *Rules) Add(lines ...string) {
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var ru rule
+		if strings.HasPrefix(line, "!") {
+			ru.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:] // escaped leading # or !
+		}
+		if strings.HasSuffix(line, "/") {
+			ru.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		ru.pattern = line
+		r.rules = append(r.rules, ru)
+	}
+}
+
+// Match reports whether rel (slash-separated, relative to the root) is ignored. Parents are not
+// consulted: callers walking a tree skip ignored directories themselves.
+func (r *Rules) Match(rel string, isDir bool) bool {
+	if r == nil {
+		return false
+	}
+	ignored := false
+	for _, ru := range r.rules {
+		if ru.dirOnly && !isDir {
+			continue
+		}
+		if Glob(ru.pattern, rel) {
+			ignored = !ru.negate
+		}
+	}
+	return ignored
+}
+
+// Glob reports whether the slash-separated path rel matches pattern, where ** matches any number of
+// whole segments (including none). A malformed pattern matches nothing.
+func Glob(pattern, rel string) bool {
+	return matchSegs(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// ValidGlob reports whether pattern is well formed.
+func ValidGlob(pattern string) bool {
+	for seg := range strings.SplitSeq(pattern, "/") {
+		if seg == "**" {
+			continue
+		}
+		if _, err := path.Match(seg, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func matchSegs(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			rest := pat[1:]
+			for i := 0; i <= len(segs); i++ {
+				if matchSegs(rest, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pat[0], segs[0]); err != nil || !ok {
+			return false
+		}
+		pat, segs = pat[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
@@ -0,0 +1,81 @@
+package ignore
+
+import "testing"
+
+func TestGlob(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{pattern: "*.go", rel: "main.go", want: true},
+		{pattern: "*.go", rel: "pkg/main.go", want: false},
+		{pattern: "**/*.go", rel: "pkg/main.go", want: true},
+		{pattern: "**/*.go", rel: "main.go", want: true},
+		{pattern: "src/**", rel: "src/a/b.txt", want: true},
+		{pattern: "src/**", rel: "src", want: true},
+		{pattern: "src/**/test", rel: "src/test", want: true},
+		{pattern: "src/**/test", rel: "src/x/y/test", want: true},
+		{pattern: "src/**/test", rel: "lib/test", want: false},
+		{pattern: "a?c/[xy].txt", rel: "abc/y.txt", want: true},
+		{pattern: "[", rel: "[", want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.pattern+" "+tc.rel, func(t *testing.T) {
+			t.Parallel()
+			if got := Glob(tc.pattern, tc.rel); got != tc.want {
+				t.Fatalf("Glob(%q, %q) = %v, want %v", tc.pattern, tc.rel, got, tc.want)
+			}
+		})
+	}
+	if ValidGlob("a/[") || !ValidGlob("**/*.go") {
+		t.Fatal("ValidGlob misreports")
+	}
+}
+
+func TestRules_Match(t *testing.T) {
+	t.Parallel()
+
+	r := New(
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/root-only.txt",
+		"docs/*.tmp",
+		`\#hash`,
+	)
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{rel: "a.log", want: true},
+		{rel: "sub/b.log", want: true},
+		{rel: "sub/keep.log", want: false},
+		{rel: "build", isDir: true, want: true},
+		{rel: "sub/build", isDir: true, want: true},
+		{rel: "build", isDir: false, want: false},
+		{rel: "root-only.txt", want: true},
+		{rel: "sub/root-only.txt", want: false},
+		{rel: "docs/x.tmp", want: true},
+		{rel: "docs/deep/x.tmp", want: false},
+		{rel: "#hash", want: true},
+		{rel: "main.go", want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.rel, func(t *testing.T) {
+			t.Parallel()
+			if got := r.Match(tc.rel, tc.isDir); got != tc.want {
+				t.Fatalf("Match(%q, %v) = %v, want %v", tc.rel, tc.isDir, got, tc.want)
+			}
+		})
+	}
+	var zero *Rules
+	if zero.Match("a.log", false) {
+		t.Fatal("nil rules must ignore nothing")
+	}
+}
@@ -85,3 +85,20 @@ func syncDirBestEffort(dir string) error {
 	defer f.Close()
 	return f.Sync()
 }
+
+// renameNoReplace moves src -> dst and fails with os.ErrExist if dst already exists.
+// Unix: hardlink + unlink gives an atomic no-clobber rename; filesystems without hardlink support fall back to a
+// best-effort existence check before rename.
+func renameNoReplace(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return os.Remove(src)
+	} else if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("destination already exists: %w", os.ErrExist)
+	} else if IsCrossDeviceErr(err) {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("destination already exists: %w", os.ErrExist)
+	}
+	return os.Rename(src, dst)
+}
@@ -0,0 +1,69 @@
+package ioutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// CommitTempFile atomically moves the temp file tmpName (created in dst's directory or on the same
+// filesystem) to dst with permissions perm. Without overwrite an existing dst fails with an error
+// wrapping os.ErrExist.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func CommitTempFile(fsys vfs.FS, tmpName, dst string, perm fs.FileMode, overwrite bool) error {
+	return commitAtomicTempFileFS(fsys, tmpName, dst, filepath.Dir(dst), perm, overwrite)
+}
+
+// commitAtomicTempFileFS commits tmpName to dst on fsys. The OS filesystem uses the platform
+// commit (hardlink/rename, directory fsync); other filesystems get the same no-clobber semantics
+// from Link, falling back to an existence check and Rename.
+func commitAtomicTempFileFS(fsys vfs.FS, tmpName, dst, parent string, perm fs.FileMode, overwrite bool) error {
+	if vfs.IsOS(fsys) {
+		return commitAtomicTempFile(tmpName, dst, parent, perm, overwrite)
+	}
+	if !overwrite {
+		if err := renameNoReplaceFS(fsys, tmpName, dst); err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return fmt.Errorf("file already exists: %w", os.ErrExist)
+			}
+			return err
+		}
+		_ = fsys.Chmod(dst, perm)
+		return nil
+	}
+	if err := fsys.Rename(tmpName, dst); err != nil {
+		return err
+	}
+	_ = fsys.Chmod(dst, perm)
+	return nil
+}
+
+// renameNoReplaceFS is renameNoReplace on fsys.
+func renameNoReplaceFS(fsys vfs.FS, src, dst string) error {
+	if vfs.IsOS(fsys) {
+		return renameNoReplace(src, dst)
+	}
+	if err := fsys.Link(src, dst); err == nil {
+		return fsys.Remove(src)
+	} else if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("destination already exists: %w", os.ErrExist)
+	}
+	// Directories cannot be hardlinked.
+	if _, err := fsys.Lstat(dst); err == nil {
+		return fmt.Errorf("destination already exists: %w", os.ErrExist)
+	}
+	return fsys.Rename(src, dst)
+}
+
+// syncDirBestEffortFS fsyncs dir on the OS filesystem; other filesystems have nothing to sync.
+func syncDirBestEffortFS(fsys vfs.FS, dir string) error {
+	if !vfs.IsOS(fsys) {
+		return nil
+	}
+	return syncDirBestEffort(dir)
+}
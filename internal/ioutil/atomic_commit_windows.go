@@ -3,9 +3,11 @@
 package ioutil
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
+	"syscall"
 	"time"
 )
 
@@ -46,3 +48,30 @@ func syncDirBestEffort(dir string) error {
 	_ = dir
 	return nil
 }
+
+// errorNotSameDevice is ERROR_NOT_SAME_DEVICE.
+const errorNotSameDevice = syscall.Errno(17)
+
+// renameNoReplace moves src -> dst and fails with os.ErrExist if dst already exists.
+// Windows: MoveFile (without MOVEFILE_REPLACE_EXISTING) refuses to replace an existing destination.
+func renameNoReplace(src, dst string) error {
+	from, err := syscall.UTF16PtrFromString(src)
+	if err != nil {
+		return err
+	}
+	to, err := syscall.UTF16PtrFromString(dst)
+	if err != nil {
+		return err
+	}
+	if err := syscall.MoveFile(from, to); err != nil {
+		if _, stErr := os.Lstat(dst); stErr == nil {
+			return fmt.Errorf("destination already exists: %w", os.ErrExist)
+		}
+		if errors.Is(err, errorNotSameDevice) {
+			// Normalize so IsCrossDeviceErr-based fallbacks work the same as on Unix.
+			err = syscall.EXDEV
+		}
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: err}
+	}
+	return nil
+}
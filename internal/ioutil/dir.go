@@ -10,15 +10,17 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 // ListDirectoryNormalized lists entries in a directory that is assumed to be already normalized.
 // It does not normalize or resolve relative paths; callers must do that.
-func ListDirectoryNormalized(dir, pattern string) ([]string, error) {
+func ListDirectoryNormalized(fsys vfs.FS, dir, pattern string) ([]string, error) {
 	if strings.TrimSpace(dir) == "" {
 		return nil, ErrInvalidPath
 	}
-	entries, err := os.ReadDir(dir)
+	entries, err := fsys.ReadDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("read dir error %w", err)
 	}
@@ -41,7 +43,7 @@ func ListDirectoryNormalized(dir, pattern string) ([]string, error) {
 	return out, nil
 }
 
-func UniquePathInDir(dir, base string) (string, error) {
+func UniquePathInDir(fsys vfs.FS, dir, base string) (string, error) {
 	dir = strings.TrimSpace(dir)
 	base = strings.TrimSpace(base)
 	if dir == "" || base == "" {
@@ -52,7 +54,7 @@ func UniquePathInDir(dir, base string) (string, error) {
 	}
 
 	// Ensure dir exists and is a directory.
-	if st, err := os.Stat(dir); err != nil {
+	if st, err := fsys.Stat(dir); err != nil {
 		return "", err
 	} else if !st.IsDir() {
 		return "", fmt.Errorf("directory: %s, err: %w", dir, ErrInvalidDir)
@@ -65,7 +67,7 @@ func UniquePathInDir(dir, base string) (string, error) {
 
 	// First try the plain name.
 	p := filepath.Join(dir, base)
-	_, err := os.Lstat(p)
+	_, err := fsys.Lstat(p)
 
 	if err != nil && errors.Is(err, os.ErrNotExist) {
 		return p, nil
@@ -85,7 +87,7 @@ func UniquePathInDir(dir, base string) (string, error) {
 		ts := time.Now().UTC().Format("20060102T150405.000000000Z")
 		name := fmt.Sprintf("%s.%s.%s%s", stem, ts, sfx, ext)
 		candidate := filepath.Join(dir, name)
-		if _, err := os.Lstat(candidate); err == nil {
+		if _, err := fsys.Lstat(candidate); err == nil {
 			continue
 		} else if errors.Is(err, os.ErrNotExist) {
 			return candidate, nil
@@ -0,0 +1,268 @@
+package ioutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// ErrTreeLimitExceeded indicates a recursive directory operation would exceed its entry/size caps.
+var ErrTreeLimitExceeded = errors.New("directory tree exceeds limits")
+
+// TreeLimits bounds recursive directory operations.
+// Zero values mean "no limit".
+type TreeLimits struct {
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// TreeStats summarizes a directory tree. The root directory itself is not counted.
+type TreeStats struct {
+	Files    int   `json:"files"`
+	Dirs     int   `json:"dirs"`
+	Symlinks int   `json:"symlinks,omitempty"`
+	Bytes    int64 `json:"bytes"`
+}
+
+// Entries returns the total number of entries in the tree.
+func (s TreeStats) Entries() int {
+	return s.Files + s.Dirs + s.Symlinks
+}
+
+// ScanTree walks root without following symlinks and returns entry and byte counts.
+// Root must be an already-resolved absolute directory path.
+//
+// FSPolicy enforcement:
+//   - if policy.BlockSymlinks == true: any symlink entry fails the scan.
+//   - if allowedRoots is set: each entry is policy-checked.
+//   - special files (devices, sockets, pipes) fail the scan.
+func ScanTree(ctx context.Context, p fspolicy.FSPolicy, root string, limits TreeLimits) (TreeStats, error) {
+	var stats TreeStats
+	err := vfs.WalkDir(p.FS(), root, func(path string, d fs.DirEntry, walkErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if walkErr != nil {
+			return walkErr
+		}
+		if path == root {
+			if !d.IsDir() {
+				return fmt.Errorf("not a directory: %s", root)
+			}
+			return nil
+		}
+		if p.HasAllowedRoots() {
+			if _, err := p.ResolvePath(path, ""); err != nil {
+				return err
+			}
+		}
+
+		t := d.Type()
+		switch {
+		case t&os.ModeSymlink != 0:
+			if p.BlockSymlinks() {
+				return fmt.Errorf("%w: refusing to operate on symlink: %s", fspolicy.ErrSymlinkDisallowed, path)
+			}
+			stats.Symlinks++
+		case d.IsDir():
+			stats.Dirs++
+		case t.IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			stats.Files++
+			stats.Bytes += info.Size()
+		default:
+			return fmt.Errorf("refusing to operate on non-regular file: %s", path)
+		}
+
+		if limits.MaxEntries > 0 && stats.Entries() > limits.MaxEntries {
+			return fmt.Errorf("%w: more than %d entries under %s", ErrTreeLimitExceeded, limits.MaxEntries, root)
+		}
+		if limits.MaxBytes > 0 && stats.Bytes > limits.MaxBytes {
+			return fmt.Errorf("%w: more than %d bytes under %s", ErrTreeLimitExceeded, limits.MaxBytes, root)
+		}
+		return nil
+	})
+	if err != nil {
+		return TreeStats{}, err
+	}
+	return stats, nil
+}
+
+// CopyTreeResolved recursively copies the directory src into dst.
+// Both paths must be absolute and policy-resolved; dst's parent must already exist.
+//
+// Behavior:
+//   - The source tree is scanned first and the whole operation fails before writing anything if limits are exceeded.
+//   - If dst does not exist, the tree is staged in a temp dir next to dst and renamed into place, so dst appears
+//     complete or not at all.
+//   - If dst exists and overwrite is false, it fails with os.ErrExist.
+//   - If dst exists and overwrite is true, src is merged into dst: files at the same relative path are replaced
+//     atomically; other existing entries are kept. File/dir type conflicts fail.
+//   - Symlinks are recreated as symlinks (never followed) when the policy allows them.
+func CopyTreeResolved(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	src, dst string,
+	overwrite bool,
+	limits TreeLimits,
+) (TreeStats, error) {
+	if err := requireDisjointTrees(src, dst); err != nil {
+		return TreeStats{}, err
+	}
+	srcInfo, err := p.FS().Lstat(src)
+	if err != nil {
+		return TreeStats{}, err
+	}
+	if !srcInfo.IsDir() {
+		return TreeStats{}, fmt.Errorf("not a directory: %s", src)
+	}
+
+	stats, err := ScanTree(ctx, p, src, limits)
+	if err != nil {
+		return TreeStats{}, err
+	}
+
+	dstInfo, err := p.FS().Lstat(dst)
+	if err == nil {
+		if !overwrite {
+			return TreeStats{}, fmt.Errorf("destination already exists: %w", os.ErrExist)
+		}
+		if !dstInfo.IsDir() || (dstInfo.Mode()&os.ModeSymlink) != 0 {
+			return TreeStats{}, fmt.Errorf("destination exists and is not a directory: %s", dst)
+		}
+		if err := copyTreeInto(ctx, p, src, dst, true); err != nil {
+			return TreeStats{}, err
+		}
+		return stats, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return TreeStats{}, err
+	}
+
+	parent := filepath.Dir(dst)
+	if p.BlockSymlinks() {
+		if err := p.VerifyDirResolved(parent); err != nil {
+			return TreeStats{}, err
+		}
+	}
+	staging, err := p.FS().MkdirTemp(parent, ".tmp-llmtools-*")
+	if err != nil {
+		return TreeStats{}, err
+	}
+	// Staging dir is ours; removing it on failure is not destructive to user data.
+	cleanup := func(retErr error) (TreeStats, error) {
+		_ = p.FS().RemoveAll(staging)
+		return TreeStats{}, retErr
+	}
+	if err := copyTreeInto(ctx, p, src, staging, false); err != nil {
+		return cleanup(err)
+	}
+	_ = p.FS().Chmod(staging, srcInfo.Mode().Perm())
+
+	if _, err := p.FS().Lstat(dst); err == nil {
+		return cleanup(fmt.Errorf("destination already exists: %w", os.ErrExist))
+	}
+	if err := p.FS().Rename(staging, dst); err != nil {
+		return cleanup(err)
+	}
+	_ = syncDirBestEffortFS(p.FS(), parent)
+	return stats, nil
+}
+
+// copyTreeInto copies the contents of src into the existing directory dst.
+func copyTreeInto(ctx context.Context, p fspolicy.FSPolicy, src, dst string, overwrite bool) error {
+	return vfs.WalkDir(p.FS(), src, func(path string, d fs.DirEntry, walkErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if walkErr != nil {
+			return walkErr
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			if p.BlockSymlinks() {
+				return fmt.Errorf("%w: refusing to copy symlink: %s", fspolicy.ErrSymlinkDisallowed, path)
+			}
+			return copySymlink(p.FS(), path, target, overwrite)
+		case info.IsDir():
+			st, err := p.FS().Lstat(target)
+			if err == nil {
+				if !st.IsDir() || (st.Mode()&os.ModeSymlink) != 0 {
+					return fmt.Errorf("destination exists and is not a directory: %s", target)
+				}
+				return nil
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			return p.FS().Mkdir(target, info.Mode().Perm()|0o700)
+		case info.Mode().IsRegular():
+			_, err := CopyFileAtomicResolved(ctx, p, path, target, info.Mode().Perm(), overwrite)
+			if err != nil {
+				return fmt.Errorf("copy %s: %w", rel, err)
+			}
+			return nil
+		default:
+			return fmt.Errorf("refusing to copy non-regular file: %s", path)
+		}
+	})
+}
+
+func copySymlink(fsys vfs.FS, src, dst string, overwrite bool) error {
+	linkTarget, err := fsys.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if st, err := fsys.Lstat(dst); err == nil {
+		if !overwrite {
+			return fmt.Errorf("destination already exists: %w", os.ErrExist)
+		}
+		if st.IsDir() {
+			return fmt.Errorf("destination exists and is a directory: %s", dst)
+		}
+		if err := fsys.Remove(dst); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return fsys.Symlink(linkTarget, dst)
+}
+
+// requireDisjointTrees refuses operations where dst is src or lies inside src.
+func requireDisjointTrees(src, dst string) error {
+	rel, err := filepath.Rel(src, dst)
+	if err != nil {
+		return nil //nolint:nilerr // Different volumes cannot nest.
+	}
+	rel = filepath.Clean(rel)
+	if rel == "." {
+		return fmt.Errorf("source and destination are the same path: %s", src)
+	}
+	if rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return fmt.Errorf("destination %s is inside source directory %s", dst, src)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package ioutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+)
+
+func TestScanTree(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	mustWriteBytes(t, filepath.Join(dir, "a", "one.txt"), []byte("12345"))
+	mustWriteBytes(t, filepath.Join(dir, "a", "b", "two.txt"), []byte("123"))
+
+	p, err := fspolicy.New(dir, nil, true)
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		limits    TreeLimits
+		wantStats TreeStats
+		wantErrIs error
+	}{
+		{
+			name:      "no_limits",
+			wantStats: TreeStats{Files: 2, Dirs: 2, Bytes: 8},
+		},
+		{
+			name:      "entry_limit_exceeded",
+			limits:    TreeLimits{MaxEntries: 3},
+			wantErrIs: ErrTreeLimitExceeded,
+		},
+		{
+			name:      "byte_limit_exceeded",
+			limits:    TreeLimits{MaxBytes: 7},
+			wantErrIs: ErrTreeLimitExceeded,
+		},
+		{
+			name:      "limits_exactly_met",
+			limits:    TreeLimits{MaxEntries: 4, MaxBytes: 8},
+			wantStats: TreeStats{Files: 2, Dirs: 2, Bytes: 8},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ScanTree(t.Context(), p, dir, tc.limits)
+			if tc.wantErrIs != nil {
+				if !errors.Is(err, tc.wantErrIs) {
+					t.Fatalf("err=%v want errors.Is(_, %v)", err, tc.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.wantStats {
+				t.Fatalf("stats=%+v want=%+v", got, tc.wantStats)
+			}
+		})
+	}
+}
+
+func TestCopyTreeResolved(t *testing.T) {
+	seed := func(t *testing.T) (string, fspolicy.FSPolicy) {
+		t.Helper()
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, "src", "sub"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		mustWriteBytes(t, filepath.Join(dir, "src", "a.txt"), []byte("a"))
+		mustWriteBytes(t, filepath.Join(dir, "src", "sub", "b.txt"), []byte("b"))
+		p, err := fspolicy.New(dir, []string{dir}, false)
+		if err != nil {
+			t.Fatalf("policy: %v", err)
+		}
+		return dir, p
+	}
+
+	t.Run("copies_into_new_destination_without_leftovers", func(t *testing.T) {
+		dir, p := seed(t)
+		dst := filepath.Join(dir, "dst")
+		stats, err := CopyTreeResolved(t.Context(), p, filepath.Join(dir, "src"), dst, false, TreeLimits{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stats.Files != 2 || stats.Dirs != 1 {
+			t.Fatalf("stats=%+v", stats)
+		}
+		b, err := os.ReadFile(filepath.Join(dst, "sub", "b.txt"))
+		if err != nil || string(b) != "b" {
+			t.Fatalf("read copied: %q err=%v", string(b), err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("readdir: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("expected only src and dst, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("limit_exceeded_writes_nothing", func(t *testing.T) {
+		dir, p := seed(t)
+		dst := filepath.Join(dir, "dst")
+		_, err := CopyTreeResolved(t.Context(), p, filepath.Join(dir, "src"), dst, false, TreeLimits{MaxEntries: 1})
+		if !errors.Is(err, ErrTreeLimitExceeded) {
+			t.Fatalf("err=%v want ErrTreeLimitExceeded", err)
+		}
+		if _, err := os.Lstat(dst); !os.IsNotExist(err) {
+			t.Fatalf("expected no destination, stat err=%v", err)
+		}
+	})
+
+	t.Run("existing_destination_without_overwrite_errors", func(t *testing.T) {
+		dir, p := seed(t)
+		dst := filepath.Join(dir, "dst")
+		if err := os.Mkdir(dst, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		_, err := CopyTreeResolved(t.Context(), p, filepath.Join(dir, "src"), dst, false, TreeLimits{})
+		if !errors.Is(err, os.ErrExist) {
+			t.Fatalf("err=%v want os.ErrExist", err)
+		}
+	})
+
+	t.Run("merge_type_conflict_errors", func(t *testing.T) {
+		dir, p := seed(t)
+		dst := filepath.Join(dir, "dst")
+		if err := os.Mkdir(dst, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		mustWriteBytes(t, filepath.Join(dst, "sub"), []byte("file, not dir"))
+		_, err := CopyTreeResolved(t.Context(), p, filepath.Join(dir, "src"), dst, true, TreeLimits{})
+		if err == nil {
+			t.Fatalf("expected type conflict error")
+		}
+	})
+
+	t.Run("destination_inside_source_errors", func(t *testing.T) {
+		dir, p := seed(t)
+		src := filepath.Join(dir, "src")
+		_, err := CopyTreeResolved(t.Context(), p, src, filepath.Join(src, "sub", "x"), false, TreeLimits{})
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestCopyFileAtomicResolved(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	mustWriteBytes(t, src, []byte("payload"))
+	p, err := fspolicy.New(dir, nil, true)
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+
+	dst := filepath.Join(dir, "dst.txt")
+	n, err := CopyFileAtomicResolved(t.Context(), p, src, dst, 0o600, false)
+	if err != nil || n != int64(len("payload")) {
+		t.Fatalf("n=%d err=%v", n, err)
+	}
+	if _, err := CopyFileAtomicResolved(t.Context(), p, src, dst, 0o600, false); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("second copy err=%v want os.ErrExist", err)
+	}
+	if _, err := CopyFileAtomicResolved(t.Context(), p, src, "relative.txt", 0o600, false); err == nil {
+		t.Fatalf("expected error for relative destination")
+	}
+	if _, err := CopyFileAtomicResolved(canceledContext(t.Context()), p, src, dst, 0o600, true); err == nil {
+		t.Fatalf("expected error for canceled context")
+	}
+}
@@ -0,0 +1,149 @@
+package ioutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// TreeEntryKind is the type of an entry returned by ListTreeEntries.
+type TreeEntryKind string
+
+const (
+	TreeEntryFile    TreeEntryKind = "file"
+	TreeEntryDir     TreeEntryKind = "dir"
+	TreeEntrySymlink TreeEntryKind = "symlink"
+)
+
+// TreeEntry is one entry below a directory root.
+type TreeEntry struct {
+	RelPath    string // slash-separated, relative to the root
+	Kind       TreeEntryKind
+	Size       int64  // files only
+	LinkTarget string // symlinks only
+}
+
+// ListTreeEntries walks root without following symlinks and returns its entries in lexical order.
+// Root must be an already-resolved absolute directory path and is not included.
+//
+// FSPolicy enforcement matches ScanTree: symlinks fail the walk when blocked, entries are checked
+// against allowedRoots, and special files (devices, sockets, pipes) fail the walk.
+func ListTreeEntries(ctx context.Context, p fspolicy.FSPolicy, root string, maxEntries int) ([]TreeEntry, error) {
+	var entries []TreeEntry
+	err := vfs.WalkDir(p.FS(), root, func(path string, d fs.DirEntry, walkErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if walkErr != nil {
+			return walkErr
+		}
+		if path == root {
+			if !d.IsDir() {
+				return fmt.Errorf("not a directory: %s", root)
+			}
+			return nil
+		}
+		if p.HasAllowedRoots() {
+			if _, err := p.ResolvePath(path, ""); err != nil {
+				return err
+			}
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		e := TreeEntry{RelPath: filepath.ToSlash(rel)}
+
+		t := d.Type()
+		switch {
+		case t&os.ModeSymlink != 0:
+			if p.BlockSymlinks() {
+				return fmt.Errorf("%w: refusing to operate on symlink: %s", fspolicy.ErrSymlinkDisallowed, path)
+			}
+			e.Kind = TreeEntrySymlink
+			if e.LinkTarget, err = p.FS().Readlink(path); err != nil {
+				return err
+			}
+		case d.IsDir():
+			e.Kind = TreeEntryDir
+		case t.IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			e.Kind = TreeEntryFile
+			e.Size = info.Size()
+		default:
+			return fmt.Errorf("refusing to operate on non-regular file: %s", path)
+		}
+
+		entries = append(entries, e)
+		if maxEntries > 0 && len(entries) > maxEntries {
+			return fmt.Errorf("%w: more than %d entries under %s", ErrTreeLimitExceeded, maxEntries, root)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SameFileContent reports whether two files have identical bytes, reading both in chunks.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func SameFileContent(ctx context.Context, fsys vfs.FS, pathA, pathB string) (bool, error) {
+	fa, err := fsys.Open(pathA)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := fsys.Open(pathB)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	sa, err := fa.Stat()
+	if err != nil {
+		return false, err
+	}
+	sb, err := fb.Stat()
+	if err != nil {
+		return false, err
+	}
+	if sa.Size() != sb.Size() {
+		return false, nil
+	}
+
+	bufA := make([]byte, 64*1024)
+	bufB := make([]byte, 64*1024)
+	for {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		doneA := errors.Is(errA, io.EOF) || errors.Is(errA, io.ErrUnexpectedEOF)
+		doneB := errors.Is(errB, io.EOF) || errors.Is(errB, io.ErrUnexpectedEOF)
+		switch {
+		case errA != nil && !doneA:
+			return false, errA
+		case errB != nil && !doneB:
+			return false, errB
+		case doneA || doneB:
+			return doneA == doneB, nil
+		}
+	}
+}
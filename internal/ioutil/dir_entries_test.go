@@ -0,0 +1,71 @@
+package ioutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestListTreeEntries(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	mustWriteBytes(t, filepath.Join(dir, "a", "one.txt"), []byte("12345"))
+	mustWriteBytes(t, filepath.Join(dir, "a", "b", "two.txt"), []byte("123"))
+
+	p, err := fspolicy.New(dir, nil, true)
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+
+	got, err := ListTreeEntries(t.Context(), p, dir, 0)
+	if err != nil {
+		t.Fatalf("ListTreeEntries: %v", err)
+	}
+	want := []TreeEntry{
+		{RelPath: "a", Kind: TreeEntryDir},
+		{RelPath: "a/b", Kind: TreeEntryDir},
+		{RelPath: "a/b/two.txt", Kind: TreeEntryFile, Size: 3},
+		{RelPath: "a/one.txt", Kind: TreeEntryFile, Size: 5},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+
+	if _, err := ListTreeEntries(t.Context(), p, dir, 3); !errors.Is(err, ErrTreeLimitExceeded) {
+		t.Fatalf("err=%v want ErrTreeLimitExceeded", err)
+	}
+}
+
+func TestSameFileContent(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("x", 200*1024)
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{name: "empty", a: "", b: "", want: true},
+		{name: "equal_multi_chunk", a: big, b: big, want: true},
+		{name: "differ_in_last_chunk", a: big + "a", b: big + "b", want: false},
+		{name: "different_size", a: "abc", b: "abcd", want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pa, pb := filepath.Join(dir, tc.name+".a"), filepath.Join(dir, tc.name+".b")
+			mustWriteBytes(t, pa, []byte(tc.a))
+			mustWriteBytes(t, pb, []byte(tc.b))
+			got, err := SameFileContent(t.Context(), vfs.OS(), pa, pb)
+			if err != nil || got != tc.want {
+				t.Fatalf("got %v, %v want %v", got, err, tc.want)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 func TestListDirectory(t *testing.T) {
@@ -99,7 +100,7 @@ func TestListDirectory(t *testing.T) {
 				}
 				return
 			}
-			got, err := ListDirectoryNormalized(dir, tc.pattern)
+			got, err := ListDirectoryNormalized(vfs.OS(), dir, tc.pattern)
 
 			if tc.wantErrIs != nil || tc.wantIsNotExist {
 				if err == nil {
@@ -143,7 +144,7 @@ func TestListDirectory_DefaultPathDot(t *testing.T) {
 		t.Fatalf("got normalize err (got=%v)", err)
 	}
 
-	got, err := ListDirectoryNormalized(dir, "")
+	got, err := ListDirectoryNormalized(vfs.OS(), dir, "")
 	if err != nil {
 		t.Fatalf("expected nil error, got %v", err)
 	}
@@ -224,7 +225,7 @@ func TestListDirectory_Additional(t *testing.T) {
 				t.Fatalf("got normalization error (got=%v)", err)
 			}
 
-			got, err := ListDirectoryNormalized(dir, tc.pattern)
+			got, err := ListDirectoryNormalized(vfs.OS(), dir, tc.pattern)
 
 			if tc.wantErr {
 				if err == nil {
@@ -287,7 +288,7 @@ func TestListDirectoryNormalized_SortsAndFiltersAndErrors(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := ListDirectoryNormalized(tc.dir, tc.pattern)
+			got, err := ListDirectoryNormalized(vfs.OS(), tc.dir, tc.pattern)
 			if tc.wantErrSubstr != "" {
 				if err == nil {
 					t.Fatalf("expected error")
@@ -390,7 +391,7 @@ func TestUniquePathInDir(t *testing.T) {
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := UniquePathInDir(tc.dir, tc.base)
+			got, err := UniquePathInDir(vfs.OS(), tc.dir, tc.base)
 			if tc.wantErrIs != nil {
 				if err == nil {
 					t.Fatalf("expected error, got nil (got=%q)", got)
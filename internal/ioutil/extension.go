@@ -5,11 +5,11 @@ import (
 	"io"
 	"mime"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 var (
@@ -82,6 +82,9 @@ const (
 	ExtCmake    FileExt = ".cmake"
 	ExtBazel    FileExt = ".bazel"
 	ExtXML      FileExt = ".xml"
+	ExtCSV      FileExt = ".csv"
+	ExtTSV      FileExt = ".tsv"
+	ExtNDJSON   FileExt = ".ndjson"
 
 	ExtJPG  FileExt = ".jpg"
 	ExtJPEG FileExt = ".jpeg"
@@ -100,6 +103,8 @@ const (
 	ExtXLSX FileExt = ".xlsx"
 	ExtODT  FileExt = ".odt"
 	ExtODS  FileExt = ".ods"
+
+	ExtParquet FileExt = ".parquet"
 )
 
 type MIMEType string
@@ -112,13 +117,16 @@ const (
 	MIMETextMarkdown MIMEType = "text/markdown; charset=utf-8"
 	MIMETextHTML     MIMEType = "text/html; charset=utf-8"
 	MIMETextCSS      MIMEType = "text/css; charset=utf-8"
+	MIMETextCSV      MIMEType = "text/csv; charset=utf-8"
+	MIMETextTSV      MIMEType = "text/tab-separated-values; charset=utf-8"
 
-	MIMEApplicationJSON MIMEType = "application/json"
-	MIMEApplicationXML  MIMEType = "application/xml"
-	MIMEApplicationYAML MIMEType = "application/x-yaml"
-	MIMEApplicationTOML MIMEType = "application/toml"
-	MIMEApplicationSQL  MIMEType = "application/sql"
-	MIMEApplicationJS   MIMEType = "application/javascript"
+	MIMEApplicationJSON  MIMEType = "application/json"
+	MIMEApplicationXML   MIMEType = "application/xml"
+	MIMEApplicationYAML  MIMEType = "application/x-yaml"
+	MIMEApplicationTOML  MIMEType = "application/toml"
+	MIMEApplicationSQL   MIMEType = "application/sql"
+	MIMEApplicationJS    MIMEType = "application/javascript"
+	MIMEApplicationJSONL MIMEType = "application/jsonl"
 
 	MIMEImageJPEG MIMEType = "image/jpeg"
 	MIMEImagePNG  MIMEType = "image/png"
@@ -136,6 +144,8 @@ const (
 	MIMEApplicationOpenXMLXLS MIMEType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 	MIMEApplicationODT        MIMEType = "application/vnd.oasis.opendocument.text"
 	MIMEApplicationODS        MIMEType = "application/vnd.oasis.opendocument.spreadsheet"
+
+	MIMEApplicationParquet MIMEType = "application/vnd.apache.parquet"
 )
 
 // ExtensionToMIMEType is an internal registry of common/explicitly-supported extensions.
@@ -171,7 +181,10 @@ var ExtensionToMIMEType = map[FileExt]MIMEType{
 	ExtSQL:      MIMEApplicationSQL,
 	ExtMod:      MIMETextPlain,
 	ExtSum:      MIMETextPlain,
-	ExtJSONL:    MIMETextPlain,
+	ExtJSONL:    MIMEApplicationJSONL,
+	ExtNDJSON:   MIMEApplicationJSONL,
+	ExtCSV:      MIMETextCSV,
+	ExtTSV:      MIMETextTSV,
 	ExtShell:    MIMETextPlain,
 	ExtSWIFT:    MIMETextPlain,
 	ExtM:        MIMETextPlain,
@@ -202,6 +215,8 @@ var ExtensionToMIMEType = map[FileExt]MIMEType{
 	ExtXLSX: MIMEApplicationOpenXMLXLS,
 	ExtODT:  MIMEApplicationODT,
 	ExtODS:  MIMEApplicationODS,
+
+	ExtParquet: MIMEApplicationParquet,
 }
 
 // BaseMIMEToMode maps base mime types (no parameters) to a coarse mode.
@@ -221,6 +236,7 @@ var BaseMIMEToMode = map[string]ExtensionMode{
 	"application/toml":       ExtensionModeText,
 	"application/sql":        ExtensionModeText,
 	"application/javascript": ExtensionModeText,
+	"application/jsonl":      ExtensionModeText,
 
 	// Images.
 	"image/jpeg":    ExtensionModeImage,
@@ -258,7 +274,7 @@ const (
 	MIMEDetectMethodSniff     MIMEDetectMethod = "sniff"
 )
 
-func MIMEForLocalFile(path string) (mimeType MIMEType, mode ExtensionMode, method MIMEDetectMethod, err error) {
+func MIMEForLocalFile(fsys vfs.FS, path string) (mimeType MIMEType, mode ExtensionMode, method MIMEDetectMethod, err error) {
 	if strings.TrimSpace(path) == "" {
 		return MIMEEmpty, ExtensionModeDefault, MIMEDetectMethodSniff, ErrInvalidPath
 	}
@@ -274,7 +290,7 @@ func MIMEForLocalFile(path string) (mimeType MIMEType, mode ExtensionMode, metho
 		}
 	}
 
-	mt, m, e := SniffFileMIME(path)
+	mt, m, e := SniffFileMIME(fsys, path)
 	if e != nil {
 		return MIMEEmpty, ExtensionModeDefault, MIMEDetectMethodSniff, e
 	}
@@ -302,12 +318,12 @@ func MIMEFromExtensionString(ext string) (MIMEType, error) {
 	return MIMEApplicationOctetStream, ErrUnknownExtension
 }
 
-func SniffFileMIME(path string) (MIMEType, ExtensionMode, error) {
+func SniffFileMIME(fsys vfs.FS, path string) (MIMEType, ExtensionMode, error) {
 	if strings.TrimSpace(path) == "" {
 		return MIMEEmpty, ExtensionModeDefault, ErrInvalidPath
 	}
 
-	f, err := os.Open(path)
+	f, err := fsys.Open(path)
 	if err != nil {
 		return MIMEEmpty, ExtensionModeDefault, err
 	}
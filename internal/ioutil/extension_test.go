@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 func TestGetNormalizedExt(t *testing.T) {
@@ -221,7 +223,7 @@ func TestMIMEForLocalFile_ExtensionVsSniff(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			mt, mode, method, err := MIMEForLocalFile(tc.path)
+			mt, mode, method, err := MIMEForLocalFile(vfs.OS(), tc.path)
 			if tc.wantErr {
 				if err == nil {
 					t.Fatalf("expected error, got nil (mt=%q mode=%q method=%q)", mt, mode, method)
@@ -316,7 +318,7 @@ func TestSniffFileMIME(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			m, mode, err := SniffFileMIME(tc.path)
+			m, mode, err := SniffFileMIME(vfs.OS(), tc.path)
 
 			if tc.wantErr {
 				if err == nil {
@@ -0,0 +1,281 @@
+package ioutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// FileChunk is a bounded window of a file, as returned by ReadFileChunk and ReadFileLines.
+type FileChunk struct {
+	Data       []byte
+	Offset     int64 // byte offset of Data[0]
+	NextOffset int64 // byte offset right after Data
+	TotalSize  int64
+	EOF        bool // NextOffset == TotalSize
+
+	// Line mode only (1-based, inclusive); zero otherwise.
+	StartLine int
+	EndLine   int
+}
+
+// ReadFileChunk reads up to length bytes starting at offset.
+//
+// If alignUTF8 is true, the window is shrunk so it never starts or ends inside a UTF-8 sequence:
+// leading continuation bytes are skipped and an incomplete trailing sequence is left for the next chunk.
+// Offset and NextOffset always describe the returned bytes exactly.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func ReadFileChunk(ctx context.Context, fsys vfs.FS, path string, offset, length int64, alignUTF8 bool) (*FileChunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path = strings.TrimSpace(path)
+	if path == "" || strings.ContainsRune(path, 0) {
+		return nil, ErrInvalidPath
+	}
+	if offset < 0 {
+		return nil, errors.New("offset must be >= 0")
+	}
+	if length <= 0 {
+		return nil, errors.New("length must be > 0")
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := st.Size()
+	if offset > size {
+		return nil, fmt.Errorf("offset %d is beyond end of file (size %d bytes)", offset, size)
+	}
+
+	start := offset
+	if alignUTF8 && start > 0 && start < size {
+		head := make([]byte, utf8.UTFMax-1)
+		n, err := f.ReadAt(head, start)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		for i := 0; i < n && !utf8.RuneStart(head[i]); i++ {
+			start++
+		}
+	}
+
+	n := min(length, size-start)
+	data := make([]byte, n)
+	if n > 0 {
+		if _, err := f.ReadAt(data, start); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	}
+	if alignUTF8 && start+n < size {
+		data = trimIncompleteUTF8Tail(data)
+		if len(data) == 0 && n > 0 {
+			return nil, fmt.Errorf("length %d is too small to hold a complete UTF-8 character at offset %d", length, start)
+		}
+	}
+
+	next := start + int64(len(data))
+	return &FileChunk{
+		Data:       data,
+		Offset:     start,
+		NextOffset: next,
+		TotalSize:  size,
+		EOF:        next >= size,
+	}, nil
+}
+
+// ReadFileLines reads lineCount lines starting at 1-based startLine.
+// Returned lines keep their line terminators. lineCount <= 0 reads as many lines as fit in maxBytes.
+// Reading stops early (at a line boundary) once maxBytes would be exceeded; a single line larger than
+// maxBytes is an error.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func ReadFileLines(ctx context.Context, fsys vfs.FS, path string, startLine, lineCount int, maxBytes int64) (*FileChunk, error) {
+	return readLines(ctx, fsys, path, startLine, lineCount, maxBytes, TextEncodingUTF8)
+}
+
+func readLines(
+	ctx context.Context,
+	fsys vfs.FS,
+	path string,
+	startLine, lineCount int,
+	maxBytes int64,
+	enc TextEncoding,
+) (*FileChunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path = strings.TrimSpace(path)
+	if path == "" || strings.ContainsRune(path, 0) {
+		return nil, ErrInvalidPath
+	}
+	if startLine < 1 {
+		return nil, errors.New("startLine must be >= 1")
+	}
+	if maxBytes <= 0 {
+		return nil, errors.New("maxBytes must be > 0")
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := st.Size()
+
+	readFrag := lineFragReader(bufio.NewReaderSize(f, 64*1024), enc)
+	var (
+		out       bytes.Buffer
+		pos       int64
+		start     int64 = -1
+		endLine   int
+		linesSeen int
+	)
+	for lineNo := 1; ; lineNo++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		want := lineNo >= startLine
+		lineStart := pos
+		var (
+			line   []byte
+			tooBig bool
+			eof    bool
+		)
+		for {
+			frag, rerr := readFrag()
+			pos += int64(len(frag))
+			if want && !tooBig {
+				if int64(out.Len()+len(line)+len(frag)) > maxBytes {
+					tooBig = true
+				} else {
+					line = append(line, frag...)
+				}
+			}
+			if rerr == nil {
+				break
+			}
+			if errors.Is(rerr, bufio.ErrBufferFull) {
+				continue
+			}
+			if errors.Is(rerr, io.EOF) {
+				eof = true
+				break
+			}
+			return nil, rerr
+		}
+		if pos == lineStart {
+			break // EOF without a further line
+		}
+		linesSeen++
+		if want {
+			if tooBig {
+				if endLine == 0 {
+					return nil, fmt.Errorf(
+						"line %d exceeds maximum chunk size (%d bytes); use byte offset/length instead",
+						lineNo, maxBytes,
+					)
+				}
+				break
+			}
+			if start < 0 {
+				start = lineStart
+			}
+			out.Write(line)
+			endLine = lineNo
+			if lineCount > 0 && endLine-startLine+1 >= lineCount {
+				break
+			}
+		}
+		if eof {
+			break
+		}
+	}
+
+	if endLine == 0 {
+		if startLine == 1 && size == 0 {
+			return &FileChunk{Data: []byte{}, EOF: true}, nil
+		}
+		return nil, fmt.Errorf("startLine %d is beyond end of file (%d lines)", startLine, linesSeen)
+	}
+	next := start + int64(out.Len())
+	return &FileChunk{
+		Data:       out.Bytes(),
+		Offset:     start,
+		NextOffset: next,
+		TotalSize:  size,
+		EOF:        next >= size,
+		StartLine:  startLine,
+		EndLine:    endLine,
+	}, nil
+}
+
+// CountLines counts lines in a file: newline-terminated lines plus a final unterminated line, if any.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func CountLines(ctx context.Context, fsys vfs.FS, path string) (int, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 128*1024)
+	var (
+		count   int
+		last    byte
+		nonzero bool
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		n, err := f.Read(buf)
+		if n > 0 {
+			count += bytes.Count(buf[:n], []byte{'\n'})
+			last = buf[n-1]
+			nonzero = true
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, err
+		}
+	}
+	if nonzero && last != '\n' {
+		count++
+	}
+	return count, nil
+}
+
+// trimIncompleteUTF8Tail drops a trailing, incomplete UTF-8 sequence (if any).
+func trimIncompleteUTF8Tail(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			return b
+		}
+	}
+	return b
+}
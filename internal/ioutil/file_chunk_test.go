@@ -0,0 +1,135 @@
+package ioutil
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestReadFileChunk(t *testing.T) {
+	dir := t.TempDir()
+	// "é" is 2 bytes (0xC3 0xA9); "日" is 3 bytes.
+	content := "aé日bc"
+	path := filepath.Join(dir, "u.txt")
+	writeFile(t, path, content)
+
+	tests := []struct {
+		name       string
+		offset     int64
+		length     int64
+		alignUTF8  bool
+		want       string
+		wantOffset int64
+		wantNext   int64
+		wantEOF    bool
+		wantErr    string
+	}{
+		{name: "whole_file", offset: 0, length: 100, alignUTF8: true, want: content, wantNext: 8, wantEOF: true},
+		{name: "aligned_trims_partial_tail", offset: 0, length: 2, alignUTF8: true, want: "a", wantNext: 1},
+		{name: "aligned_skips_partial_head", offset: 2, length: 10, alignUTF8: true, want: "日bc", wantOffset: 3, wantNext: 8, wantEOF: true},
+		{name: "raw_is_exact", offset: 2, length: 2, want: "\xa9\xe6", wantOffset: 2, wantNext: 4},
+		{name: "offset_at_eof_is_empty", offset: 8, length: 1, alignUTF8: true, want: "", wantOffset: 8, wantNext: 8, wantEOF: true},
+		{name: "offset_beyond_eof_errors", offset: 9, length: 1, wantErr: "beyond end of file"},
+		{name: "length_too_small_for_rune_errors", offset: 3, length: 2, alignUTF8: true, wantErr: "too small"},
+		{name: "negative_offset_errors", offset: -1, length: 1, wantErr: "offset"},
+		{name: "zero_length_errors", offset: 0, length: 0, wantErr: "length"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadFileChunk(t.Context(), vfs.OS(), path, tc.offset, tc.length, tc.alignUTF8)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err=%v want substring %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got.Data) != tc.want || got.Offset != tc.wantOffset || got.NextOffset != tc.wantNext ||
+				got.EOF != tc.wantEOF || got.TotalSize != int64(len(content)) {
+				t.Fatalf("got=%+v (data %q)", got, string(got.Data))
+			}
+		})
+	}
+
+	t.Run("canceled_context", func(t *testing.T) {
+		if _, err := ReadFileChunk(canceledContext(t.Context()), vfs.OS(), path, 0, 1, false); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestReadFileLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "l.txt")
+	writeFile(t, path, "one\ntwo\r\nthree\nfour")
+	empty := filepath.Join(dir, "empty.txt")
+	writeFile(t, empty, "")
+
+	tests := []struct {
+		name      string
+		path      string
+		startLine int
+		lineCount int
+		maxBytes  int64
+		want      string
+		wantStart int
+		wantEnd   int
+		wantEOF   bool
+		wantErr   string
+	}{
+		{name: "first_two", path: path, startLine: 1, lineCount: 2, maxBytes: 100, want: "one\ntwo\r\n", wantStart: 1, wantEnd: 2},
+		{name: "to_end_without_final_newline", path: path, startLine: 3, maxBytes: 100, want: "three\nfour", wantStart: 3, wantEnd: 4, wantEOF: true},
+		{name: "count_past_end_is_clamped", path: path, startLine: 4, lineCount: 10, maxBytes: 100, want: "four", wantStart: 4, wantEnd: 4, wantEOF: true},
+		{name: "max_bytes_stops_at_line_boundary", path: path, startLine: 1, maxBytes: 10, want: "one\ntwo\r\n", wantStart: 1, wantEnd: 2},
+		{name: "single_line_over_max_errors", path: path, startLine: 3, maxBytes: 3, wantErr: "exceeds maximum chunk size"},
+		{name: "start_beyond_end_errors", path: path, startLine: 5, maxBytes: 100, wantErr: "(4 lines)"},
+		{name: "empty_file_first_line", path: empty, startLine: 1, maxBytes: 100, want: "", wantEOF: true},
+		{name: "zero_start_errors", path: path, startLine: 0, maxBytes: 100, wantErr: "startLine"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadFileLines(t.Context(), vfs.OS(), tc.path, tc.startLine, tc.lineCount, tc.maxBytes)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err=%v want substring %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got.Data) != tc.want || got.StartLine != tc.wantStart || got.EndLine != tc.wantEnd || got.EOF != tc.wantEOF {
+				t.Fatalf("got=%+v (data %q)", got, string(got.Data))
+			}
+			if got.NextOffset-got.Offset != int64(len(got.Data)) {
+				t.Fatalf("offsets do not match data: %+v", got)
+			}
+		})
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"a\n", 1},
+		{"a\nb", 2},
+		{"\n\n", 2},
+	}
+	for i, tc := range tests {
+		path := filepath.Join(dir, "f"+strings.Repeat("x", i))
+		writeFile(t, path, tc.content)
+		got, err := CountLines(t.Context(), vfs.OS(), path)
+		if err != nil || got != tc.want {
+			t.Fatalf("CountLines(%q)=%d,%v want %d", tc.content, got, err, tc.want)
+		}
+	}
+}
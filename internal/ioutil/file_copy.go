@@ -5,18 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 // CopyFileToExistingCtx copies src -> dst where dst is expected to already exist (typically a placeholder reserved with
 // O_EXCL). "dst" is truncated and overwritten.
 //
 // NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
-func CopyFileToExistingCtx(ctx context.Context, src, dst string) (int64, error) {
+func CopyFileToExistingCtx(ctx context.Context, fsys vfs.FS, src, dst string) (int64, error) {
 	if err := ctx.Err(); err != nil {
 		return 0, err
 	}
-	st, err := os.Lstat(dst)
+	st, err := fsys.Lstat(dst)
 	if err != nil {
 		return 0, err
 	}
@@ -24,41 +31,21 @@ func CopyFileToExistingCtx(ctx context.Context, src, dst string) (int64, error)
 		return 0, fmt.Errorf("destination is not a regular file: %s", dst)
 	}
 
-	in, err := os.Open(src)
+	in, err := fsys.Open(src)
 	if err != nil {
 		return 0, err
 	}
 	defer in.Close()
 
-	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_TRUNC, 0)
+	out, err := fsys.OpenFile(dst, os.O_WRONLY|os.O_TRUNC, 0)
 	if err != nil {
 		return 0, err
 	}
 	defer out.Close()
 
-	buf := make([]byte, 128*1024)
-	var written int64
-	for {
-		if err := ctx.Err(); err != nil {
-			return written, err
-		}
-		nr, er := in.Read(buf)
-		if nr > 0 {
-			nw, ew := out.Write(buf[:nr])
-			if ew != nil {
-				return written, ew
-			}
-			if nw != nr {
-				return written, errors.New("short write during copy")
-			}
-			written += int64(nw)
-		}
-		if er != nil {
-			if errors.Is(er, io.EOF) {
-				break
-			}
-			return written, er
-		}
+	written, err := copyWithContext(ctx, out, in)
+	if err != nil {
+		return written, err
 	}
 	if err := out.Sync(); err != nil {
 		return written, err
@@ -70,18 +57,18 @@ func CopyFileToExistingCtx(ctx context.Context, src, dst string) (int64, error)
 // It checks ctx between read iterations.
 //
 // NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
-func CopyFileCtx(ctx context.Context, src, dst string, perm os.FileMode) (written int64, err error) {
+func CopyFileCtx(ctx context.Context, fsys vfs.FS, src, dst string, perm os.FileMode) (written int64, err error) {
 	if err := ctx.Err(); err != nil {
 		return 0, err
 	}
 
-	in, err := os.Open(src)
+	in, err := fsys.Open(src)
 	if err != nil {
 		return 0, err
 	}
 	defer in.Close()
 
-	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	out, err := fsys.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
 	if err != nil {
 		return 0, err
 	}
@@ -93,20 +80,71 @@ func CopyFileCtx(ctx context.Context, src, dst string, perm os.FileMode) (writte
 			err = cerr
 		}
 		if err != nil {
-			_ = os.Remove(dst)
+			_ = fsys.Remove(dst)
 		}
 	}()
 
-	buf := make([]byte, 128*1024)
+	written, err = copyWithContext(ctx, out, in)
+	if err != nil {
+		return written, err
+	}
 
+	if err := out.Sync(); err != nil {
+		return written, err
+	}
+	return written, nil
+}
+
+// CopyFileAtomicResolved copies src -> dst by streaming into a temp file in dst's parent directory and committing it
+// via the atomic commit helpers (so readers never observe a partially written dst).
+//
+// Both paths must already be absolute and policy-resolved (i.e. returned from p.ResolvePath). The caller is expected to
+// have validated src (type/symlink rules); dst is validated the same way as WriteFileAtomicBytesResolved.
+func CopyFileAtomicResolved(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	src, dst string,
+	perm fs.FileMode,
+	overwrite bool,
+) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	dst = strings.TrimSpace(dst)
+	if dst == "" || strings.ContainsRune(dst, 0) {
+		return 0, ErrInvalidPath
+	}
+	if !filepath.IsAbs(dst) {
+		return 0, fmt.Errorf("path must be absolute: %s", dst)
+	}
+	dst = filepath.Clean(dst)
+
+	in, err := p.FS().Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	return writeFileAtomicReaderResolved(ctx, p, dst, in, perm, overwrite, false)
+}
+
+// IsCrossDeviceErr reports whether err is a cross-device rename/link failure.
+// In go 1.25: syscall.EXDEV exists on Windows too, and os.LinkError unwraps to the errno.
+func IsCrossDeviceErr(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// copyWithContext copies r -> w in chunks, checking ctx between read iterations.
+func copyWithContext(ctx context.Context, w io.Writer, r io.Reader) (int64, error) {
+	buf := make([]byte, 128*1024)
+	var written int64
 	for {
 		if err := ctx.Err(); err != nil {
 			return written, err
 		}
-
-		nr, er := in.Read(buf)
+		nr, er := r.Read(buf)
 		if nr > 0 {
-			nw, ew := out.Write(buf[:nr])
+			nw, ew := w.Write(buf[:nr])
 			if ew != nil {
 				return written, ew
 			}
@@ -117,14 +155,9 @@ func CopyFileCtx(ctx context.Context, src, dst string, perm os.FileMode) (writte
 		}
 		if er != nil {
 			if errors.Is(er, io.EOF) {
-				break
+				return written, nil
 			}
 			return written, er
 		}
 	}
-
-	if err := out.Sync(); err != nil {
-		return written, err
-	}
-	return written, nil
 }
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 func TestCopyFileCtx(t *testing.T) {
@@ -21,7 +22,7 @@ func TestCopyFileCtx(t *testing.T) {
 	content := []byte("hello copy\n")
 	mustWriteBytes(t, src, content)
 
-	written, err := CopyFileCtx(t.Context(), src, dst, 0o640)
+	written, err := CopyFileCtx(t.Context(), vfs.OS(), src, dst, 0o640)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -118,7 +119,7 @@ func TestCopyFileCtx_Errors(t *testing.T) {
 			if tc.dstSetup != nil {
 				tc.dstSetup(t, tc.dst)
 			}
-			_, err := CopyFileCtx(tc.ctx, tc.src, tc.dst, 0o600)
+			_, err := CopyFileCtx(tc.ctx, vfs.OS(), tc.src, tc.dst, 0o600)
 			if tc.wantErr {
 				if err == nil {
 					t.Fatalf("expected error, got nil")
@@ -159,7 +160,7 @@ func TestCopyFileToExistingCtx(t *testing.T) {
 	mustWriteBytes(t, src, []byte("NEW"))
 	mustWriteBytes(t, dst, []byte("OLD-TO-BE-TRUNCATED"))
 
-	written, err := CopyFileToExistingCtx(t.Context(), src, dst)
+	written, err := CopyFileToExistingCtx(t.Context(), vfs.OS(), src, dst)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -251,7 +252,7 @@ func TestCopyFileToExistingCtx_Errors(t *testing.T) {
 			if tc.dstSetup != nil {
 				tc.dstSetup(t, tc.dst)
 			}
-			_, err := CopyFileToExistingCtx(tc.ctx, tc.src, tc.dst)
+			_, err := CopyFileToExistingCtx(tc.ctx, vfs.OS(), tc.src, tc.dst)
 
 			if err == nil {
 				t.Fatalf("expected error, got nil")
@@ -0,0 +1,26 @@
+package ioutil
+
+import (
+	"context"
+	"hash"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// HashFile streams the file at path through h and returns the digest and the number of bytes hashed.
+// h is reset first.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func HashFile(ctx context.Context, fsys vfs.FS, path string, h hash.Hash) (sum []byte, size int64, err error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	h.Reset()
+	n, err := copyWithContext(ctx, h, f)
+	if err != nil {
+		return nil, 0, err
+	}
+	return h.Sum(nil), n, nil
+}
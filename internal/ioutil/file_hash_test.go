@@ -0,0 +1,52 @@
+package ioutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestHashFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.bin")
+	data := []byte(strings.Repeat("abc", 100*1024))
+	mustWriteBytes(t, path, data)
+	want := sha256.Sum256(data)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		path      string
+		wantErrIs error
+	}{
+		{name: "streams_whole_file", ctx: t.Context(), path: path},
+		{name: "missing_file", ctx: t.Context(), path: filepath.Join(dir, "nope"), wantErrIs: os.ErrNotExist},
+		{name: "context_canceled", ctx: canceledContext(t.Context()), path: path, wantErrIs: context.Canceled},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := sha256.New()
+			h.Write([]byte("stale state that must be reset"))
+			sum, n, err := HashFile(tc.ctx, vfs.OS(), tc.path, h)
+			if tc.wantErrIs != nil {
+				if !errors.Is(err, tc.wantErrIs) {
+					t.Fatalf("err=%v want %v", err, tc.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("HashFile: %v", err)
+			}
+			if hex.EncodeToString(sum) != hex.EncodeToString(want[:]) || n != int64(len(data)) {
+				t.Fatalf("sum=%x n=%d", sum, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package ioutil
+
+import "github.com/flexigpt/llmtools-go/vfs"
+
+// FileID returns an identity of the file at path that is kept across renames and appends but differs
+// once the path names another file (e.g. after log rotation): device and inode on Unix, volume serial
+// number and file index on Windows. It is "" when the FS or platform exposes no such identity.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func FileID(fsys vfs.FS, path string) (string, error) {
+	st, err := fsys.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return platformFileID(fsys, path, st), nil
+}
@@ -0,0 +1,12 @@
+//go:build !unix && !windows
+
+package ioutil
+
+import (
+	"io/fs"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// platformFileID reports no identity where the platform exposes none.
+func platformFileID(vfs.FS, string, fs.FileInfo) string { return "" }
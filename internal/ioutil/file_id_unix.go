@@ -0,0 +1,20 @@
+//go:build unix
+
+package ioutil
+
+import (
+	"io/fs"
+	"strconv"
+	"syscall"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func platformFileID(_ vfs.FS, _ string, info fs.FileInfo) string {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	//nolint:unconvert // Dev and Ino widths vary by platform.
+	return strconv.FormatUint(uint64(st.Dev), 16) + "-" + strconv.FormatUint(uint64(st.Ino), 16)
+}
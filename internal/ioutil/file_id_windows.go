@@ -0,0 +1,36 @@
+//go:build windows
+
+package ioutil
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+	"golang.org/x/sys/windows"
+)
+
+// platformFileID reads the volume serial number and file index, which FileInfo.Sys does not carry.
+func platformFileID(fsys vfs.FS, path string, _ fs.FileInfo) string {
+	if !vfs.IsOS(fsys) {
+		return ""
+	}
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return ""
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS is required to open directories; zero access only reads attributes.
+	h, err := windows.CreateFile(
+		p, 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0,
+	)
+	if err != nil {
+		return ""
+	}
+	defer windows.CloseHandle(h) //nolint:errcheck // Read-only handle.
+	var bhfi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &bhfi); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x%08x", bhfi.VolumeSerialNumber, bhfi.FileIndexHigh, bhfi.FileIndexLow)
+}
@@ -6,8 +6,9 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"os"
 	"strings"
+
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 type ReadEncoding string
@@ -21,7 +22,7 @@ const (
 // If maxBytes > 0, it enforces a hard cap during reading.
 //
 // NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
-func ReadFile(path string, encoding ReadEncoding, maxBytes int64) (string, error) {
+func ReadFile(fsys vfs.FS, path string, encoding ReadEncoding, maxBytes int64) (string, error) {
 	path = strings.TrimSpace(path)
 	if path == "" || strings.ContainsRune(path, 0) {
 		return "", ErrInvalidPath
@@ -31,7 +32,7 @@ func ReadFile(path string, encoding ReadEncoding, maxBytes int64) (string, error
 		return "", errors.New(`encoding must be "text" or "binary"`)
 	}
 
-	f, err := os.Open(path)
+	f, err := fsys.Open(path)
 	if err != nil {
 		return "", err
 	}
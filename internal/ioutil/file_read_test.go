@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 func TestReadFile(t *testing.T) {
@@ -87,7 +88,7 @@ func TestReadFile(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := ReadFile(tc.path, tc.encoding, toolutil.MaxFileReadBytes)
+			got, err := ReadFile(vfs.OS(), tc.path, tc.encoding, toolutil.MaxFileReadBytes)
 
 			if tc.wantErr {
 				if err == nil {
@@ -106,7 +107,7 @@ func TestReadFile(t *testing.T) {
 				t.Fatalf("unexpected error: %v", err)
 			}
 			if got != tc.want {
-				t.Errorf("ReadFile(%q) = %q, want %q", tc.path, got, tc.want)
+				t.Errorf("ReadFile(vfs.OS(), %q) = %q, want %q", tc.path, got, tc.want)
 			}
 		})
 	}
@@ -162,7 +163,7 @@ func TestReadFile_MaxBytes(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := ReadFile(p, tc.encoding, tc.maxBytes)
+			got, err := ReadFile(vfs.OS(), p, tc.encoding, tc.maxBytes)
 			if tc.wantErr {
 				if err == nil {
 					t.Fatalf("expected error, got nil (got=%q)", got)
@@ -0,0 +1,138 @@
+package ioutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const tailBlockSize = 64 * 1024
+
+// TailFileLines returns the last n lines of a file by reading backwards from the end in blocks.
+// At most maxBytes are returned; if the last n lines do not fit, the chunk starts at the first line
+// boundary inside the last maxBytes (or a UTF-8 boundary if there is none).
+// The returned chunk always ends at EOF (NextOffset == TotalSize).
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func TailFileLines(ctx context.Context, fsys vfs.FS, path string, n int, maxBytes int64) (*FileChunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	path = strings.TrimSpace(path)
+	if path == "" || strings.ContainsRune(path, 0) {
+		return nil, ErrInvalidPath
+	}
+	if n <= 0 {
+		return nil, errors.New("line count must be > 0")
+	}
+	if maxBytes <= 0 {
+		return nil, errors.New("maxBytes must be > 0")
+	}
+
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := st.Size()
+	floor := max(size-maxBytes, 0)
+
+	// Walk backwards counting newlines. A trailing newline terminates the last line rather than
+	// starting a new one, so it is not counted.
+	end := size
+	if size > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, size-1); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if last[0] == '\n' {
+			end = size - 1
+		}
+	}
+
+	start := int64(-1)
+	found := 0
+	buf := make([]byte, tailBlockSize)
+	for pos := end; pos > floor && start < 0; {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		blockStart := max(pos-tailBlockSize, floor)
+		block := buf[:pos-blockStart]
+		if _, err := f.ReadAt(block, blockStart); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		for i := len(block) - 1; i >= 0; i-- {
+			if block[i] != '\n' {
+				continue
+			}
+			found++
+			if found == n {
+				start = blockStart + int64(i) + 1
+				break
+			}
+		}
+		pos = blockStart
+	}
+	if start < 0 {
+		if floor == 0 {
+			start = 0
+		} else {
+			start = floor
+			if i, err := indexByteFrom(f, floor, end, '\n'); err != nil {
+				return nil, err
+			} else if i >= 0 {
+				start = i + 1
+			}
+		}
+	}
+
+	return ReadFileChunk(ctx, fsys, path, start, max(size-start, 1), true)
+}
+
+// FileHeadFingerprint returns a short hex digest of the first n bytes of a file (fewer if the file is smaller).
+// It is used to detect that a file was replaced (e.g. rotated) between calls.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func FileHeadFingerprint(fsys vfs.FS, path string, n int64) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(f, n)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// indexByteFrom returns the absolute offset of the first c in [from, to), or -1.
+func indexByteFrom(f vfs.File, from, to int64, c byte) (int64, error) {
+	buf := make([]byte, tailBlockSize)
+	for pos := from; pos < to; {
+		blk := buf[:min(int64(len(buf)), to-pos)]
+		n, err := f.ReadAt(blk, pos)
+		if i := bytes.IndexByte(blk[:n], c); i >= 0 {
+			return pos + int64(i), nil
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return -1, nil
+			}
+			return -1, err
+		}
+		pos += int64(n)
+	}
+	return -1, nil
+}
@@ -0,0 +1,120 @@
+package ioutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestTailFileLines(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		writeFile(t, p, content)
+		return p
+	}
+	withNL := write("nl.txt", "1\n2\n3\n4\n")
+	noNL := write("nonl.txt", "1\n2\n3\n4")
+	empty := write("empty.txt", "")
+	// Many lines spanning several tail blocks.
+	var sb strings.Builder
+	for range 20000 {
+		sb.WriteString("0123456789\n")
+	}
+	big := write("big.txt", sb.String())
+
+	tests := []struct {
+		name     string
+		path     string
+		n        int
+		maxBytes int64
+		want     string
+	}{
+		{name: "last_two_trailing_newline", path: withNL, n: 2, maxBytes: 100, want: "3\n4\n"},
+		{name: "last_two_no_trailing_newline", path: noNL, n: 2, maxBytes: 100, want: "3\n4"},
+		{name: "more_than_available", path: withNL, n: 10, maxBytes: 100, want: "1\n2\n3\n4\n"},
+		{name: "empty_file", path: empty, n: 5, maxBytes: 100, want: ""},
+		{name: "max_bytes_starts_at_line_boundary", path: withNL, n: 4, maxBytes: 4, want: "4\n"},
+		{name: "across_blocks", path: big, n: 7000, maxBytes: 1 << 20, want: strings.Repeat("0123456789\n", 7000)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := TailFileLines(t.Context(), vfs.OS(), tc.path, tc.n, tc.maxBytes)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got.Data) != tc.want {
+				t.Fatalf("data=%q want=%q", string(got.Data), tc.want)
+			}
+			if !got.EOF || got.NextOffset != got.TotalSize {
+				t.Fatalf("expected chunk to end at EOF: %+v", got)
+			}
+		})
+	}
+
+	if _, err := TailFileLines(t.Context(), vfs.OS(), withNL, 0, 100); err == nil {
+		t.Fatalf("expected error for n=0")
+	}
+	if _, err := TailFileLines(canceledContext(t.Context()), vfs.OS(), withNL, 1, 100); err == nil {
+		t.Fatalf("expected error for canceled context")
+	}
+}
+
+func TestFileHeadFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	writeFile(t, a, "hello world")
+	writeFile(t, b, "hello there")
+
+	fa5, err := FileHeadFingerprint(vfs.OS(), a, 5)
+	if err != nil {
+		t.Fatalf("fingerprint: %v", err)
+	}
+	fb5, _ := FileHeadFingerprint(vfs.OS(), b, 5)
+	if fa5 != fb5 {
+		t.Fatalf("equal prefixes should have equal fingerprints")
+	}
+	fa, _ := FileHeadFingerprint(vfs.OS(), a, 100)
+	fb, _ := FileHeadFingerprint(vfs.OS(), b, 100)
+	if fa == fb {
+		t.Fatalf("different contents should have different fingerprints")
+	}
+}
+
+func TestFileID(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "app.log")
+	writeFile(t, p, "one\n")
+	before, err := FileID(vfs.OS(), p)
+	if err != nil {
+		t.Fatalf("FileID: %v", err)
+	}
+	if before == "" {
+		t.Skip("no file identity on this platform")
+	}
+	if err := os.Rename(p, p+".1"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if moved, _ := FileID(vfs.OS(), p+".1"); moved != before {
+		t.Fatalf("identity changed on rename: %q != %q", moved, before)
+	}
+	writeFile(t, p, "one\n")
+	if after, _ := FileID(vfs.OS(), p); after == before {
+		t.Fatalf("replacement file kept identity %q", after)
+	}
+
+	mem := vfs.NewMemFS()
+	if err := mem.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := vfs.WriteFile(mem, p, []byte("x"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if id, err := FileID(mem, p); err != nil || id != "" {
+		t.Fatalf("MemFS FileID = %q, %v; want empty", id, err)
+	}
+}
@@ -5,27 +5,30 @@ import (
 	"io/fs"
 	"strings"
 	"time"
-	"unicode/utf8"
 
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
 )
 
 var (
-	// ErrNotUTF8Text indicates a file could be read but is not valid UTF‑8.
-	ErrNotUTF8Text        = errors.New("file is not valid UTF-8 text")
+	// ErrNotUTF8Text indicates a file could be read but is not text in UTF‑8 or any detectable encoding
+	// (see DecodeText).
+	ErrNotUTF8Text        = errors.New("file is not valid UTF-8 text or a detectable text encoding")
 	ErrFileExceedsMaxSize = errors.New("file exceeds maximum allowed size")
 )
 
-// TextFile is a normalized in-memory view of a UTF‑8 text file.
+// TextFile is a normalized in-memory view of a text file, decoded to UTF‑8.
 // Lines never include trailing newline characters.
 type TextFile struct {
 	Path            string
 	Perm            fs.FileMode
 	Newline         NewlineKind
 	HasFinalNewline bool
+	Encoding        TextEncoding // on-disk encoding; "" means UTF-8
+	HasBOM          bool         // the file starts with a byte order mark
 	Lines           []string
 	SizeBytes       int64
 	ModTimeUTC      *time.Time
+	Version         string // see ContentVersion; used for optimistic concurrency checks
 }
 
 // Render converts Lines back into a file string preserving newline style and final newline presence.
@@ -44,8 +47,15 @@ func (t *TextFile) Render() string {
 	return s
 }
 
-// ReadTextFileUTF8 reads a file as UTF‑8 text and returns a normalized TextFile view.
-// It preserves newline kind (LF vs CRLF) and whether the file ended with a final newline.
+// Encode renders the file and encodes it in its original encoding, restoring any byte order mark.
+// It fails with ErrUnencodableText if the lines hold characters the encoding cannot represent.
+func (t *TextFile) Encode() ([]byte, error) {
+	return EncodeText(t.Render(), t.Encoding, t.HasBOM)
+}
+
+// ReadTextFileUTF8 reads a text file, decodes it to UTF‑8 and returns a normalized TextFile view.
+// It preserves newline kind (LF vs CRLF), whether the file ended with a final newline, and the
+// detected encoding and BOM (see DecodeText), so Encode can write the file back unchanged.
 //
 // Safety behavior (policy-driven):
 //   - Enforces maxBytes if > 0.
@@ -62,13 +72,15 @@ func ReadTextFileUTF8(p fspolicy.FSPolicy, path string, maxBytes int64) (*TextFi
 	}
 
 	// Use existing utility (bounded).
-	s, err := ReadFile(abs, ReadEncodingText, maxBytes)
+	raw, err := ReadFile(p.FS(), abs, ReadEncodingText, maxBytes)
 	if err != nil {
 		return nil, err
 	}
-	if !utf8.ValidString(s) {
-		return nil, ErrNotUTF8Text
+	dec, err := DecodeText([]byte(raw))
+	if err != nil {
+		return nil, err
 	}
+	s := dec.Text
 
 	kind := detectNewlineKind(s)
 	norm, hasFinal := normalizeNewlines(s, kind)
@@ -87,9 +99,12 @@ func ReadTextFileUTF8(p fspolicy.FSPolicy, path string, maxBytes int64) (*TextFi
 		Perm:            st.Mode().Perm(),
 		Newline:         kind,
 		HasFinalNewline: hasFinal,
+		Encoding:        dec.Encoding,
+		HasBOM:          dec.HasBOM,
 		Lines:           lines,
 		SizeBytes:       st.Size(),
 		ModTimeUTC:      &mt,
+		Version:         ContentVersion([]byte(raw), mt),
 	}
 	return out, nil
 }
@@ -0,0 +1,246 @@
+package ioutil
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const (
+	// textSniffBytes bounds how much of a file DetectFileTextEncoding inspects.
+	textSniffBytes = 64 * 1024
+	// shiftJISSyncBytes bounds how far ReadTextChunk looks back from an offset for a byte that can
+	// only end a Shift_JIS character (ASCII below 0x40, such as a newline or space).
+	shiftJISSyncBytes = 64 * 1024
+)
+
+// DetectFileTextEncoding detects the encoding of a text file from its first bytes, the way DecodeText
+// does for whole files, and reports whether the file starts with a byte order mark. Files that look
+// binary fail with ErrNotUTF8Text.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func DetectFileTextEncoding(fsys vfs.FS, path string) (enc TextEncoding, bom bool, err error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	head := make([]byte, textSniffBytes+1)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", false, err
+	}
+	truncated := n > textSniffBytes
+	return detectTextEncoding(head[:min(n, textSniffBytes)], truncated)
+}
+
+// ReadTextChunk is ReadFileChunk for a text file in encoding enc: the window is shrunk so it never
+// starts or ends inside a character (or, for UTF-16, a surrogate pair), and the raw bytes can be
+// converted with DecodeTextAs. Offset and NextOffset are byte offsets into the file.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func ReadTextChunk(ctx context.Context, fsys vfs.FS, path string, offset, length int64, enc TextEncoding) (*FileChunk, error) {
+	switch enc {
+	case "", TextEncodingUTF8:
+		return ReadFileChunk(ctx, fsys, path, offset, length, true)
+	case TextEncodingWindows1252, TextEncodingLatin1:
+		return ReadFileChunk(ctx, fsys, path, offset, length, false)
+	case TextEncodingUTF16LE, TextEncodingUTF16BE, TextEncodingShiftJIS:
+	default:
+		return nil, fmt.Errorf("unsupported text encoding %q", enc)
+	}
+
+	if offset > 0 {
+		start, err := alignTextOffset(fsys, path, offset, enc)
+		if err != nil {
+			return nil, err
+		}
+		offset = start
+	}
+	chunk, err := ReadFileChunk(ctx, fsys, path, offset, length, false)
+	if err != nil || chunk.EOF {
+		return chunk, err
+	}
+	n := len(chunk.Data)
+	if enc == TextEncodingShiftJIS {
+		chunk.Data = trimIncompleteShiftJISTail(chunk.Data)
+	} else {
+		chunk.Data = trimIncompleteUTF16Tail(chunk.Data, enc)
+	}
+	if len(chunk.Data) == 0 && n > 0 {
+		return nil, fmt.Errorf("length %d is too small to hold a complete %s character at offset %d", length, enc, offset)
+	}
+	chunk.NextOffset = chunk.Offset + int64(len(chunk.Data))
+	return chunk, nil
+}
+
+// ReadTextLines is ReadFileLines for a text file in encoding enc. Lines end at U+000A; for the
+// ASCII-compatible encodings that is the byte '\n', for UTF-16 the two-byte code unit.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func ReadTextLines(
+	ctx context.Context,
+	fsys vfs.FS,
+	path string,
+	startLine, lineCount int,
+	maxBytes int64,
+	enc TextEncoding,
+) (*FileChunk, error) {
+	return readLines(ctx, fsys, path, startLine, lineCount, maxBytes, enc)
+}
+
+// CountTextLines is CountLines for a text file in encoding enc (see ReadTextLines).
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func CountTextLines(ctx context.Context, fsys vfs.FS, path string, enc TextEncoding) (int, error) {
+	if enc != TextEncodingUTF16LE && enc != TextEncodingUTF16BE {
+		return CountLines(ctx, fsys, path)
+	}
+	f, err := fsys.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	readFrag := lineFragReader(bufio.NewReaderSize(f, 64*1024), enc)
+	count, partial := 0, false
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		frag, err := readFrag()
+		switch {
+		case err == nil:
+			count++
+			partial = false
+		case errors.Is(err, bufio.ErrBufferFull):
+			partial = partial || len(frag) > 0
+		case errors.Is(err, io.EOF):
+			if partial || len(frag) > 0 {
+				count++
+			}
+			return count, nil
+		default:
+			return 0, err
+		}
+	}
+}
+
+// lineFragReader returns a reader of line fragments with the semantics of bufio.Reader.ReadSlice('\n'):
+// a nil error ends a line, bufio.ErrBufferFull means the line continues. For UTF-16 a '\n' byte only
+// ends a line when it is the U+000A code unit; the returned bytes then include the whole unit.
+func lineFragReader(br *bufio.Reader, enc TextEncoding) func() ([]byte, error) {
+	if enc != TextEncodingUTF16LE && enc != TextEncodingUTF16BE {
+		return func() ([]byte, error) { return br.ReadSlice('\n') }
+	}
+	var (
+		pos  int64 // file offset of the next byte
+		last byte  // byte before pos
+	)
+	return func() ([]byte, error) {
+		frag, err := br.ReadSlice('\n')
+		prev := last
+		if len(frag) > 1 {
+			prev = frag[len(frag)-2]
+		}
+		pos += int64(len(frag))
+		if len(frag) > 0 {
+			last = frag[len(frag)-1]
+		}
+		if err != nil {
+			return frag, err
+		}
+		at := pos - 1 // offset of the '\n'
+		if enc == TextEncodingUTF16BE {
+			if at%2 == 1 && prev == 0 {
+				return frag, nil
+			}
+			return frag, bufio.ErrBufferFull
+		}
+		if at%2 != 0 {
+			return frag, bufio.ErrBufferFull
+		}
+		// The high byte follows; Peek may reuse the buffer frag points into.
+		frag = slices.Clone(frag)
+		if b, perr := br.Peek(1); perr == nil && b[0] == 0 {
+			_, _ = br.Discard(1)
+			pos++
+			last = 0
+			return append(frag, 0), nil
+		}
+		return frag, bufio.ErrBufferFull
+	}
+}
+
+// alignTextOffset moves offset (> 0) forward to the next character boundary of a UTF-16 or Shift_JIS
+// file.
+func alignTextOffset(fsys vfs.FS, path string, offset int64, enc TextEncoding) (int64, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if enc != TextEncodingShiftJIS {
+		offset += offset % 2
+		unit := make([]byte, 2)
+		if n, err := f.ReadAt(unit, offset); n == 2 && isUTF16LowSurrogate(unit, enc) {
+			offset += 2
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		return offset, nil
+	}
+
+	// Trail bytes are 0x40-0xFC except 0x7F, so a byte below 0x40 (or 0x7F) always ends a character:
+	// step over characters from the last such byte before offset.
+	from := max(offset-shiftJISSyncBytes, 0)
+	buf := make([]byte, offset-from)
+	n, err := f.ReadAt(buf, from)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	buf = buf[:n]
+	i := len(buf) - 1
+	for i >= 0 && buf[i] >= 0x40 && buf[i] != 0x7f {
+		i--
+	}
+	if i < 0 && from > 0 {
+		return 0, fmt.Errorf("no %s character boundary found within %d bytes before offset %d", enc, shiftJISSyncBytes, offset)
+	}
+	for i++; i < len(buf); i++ {
+		if isShiftJISLead(buf[i]) {
+			i++
+		}
+	}
+	return from + int64(i), nil
+}
+
+func isUTF16LowSurrogate(unit []byte, enc TextEncoding) bool {
+	hi := unit[0]
+	if enc == TextEncodingUTF16LE {
+		hi = unit[1]
+	}
+	return hi >= 0xdc && hi <= 0xdf
+}
+
+// trimIncompleteUTF16Tail drops an odd trailing byte and a trailing high surrogate (if any).
+func trimIncompleteUTF16Tail(b []byte, enc TextEncoding) []byte {
+	b = b[:len(b)&^1]
+	if len(b) < 2 {
+		return b
+	}
+	hi := b[len(b)-2]
+	if enc == TextEncodingUTF16LE {
+		hi = b[len(b)-1]
+	}
+	if hi >= 0xd8 && hi <= 0xdb {
+		return b[:len(b)-2]
+	}
+	return b
+}
@@ -0,0 +1,157 @@
+package ioutil
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestReadTextChunk(t *testing.T) {
+	dir := t.TempDir()
+	// "a😀b" in UTF-16BE without BOM: a=0-1, surrogate pair=2-5, b=6-7.
+	be, err := EncodeText("a😀b", TextEncodingUTF16BE, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bePath := filepath.Join(dir, "be.txt")
+	mustWriteBytes(t, bePath, be)
+	// "ｱ" is single-byte (0xB1); "日本" are double-byte. A space anchors the Shift_JIS resync.
+	sjis, err := EncodeText("ｱ日本 日", TextEncodingShiftJIS, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sjisPath := filepath.Join(dir, "sjis.txt")
+	mustWriteBytes(t, sjisPath, sjis)
+
+	tests := []struct {
+		name       string
+		path       string
+		enc        TextEncoding
+		offset     int64
+		length     int64
+		want       string
+		wantOffset int64
+		wantNext   int64
+		wantErr    string
+	}{
+		{name: "utf16_odd_offset_rounds_up", path: bePath, enc: TextEncodingUTF16BE, offset: 1, length: 10, want: "😀b", wantOffset: 2, wantNext: 8},
+		{name: "utf16_low_surrogate_skipped", path: bePath, enc: TextEncodingUTF16BE, offset: 4, length: 10, want: "b", wantOffset: 6, wantNext: 8},
+		{name: "utf16_high_surrogate_left_for_next", path: bePath, enc: TextEncodingUTF16BE, offset: 0, length: 5, want: "a", wantNext: 2},
+		{name: "utf16_too_small_errors", path: bePath, enc: TextEncodingUTF16BE, offset: 2, length: 2, wantErr: "too small"},
+		{name: "sjis_trail_byte_skipped", path: sjisPath, enc: TextEncodingShiftJIS, offset: 2, length: 10, want: "本 日", wantOffset: 3, wantNext: 8},
+		{name: "sjis_after_anchor", path: sjisPath, enc: TextEncodingShiftJIS, offset: 7, length: 10, want: "", wantOffset: 8, wantNext: 8},
+		{name: "sjis_lead_byte_left_for_next", path: sjisPath, enc: TextEncodingShiftJIS, offset: 0, length: 4, want: "ｱ日", wantNext: 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadTextChunk(t.Context(), vfs.OS(), tc.path, tc.offset, tc.length, tc.enc)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err=%v want substring %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			text, err := DecodeTextAs(got.Data, tc.enc)
+			if err != nil || text != tc.want || got.Offset != tc.wantOffset || got.NextOffset != tc.wantNext {
+				t.Fatalf("got=%+v text=%q err=%v", got, text, err)
+			}
+		})
+	}
+}
+
+func TestReadTextLines(t *testing.T) {
+	dir := t.TempDir()
+	// U+010A ("Ċ") is 0A 01 in UTF-16LE: its 0x0A byte must not end a line.
+	le, err := EncodeText("Ċx\nyz\n", TextEncodingUTF16LE, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lePath := filepath.Join(dir, "le.txt")
+	mustWriteBytes(t, lePath, le)
+	// U+0A00 is 0A 00 in UTF-16BE, and the unit after it starts with 00.
+	be, err := EncodeText("\u0a00a\nb", TextEncodingUTF16BE, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bePath := filepath.Join(dir, "be.txt")
+	mustWriteBytes(t, bePath, be)
+
+	tests := []struct {
+		name      string
+		path      string
+		enc       TextEncoding
+		startLine int
+		want      string
+		wantLines int
+	}{
+		{name: "utf16le_first", path: lePath, enc: TextEncodingUTF16LE, startLine: 1, want: "\ufeffĊx\n", wantLines: 2},
+		{name: "utf16le_second", path: lePath, enc: TextEncodingUTF16LE, startLine: 2, want: "yz\n", wantLines: 2},
+		{name: "utf16be_first", path: bePath, enc: TextEncodingUTF16BE, startLine: 1, want: "\u0a00a\n", wantLines: 2},
+		{name: "utf16be_unterminated_last", path: bePath, enc: TextEncodingUTF16BE, startLine: 2, want: "b", wantLines: 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ReadTextLines(t.Context(), vfs.OS(), tc.path, tc.startLine, 1, 1024, tc.enc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			text, err := DecodeTextAs(got.Data, tc.enc)
+			if err != nil || text != tc.want || got.StartLine != tc.startLine || got.EndLine != tc.startLine {
+				t.Fatalf("got=%+v text=%q err=%v", got, text, err)
+			}
+			n, err := CountTextLines(t.Context(), vfs.OS(), tc.path, tc.enc)
+			if err != nil || n != tc.wantLines {
+				t.Fatalf("CountTextLines=%d,%v want %d", n, err, tc.wantLines)
+			}
+		})
+	}
+}
+
+func TestDetectFileTextEncoding(t *testing.T) {
+	dir := t.TempDir()
+	// The sniffed head ends between the two bytes of a character; detection must still succeed.
+	kana, err := EncodeText("あ", TextEncodingShiftJIS, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sjis := append(bytes.Repeat([]byte{'a'}, textSniffBytes-5), bytes.Repeat(kana, 4)...)
+	le, err := EncodeText("hi", TextEncodingUTF16LE, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    TextEncoding
+		wantBOM bool
+		wantErr bool
+	}{
+		{name: "sjis_cut_head", data: sjis, want: TextEncodingShiftJIS},
+		{name: "utf16_bom", data: le, want: TextEncodingUTF16LE, wantBOM: true},
+		{name: "utf8", data: []byte("héllo"), want: TextEncodingUTF8},
+		{name: "binary_errors", data: []byte{0, 1, 2, 0xff}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name)
+			mustWriteBytes(t, path, tc.data)
+			enc, bom, err := DetectFileTextEncoding(vfs.OS(), path)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", enc)
+				}
+				return
+			}
+			if err != nil || enc != tc.want || bom != tc.wantBOM {
+				t.Fatalf("got %q bom=%v err=%v, want %q bom=%v", enc, bom, err, tc.want, tc.wantBOM)
+			}
+		})
+	}
+}
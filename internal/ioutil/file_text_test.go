@@ -173,3 +173,79 @@ func TestReadTextFileUTF8_Behavior(t *testing.T) {
 		})
 	}
 }
+
+func TestReadTextFileUTF8_PreservesEncoding(t *testing.T) {
+	dir := t.TempDir()
+	policy, err := fspolicy.New("", nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		edit    string // replaces the first line
+		wantEnc TextEncoding
+		wantBOM bool
+		want    []byte
+		wantErr error
+	}{
+		{
+			name:    "windows1252_crlf",
+			data:    []byte("caf\xe9\r\n\x93q\x94\r\n"),
+			edit:    "thé",
+			wantEnc: TextEncodingWindows1252,
+			want:    []byte("th\xe9\r\n\x93q\x94\r\n"),
+		},
+		{
+			name:    "utf16le_bom",
+			data:    []byte("\xff\xfea\x00\n\x00b\x00"),
+			edit:    "é",
+			wantEnc: TextEncodingUTF16LE,
+			wantBOM: true,
+			want:    []byte("\xff\xfe\xe9\x00\n\x00b\x00"),
+		},
+		{
+			name:    "utf8_bom",
+			data:    []byte("\xef\xbb\xbfa\nb\n"),
+			edit:    "z",
+			wantEnc: TextEncodingUTF8,
+			wantBOM: true,
+			want:    []byte("\xef\xbb\xbfz\nb\n"),
+		},
+		{
+			name:    "unencodable_edit",
+			data:    []byte("caf\xe9\n"),
+			edit:    "日本",
+			wantEnc: TextEncodingLatin1,
+			wantErr: ErrUnencodableText,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := filepath.Join(dir, tc.name+".txt")
+			mustWriteBytes(t, p, tc.data)
+			tf, err := ReadTextFileUTF8(policy, p, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tf.Encoding != tc.wantEnc || tf.HasBOM != tc.wantBOM {
+				t.Fatalf("Encoding=%q HasBOM=%v want %q %v", tf.Encoding, tf.HasBOM, tc.wantEnc, tc.wantBOM)
+			}
+			tf.Lines[0] = tc.edit
+			got, err := tf.Encode()
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err=%v want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if string(got) != string(tc.want) {
+				t.Fatalf("Encode()=%q want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package ioutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// ErrStaleVersion indicates a file changed after the caller obtained its version token.
+var ErrStaleVersion = errors.New("file changed since it was read (stale version)")
+
+const fileVersionPrefix = "sha256:"
+
+// ContentVersion returns the version token for file content data last modified at modTime.
+// Format: "sha256:<hex digest>@<mtime unix nanoseconds>".
+func ContentVersion(data []byte, modTime time.Time) string {
+	sum := sha256.Sum256(data)
+	return formatFileVersion(sum[:], modTime)
+}
+
+// FileVersion hashes the file at path and returns its version token.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func FileVersion(ctx context.Context, fsys vfs.FS, path string) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := copyWithContext(ctx, h, f); err != nil {
+		return "", err
+	}
+	return formatFileVersion(h.Sum(nil), st.ModTime()), nil
+}
+
+// WrittenFileVersion returns the version token of a file that was just written with data,
+// without reading it back.
+func WrittenFileVersion(fsys vfs.FS, path string, data []byte) (string, error) {
+	st, err := fsys.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return ContentVersion(data, st.ModTime()), nil
+}
+
+// CheckFileVersion returns an error wrapping ErrStaleVersion if the file at path no longer matches expected.
+// An empty expected version always passes. A missing file is stale.
+//
+// The check narrows, but does not close, the window between reading and writing a file:
+// callers should check immediately before committing their write.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func CheckFileVersion(ctx context.Context, fsys vfs.FS, path, expected string) error {
+	expected = strings.TrimSpace(expected)
+	if expected == "" {
+		return nil
+	}
+	if err := validateFileVersion(expected); err != nil {
+		return err
+	}
+	current, err := FileVersion(ctx, fsys, path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s no longer exists", ErrStaleVersion, path)
+		}
+		return err
+	}
+	return compareFileVersion(path, expected, current)
+}
+
+// CheckVersion is CheckFileVersion against the version captured when t was read.
+func (t *TextFile) CheckVersion(expected string) error {
+	expected = strings.TrimSpace(expected)
+	if expected == "" {
+		return nil
+	}
+	if err := validateFileVersion(expected); err != nil {
+		return err
+	}
+	return compareFileVersion(t.Path, expected, t.Version)
+}
+
+func formatFileVersion(sum []byte, modTime time.Time) string {
+	return fileVersionPrefix + hex.EncodeToString(sum) + "@" + strconv.FormatInt(modTime.UTC().UnixNano(), 10)
+}
+
+func compareFileVersion(path, expected, current string) error {
+	if expected != current {
+		return fmt.Errorf(
+			"%w: %s (expected %s, current %s); read the file again before editing",
+			ErrStaleVersion, path, expected, current,
+		)
+	}
+	return nil
+}
+
+func validateFileVersion(v string) error {
+	rest, ok := strings.CutPrefix(v, fileVersionPrefix)
+	digest, mtime, ok2 := strings.Cut(rest, "@")
+	if !ok || !ok2 || len(digest) != 2*sha256.Size {
+		return fmt.Errorf("invalid expectedVersion: %q", v)
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return fmt.Errorf("invalid expectedVersion: %q", v)
+	}
+	if _, err := strconv.ParseInt(mtime, 10, 64); err != nil {
+		return fmt.Errorf("invalid expectedVersion: %q", v)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package ioutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestFileVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "v.txt")
+	writeFile(t, path, "hello")
+	mt := time.Unix(1700000000, 5)
+	if err := os.Chtimes(path, mt, mt); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+
+	got, err := FileVersion(t.Context(), vfs.OS(), path)
+	if err != nil {
+		t.Fatalf("FileVersion: %v", err)
+	}
+	if want := ContentVersion([]byte("hello"), mt); got != want {
+		t.Fatalf("FileVersion=%q want ContentVersion=%q", got, want)
+	}
+	if w, err := WrittenFileVersion(vfs.OS(), path, []byte("hello")); err != nil || w != got {
+		t.Fatalf("WrittenFileVersion=%q,%v want %q", w, err, got)
+	}
+
+	tests := []struct {
+		name      string
+		mutate    func(t *testing.T)
+		expected  string
+		wantStale bool
+		wantErr   string
+	}{
+		{name: "empty_expected_always_passes", expected: ""},
+		{name: "unchanged_passes", expected: got},
+		{name: "malformed_errors", expected: "sha256:zz@1", wantErr: "invalid expectedVersion"},
+		{
+			name: "content_change_is_stale",
+			mutate: func(t *testing.T) {
+				t.Helper()
+				writeFile(t, path, "jello")
+				if err := os.Chtimes(path, mt, mt); err != nil {
+					t.Fatalf("chtimes: %v", err)
+				}
+			},
+			expected:  got,
+			wantStale: true,
+		},
+		{
+			name: "mtime_change_is_stale",
+			mutate: func(t *testing.T) {
+				t.Helper()
+				later := mt.Add(time.Second)
+				if err := os.Chtimes(path, later, later); err != nil {
+					t.Fatalf("chtimes: %v", err)
+				}
+			},
+			expected:  got,
+			wantStale: true,
+		},
+		{
+			name: "missing_file_is_stale",
+			mutate: func(t *testing.T) {
+				t.Helper()
+				if err := os.Remove(path); err != nil {
+					t.Fatalf("remove: %v", err)
+				}
+			},
+			expected:  got,
+			wantStale: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			writeFile(t, path, "hello")
+			if err := os.Chtimes(path, mt, mt); err != nil {
+				t.Fatalf("chtimes: %v", err)
+			}
+			if tc.mutate != nil {
+				tc.mutate(t)
+			}
+			err := CheckFileVersion(t.Context(), vfs.OS(), path, tc.expected)
+			switch {
+			case tc.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err=%v want substring %q", err, tc.wantErr)
+				}
+			case tc.wantStale:
+				if !errors.Is(err, ErrStaleVersion) {
+					t.Fatalf("err=%v want ErrStaleVersion", err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	t.Run("canceled_context", func(t *testing.T) {
+		if _, err := FileVersion(canceledContext(t.Context()), vfs.OS(), path); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
+
+func TestTextFileCheckVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "t.txt")
+	writeFile(t, path, "a\nb\n")
+	policy, err := fspolicy.New("", nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tf, err := ReadTextFileUTF8(policy, path, 0)
+	if err != nil {
+		t.Fatalf("ReadTextFileUTF8: %v", err)
+	}
+	want, err := FileVersion(t.Context(), vfs.OS(), path)
+	if err != nil {
+		t.Fatalf("FileVersion: %v", err)
+	}
+	if tf.Version != want {
+		t.Fatalf("TextFile.Version=%q want %q", tf.Version, want)
+	}
+	if err := tf.CheckVersion(want); err != nil {
+		t.Fatalf("CheckVersion(current): %v", err)
+	}
+	other := ContentVersion([]byte("x"), time.Unix(0, 0))
+	if err := tf.CheckVersion(other); !errors.Is(err, ErrStaleVersion) {
+		t.Fatalf("CheckVersion(other)=%v want ErrStaleVersion", err)
+	}
+}
@@ -1,8 +1,11 @@
 package ioutil
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -67,6 +70,26 @@ func WriteFileAtomicBytesWithParents(
 	return dst, writeFileAtomicBytesResolved(p, dst, data, perm, overwrite, true)
 }
 
+// WriteFileAtomicReaderResolved streams r into dst atomically. dst must be an absolute,
+// policy-resolved path; its parent is verified when symlinks are blocked.
+func WriteFileAtomicReaderResolved(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	dst string,
+	r io.Reader,
+	perm fs.FileMode,
+	overwrite bool,
+) (int64, error) {
+	dst = strings.TrimSpace(dst)
+	if dst == "" || strings.ContainsRune(dst, 0) {
+		return 0, ErrInvalidPath
+	}
+	if !filepath.IsAbs(dst) {
+		return 0, fmt.Errorf("path must be absolute: %s", dst)
+	}
+	return writeFileAtomicReaderResolved(ctx, p, filepath.Clean(dst), r, perm, overwrite, false)
+}
+
 func writeFileAtomicBytesResolved(
 	p fspolicy.FSPolicy,
 	dst string,
@@ -75,73 +98,121 @@ func writeFileAtomicBytesResolved(
 	overwrite bool,
 	parentAlreadyChecked bool,
 ) error {
+	// The size is known up front: fail before writing anything rather than part way through.
+	if err := p.Quota().Check(int64(len(data)), int64(len(data))); err != nil {
+		return err
+	}
+	n, err := writeFileAtomicReaderResolved(
+		context.Background(),
+		p,
+		dst,
+		bytes.NewReader(data),
+		perm,
+		overwrite,
+		parentAlreadyChecked,
+	)
+	if err != nil {
+		return err
+	}
+	if n != int64(len(data)) {
+		return fmt.Errorf("short write: wrote %d bytes, expected %d", n, len(data))
+	}
+	return nil
+}
+
+// writeFileAtomicReaderResolved streams r into a temp file next to dst and commits it atomically.
+// It checks ctx between write iterations.
+func writeFileAtomicReaderResolved(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	dst string,
+	r io.Reader,
+	perm fs.FileMode,
+	overwrite bool,
+	parentAlreadyChecked bool,
+) (int64, error) {
 	parent := filepath.Dir(dst)
+	fsys := p.FS()
 
 	if p.BlockSymlinks() && !parentAlreadyChecked {
 		if err := p.VerifyDirResolved(parent); err != nil {
-			return err
+			return 0, err
 		}
 	}
 
 	// Validate destination type if it already exists (race-hardened).
-	if st, err := os.Lstat(dst); err == nil {
+	created := false
+	if st, err := fsys.Lstat(dst); err == nil {
 		if st.IsDir() {
-			return fmt.Errorf("path is a directory, not a file: %s", dst)
+			return 0, fmt.Errorf("path is a directory, not a file: %s", dst)
 		}
 		if (st.Mode() & os.ModeSymlink) != 0 {
 			if p.BlockSymlinks() {
-				return fmt.Errorf(
+				return 0, fmt.Errorf(
 					"%w: refusing to write to symlink destination: %s",
 					fspolicy.ErrSymlinkDisallowed,
 					dst,
 				)
 			}
 			// Even if symlinks are allowed, writing to an existing symlink destination is ambiguous across platforms.
-			return fmt.Errorf("refusing to write to symlink destination: %s", dst)
+			return 0, fmt.Errorf("refusing to write to symlink destination: %s", dst)
 		}
 		if !st.Mode().IsRegular() {
-			return fmt.Errorf("refusing to write to non-regular file: %s", dst)
+			return 0, fmt.Errorf("refusing to write to non-regular file: %s", dst)
 		}
 		if !overwrite {
-			return fmt.Errorf("file already exists: %w", os.ErrExist)
+			return 0, fmt.Errorf("file already exists: %w", os.ErrExist)
 		}
-	} else if !errors.Is(err, os.ErrNotExist) {
-		return err
+	} else if errors.Is(err, os.ErrNotExist) {
+		created = true
+	} else {
+		return 0, err
 	}
 
-	tmp, err := os.CreateTemp(parent, ".tmp-llmtools-*")
+	q := p.Quota()
+	if created {
+		if err := q.CreateFile(); err != nil {
+			return 0, err
+		}
+	}
+	tmp, err := fsys.CreateTemp(parent, ".tmp-llmtools-*")
 	if err != nil {
-		return err
+		if created {
+			q.ReleaseFile()
+		}
+		return 0, err
 	}
 	tmpName := tmp.Name()
 
+	mr := MeteredReader(r, q, 0)
 	cleanup := func(retErr error) error {
 		_ = tmp.Close()
-		_ = os.Remove(tmpName)
+		_ = fsys.Remove(tmpName)
+		mr.Refund()
+		if created {
+			q.ReleaseFile()
+		}
 		return retErr
 	}
 
-	_ = tmp.Chmod(perm)
+	_ = fsys.Chmod(tmpName, perm)
 
-	n, err := tmp.Write(data)
+	written, err := copyWithContext(ctx, tmp, mr)
 	if err != nil {
-		return cleanup(err)
-	}
-	if n != len(data) {
-		return cleanup(fmt.Errorf("short write: wrote %d bytes, expected %d", n, len(data)))
+		return written, cleanup(err)
 	}
 	if err := tmp.Sync(); err != nil {
-		return cleanup(err)
+		return written, cleanup(err)
 	}
 	if err := tmp.Close(); err != nil {
-		return cleanup(err)
+		return written, cleanup(err)
 	}
 
-	if err := commitAtomicTempFile(tmpName, dst, parent, perm, overwrite); err != nil {
-		return cleanup(err)
+	if err := commitAtomicTempFileFS(fsys, tmpName, dst, parent, perm, overwrite); err != nil {
+		return written, cleanup(err)
 	}
 
 	// TmpName may or may not exist depending on commit strategy; remove is best-effort.
-	_ = os.Remove(tmpName)
-	return nil
+	_ = fsys.Remove(tmpName)
+	return written, nil
 }
@@ -0,0 +1,213 @@
+package ioutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// AppendFileBytesResolved appends data to dst, creating it (with perm) if it does not exist.
+// dst must be an absolute, policy-resolved path whose parent already exists.
+//
+// The data is written through an O_APPEND descriptor in a single write call, so on local filesystems
+// concurrent appenders do not interleave or overwrite each other (a POSIX guarantee that Windows
+// also provides for FILE_APPEND_DATA handles). Network filesystems may not honor this.
+//
+// It returns the offset at which data was written and the file size right after the write.
+func AppendFileBytesResolved(
+	p fspolicy.FSPolicy,
+	dst string,
+	data []byte,
+	perm fs.FileMode,
+) (offset, size int64, err error) {
+	dst, err = cleanAbsFilePath(dst)
+	if err != nil {
+		return 0, 0, err
+	}
+	before, err := checkInPlaceWriteTarget(p, dst, false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	q := p.Quota()
+	var f vfs.File
+	created := false
+	if before == nil {
+		if err := q.CreateFile(); err != nil {
+			return 0, 0, err
+		}
+		// O_EXCL never follows a symlink planted after the Lstat above. Losing a creation race to
+		// another appender is fine: re-check the file it created and append to that.
+		f, err = p.FS().OpenFile(dst, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, perm)
+		created = err == nil
+		if !created {
+			q.ReleaseFile()
+		}
+		if errors.Is(err, os.ErrExist) {
+			if before, err = checkInPlaceWriteTarget(p, dst, true); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if before != nil {
+		f, err = p.FS().OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	if err := verifyOpenedSameFile(f, dst, before); err != nil {
+		return 0, 0, err
+	}
+	var prior int64
+	if before != nil {
+		st, err := f.Stat()
+		if err != nil {
+			return 0, 0, err
+		}
+		prior = st.Size()
+	}
+	if err := q.Write(int64(len(data)), prior+int64(len(data))); err != nil {
+		if created {
+			// The file is ours and still empty.
+			_ = f.Close()
+			_ = p.FS().Remove(dst)
+			q.ReleaseFile()
+		}
+		return 0, 0, err
+	}
+
+	n, err := f.Write(data)
+	if err != nil {
+		q.Refund(int64(len(data) - n))
+		return 0, 0, err
+	}
+	end, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, 0, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	return end - int64(n), st.Size(), nil
+}
+
+// WriteFileAtResolved overwrites len(data) bytes of an existing regular file starting at offset,
+// extending the file if the write runs past its end. offset must not exceed the current size
+// (no sparse holes). The write is in place and therefore not atomic.
+//
+// It returns the file size right after the write.
+func WriteFileAtResolved(
+	p fspolicy.FSPolicy,
+	dst string,
+	offset int64,
+	data []byte,
+) (size int64, err error) {
+	if offset < 0 {
+		return 0, errors.New("offset must be >= 0")
+	}
+	dst, err = cleanAbsFilePath(dst)
+	if err != nil {
+		return 0, err
+	}
+	before, err := checkInPlaceWriteTarget(p, dst, true)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := p.FS().OpenFile(dst, os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if err := verifyOpenedSameFile(f, dst, before); err != nil {
+		return 0, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if offset > st.Size() {
+		return 0, fmt.Errorf("offset %d is beyond end of file (size %d bytes)", offset, st.Size())
+	}
+	after := max(st.Size(), offset+int64(len(data)))
+	if err := p.Quota().Write(int64(len(data)), after); err != nil {
+		return 0, err
+	}
+
+	if n, err := f.WriteAt(data, offset); err != nil {
+		p.Quota().Refund(int64(len(data) - n))
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	return after, nil
+}
+
+func cleanAbsFilePath(dst string) (string, error) {
+	dst = strings.TrimSpace(dst)
+	if dst == "" || strings.ContainsRune(dst, 0) {
+		return "", ErrInvalidPath
+	}
+	if !filepath.IsAbs(dst) {
+		return "", fmt.Errorf("path must be absolute: %s", dst)
+	}
+	return filepath.Clean(filepath.FromSlash(dst)), nil
+}
+
+// checkInPlaceWriteTarget validates dst for an in-place write and returns its Lstat info (nil if missing).
+// Parents are verified when symlinks are blocked; the target itself must be a regular file, never a symlink.
+func checkInPlaceWriteTarget(p fspolicy.FSPolicy, dst string, mustExist bool) (fs.FileInfo, error) {
+	if p.BlockSymlinks() {
+		if err := p.VerifyDirResolved(filepath.Dir(dst)); err != nil {
+			return nil, err
+		}
+	}
+	st, err := p.FS().Lstat(dst)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !mustExist {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if st.IsDir() {
+		return nil, fmt.Errorf("path is a directory, not a file: %s", dst)
+	}
+	if (st.Mode() & os.ModeSymlink) != 0 {
+		if p.BlockSymlinks() {
+			return nil, fmt.Errorf("%w: refusing to write to symlink destination: %s", fspolicy.ErrSymlinkDisallowed, dst)
+		}
+		return nil, fmt.Errorf("refusing to write to symlink destination: %s", dst)
+	}
+	if !st.Mode().IsRegular() {
+		return nil, fmt.Errorf("refusing to write to non-regular file: %s", dst)
+	}
+	return st, nil
+}
+
+// verifyOpenedSameFile guards against dst being swapped (e.g. for a symlink) between Lstat and open.
+func verifyOpenedSameFile(f vfs.File, dst string, before fs.FileInfo) error {
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !st.Mode().IsRegular() {
+		return fmt.Errorf("refusing to write to non-regular file: %s", dst)
+	}
+	if before != nil && !vfs.SameFile(before, st) {
+		return fmt.Errorf("file changed while opening: %s", dst)
+	}
+	return nil
+}
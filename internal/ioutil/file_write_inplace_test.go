@@ -0,0 +1,140 @@
+package ioutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+)
+
+func TestAppendFileBytesResolved(t *testing.T) {
+	dir := t.TempDir()
+	p, err := fspolicy.New(dir, nil, true)
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+
+	t.Run("creates_then_appends", func(t *testing.T) {
+		path := filepath.Join(dir, "log.txt")
+		off, size, err := AppendFileBytesResolved(p, path, []byte("a\n"), 0o600)
+		if err != nil || off != 0 || size != 2 {
+			t.Fatalf("first append: off=%d size=%d err=%v", off, size, err)
+		}
+		off, size, err = AppendFileBytesResolved(p, path, []byte("bc\n"), 0o600)
+		if err != nil || off != 2 || size != 5 {
+			t.Fatalf("second append: off=%d size=%d err=%v", off, size, err)
+		}
+		if got, _ := os.ReadFile(path); string(got) != "a\nbc\n" {
+			t.Fatalf("content=%q", got)
+		}
+	})
+
+	t.Run("concurrent_appenders_do_not_clobber", func(t *testing.T) {
+		path := filepath.Join(dir, "concurrent.txt")
+		const writers, perWriter = 8, 50
+		var wg sync.WaitGroup
+		errs := make(chan error, writers*perWriter)
+		for w := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range perWriter {
+					line := fmt.Sprintf("w%d-%03d\n", w, i)
+					if _, _, err := AppendFileBytesResolved(p, path, []byte(line), 0o600); err != nil {
+						errs <- err
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("append: %v", err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+		if len(lines) != writers*perWriter {
+			t.Fatalf("got %d lines, want %d", len(lines), writers*perWriter)
+		}
+		for _, l := range lines {
+			if len(l) != len("w0-000") {
+				t.Fatalf("interleaved line %q", l)
+			}
+		}
+	})
+
+	t.Run("refuses_directory", func(t *testing.T) {
+		if _, _, err := AppendFileBytesResolved(p, dir, []byte("x"), 0o600); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+
+	t.Run("refuses_symlink_target", func(t *testing.T) {
+		target := filepath.Join(dir, "target.txt")
+		writeFile(t, target, "t")
+		link := filepath.Join(dir, "link.txt")
+		mustSymlinkOrSkip(t, target, link)
+		_, _, err := AppendFileBytesResolved(p, link, []byte("x"), 0o600)
+		if !errors.Is(err, fspolicy.ErrSymlinkDisallowed) {
+			t.Fatalf("err=%v want ErrSymlinkDisallowed", err)
+		}
+		if got, _ := os.ReadFile(target); string(got) != "t" {
+			t.Fatalf("target modified: %q", got)
+		}
+	})
+}
+
+func TestWriteFileAtResolved(t *testing.T) {
+	dir := t.TempDir()
+	p, err := fspolicy.New(dir, nil, true)
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		initial  []byte
+		offset   int64
+		data     []byte
+		want     []byte
+		wantSize int64
+		wantErr  string
+	}{
+		{name: "patch_middle", initial: []byte("abcdef"), offset: 2, data: []byte("XY"), want: []byte("abXYef"), wantSize: 6},
+		{name: "extend_past_end", initial: []byte("abc"), offset: 2, data: []byte("XYZ"), want: []byte("abXYZ"), wantSize: 5},
+		{name: "offset_at_end_appends", initial: []byte("abc"), offset: 3, data: []byte("d"), want: []byte("abcd"), wantSize: 4},
+		{name: "offset_beyond_end_errors", initial: []byte("abc"), offset: 4, data: []byte("d"), want: []byte("abc"), wantErr: "beyond end of file"},
+		{name: "negative_offset_errors", initial: []byte("abc"), offset: -1, data: []byte("d"), want: []byte("abc"), wantErr: "offset"},
+		{name: "missing_file_errors", offset: 0, data: []byte("d"), wantErr: "no such file"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name+".bin")
+			if tc.initial != nil {
+				mustWriteBytes(t, path, tc.initial)
+			}
+			size, err := WriteFileAtResolved(p, path, tc.offset, tc.data)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err=%v want substring %q", err, tc.wantErr)
+				}
+			} else if err != nil || size != tc.wantSize {
+				t.Fatalf("size=%d err=%v want size %d", size, err, tc.wantSize)
+			}
+			if tc.want != nil {
+				if got, _ := os.ReadFile(path); !bytes.Equal(got, tc.want) {
+					t.Fatalf("content=%q want %q", got, tc.want)
+				}
+			}
+		})
+	}
+}
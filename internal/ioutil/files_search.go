@@ -10,6 +10,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 var errSearchLimitReached = errors.New("search limit reached")
@@ -55,8 +56,8 @@ func SearchFiles(
 	// returned path formatting based on rootArg/rootReturn.
 	walkRoot := rootAbs
 	if !p.BlockSymlinks() {
-		if st, lerr := os.Lstat(rootAbs); lerr == nil && (st.Mode()&os.ModeSymlink) != 0 {
-			if resolved, rerr := filepath.EvalSymlinks(rootAbs); rerr == nil && resolved != "" {
+		if st, lerr := p.FS().Lstat(rootAbs); lerr == nil && (st.Mode()&os.ModeSymlink) != 0 {
+			if resolved, rerr := p.FS().EvalSymlinks(rootAbs); rerr == nil && resolved != "" {
 				walkRoot = filepath.Clean(resolved)
 			}
 		}
@@ -132,7 +133,7 @@ func SearchFiles(
 		} else {
 			// Check file content only for reasonably small files.
 			if info, _ := d.Info(); info != nil && info.Size() < 1*1024*1024 { // 1 MB guard
-				if data, rerr := os.ReadFile(path); rerr == nil {
+				if data, rerr := vfs.ReadFile(p.FS(), path); rerr == nil {
 					sample := data[:min(len(data), 4096)]
 					if !isProbablyTextSample(sample) || !utf8.Valid(data) {
 						return nil
@@ -151,7 +152,7 @@ func SearchFiles(
 		return nil
 	}
 
-	err = filepath.WalkDir(walkRoot, walkFn)
+	err = vfs.WalkDir(p.FS(), walkRoot, walkFn)
 	if err != nil && !errors.Is(err, errSearchLimitReached) {
 		return nil, reachedLimit, err
 	}
@@ -70,7 +70,7 @@ func ReadImage(
 		}
 	}
 
-	st, err := os.Lstat(abs)
+	st, err := p.FS().Lstat(abs)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			out.Exists = false
@@ -103,7 +103,7 @@ func ReadImage(
 
 	if includeBase64Data {
 
-		f, err := os.Open(out.Path)
+		f, err := p.FS().Open(out.Path)
 		if err != nil {
 			return nil, err
 		}
@@ -136,7 +136,7 @@ func ReadImage(
 	}
 
 	// No base64 requested: just open and decode config.
-	f, err := os.Open(out.Path)
+	f, err := p.FS().Open(out.Path)
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +38,7 @@ func MIMEForPath(
 		return abs, MIMEEmpty, ExtensionModeDefault, MIMEDetectMethodSniff, err
 	}
 
-	mt, m, e := SniffFileMIME(abs)
+	mt, m, e := SniffFileMIME(p.FS(), abs)
 	if e != nil {
 		return abs, MIMEEmpty, ExtensionModeDefault, MIMEDetectMethodSniff, e
 	}
@@ -0,0 +1,194 @@
+package ioutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+)
+
+type MoveMethod string
+
+const (
+	MoveMethodRename        MoveMethod = "rename"
+	MoveMethodCopyAndRemove MoveMethod = "copyAndRemove"
+	MoveMethodMerge         MoveMethod = "merge"
+)
+
+// MovePathResolved moves a file, symlink or directory tree from src to dst.
+// Both paths must be absolute and policy-resolved; dst's parent must already exist.
+//
+// Behavior:
+//   - Same-device moves use rename. Without overwrite, files are moved with a no-clobber rename.
+//   - Cross-device moves fall back to an atomic copy followed by removal of the source.
+//   - Directory moves are scanned against limits first (also bounding any copy fallback).
+//   - If dst is an existing directory and overwrite is true, a directory src is merged into it (see CopyTreeResolved).
+func MovePathResolved(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	src, dst string,
+	overwrite bool,
+	limits TreeLimits,
+) (MoveMethod, TreeStats, error) {
+	if err := ctx.Err(); err != nil {
+		return "", TreeStats{}, err
+	}
+	srcInfo, err := p.FS().Lstat(src)
+	if err != nil {
+		return "", TreeStats{}, err
+	}
+	if srcInfo.IsDir() {
+		return moveTreeResolved(ctx, p, src, dst, overwrite, limits)
+	}
+	method, err := moveNonDirResolved(ctx, p, src, dst, srcInfo, overwrite)
+	if err != nil {
+		return "", TreeStats{}, err
+	}
+	stats := TreeStats{}
+	if (srcInfo.Mode() & os.ModeSymlink) != 0 {
+		stats.Symlinks = 1
+	} else {
+		stats.Files = 1
+		stats.Bytes = srcInfo.Size()
+	}
+	return method, stats, nil
+}
+
+func moveNonDirResolved(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	src, dst string,
+	srcInfo os.FileInfo,
+	overwrite bool,
+) (MoveMethod, error) {
+	isLink := (srcInfo.Mode() & os.ModeSymlink) != 0
+	if isLink && p.BlockSymlinks() {
+		return "", fmt.Errorf("%w: refusing to move symlink: %s", fspolicy.ErrSymlinkDisallowed, src)
+	}
+	if !isLink && !srcInfo.Mode().IsRegular() {
+		return "", fmt.Errorf("refusing to move non-regular file: %s", src)
+	}
+
+	if st, err := p.FS().Lstat(dst); err == nil {
+		if st.IsDir() {
+			return "", fmt.Errorf("destination exists and is a directory: %s", dst)
+		}
+		if (st.Mode()&os.ModeSymlink) != 0 && p.BlockSymlinks() {
+			return "", fmt.Errorf("%w: refusing to replace symlink destination: %s", fspolicy.ErrSymlinkDisallowed, dst)
+		}
+		if !overwrite {
+			return "", fmt.Errorf("destination already exists: %w", os.ErrExist)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	var err error
+	if overwrite {
+		err = p.FS().Rename(src, dst)
+	} else {
+		err = renameNoReplaceFS(p.FS(), src, dst)
+	}
+	if err == nil {
+		_ = syncDirBestEffortFS(p.FS(), filepath.Dir(dst))
+		return MoveMethodRename, nil
+	}
+	if !IsCrossDeviceErr(err) {
+		return "", err
+	}
+
+	// Cross-device: copy then remove the source.
+	if isLink {
+		if err := copySymlink(p.FS(), src, dst, overwrite); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := CopyFileAtomicResolved(ctx, p, src, dst, srcInfo.Mode().Perm(), overwrite); err != nil {
+			return "", err
+		}
+	}
+	if err := p.FS().Remove(src); err != nil {
+		return "", fmt.Errorf("copied to %s but could not remove source: %w", dst, err)
+	}
+	return MoveMethodCopyAndRemove, nil
+}
+
+func moveTreeResolved(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	src, dst string,
+	overwrite bool,
+	limits TreeLimits,
+) (MoveMethod, TreeStats, error) {
+	if err := requireDisjointTrees(src, dst); err != nil {
+		return "", TreeStats{}, err
+	}
+	stats, err := ScanTree(ctx, p, src, limits)
+	if err != nil {
+		return "", TreeStats{}, err
+	}
+
+	if st, err := p.FS().Lstat(dst); err == nil {
+		if !overwrite {
+			return "", TreeStats{}, fmt.Errorf("destination already exists: %w", os.ErrExist)
+		}
+		if !st.IsDir() || (st.Mode()&os.ModeSymlink) != 0 {
+			return "", TreeStats{}, fmt.Errorf("destination exists and is not a directory: %s", dst)
+		}
+		if err := mergeTreeInto(ctx, p, src, dst); err != nil {
+			return "", TreeStats{}, err
+		}
+		return MoveMethodMerge, stats, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", TreeStats{}, err
+	}
+
+	if err := p.FS().Rename(src, dst); err == nil {
+		_ = syncDirBestEffortFS(p.FS(), filepath.Dir(dst))
+		return MoveMethodRename, stats, nil
+	} else if !IsCrossDeviceErr(err) {
+		return "", TreeStats{}, err
+	}
+
+	// Cross-device: copy the (already bounded) tree, then remove the source tree.
+	if _, err := CopyTreeResolved(ctx, p, src, dst, false, limits); err != nil {
+		return "", TreeStats{}, err
+	}
+	if err := p.FS().RemoveAll(src); err != nil {
+		return "", TreeStats{}, fmt.Errorf("copied to %s but could not remove source: %w", dst, err)
+	}
+	return MoveMethodCopyAndRemove, stats, nil
+}
+
+// mergeTreeInto moves each entry of src into the existing directory dst (replacing files), then removes src.
+func mergeTreeInto(ctx context.Context, p fspolicy.FSPolicy, src, dst string) error {
+	entries, err := p.FS().ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		from := filepath.Join(src, e.Name())
+		to := filepath.Join(dst, e.Name())
+		info, err := p.FS().Lstat(from)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// Limits were enforced on the full source tree up front.
+			if _, _, err := moveTreeResolved(ctx, p, from, to, true, TreeLimits{}); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := moveNonDirResolved(ctx, p, from, to, info, true); err != nil {
+			return err
+		}
+	}
+	return p.FS().Remove(src)
+}
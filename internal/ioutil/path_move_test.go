@@ -0,0 +1,142 @@
+package ioutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+)
+
+func TestMovePathResolved(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, dir string) (src, dst string)
+		overwrite  bool
+		wantMethod MoveMethod
+		wantErrIs  error
+		wantErr    bool
+		check      func(t *testing.T, dir string)
+	}{
+		{
+			name: "file_no_clobber",
+			setup: func(t *testing.T, dir string) (string, string) {
+				t.Helper()
+				mustWriteBytes(t, filepath.Join(dir, "a"), []byte("a"))
+				mustWriteBytes(t, filepath.Join(dir, "b"), []byte("b"))
+				return filepath.Join(dir, "a"), filepath.Join(dir, "b")
+			},
+			wantErrIs: os.ErrExist,
+			check: func(t *testing.T, dir string) {
+				t.Helper()
+				if b, _ := os.ReadFile(filepath.Join(dir, "b")); string(b) != "b" {
+					t.Fatalf("destination clobbered: %q", string(b))
+				}
+			},
+		},
+		{
+			name: "file_rename",
+			setup: func(t *testing.T, dir string) (string, string) {
+				t.Helper()
+				mustWriteBytes(t, filepath.Join(dir, "a"), []byte("a"))
+				return filepath.Join(dir, "a"), filepath.Join(dir, "b")
+			},
+			wantMethod: MoveMethodRename,
+			check: func(t *testing.T, dir string) {
+				t.Helper()
+				if _, err := os.Lstat(filepath.Join(dir, "a")); !os.IsNotExist(err) {
+					t.Fatalf("source still present: %v", err)
+				}
+			},
+		},
+		{
+			name: "file_onto_directory_errors",
+			setup: func(t *testing.T, dir string) (string, string) {
+				t.Helper()
+				mustWriteBytes(t, filepath.Join(dir, "a"), []byte("a"))
+				if err := os.Mkdir(filepath.Join(dir, "d"), 0o755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+				return filepath.Join(dir, "a"), filepath.Join(dir, "d")
+			},
+			overwrite: true,
+			wantErr:   true,
+		},
+		{
+			name: "directory_rename",
+			setup: func(t *testing.T, dir string) (string, string) {
+				t.Helper()
+				if err := os.MkdirAll(filepath.Join(dir, "s", "x"), 0o755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+				return filepath.Join(dir, "s"), filepath.Join(dir, "t")
+			},
+			wantMethod: MoveMethodRename,
+			check: func(t *testing.T, dir string) {
+				t.Helper()
+				if st, err := os.Stat(filepath.Join(dir, "t", "x")); err != nil || !st.IsDir() {
+					t.Fatalf("moved dir missing: %v", err)
+				}
+			},
+		},
+		{
+			name: "directory_merge",
+			setup: func(t *testing.T, dir string) (string, string) {
+				t.Helper()
+				if err := os.MkdirAll(filepath.Join(dir, "s", "x"), 0o755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+				if err := os.MkdirAll(filepath.Join(dir, "t", "x"), 0o755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+				mustWriteBytes(t, filepath.Join(dir, "s", "x", "f"), []byte("new"))
+				mustWriteBytes(t, filepath.Join(dir, "t", "x", "f"), []byte("old"))
+				return filepath.Join(dir, "s"), filepath.Join(dir, "t")
+			},
+			overwrite:  true,
+			wantMethod: MoveMethodMerge,
+			check: func(t *testing.T, dir string) {
+				t.Helper()
+				if b, _ := os.ReadFile(filepath.Join(dir, "t", "x", "f")); string(b) != "new" {
+					t.Fatalf("merged content=%q", string(b))
+				}
+				if _, err := os.Lstat(filepath.Join(dir, "s")); !os.IsNotExist(err) {
+					t.Fatalf("source dir still present: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			p, err := fspolicy.New(dir, []string{dir}, true)
+			if err != nil {
+				t.Fatalf("policy: %v", err)
+			}
+			src, dst := tc.setup(t, dir)
+			method, _, err := MovePathResolved(t.Context(), p, src, dst, tc.overwrite, TreeLimits{})
+			switch {
+			case tc.wantErrIs != nil:
+				if !errors.Is(err, tc.wantErrIs) {
+					t.Fatalf("err=%v want errors.Is(_, %v)", err, tc.wantErrIs)
+				}
+			case tc.wantErr:
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if method != tc.wantMethod {
+					t.Fatalf("method=%q want=%q", method, tc.wantMethod)
+				}
+			}
+			if tc.check != nil {
+				tc.check(t, dir)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package ioutil
+
+import (
+	"io"
+
+	"github.com/flexigpt/llmtools-go/quota"
+)
+
+// MeteredReader returns r charging q for every chunk before handing it on, so a write fails with
+// quota.ErrExceeded before the chunk that would exceed a limit is written. base is the size of the
+// file before the streamed bytes (0 for a new or replaced file). A nil q charges nothing.
+//
+// Callers whose write is not committed after all call Refund.
+func MeteredReader(r io.Reader, q *quota.Quota, base int64) *Metered {
+	return &Metered{r: r, q: q, size: base}
+}
+
+// Metered is a reader that charges a write quota; see MeteredReader.
+type Metered struct {
+	r       io.Reader
+	q       *quota.Quota
+	size    int64
+	charged int64
+}
+
+func (m *Metered) Read(b []byte) (int, error) {
+	n, err := m.r.Read(b)
+	if n > 0 {
+		if qerr := m.q.Write(int64(n), m.size+int64(n)); qerr != nil {
+			return 0, qerr
+		}
+		m.size += int64(n)
+		m.charged += int64(n)
+	}
+	return n, err
+}
+
+// Charged reports the bytes charged so far.
+func (m *Metered) Charged() int64 { return m.charged }
+
+// Refund returns the bytes charged so far to the quota.
+func (m *Metered) Refund() {
+	m.q.Refund(m.charged)
+	m.charged = 0
+}
@@ -2,11 +2,13 @@ package ioutil
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"time"
 
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 type PathInfo struct {
@@ -16,11 +18,27 @@ type PathInfo struct {
 	IsDir   bool       `json:"isDir"`
 	Size    int64      `json:"size,omitempty"`
 	ModTime *time.Time `json:"modTime,omitempty"`
+
+	Mode       string `json:"mode,omitempty"` // e.g. "-rw-r--r--" or "drwxr-xr-x"
+	Perm       string `json:"perm,omitempty"` // octal permission bits, e.g. "0644"
+	IsSymlink  bool   `json:"isSymlink,omitempty"`
+	LinkTarget string `json:"linkTarget,omitempty"`
+
+	// Platform-dependent; unset where the OS does not report them.
+	UID        *int       `json:"uid,omitempty"`
+	GID        *int       `json:"gid,omitempty"`
+	OwnerSID   string     `json:"ownerSid,omitempty"` // Windows
+	HardLinks  uint64     `json:"hardLinks,omitempty"`
+	CreateTime *time.Time `json:"createTime,omitempty"`
+	AccessTime *time.Time `json:"accessTime,omitempty"`
 }
 
 // StatPath returns basic metadata for the supplied path without mutating the filesystem.
 // If the path does not exist, exists == false and err == nil.
 //
+// A symlink is reported with IsSymlink and LinkTarget, and the remaining fields describe its target.
+// A dangling symlink has exists == false.
+//
 // FSPolicy enforcement:
 //   - path resolved via policy (base dir + allowed roots)
 //   - if policy.BlockSymlinks == true: refuses symlink targets (Lstat + reject).
@@ -35,13 +53,7 @@ func StatPath(p fspolicy.FSPolicy, path string) (pathInfo *PathInfo, err error)
 		Exists: false,
 	}
 
-	var info fs.FileInfo
-	if p.BlockSymlinks() {
-		info, err = os.Lstat(abs)
-	} else {
-		info, err = os.Stat(abs)
-	}
-
+	info, err := p.FS().Lstat(abs)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			return pathInfo, nil
@@ -49,11 +61,29 @@ func StatPath(p fspolicy.FSPolicy, path string) (pathInfo *PathInfo, err error)
 		return nil, err
 	}
 
-	if p.BlockSymlinks() && (info.Mode()&os.ModeSymlink) != 0 {
-		return nil, fspolicy.ErrSymlinkDisallowed
+	var linkTarget string
+	if (info.Mode() & os.ModeSymlink) != 0 {
+		if p.BlockSymlinks() {
+			return nil, fspolicy.ErrSymlinkDisallowed
+		}
+		if linkTarget, err = p.FS().Readlink(abs); err != nil {
+			return nil, err
+		}
+		if info, err = p.FS().Stat(abs); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				pathInfo.IsSymlink, pathInfo.LinkTarget = true, linkTarget
+				return pathInfo, nil
+			}
+			return nil, err
+		}
 	}
 
 	pInfo := getPathInfoFromFileInfo(abs, info)
+	pInfo.IsSymlink, pInfo.LinkTarget = linkTarget != "", linkTarget
+	// Platform fields (owner, inode, birth time) exist only for the OS filesystem.
+	if vfs.IsOS(p.FS()) {
+		fillPlatformPathInfo(abs, info, &pInfo)
+	}
 	return &pInfo, nil
 }
 
@@ -66,5 +96,12 @@ func getPathInfoFromFileInfo(path string, info fs.FileInfo) PathInfo {
 		IsDir:   info.IsDir(),
 		Size:    info.Size(),
 		ModTime: &m,
+		Mode:    info.Mode().String(),
+		Perm:    fmt.Sprintf("%04o", info.Mode().Perm()),
 	}
 }
+
+func utcTimePtr(t time.Time) *time.Time {
+	t = t.UTC()
+	return &t
+}
@@ -0,0 +1,23 @@
+//go:build darwin
+
+package ioutil
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+// fillPlatformPathInfo adds ownership, link count and access/birth times. info describes path
+// after symlink resolution.
+func fillPlatformPathInfo(_ string, info fs.FileInfo, pi *PathInfo) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	uid, gid := int(st.Uid), int(st.Gid)
+	pi.UID, pi.GID = &uid, &gid
+	pi.HardLinks = uint64(st.Nlink)
+	pi.AccessTime = utcTimePtr(time.Unix(st.Atimespec.Unix()))
+	pi.CreateTime = utcTimePtr(time.Unix(st.Birthtimespec.Unix()))
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package ioutil
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fillPlatformPathInfo adds ownership, link count and access/birth times. info describes path
+// after symlink resolution. Birth time comes from statx and is left unset when the filesystem
+// does not record it.
+func fillPlatformPathInfo(path string, info fs.FileInfo, pi *PathInfo) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		uid, gid := int(st.Uid), int(st.Gid)
+		pi.UID, pi.GID = &uid, &gid
+		pi.HardLinks = uint64(st.Nlink) //nolint:unconvert // Nlink is uint32 on some architectures.
+		pi.AccessTime = utcTimePtr(time.Unix(st.Atim.Unix()))
+	}
+	var stx unix.Statx_t
+	if err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &stx); err == nil &&
+		stx.Mask&unix.STATX_BTIME != 0 {
+		pi.CreateTime = utcTimePtr(time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)))
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package ioutil
+
+import "io/fs"
+
+// fillPlatformPathInfo is a no-op where no platform-specific metadata is collected.
+func fillPlatformPathInfo(string, fs.FileInfo, *PathInfo) {}
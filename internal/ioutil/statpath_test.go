@@ -218,7 +218,56 @@ func TestStatPath_SymlinkBehavior(t *testing.T) {
 	if err != nil {
 		t.Fatalf("StatPath error: %v", err)
 	}
-	if info == nil || !info.Exists || info.IsDir {
+	if info == nil || !info.Exists || info.IsDir || !info.IsSymlink || info.LinkTarget != realTxt || info.Size != 1 {
 		t.Fatalf("unexpected PathInfo: %+v", info)
 	}
+
+	dangling := filepath.Join(dir, "dangling")
+	mustSymlinkOrSkip(t, filepath.Join(dir, "nope"), dangling)
+	info, err = StatPath(pAllow, dangling)
+	if err != nil {
+		t.Fatalf("StatPath error: %v", err)
+	}
+	if info.Exists || !info.IsSymlink || info.LinkTarget == "" {
+		t.Fatalf("unexpected PathInfo for dangling link: %+v", info)
+	}
+}
+
+func TestStatPath_PlatformFields(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	mustWriteBytes(t, path, []byte("x"))
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	policy, err := fspolicy.New("", nil, true)
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+	got, err := StatPath(policy, path)
+	if err != nil {
+		t.Fatalf("StatPath: %v", err)
+	}
+	switch runtime.GOOS {
+	case toolutil.GOOSLinux, toolutil.GOOSDarwin, toolutil.GOOSWindows:
+		if got.HardLinks != 1 || got.AccessTime == nil {
+			t.Fatalf("HardLinks=%d AccessTime=%v", got.HardLinks, got.AccessTime)
+		}
+	default:
+		t.Skipf("no platform metadata collected on %s", runtime.GOOS)
+	}
+
+	switch runtime.GOOS {
+	case toolutil.GOOSWindows:
+		if got.OwnerSID == "" || got.CreateTime == nil {
+			t.Fatalf("OwnerSID=%q CreateTime=%v", got.OwnerSID, got.CreateTime)
+		}
+	case toolutil.GOOSLinux, toolutil.GOOSDarwin:
+		if got.Mode != "-rw-r-----" || got.Perm != "0640" {
+			t.Fatalf("Mode=%q Perm=%q", got.Mode, got.Perm)
+		}
+		if got.UID == nil || *got.UID != os.Getuid() || got.GID == nil {
+			t.Fatalf("UID=%v GID=%v", got.UID, got.GID)
+		}
+	}
 }
@@ -0,0 +1,46 @@
+//go:build windows
+
+package ioutil
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// fillPlatformPathInfo adds the owner SID, link count and creation/access times. info describes
+// path after symlink resolution. Windows has no uid/gid.
+func fillPlatformPathInfo(path string, info fs.FileInfo, pi *PathInfo) {
+	if d, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		pi.CreateTime = utcTimePtr(time.Unix(0, d.CreationTime.Nanoseconds()))
+		pi.AccessTime = utcTimePtr(time.Unix(0, d.LastAccessTime.Nanoseconds()))
+	}
+
+	if sd, err := windows.GetNamedSecurityInfo(
+		path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION,
+	); err == nil {
+		if owner, _, err := sd.Owner(); err == nil && owner != nil {
+			pi.OwnerSID = owner.String()
+		}
+	}
+
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return
+	}
+	// FILE_FLAG_BACKUP_SEMANTICS is required to open directories; zero access only reads attributes.
+	h, err := windows.CreateFile(
+		p, 0, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS, 0,
+	)
+	if err != nil {
+		return
+	}
+	defer windows.CloseHandle(h) //nolint:errcheck // Read-only handle.
+	var bhfi windows.ByHandleFileInformation
+	if err := windows.GetFileInformationByHandle(h, &bhfi); err == nil {
+		pi.HardLinks = uint64(bhfi.NumberOfLinks)
+	}
+}
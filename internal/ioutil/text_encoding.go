@@ -0,0 +1,317 @@
+package ioutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// TextEncoding is the character encoding of a text file.
+// The zero value means UTF-8.
+type TextEncoding string
+
+const (
+	TextEncodingUTF8        TextEncoding = "utf-8"
+	TextEncodingUTF16LE     TextEncoding = "utf-16le"
+	TextEncodingUTF16BE     TextEncoding = "utf-16be"
+	TextEncodingWindows1252 TextEncoding = "windows-1252"
+	TextEncodingLatin1      TextEncoding = "iso-8859-1"
+	TextEncodingShiftJIS    TextEncoding = "shift_jis"
+)
+
+// ErrUnencodableText indicates text contains characters the target encoding cannot represent.
+var ErrUnencodableText = errors.New("text cannot be represented in the file's encoding")
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// DecodedText is the result of DecodeText.
+type DecodedText struct {
+	Text     string // UTF-8, without BOM
+	Encoding TextEncoding
+	HasBOM   bool
+}
+
+// DecodeText detects the encoding of data and returns it as UTF-8.
+//
+// Detection order:
+//   - A byte order mark selects UTF-8 or UTF-16 (LE/BE).
+//   - UTF-16 without BOM is recognized by its pattern of zero bytes (mostly-ASCII text).
+//   - Valid UTF-8 is returned as is.
+//   - Otherwise legacy single/double-byte encodings are tried: Shift_JIS when the bytes form valid,
+//     kana-rich double-byte sequences; Windows-1252 when bytes 0x80-0x9F occur; else ISO-8859-1.
+//
+// Data with NUL bytes or other control characters that is not UTF-8 or UTF-16 is rejected with
+// ErrNotUTF8Text, since it is most likely binary.
+func DecodeText(data []byte) (*DecodedText, error) {
+	enc, bom, err := detectTextEncoding(data, false)
+	if err != nil {
+		return nil, err
+	}
+	if bom {
+		data = data[TextBOMLen(enc):]
+	}
+	text, err := DecodeTextAs(data, enc)
+	if err != nil {
+		return nil, err
+	}
+	return &DecodedText{Text: text, Encoding: enc, HasBOM: bom}, nil
+}
+
+// DecodeTextAs converts data, in encoding enc and without a byte order mark, to UTF-8. Data that is
+// not valid in enc fails with ErrNotUTF8Text.
+func DecodeTextAs(data []byte, enc TextEncoding) (string, error) {
+	switch enc {
+	case "", TextEncodingUTF8:
+		if !utf8.Valid(data) {
+			return "", ErrNotUTF8Text
+		}
+		return string(data), nil
+	case TextEncodingUTF16LE, TextEncodingUTF16BE:
+		dec, err := decodeUTF16(data, enc, false)
+		if err != nil {
+			return "", err
+		}
+		return dec.Text, nil
+	case TextEncodingWindows1252, TextEncodingLatin1, TextEncodingShiftJIS:
+		s, err := textEncoder(enc).NewDecoder().Bytes(data)
+		if err != nil || bytes.ContainsRune(s, utf8.RuneError) {
+			return "", ErrNotUTF8Text
+		}
+		return string(s), nil
+	}
+	return "", fmt.Errorf("unsupported text encoding %q", enc)
+}
+
+// detectTextEncoding picks the encoding of data as documented on DecodeText and reports whether data
+// starts with its byte order mark. A truncated sample (the head of a longer file) may end inside a
+// character; the incomplete tail is ignored.
+func detectTextEncoding(data []byte, truncated bool) (TextEncoding, bool, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return TextEncodingUTF8, true, nil
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return TextEncodingUTF16LE, true, nil
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return TextEncodingUTF16BE, true, nil
+	}
+	whole, utf8Head, legacyHead := data, data, data
+	if truncated {
+		whole = data[:len(data)&^1]
+		utf8Head = trimIncompleteUTF8Tail(data)
+		legacyHead = trimIncompleteShiftJISTail(data)
+	}
+	// Checked before UTF-8: ASCII text in UTF-16 is also valid (NUL-laden) UTF-8.
+	if enc, ok := sniffUTF16(whole); ok {
+		return enc, false, nil
+	}
+	if utf8.Valid(utf8Head) {
+		return TextEncodingUTF8, false, nil
+	}
+	if looksBinary(data) {
+		return "", false, ErrNotUTF8Text
+	}
+	switch {
+	case looksShiftJIS(legacyHead):
+		return TextEncodingShiftJIS, false, nil
+	case hasC1Bytes(data):
+		return TextEncodingWindows1252, false, nil
+	}
+	return TextEncodingLatin1, false, nil
+}
+
+// TextBOMLen returns the length of enc's byte order mark.
+func TextBOMLen(enc TextEncoding) int {
+	switch enc {
+	case TextEncodingUTF16LE, TextEncodingUTF16BE:
+		return len(bomUTF16LE)
+	case "", TextEncodingUTF8:
+		return len(bomUTF8)
+	}
+	return 0
+}
+
+// EncodeText converts UTF-8 text s to enc, optionally prefixed with the encoding's byte order mark.
+// Characters enc cannot represent fail with ErrUnencodableText.
+func EncodeText(s string, enc TextEncoding, bom bool) ([]byte, error) {
+	switch enc {
+	case "", TextEncodingUTF8:
+		if bom {
+			return append(append([]byte{}, bomUTF8...), s...), nil
+		}
+		return []byte(s), nil
+	case TextEncodingUTF16LE, TextEncodingUTF16BE:
+		units := utf16.Encode([]rune(s))
+		out := make([]byte, 0, 2*len(units)+2)
+		put := func(u uint16) {
+			if enc == TextEncodingUTF16LE {
+				out = append(out, byte(u), byte(u>>8))
+			} else {
+				out = append(out, byte(u>>8), byte(u))
+			}
+		}
+		if bom {
+			put(0xfeff)
+		}
+		for _, u := range units {
+			put(u)
+		}
+		return out, nil
+	case TextEncodingWindows1252, TextEncodingLatin1, TextEncodingShiftJIS:
+		out, err := textEncoder(enc).NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			return nil, fmt.Errorf("%w (%s): %w", ErrUnencodableText, enc, err)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported text encoding %q", enc)
+}
+
+func textEncoder(enc TextEncoding) encoding.Encoding {
+	switch enc {
+	case TextEncodingShiftJIS:
+		return japanese.ShiftJIS
+	case TextEncodingWindows1252:
+		return charmap.Windows1252
+	default:
+		return charmap.ISO8859_1
+	}
+}
+
+func decodeUTF16(data []byte, enc TextEncoding, bom bool) (*DecodedText, error) {
+	if len(data)%2 != 0 {
+		return nil, ErrNotUTF8Text
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if enc == TextEncodingUTF16LE {
+			units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+		} else {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		}
+	}
+	var sb strings.Builder
+	sb.Grow(len(units))
+	for i := 0; i < len(units); i++ {
+		u := units[i]
+		switch {
+		case utf16.IsSurrogate(rune(u)):
+			if i+1 >= len(units) {
+				return nil, ErrNotUTF8Text
+			}
+			r := utf16.DecodeRune(rune(u), rune(units[i+1]))
+			if r == utf8.RuneError {
+				return nil, ErrNotUTF8Text
+			}
+			sb.WriteRune(r)
+			i++
+		default:
+			sb.WriteRune(rune(u))
+		}
+	}
+	return &DecodedText{Text: sb.String(), Encoding: enc, HasBOM: bom}, nil
+}
+
+// sniffUTF16 recognizes BOM-less UTF-16 holding mostly ASCII/Latin text: one byte of nearly every
+// code unit is zero, always on the same side.
+func sniffUTF16(data []byte) (TextEncoding, bool) {
+	if len(data) < 4 || len(data)%2 != 0 {
+		return "", false
+	}
+	var zeroEven, zeroOdd int
+	for i := 0; i < len(data); i += 2 {
+		if data[i] == 0 {
+			zeroEven++
+		}
+		if data[i+1] == 0 {
+			zeroOdd++
+		}
+	}
+	units := len(data) / 2
+	switch {
+	case zeroOdd*10 >= units*7 && zeroEven*20 <= units:
+		return TextEncodingUTF16LE, true
+	case zeroEven*10 >= units*7 && zeroOdd*20 <= units:
+		return TextEncodingUTF16BE, true
+	}
+	return "", false
+}
+
+// looksBinary reports NUL bytes or C0 control characters that do not occur in text.
+func looksBinary(data []byte) bool {
+	for _, b := range data {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\v' && b != 0x1b {
+			return true
+		}
+	}
+	return false
+}
+
+func hasC1Bytes(data []byte) bool {
+	for _, b := range data {
+		if b >= 0x80 && b <= 0x9f {
+			return true
+		}
+	}
+	return false
+}
+
+// looksShiftJIS reports whether all non-ASCII bytes form valid Shift_JIS sequences and at least a
+// third of the double-byte characters are kana or Japanese punctuation (lead bytes 0x81-0x83), as in
+// ordinary Japanese prose. Windows-1252 text rarely uses those bytes (‚ ƒ), so it fails the second test
+// even when its accented letters and smart quotes happen to pair up.
+func looksShiftJIS(data []byte) bool {
+	pairs, kana := 0, 0
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case b < 0x80:
+		case b >= 0xa1 && b <= 0xdf: // half-width katakana
+		case isShiftJISLead(b):
+			if i+1 >= len(data) {
+				return false
+			}
+			t := data[i+1]
+			if t < 0x40 || t == 0x7f || t > 0xfc {
+				return false
+			}
+			pairs++
+			if b <= 0x83 {
+				kana++
+			}
+			i++
+		default:
+			return false
+		}
+	}
+	return pairs > 0 && kana*3 >= pairs
+}
+
+// isShiftJISLead reports whether b starts a double-byte Shift_JIS character.
+func isShiftJISLead(b byte) bool {
+	return b >= 0x81 && b <= 0x9f || b >= 0xe0 && b <= 0xfc
+}
+
+// trimIncompleteShiftJISTail drops a trailing lead byte whose second byte is missing. b must start on
+// a character boundary.
+func trimIncompleteShiftJISTail(b []byte) []byte {
+	for i := 0; i < len(b); i++ {
+		if isShiftJISLead(b[i]) {
+			if i+1 == len(b) {
+				return b[:i]
+			}
+			i++
+		}
+	}
+	return b
+}
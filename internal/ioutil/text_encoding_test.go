@@ -0,0 +1,97 @@
+package ioutil
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	sjis := []byte("\x93\xfa\x96{\x8c\xea\x82\xcc\x83e\x83L\x83X\x83g\x82\xc5\x82\xb7\x81B")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantEnc TextEncoding
+		wantBOM bool
+		wantErr error
+	}{
+		{name: "utf8", data: []byte("héllo\n"), want: "héllo\n", wantEnc: TextEncodingUTF8},
+		{name: "empty", data: nil, want: "", wantEnc: TextEncodingUTF8},
+		{
+			name:    "utf8_bom_stripped",
+			data:    []byte("\xef\xbb\xbfa\r\nb"),
+			want:    "a\r\nb",
+			wantEnc: TextEncodingUTF8,
+			wantBOM: true,
+		},
+		{
+			name:    "utf16le_bom",
+			data:    []byte("\xff\xfeh\x00i\x00\x3d\xd8\x00\xde"),
+			want:    "hi😀",
+			wantEnc: TextEncodingUTF16LE,
+			wantBOM: true,
+		},
+		{name: "utf16be_bom", data: []byte("\xfe\xff\x00h\x00\xe9"), want: "hé", wantEnc: TextEncodingUTF16BE, wantBOM: true},
+		{name: "utf16le_without_bom", data: []byte("a\x00b\x00\n\x00c\x00"), want: "ab\nc", wantEnc: TextEncodingUTF16LE},
+		{name: "windows1252", data: []byte("\x93Smart\x94 caf\xe9 \x96 ok"), want: "“Smart” café – ok", wantEnc: TextEncodingWindows1252},
+		{name: "windows1252_short", data: []byte("\x93caf\xe9\x94"), want: "“café”", wantEnc: TextEncodingWindows1252},
+		{name: "latin1", data: []byte("caf\xe9 cr\xe8me br\xfbl\xe9e"), want: "café crème brûlée", wantEnc: TextEncodingLatin1},
+		{name: "shift_jis", data: sjis, want: "日本語のテキストです。", wantEnc: TextEncodingShiftJIS},
+		{name: "binary_rejected", data: []byte("\x00\x01\x02\xff\xfa"), wantErr: ErrNotUTF8Text},
+		{name: "odd_utf16_rejected", data: []byte{0xff, 0xfe, 0xfd}, wantErr: ErrNotUTF8Text},
+		{name: "unpaired_surrogate_rejected", data: []byte("\xff\xfe\x3d\xd8a\x00"), wantErr: ErrNotUTF8Text},
+		{name: "bad_utf8_after_bom_rejected", data: []byte("\xef\xbb\xbf\xff"), wantErr: ErrNotUTF8Text},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := DecodeText(tc.data)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err=%v want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Text != tc.want || got.Encoding != tc.wantEnc || got.HasBOM != tc.wantBOM {
+				t.Fatalf("got %+v, want text=%q enc=%q bom=%v", got, tc.want, tc.wantEnc, tc.wantBOM)
+			}
+
+			// Encoding the decoded text must reproduce the input.
+			back, err := EncodeText(got.Text, got.Encoding, got.HasBOM)
+			if err != nil {
+				t.Fatalf("EncodeText: %v", err)
+			}
+			if !bytes.Equal(back, tc.data) {
+				t.Fatalf("round trip = %q, want %q", back, tc.data)
+			}
+		})
+	}
+}
+
+func TestEncodeText_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		enc     TextEncoding
+		wantErr error
+	}{
+		{name: "euro_not_in_latin1", text: "5 €", enc: TextEncodingLatin1, wantErr: ErrUnencodableText},
+		{name: "kanji_not_in_windows1252", text: "日本", enc: TextEncodingWindows1252, wantErr: ErrUnencodableText},
+		{name: "unknown_encoding", text: "x", enc: "ebcdic"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := EncodeText(tc.text, tc.enc, false)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("err=%v want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package ioutil
+
+import (
+	"archive/zip"
+	"io"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// OpenZip opens the zip archive at path on fsys. The returned closer releases the underlying file.
+//
+// NOTE: This is a raw IO helper; callers should resolve/enforce policy before calling.
+func OpenZip(fsys vfs.FS, path string) (*zip.Reader, io.Closer, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	zr, err := zip.NewReader(f, st.Size())
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return zr, f, nil
+}
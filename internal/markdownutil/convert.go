@@ -0,0 +1,125 @@
+// Package markdownutil converts markup documents (HTML, EPUB and RTF) into readable markdown.
+// Conversion is offline and pure Go: scripts, styles and other non-content elements are dropped;
+// headings, lists, links, emphasis, code and tables become their markdown equivalents.
+package markdownutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// maxInputBytes bounds the size of any single markup input (a whole HTML/RTF file or one EPUB part),
+// so a small, highly compressed EPUB cannot make the converter consume unbounded input.
+const maxInputBytes = 64 * 1024 * 1024
+
+var errInputTooLarge = errors.New("document too large")
+
+// IsMarkdownExt reports whether ext (e.g. ".html", case-insensitive) has a markdown converter.
+func IsMarkdownExt(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".html", ".htm", ".xhtml", ".epub", ".rtf":
+		return true
+	}
+	return false
+}
+
+// ConvertToMarkdownSafe converts a local HTML, EPUB or RTF file to markdown with a byte limit and
+// panic recovery. The format is chosen by the file extension (see IsMarkdownExt). Output beyond
+// maxBytes is truncated.
+//
+//   - html/htm/xhtml: the document body; script, style, template and similar elements are dropped.
+//   - epub: the content documents in spine (reading) order.
+//   - rtf: plain text with paragraph breaks; font, color, style tables, pictures and other ignorable
+//     destinations are skipped.
+func ConvertToMarkdownSafe(ctx context.Context, fsys vfs.FS, path string, maxBytes int) (string, error) {
+	return toolutil.WithRecoveryResp(func() (string, error) {
+		return convertToMarkdown(ctx, fsys, path, maxBytes)
+	})
+}
+
+func convertToMarkdown(ctx context.Context, fsys vfs.FS, p string, maxBytes int) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	var (
+		text string
+		err  error
+	)
+	switch ext := strings.ToLower(filepath.Ext(p)); ext {
+	case ".html", ".htm", ".xhtml":
+		var data []byte
+		if data, err = readCapped(fsys, p); err == nil {
+			text, err = htmlToMarkdown(ctx, bytes.NewReader(data))
+		}
+	case ".rtf":
+		var data []byte
+		if data, err = readCapped(fsys, p); err == nil {
+			text, err = rtfToText(ctx, data)
+		}
+	case ".epub":
+		text, err = epubToMarkdown(ctx, fsys, p, maxBytes)
+	default:
+		return "", fmt.Errorf("unsupported markup document type %q", ext)
+	}
+	if err != nil {
+		return "", err
+	}
+	text = truncateUTF8(strings.TrimSpace(text), maxBytes)
+	if text == "" {
+		return "", errors.New("empty document text after conversion")
+	}
+	return text, nil
+}
+
+func readCapped(fsys vfs.FS, p string) ([]byte, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readAllCapped(f, maxInputBytes)
+}
+
+func readAllCapped(r io.Reader, limit int) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", errInputTooLarge, limit)
+	}
+	return data, nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:max(n, 0)]
+	for s != "" && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+var excessBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// tidyLines trims trailing whitespace from each line and collapses runs of blank lines.
+func tidyLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	return excessBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
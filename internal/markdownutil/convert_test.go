@@ -0,0 +1,192 @@
+package markdownutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func writeFile(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return p
+}
+
+func writeZip(t *testing.T, dir, name string, parts map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for n, body := range parts {
+		w, err := zw.Create(n)
+		if err != nil {
+			t.Fatalf("zip Create: %v", err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("zip Write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip Close: %v", err)
+	}
+	return writeFile(t, dir, name, buf.String())
+}
+
+func TestConvertToMarkdownSafe(t *testing.T) {
+	dir := t.TempDir()
+
+	page := writeFile(t, dir, "page.html", `<!DOCTYPE html>
+<html><head><title>T</title><style>body{color:red}</style><script>alert(1)</script></head>
+<body>
+<nav hidden>menu</nav>
+<h1>Main   title</h1>
+<p>Some <b>bold</b> and <em>italic</em> text with a
+<a href="https://example.com/x">link</a>, <a href="#top">anchor</a> and <code>x := 1</code>.<br>Next line.</p>
+<ul><li>one</li><li>two<ol start="3"><li>nested</li></ol></li></ul>
+<table><thead><tr><th>Name</th><th>Qty</th></tr></thead>
+<tbody><tr><td>a|b</td><td>2</td></tr><tr><td>c</td></tr></tbody></table>
+<blockquote><p>quoted</p></blockquote>
+<pre>line 1
+  line 2</pre>
+<div>loose <img alt="logo" src="l.png"> text</div>
+<hr>
+</body></html>`)
+
+	rtf := writeFile(t, dir, "doc.rtf", `{\rtf1\ansi\deff0{\fonttbl{\f0 Times;}}{\colortbl;\red0\green0\blue0;}
+{\*\generator Writer;}{\info{\title Hidden}}
+\pard\b Heading\b0\par
+Caf\'e9 \u8364? costs \{5\}\tab done\line next\par
+{\field{\*\fldinst HYPERLINK "x"}{\fldrslt shown}}\par
+}`)
+
+	epub := writeZip(t, dir, "book.epub", map[string]string{
+		"mimetype": "application/epub+zip",
+		"META-INF/container.xml": `<?xml version="1.0"?><container xmlns="urn:oasis:names:tc:opendocument:xmlns:container">` +
+			`<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`,
+		"OEBPS/content.opf": `<?xml version="1.0"?><package xmlns="http://www.idpf.org/2007/opf"><manifest>` +
+			`<item id="c1" href="text/ch%201.xhtml" media-type="application/xhtml+xml"/>` +
+			`<item id="c2" href="text/ch2.xhtml" media-type="application/xhtml+xml"/>` +
+			`<item id="c2again" href="text/ch2.xhtml#end" media-type="application/xhtml+xml"/>` +
+			`<item id="css" href="style.css" media-type="text/css"/></manifest>` +
+			`<spine><itemref idref="c2"/><itemref idref="css"/><itemref idref="c1"/>` +
+			`<itemref idref="c2"/><itemref idref="c2again"/></spine></package>`,
+		"OEBPS/text/ch 1.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><body><h2>Chapter One</h2><p>First.</p></body></html>`,
+		"OEBPS/text/ch2.xhtml":  `<html xmlns="http://www.w3.org/1999/xhtml"><body><h1>Prologue</h1><p>Opening.</p></body></html>`,
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		maxBytes int
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "html",
+			path:     page,
+			maxBytes: 1 << 20,
+			want: "# Main title\n\n" +
+				"Some **bold** and *italic* text with a [link](https://example.com/x), anchor and `x := 1`.\nNext line.\n\n" +
+				"- one\n- two\n  3. nested\n\n" +
+				"| Name | Qty |\n| --- | --- |\n| a\\|b | 2 |\n| c |  |\n\n" +
+				"> quoted\n\n" +
+				"```\nline 1\n  line 2\n```\n\n" +
+				"loose [image: logo] text\n\n---",
+		},
+		{
+			name:     "rtf",
+			path:     rtf,
+			maxBytes: 1 << 20,
+			want:     "Heading\nCafé € costs {5}\tdone\nnext\nshown",
+		},
+		{
+			name:     "epub_spine_order",
+			path:     epub,
+			maxBytes: 1 << 20,
+			want:     "# Prologue\n\nOpening.\n\n## Chapter One\n\nFirst.",
+		},
+		{name: "byte_limit_truncates", path: epub, maxBytes: 10, want: "# Prologue"},
+		{name: "not_rtf", path: writeFile(t, dir, "bad.rtf", "plain"), maxBytes: 1 << 20, wantErr: "not an RTF document"},
+		{
+			name:     "epub_missing_container",
+			path:     writeZip(t, dir, "bad.epub", map[string]string{"a.txt": "x"}),
+			maxBytes: 1 << 20,
+			wantErr:  "missing META-INF/container.xml",
+		},
+		{name: "not_a_zip_epub", path: writeFile(t, dir, "plain.epub", "x"), maxBytes: 1 << 20, wantErr: "not a valid EPUB"},
+		{
+			name:     "empty_html",
+			path:     writeFile(t, dir, "empty.html", "<html><script>x</script></html>"),
+			maxBytes: 1 << 20,
+			wantErr:  "empty document text",
+		},
+		{name: "unsupported_ext", path: writeFile(t, dir, "x.txt", "x"), maxBytes: 1 << 20, wantErr: "unsupported markup"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ConvertToMarkdownSafe(t.Context(), vfs.OS(), tc.path, tc.maxBytes)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got:\n%q\nwant:\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConvertToMarkdownSafe_Canceled(t *testing.T) {
+	p := writeFile(t, t.TempDir(), "a.html", "<p>x</p>")
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := ConvertToMarkdownSafe(ctx, vfs.OS(), p, 1<<20); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSpineToMarkdown_Budget(t *testing.T) {
+	t.Parallel()
+	one := `<p>One.</p>`
+	two := `<p>Two.</p>`
+	p := writeZip(t, t.TempDir(), "b.epub", map[string]string{"1.xhtml": one, "2.xhtml": two})
+	zr, closer, err := ioutil.OpenZip(vfs.OS(), p)
+	if err != nil {
+		t.Fatalf("OpenZip: %v", err)
+	}
+	defer closer.Close()
+
+	tests := []struct {
+		name    string
+		budget  int
+		want    string
+		wantErr error
+	}{
+		{name: "fits", budget: len(one) + len(two), want: "One.\n\nTwo."},
+		{name: "stops_at_budget", budget: len(one) + len(two) - 1, want: "One."},
+		{name: "first_part_too_large", budget: len(one) - 1, wantErr: errInputTooLarge},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := spineToMarkdown(t.Context(), zr, []string{"1.xhtml", "2.xhtml"}, 1<<20, tc.budget)
+			if !errors.Is(err, tc.wantErr) || got != tc.want {
+				t.Fatalf("got %q, %v; want %q, %v", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
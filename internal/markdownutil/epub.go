@@ -0,0 +1,172 @@
+package markdownutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// maxEPUBBytes bounds the decompressed bytes read across all spine documents of one EPUB, on top of the
+// per-document maxInputBytes.
+const maxEPUBBytes = 4 * maxInputBytes
+
+// epubToMarkdown converts the content documents of an EPUB, in spine order, stopping once maxBytes
+// of output have been produced or maxEPUBBytes of documents have been read.
+func epubToMarkdown(ctx context.Context, fsys vfs.FS, p string, maxBytes int) (string, error) {
+	zr, closer, err := ioutil.OpenZip(fsys, p)
+	if err != nil {
+		return "", fmt.Errorf("not a valid EPUB: %w", err)
+	}
+	defer closer.Close()
+
+	opfPath, err := epubRootfile(zr)
+	if err != nil {
+		return "", err
+	}
+	parts, err := epubSpine(zr, opfPath)
+	if err != nil {
+		return "", err
+	}
+	return spineToMarkdown(ctx, zr, parts, maxBytes, maxEPUBBytes)
+}
+
+// spineToMarkdown converts parts in order until maxBytes of output have been produced. At most budget
+// decompressed bytes are read in total; a part that does not fit in what is left ends the output, or
+// fails the conversion when nothing has been converted yet.
+func spineToMarkdown(ctx context.Context, zr *zip.Reader, parts []string, maxBytes, budget int) (string, error) {
+	var out []string
+	size, left := 0, budget
+	for _, part := range parts {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		if size > maxBytes {
+			break
+		}
+		limit := min(maxInputBytes, left)
+		data, err := readZipPart(zr, part, limit)
+		if errors.Is(err, errInputTooLarge) && limit < maxInputBytes {
+			if len(out) > 0 {
+				break
+			}
+			return "", fmt.Errorf("%w: EPUB content exceeds %d bytes", errInputTooLarge, budget)
+		}
+		if err != nil {
+			return "", err
+		}
+		left -= len(data)
+		text, err := htmlToMarkdown(ctx, bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("convert %s: %w", part, err)
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			out = append(out, text)
+			size += len(text) + 2
+		}
+	}
+	return strings.Join(out, "\n\n"), nil
+}
+
+// epubRootfile returns the package document path named by META-INF/container.xml.
+func epubRootfile(zr *zip.Reader) (string, error) {
+	data, err := readZipPart(zr, "META-INF/container.xml", maxInputBytes)
+	if err != nil {
+		return "", err
+	}
+	var container struct {
+		Rootfiles []struct {
+			FullPath  string `xml:"full-path,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(data, &container); err != nil {
+		return "", fmt.Errorf("parse META-INF/container.xml: %w", err)
+	}
+	for _, rf := range container.Rootfiles {
+		if rf.FullPath != "" && (rf.MediaType == "" || rf.MediaType == "application/oebps-package+xml") {
+			return rf.FullPath, nil
+		}
+	}
+	return "", errors.New("not a valid EPUB: no package document in META-INF/container.xml")
+}
+
+// epubSpine returns the zip paths of the package's (X)HTML content documents in reading order. A document
+// referenced more than once is listed at its first position only.
+func epubSpine(zr *zip.Reader, opfPath string) ([]string, error) {
+	data, err := readZipPart(zr, opfPath, maxInputBytes)
+	if err != nil {
+		return nil, err
+	}
+	var pkg struct {
+		Items []struct {
+			ID        string `xml:"id,attr"`
+			Href      string `xml:"href,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"manifest>item"`
+		ItemRefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", opfPath, err)
+	}
+
+	type item struct{ href, mediaType string }
+	manifest := make(map[string]item, len(pkg.Items))
+	for _, it := range pkg.Items {
+		manifest[it.ID] = item{href: it.Href, mediaType: it.MediaType}
+	}
+	dir := path.Dir(opfPath)
+	var parts []string
+	seen := make(map[string]bool, len(pkg.ItemRefs))
+	for _, ref := range pkg.ItemRefs {
+		it, ok := manifest[ref.IDRef]
+		if !ok || (it.mediaType != "application/xhtml+xml" && it.mediaType != "text/html") {
+			continue
+		}
+		href, _, _ := strings.Cut(it.href, "#")
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		part := path.Join(dir, href)
+		if seen[part] {
+			continue
+		}
+		seen[part] = true
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("not a valid EPUB: spine has no content documents")
+	}
+	return parts, nil
+}
+
+// readZipPart reads the named entry, failing with errInputTooLarge past limit decompressed bytes.
+func readZipPart(zr *zip.Reader, name string, limit int) ([]byte, error) {
+	name = strings.TrimPrefix(name, "/")
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		data, err := readAllCapped(rc, limit)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("not a valid EPUB: missing %s", name)
+}
@@ -0,0 +1,380 @@
+package markdownutil
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxNestingDepth bounds recursion over the parsed tree; deeper content is rendered as plain text.
+const maxNestingDepth = 256
+
+var sourceBreaks = strings.NewReplacer("\r", " ", "\n", " ")
+
+// htmlToMarkdown renders the body of an HTML document as markdown.
+func htmlToMarkdown(ctx context.Context, r io.Reader) (string, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+	c := &htmlConverter{ctx: ctx}
+	start := root
+	if body := findElement(root, atom.Body); body != nil {
+		start = body
+	}
+	blocks := c.blocks(start, 0)
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return tidyLines(strings.Join(blocks, "\n\n")), nil
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if found := findElement(ch, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+type htmlConverter struct {
+	ctx context.Context
+}
+
+// skipped reports elements whose content is never readable text.
+func skipped(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Head, atom.Iframe, atom.Object,
+		atom.Embed, atom.Svg, atom.Math, atom.Canvas, atom.Select, atom.Button, atom.Input, atom.Textarea:
+		return true
+	}
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, a := range n.Attr {
+		if a.Key == "hidden" || a.Key == "aria-hidden" && strings.EqualFold(a.Val, "true") {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlock(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	switch n.DataAtom {
+	case atom.Address, atom.Article, atom.Aside, atom.Blockquote, atom.Body, atom.Dd, atom.Details,
+		atom.Div, atom.Dl, atom.Dt, atom.Fieldset, atom.Figcaption, atom.Figure, atom.Footer, atom.Form,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Header, atom.Hr, atom.Li, atom.Main,
+		atom.Nav, atom.Ol, atom.P, atom.Pre, atom.Section, atom.Summary, atom.Table, atom.Ul, atom.Html:
+		return true
+	}
+	return false
+}
+
+// blocks renders the children of n as a list of markdown blocks. Runs of inline content between
+// block children form paragraphs.
+func (c *htmlConverter) blocks(n *html.Node, depth int) []string {
+	var (
+		out  []string
+		para strings.Builder
+	)
+	flush := func() {
+		if text := cleanInline(para.String()); text != "" {
+			out = append(out, text)
+		}
+		para.Reset()
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		if c.ctx.Err() != nil {
+			break
+		}
+		if ch.Type == html.ElementNode && skipped(ch) || ch.Type == html.CommentNode {
+			continue
+		}
+		if !isBlock(ch) || depth >= maxNestingDepth {
+			c.inline(ch, &para, depth+1)
+			continue
+		}
+		flush()
+		if b := c.block(ch, depth+1); b != "" {
+			out = append(out, b)
+		}
+	}
+	flush()
+	return out
+}
+
+// block renders one block-level element.
+func (c *htmlConverter) block(n *html.Node, depth int) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := c.inlineText(n, depth)
+		if text == "" {
+			return ""
+		}
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + strings.ReplaceAll(text, "\n", " ")
+	case atom.Hr:
+		return "---"
+	case atom.Pre:
+		code := strings.Trim(textContent(n), "\n")
+		if strings.TrimSpace(code) == "" {
+			return ""
+		}
+		return "```\n" + code + "\n```"
+	case atom.Blockquote:
+		inner := strings.Join(c.blocks(n, depth), "\n\n")
+		if inner == "" {
+			return ""
+		}
+		return prefixLines(inner, "> ", "> ")
+	case atom.Ul, atom.Ol:
+		return c.list(n, depth)
+	case atom.Table:
+		return c.table(n, depth)
+	case atom.Dt:
+		if text := c.inlineText(n, depth); text != "" {
+			return "**" + text + "**"
+		}
+		return ""
+	case atom.Dd:
+		inner := strings.Join(c.blocks(n, depth), "\n")
+		if inner == "" {
+			return ""
+		}
+		return prefixLines(inner, ": ", "  ")
+	}
+	return strings.Join(c.blocks(n, depth), "\n\n")
+}
+
+func (c *htmlConverter) list(n *html.Node, depth int) string {
+	ordered := n.DataAtom == atom.Ol
+	num := 1
+	if s, err := strconv.Atoi(attrValue(n, "start")); ordered && err == nil {
+		num = s
+	}
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || skipped(li) {
+			continue
+		}
+		var inner string
+		if li.DataAtom == atom.Li {
+			inner = strings.Join(c.blocks(li, depth+1), "\n")
+		} else {
+			// Malformed markup: a non-li child of a list is rendered as its own item.
+			inner = c.block(li, depth+1)
+		}
+		if inner == "" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+		items = append(items, prefixLines(inner, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (c *htmlConverter) table(n *html.Node, depth int) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(p *html.Node) {
+		for ch := p.FirstChild; ch != nil; ch = ch.NextSibling {
+			if ch.Type != html.ElementNode {
+				continue
+			}
+			switch ch.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(ch)
+			case atom.Tr:
+				var row []string
+				for cell := ch.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						text := strings.Join(c.blocks(cell, depth+1), " ")
+						text = strings.ReplaceAll(strings.ReplaceAll(text, "\n", " "), "|", `\|`)
+						row = append(row, text)
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, len(r))
+	}
+	var sb strings.Builder
+	writeRow := func(r []string) {
+		sb.WriteString("|")
+		for i := range cols {
+			cell := ""
+			if i < len(r) {
+				cell = r[i]
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+	}
+	writeRow(rows[0])
+	sb.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+	for _, r := range rows[1:] {
+		writeRow(r)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// inlineText renders the inline content of n as a single cleaned string.
+func (c *htmlConverter) inlineText(n *html.Node, depth int) string {
+	var sb strings.Builder
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		c.inline(ch, &sb, depth+1)
+	}
+	return cleanInline(sb.String())
+}
+
+// inline renders n in inline context. Whitespace is left raw and normalized by cleanInline; explicit
+// line breaks are written as "\n".
+func (c *htmlConverter) inline(n *html.Node, sb *strings.Builder, depth int) {
+	switch n.Type {
+	case html.TextNode:
+		// Source line breaks are ordinary whitespace; only <br> and block boundaries break lines.
+		sb.WriteString(sourceBreaks.Replace(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	if skipped(n) {
+		return
+	}
+	switch n.DataAtom {
+	case atom.Br:
+		sb.WriteString("\n")
+		return
+	case atom.Img:
+		if alt := strings.TrimSpace(attrValue(n, "alt")); alt != "" {
+			sb.WriteString("[image: " + alt + "]")
+		}
+		return
+	case atom.A:
+		text := c.inlineText(n, depth)
+		href := strings.TrimSpace(attrValue(n, "href"))
+		lower := strings.ToLower(href)
+		if text == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(lower, "javascript:") {
+			sb.WriteString(text)
+			return
+		}
+		sb.WriteString("[" + text + "](" + href + ")")
+		return
+	case atom.Strong, atom.B:
+		wrapInline(sb, c.inlineText(n, depth), "**")
+		return
+	case atom.Em, atom.I:
+		wrapInline(sb, c.inlineText(n, depth), "*")
+		return
+	case atom.Code, atom.Kbd, atom.Samp:
+		if code := strings.Join(strings.Fields(textContent(n)), " "); code != "" {
+			sb.WriteString("`" + code + "`")
+		}
+		return
+	case atom.Td, atom.Th:
+		sb.WriteString(" ")
+	}
+	if isBlock(n) {
+		// Block content met in inline context (e.g. deep nesting or a div inside a link) keeps
+		// its line structure.
+		sb.WriteString("\n")
+		defer sb.WriteString("\n")
+	}
+	if depth >= maxNestingDepth {
+		sb.WriteString(textContent(n))
+		return
+	}
+	for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+		c.inline(ch, sb, depth+1)
+	}
+}
+
+func wrapInline(sb *strings.Builder, text, marker string) {
+	if text == "" {
+		return
+	}
+	sb.WriteString(marker + text + marker)
+}
+
+// cleanInline collapses whitespace within each line and drops blank lines.
+func cleanInline(s string) string {
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	for _, l := range lines {
+		if l = strings.Join(strings.Fields(l), " "); l != "" {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// textContent returns the raw text of n and its descendants.
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(p *html.Node) {
+		if p.Type == html.TextNode {
+			sb.WriteString(p.Data)
+		}
+		if p.Type == html.ElementNode && p.DataAtom == atom.Br {
+			sb.WriteString("\n")
+		}
+		for ch := p.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func attrValue(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// prefixLines prefixes the first line of s with first and every later line with rest.
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		p := rest
+		if i == 0 {
+			p = first
+		}
+		if l == "" {
+			lines[i] = strings.TrimRight(p, " ")
+			continue
+		}
+		lines[i] = p + l
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,216 @@
+package markdownutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxRTFGroupDepth bounds brace nesting; deeper groups are treated as ignorable.
+const maxRTFGroupDepth = 1024
+
+// rtfSkippedDestinations are groups whose content is not document text.
+var rtfSkippedDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true, "object": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true, "footer": true, "footerl": true,
+	"footerr": true, "footerf": true, "listtable": true, "listoverridetable": true, "rsidtbl": true,
+	"generator": true, "filetbl": true, "revtbl": true, "themedata": true, "colorschememapping": true,
+	"latentstyles": true, "datastore": true, "xmlnstbl": true, "fldinst": true, "bkmkstart": true,
+	"bkmkend": true, "pgdsctbl": true, "mmathPr": true, "nonshppict": true, "userprops": true,
+}
+
+// rtfSymbols maps control words that stand for a character.
+var rtfSymbols = map[string]string{
+	"par": "\n", "line": "\n", "sect": "\n\n", "page": "\n\n", "tab": "\t", "cell": " | ", "row": "\n",
+	"emdash": "—", "endash": "–", "bullet": "•", "lquote": "‘", "rquote": "’",
+	"ldblquote": "“", "rdblquote": "”", "emspace": " ", "enspace": " ", "qmspace": " ",
+}
+
+// cp1252High maps bytes 0x80-0x9F of Windows-1252 (the default RTF code page); other bytes map to
+// the same Unicode code point (Latin-1).
+var cp1252High = [32]rune{
+	'€', 0xfffd, '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', 0xfffd, 'Ž', 0xfffd,
+	0xfffd, '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', 0xfffd, 'ž', 'Ÿ',
+}
+
+type rtfState struct {
+	skip bool // inside an ignorable destination
+	uc   int  // fallback characters to skip after \uN
+}
+
+// rtfToText extracts the plain text of an RTF document. Paragraph and line breaks become newlines,
+// table cells are separated by " | ", and \'hh escapes are decoded as Windows-1252.
+func rtfToText(ctx context.Context, data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(`{\rtf`)) {
+		return "", errors.New("not an RTF document")
+	}
+	var (
+		out       strings.Builder
+		state     = rtfState{uc: 1}
+		stack     []rtfState
+		skipChars int // pending \uN fallback characters
+		groupOpen bool
+	)
+	emit := func(s string) {
+		if state.skip {
+			return
+		}
+		if skipChars > 0 {
+			skipChars--
+			return
+		}
+		out.WriteString(s)
+	}
+
+	for i, steps := 0, 0; i < len(data); steps++ {
+		if steps%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+		}
+		ch := data[i]
+		switch ch {
+		case '{':
+			stack = append(stack, state)
+			if len(stack) > maxRTFGroupDepth {
+				state.skip = true
+			}
+			groupOpen = true
+			i++
+			continue
+		case '}':
+			if n := len(stack); n > 0 {
+				state, stack = stack[n-1], stack[:n-1]
+			}
+			skipChars = 0
+			groupOpen = false
+			i++
+			continue
+		case '\r', '\n':
+			i++
+			continue
+		case '\\':
+		default:
+			groupOpen = false
+			r := rune(ch)
+			if ch >= 0x80 && ch <= 0x9f {
+				r = cp1252High[ch-0x80]
+			}
+			emit(string(r))
+			i++
+			continue
+		}
+
+		// Control symbol or control word.
+		i++
+		if i >= len(data) {
+			break
+		}
+		first := groupOpen
+		groupOpen = false
+		c := data[i]
+		switch {
+		case c == '\\' || c == '{' || c == '}':
+			emit(string(c))
+			i++
+		case c == '~':
+			emit(" ")
+			i++
+		case c == '_':
+			emit("-")
+			i++
+		case c == '-':
+			i++ // optional hyphen
+		case c == '*':
+			state.skip = true // ignorable destination
+			i++
+		case c == '\r' || c == '\n':
+			emit("\n")
+			i++
+		case c == '\'':
+			if i+2 < len(data) {
+				if b, ok := hexByte(data[i+1], data[i+2]); ok {
+					r := rune(b)
+					if b >= 0x80 && b <= 0x9f {
+						r = cp1252High[b-0x80]
+					}
+					emit(string(r))
+				}
+			}
+			i += 3
+		case isASCIILetter(c):
+			start := i
+			for i < len(data) && isASCIILetter(data[i]) {
+				i++
+			}
+			word := string(data[start:i])
+			param, hasParam := 0, false
+			neg := i < len(data) && data[i] == '-'
+			if neg {
+				i++
+			}
+			for i < len(data) && data[i] >= '0' && data[i] <= '9' && param < 1<<24 {
+				param = param*10 + int(data[i]-'0')
+				hasParam = true
+				i++
+			}
+			if neg {
+				param = -param
+			}
+			if i < len(data) && data[i] == ' ' {
+				i++ // the delimiting space belongs to the control word
+			}
+			switch {
+			case word == "u" && hasParam:
+				if param < 0 {
+					param += 65536
+				}
+				r := rune(param)
+				if !utf8.ValidRune(r) {
+					r = utf8.RuneError
+				}
+				emit(string(r))
+				if !state.skip {
+					skipChars = state.uc
+				}
+			case word == "uc" && hasParam:
+				state.uc = max(param, 0)
+			case first && rtfSkippedDestinations[word]:
+				state.skip = true
+			default:
+				if s, ok := rtfSymbols[word]; ok {
+					emit(s)
+				}
+			}
+		default:
+			i++
+		}
+	}
+	return tidyLines(out.String()), nil
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func hexByte(a, b byte) (byte, bool) {
+	hi, ok1 := hexDigit(a)
+	lo, ok2 := hexDigit(b)
+	return hi<<4 | lo, ok1 && ok2
+}
+
+func hexDigit(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
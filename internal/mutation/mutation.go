@@ -0,0 +1,83 @@
+// Package mutation holds what the tool runners do around a call that changes files: charging the
+// write quota, locking the paths against concurrent read-modify-write cycles and snapshotting them
+// for checkpoints. fstool and texttool share it so their lock ordering and accounting stay the same.
+package mutation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/flexigpt/llmtools-go/checkpoint"
+	"github.com/flexigpt/llmtools-go/internal/filelock"
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/quota"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// Config is the mutation setup of a tool runner. The zero value locks in process only, waits for
+// locks until the context is done, and neither meters nor snapshots anything.
+type Config struct {
+	Locks       filelock.Config
+	Checkpoints *checkpoint.Manager
+	Quota       *quota.Quota
+}
+
+// DefaultConfig returns the Config tool runners start from.
+func DefaultConfig() Config {
+	return Config{Locks: filelock.Config{Timeout: filelock.DefaultTimeout}}
+}
+
+// SetOSLocks enables OS advisory locks with lock files in dir (the default lock dir if empty).
+func (c *Config) SetOSLocks(dir string) error {
+	if dir != "" && !filepath.IsAbs(dir) {
+		return fmt.Errorf("lock dir must be absolute: %q", dir)
+	}
+	c.Locks.OSLocks = true
+	c.Locks.Dir = dir
+	return nil
+}
+
+// Validate checks that the checkpoint manager, if any, works on fsys (nil meaning the OS filesystem).
+func (c Config) Validate(fsys vfs.FS) error {
+	if c.Checkpoints != nil && c.Checkpoints.FS() != vfs.OrOS(fsys) {
+		return errors.New("checkpoint manager must use the same filesystem as the tools")
+	}
+	return nil
+}
+
+// Metered returns p for a call that writes content: the call is counted against the rate limit of
+// the quota, and the returned policy charges the quota for what it writes.
+func (c Config) Metered(p fspolicy.FSPolicy) (fspolicy.FSPolicy, error) {
+	if err := c.Quota.Call(); err != nil {
+		return p, err
+	}
+	return p.WithQuota(c.Quota), nil
+}
+
+// Begin prepares the paths tool is about to change: it locks them against concurrent
+// read-modify-write cycles, then snapshots them for checkpoints. Paths that do not resolve are left
+// to the tool to reject. The returned function releases the locks.
+func (c Config) Begin(ctx context.Context, p fspolicy.FSPolicy, tool string, paths ...string) (func(), error) {
+	abs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if r, err := p.ResolvePath(path, ""); err == nil {
+			abs = append(abs, r)
+		}
+	}
+	if len(abs) == 0 {
+		return func() {}, nil
+	}
+	unlock, err := filelock.Lock(ctx, p.FS(), c.Locks, abs...)
+	if err != nil {
+		return nil, err
+	}
+	if c.Checkpoints != nil {
+		if err := c.Checkpoints.Snapshot(ctx, tool, abs...); err != nil {
+			unlock()
+			return nil, fmt.Errorf("checkpoint: %w", err)
+		}
+	}
+	return unlock, nil
+}
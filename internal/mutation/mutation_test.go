@@ -0,0 +1,79 @@
+package mutation
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/checkpoint"
+	"github.com/flexigpt/llmtools-go/internal/filelock"
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/quota"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestConfig_SetOSLocksAndValidate(t *testing.T) {
+	t.Parallel()
+
+	c := DefaultConfig()
+	if err := c.SetOSLocks("relative"); err == nil {
+		t.Fatal("expected error for a relative lock dir")
+	}
+	if c.Locks.OSLocks {
+		t.Fatal("OS locks enabled by a rejected dir")
+	}
+	dir := t.TempDir()
+	if err := c.SetOSLocks(dir); err != nil || !c.Locks.OSLocks || c.Locks.Dir != dir {
+		t.Fatalf("SetOSLocks = %v, locks = %+v", err, c.Locks)
+	}
+
+	m, err := checkpoint.New(filepath.Join(t.TempDir(), "cp"))
+	if err != nil {
+		t.Fatalf("checkpoint.New: %v", err)
+	}
+	c.Checkpoints = m
+	if err := c.Validate(nil); err != nil {
+		t.Fatalf("Validate(OS) = %v", err)
+	}
+	if err := c.Validate(vfs.NewMemFS()); err == nil {
+		t.Fatal("expected error for a checkpoint manager on another filesystem")
+	}
+}
+
+func TestConfig_MeteredAndBegin(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	p, err := fspolicy.New(dir, nil, false)
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+	q, err := quota.New(quota.Limits{MaxCallsPerMinute: 1})
+	if err != nil {
+		t.Fatalf("quota: %v", err)
+	}
+	c := Config{Locks: filelock.Config{Timeout: 20 * time.Millisecond}, Quota: q}
+
+	mp, err := c.Metered(p)
+	if err != nil || mp.Quota() != q {
+		t.Fatalf("Metered = %v, quota attached = %v", err, mp.Quota() == q)
+	}
+	if _, err := c.Metered(p); !errors.Is(err, quota.ErrExceeded) {
+		t.Fatalf("second Metered err = %v, want quota.ErrExceeded", err)
+	}
+
+	unlock, err := c.Begin(t.Context(), p, "test", "a.txt")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := c.Begin(t.Context(), p, "test", filepath.Join(dir, "a.txt")); !errors.Is(err, filelock.ErrTimeout) {
+		t.Fatalf("Begin on a held path err = %v, want filelock.ErrTimeout", err)
+	}
+	unlock()
+	unlock, err = c.Begin(t.Context(), p, "test", "a.txt")
+	if err != nil {
+		t.Fatalf("Begin after unlock: %v", err)
+	}
+	unlock()
+}
@@ -0,0 +1,154 @@
+package officeutil
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// docxPara is an open paragraph. Paragraphs nest when a run holds a text box.
+type docxPara struct {
+	text     strings.Builder
+	heading  int
+	listItem bool
+}
+
+// extractDOCX renders word/document.xml: paragraphs, headings (Heading1-9/Title styles), list items and
+// top-level tables. Nested tables are flattened into the enclosing cell; a text box paragraph is written
+// on its own, before the paragraph that anchors it.
+func (d *doc) extractDOCX() error {
+	var (
+		paras      []*docxPara
+		runDepth   int
+		inText     bool
+		tableDepth int
+		rows       [][]string
+		row        []string
+		cell       strings.Builder
+	)
+	write := func(s string) {
+		if len(paras) > 0 {
+			paras[len(paras)-1].text.WriteString(s)
+		}
+	}
+	return d.walkPart("word/document.xml", false, func(dec *xml.Decoder, tok xml.Token) error {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paras = append(paras, &docxPara{})
+			case "pStyle":
+				if len(paras) > 0 {
+					paras[len(paras)-1].heading = docxHeadingLevel(attr(t, "val"))
+				}
+			case "numPr":
+				if len(paras) > 0 {
+					paras[len(paras)-1].listItem = true
+				}
+			case "r":
+				runDepth++
+			case "t":
+				inText = true
+			case "tab":
+				// Only a run's tab character; w:tab in w:pPr/w:tabs is a tab stop definition.
+				if runDepth > 0 {
+					write("\t")
+				}
+			case "br", "cr":
+				write("\n")
+			case "delText", "instrText":
+				return dec.Skip()
+			case "Fallback":
+				// mc:Fallback repeats the mc:Choice content (e.g. a text box) for older readers.
+				return dec.Skip()
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					rows = nil
+				}
+			case "tr":
+				if tableDepth == 1 {
+					row = nil
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell.Reset()
+				}
+			}
+		case xml.CharData:
+			if inText {
+				write(string(t))
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "r":
+				runDepth = max(runDepth-1, 0)
+			case "t":
+				inText = false
+			case "p":
+				if len(paras) == 0 {
+					return nil
+				}
+				para := paras[len(paras)-1]
+				paras = paras[:len(paras)-1]
+				text := strings.TrimSpace(para.text.String())
+				if text == "" {
+					return nil
+				}
+				if tableDepth > 0 {
+					if cell.Len() > 0 {
+						cell.WriteString(" ")
+					}
+					cell.WriteString(text)
+					return nil
+				}
+				return d.w.write(formatParagraph(text, para.heading, para.listItem))
+			case "tc":
+				if tableDepth == 1 {
+					row = append(row, cell.String())
+				}
+			case "tr":
+				if tableDepth == 1 {
+					if r := trimRow(row); r != nil {
+						rows = append(rows, r)
+					}
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 {
+					if err := d.w.writeTable(rows); err != nil {
+						return err
+					}
+					return d.w.write("\n")
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// docxHeadingLevel maps a paragraph style ID to a heading level (0 = not a heading).
+func docxHeadingLevel(style string) int {
+	s := strings.ToLower(style)
+	if s == "title" {
+		return 1
+	}
+	if rest, ok := strings.CutPrefix(s, "heading"); ok {
+		if n, err := strconv.Atoi(rest); err == nil && n >= 1 && n <= 6 {
+			return n
+		}
+	}
+	return 0
+}
+
+// formatParagraph renders one paragraph line. Headings get a blank line before them.
+func formatParagraph(text string, heading int, listItem bool) string {
+	switch {
+	case heading > 0:
+		return "\n" + strings.Repeat("#", heading) + " " + text + "\n"
+	case listItem:
+		return "- " + text + "\n"
+	default:
+		return text + "\n"
+	}
+}
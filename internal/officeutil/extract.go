@@ -0,0 +1,288 @@
+// Package officeutil extracts readable text from OOXML (docx, xlsx, pptx) and ODF (odt, ods) documents.
+// Both formats are zip containers of XML parts; parts are streamed through encoding/xml, so there are
+// no third-party dependencies.
+package officeutil
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// maxPartBytes bounds the uncompressed size of any single XML part read from the container, so a
+// small, highly compressed document cannot make the parser consume unbounded input.
+const maxPartBytes = 64 * 1024 * 1024
+
+// maxColumns bounds the columns materialized for one table row (spreadsheets may declare up to 16384).
+const maxColumns = 256
+
+var (
+	errOutputFull   = errors.New("output limit reached")
+	errPartTooLarge = errors.New("document part too large")
+)
+
+// IsOfficeExt reports whether ext (e.g. ".docx", case-insensitive) has an extractor.
+func IsOfficeExt(ext string) bool {
+	switch strings.ToLower(ext) {
+	case ".docx", ".xlsx", ".pptx", ".odt", ".ods":
+		return true
+	}
+	return false
+}
+
+// ExtractOfficeTextSafe extracts text from a local office document with a byte limit and panic recovery.
+// The format is chosen by the file extension (see IsOfficeExt). Output beyond maxBytes is truncated.
+//
+//   - docx/odt: one line per paragraph; headings become "#"-prefixed lines, list items "- ", tables markdown.
+//   - xlsx/ods: one "## <sheet>" section per sheet with its used cells as a markdown table.
+//   - pptx: one "## Slide N" section per slide with its text.
+func ExtractOfficeTextSafe(ctx context.Context, fsys vfs.FS, path string, maxBytes int) (string, error) {
+	return toolutil.WithRecoveryResp(func() (string, error) {
+		return extractOfficeTextSafe(ctx, fsys, path, maxBytes)
+	})
+}
+
+func extractOfficeTextSafe(ctx context.Context, fsys vfs.FS, p string, maxBytes int) (string, error) {
+	zr, closer, err := ioutil.OpenZip(fsys, p)
+	if err != nil {
+		return "", fmt.Errorf("not a valid office document: %w", err)
+	}
+	defer closer.Close()
+
+	d := &doc{ctx: ctx, zr: zr, w: &textWriter{max: maxBytes}}
+	switch ext := strings.ToLower(filepath.Ext(p)); ext {
+	case ".docx":
+		err = d.extractDOCX()
+	case ".xlsx":
+		err = d.extractXLSX()
+	case ".pptx":
+		err = d.extractPPTX()
+	case ".odt":
+		err = d.extractODF(false)
+	case ".ods":
+		err = d.extractODF(true)
+	default:
+		return "", fmt.Errorf("unsupported office document type %q", ext)
+	}
+	if err != nil && !errors.Is(err, errOutputFull) {
+		return "", err
+	}
+	text := strings.TrimSpace(d.w.b.String())
+	if text == "" {
+		return "", errors.New("empty document text after extraction")
+	}
+	return text, nil
+}
+
+// doc is a zip container being extracted into w.
+type doc struct {
+	ctx context.Context
+	zr  *zip.Reader
+	w   *textWriter
+}
+
+func (d *doc) find(name string) *zip.File {
+	name = strings.TrimPrefix(name, "/")
+	for _, f := range d.zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// walkPart streams the XML part name through fn, one token at a time. fn may call dec.Skip.
+// A missing part is an error unless optional is set.
+func (d *doc) walkPart(name string, optional bool, fn func(dec *xml.Decoder, tok xml.Token) error) error {
+	f := d.find(name)
+	if f == nil {
+		if optional {
+			return nil
+		}
+		return fmt.Errorf("not a valid office document: missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	dec := xml.NewDecoder(&cappedReader{r: rc, n: maxPartBytes})
+	dec.Strict = false
+	for {
+		if err := d.ctx.Err(); err != nil {
+			return err
+		}
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if errors.Is(err, errPartTooLarge) {
+				return fmt.Errorf("%w: %s exceeds %d bytes", errPartTooLarge, name, maxPartBytes)
+			}
+			return fmt.Errorf("parse %s: %w", name, err)
+		}
+		if err := fn(dec, tok); err != nil {
+			return err
+		}
+	}
+}
+
+// readRels maps relationship IDs to part names for the relationships part of source
+// (e.g. "xl/workbook.xml" -> "xl/_rels/workbook.xml.rels"). External targets are skipped.
+func (d *doc) readRels(source string) (map[string]string, error) {
+	dir := path.Dir(source)
+	relsName := path.Join(dir, "_rels", path.Base(source)+".rels")
+	rels := map[string]string{}
+	err := d.walkPart(relsName, true, func(_ *xml.Decoder, tok xml.Token) error {
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "Relationship" || attr(se, "TargetMode") == "External" {
+			return nil
+		}
+		target := attr(se, "Target")
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(dir, target)
+		}
+		rels[attr(se, "Id")] = target
+		return nil
+	})
+	return rels, err
+}
+
+// attr returns the value of the attribute with the given local name, ignoring namespaces.
+func attr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// relAttr returns the namespaced relationship attribute (r:id / r:embed) with the given local name.
+func relAttr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local && a.Name.Space != "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// textWriter accumulates output up to max bytes, cutting at a UTF-8 boundary.
+type textWriter struct {
+	b   strings.Builder
+	max int
+}
+
+func (w *textWriter) write(s string) error {
+	remaining := w.max - w.b.Len()
+	if len(s) > remaining {
+		s = s[:max(remaining, 0)]
+		for s != "" && !utf8.ValidString(s) {
+			s = s[:len(s)-1]
+		}
+		w.b.WriteString(s)
+		return errOutputFull
+	}
+	w.b.WriteString(s)
+	return nil
+}
+
+// writeBlock writes s as a block separated from previous output by a blank line.
+func (w *textWriter) writeBlock(s string) error {
+	if w.b.Len() > 0 {
+		if err := w.write("\n"); err != nil {
+			return err
+		}
+	}
+	return w.write(s)
+}
+
+// writeTable renders rows as a markdown table (first row as header). Ragged rows are padded.
+func (w *textWriter) writeTable(rows [][]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r))
+	}
+	if width == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	line := func(cells []string) {
+		sb.WriteString("|")
+		for i := range width {
+			c := ""
+			if i < len(cells) {
+				c = cells[i]
+			}
+			sb.WriteString(" ")
+			sb.WriteString(escapeCell(c))
+			sb.WriteString(" |")
+		}
+		sb.WriteString("\n")
+	}
+	line(rows[0])
+	sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	if err := w.writeBlock(sb.String()); err != nil {
+		return err
+	}
+	for _, r := range rows[1:] {
+		sb.Reset()
+		line(r)
+		if err := w.write(sb.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var cellEscaper = strings.NewReplacer("|", `\|`, "\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+func escapeCell(s string) string { return strings.TrimSpace(cellEscaper.Replace(s)) }
+
+// trimRow drops trailing empty cells; it returns nil for an all-empty row.
+func trimRow(r []string) []string {
+	n := len(r)
+	for n > 0 && strings.TrimSpace(r[n-1]) == "" {
+		n--
+	}
+	if n == 0 {
+		return nil
+	}
+	return r[:n]
+}
+
+type cappedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.n <= 0 {
+		return 0, errPartTooLarge
+	}
+	if int64(len(p)) > c.n {
+		p = p[:c.n]
+	}
+	n, err := c.r.Read(p)
+	c.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,206 @@
+package officeutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const (
+	wordNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+	relNS  = `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	odfNS  = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+		`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" ` +
+		`xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"`
+)
+
+func writeZipDoc(t *testing.T, dir, name string, parts map[string]string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for n, body := range parts {
+		w, err := zw.Create(n)
+		if err != nil {
+			t.Fatalf("zip Create: %v", err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("zip Write: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip Close: %v", err)
+	}
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return p
+}
+
+func rels(entries ...string) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 0; i+1 < len(entries); i += 2 {
+		sb.WriteString(`<Relationship Id="` + entries[i] + `" Target="` + entries[i+1] + `" Type="x"/>`)
+	}
+	sb.WriteString(`<Relationship Id="ext" Target="https://example.com" TargetMode="External" Type="x"/>`)
+	sb.WriteString(`</Relationships>`)
+	return sb.String()
+}
+
+func TestExtractOfficeTextSafe(t *testing.T) {
+	dir := t.TempDir()
+
+	docx := writeZipDoc(t, dir, "a.docx", map[string]string{
+		"word/document.xml": `<?xml version="1.0"?><w:document ` + wordNS + `><w:body>
+<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Report</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Hello </w:t></w:r><w:r><w:t>world</w:t></w:r><w:r><w:delText>gone</w:delText></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Details</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>item one</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Name</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Qty</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>a|b</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>2</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:pPr><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>After</w:t><w:tab/><w:t>tab</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Outer </w:t></w:r><w:r><mc:AlternateContent xmlns:mc="mc"><mc:Choice><w:drawing><w:txbxContent>` +
+			`<w:p><w:r><w:t>Boxed</w:t></w:r></w:p></w:txbxContent></w:drawing></mc:Choice><mc:Fallback><w:pict><w:txbxContent>` +
+			`<w:p><w:r><w:t>Boxed</w:t></w:r></w:p></w:txbxContent></w:pict></mc:Fallback></mc:AlternateContent></w:r>` +
+			`<w:r><w:t>text</w:t></w:r></w:p>
+</w:body></w:document>`,
+	})
+
+	xlsx := writeZipDoc(t, dir, "b.xlsx", map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0"?><workbook ` + relNS + `><sheets>` +
+			`<sheet name="Second" sheetId="2" r:id="rId2"/><sheet name="First" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": rels("rId1", "worksheets/sheet1.xml", "rId2", "/xl/worksheets/sheet2.xml"),
+		"xl/sharedStrings.xml": `<?xml version="1.0"?><sst><si><t>Name</t></si><si><r><t>Val</t></r><r><t>ue</t></r>` +
+			`<rPh><t>ignored</t></rPh></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<?xml version="1.0"?><worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+			`<row r="2"><c r="A2" t="inlineStr"><is><t>x</t></is></c><c r="B2" t="b"><v>1</v></c><c r="C2"><f>1+1</f><v>2</v></c></row>` +
+			`<row r="3"/></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<?xml version="1.0"?><worksheet><sheetData><row r="1"><c r="A1" t="str"><v>only</v></c></row></sheetData></worksheet>`,
+	})
+
+	pptx := writeZipDoc(t, dir, "c.pptx", map[string]string{
+		"ppt/presentation.xml": `<?xml version="1.0"?><p:presentation xmlns:p="p" ` + relNS + `><p:sldIdLst>` +
+			`<p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": rels("rId2", "slides/slide1.xml", "rId3", "slides/slide2.xml"),
+		"ppt/slides/slide1.xml":           `<?xml version="1.0"?><p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Second slide</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/slide2.xml": `<?xml version="1.0"?><p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Intro</a:t></a:r></a:p>` +
+			`<a:p><a:r><a:t>line</a:t></a:r><a:br/><a:r><a:t>two</a:t></a:r></a:p></p:sld>`,
+	})
+
+	odt := writeZipDoc(t, dir, "d.odt", map[string]string{
+		"content.xml": `<?xml version="1.0"?><office:document-content ` + odfNS + `><office:body><office:text>
+<text:h text:outline-level="2">Chapter</text:h>
+<text:p>Some<text:s text:c="2"/>text<office:annotation><text:p>comment</text:p></office:annotation></text:p>
+<text:list><text:list-item><text:p>bullet</text:p></text:list-item></text:list>
+<table:table table:name="T"><table:table-row><table:table-cell><text:p>h1</text:p></table:table-cell><table:table-cell><text:p>h2</text:p></table:table-cell></table:table-row>
+<table:table-row><table:table-cell table:number-columns-repeated="2"><text:p>v</text:p></table:table-cell></table:table-row></table:table>
+</office:text></office:body></office:document-content>`,
+	})
+
+	ods := writeZipDoc(t, dir, "e.ods", map[string]string{
+		"content.xml": `<?xml version="1.0"?><office:document-content ` + odfNS + `><office:body><office:spreadsheet>
+<table:table table:name="Data">
+<table:table-row><table:table-cell><text:p>a</text:p></table:table-cell><table:table-cell table:number-columns-repeated="3"/><table:table-cell><text:p>e</text:p></table:table-cell><table:table-cell table:number-columns-repeated="16379"/></table:table-row>
+<table:table-row table:number-rows-repeated="1048570"><table:table-cell table:number-columns-repeated="16384"/></table:table-row>
+<table:table-row table:number-rows-repeated="2"><table:table-cell><text:p>r</text:p></table:table-cell></table:table-row>
+</table:table></office:spreadsheet></office:body></office:document-content>`,
+	})
+
+	notZip := filepath.Join(dir, "bad.docx")
+	if err := os.WriteFile(notZip, []byte("plain"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missingPart := writeZipDoc(t, dir, "missing.xlsx", map[string]string{"other.xml": "<a/>"})
+	empty := writeZipDoc(t, dir, "empty.docx", map[string]string{
+		"word/document.xml": `<w:document ` + wordNS + `><w:body><w:p/></w:body></w:document>`,
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		maxBytes int
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "docx",
+			path:     docx,
+			maxBytes: 1 << 20,
+			want: "# Report\nHello world\n\n## Details\n- item one\n\n| Name | Qty |\n| --- | --- |\n" +
+				"| a\\|b | 2 |\n\nAfter\ttab\nBoxed\nOuter text",
+		},
+		{
+			name:     "xlsx_workbook_order_shared_and_inline_strings",
+			path:     xlsx,
+			maxBytes: 1 << 20,
+			want: "## Second\n\n| only |\n| --- |\n\n## First\n\n| Name |  | Value |\n| --- | --- | --- |\n" +
+				"| x | TRUE | 2 |",
+		},
+		{
+			name:     "pptx_presentation_order",
+			path:     pptx,
+			maxBytes: 1 << 20,
+			want:     "## Slide 1\nIntro\nline\ntwo\n\n## Slide 2\nSecond slide",
+		},
+		{
+			name:     "odt",
+			path:     odt,
+			maxBytes: 1 << 20,
+			want:     "## Chapter\nSome  text\n- bullet\n\n| h1 | h2 |\n| --- | --- |\n| v | v |",
+		},
+		{
+			name:     "ods_repeats_bounded",
+			path:     ods,
+			maxBytes: 1 << 20,
+			want:     "## Data\n\n| a |  |  |  | e |\n| --- | --- | --- | --- | --- |\n| r |  |  |  |  |\n| r |  |  |  |  |",
+		},
+		{
+			name:     "byte_limit_truncates",
+			path:     docx,
+			maxBytes: 10,
+			want:     "# Report",
+		},
+		{name: "not_a_zip", path: notZip, maxBytes: 1 << 20, wantErr: "not a valid office document"},
+		{name: "missing_part", path: missingPart, maxBytes: 1 << 20, wantErr: "missing xl/workbook.xml"},
+		{name: "empty_text", path: empty, maxBytes: 1 << 20, wantErr: "empty document text"},
+		{name: "unsupported_ext", path: writeZipDoc(t, dir, "x.doc", nil), maxBytes: 1 << 20, wantErr: "unsupported office document"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ExtractOfficeTextSafe(t.Context(), vfs.OS(), tc.path, tc.maxBytes)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got:\n%q\nwant:\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExtractOfficeTextSafe_Canceled(t *testing.T) {
+	dir := t.TempDir()
+	p := writeZipDoc(t, dir, "a.docx", map[string]string{
+		"word/document.xml": `<w:document ` + wordNS + `><w:body><w:p><w:r><w:t>x</w:t></w:r></w:p></w:body></w:document>`,
+	})
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := ExtractOfficeTextSafe(ctx, vfs.OS(), p, 1<<20); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
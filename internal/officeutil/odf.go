@@ -0,0 +1,149 @@
+package officeutil
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// maxRepeatedRows bounds how often a non-empty row with number-rows-repeated is materialized.
+const maxRepeatedRows = 100
+
+// extractODF renders content.xml of an ODF document. Text documents (odt) produce paragraphs,
+// headings, list items and markdown tables; spreadsheets (ods) produce one "## <name>" section with a
+// markdown table per sheet. Annotations and tracked deletions are skipped.
+func (d *doc) extractODF(spreadsheet bool) error {
+	var (
+		para       strings.Builder
+		paraDepth  int
+		heading    int
+		listDepth  int
+		listItem   bool
+		tableDepth int
+		tableName  string
+		rows       [][]string
+		row        []string
+		rowRepeat  int
+		pendingCol int // empty repeated cells not yet materialized
+		cell       strings.Builder
+		cellRepeat int
+	)
+	return d.walkPart("content.xml", false, func(dec *xml.Decoder, tok xml.Token) error {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "annotation", "tracked-changes", "note-citation":
+				return dec.Skip()
+			case "h", "p":
+				if paraDepth == 0 {
+					para.Reset()
+					heading, listItem = 0, listDepth > 0
+					if t.Name.Local == "h" {
+						heading = max(min(atoiDefault(attr(t, "outline-level"), 1), 6), 1)
+					}
+				} else {
+					para.WriteString(" ")
+				}
+				paraDepth++
+			case "s":
+				para.WriteString(strings.Repeat(" ", min(atoiDefault(attr(t, "c"), 1), 100)))
+			case "tab":
+				para.WriteString("\t")
+			case "line-break":
+				para.WriteString("\n")
+			case "list-item":
+				listDepth++
+			case "table":
+				tableDepth++
+				if tableDepth == 1 {
+					rows, tableName = nil, attr(t, "name")
+				}
+			case "table-row":
+				if tableDepth == 1 {
+					row, pendingCol = nil, 0
+					rowRepeat = atoiDefault(attr(t, "number-rows-repeated"), 1)
+				}
+			case "table-cell", "covered-table-cell":
+				if tableDepth == 1 {
+					cell.Reset()
+					cellRepeat = atoiDefault(attr(t, "number-columns-repeated"), 1)
+				}
+			}
+		case xml.CharData:
+			if paraDepth > 0 {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "h", "p":
+				paraDepth--
+				if paraDepth > 0 {
+					return nil
+				}
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					return nil
+				}
+				if tableDepth > 0 {
+					if cell.Len() > 0 {
+						cell.WriteString(" ")
+					}
+					cell.WriteString(text)
+					return nil
+				}
+				return d.w.write(formatParagraph(text, heading, listItem))
+			case "list-item":
+				listDepth--
+			case "table-cell", "covered-table-cell":
+				if tableDepth != 1 {
+					return nil
+				}
+				text := cell.String()
+				if text == "" {
+					pendingCol += cellRepeat
+					return nil
+				}
+				for range min(pendingCol, maxColumns-len(row)) {
+					row = append(row, "")
+				}
+				pendingCol = 0
+				for range min(cellRepeat, maxColumns-len(row)) {
+					row = append(row, text)
+				}
+			case "table-row":
+				if tableDepth != 1 {
+					return nil
+				}
+				if r := trimRow(row); r != nil {
+					for range min(rowRepeat, maxRepeatedRows) {
+						rows = append(rows, r)
+					}
+				}
+			case "table":
+				tableDepth--
+				if tableDepth > 0 {
+					return nil
+				}
+				if spreadsheet {
+					if err := d.w.writeBlock("## " + tableName + "\n"); err != nil {
+						return err
+					}
+					return d.w.writeTable(rows)
+				}
+				if err := d.w.writeTable(rows); err != nil {
+					return err
+				}
+				return d.w.write("\n")
+			}
+		}
+		return nil
+	})
+}
+
+func atoiDefault(s string, def int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,73 @@
+package officeutil
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// extractPPTX renders each slide, in presentation order, as "## Slide N" followed by one line per
+// text paragraph.
+func (d *doc) extractPPTX() error {
+	const presentation = "ppt/presentation.xml"
+	rels, err := d.readRels(presentation)
+	if err != nil {
+		return err
+	}
+	var slides []string
+	err = d.walkPart(presentation, false, func(_ *xml.Decoder, tok xml.Token) error {
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "sldId" {
+			if part, ok := rels[relAttr(se, "id")]; ok {
+				slides = append(slides, part)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, part := range slides {
+		if err := d.w.writeBlock(fmt.Sprintf("## Slide %d\n", i+1)); err != nil {
+			return err
+		}
+		if err := d.extractSlide(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *doc) extractSlide(part string) error {
+	var (
+		para   strings.Builder
+		inText bool
+	)
+	return d.walkPart(part, false, func(_ *xml.Decoder, tok xml.Token) error {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+			case "t":
+				inText = true
+			case "br":
+				para.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if text := strings.TrimSpace(para.String()); text != "" {
+					return d.w.write(text + "\n")
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,175 @@
+package officeutil
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+type xlsxSheet struct {
+	name string
+	part string
+}
+
+// extractXLSX renders each worksheet, in workbook order, as "## <name>" plus a markdown table of its
+// cell values (shared and inline strings resolved; numbers as stored, booleans as TRUE/FALSE).
+func (d *doc) extractXLSX() error {
+	const workbook = "xl/workbook.xml"
+	rels, err := d.readRels(workbook)
+	if err != nil {
+		return err
+	}
+	var sheets []xlsxSheet
+	err = d.walkPart(workbook, false, func(_ *xml.Decoder, tok xml.Token) error {
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "sheet" {
+			if part, ok := rels[relAttr(se, "id")]; ok {
+				sheets = append(sheets, xlsxSheet{name: attr(se, "name"), part: part})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	shared, err := d.readSharedStrings()
+	if err != nil {
+		return err
+	}
+	for _, s := range sheets {
+		rows, err := d.readXLSXSheet(s.part, shared)
+		if err != nil {
+			return err
+		}
+		if err := d.w.writeBlock("## " + s.name + "\n"); err != nil {
+			return err
+		}
+		if err := d.w.writeTable(rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *doc) readSharedStrings() ([]string, error) {
+	var (
+		out     []string
+		cur     strings.Builder
+		inText  bool
+		inSI    bool
+		skipRPh int
+	)
+	err := d.walkPart("xl/sharedStrings.xml", true, func(_ *xml.Decoder, tok xml.Token) error {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				inSI = true
+				cur.Reset()
+			case "rPh": // phonetic hints are not part of the displayed value
+				skipRPh++
+			case "t":
+				inText = inSI && skipRPh == 0
+			}
+		case xml.CharData:
+			if inText {
+				cur.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				inSI = false
+				out = append(out, cur.String())
+			case "rPh":
+				skipRPh--
+			case "t":
+				inText = false
+			}
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (d *doc) readXLSXSheet(part string, shared []string) ([][]string, error) {
+	var (
+		rows     [][]string
+		row      []string
+		col      int
+		cellType string
+		value    strings.Builder
+		inValue  bool
+	)
+	err := d.walkPart(part, false, func(_ *xml.Decoder, tok xml.Token) error {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row, col = nil, 0
+			case "c":
+				if c, ok := xlsxColumn(attr(t, "r")); ok {
+					col = c
+				}
+				cellType = attr(t, "t")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				if col < maxColumns {
+					for len(row) <= col {
+						row = append(row, "")
+					}
+					row[col] = xlsxCellValue(cellType, value.String(), shared)
+				}
+				col++
+			case "row":
+				if r := trimRow(row); r != nil {
+					rows = append(rows, r)
+				}
+			}
+		}
+		return nil
+	})
+	return rows, err
+}
+
+func xlsxCellValue(cellType, raw string, shared []string) string {
+	switch cellType {
+	case "s":
+		if i, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil && i >= 0 && i < len(shared) {
+			return shared[i]
+		}
+		return ""
+	case "b":
+		if strings.TrimSpace(raw) == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return raw
+}
+
+// xlsxColumn returns the 0-based column of a cell reference such as "B7" or "AA10".
+func xlsxColumn(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, c := range strings.ToUpper(ref) {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		n++
+	}
+	if n == 0 || n > 3 {
+		return 0, false
+	}
+	return col - 1, true
+}
@@ -8,18 +8,19 @@ import (
 	"strings"
 
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
 	"github.com/ledongthuc/pdf"
 )
 
 // ExtractPDFTextSafe extracts text from a local PDF with a byte limit and panic recovery.
-func ExtractPDFTextSafe(ctx context.Context, path string, maxBytes int) (string, error) {
+func ExtractPDFTextSafe(ctx context.Context, fsys vfs.FS, path string, maxBytes int) (string, error) {
 	return toolutil.WithRecoveryResp(func() (string, error) {
-		return extractPDFTextSafe(ctx, path, maxBytes)
+		return extractPDFTextSafe(ctx, fsys, path, maxBytes)
 	})
 }
 
-func extractPDFTextSafe(ctx context.Context, path string, maxBytes int) (text string, err error) {
-	f, r, err := pdf.Open(path)
+func extractPDFTextSafe(ctx context.Context, fsys vfs.FS, path string, maxBytes int) (text string, err error) {
+	f, r, err := openPDF(fsys, path)
 	if err != nil {
 		return "", err
 	}
@@ -44,3 +45,22 @@ func extractPDFTextSafe(ctx context.Context, path string, maxBytes int) (text st
 	}
 	return text, nil
 }
+
+// openPDF opens the PDF at path on fsys. The caller closes the returned file.
+func openPDF(fsys vfs.FS, path string) (vfs.File, *pdf.Reader, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	r, err := pdf.NewReader(f, st.Size())
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return f, r, nil
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
 func TestExtractPDFTextSafe_TableDriven(t *testing.T) {
@@ -89,7 +91,7 @@ func TestExtractPDFTextSafe_TableDriven(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := ExtractPDFTextSafe(ctx, tt.path, tt.maxBytes)
+			got, err := ExtractPDFTextSafe(ctx, vfs.OS(), tt.path, tt.maxBytes)
 
 			if tt.wantErr {
 				if err == nil {
@@ -129,7 +131,7 @@ func TestExtractPDFTextSafe_TableDriven(t *testing.T) {
 // 	pdfBytes, _ := base64.StdEncoding.DecodeString(b64)
 // 	path := writeTempFile(t, dir, "fixture.pdf", pdfBytes)
 //
-// 	_, err := ExtractPDFTextSafe(ctx, path, 1<<20)
+// 	_, err := ExtractPDFTextSafe(ctx, vfs.OS(), path, 1<<20)
 // 	if err == nil {
 // 		t.Fatalf("expected error with placeholder fixture")
 // 	}
@@ -0,0 +1,256 @@
+package pdfutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+	"github.com/ledongthuc/pdf"
+)
+
+// ErrPageOutOfRange indicates a requested page is outside the document.
+var ErrPageOutOfRange = errors.New("page out of range")
+
+const (
+	maxOutlineItems = 1000
+	maxOutlineDepth = 8
+)
+
+// PDFInfo is a PDF's page count and document information.
+type PDFInfo struct {
+	PageCount    int              `json:"pageCount"`
+	Title        string           `json:"title,omitempty"`
+	Author       string           `json:"author,omitempty"`
+	Subject      string           `json:"subject,omitempty"`
+	Keywords     string           `json:"keywords,omitempty"`
+	Creator      string           `json:"creator,omitempty"`
+	Producer     string           `json:"producer,omitempty"`
+	CreationDate *time.Time       `json:"creationDate,omitempty"`
+	ModDate      *time.Time       `json:"modDate,omitempty"`
+	Outline      []PDFOutlineItem `json:"outline,omitempty"` // bookmarks; capped at 1000 items, 8 levels
+}
+
+// PDFOutlineItem is one bookmark and its nested entries.
+type PDFOutlineItem struct {
+	Title    string           `json:"title"`
+	Children []PDFOutlineItem `json:"children,omitempty"`
+}
+
+// PageRange is an inclusive 1-based page range.
+type PageRange struct {
+	From int
+	To   int // 0 means "to the last page"
+}
+
+// PDFPages is the text of selected pages.
+type PDFPages struct {
+	Info       PDFInfo // page count and document information, from the same parse as Text
+	Text       string
+	TotalPages int
+	Pages      []int // pages included in Text, in order
+	Truncated  bool  // the byte limit stopped extraction early
+	NextPage   int   // when Truncated, the first page not (fully) included
+}
+
+// ParsePageRanges parses a comma-separated page list such as "1-3,7,10-" ("10-" means 10 to the end).
+func ParsePageRanges(spec string) ([]PageRange, error) {
+	var out []PageRange
+	for part := range strings.SplitSeq(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		a, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || a < 1 {
+			return nil, fmt.Errorf("invalid page range %q: pages are 1-based numbers like \"1-3,7,10-\"", part)
+		}
+		r := PageRange{From: a, To: a}
+		if isRange {
+			r.To = 0
+			if to = strings.TrimSpace(to); to != "" {
+				if r.To, err = strconv.Atoi(to); err != nil || r.To < a {
+					return nil, fmt.Errorf("invalid page range %q: end must be a number >= start", part)
+				}
+			}
+		}
+		out = append(out, r)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("empty page range")
+	}
+	return out, nil
+}
+
+// ReadPDFInfoSafe reads a PDF's page count, document info dictionary and outline, with panic recovery.
+func ReadPDFInfoSafe(ctx context.Context, fsys vfs.FS, path string) (*PDFInfo, error) {
+	return toolutil.WithRecoveryResp(func() (*PDFInfo, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		f, r, err := openPDF(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readPDFInfo(r), nil
+	})
+}
+
+func readPDFInfo(r *pdf.Reader) *PDFInfo {
+	info := r.Trailer().Key("Info")
+	out := &PDFInfo{
+		PageCount: r.NumPage(),
+		Title:     strings.TrimSpace(info.Key("Title").Text()),
+		Author:    strings.TrimSpace(info.Key("Author").Text()),
+		Subject:   strings.TrimSpace(info.Key("Subject").Text()),
+		Keywords:  strings.TrimSpace(info.Key("Keywords").Text()),
+		Creator:   strings.TrimSpace(info.Key("Creator").Text()),
+		Producer:  strings.TrimSpace(info.Key("Producer").Text()),
+	}
+	out.CreationDate = parsePDFDate(info.Key("CreationDate").Text())
+	out.ModDate = parsePDFDate(info.Key("ModDate").Text())
+
+	budget := maxOutlineItems
+	out.Outline = readOutline(r.Trailer().Key("Root").Key("Outlines").Key("First"), 1, &budget)
+	return out
+}
+
+// readOutline walks sibling outline entries starting at first. The shared budget also stops cyclic
+// First/Next chains in malformed files.
+func readOutline(first pdf.Value, depth int, budget *int) []PDFOutlineItem {
+	var items []PDFOutlineItem
+	for e := first; e.Kind() == pdf.Dict && *budget > 0; e = e.Key("Next") {
+		*budget--
+		item := PDFOutlineItem{Title: strings.TrimSpace(e.Key("Title").Text())}
+		if depth < maxOutlineDepth {
+			item.Children = readOutline(e.Key("First"), depth+1, budget)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// parsePDFDate parses a PDF date string ("D:YYYYMMDDHHmmSSOHH'mm'", all parts after the year optional).
+func parsePDFDate(s string) *time.Time {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	digits := 0
+	for digits < len(s) && digits < 14 && s[digits] >= '0' && s[digits] <= '9' {
+		digits++
+	}
+	if digits < 4 || digits%2 != 0 {
+		return nil
+	}
+	// Pad missing month/day with "01" and missing time fields with "00".
+	stamp := s[:digits] + "0101000000"[digits-4:]
+	t, err := time.Parse("20060102150405", stamp)
+	if err != nil {
+		return nil
+	}
+	loc := time.UTC
+	if tz := strings.ReplaceAll(s[digits:], "'", ""); len(tz) >= 3 && (tz[0] == '+' || tz[0] == '-') {
+		h, errH := strconv.Atoi(tz[1:3])
+		m := 0
+		if len(tz) >= 5 {
+			m, _ = strconv.Atoi(tz[3:5])
+		}
+		if errH == nil {
+			off := h*3600 + m*60
+			if tz[0] == '-' {
+				off = -off
+			}
+			loc = time.FixedZone("", off)
+		}
+	}
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc).UTC()
+	return &t
+}
+
+// ExtractPDFPagesSafe extracts the text of the given page ranges, each page preceded by a
+// "--- Page N of M ---" marker, up to maxBytes, with panic recovery. The document information is
+// returned too, so callers need not parse the file a second time with ReadPDFInfoSafe.
+// A range starting or ending past the last page fails with ErrPageOutOfRange (the message includes the
+// page count); open-ended ranges run to the last page.
+func ExtractPDFPagesSafe(ctx context.Context, fsys vfs.FS, path string, ranges []PageRange, maxBytes int) (*PDFPages, error) {
+	return toolutil.WithRecoveryResp(func() (*PDFPages, error) {
+		return extractPDFPages(ctx, fsys, path, ranges, maxBytes)
+	})
+}
+
+func extractPDFPages(ctx context.Context, fsys vfs.FS, path string, ranges []PageRange, maxBytes int) (*PDFPages, error) {
+	f, r, err := openPDF(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	total := r.NumPage()
+	var pages []int
+	for _, pr := range ranges {
+		if pr.From > total {
+			return nil, fmt.Errorf("%w: page %d requested but the document has %d pages", ErrPageOutOfRange, pr.From, total)
+		}
+		if pr.To > total {
+			return nil, fmt.Errorf(
+				"%w: pages %d-%d requested but the document has %d pages", ErrPageOutOfRange, pr.From, pr.To, total,
+			)
+		}
+		to := pr.To
+		if to == 0 {
+			to = total
+		}
+		for n := pr.From; n <= to; n++ {
+			pages = append(pages, n)
+		}
+	}
+
+	out := &PDFPages{Info: *readPDFInfo(r), TotalPages: total}
+	var b strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	for i, n := range pages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p := r.Page(n)
+		for _, name := range p.Fonts() { // cache fonts across pages, as pdf.Reader.GetPlainText does
+			if _, ok := fonts[name]; !ok {
+				f := p.Font(name)
+				fonts[name] = &f
+			}
+		}
+		text, err := p.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", n, err)
+		}
+		chunk := fmt.Sprintf("--- Page %d of %d ---\n%s\n", n, total, strings.TrimSpace(text))
+		if b.Len()+len(chunk) > maxBytes {
+			out.Truncated = true
+			out.NextPage = n
+			if i == 0 {
+				// A single oversized page: return its beginning rather than nothing.
+				chunk = chunk[:max(maxBytes, 0)]
+				for chunk != "" && !utf8.ValidString(chunk) {
+					chunk = chunk[:len(chunk)-1]
+				}
+				b.WriteString(chunk)
+				out.Pages = append(out.Pages, n)
+				if i+1 < len(pages) {
+					out.NextPage = pages[i+1]
+				} else {
+					out.NextPage = 0
+				}
+			}
+			break
+		}
+		b.WriteString(chunk)
+		out.Pages = append(out.Pages, n)
+	}
+	out.Text = strings.TrimSpace(b.String())
+	return out, nil
+}
@@ -0,0 +1,238 @@
+package pdfutil
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestParsePageRanges(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []PageRange
+		wantErr bool
+	}{
+		{spec: "3", want: []PageRange{{From: 3, To: 3}}},
+		{spec: " 1-3, 7 ,10-", want: []PageRange{{From: 1, To: 3}, {From: 7, To: 7}, {From: 10, To: 0}}},
+		{spec: "", wantErr: true},
+		{spec: "0", wantErr: true},
+		{spec: "-3", wantErr: true},
+		{spec: "5-2", wantErr: true},
+		{spec: "a-b", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			got, err := ParsePageRanges(tc.spec)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParsePDFDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "D:20240102150405Z", want: "2024-01-02T15:04:05Z"},
+		{in: "D:20240102150405+05'30'", want: "2024-01-02T09:34:05Z"},
+		{in: "D:20240102150405-02'00", want: "2024-01-02T17:04:05Z"},
+		{in: "D:2024", want: "2024-01-01T00:00:00Z"},
+		{in: "202403", want: "2024-03-01T00:00:00Z"},
+		{in: "D:20241", want: ""},
+		{in: "garbage", want: ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got := parsePDFDate(tc.in)
+			if tc.want == "" {
+				if got != nil {
+					t.Fatalf("expected nil, got %v", got)
+				}
+				return
+			}
+			if got == nil || got.Format(time.RFC3339) != tc.want {
+				t.Fatalf("got %v, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadPDFInfoSafe(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "doc.pdf", buildPagedPDF([]string{"one", "two", "three"}, true))
+	plain := writeTempFile(t, dir, "plain.pdf", buildMinimalPDF("Hello"))
+
+	info, err := ReadPDFInfoSafe(t.Context(), vfs.OS(), path)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if info.PageCount != 3 || info.Title != "Quarterly Report" || info.Author != "Jane Doe" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if info.CreationDate == nil || info.CreationDate.Format(time.RFC3339) != "2024-05-06T07:08:09Z" {
+		t.Fatalf("unexpected creation date: %v", info.CreationDate)
+	}
+	wantOutline := []PDFOutlineItem{
+		{Title: "Intro", Children: []PDFOutlineItem{{Title: "Background"}}},
+		{Title: "Appendix"},
+	}
+	if !reflect.DeepEqual(info.Outline, wantOutline) {
+		t.Fatalf("outline = %+v, want %+v", info.Outline, wantOutline)
+	}
+
+	info, err = ReadPDFInfoSafe(t.Context(), vfs.OS(), plain)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if info.PageCount != 1 || info.Title != "" || info.Outline != nil || info.CreationDate != nil {
+		t.Fatalf("unexpected info for plain PDF: %+v", info)
+	}
+}
+
+func TestExtractPDFPagesSafe(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTempFile(t, dir, "doc.pdf", buildPagedPDF([]string{"alpha", "beta", "gamma"}, false))
+
+	tests := []struct {
+		name      string
+		ranges    string
+		maxBytes  int
+		wantText  string
+		wantPages []int
+		wantNext  int
+		wantErrIs error
+	}{
+		{
+			name:      "single_page",
+			ranges:    "2",
+			maxBytes:  1 << 20,
+			wantText:  "--- Page 2 of 3 ---\nbeta",
+			wantPages: []int{2},
+		},
+		{
+			name:      "open_ended_and_list",
+			ranges:    "3,1-",
+			maxBytes:  1 << 20,
+			wantText:  "--- Page 3 of 3 ---\ngamma\n--- Page 1 of 3 ---\nalpha\n--- Page 2 of 3 ---\nbeta\n--- Page 3 of 3 ---\ngamma",
+			wantPages: []int{3, 1, 2, 3},
+		},
+		{
+			name:      "range_to_last_page",
+			ranges:    "2-3",
+			maxBytes:  1 << 20,
+			wantText:  "--- Page 2 of 3 ---\nbeta\n--- Page 3 of 3 ---\ngamma",
+			wantPages: []int{2, 3},
+		},
+		{
+			name:      "byte_limit_stops_at_page_boundary",
+			ranges:    "1-3",
+			maxBytes:  30,
+			wantText:  "--- Page 1 of 3 ---\nalpha",
+			wantPages: []int{1},
+			wantNext:  2,
+		},
+		{
+			name:      "oversized_first_page_is_cut",
+			ranges:    "1-2",
+			maxBytes:  22,
+			wantText:  "--- Page 1 of 3 ---\nal",
+			wantPages: []int{1},
+			wantNext:  2,
+		},
+		{name: "out_of_range", ranges: "2,4-5", maxBytes: 1 << 20, wantErrIs: ErrPageOutOfRange},
+		{name: "end_past_last_page", ranges: "2-99", maxBytes: 1 << 20, wantErrIs: ErrPageOutOfRange},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ranges, err := ParsePageRanges(tc.ranges)
+			if err != nil {
+				t.Fatalf("ParsePageRanges: %v", err)
+			}
+			got, err := ExtractPDFPagesSafe(t.Context(), vfs.OS(), path, ranges, tc.maxBytes)
+			if tc.wantErrIs != nil {
+				if !errors.Is(err, tc.wantErrIs) || !strings.Contains(err.Error(), "has 3 pages") {
+					t.Fatalf("err = %v, want %v mentioning the page count", err, tc.wantErrIs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if got.Text != tc.wantText {
+				t.Fatalf("text:\n%q\nwant:\n%q", got.Text, tc.wantText)
+			}
+			if !reflect.DeepEqual(got.Pages, tc.wantPages) || got.NextPage != tc.wantNext ||
+				got.Truncated != (tc.wantNext != 0) || got.TotalPages != 3 || got.Info.PageCount != 3 {
+				t.Fatalf("unexpected result: %+v", got)
+			}
+		})
+	}
+}
+
+// buildPagedPDF returns a PDF with one page per text. With meta, it also carries an Info dictionary
+// and a two-level outline.
+func buildPagedPDF(texts []string, meta bool) []byte {
+	// Objects: 1 catalog, 2 pages, 3 font, 4 outline root, 5-7 outline items, 8 info, then a
+	// page and a content stream per text.
+	objs := []string{
+		"", // catalog, filled below
+		"", // pages, filled below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Outlines /First 5 0 R /Last 6 0 R /Count 2 >>",
+		"<< /Title (Intro) /Parent 4 0 R /Next 6 0 R /First 7 0 R /Last 7 0 R /Count 1 >>",
+		"<< /Title (Appendix) /Parent 4 0 R /Prev 5 0 R >>",
+		"<< /Title (Background) /Parent 5 0 R >>",
+		"<< /Title (Quarterly Report) /Author (Jane Doe) /CreationDate (D:20240506070809Z) >>",
+	}
+	catalog := "<< /Type /Catalog /Pages 2 0 R >>"
+	if meta {
+		catalog = "<< /Type /Catalog /Pages 2 0 R /Outlines 4 0 R >>"
+	}
+	objs[0] = catalog
+
+	var kids []string
+	for _, text := range texts {
+		pageID := len(objs) + 1
+		content := "BT\n/F1 24 Tf\n72 120 Td\n(" + text + ") Tj\nET\n"
+		objs = append(objs,
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Contents "+itoa(pageID+1)+
+				" 0 R /Resources << /Font << /F1 3 0 R >> >> >>",
+			"<< /Length "+itoa(len(content))+" >>\nstream\n"+content+"endstream",
+		)
+		kids = append(kids, itoa(pageID)+" 0 R")
+	}
+	objs[1] = "<< /Type /Pages /Kids [" + strings.Join(kids, " ") + "] /Count " + itoa(len(texts)) + " >>"
+
+	b := []byte("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, o := range objs {
+		offsets[i] = len(b)
+		b = append(b, itoa(i+1)+" 0 obj\n"+o+"\nendobj\n"...)
+	}
+	xrefStart := len(b)
+	b = append(b, "xref\n0 "+itoa(len(objs)+1)+"\n0000000000 65535 f \n"...)
+	for _, off := range offsets {
+		b = append(b, pad10(off)+" 00000 n \n"...)
+	}
+	trailer := "<< /Size " + itoa(len(objs)+1) + " /Root 1 0 R"
+	if meta {
+		trailer += " /Info 8 0 R"
+	}
+	b = append(b, "trailer\n"+trailer+" >>\nstartxref\n"+itoa(xrefStart)+"\n%%EOF\n"...)
+	return b
+}
@@ -21,3 +21,9 @@ const MaxFileReadBytes = maxToolBytes
 
 // MaxFileWriteBytes caps raw bytes written to disk by “write file” style tools.
 const MaxFileWriteBytes = maxToolBytes
+
+// MaxTreeEntries caps the number of entries touched by recursive directory operations (copy/move/trash).
+const MaxTreeEntries = 10000
+
+// MaxTreeBytes caps total file bytes handled by recursive directory operations.
+const MaxTreeBytes = 16 * maxToolBytes
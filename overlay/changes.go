@@ -0,0 +1,508 @@
+package overlay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flexigpt/llmtools-go/internal/diffutil"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// maxDiffBytes bounds the size of each side of a rendered diff.
+const maxDiffBytes = 1 << 20
+
+type ChangeKind string
+
+const (
+	ChangeCreated  ChangeKind = "created"
+	ChangeModified ChangeKind = "modified"
+	ChangeDeleted  ChangeKind = "deleted"
+)
+
+// Change is one pending difference between the merged view and the lower layer.
+type Change struct {
+	Path  string      `json:"path"`
+	Kind  ChangeKind  `json:"kind"`
+	IsDir bool        `json:"isDir,omitempty"`
+	Mode  fs.FileMode `json:"mode,omitempty"` // merged-view type and permissions (zero when deleted)
+
+	// Regular files only: a unified diff against the lower content ("/dev/null" for created and
+	// deleted files). Empty for mode-only changes, binary files and files over 1 MiB.
+	Diff    string `json:"diff,omitempty"`
+	Binary  bool   `json:"binary,omitempty"`
+	Added   int    `json:"added,omitempty"`
+	Removed int    `json:"removed,omitempty"`
+}
+
+// Changes lists the pending changes, sorted by path. Directories are listed when created or deleted
+// (each deleted or created entry below them is listed too) or when their permissions changed.
+func (w *Workspace) Changes(ctx context.Context) ([]Change, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.changes(ctx, true)
+}
+
+// Commit applies the pending changes to the lower layer and, on success, empties the overlay.
+//
+// It first stages the content of every created or modified file as a temp file next to its
+// destination; any failure there removes the temp files and leaves the lower layer untouched. It then
+// moves deleted and replaced entries aside (deepest first), creates directories and symlinks, and
+// moves each staged file into place with the atomic commit helpers, so readers never see a partially
+// written file; overwritten files are kept as backups. If this second phase fails, every step taken
+// is undone from the backups and the overlay is kept: the error is returned and Changes lists what is
+// still pending. The backups are removed once the commit succeeds.
+func (w *Workspace) Commit(ctx context.Context) ([]Change, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changes, err := w.changes(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Phase 1: stage file contents.
+	staged := map[string]string{}
+	cleanup := func() {
+		for _, tmp := range staged {
+			_ = w.lower.Remove(tmp)
+		}
+	}
+	for _, c := range changes {
+		if c.Kind == ChangeDeleted || !c.Mode.IsRegular() {
+			continue
+		}
+		if same, err := w.sameLowerFile(c.Path); err != nil {
+			cleanup()
+			return nil, err
+		} else if same {
+			continue // mode-only change
+		}
+		tmp, err := w.stage(ctx, c.Path)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("stage %s: %w", c.Path, err)
+		}
+		staged[c.Path] = tmp
+	}
+
+	// Phase 2: apply. Replacements of a different type count as delete + create.
+	log := &commitLog{fsys: w.lower}
+	fail := func(err error) ([]Change, error) {
+		cleanup()
+		if rerr := log.rollback(); rerr != nil {
+			return nil, fmt.Errorf("commit incomplete and rollback failed, pending changes kept: %w",
+				errors.Join(err, rerr))
+		}
+		return nil, fmt.Errorf("commit rolled back, pending changes kept: %w", err)
+	}
+	var removed []string
+	for _, c := range slices.Backward(changes) {
+		li, err := w.lower.Lstat(c.Path)
+		if err != nil {
+			continue
+		}
+		if c.Kind == ChangeDeleted || li.Mode().Type() != c.Mode.Type() || c.Mode&fs.ModeSymlink != 0 {
+			removed = append(removed, c.Path)
+		}
+	}
+	for _, p := range removed {
+		// An entry inside a directory that is moved aside goes with it.
+		if slices.ContainsFunc(removed, func(a string) bool { return a != p && within(a, p) }) {
+			continue
+		}
+		if err := log.moveAside(p); err != nil {
+			return fail(err)
+		}
+	}
+	for _, c := range changes {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		if c.Kind == ChangeDeleted {
+			continue
+		}
+		li, lerr := w.lower.Lstat(c.Path)
+		var err error
+		switch {
+		case c.Mode.IsDir():
+			if lerr != nil {
+				if err = w.lower.Mkdir(c.Path, c.Mode.Perm()); err == nil {
+					log.created(c.Path)
+				}
+			}
+			if err == nil {
+				err = log.chmod(c.Path, c.Mode.Perm())
+			}
+		case c.Mode&fs.ModeSymlink != 0:
+			var target string
+			if target, err = w.upper.Readlink(c.Path); err == nil {
+				if err = w.lower.Symlink(target, c.Path); err == nil {
+					log.created(c.Path)
+				}
+			}
+		case staged[c.Path] != "":
+			if lerr == nil {
+				err = log.keepCopy(ctx, c.Path, li.Mode().Perm())
+			}
+			if err == nil {
+				err = ioutil.CommitTempFile(w.lower, staged[c.Path], c.Path, c.Mode.Perm(), true)
+			}
+			if err == nil {
+				delete(staged, c.Path)
+				if lerr != nil {
+					log.created(c.Path)
+				}
+			}
+		default:
+			err = log.chmod(c.Path, c.Mode.Perm())
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+	log.discard()
+
+	w.upper = vfs.NewMemFS()
+	w.whiteouts = make(map[string]struct{})
+	w.volumes = make(map[string]struct{})
+	return changes, nil
+}
+
+// commitLog records how to undo each step of Commit's apply phase, and the backup directories it
+// keeps next to the entries it moved aside or overwrote.
+type commitLog struct {
+	fsys    vfs.FS
+	undo    []func() error
+	backups []string
+}
+
+// backupDir creates a new backup directory next to p and returns the backup path for p in it.
+func (l *commitLog) backupDir(p string) (string, error) {
+	dir, err := l.fsys.MkdirTemp(filepath.Dir(p), "."+filepath.Base(p)+".overlay-old-*")
+	if err != nil {
+		return "", err
+	}
+	l.backups = append(l.backups, dir)
+	return filepath.Join(dir, filepath.Base(p)), nil
+}
+
+// moveAside renames p (and everything below it) into a backup directory.
+func (l *commitLog) moveAside(p string) error {
+	saved, err := l.backupDir(p)
+	if err != nil {
+		return err
+	}
+	if err := l.fsys.Rename(p, saved); err != nil {
+		return err
+	}
+	l.undo = append(l.undo, func() error { return l.fsys.Rename(saved, p) })
+	return nil
+}
+
+// keepCopy backs up the regular file p, which is about to be replaced, as a hard link or else a copy.
+func (l *commitLog) keepCopy(ctx context.Context, p string, perm fs.FileMode) error {
+	saved, err := l.backupDir(p)
+	if err != nil {
+		return err
+	}
+	if err := l.fsys.Link(p, saved); err != nil {
+		if _, err := ioutil.CopyFileCtx(ctx, l.fsys, p, saved, perm); err != nil {
+			return err
+		}
+	}
+	l.undo = append(l.undo, func() error { return l.fsys.Rename(saved, p) })
+	return nil
+}
+
+// created records that p did not exist before.
+func (l *commitLog) created(p string) {
+	l.undo = append(l.undo, func() error { return l.fsys.Remove(p) })
+}
+
+// chmod sets the permissions of p, recording the previous ones.
+func (l *commitLog) chmod(p string, perm fs.FileMode) error {
+	fi, err := l.fsys.Lstat(p)
+	if err != nil {
+		return err
+	}
+	if err := l.fsys.Chmod(p, perm); err != nil {
+		return err
+	}
+	old := fi.Mode().Perm()
+	l.undo = append(l.undo, func() error { return l.fsys.Chmod(p, old) })
+	return nil
+}
+
+// rollback undoes the recorded steps, newest first, and removes the emptied backup directories.
+func (l *commitLog) rollback() error {
+	var errs []error
+	for _, u := range slices.Backward(l.undo) {
+		if err := u(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...) // keep the backups for manual recovery
+	}
+	for _, dir := range l.backups {
+		_ = l.fsys.Remove(dir)
+	}
+	return nil
+}
+
+// discard removes the backups of a successful commit.
+func (l *commitLog) discard() {
+	for _, dir := range l.backups {
+		_ = l.fsys.RemoveAll(dir)
+	}
+}
+
+// within reports whether p lies beneath dir.
+func within(dir, p string) bool {
+	return strings.HasPrefix(p, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// changes computes the pending changes. The candidates are every upper path plus every lower path
+// below a whiteout; each one is compared between the merged view and the lower layer.
+func (w *Workspace) changes(ctx context.Context, withDiff bool) ([]Change, error) {
+	seen := map[string]struct{}{}
+	collect := func(fsys vfs.FS, root string) error {
+		return vfs.WalkDir(fsys, root, func(p string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if filepath.Dir(p) != p {
+				seen[p] = struct{}{}
+			}
+			return nil
+		})
+	}
+	for vol := range w.volumes {
+		if err := collect(w.upper, vol); err != nil {
+			return nil, err
+		}
+	}
+	for p := range w.whiteouts {
+		if err := collect(w.lower, p); err != nil {
+			return nil, err
+		}
+	}
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+
+	var out []Change
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c, ok, err := w.change(p, withDiff)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (w *Workspace) change(p string, withDiff bool) (Change, bool, error) {
+	mi, merged := w.mergedLstat(p)
+	li, lerr := w.lower.Lstat(p)
+	lower := lerr == nil
+	c := Change{Path: p}
+	switch {
+	case !merged && !lower:
+		return c, false, nil
+	case !merged:
+		c.Kind, c.IsDir = ChangeDeleted, li.IsDir()
+	case !lower:
+		c.Kind, c.IsDir, c.Mode = ChangeCreated, mi.IsDir(), mi.Mode()
+	default:
+		c.Kind, c.IsDir, c.Mode = ChangeModified, mi.IsDir(), mi.Mode()
+		same, err := w.sameAsLower(p, mi, li)
+		if err != nil || same {
+			return c, false, err
+		}
+	}
+	if withDiff && !c.IsDir {
+		if err := w.fillDiff(&c, mi, li); err != nil {
+			return c, false, err
+		}
+	}
+	return c, true, nil
+}
+
+// mergedLstat looks up p in the merged view without following symlinks in any component: p is only
+// present if all of its ancestors are directories there.
+func (w *Workspace) mergedLstat(p string) (fs.FileInfo, bool) {
+	for a := filepath.Dir(p); filepath.Dir(a) != a; a = filepath.Dir(a) {
+		if fi, _, err := w.lstatAt(a); err != nil || !fi.IsDir() {
+			return nil, false
+		}
+	}
+	fi, _, err := w.lstatAt(p)
+	return fi, err == nil
+}
+
+func (w *Workspace) sameAsLower(p string, mi, li fs.FileInfo) (bool, error) {
+	// Only type and permission bits: copied-up directories lose sticky/setgid bits.
+	if mi.Mode().Type() != li.Mode().Type() || mi.Mode().Perm() != li.Mode().Perm() {
+		return false, nil
+	}
+	switch {
+	case mi.IsDir():
+		return true, nil
+	case mi.Mode()&fs.ModeSymlink != 0:
+		mt, err := w.readlinkAt("readlink", p)
+		if err != nil {
+			return false, err
+		}
+		lt, err := w.lower.Readlink(p)
+		return mt == lt, err
+	default:
+		return w.sameLowerFile(p)
+	}
+}
+
+// sameLowerFile reports whether the regular file p has the same content in both views.
+func (w *Workspace) sameLowerFile(p string) (bool, error) {
+	mi, err := w.upper.Lstat(p)
+	if err != nil {
+		return true, nil //nolint:nilerr // Not in the upper layer: the lower file is the merged file.
+	}
+	li, err := w.lower.Lstat(p)
+	if err != nil || !li.Mode().IsRegular() || li.Size() != mi.Size() {
+		return false, nil //nolint:nilerr // A missing or different lower file is a difference, not an error.
+	}
+	a, err := vfs.ReadFile(w.upper, p)
+	if err != nil {
+		return false, err
+	}
+	b, err := vfs.ReadFile(w.lower, p)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(a, b), nil
+}
+
+func (w *Workspace) fillDiff(c *Change, mi, li fs.FileInfo) error {
+	var oldData, newData []byte
+	oldName, newName := c.Path, c.Path
+	read := func(fsys vfs.FS, fi fs.FileInfo) ([]byte, bool, error) {
+		if fi == nil || !fi.Mode().IsRegular() {
+			return nil, false, nil
+		}
+		if fi.Size() > maxDiffBytes {
+			return nil, true, nil
+		}
+		b, err := vfs.ReadFile(fsys, c.Path)
+		return b, false, err
+	}
+	var tooBig bool
+	if c.Kind == ChangeCreated {
+		oldName = "/dev/null"
+	} else {
+		b, big, err := read(w.lower, li)
+		if err != nil {
+			return err
+		}
+		oldData, tooBig = b, big
+	}
+	if c.Kind == ChangeDeleted {
+		newName = "/dev/null"
+	} else {
+		// Reading through w would take w.mu again; changed files live in the upper layer.
+		newFS := w.lower
+		if _, err := w.upper.Lstat(c.Path); err == nil {
+			newFS = w.upper
+		}
+		b, big, err := read(newFS, mi)
+		if err != nil {
+			return err
+		}
+		newData, tooBig = b, tooBig || big
+	}
+	if tooBig {
+		return nil
+	}
+	if !utf8.Valid(oldData) || !utf8.Valid(newData) ||
+		bytes.IndexByte(oldData, 0) >= 0 || bytes.IndexByte(newData, 0) >= 0 {
+		c.Binary = true
+		return nil
+	}
+	a, aNL := diffutil.SplitLines(string(oldData))
+	b, bNL := diffutil.SplitLines(string(newData))
+	c.Diff, c.Added, c.Removed = diffutil.Unified(
+		filepath.ToSlash(oldName), filepath.ToSlash(newName), a, b, aNL, bNL,
+		diffutil.DiffOptions{Context: diffutil.DefaultContext},
+	)
+	return nil
+}
+
+// stage copies the merged content of p into a new temp file on the lower layer, in p's directory or
+// else its closest existing ancestor, and returns the temp file's name.
+func (w *Workspace) stage(ctx context.Context, p string) (string, error) {
+	dir := filepath.Dir(p)
+	for {
+		if fi, err := w.lower.Stat(dir); err == nil && fi.IsDir() {
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			return "", &fs.PathError{Op: "stage", Path: p, Err: fs.ErrNotExist}
+		}
+		dir = filepath.Dir(dir)
+	}
+	src, err := w.upper.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	tmp, err := w.lower.CreateTemp(dir, "."+filepath.Base(p)+".overlay-*")
+	if err != nil {
+		return "", err
+	}
+	name := tmp.Name()
+	_, err = io.Copy(tmp, readerCtx{ctx, src})
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = w.lower.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
+
+// readerCtx stops a copy once ctx is done.
+type readerCtx struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r readerCtx) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
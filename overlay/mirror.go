@@ -0,0 +1,231 @@
+package overlay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// Materialize makes the OS directory dir a copy of the merged view of root, so programs that need a
+// real filesystem (exec tools) can run against the overlay. dir is created if needed; entries in it
+// that are not in the view are removed.
+//
+// Repeated calls are incremental: files whose size and modification time already match are skipped
+// (copies keep the source modification time), so a long-lived dir only pays for what changed.
+func (w *Workspace) Materialize(ctx context.Context, root, dir string) error {
+	return mirror(ctx, w, root, vfs.OS(), dir)
+}
+
+// Absorb is the inverse of Materialize: it applies the differences between the OS directory dir and
+// the merged view of root to the overlay, so changes made by programs in dir become pending changes.
+// Files with equal content are left alone and do not show up in Changes.
+func (w *Workspace) Absorb(ctx context.Context, root, dir string) error {
+	return mirror(ctx, vfs.OS(), dir, w, root)
+}
+
+// mirror makes the tree dstRoot on dst equal to srcRoot on src: same entries, types, permissions,
+// symlink targets and file contents. Other file types (devices, sockets, ...) are skipped.
+func mirror(ctx context.Context, src vfs.FS, srcRoot string, dst vfs.FS, dstRoot string) error {
+	if !filepath.IsAbs(srcRoot) || !filepath.IsAbs(dstRoot) {
+		return fmt.Errorf("overlay: paths must be absolute: %q, %q", srcRoot, dstRoot)
+	}
+	si, err := src.Stat(srcRoot)
+	if err != nil {
+		return err
+	}
+	if !si.IsDir() {
+		return &fs.PathError{Op: "mirror", Path: srcRoot, Err: errors.New("not a directory")}
+	}
+	if err := dst.MkdirAll(dstRoot, si.Mode().Perm()); err != nil {
+		return err
+	}
+	return mirrorDir(ctx, src, filepath.Clean(srcRoot), dst, filepath.Clean(dstRoot))
+}
+
+func mirrorDir(ctx context.Context, src vfs.FS, s string, dst vfs.FS, d string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	srcEnts, err := src.ReadDir(s)
+	if err != nil {
+		return err
+	}
+	dstEnts, err := dst.ReadDir(d)
+	if err != nil {
+		return err
+	}
+	want := make(map[string]struct{}, len(srcEnts))
+	for _, e := range srcEnts {
+		want[e.Name()] = struct{}{}
+	}
+	for _, e := range dstEnts {
+		if _, ok := want[e.Name()]; !ok {
+			if err := dst.RemoveAll(filepath.Join(d, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	for _, e := range srcEnts {
+		sp, dp := filepath.Join(s, e.Name()), filepath.Join(d, e.Name())
+		si, err := src.Lstat(sp)
+		if err != nil {
+			return err
+		}
+		if err := mirrorEntry(ctx, src, sp, si, dst, dp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mirrorEntry(ctx context.Context, src vfs.FS, sp string, si fs.FileInfo, dst vfs.FS, dp string) error {
+	di, derr := dst.Lstat(dp)
+	exists := derr == nil
+	mode := si.Mode()
+	switch {
+	case mode.IsDir():
+		if exists && !di.IsDir() {
+			if err := dst.RemoveAll(dp); err != nil {
+				return err
+			}
+			exists = false
+		}
+		if !exists {
+			// Chmod too: Mkdir on the OS is subject to the umask.
+			if err := dst.Mkdir(dp, mode.Perm()); err != nil {
+				return err
+			}
+			if err := dst.Chmod(dp, mode.Perm()); err != nil {
+				return err
+			}
+		} else if permDiffers(di.Mode(), mode) {
+			if err := dst.Chmod(dp, mode.Perm()); err != nil {
+				return err
+			}
+		}
+		return mirrorDir(ctx, src, sp, dst, dp)
+
+	case mode&fs.ModeSymlink != 0:
+		target, err := src.Readlink(sp)
+		if err != nil {
+			return err
+		}
+		if exists && di.Mode()&fs.ModeSymlink != 0 {
+			if cur, err := dst.Readlink(dp); err == nil && cur == target {
+				return nil
+			}
+		}
+		if exists {
+			if err := dst.RemoveAll(dp); err != nil {
+				return err
+			}
+		}
+		return dst.Symlink(target, dp)
+
+	case mode.IsRegular():
+		if exists && di.Mode().IsRegular() && di.Size() == si.Size() {
+			same := di.ModTime().Equal(si.ModTime())
+			if !same {
+				var err error
+				if same, err = sameContent(src, sp, dst, dp); err != nil {
+					return err
+				}
+				// Keep the quick check cheap next time, without copying an unchanged file up.
+				if same && vfs.IsOS(dst) {
+					_ = dst.Chtimes(dp, si.ModTime(), si.ModTime())
+				}
+			}
+			if same {
+				if permDiffers(di.Mode(), mode) {
+					return dst.Chmod(dp, mode.Perm())
+				}
+				return nil
+			}
+		}
+		if exists && !di.Mode().IsRegular() {
+			if err := dst.RemoveAll(dp); err != nil {
+				return err
+			}
+		}
+		if err := copyFile(ctx, src, sp, dst, dp); err != nil {
+			return err
+		}
+		if err := dst.Chmod(dp, mode.Perm()); err != nil {
+			return err
+		}
+		return dst.Chtimes(dp, si.ModTime(), si.ModTime())
+	}
+	return nil
+}
+
+// permDiffers compares permission bits. Windows only keeps the read-only attribute, so only the owner
+// write bit is compared there.
+func permDiffers(a, b fs.FileMode) bool {
+	if runtime.GOOS == toolutil.GOOSWindows {
+		return a&0o200 != b&0o200
+	}
+	return a.Perm() != b.Perm()
+}
+
+func copyFile(ctx context.Context, src vfs.FS, sp string, dst vfs.FS, dp string) error {
+	in, err := src.Open(sp)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	// Make a read-only destination writable for the copy; mirrorEntry restores the mode afterwards.
+	if fi, err := dst.Lstat(dp); err == nil && fi.Mode().Perm()&0o200 == 0 {
+		if err := dst.Chmod(dp, fi.Mode().Perm()|0o200); err != nil {
+			return err
+		}
+	}
+	out, err := dst.OpenFile(dp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, readerCtx{ctx, in})
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func sameContent(a vfs.FS, ap string, b vfs.FS, bp string) (bool, error) {
+	fa, err := a.Open(ap)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := b.Open(bp)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+	bufA, bufB := make([]byte, 64*1024), make([]byte, 64*1024)
+	for {
+		na, ea := io.ReadFull(fa, bufA)
+		nb, eb := io.ReadFull(fb, bufB)
+		if na != nb || string(bufA[:na]) != string(bufB[:nb]) {
+			return false, nil
+		}
+		doneA := errors.Is(ea, io.EOF) || errors.Is(ea, io.ErrUnexpectedEOF)
+		doneB := errors.Is(eb, io.EOF) || errors.Is(eb, io.ErrUnexpectedEOF)
+		if doneA || doneB {
+			return doneA && doneB, nil
+		}
+		if ea != nil {
+			return false, ea
+		}
+		if eb != nil {
+			return false, eb
+		}
+	}
+}
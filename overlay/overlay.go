@@ -0,0 +1,784 @@
+// Package overlay provides a copy-on-write workspace: a vfs.FS that layers an in-memory scratch
+// filesystem over a lower filesystem (usually the OS). Reads see the merged view; writes, deletes,
+// renames and attribute changes only touch the upper layer, so the lower tree is left untouched until
+// the pending changes are committed (or discarded).
+//
+// Hosts pass a Workspace to the file and text tools via their WithFS options, review the pending
+// changes with Changes, then Commit or Discard them. Exec tools cannot run against a virtual
+// filesystem; they use a materialized view instead (see Materialize, Absorb and exectool.WithOverlay).
+package overlay
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// maxSymlinkHops bounds symlink resolution in the merged view, like the kernel's ELOOP limit.
+const maxSymlinkHops = 40
+
+// writeFlags are the open flags that need the file in the upper layer.
+const writeFlags = os.O_WRONLY | os.O_RDWR | os.O_CREATE | os.O_TRUNC | os.O_APPEND
+
+// Workspace is a copy-on-write overlay over a lower filesystem. The zero value is not usable; create
+// one with New. Workspace is safe for concurrent use.
+//
+// Deleting a path that exists in the lower layer records a whiteout for it: the lower path and
+// everything below it stay hidden, even if the path is later recreated in the upper layer (a
+// recreated directory is "opaque" and starts out empty).
+type Workspace struct {
+	mu        sync.RWMutex
+	lower     vfs.FS
+	upper     *vfs.MemFS
+	whiteouts map[string]struct{}
+	// volumes holds the root of every volume the upper layer has touched ("/" on Unix).
+	volumes map[string]struct{}
+}
+
+var _ vfs.FS = (*Workspace)(nil)
+
+// New returns an empty overlay over lower (nil means the OS filesystem).
+func New(lower vfs.FS) *Workspace {
+	return &Workspace{
+		lower:     vfs.OrOS(lower),
+		upper:     vfs.NewMemFS(),
+		whiteouts: make(map[string]struct{}),
+		volumes:   make(map[string]struct{}),
+	}
+}
+
+// Lower returns the filesystem the overlay is layered over.
+func (w *Workspace) Lower() vfs.FS { return w.lower }
+
+// Discard drops every pending change, restoring the view of the lower layer. Files opened before the
+// call keep working but are no longer part of the workspace.
+func (w *Workspace) Discard() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.upper = vfs.NewMemFS()
+	w.whiteouts = make(map[string]struct{})
+	w.volumes = make(map[string]struct{})
+}
+
+func (w *Workspace) Open(name string) (vfs.File, error) { return w.OpenFile(name, os.O_RDONLY, 0) }
+
+func (w *Workspace) OpenFile(name string, flag int, perm fs.FileMode) (vfs.File, error) {
+	if flag&writeFlags == 0 {
+		w.mu.RLock()
+		defer w.mu.RUnlock()
+		r, err := w.resolve("open", name, true)
+		if err != nil {
+			return nil, err
+		}
+		fi, inUpper, err := w.lstatAt(r)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		layer := w.lower
+		if inUpper {
+			layer = w.upper
+		}
+		f, err := layer.OpenFile(r, flag, perm)
+		if err != nil {
+			return nil, err
+		}
+		of := &file{File: f, name: name}
+		if fi.IsDir() {
+			of.w, of.dir = w, r
+		}
+		return of, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, err := w.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	fi, inUpper, err := w.lstatAt(r)
+	switch {
+	case err == nil && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case err == nil && !inUpper:
+		if err := w.copyUp(r, fi, flag&os.O_TRUNC != 0); err != nil {
+			return nil, err
+		}
+	case err != nil && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case err != nil:
+		if err := w.prepareCreate("open", r); err != nil {
+			return nil, err
+		}
+	}
+	f, err := w.upper.OpenFile(r, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, name: name}, nil
+}
+
+func (w *Workspace) CreateTemp(dir, pattern string) (vfs.File, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, err := w.upperDir("createtemp", dir)
+	if err != nil {
+		return nil, err
+	}
+	for range 100 {
+		f, err := w.upper.CreateTemp(r, pattern)
+		if err != nil {
+			return nil, err
+		}
+		// The name must be free in the merged view, not just in the upper layer.
+		if w.lowerVisible(f.Name()) {
+			_ = f.Close()
+			_ = w.upper.Remove(f.Name())
+			continue
+		}
+		return f, nil
+	}
+	return nil, &fs.PathError{Op: "createtemp", Path: filepath.Join(dir, pattern), Err: fs.ErrExist}
+}
+
+func (w *Workspace) MkdirTemp(dir, pattern string) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, err := w.upperDir("mkdirtemp", dir)
+	if err != nil {
+		return "", err
+	}
+	for range 100 {
+		name, err := w.upper.MkdirTemp(r, pattern)
+		if err != nil {
+			return "", err
+		}
+		if w.lowerVisible(name) {
+			_ = w.upper.Remove(name)
+			continue
+		}
+		return name, nil
+	}
+	return "", &fs.PathError{Op: "mkdirtemp", Path: filepath.Join(dir, pattern), Err: fs.ErrExist}
+}
+
+func (w *Workspace) Stat(name string) (fs.FileInfo, error) { return w.stat("stat", name, true) }
+
+func (w *Workspace) Lstat(name string) (fs.FileInfo, error) { return w.stat("lstat", name, false) }
+
+func (w *Workspace) stat(op, name string, follow bool) (fs.FileInfo, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	r, err := w.resolve(op, name, follow)
+	if err != nil {
+		return nil, err
+	}
+	fi, _, err := w.lstatAt(r)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return fi, nil
+}
+
+func (w *Workspace) ReadDir(name string) ([]fs.DirEntry, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	r, err := w.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	fi, _, err := w.lstatAt(r)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !fi.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: syscall.ENOTDIR}
+	}
+	return w.entries(r)
+}
+
+func (w *Workspace) Readlink(name string) (string, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	r, err := w.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	return w.readlinkAt("readlink", r)
+}
+
+func (w *Workspace) EvalSymlinks(name string) (string, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	r, err := w.resolve("lstat", name, true)
+	if err != nil {
+		return "", err
+	}
+	if _, _, err := w.lstatAt(r); err != nil {
+		return "", &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+	}
+	return r, nil
+}
+
+func (w *Workspace) Mkdir(name string, perm fs.FileMode) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, err := w.resolve("mkdir", name, false)
+	if err != nil {
+		return err
+	}
+	return w.mkdir(r, perm)
+}
+
+func (w *Workspace) MkdirAll(name string, perm fs.FileMode) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, err := w.resolve("mkdir", name, true)
+	if err != nil {
+		return err
+	}
+	// Create the missing suffix of r, shallowest first.
+	var missing []string
+	for p := r; ; p = filepath.Dir(p) {
+		fi, _, err := w.lstatAt(p)
+		if err == nil {
+			if !fi.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: p, Err: syscall.ENOTDIR}
+			}
+			break
+		}
+		missing = append(missing, p)
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+	for _, p := range slices.Backward(missing) {
+		if err := w.mkdir(p, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Workspace) mkdir(r string, perm fs.FileMode) error {
+	if _, _, err := w.lstatAt(r); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: r, Err: fs.ErrExist}
+	}
+	if err := w.prepareCreate("mkdir", r); err != nil {
+		return err
+	}
+	return w.upper.Mkdir(r, perm)
+}
+
+func (w *Workspace) Remove(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, err := w.resolve("remove", name, false)
+	if err != nil {
+		return err
+	}
+	fi, inUpper, err := w.lstatAt(r)
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if fi.IsDir() {
+		ents, err := w.entries(r)
+		if err != nil {
+			return err
+		}
+		if len(ents) > 0 {
+			return &fs.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+		}
+	}
+	return w.remove(r, inUpper)
+}
+
+func (w *Workspace) RemoveAll(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, err := w.resolve("removeall", name, false)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	_, inUpper, err := w.lstatAt(r)
+	if err != nil {
+		return nil //nolint:nilerr // Like os.RemoveAll, a missing path is not an error.
+	}
+	return w.remove(r, inUpper)
+}
+
+// remove deletes the existing path r (and anything below it) from the merged view.
+func (w *Workspace) remove(r string, inUpper bool) error {
+	if inUpper {
+		if err := w.upper.RemoveAll(r); err != nil {
+			return err
+		}
+	}
+	if w.lowerVisible(r) {
+		w.whiteouts[r] = struct{}{}
+	}
+	return nil
+}
+
+func (w *Workspace) Rename(oldname, newname string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	linkErr := func(err error) error { return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err} }
+	ro, err := w.resolve("rename", oldname, false)
+	if err != nil {
+		return linkErr(fs.ErrNotExist)
+	}
+	rn, err := w.resolve("rename", newname, false)
+	if err != nil {
+		return linkErr(fs.ErrNotExist)
+	}
+	si, srcUpper, err := w.lstatAt(ro)
+	if err != nil {
+		return linkErr(fs.ErrNotExist)
+	}
+	if ro == rn {
+		return nil
+	}
+	if si.IsDir() && strings.HasPrefix(rn, ro+string(filepath.Separator)) {
+		return linkErr(fs.ErrInvalid)
+	}
+	if di, dstUpper, err := w.lstatAt(rn); err == nil {
+		switch {
+		case si.IsDir() && !di.IsDir():
+			return linkErr(syscall.ENOTDIR)
+		case !si.IsDir() && di.IsDir():
+			return linkErr(syscall.EISDIR)
+		case di.IsDir():
+			ents, err := w.entries(rn)
+			if err != nil {
+				return err
+			}
+			if len(ents) > 0 {
+				return linkErr(syscall.ENOTEMPTY)
+			}
+		}
+		if err := w.remove(rn, dstUpper); err != nil {
+			return err
+		}
+	} else if err := w.prepareCreate("rename", rn); err != nil {
+		return err
+	}
+	if err := w.copyUpTree(ro, si, srcUpper); err != nil {
+		return err
+	}
+	if err := w.upper.Rename(ro, rn); err != nil {
+		return err
+	}
+	if w.lowerVisible(ro) {
+		w.whiteouts[ro] = struct{}{}
+	}
+	return nil
+}
+
+func (w *Workspace) Link(oldname, newname string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	linkErr := func(err error) error { return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err} }
+	ro, err := w.resolve("link", oldname, false)
+	if err != nil {
+		return linkErr(fs.ErrNotExist)
+	}
+	rn, err := w.resolve("link", newname, false)
+	if err != nil {
+		return linkErr(fs.ErrNotExist)
+	}
+	si, srcUpper, err := w.lstatAt(ro)
+	if err != nil {
+		return linkErr(fs.ErrNotExist)
+	}
+	if si.IsDir() {
+		return linkErr(fs.ErrPermission)
+	}
+	if _, _, err := w.lstatAt(rn); err == nil {
+		return linkErr(fs.ErrExist)
+	}
+	if !srcUpper {
+		if err := w.copyUp(ro, si, false); err != nil {
+			return err
+		}
+	}
+	if err := w.prepareCreate("link", rn); err != nil {
+		return err
+	}
+	return w.upper.Link(ro, rn)
+}
+
+func (w *Workspace) Symlink(oldname, newname string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	rn, err := w.resolve("symlink", newname, false)
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	if _, _, err := w.lstatAt(rn); err == nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+	if err := w.prepareCreate("symlink", rn); err != nil {
+		return err
+	}
+	return w.upper.Symlink(oldname, rn)
+}
+
+func (w *Workspace) Chmod(name string, mode fs.FileMode) error {
+	return w.update("chmod", name, func(r string) error { return w.upper.Chmod(r, mode) })
+}
+
+func (w *Workspace) Chtimes(name string, atime, mtime time.Time) error {
+	return w.update("chtimes", name, func(r string) error { return w.upper.Chtimes(r, atime, mtime) })
+}
+
+// update copies the (symlink-resolved) file up and applies fn to the upper copy.
+func (w *Workspace) update(op, name string, fn func(r string) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r, err := w.resolve(op, name, true)
+	if err != nil {
+		return err
+	}
+	fi, inUpper, err := w.lstatAt(r)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !inUpper {
+		if err := w.copyUp(r, fi, false); err != nil {
+			return err
+		}
+	}
+	return fn(r)
+}
+
+// resolve cleans the absolute path name and resolves symlinks in the merged view: in every parent
+// component, and in the last one too if follow is set. Resolution stops at the first missing
+// component, leaving the rest as is, so callers see a not-exist error from the lookup that follows.
+func (w *Workspace) resolve(op, name string, follow bool) (string, error) {
+	if name == "" || !filepath.IsAbs(name) || strings.ContainsRune(name, 0) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	p := filepath.Clean(name)
+	vol := filepath.VolumeName(p)
+	resolved := vol + string(filepath.Separator)
+	rest := splitPath(p[len(vol):])
+	for hops := 0; len(rest) > 0; {
+		elem := rest[0]
+		rest = rest[1:]
+		next := filepath.Join(resolved, elem)
+		if len(rest) == 0 && !follow {
+			return next, nil
+		}
+		fi, _, err := w.lstatAt(next)
+		if err != nil {
+			return filepath.Join(append([]string{next}, rest...)...), nil //nolint:nilerr // See doc comment.
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		if hops++; hops > maxSymlinkHops {
+			return "", &fs.PathError{Op: op, Path: name, Err: syscall.ELOOP}
+		}
+		target, err := w.readlinkAt(op, next)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(resolved, target)
+		}
+		target = filepath.Clean(target)
+		vol = filepath.VolumeName(target)
+		resolved = vol + string(filepath.Separator)
+		rest = append(splitPath(target[len(vol):]), rest...)
+	}
+	return resolved, nil
+}
+
+func splitPath(p string) []string {
+	return strings.FieldsFunc(p, func(r rune) bool { return r == filepath.Separator })
+}
+
+// lstatAt looks up the resolved path r in the merged view without following a final symlink, and
+// reports which layer holds it.
+func (w *Workspace) lstatAt(r string) (fi fs.FileInfo, inUpper bool, err error) {
+	if fi, err := w.upper.Lstat(r); err == nil {
+		return fi, true, nil
+	}
+	if w.hidden(r) {
+		return nil, false, &fs.PathError{Op: "lstat", Path: r, Err: fs.ErrNotExist}
+	}
+	fi, err = w.lower.Lstat(r)
+	return fi, false, err
+}
+
+// hidden reports whether r or one of its ancestors has a whiteout.
+func (w *Workspace) hidden(r string) bool {
+	if len(w.whiteouts) == 0 {
+		return false
+	}
+	for p := r; ; p = filepath.Dir(p) {
+		if _, ok := w.whiteouts[p]; ok {
+			return true
+		}
+		if filepath.Dir(p) == p {
+			return false
+		}
+	}
+}
+
+// lowerVisible reports whether the lower layer has r and no whiteout hides it.
+func (w *Workspace) lowerVisible(r string) bool {
+	if w.hidden(r) {
+		return false
+	}
+	_, err := w.lower.Lstat(r)
+	return err == nil
+}
+
+func (w *Workspace) readlinkAt(op, r string) (string, error) {
+	fi, inUpper, err := w.lstatAt(r)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: r, Err: fs.ErrNotExist}
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: op, Path: r, Err: fs.ErrInvalid}
+	}
+	if inUpper {
+		return w.upper.Readlink(r)
+	}
+	return w.lower.Readlink(r)
+}
+
+// entries returns the merged entries of the directory r, sorted by name. A directory with a whiteout
+// (deleted, then recreated) shows only its upper entries.
+func (w *Workspace) entries(r string) ([]fs.DirEntry, error) {
+	byName := map[string]fs.DirEntry{}
+	if fi, err := w.upper.Lstat(r); err == nil && fi.IsDir() {
+		ents, err := w.upper.ReadDir(r)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range ents {
+			byName[e.Name()] = e
+		}
+	}
+	if !w.hidden(r) {
+		if fi, err := w.lower.Lstat(r); err == nil && fi.IsDir() {
+			ents, err := w.lower.ReadDir(r)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range ents {
+				if _, ok := byName[e.Name()]; ok {
+					continue
+				}
+				if _, ok := w.whiteouts[filepath.Join(r, e.Name())]; ok {
+					continue
+				}
+				byName[e.Name()] = e
+			}
+		}
+	}
+	out := make([]fs.DirEntry, 0, len(byName))
+	for _, e := range byName {
+		out = append(out, e)
+	}
+	slices.SortFunc(out, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return out, nil
+}
+
+// prepareCreate checks that the parent of the missing path r is a directory in the merged view and
+// makes sure the upper layer has it.
+func (w *Workspace) prepareCreate(op, r string) error {
+	parent := filepath.Dir(r)
+	fi, _, err := w.lstatAt(parent)
+	if err != nil {
+		return &fs.PathError{Op: op, Path: r, Err: fs.ErrNotExist}
+	}
+	if !fi.IsDir() {
+		return &fs.PathError{Op: op, Path: r, Err: syscall.ENOTDIR}
+	}
+	return w.upperParents(r)
+}
+
+// upperParents creates the ancestors of r that are missing from the upper layer, copying the mode
+// and modification time of the merged directories.
+func (w *Workspace) upperParents(r string) error {
+	vol := filepath.VolumeName(r)
+	w.volumes[vol+string(filepath.Separator)] = struct{}{}
+	var missing []string
+	for p := filepath.Dir(r); filepath.Dir(p) != p; p = filepath.Dir(p) {
+		if _, err := w.upper.Lstat(p); err == nil {
+			break
+		}
+		missing = append(missing, p)
+	}
+	for _, p := range slices.Backward(missing) {
+		fi, _, err := w.lstatAt(p)
+		if err != nil {
+			return err
+		}
+		if err := w.upper.Mkdir(p, fi.Mode().Perm()); err != nil {
+			return err
+		}
+		_ = w.upper.Chtimes(p, fi.ModTime(), fi.ModTime())
+	}
+	return nil
+}
+
+// upperDir resolves the directory dir and makes sure the upper layer has it.
+func (w *Workspace) upperDir(op, dir string) (string, error) {
+	if dir == "" {
+		return "", &fs.PathError{Op: op, Path: dir, Err: fs.ErrInvalid}
+	}
+	r, err := w.resolve(op, dir, true)
+	if err != nil {
+		return "", err
+	}
+	fi, inUpper, err := w.lstatAt(r)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: dir, Err: fs.ErrNotExist}
+	}
+	if !fi.IsDir() {
+		return "", &fs.PathError{Op: op, Path: dir, Err: syscall.ENOTDIR}
+	}
+	if !inUpper {
+		if err := w.copyUp(r, fi, false); err != nil {
+			return "", err
+		}
+	}
+	return r, nil
+}
+
+// copyUp copies the lower entry r (described by fi) into the upper layer. Directories are copied
+// without their contents; truncate skips copying file data.
+func (w *Workspace) copyUp(r string, fi fs.FileInfo, truncate bool) error {
+	if err := w.upperParents(r); err != nil {
+		return err
+	}
+	mode := fi.Mode()
+	switch {
+	case mode.IsDir():
+		if err := w.upper.Mkdir(r, mode.Perm()); err != nil {
+			return err
+		}
+	case mode&fs.ModeSymlink != 0:
+		target, err := w.lower.Readlink(r)
+		if err != nil {
+			return err
+		}
+		return w.upper.Symlink(target, r)
+	case mode.IsRegular():
+		if err := w.copyUpData(r, truncate); err != nil {
+			return err
+		}
+		if err := w.upper.Chmod(r, mode.Perm()); err != nil {
+			return err
+		}
+	default:
+		return &fs.PathError{Op: "copyup", Path: r, Err: fs.ErrInvalid}
+	}
+	return w.upper.Chtimes(r, fi.ModTime(), fi.ModTime())
+}
+
+func (w *Workspace) copyUpData(r string, truncate bool) error {
+	// Created writable; copyUp restores the original permissions afterwards.
+	dst, err := w.upper.OpenFile(r, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	if truncate {
+		return nil
+	}
+	src, err := w.lower.Open(r)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// copyUpTree copies r and, for directories, everything visible below it into the upper layer.
+func (w *Workspace) copyUpTree(r string, fi fs.FileInfo, inUpper bool) error {
+	if !inUpper {
+		if err := w.copyUp(r, fi, false); err != nil {
+			return err
+		}
+	}
+	if !fi.IsDir() {
+		return nil
+	}
+	ents, err := w.entries(r)
+	if err != nil {
+		return err
+	}
+	for _, e := range ents {
+		p := filepath.Join(r, e.Name())
+		cfi, cUpper, err := w.lstatAt(p)
+		if err != nil {
+			return err
+		}
+		if err := w.copyUpTree(p, cfi, cUpper); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// file is an open overlay file. It reports the name it was opened with and, for directories, lists
+// the merged entries.
+type file struct {
+	vfs.File
+
+	name string
+	// Directories only.
+	w       *Workspace
+	dir     string
+	ents    []fs.DirEntry
+	entsErr error
+	loaded  bool
+}
+
+func (f *file) Name() string { return f.name }
+
+func (f *file) ReadDir(n int) ([]fs.DirEntry, error) {
+	if f.w == nil {
+		return f.File.ReadDir(n)
+	}
+	if !f.loaded {
+		f.loaded = true
+		f.w.mu.RLock()
+		f.ents, f.entsErr = f.w.entries(f.dir)
+		f.w.mu.RUnlock()
+	}
+	if f.entsErr != nil {
+		return nil, f.entsErr
+	}
+	if n <= 0 {
+		out := f.ents
+		f.ents = nil
+		return out, nil
+	}
+	if len(f.ents) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(f.ents))
+	out := f.ents[:n:n]
+	f.ents = f.ents[n:]
+	return out, nil
+}
@@ -0,0 +1,418 @@
+package overlay
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/fstool"
+	"github.com/flexigpt/llmtools-go/texttool"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+// newLower returns a MemFS holding root/{a.txt, sub/b.txt, sub/deep/c.txt, link -> sub}.
+func newLower(t *testing.T) (lower *vfs.MemFS, root string) {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lower = vfs.NewMemFS()
+	if err := lower.MkdirAll(filepath.Join(root, "sub", "deep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, lower, filepath.Join(root, "a.txt"), "alpha\n")
+	mustWrite(t, lower, filepath.Join(root, "sub", "b.txt"), "beta\n")
+	mustWrite(t, lower, filepath.Join(root, "sub", "deep", "c.txt"), "gamma\n")
+	if err := lower.Symlink("sub", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	return lower, root
+}
+
+func mustWrite(t *testing.T, fsys vfs.FS, name, content string) {
+	t.Helper()
+	if err := vfs.WriteFile(fsys, name, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func mustRead(t *testing.T, fsys vfs.FS, name string) string {
+	t.Helper()
+	b, err := vfs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(b)
+}
+
+func names(t *testing.T, fsys vfs.FS, dir string) []string {
+	t.Helper()
+	ents, err := fsys.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("readdir %s: %v", dir, err)
+	}
+	out := make([]string, 0, len(ents))
+	for _, e := range ents {
+		out = append(out, e.Name())
+	}
+	return out
+}
+
+// summary renders changes as "kind path" lines relative to root.
+func summary(changes []Change, root string) []string {
+	out := make([]string, 0, len(changes))
+	for _, c := range changes {
+		rel, _ := filepath.Rel(root, c.Path)
+		out = append(out, string(c.Kind)+" "+filepath.ToSlash(rel))
+	}
+	return out
+}
+
+func TestWorkspace(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, w *Workspace, lower *vfs.MemFS, root string)
+	}{
+		{
+			name: "writes_stay_in_upper_layer",
+			run: func(t *testing.T, w *Workspace, lower *vfs.MemFS, root string) {
+				t.Helper()
+				mustWrite(t, w, filepath.Join(root, "a.txt"), "changed\n")
+				mustWrite(t, w, filepath.Join(root, "link", "new.txt"), "new\n")
+				if got := mustRead(t, w, filepath.Join(root, "a.txt")); got != "changed\n" {
+					t.Fatalf("merged a.txt = %q", got)
+				}
+				if got := mustRead(t, lower, filepath.Join(root, "a.txt")); got != "alpha\n" {
+					t.Fatalf("lower a.txt = %q", got)
+				}
+				// Writing through the symlink lands in its target directory.
+				if got := names(t, w, filepath.Join(root, "sub")); !slices.Equal(got, []string{"b.txt", "deep", "new.txt"}) {
+					t.Fatalf("merged sub = %v", got)
+				}
+				if _, err := lower.Lstat(filepath.Join(root, "sub", "new.txt")); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("lower sub/new.txt err = %v", err)
+				}
+			},
+		},
+		{
+			name: "deletes_hide_lower_entries",
+			run: func(t *testing.T, w *Workspace, lower *vfs.MemFS, root string) {
+				t.Helper()
+				if err := w.Remove(filepath.Join(root, "sub")); err == nil {
+					t.Fatal("removing a non-empty directory must fail")
+				}
+				if err := w.RemoveAll(filepath.Join(root, "sub")); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := w.Stat(filepath.Join(root, "sub", "b.txt")); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("stat deleted file err = %v", err)
+				}
+				if got := names(t, w, root); !slices.Equal(got, []string{"a.txt", "link"}) {
+					t.Fatalf("merged root = %v", got)
+				}
+				// A recreated directory is opaque: the lower entries stay deleted.
+				if err := w.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if got := names(t, w, filepath.Join(root, "sub")); len(got) != 0 {
+					t.Fatalf("recreated sub = %v", got)
+				}
+				if got := names(t, lower, filepath.Join(root, "sub")); len(got) != 2 {
+					t.Fatalf("lower sub = %v", got)
+				}
+			},
+		},
+		{
+			name: "rename_moves_tree",
+			run: func(t *testing.T, w *Workspace, lower *vfs.MemFS, root string) {
+				t.Helper()
+				if err := w.Rename(filepath.Join(root, "sub"), filepath.Join(root, "moved")); err != nil {
+					t.Fatal(err)
+				}
+				if got := mustRead(t, w, filepath.Join(root, "moved", "deep", "c.txt")); got != "gamma\n" {
+					t.Fatalf("moved c.txt = %q", got)
+				}
+				if _, err := w.Stat(filepath.Join(root, "sub")); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("old dir err = %v", err)
+				}
+				if err := w.Rename(filepath.Join(root, "moved"), filepath.Join(root, "moved", "x")); err == nil {
+					t.Fatal("moving a directory into itself must fail")
+				}
+				if got := mustRead(t, lower, filepath.Join(root, "sub", "b.txt")); got != "beta\n" {
+					t.Fatalf("lower b.txt = %q", got)
+				}
+			},
+		},
+		{
+			name: "changes_list_diffs",
+			run: func(t *testing.T, w *Workspace, _ *vfs.MemFS, root string) {
+				t.Helper()
+				mustWrite(t, w, filepath.Join(root, "a.txt"), "alpha\nmore\n")
+				mustWrite(t, w, filepath.Join(root, "sub", "b.txt"), "beta\n") // same content: no change
+				mustWrite(t, w, filepath.Join(root, "n.txt"), "n\n")
+				if err := w.Remove(filepath.Join(root, "sub", "deep", "c.txt")); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Chmod(filepath.Join(root, "sub", "deep"), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				changes, err := w.Changes(t.Context())
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := []string{"modified a.txt", "created n.txt", "modified sub/deep", "deleted sub/deep/c.txt"}
+				if got := summary(changes, root); !slices.Equal(got, want) {
+					t.Fatalf("changes = %v, want %v", got, want)
+				}
+				if d := changes[0].Diff; !strings.Contains(d, "+more") || changes[0].Added != 1 {
+					t.Fatalf("a.txt diff = %q", d)
+				}
+				if d := changes[1].Diff; !strings.HasPrefix(d, "--- /dev/null\n") {
+					t.Fatalf("n.txt diff = %q", d)
+				}
+				if d := changes[3].Diff; !strings.Contains(d, "-gamma") || changes[3].Removed != 1 {
+					t.Fatalf("c.txt diff = %q", d)
+				}
+			},
+		},
+		{
+			name: "commit_applies_and_empties",
+			run: func(t *testing.T, w *Workspace, lower *vfs.MemFS, root string) {
+				t.Helper()
+				mustWrite(t, w, filepath.Join(root, "a.txt"), "changed\n")
+				if err := w.MkdirAll(filepath.Join(root, "x", "y"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				mustWrite(t, w, filepath.Join(root, "x", "y", "z.txt"), "z\n")
+				if err := w.RemoveAll(filepath.Join(root, "sub", "deep")); err != nil {
+					t.Fatal(err)
+				}
+				// A directory replaced by a file.
+				if err := w.Remove(filepath.Join(root, "link")); err != nil {
+					t.Fatal(err)
+				}
+				mustWrite(t, w, filepath.Join(root, "link"), "not a link\n")
+
+				committed, err := w.Commit(t.Context())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(committed) == 0 {
+					t.Fatal("no changes committed")
+				}
+				if got := mustRead(t, lower, filepath.Join(root, "a.txt")); got != "changed\n" {
+					t.Fatalf("lower a.txt = %q", got)
+				}
+				if got := mustRead(t, lower, filepath.Join(root, "x", "y", "z.txt")); got != "z\n" {
+					t.Fatalf("lower z.txt = %q", got)
+				}
+				if got := mustRead(t, lower, filepath.Join(root, "link")); got != "not a link\n" {
+					t.Fatalf("lower link = %q", got)
+				}
+				if _, err := lower.Lstat(filepath.Join(root, "sub", "deep")); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("lower deep err = %v", err)
+				}
+				if got := names(t, lower, filepath.Join(root, "sub")); !slices.Equal(got, []string{"b.txt"}) {
+					t.Fatalf("lower sub = %v (temp files left?)", got)
+				}
+				if changes, err := w.Changes(t.Context()); err != nil || len(changes) != 0 {
+					t.Fatalf("changes after commit = %v, %v", changes, err)
+				}
+			},
+		},
+		{
+			name: "discard_restores_lower_view",
+			run: func(t *testing.T, w *Workspace, _ *vfs.MemFS, root string) {
+				t.Helper()
+				mustWrite(t, w, filepath.Join(root, "a.txt"), "changed\n")
+				if err := w.RemoveAll(filepath.Join(root, "sub")); err != nil {
+					t.Fatal(err)
+				}
+				w.Discard()
+				if got := mustRead(t, w, filepath.Join(root, "a.txt")); got != "alpha\n" {
+					t.Fatalf("a.txt after discard = %q", got)
+				}
+				if got := mustRead(t, w, filepath.Join(root, "sub", "b.txt")); got != "beta\n" {
+					t.Fatalf("b.txt after discard = %q", got)
+				}
+			},
+		},
+		{
+			name: "exclusive_create_and_temp_files",
+			run: func(t *testing.T, w *Workspace, _ *vfs.MemFS, root string) {
+				t.Helper()
+				_, err := w.OpenFile(filepath.Join(root, "a.txt"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+				if !errors.Is(err, fs.ErrExist) {
+					t.Fatalf("O_EXCL on a lower file err = %v", err)
+				}
+				f, err := w.CreateTemp(filepath.Join(root, "sub"), "tmp-*")
+				if err != nil {
+					t.Fatal(err)
+				}
+				_ = f.Close()
+				if err := w.Rename(f.Name(), filepath.Join(root, "sub", "b.txt")); err != nil {
+					t.Fatal(err)
+				}
+				if got := mustRead(t, w, filepath.Join(root, "sub", "b.txt")); got != "" {
+					t.Fatalf("b.txt = %q", got)
+				}
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			lower, root := newLower(t)
+			tc.run(t, New(lower), lower, root)
+		})
+	}
+}
+
+// symlinkFailFS is a lower layer whose Symlink always fails.
+type symlinkFailFS struct{ *vfs.MemFS }
+
+func (symlinkFailFS) Symlink(_, newname string) error {
+	return &fs.PathError{Op: "symlink", Path: newname, Err: fs.ErrPermission}
+}
+
+func TestWorkspace_CommitRollsBack(t *testing.T) {
+	t.Parallel()
+
+	mem, root := newLower(t)
+	lower := symlinkFailFS{mem}
+	w := New(lower)
+	mustWrite(t, w, filepath.Join(root, "a.txt"), "changed\n")
+	if err := w.RemoveAll(filepath.Join(root, "sub", "deep")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Remove(filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, w, filepath.Join(root, "link"), "not a link\n")
+	if err := w.Chmod(filepath.Join(root, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// Symlinks are created after every other step above, so the commit fails last.
+	if err := w.Symlink("a.txt", filepath.Join(root, "zz")); err != nil {
+		t.Fatal(err)
+	}
+	before, err := w.Changes(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.Commit(t.Context()); !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("Commit err = %v, want the injected failure", err)
+	}
+	if got := mustRead(t, mem, filepath.Join(root, "a.txt")); got != "alpha\n" {
+		t.Fatalf("lower a.txt = %q", got)
+	}
+	if got := mustRead(t, mem, filepath.Join(root, "sub", "deep", "c.txt")); got != "gamma\n" {
+		t.Fatalf("lower c.txt = %q", got)
+	}
+	if target, err := mem.Readlink(filepath.Join(root, "link")); err != nil || target != "sub" {
+		t.Fatalf("lower link = %q, %v", target, err)
+	}
+	if fi, err := mem.Stat(filepath.Join(root, "sub")); err != nil || fi.Mode().Perm() != 0o755 {
+		t.Fatalf("lower sub = %v, %v", fi, err)
+	}
+	for dir, want := range map[string][]string{
+		root:                       {"a.txt", "link", "sub"},
+		filepath.Join(root, "sub"): {"b.txt", "deep"},
+	} {
+		if got := names(t, mem, dir); !slices.Equal(got, want) {
+			t.Fatalf("lower %s = %v (backups or temp files left?)", dir, got)
+		}
+	}
+	after, err := w.Changes(t.Context())
+	if err != nil || !slices.Equal(summary(after, root), summary(before, root)) {
+		t.Fatalf("pending changes = %v, %v; want %v", summary(after, root), err, summary(before, root))
+	}
+}
+
+func TestWorkspace_OSCommitAndMaterialize(t *testing.T) {
+	t.Parallel()
+
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := filepath.Join(t.TempDir(), "view")
+	mustWrite(t, vfs.OS(), filepath.Join(root, "a.txt"), "alpha\n")
+	w := New(nil)
+
+	mustWrite(t, w, filepath.Join(root, "b.txt"), "beta\n")
+	if err := w.Materialize(t.Context(), root, view); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustRead(t, vfs.OS(), filepath.Join(view, "b.txt")); got != "beta\n" {
+		t.Fatalf("view b.txt = %q", got)
+	}
+	// A program edits the materialized view; Absorb brings its changes back as pending changes.
+	mustWrite(t, vfs.OS(), filepath.Join(view, "a.txt"), "edited\n")
+	if err := os.Remove(filepath.Join(view, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Absorb(t.Context(), root, view); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := w.Changes(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary(changes, root); !slices.Equal(got, []string{"modified a.txt"}) {
+		t.Fatalf("changes = %v", got)
+	}
+	if got := mustRead(t, vfs.OS(), filepath.Join(root, "a.txt")); got != "alpha\n" {
+		t.Fatalf("disk a.txt before commit = %q", got)
+	}
+	if _, err := w.Commit(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustRead(t, vfs.OS(), filepath.Join(root, "a.txt")); got != "edited\n" {
+		t.Fatalf("disk a.txt after commit = %q", got)
+	}
+}
+
+func TestWorkspace_Tools(t *testing.T) {
+	t.Parallel()
+
+	lower, root := newLower(t)
+	w := New(lower)
+	ft, err := fstool.NewFSTool(fstool.WithFS(w), fstool.WithWorkBaseDir(root), fstool.WithAllowedRoots([]string{root}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt, err := texttool.NewTextTool(
+		texttool.WithFS(w), texttool.WithWorkBaseDir(root), texttool.WithAllowedRoots([]string{root}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ft.WriteFile(t.Context(), fstool.WriteFileArgs{Path: "n.txt", Content: "n\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tt.ReplaceTextLines(t.Context(), texttool.ReplaceTextLinesArgs{
+		Path: "sub/b.txt", MatchLines: []string{"beta"}, ReplaceWithLines: []string{"BETA"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := w.Changes(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := summary(changes, root); !slices.Equal(got, []string{"created n.txt", "modified sub/b.txt"}) {
+		t.Fatalf("changes = %v", got)
+	}
+	if got := mustRead(t, lower, filepath.Join(root, "sub", "b.txt")); got != "beta\n" {
+		t.Fatalf("lower b.txt = %q", got)
+	}
+}
@@ -0,0 +1,195 @@
+// Package quota bounds how much the mutating tools may write in a session.
+//
+// A host creates one Quota per registry or session and passes it to fstool.WithQuota and
+// texttool.WithQuota. The tools then charge it as they write: bytes are counted as they reach the
+// disk (and refunded if the write fails), new files when they are created, and each mutating call
+// against a per-minute rate. Writes that would exceed a limit fail with ErrExceeded before the
+// offending bytes are written. Usage reports what remains.
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrExceeded is returned (wrapped) when a write or call would exceed a limit.
+var ErrExceeded = errors.New("quota exceeded")
+
+// Limits configures a Quota. Zero values mean "unlimited".
+type Limits struct {
+	// MaxBytesWritten caps the total bytes written.
+	MaxBytesWritten int64 `json:"maxBytesWritten,omitempty"`
+	// MaxFilesCreated caps the number of files created (writes to existing files do not count).
+	MaxFilesCreated int64 `json:"maxFilesCreated,omitempty"`
+	// MaxFileBytes caps the size of any file a write produces.
+	MaxFileBytes int64 `json:"maxFileBytes,omitempty"`
+	// MaxCallsPerMinute caps mutating tool calls in any sliding one-minute window.
+	MaxCallsPerMinute int `json:"maxCallsPerMinute,omitempty"`
+}
+
+// Usage reports consumption and what remains; Remaining* fields are -1 for unlimited.
+type Usage struct {
+	Limits Limits `json:"limits"`
+
+	BytesWritten    int64 `json:"bytesWritten"`
+	FilesCreated    int64 `json:"filesCreated"`
+	CallsLastMinute int   `json:"callsLastMinute"`
+
+	RemainingBytes int64 `json:"remainingBytes"`
+	RemainingFiles int64 `json:"remainingFiles"`
+	RemainingCalls int   `json:"remainingCalls"`
+}
+
+// Quota tracks usage against Limits. It is safe for concurrent use. A nil *Quota imposes no limits.
+type Quota struct {
+	mu     sync.Mutex
+	limits Limits
+	bytes  int64
+	files  int64
+	calls  []time.Time // mutating calls in the last minute, oldest first
+	now    func() time.Time
+}
+
+// New returns a Quota enforcing l.
+func New(l Limits) (*Quota, error) {
+	if l.MaxBytesWritten < 0 || l.MaxFilesCreated < 0 || l.MaxFileBytes < 0 || l.MaxCallsPerMinute < 0 {
+		return nil, errors.New("quota limits must not be negative")
+	}
+	return &Quota{limits: l, now: time.Now}, nil
+}
+
+// Limits returns the configured limits.
+func (q *Quota) Limits() Limits {
+	if q == nil {
+		return Limits{}
+	}
+	return q.limits
+}
+
+// Usage returns the current consumption.
+func (q *Quota) Usage() Usage {
+	if q == nil {
+		return Usage{RemainingBytes: -1, RemainingFiles: -1, RemainingCalls: -1}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pruneCalls()
+	u := Usage{
+		Limits:          q.limits,
+		BytesWritten:    q.bytes,
+		FilesCreated:    q.files,
+		CallsLastMinute: len(q.calls),
+		RemainingBytes:  -1,
+		RemainingFiles:  -1,
+		RemainingCalls:  -1,
+	}
+	if q.limits.MaxBytesWritten > 0 {
+		u.RemainingBytes = max(q.limits.MaxBytesWritten-q.bytes, 0)
+	}
+	if q.limits.MaxFilesCreated > 0 {
+		u.RemainingFiles = max(q.limits.MaxFilesCreated-q.files, 0)
+	}
+	if q.limits.MaxCallsPerMinute > 0 {
+		u.RemainingCalls = max(q.limits.MaxCallsPerMinute-len(q.calls), 0)
+	}
+	return u
+}
+
+// Call records a mutating tool call, failing if the per-minute rate is used up.
+func (q *Quota) Call() error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pruneCalls()
+	if q.limits.MaxCallsPerMinute > 0 && len(q.calls) >= q.limits.MaxCallsPerMinute {
+		wait := q.calls[0].Add(time.Minute).Sub(q.now()).Round(time.Second)
+		return fmt.Errorf("%w: more than %d mutating calls per minute (retry in %s)",
+			ErrExceeded, q.limits.MaxCallsPerMinute, wait)
+	}
+	q.calls = append(q.calls, q.now())
+	return nil
+}
+
+// Write records n more bytes written to a file that will then be size bytes long. Nothing is
+// recorded if either limit would be exceeded.
+func (q *Quota) Write(n, size int64) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.check(n, size); err != nil {
+		return err
+	}
+	q.bytes += n
+	return nil
+}
+
+// Check reports whether Write(n, size) would succeed, without recording anything.
+func (q *Quota) Check(n, size int64) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.check(n, size)
+}
+
+func (q *Quota) check(n, size int64) error {
+	if q.limits.MaxFileBytes > 0 && size > q.limits.MaxFileBytes {
+		return fmt.Errorf("%w: file would be %d bytes (max %d per file)", ErrExceeded, size, q.limits.MaxFileBytes)
+	}
+	if q.limits.MaxBytesWritten > 0 && q.bytes+n > q.limits.MaxBytesWritten {
+		return fmt.Errorf("%w: writing %d more bytes would exceed %d total (%d left)",
+			ErrExceeded, n, q.limits.MaxBytesWritten, q.limits.MaxBytesWritten-q.bytes)
+	}
+	return nil
+}
+
+// Refund returns n bytes recorded by Write for a write that was not committed.
+func (q *Quota) Refund(n int64) {
+	if q == nil || n <= 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.bytes = max(q.bytes-n, 0)
+}
+
+// CreateFile records a new file, failing if the file limit is used up. Callers that fail to create
+// the file after all call ReleaseFile.
+func (q *Quota) CreateFile() error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.limits.MaxFilesCreated > 0 && q.files >= q.limits.MaxFilesCreated {
+		return fmt.Errorf("%w: more than %d files created", ErrExceeded, q.limits.MaxFilesCreated)
+	}
+	q.files++
+	return nil
+}
+
+// ReleaseFile undoes a CreateFile whose file was not created.
+func (q *Quota) ReleaseFile() {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.files = max(q.files-1, 0)
+}
+
+func (q *Quota) pruneCalls() {
+	cutoff := q.now().Add(-time.Minute)
+	i := 0
+	for i < len(q.calls) && !q.calls[i].After(cutoff) {
+		i++
+	}
+	q.calls = q.calls[i:]
+}
@@ -0,0 +1,156 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	// step is one operation on the quota; a nil wantErr means it must succeed.
+	type step struct {
+		op      string // "write n size", "create", "release", "call", "advance"
+		n, size int64
+		d       time.Duration
+		wantErr error
+	}
+	tests := []struct {
+		name   string
+		limits Limits
+		steps  []step
+		want   Usage
+	}{
+		{
+			name:   "unlimited",
+			limits: Limits{},
+			steps:  []step{{op: "write", n: 1 << 30, size: 1 << 30}, {op: "create"}, {op: "call"}},
+			want: Usage{
+				BytesWritten: 1 << 30, FilesCreated: 1, CallsLastMinute: 1,
+				RemainingBytes: -1, RemainingFiles: -1, RemainingCalls: -1,
+			},
+		},
+		{
+			name:   "total bytes",
+			limits: Limits{MaxBytesWritten: 10},
+			steps: []step{
+				{op: "write", n: 6, size: 6},
+				{op: "write", n: 5, size: 5, wantErr: ErrExceeded},
+				{op: "write", n: 4, size: 4},
+			},
+			want: Usage{BytesWritten: 10, RemainingBytes: 0, RemainingFiles: -1, RemainingCalls: -1},
+		},
+		{
+			name:   "single file size",
+			limits: Limits{MaxFileBytes: 4},
+			steps: []step{
+				{op: "write", n: 1, size: 5, wantErr: ErrExceeded},
+				{op: "write", n: 4, size: 4},
+			},
+			want: Usage{BytesWritten: 4, RemainingBytes: -1, RemainingFiles: -1, RemainingCalls: -1},
+		},
+		{
+			name:   "files created",
+			limits: Limits{MaxFilesCreated: 1},
+			steps: []step{
+				{op: "create"},
+				{op: "create", wantErr: ErrExceeded},
+				{op: "release"},
+				{op: "create"},
+			},
+			want: Usage{FilesCreated: 1, RemainingBytes: -1, RemainingFiles: 0, RemainingCalls: -1},
+		},
+		{
+			name:   "calls per minute slide",
+			limits: Limits{MaxCallsPerMinute: 2},
+			steps: []step{
+				{op: "call"},
+				{op: "advance", d: 30 * time.Second},
+				{op: "call"},
+				{op: "call", wantErr: ErrExceeded},
+				{op: "advance", d: 31 * time.Second},
+				{op: "call"},
+			},
+			want: Usage{CallsLastMinute: 2, RemainingBytes: -1, RemainingFiles: -1, RemainingCalls: 0},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			q, err := New(tc.limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Unix(1_700_000_000, 0)
+			q.now = func() time.Time { return now }
+
+			for i, s := range tc.steps {
+				var err error
+				switch s.op {
+				case "write":
+					err = q.Write(s.n, s.size)
+				case "create":
+					err = q.CreateFile()
+				case "release":
+					q.ReleaseFile()
+				case "call":
+					err = q.Call()
+				case "advance":
+					now = now.Add(s.d)
+				}
+				if (s.wantErr == nil) != (err == nil) || (s.wantErr != nil && !errors.Is(err, s.wantErr)) {
+					t.Fatalf("step %d (%s): err = %v, want %v", i, s.op, err, s.wantErr)
+				}
+			}
+			want := tc.want
+			want.Limits = tc.limits
+			if got := q.Usage(); got != want {
+				t.Fatalf("Usage = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestQuota_NilAndValidation(t *testing.T) {
+	t.Parallel()
+
+	var q *Quota
+	if err := q.Write(1<<40, 1<<40); err != nil {
+		t.Fatalf("nil Write: %v", err)
+	}
+	if err := q.Call(); err != nil {
+		t.Fatalf("nil Call: %v", err)
+	}
+	if u := q.Usage(); u.RemainingBytes != -1 || u.RemainingFiles != -1 || u.RemainingCalls != -1 {
+		t.Fatalf("nil Usage = %+v", u)
+	}
+
+	if _, err := New(Limits{MaxBytesWritten: -1}); err == nil {
+		t.Fatal("expected error for negative limits")
+	}
+	q, err := New(Limits{MaxBytesWritten: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Check(4, 4); !errors.Is(err, ErrExceeded) {
+		t.Fatalf("Check err = %v", err)
+	}
+	if err := q.Check(3, 3); err != nil || q.Usage().BytesWritten != 0 {
+		t.Fatalf("Check recorded usage or failed: %v", err)
+	}
+	if err := q.Write(3, 3); err != nil {
+		t.Fatal(err)
+	}
+	q.Refund(2)
+	if u := q.Usage(); u.BytesWritten != 1 {
+		t.Fatalf("after Refund usage = %+v", u)
+	}
+	q.Refund(5)
+	if u := q.Usage(); u.BytesWritten != 0 {
+		t.Fatalf("Refund went below zero: %+v", u)
+	}
+	q = nil
+	q.Refund(1)
+}
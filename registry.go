@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/flexigpt/llmtools-go/checkpoint"
 	"github.com/flexigpt/llmtools-go/exectool"
 	"github.com/flexigpt/llmtools-go/fstool"
 	"github.com/flexigpt/llmtools-go/imagetool"
@@ -18,6 +19,7 @@ import (
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
 	"github.com/flexigpt/llmtools-go/spec"
 	"github.com/flexigpt/llmtools-go/texttool"
+	"github.com/flexigpt/llmtools-go/watch"
 )
 
 // Registry provides lookup/register for Go tools by funcID, with json.RawMessage I/O.
@@ -92,6 +94,9 @@ func RegisterBuiltins(r *Registry) error {
 	if err := RegisterOutputsTool(r, ft.ReadFileTool(), ft.ReadFile); err != nil {
 		return err
 	}
+	if err := RegisterTypedAsTextTool(r, ft.TailFileTool(), ft.TailFile); err != nil {
+		return err
+	}
 	if err := RegisterTypedAsTextTool(r, ft.SearchFilesTool(), ft.SearchFiles); err != nil {
 		return err
 	}
@@ -101,12 +106,48 @@ func RegisterBuiltins(r *Registry) error {
 	if err := RegisterTypedAsTextTool(r, ft.DeleteFileTool(), ft.DeleteFile); err != nil {
 		return err
 	}
+	if err := RegisterTypedAsTextTool(r, ft.ListTrashTool(), ft.ListTrash); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.RestoreFromTrashTool(), ft.RestoreFromTrash); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.PurgeTrashTool(), ft.PurgeTrash); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.CopyPathTool(), ft.CopyPath); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.MovePathTool(), ft.MovePath); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.CreateDirectoryTool(), ft.CreateDirectory); err != nil {
+		return err
+	}
 	if err := RegisterTypedAsTextTool(r, ft.ListDirectoryTool(), ft.ListDirectory); err != nil {
 		return err
 	}
 	if err := RegisterTypedAsTextTool(r, ft.StatPathTool(), ft.StatPath); err != nil {
 		return err
 	}
+	if err := RegisterTypedAsTextTool(r, ft.SetAttributesTool(), ft.SetAttributes); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.HashFileTool(), ft.HashFile); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.PreviewDataTool(), ft.PreviewData); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.DiffPathsTool(), ft.DiffPaths); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.ExtractArchiveTool(), ft.ExtractArchive); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, ft.CreateArchiveTool(), ft.CreateArchive); err != nil {
+		return err
+	}
 	if err := RegisterTypedAsTextTool(r, ft.MIMEForPathTool(), ft.MIMEForPath); err != nil {
 		return err
 	}
@@ -157,10 +198,41 @@ func RegisterBuiltins(r *Registry) error {
 	if err := RegisterTypedAsTextTool(r, tt.DeleteTextLinesTool(), tt.DeleteTextLines); err != nil {
 		return err
 	}
+	if err := RegisterTypedAsTextTool(r, tt.ApplyPatchTool(), tt.ApplyPatch); err != nil {
+		return err
+	}
 
 	return nil
 }
 
+// RegisterCheckpointTools registers the tools that let the model create, list and roll back to the
+// checkpoints of m. They are not builtins: the host owns m and passes it to the mutating tools with
+// fstool.WithCheckpoints and texttool.WithCheckpoints.
+func RegisterCheckpointTools(r *Registry, m *checkpoint.Manager) error {
+	if m == nil {
+		return errors.New("checkpoint manager is required")
+	}
+	if err := RegisterTypedAsTextTool(r, m.CreateCheckpointTool(), m.CreateCheckpoint); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, m.ListCheckpointsTool(), m.ListCheckpoints); err != nil {
+		return err
+	}
+	if err := RegisterTypedAsTextTool(r, m.RollbackCheckpointTool(), m.RollbackCheckpoint); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RegisterWatchTools registers the changedfiles tool reporting the changes w has seen. It is not a
+// builtin: the host starts w on a workspace subtree with watch.New and closes it when done.
+func RegisterWatchTools(r *Registry, w *watch.Watcher) error {
+	if w == nil {
+		return errors.New("watcher is required")
+	}
+	return RegisterTypedAsTextTool(r, w.ChangedFilesTool(), w.ChangedFiles)
+}
+
 // RegisterOutputsTool registers a typed tool function that directly returns []ToolOutputUnion.
 // This is a function and not a method on struct as methods cannot have type params in go.
 func RegisterOutputsTool[T any](
@@ -217,6 +289,7 @@ func (r *Registry) RegisterTool(tool spec.Tool, fn spec.ToolFunc) error {
 
 type callOptions struct {
 	timeout *time.Duration
+	callID  string
 }
 
 // CallOption configures per-call behavior.
@@ -231,6 +304,14 @@ func WithCallTimeout(d time.Duration) CallOption {
 	}
 }
 
+// WithCallID tags this call with the host's tool call ID. Checkpoint managers key the file state they
+// record for the call by it, so checkpoint.Manager.RollbackTo(id) can restore the state before it.
+func WithCallID(id string) CallOption {
+	return func(o *callOptions) {
+		o.callID = id
+	}
+}
+
 func (r *Registry) Call(
 	ctx context.Context,
 	funcID spec.FuncID,
@@ -259,9 +340,12 @@ func (r *Registry) Call(
 		}
 
 		fnCtx := ctx
+		if co.callID != "" {
+			fnCtx = checkpoint.WithCallID(fnCtx, co.callID)
+		}
 		if effectiveTimeout > 0 {
 			var cancel context.CancelFunc
-			fnCtx, cancel = context.WithTimeout(ctx, effectiveTimeout)
+			fnCtx, cancel = context.WithTimeout(fnCtx, effectiveTimeout)
 			defer cancel()
 		}
 
@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/flexigpt/llmtools-go/checkpoint"
 	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/watch"
 )
 
 func TestNewRegistry_Options(t *testing.T) {
@@ -351,6 +355,70 @@ func TestRegistry_Call_TimeoutResolution(t *testing.T) {
 	}
 }
 
+func TestRegistry_Call_CallIDAndCheckpointTools(t *testing.T) {
+	r, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry error: %v", err)
+	}
+	tool := mkTool("github.com/acme/tools.CallID", "callid")
+	fn := func(ctx context.Context, _ json.RawMessage) ([]spec.ToolOutputUnion, error) {
+		return textOut(checkpoint.CallID(ctx)), nil
+	}
+	if err := r.RegisterTool(tool, fn); err != nil {
+		t.Fatalf("RegisterTool error: %v", err)
+	}
+	out, err := r.Call(t.Context(), tool.GoImpl.FuncID, json.RawMessage(`{}`), WithCallID("toolu_1"))
+	if err != nil || len(out) != 1 || out[0].TextItem == nil || out[0].TextItem.Text != "toolu_1" {
+		t.Fatalf("Call = %#v, %v; want the call id in ctx", out, err)
+	}
+	// The call ID survives the timeout context.
+	out, err = r.Call(t.Context(), tool.GoImpl.FuncID, json.RawMessage(`{}`),
+		WithCallID("toolu_2"), WithCallTimeout(time.Minute))
+	if err != nil || len(out) != 1 || out[0].TextItem == nil || out[0].TextItem.Text != "toolu_2" {
+		t.Fatalf("Call with timeout = %#v, %v; want the call id in ctx", out, err)
+	}
+
+	if err := RegisterCheckpointTools(r, nil); err == nil {
+		t.Fatal("expected error for a nil checkpoint manager")
+	}
+	m, err := checkpoint.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("checkpoint.New error: %v", err)
+	}
+	if err := RegisterCheckpointTools(r, m); err != nil {
+		t.Fatalf("RegisterCheckpointTools error: %v", err)
+	}
+	out, err = r.Call(t.Context(), m.CreateCheckpointTool().GoImpl.FuncID, json.RawMessage(`{"label":"x"}`))
+	if err != nil || len(out) != 1 || out[0].TextItem == nil || !strings.Contains(out[0].TextItem.Text, "checkpoint-1") {
+		t.Fatalf("createcheckpoint = %#v, %v", out, err)
+	}
+}
+
+func TestRegisterWatchTools(t *testing.T) {
+	r, err := NewRegistry()
+	if err != nil {
+		t.Fatalf("NewRegistry error: %v", err)
+	}
+	if err := RegisterWatchTools(r, nil); err == nil {
+		t.Fatal("expected error for a nil watcher")
+	}
+	w, err := watch.New(t.TempDir(), watch.WithPolling())
+	if err != nil {
+		t.Fatalf("watch.New error: %v", err)
+	}
+	defer w.Close()
+	if err := RegisterWatchTools(r, w); err != nil {
+		t.Fatalf("RegisterWatchTools error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(w.Root(), "a.txt"), []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out, err := r.Call(t.Context(), w.ChangedFilesTool().GoImpl.FuncID, json.RawMessage(`{"globs":["*.txt"]}`))
+	if err != nil || len(out) != 1 || out[0].TextItem == nil || !strings.Contains(out[0].TextItem.Text, `"created"`) {
+		t.Fatalf("changedfiles = %#v, %v", out, err)
+	}
+}
+
 func TestRegisterTypedAsTextTool_StrictDecode_And_TextWrapping(t *testing.T) {
 	type args struct {
 		A int `json:"a"`
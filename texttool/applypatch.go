@@ -0,0 +1,343 @@
+package texttool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/diffutil"
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const applyPatchFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/texttool/applypatch.ApplyPatch"
+
+var applyPatchTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14f2b-04ba-7943-b7c8-39580534ef87",
+	Slug:          "applypatch",
+	Version:       "v1.0.0",
+	DisplayName:   "Apply patch",
+	Description: "Apply a unified diff (one or more files; new-file and delete-file sections allowed) to UTF-8 text files.\n" +
+		"Hunks are located near their stated line numbers; fuzz and ignoreWhitespace make matching more tolerant.\n" +
+		"All-or-nothing: if any hunk fails, no file is changed. Reports where each hunk was applied.",
+	Tags: []string{"text"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"patch": {
+		"type": "string",
+		"description": "Unified diff text (e.g. from diff -u or git diff). Paths are resolved like other path arguments; git a/ and b/ prefixes are stripped."
+	},
+	"fuzz": {
+		"type": "integer",
+		"minimum": 0,
+		"maximum": 3,
+		"default": 0,
+		"description": "Number of context lines that may be ignored at each end of a hunk that does not match exactly."
+	},
+	"ignoreWhitespace": {
+		"type": "boolean",
+		"default": false,
+		"description": "Compare lines ignoring leading/trailing whitespace and differences in runs of whitespace."
+	},
+	"dryRun": {
+		"type": "boolean",
+		"default": false,
+		"description": "Check that the patch applies and report hunk positions without changing any file."
+	}
+},
+"required": ["patch"],
+"additionalProperties": false
+}`),
+
+	GoImpl: spec.GoToolImpl{FuncID: applyPatchFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+// maxApplyPatchFiles bounds the number of files a single patch may touch.
+const maxApplyPatchFiles = 100
+
+type ApplyPatchArgs struct {
+	Patch            string `json:"patch"`
+	Fuzz             int    `json:"fuzz,omitempty"`
+	IgnoreWhitespace bool   `json:"ignoreWhitespace,omitempty"`
+	DryRun           bool   `json:"dryRun,omitempty"`
+}
+
+type ApplyPatchOperation string
+
+const (
+	ApplyPatchModify ApplyPatchOperation = "modify"
+	ApplyPatchCreate ApplyPatchOperation = "create"
+	ApplyPatchDelete ApplyPatchOperation = "delete"
+)
+
+type ApplyPatchHunk struct {
+	Hunk   int    `json:"hunk"` // 1-based within the file
+	Header string `json:"header"`
+	Line   int    `json:"line"`   // 1-based line in the patched file where the hunk's lines start
+	Offset int    `json:"offset"` // lines away from the position stated in the hunk header
+	Fuzz   int    `json:"fuzz"`   // context lines ignored at each end
+}
+
+type ApplyPatchFile struct {
+	Path      string              `json:"path"`
+	Operation ApplyPatchOperation `json:"operation"`
+	Hunks     []ApplyPatchHunk    `json:"hunks,omitempty"` // modify only
+	Version   string              `json:"version,omitempty"`
+}
+
+type ApplyPatchOut struct {
+	DryRun bool             `json:"dryRun,omitempty"`
+	Files  []ApplyPatchFile `json:"files"`
+}
+
+// patchedFile is one planned file change.
+type patchedFile struct {
+	out      ApplyPatchFile
+	data     []byte      // new content (modify/create)
+	original []byte      // content before the change, for rollback (modify/delete)
+	perm     os.FileMode // modify/delete
+	version  string      // version of the file as read (modify/delete)
+}
+
+// applyPatch applies a unified diff to one or more UTF‑8 text files.
+//
+// Behavior notes (entry point):
+//   - Every file is read and patched in memory first; if any hunk fails (or a created file already
+//     exists, or a deleted file does not match), an error listing every failure is returned and nothing
+//     is written.
+//   - Modified files keep their newline style; "\ No newline at end of file" markers are honored.
+//   - Files are committed one by one with atomic writes (deletions last). Each is re-checked against the
+//     version read in the first pass, and if a later write fails, earlier ones are rolled back best-effort.
+//   - Created files get LF newlines; up to 8 missing parent directories are created.
+//   - Renames (different old/new paths) are not supported.
+func applyPatch(ctx context.Context, args ApplyPatchArgs, p fspolicy.FSPolicy) (*ApplyPatchOut, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(args.Patch) == "" {
+		return nil, errors.New("patch is required")
+	}
+	if len(args.Patch) > toolutil.MaxTextProcessingBytes {
+		return nil, fmt.Errorf("patch too large (%d bytes; max %d)", len(args.Patch), toolutil.MaxTextProcessingBytes)
+	}
+	if args.Fuzz < 0 || args.Fuzz > diffutil.MaxFuzz {
+		return nil, fmt.Errorf("fuzz must be between 0 and %d", diffutil.MaxFuzz)
+	}
+	opts := diffutil.ApplyOptions{Fuzz: args.Fuzz, IgnoreWhitespace: args.IgnoreWhitespace}
+
+	filePatches, err := diffutil.ParseUnified(args.Patch)
+	if err != nil {
+		return nil, err
+	}
+	if len(filePatches) > maxApplyPatchFiles {
+		return nil, fmt.Errorf("patch touches too many files (%d; max %d)", len(filePatches), maxApplyPatchFiles)
+	}
+
+	var (
+		plans    []*patchedFile
+		failures []string
+		seen     = map[string]bool{}
+	)
+	for _, fp := range filePatches {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		plan, err := planFilePatch(fp, opts, p)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", fp.Path(), strings.ReplaceAll(err.Error(), "\n", "; ")))
+			continue
+		}
+		if seen[plan.out.Path] {
+			failures = append(failures, fmt.Sprintf("%s: file appears more than once in the patch", fp.Path()))
+			continue
+		}
+		seen[plan.out.Path] = true
+		plans = append(plans, plan)
+	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("patch not applied (no files were changed):\n%s", strings.Join(failures, "\n"))
+	}
+
+	out := &ApplyPatchOut{DryRun: args.DryRun, Files: make([]ApplyPatchFile, 0, len(plans))}
+	if !args.DryRun {
+		if err := commitPatchedFiles(ctx, plans, p); err != nil {
+			return nil, err
+		}
+	}
+	for _, plan := range plans {
+		out.Files = append(out.Files, plan.out)
+	}
+	return out, nil
+}
+
+func planFilePatch(fp diffutil.FilePatch, opts diffutil.ApplyOptions, p fspolicy.FSPolicy) (*patchedFile, error) {
+	if !fp.IsCreate() && !fp.IsDelete() && fp.OldPath != fp.NewPath {
+		return nil, fmt.Errorf("renames are not supported (%s -> %s)", fp.OldPath, fp.NewPath)
+	}
+	abs, err := p.ResolvePath(fp.Path(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	if fp.IsCreate() {
+		if _, err := p.FS().Lstat(abs); err == nil {
+			return nil, errors.New("patch creates the file but it already exists")
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		lines, final, err := diffutil.NewFileContent(fp)
+		if err != nil {
+			return nil, err
+		}
+		tf := ioutil.TextFile{Newline: ioutil.NewlineLF, HasFinalNewline: final, Lines: lines}
+		return &patchedFile{
+			out:  ApplyPatchFile{Path: abs, Operation: ApplyPatchCreate},
+			data: []byte(tf.Render()),
+		}, nil
+	}
+
+	tf, err := ioutil.ReadTextFileUTF8(p, abs, toolutil.MaxTextProcessingBytes)
+	if err != nil {
+		return nil, err
+	}
+	original, err := vfs.ReadFile(p.FS(), tf.Path)
+	if err != nil {
+		return nil, err
+	}
+	plan := &patchedFile{
+		out:      ApplyPatchFile{Path: tf.Path},
+		original: original,
+		perm:     tf.Perm,
+		version:  tf.Version,
+	}
+
+	if fp.IsDelete() {
+		if err := diffutil.VerifyDeletedContent(fp, tf.Lines, opts); err != nil {
+			return nil, err
+		}
+		plan.out.Operation = ApplyPatchDelete
+		return plan, nil
+	}
+
+	lines, final, results, errs := diffutil.ApplyHunks(tf.Lines, tf.HasFinalNewline, fp.Hunks, opts)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	tf.Lines, tf.HasFinalNewline = lines, final
+	if plan.data, err = tf.Encode(); err != nil {
+		return nil, err
+	}
+	if len(plan.data) > toolutil.MaxTextProcessingBytes {
+		return nil, fmt.Errorf("patched file too large (%d bytes; max %d)", len(plan.data), toolutil.MaxTextProcessingBytes)
+	}
+	plan.out.Operation = ApplyPatchModify
+	for _, r := range results {
+		plan.out.Hunks = append(plan.out.Hunks, ApplyPatchHunk{
+			Hunk:   r.Hunk,
+			Header: r.Header,
+			Line:   r.Line,
+			Offset: r.Offset,
+			Fuzz:   r.Fuzz,
+		})
+	}
+	return plan, nil
+}
+
+// commitPatchedFiles writes all planned changes, deletions last, rolling back on failure.
+func commitPatchedFiles(ctx context.Context, plans []*patchedFile, p fspolicy.FSPolicy) error {
+	for _, plan := range plans {
+		if plan.out.Operation != ApplyPatchCreate {
+			if err := ioutil.CheckFileVersion(ctx, p.FS(), plan.out.Path, plan.version); err != nil {
+				return fmt.Errorf("patch not applied (no files were changed): %w", err)
+			}
+		}
+	}
+
+	ordered := make([]*patchedFile, 0, len(plans))
+	for _, plan := range plans {
+		if plan.out.Operation != ApplyPatchDelete {
+			ordered = append(ordered, plan)
+		}
+	}
+	for _, plan := range plans {
+		if plan.out.Operation == ApplyPatchDelete {
+			ordered = append(ordered, plan)
+		}
+	}
+
+	for i, plan := range ordered {
+		if err := commitPatchedFile(plan, p); err != nil {
+			rbErr := rollbackPatchedFiles(ordered[:i], p)
+			if rbErr != nil {
+				return fmt.Errorf("writing %s failed: %w; rollback of earlier files failed: %w", plan.out.Path, err, rbErr)
+			}
+			return fmt.Errorf("writing %s failed (earlier files were rolled back): %w", plan.out.Path, err)
+		}
+	}
+	return nil
+}
+
+func commitPatchedFile(plan *patchedFile, p fspolicy.FSPolicy) error {
+	abs := plan.out.Path
+	switch plan.out.Operation {
+	case ApplyPatchDelete:
+		return p.FS().Remove(abs)
+	case ApplyPatchCreate:
+		if _, err := p.EnsureDirResolved(filepath.Dir(abs), 8); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFileAtomicBytesResolved(p, abs, plan.data, 0o600, false); err != nil {
+			return err
+		}
+	default:
+		if err := ioutil.WriteFileAtomicBytesResolved(p, abs, plan.data, plan.perm, true); err != nil {
+			return err
+		}
+	}
+	version, err := ioutil.WrittenFileVersion(p.FS(), abs, plan.data)
+	if err != nil {
+		return err
+	}
+	plan.out.Version = version
+	return nil
+}
+
+// rollbackPatchedFiles undoes already committed files. Restores are not metered, so a quota that ran
+// out part way through the commit cannot also block the rollback; the undone writes are refunded.
+func rollbackPatchedFiles(done []*patchedFile, p fspolicy.FSPolicy) error {
+	q := p.Quota()
+	unmetered := p.WithQuota(nil)
+	var errs []error
+	for i := len(done) - 1; i >= 0; i-- {
+		plan := done[i]
+		var err error
+		if plan.out.Operation == ApplyPatchCreate {
+			if err = p.FS().Remove(plan.out.Path); err == nil {
+				q.ReleaseFile()
+				q.Refund(int64(len(plan.data)))
+			}
+		} else {
+			err = ioutil.WriteFileAtomicBytesResolved(unmetered, plan.out.Path, plan.original, plan.perm, true)
+			if err == nil && plan.out.Operation == ApplyPatchModify {
+				q.Refund(int64(len(plan.data)))
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,205 @@
+package texttool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+)
+
+func TestApplyPatch(t *testing.T) {
+	type files map[string]string
+
+	tests := []struct {
+		name      string
+		initial   files
+		args      ApplyPatchArgs
+		ctx       func() context.Context
+		wantErr   string
+		wantIs    error
+		wantFiles files // expected content after; "" value means the file must not exist
+		check     func(t *testing.T, out *ApplyPatchOut)
+	}{
+		{
+			name:    "multi_file_modify_create_delete",
+			initial: files{"a.txt": "one\r\ntwo\r\nthree\r\n", "gone.txt": "bye\n"},
+			args: ApplyPatchArgs{Patch: "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+TWO\n three\n" +
+				"--- /dev/null\n+++ b/sub/new.txt\n@@ -0,0 +1,2 @@\n+hello\n+world\n" +
+				"--- a/gone.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"},
+			wantFiles: files{"a.txt": "one\r\nTWO\r\nthree\r\n", "sub/new.txt": "hello\nworld\n", "gone.txt": ""},
+			check: func(t *testing.T, out *ApplyPatchOut) {
+				t.Helper()
+				if len(out.Files) != 3 || out.Files[0].Operation != ApplyPatchModify ||
+					out.Files[1].Operation != ApplyPatchCreate || out.Files[2].Operation != ApplyPatchDelete {
+					t.Fatalf("unexpected files: %+v", out.Files)
+				}
+				if h := out.Files[0].Hunks; len(h) != 1 || h[0].Line != 1 || h[0].Offset != 0 {
+					t.Fatalf("unexpected hunks: %+v", h)
+				}
+				if out.Files[0].Version == "" || out.Files[2].Version != "" {
+					t.Fatalf("unexpected versions: %+v", out.Files)
+				}
+			},
+		},
+		{
+			name:      "offset_and_no_final_newline",
+			initial:   files{"f.txt": "x\ny\na\nb"},
+			args:      ApplyPatchArgs{Patch: "--- f.txt\n+++ f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n"},
+			wantFiles: files{"f.txt": "x\ny\na\nc\n"},
+			check: func(t *testing.T, out *ApplyPatchOut) {
+				t.Helper()
+				if h := out.Files[0].Hunks; h[0].Line != 3 || h[0].Offset != 2 {
+					t.Fatalf("unexpected hunks: %+v", h)
+				}
+			},
+		},
+		{
+			name:      "all_or_nothing_when_one_file_fails",
+			initial:   files{"a.txt": "a\n", "b.txt": "b\n"},
+			args:      ApplyPatchArgs{Patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-zzz\n+B\n"},
+			wantErr:   "b.txt: hunk 1 (@@ -1 +1 @@): context not found",
+			wantFiles: files{"a.txt": "a\n", "b.txt": "b\n"},
+		},
+		{
+			name:      "fuzz_and_whitespace_options",
+			initial:   files{"f.txt": "func f() {\n\treturn  1\n}\n"},
+			args:      ApplyPatchArgs{Patch: "--- f.txt\n+++ f.txt\n@@ -1,3 +1,3 @@\n func g() {\n-    return 1\n+\treturn 2\n }\n", Fuzz: 1, IgnoreWhitespace: true},
+			wantFiles: files{"f.txt": "func f() {\n\treturn 2\n}\n"},
+			check: func(t *testing.T, out *ApplyPatchOut) {
+				t.Helper()
+				if out.Files[0].Hunks[0].Fuzz != 1 {
+					t.Fatalf("expected fuzz 1, got %+v", out.Files[0].Hunks)
+				}
+			},
+		},
+		{
+			name:      "dry_run_changes_nothing",
+			initial:   files{"a.txt": "a\n"},
+			args:      ApplyPatchArgs{Patch: "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-a\n+A\n", DryRun: true},
+			wantFiles: files{"a.txt": "a\n"},
+			check: func(t *testing.T, out *ApplyPatchOut) {
+				t.Helper()
+				if !out.DryRun || len(out.Files) != 1 || out.Files[0].Version != "" {
+					t.Fatalf("unexpected out: %+v", out)
+				}
+			},
+		},
+		{
+			name:      "create_existing_file_errors",
+			initial:   files{"n.txt": "already\n"},
+			args:      ApplyPatchArgs{Patch: "--- /dev/null\n+++ b/n.txt\n@@ -0,0 +1 @@\n+x\n"},
+			wantErr:   "already exists",
+			wantFiles: files{"n.txt": "already\n"},
+		},
+		{
+			name:      "delete_with_mismatched_content_errors",
+			initial:   files{"d.txt": "changed\n"},
+			args:      ApplyPatchArgs{Patch: "--- a/d.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-original\n"},
+			wantErr:   "refusing to delete",
+			wantFiles: files{"d.txt": "changed\n"},
+		},
+		{
+			name:    "rename_unsupported",
+			initial: files{"a.txt": "a\n"},
+			args:    ApplyPatchArgs{Patch: "--- a/a.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-a\n+b\n"},
+			wantErr: "renames are not supported",
+		},
+		{
+			name:    "fuzz_out_of_range_errors",
+			args:    ApplyPatchArgs{Patch: "--- a/a\n+++ b/a\n@@ -1 +1 @@\n-a\n+b\n", Fuzz: 9},
+			wantErr: "fuzz must be between",
+		},
+		{
+			name:    "empty_patch_errors",
+			args:    ApplyPatchArgs{Patch: "  "},
+			wantErr: "patch is required",
+		},
+		{
+			name: "context_canceled",
+			args: ApplyPatchArgs{Patch: "--- a/a\n+++ b/a\n@@ -1 +1 @@\n-a\n+b\n"},
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			wantIs: context.Canceled,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := newWorkDir(t)
+			policy, err := fspolicy.New(dir, []string{dir}, true)
+			if err != nil {
+				t.Fatalf("policy: %v", err)
+			}
+			for name, content := range tc.initial {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			}
+			ctx := t.Context()
+			if tc.ctx != nil {
+				ctx = tc.ctx()
+			}
+
+			out, err := applyPatch(ctx, tc.args, policy)
+			switch {
+			case tc.wantIs != nil:
+				if !errors.Is(err, tc.wantIs) {
+					t.Fatalf("err=%v want %v", err, tc.wantIs)
+				}
+			case tc.wantErr != "":
+				mustErrContains(t, err, tc.wantErr)
+			default:
+				mustNoErr(t, err)
+				if tc.check != nil {
+					tc.check(t, out)
+				}
+			}
+
+			for name, want := range tc.wantFiles {
+				got, err := os.ReadFile(filepath.Join(dir, name))
+				if want == "" {
+					if !errors.Is(err, os.ErrNotExist) {
+						t.Fatalf("%s: expected file to be gone, err=%v", name, err)
+					}
+					continue
+				}
+				if err != nil || string(got) != want {
+					t.Fatalf("%s: content=%q err=%v want %q", name, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPatchStaleFileIsNotOverwritten(t *testing.T) {
+	// Simulates a concurrent edit between planning and committing by patching a file whose
+	// version no longer matches: commitPatchedFiles must refuse before writing anything.
+	dir := newWorkDir(t)
+	policy, err := fspolicy.New(dir, nil, true)
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("a\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	plan := &patchedFile{
+		out:     ApplyPatchFile{Path: path, Operation: ApplyPatchModify},
+		data:    []byte("A\n"),
+		version: "sha256:" + strings.Repeat("0", 64) + "@0",
+	}
+	err = commitPatchedFiles(t.Context(), []*patchedFile{plan}, policy)
+	if !errors.Is(err, ErrStaleVersion) {
+		t.Fatalf("err=%v want ErrStaleVersion", err)
+	}
+	if got := readFileString(t, path); got != "a\n" {
+		t.Fatalf("content=%q", got)
+	}
+}
@@ -28,7 +28,7 @@ var deleteTextLinesTool = spec.Tool{
 "properties": {
 	"path": {
 		"type": "string",
-		"description": "Path of the UTF-8 text file."
+		"description": "Path of the text file (UTF-8, UTF-16 or a detected legacy encoding)."
 	},
 	"matchLines": {
 		"type": "array",
@@ -53,6 +53,10 @@ var deleteTextLinesTool = spec.Tool{
 		"minimum": 1,
 		"default": 1,
 		"description": "Fail if the number of matched blocks deleted != this value."
+	},
+	"expectedVersion": {
+		"type": "string",
+		"description": "Version token from an earlier read (readtextrange, findtext, readfile, statpath). If set and the file has changed since, the edit fails with a stale-version error."
 	}
 },
 "required": ["path", "matchLines"],
@@ -71,18 +75,22 @@ type DeleteTextLinesArgs struct {
 	BeforeLines       []string `json:"beforeLines,omitempty"`
 	AfterLines        []string `json:"afterLines,omitempty"`
 	ExpectedDeletions int      `json:"expectedDeletions,omitempty"` // default 1
+
+	ExpectedVersion string `json:"expectedVersion,omitempty"`
 }
 
 type DeleteTextLinesOut struct {
-	DeletionsMade  int   `json:"deletionsMade"`
-	DeletedAtLines []int `json:"deletedAtLines"` // 1-based start line of each deleted block
+	DeletionsMade  int    `json:"deletionsMade"`
+	DeletedAtLines []int  `json:"deletedAtLines"`    // 1-based start line of each deleted block
+	Version        string `json:"version,omitempty"` // version token of the file after the edit
 }
 
 // deleteTextLines deletes occurrences of MatchLines from a UTF‑8 file.
 // Behavior notes (entry point):
-//   - The file must exist, be a regular file, not a symlink, and be valid UTF‑8.
+//   - The file must exist, be a regular file, not a symlink, and be decodable text (see ioutil.DecodeText).
 //   - Matching is line-wise using strings.TrimSpace on each line.
 //   - If ExpectedDeletions is set, the tool fails unless exactly that many deletions would be made.
+//   - If expectedVersion is set and the file no longer matches it, fails with ioutil.ErrStaleVersion.
 //   - Writes are atomic (temp file + fsync + rename) and preserve newline style and final newline.
 func deleteTextLines(
 	ctx context.Context,
@@ -109,6 +117,9 @@ func deleteTextLines(
 	if err != nil {
 		return nil, err
 	}
+	if err := tf.CheckVersion(args.ExpectedVersion); err != nil {
+		return nil, err
+	}
 
 	matchIdxs := ioutil.FindTrimmedAdjacentBlockMatches(tf.Lines, beforeLines, matchLines, afterLines)
 	if err := ioutil.EnsureNonOverlappingFixedWidth(matchIdxs, len(matchLines)); err != nil {
@@ -122,6 +133,7 @@ func deleteTextLines(
 		)
 	}
 
+	version := tf.Version
 	changed := len(matchIdxs) > 0
 	if changed {
 		// Delete from the end so earlier indices remain valid.
@@ -136,8 +148,15 @@ func deleteTextLines(
 		}
 
 		// Preserve final newline behavior.
-		outStr := tf.Render()
-		if err := ioutil.WriteFileAtomicBytesResolved(p, tf.Path, []byte(outStr), tf.Perm, true); err != nil {
+		data, err := tf.Encode()
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFileAtomicBytesResolved(p, tf.Path, data, tf.Perm, true); err != nil {
+			return nil, err
+		}
+		version, err = ioutil.WrittenFileVersion(p.FS(), tf.Path, data)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -150,5 +169,6 @@ func deleteTextLines(
 	return &DeleteTextLinesOut{
 		DeletionsMade:  len(matchIdxs),
 		DeletedAtLines: deletedAt,
+		Version:        version,
 	}, nil
 }
@@ -30,7 +30,7 @@ var findTextTool = spec.Tool{
 "properties": {
 	"path": {
 		"type": "string",
-		"description": "Path of the UTF-8 text file."
+		"description": "Path of the text file (UTF-8, UTF-16 or a detected legacy encoding)."
 	},
 	"queryType": {
 		"type": "string",
@@ -132,15 +132,17 @@ type FindTextOut struct {
 	ReachedMaxMatches bool            `json:"reachedMaxMatches"`
 	MatchesReturned   int             `json:"matchesReturned"`
 	Matches           []FindTextMatch `json:"matches"`
+	Version           string          `json:"version"` // pass as expectedVersion to edit tools
 }
 
 // findText finds occurrences and returns matches with context.
 // Behavior notes (entry point):
-//   - File must exist, be regular, not a symlink, and valid UTF‑8.
+//   - File must exist, be regular, not a symlink, and decodable text (UTF‑8, UTF‑16 or a detected legacy encoding).
 //   - Matching uses TrimSpace per line for both file and input blocks.
 //   - Returned lines are original file lines (not trimmed).
 //   - Deterministic: matches are returned in ascending file order up to maxMatches.
 //   - For queryType=lineBlock, overlapping matches are rejected.
+//   - Version identifies the file content that was searched (see ioutil.ContentVersion).
 func findText(ctx context.Context, args FindTextArgs, p fspolicy.FSPolicy) (*FindTextOut, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
@@ -177,7 +179,7 @@ func findText(ctx context.Context, args FindTextArgs, p fspolicy.FSPolicy) (*Fin
 
 	// Empty file: deterministic empty output.
 	if total == 0 {
-		return &FindTextOut{Matches: nil, ReachedMaxMatches: false, MatchesReturned: 0}, nil
+		return &FindTextOut{Matches: nil, ReachedMaxMatches: false, MatchesReturned: 0, Version: tf.Version}, nil
 	}
 
 	var (
@@ -223,6 +225,7 @@ func findText(ctx context.Context, args FindTextArgs, p fspolicy.FSPolicy) (*Fin
 
 	out := &FindTextOut{
 		Matches: make([]FindTextMatch, 0, min(maxMatches, 16)),
+		Version: tf.Version,
 	}
 
 	// Helper to enforce rough output bound.
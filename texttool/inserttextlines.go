@@ -32,7 +32,7 @@ var insertTextLinesTool = spec.Tool{
 "properties": {
 	"path": {
 		"type": "string",
-		"description": "Path of the UTF-8 text file."
+		"description": "Path of the text file (UTF-8, UTF-16 or a detected legacy encoding)."
 	},
 	"position": {
 		"type": "string",
@@ -51,6 +51,10 @@ var insertTextLinesTool = spec.Tool{
 		"items": { "type": "string" },
 		"minItems": 1,
 		"description": "Anchor block to match (TrimSpace comparison). Required for position=beforeAnchor/afterAnchor and must match exactly once."
+	},
+	"expectedVersion": {
+		"type": "string",
+		"description": "Version token from an earlier read (readtextrange, findtext, readfile, statpath). If set and the file has changed since, the edit fails with a stale-version error."
 	}
 },
 "required": ["path", "linesToInsert"],
@@ -85,20 +89,24 @@ type InsertTextLinesArgs struct {
 	Position         string   `json:"position,omitempty"` // default "end"
 	LinesToInsert    []string `json:"linesToInsert"`
 	AnchorMatchLines []string `json:"anchorMatchLines,omitempty"`
+
+	ExpectedVersion string `json:"expectedVersion,omitempty"`
 }
 
 type InsertTextLinesOut struct {
-	InsertedAtLine      int  `json:"insertedAtLine"` // 1-based, where insertion begins
-	InsertedLineCount   int  `json:"insertedLineCount"`
-	AnchorMatchedAtLine *int `json:"anchorMatchedAtLine,omitempty"` // 1-based start line of anchor block
+	InsertedAtLine      int    `json:"insertedAtLine"` // 1-based, where insertion begins
+	InsertedLineCount   int    `json:"insertedLineCount"`
+	AnchorMatchedAtLine *int   `json:"anchorMatchedAtLine,omitempty"` // 1-based start line of anchor block
+	Version             string `json:"version,omitempty"`             // version token of the file after the edit
 }
 
 // insertTextLines inserts LinesToInsert into a UTF‑8 file.
 // Behavior notes (entry point):
-//   - File must exist, be regular, not a symlink, and valid UTF‑8.
+//   - File must exist, be regular, not a symlink, and decodable text (UTF‑8, UTF‑16 or a detected legacy encoding).
 //   - Matching is line-wise using strings.TrimSpace.
 //   - For beforeAnchor/afterAnchor: the anchor block must match exactly once; otherwise it fails.
-//   - Writes are atomic and preserve newline style and final newline presence.
+//   - If expectedVersion is set and the file no longer matches it, fails with ioutil.ErrStaleVersion.
+//   - Writes are atomic and preserve newline style, final newline presence and the file encoding.
 func insertTextLines(
 	ctx context.Context,
 	args InsertTextLinesArgs,
@@ -135,6 +143,9 @@ func insertTextLines(
 	if err != nil {
 		return nil, err
 	}
+	if err := tf.CheckVersion(args.ExpectedVersion); err != nil {
+		return nil, err
+	}
 
 	insertAt, anchorAt, err := computeInsertIndex(tf.Lines, pos, anchorLines)
 	if err != nil {
@@ -146,8 +157,15 @@ func insertTextLines(
 
 	tf.Lines = insertLines(tf.Lines, insertAt, linesToInsert)
 
-	outStr := tf.Render()
-	if err := ioutil.WriteFileAtomicBytesResolved(p, tf.Path, []byte(outStr), tf.Perm, true); err != nil {
+	data, err := tf.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFileAtomicBytesResolved(p, tf.Path, data, tf.Perm, true); err != nil {
+		return nil, err
+	}
+	version, err := ioutil.WrittenFileVersion(p.FS(), tf.Path, data)
+	if err != nil {
 		return nil, err
 	}
 
@@ -155,6 +173,7 @@ func insertTextLines(
 		InsertedAtLine:      insertAt + 1,
 		InsertedLineCount:   len(linesToInsert),
 		AnchorMatchedAtLine: anchorAt,
+		Version:             version,
 	}, nil
 }
 
@@ -30,7 +30,7 @@ var readTextRangeTool = spec.Tool{
 "properties": {
 "path": {
 	"type": "string",
-	"description": "Path of the UTF-8 text file."
+	"description": "Path of the text file (UTF-8, UTF-16 or a detected legacy encoding)."
 },
 "startMatchLines": {
 	"type": "array",
@@ -76,18 +76,20 @@ type ReadTextRangeOut struct {
 	EndLine       int                 `json:"endLine,omitempty"`   // 1-based
 	LinesReturned int                 `json:"linesReturned"`
 	Lines         []ReadTextRangeLine `json:"lines"`
+	Version       string              `json:"version"` // pass as expectedVersion to edit tools
 }
 
 // readTextRange reads a UTF‑8 file and returns a bounded range of lines.
 //
 // Behavior notes (entry point):
-//   - File must exist, be regular, not a symlink, and valid UTF‑8.
+//   - File must exist, be regular, not a symlink, and decodable text (UTF‑8, UTF‑16 or a detected legacy encoding).
 //   - Matching uses TrimSpace comparisons (for file + provided blocks).
 //   - Deterministic / no ambiguity:
 //   - startMatchLines (if provided) must match exactly once.
 //   - endMatchLines (if provided) must match exactly once.
 //   - if both are provided, end must occur after start block (non-overlapping).
 //   - If the selected range exceeds maxReadTextRangeOutputLines, the tool fails.
+//   - Version identifies the whole file as read, not just the returned range.
 func readTextRange(
 	ctx context.Context,
 	args ReadTextRangeArgs,
@@ -109,6 +111,7 @@ func readTextRange(
 		return &ReadTextRangeOut{
 			Lines:         nil,
 			LinesReturned: 0,
+			Version:       tf.Version,
 		}, nil
 	}
 
@@ -189,5 +192,6 @@ func readTextRange(
 		EndLine:       selEnd + 1,
 		LinesReturned: len(outLines),
 		Lines:         outLines,
+		Version:       tf.Version,
 	}, nil
 }
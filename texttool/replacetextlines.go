@@ -29,7 +29,7 @@ var replaceTextLinesTool = spec.Tool{
 "properties": {
 	"path": {
 		"type": "string",
-		"description": "Path of the UTF-8 text file."
+		"description": "Path of the text file (UTF-8, UTF-16 or a detected legacy encoding)."
 	},
 	"matchLines": {
 		"type": "array",
@@ -58,6 +58,10 @@ var replaceTextLinesTool = spec.Tool{
 		"minimum": 1,
 		"default": 1,
 		"description": "Fail if replacements made != this value."
+	},
+	"expectedVersion": {
+		"type": "string",
+		"description": "Version token from an earlier read (readtextrange, findtext, readfile, statpath). If set and the file has changed since, the edit fails with a stale-version error."
 	}
 },
 "required": ["path", "matchLines", "replaceWithLines"],
@@ -84,22 +88,26 @@ type ReplaceTextLinesArgs struct {
 
 	// Pointer is used so we can distinguish "omitted" (default to 1) from "explicit 0" (error).
 	ExpectedReplacements *int `json:"expectedReplacements,omitempty"` // default 1; minimum 1
+
+	ExpectedVersion string `json:"expectedVersion,omitempty"`
 }
 
 type ReplaceTextLinesOut struct {
-	ReplacementsMade int   `json:"replacementsMade"`
-	ReplacedAtLines  []int `json:"replacedAtLines"` // 1-based start line of each replacement
+	ReplacementsMade int    `json:"replacementsMade"`
+	ReplacedAtLines  []int  `json:"replacedAtLines"`   // 1-based start line of each replacement
+	Version          string `json:"version,omitempty"` // version token of the file after the edit
 }
 
 // replaceTextLines replaces occurrences of MatchLines in a UTF‑8 file.
 //
 // Behavior notes (entry point):
-//   - File must exist, be regular, not a symlink, and valid UTF‑8.
+//   - File must exist, be regular, not a symlink, and decodable text (UTF‑8, UTF‑16 or a detected legacy encoding).
 //   - Matching is line-wise using TrimSpace comparisons (for both file + input blocks).
 //   - Returned/inserted lines are written exactly as provided (no trimming).
 //   - Deterministic / no ambiguity: fails unless match count == expectedReplacements (default 1, minimum 1).
 //   - Deletion is not supported here; use deletetextlines.
-//   - Writes are atomic and preserve newline style and final newline presence.
+//   - If expectedVersion is set and the file no longer matches it, fails with ioutil.ErrStaleVersion.
+//   - Writes are atomic and preserve newline style, final newline presence and the file encoding.
 func replaceTextLines(
 	ctx context.Context,
 	args ReplaceTextLinesArgs,
@@ -138,6 +146,9 @@ func replaceTextLines(
 	if err != nil {
 		return nil, err
 	}
+	if err := tf.CheckVersion(args.ExpectedVersion); err != nil {
+		return nil, err
+	}
 
 	matchIdxs := ioutil.FindTrimmedAdjacentBlockMatches(tf.Lines, beforeLines, matchLines, afterLines)
 	// Overlap guard: overlapping matches make replacements ambiguous.
@@ -163,8 +174,15 @@ func replaceTextLines(
 		tf.Lines = replaceLinesSlice(tf.Lines, start, end, replaceWith)
 	}
 
-	outStr := tf.Render()
-	if err := ioutil.WriteFileAtomicBytesResolved(p, tf.Path, []byte(outStr), tf.Perm, true); err != nil {
+	data, err := tf.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFileAtomicBytesResolved(p, tf.Path, data, tf.Perm, true); err != nil {
+		return nil, err
+	}
+	version, err := ioutil.WrittenFileVersion(p.FS(), tf.Path, data)
+	if err != nil {
 		return nil, err
 	}
 
@@ -176,6 +194,7 @@ func replaceTextLines(
 	return &ReplaceTextLinesOut{
 		ReplacementsMade: len(matchIdxs),
 		ReplacedAtLines:  replacedAt,
+		Version:          version,
 	}, nil
 }
 
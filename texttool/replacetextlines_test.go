@@ -3,6 +3,7 @@ package texttool
 import (
 	"context"
 	"errors"
+	"os"
 	"testing"
 
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
@@ -314,3 +315,87 @@ func TestReplaceTextLines_ErrorCases(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceTextLines_ExpectedVersion(t *testing.T) {
+	dir := newWorkDir(t)
+	policy, err := fspolicy.New("", nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name        string
+		mutate      func(t *testing.T, path string)
+		wantStale   bool
+		wantContent string
+	}{
+		{name: "unchanged_file_is_edited", wantContent: "A\nX\nC\n"},
+		{
+			name: "concurrent_change_is_stale",
+			mutate: func(t *testing.T, path string) {
+				t.Helper()
+				if err := os.WriteFile(path, []byte("A\nB\nC\nD\n"), 0o600); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			},
+			wantStale:   true,
+			wantContent: "A\nB\nC\nD\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeTempTextFile(t, dir, "v-*.txt", "A\nB\nC\n")
+			read, err := readTextRange(t.Context(), ReadTextRangeArgs{Path: path}, policy)
+			if err != nil {
+				t.Fatalf("readTextRange: %v", err)
+			}
+			if tc.mutate != nil {
+				tc.mutate(t, path)
+			}
+			out, err := replaceTextLines(t.Context(), ReplaceTextLinesArgs{
+				Path:             path,
+				MatchLines:       []string{"B"},
+				ReplaceWithLines: []string{"X"},
+				ExpectedVersion:  read.Version,
+			}, policy)
+			if tc.wantStale {
+				if !errors.Is(err, ErrStaleVersion) {
+					t.Fatalf("err=%v want ErrStaleVersion", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				again, err := findText(t.Context(), FindTextArgs{Path: path, Query: "X"}, policy)
+				if err != nil {
+					t.Fatalf("findText: %v", err)
+				}
+				if out.Version == "" || out.Version != again.Version || out.Version == read.Version {
+					t.Fatalf("version after edit=%q, reread=%q, before=%q", out.Version, again.Version, read.Version)
+				}
+			}
+			if got := readFileString(t, path); got != tc.wantContent {
+				t.Fatalf("content=%q want %q", got, tc.wantContent)
+			}
+		})
+	}
+}
+
+func TestReplaceTextLines_PreservesEncoding(t *testing.T) {
+	dir := newWorkDir(t)
+	policy, err := fspolicy.New("", nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// UTF-16LE with BOM, CRLF line endings: "A\r\nB\r\n".
+	path := writeTempBytesFile(t, dir, "u16-*.txt", []byte("\xff\xfeA\x00\r\x00\n\x00B\x00\r\x00\n\x00"))
+	_, err = replaceTextLines(t.Context(), ReplaceTextLinesArgs{
+		Path:             path,
+		MatchLines:       []string{"B"},
+		ReplaceWithLines: []string{"é"},
+	}, policy)
+	mustNoErr(t, err)
+	want := "\xff\xfeA\x00\r\x00\n\x00\xe9\x00\r\x00\n\x00"
+	if got := readFileString(t, path); got != want {
+		t.Fatalf("content=%q want %q", got, want)
+	}
+}
@@ -3,16 +3,33 @@ package texttool
 import (
 	"context"
 	"sync"
+	"time"
 
+	"github.com/flexigpt/llmtools-go/checkpoint"
+	"github.com/flexigpt/llmtools-go/internal/diffutil"
+	"github.com/flexigpt/llmtools-go/internal/filelock"
 	"github.com/flexigpt/llmtools-go/internal/fspolicy"
+	"github.com/flexigpt/llmtools-go/internal/ioutil"
+	"github.com/flexigpt/llmtools-go/internal/mutation"
 	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/quota"
 	"github.com/flexigpt/llmtools-go/spec"
+	"github.com/flexigpt/llmtools-go/vfs"
 )
 
+// ErrStaleVersion is returned (wrapped) by mutating tools when expectedVersion no longer matches the file.
+var ErrStaleVersion = ioutil.ErrStaleVersion
+
+// ErrLockTimeout is returned (wrapped) by mutating tools when another call holds a path for longer
+// than the lock timeout.
+var ErrLockTimeout = filelock.ErrTimeout
+
 type textToolConfig struct {
+	fsys          vfs.FS
 	allowedRoots  []string
 	workBaseDir   string
 	blockSymlinks bool
+	mutation      mutation.Config
 }
 
 // TextTool is an instance-owned text tool runner.
@@ -42,6 +59,16 @@ func WithWorkBaseDir(base string) TextToolOption {
 	}
 }
 
+// WithFS sets the filesystem the tools operate on (default: the OS filesystem), e.g. a vfs.MemFS.
+// Paths stay native absolute paths; with a non-OS filesystem, WithWorkBaseDir or WithAllowedRoots
+// must name an absolute directory that exists in it.
+func WithFS(fsys vfs.FS) TextToolOption {
+	return func(tt *TextTool) error {
+		tt.cfg.fsys = fsys
+		return nil
+	}
+}
+
 // WithBlockSymlinks configures whether symlink traversal should be blocked (if supported downstream).
 func WithBlockSymlinks(block bool) TextToolOption {
 	return func(tt *TextTool) error {
@@ -50,12 +77,52 @@ func WithBlockSymlinks(block bool) TextToolOption {
 	}
 }
 
+// WithLockTimeout bounds how long a mutating tool waits for another call editing the same path
+// (default filelock.DefaultTimeout, 30s); d <= 0 waits until the call's context is done.
+func WithLockTimeout(d time.Duration) TextToolOption {
+	return func(tt *TextTool) error {
+		tt.cfg.mutation.Locks.Timeout = d
+		return nil
+	}
+}
+
+// WithOSFileLocks also takes OS advisory locks (flock, LockFileEx) around each read-modify-write, so
+// tools in other processes configured with the same lock directory are excluded too. Lock files are
+// kept in dir, or in llmtools-go-locks under the OS temp dir if dir is empty. Only the OS filesystem
+// is locked this way; in-process locking always applies.
+func WithOSFileLocks(dir string) TextToolOption {
+	return func(tt *TextTool) error {
+		return tt.cfg.mutation.SetOSLocks(dir)
+	}
+}
+
+// WithQuota charges the writes of the text edit tools and applypatch to q, which bounds the bytes
+// written, files created, single-file size and mutating calls per minute. Share one Quota between
+// the tools of a session; q.Usage reports what remains.
+func WithQuota(q *quota.Quota) TextToolOption {
+	return func(tt *TextTool) error {
+		tt.cfg.mutation.Quota = q
+		return nil
+	}
+}
+
+// WithCheckpoints records the prior state of the files the edit tools and applypatch change in m, so
+// the host (or the model, via the checkpoint tools) can roll them back. m must use the same filesystem
+// as the tools (see WithFS).
+func WithCheckpoints(m *checkpoint.Manager) TextToolOption {
+	return func(tt *TextTool) error {
+		tt.cfg.mutation.Checkpoints = m
+		return nil
+	}
+}
+
 func NewTextTool(opts ...TextToolOption) (*TextTool, error) {
 	tt := &TextTool{
 		cfg: textToolConfig{
 			allowedRoots:  nil,
 			workBaseDir:   "",
 			blockSymlinks: false,
+			mutation:      mutation.DefaultConfig(),
 		},
 	}
 
@@ -68,7 +135,11 @@ func NewTextTool(opts ...TextToolOption) (*TextTool, error) {
 		}
 	}
 
-	pol, err := fspolicy.New(tt.cfg.workBaseDir, tt.cfg.allowedRoots, tt.cfg.blockSymlinks)
+	if err := tt.cfg.mutation.Validate(tt.cfg.fsys); err != nil {
+		return nil, err
+	}
+
+	pol, err := fspolicy.NewWithFS(tt.cfg.fsys, tt.cfg.workBaseDir, tt.cfg.allowedRoots, tt.cfg.blockSymlinks)
 	if err != nil {
 		return nil, err
 	}
@@ -76,15 +147,44 @@ func NewTextTool(opts ...TextToolOption) (*TextTool, error) {
 	return tt, nil
 }
 
+func (tt *TextTool) ApplyPatchTool() spec.Tool       { return toolutil.CloneTool(applyPatchTool) }
 func (tt *TextTool) DeleteTextLinesTool() spec.Tool  { return toolutil.CloneTool(deleteTextLinesTool) }
 func (tt *TextTool) FindTextTool() spec.Tool         { return toolutil.CloneTool(findTextTool) }
 func (tt *TextTool) InsertTextLinesTool() spec.Tool  { return toolutil.CloneTool(insertTextLinesTool) }
 func (tt *TextTool) ReadTextRangeTool() spec.Tool    { return toolutil.CloneTool(readTextRangeTool) }
 func (tt *TextTool) ReplaceTextLinesTool() spec.Tool { return toolutil.CloneTool(replaceTextLinesTool) }
 
+func (tt *TextTool) ApplyPatch(ctx context.Context, args ApplyPatchArgs) (*ApplyPatchOut, error) {
+	return toolutil.WithRecoveryResp(func() (*ApplyPatchOut, error) {
+		p := tt.snapshotPolicy()
+		var paths []string
+		if !args.DryRun {
+			var err error
+			if p, err = tt.meteredPolicy(); err != nil {
+				return nil, err
+			}
+			paths = patchPaths(args.Patch)
+		}
+		done, err := tt.beginMutation(ctx, p, applyPatchTool.Slug, paths...)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+		return applyPatch(ctx, args, p)
+	})
+}
+
 func (tt *TextTool) DeleteTextLines(ctx context.Context, args DeleteTextLinesArgs) (*DeleteTextLinesOut, error) {
 	return toolutil.WithRecoveryResp(func() (*DeleteTextLinesOut, error) {
-		p := tt.snapshotPolicy()
+		p, err := tt.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		done, err := tt.beginMutation(ctx, p, deleteTextLinesTool.Slug, args.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
 		return deleteTextLines(ctx, args, p)
 	})
 }
@@ -98,7 +198,15 @@ func (tt *TextTool) FindText(ctx context.Context, args FindTextArgs) (*FindTextO
 
 func (tt *TextTool) InsertTextLines(ctx context.Context, args InsertTextLinesArgs) (*InsertTextLinesOut, error) {
 	return toolutil.WithRecoveryResp(func() (*InsertTextLinesOut, error) {
-		p := tt.snapshotPolicy()
+		p, err := tt.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		done, err := tt.beginMutation(ctx, p, insertTextLinesTool.Slug, args.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
 		return insertTextLines(ctx, args, p)
 	})
 }
@@ -112,14 +220,59 @@ func (tt *TextTool) ReadTextRange(ctx context.Context, args ReadTextRangeArgs) (
 
 func (tt *TextTool) ReplaceTextLines(ctx context.Context, args ReplaceTextLinesArgs) (*ReplaceTextLinesOut, error) {
 	return toolutil.WithRecoveryResp(func() (*ReplaceTextLinesOut, error) {
-		p := tt.snapshotPolicy()
+		p, err := tt.meteredPolicy()
+		if err != nil {
+			return nil, err
+		}
+		done, err := tt.beginMutation(ctx, p, replaceTextLinesTool.Slug, args.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
 		return replaceTextLines(ctx, args, p)
 	})
 }
 
+// meteredPolicy returns the policy for a call that writes content: the call is counted against the
+// rate limit of the write quota (see WithQuota), and the policy charges the quota for what it writes.
+func (tt *TextTool) meteredPolicy() (fspolicy.FSPolicy, error) {
+	tt.mu.RLock()
+	p := tt.policy
+	m := tt.cfg.mutation
+	tt.mu.RUnlock()
+	return m.Metered(p)
+}
+
 func (tt *TextTool) snapshotPolicy() fspolicy.FSPolicy {
 	tt.mu.RLock()
 	p := tt.policy
 	tt.mu.RUnlock()
 	return p
 }
+
+// beginMutation locks the paths a tool is about to change (see WithLockTimeout and WithOSFileLocks)
+// and snapshots them for WithCheckpoints. The returned function releases the locks.
+func (tt *TextTool) beginMutation(
+	ctx context.Context,
+	p fspolicy.FSPolicy,
+	tool string,
+	paths ...string,
+) (func(), error) {
+	tt.mu.RLock()
+	m := tt.cfg.mutation
+	tt.mu.RUnlock()
+	return m.Begin(ctx, p, tool, paths...)
+}
+
+// patchPaths returns the paths a patch touches; a patch that does not parse touches none.
+func patchPaths(patch string) []string {
+	fps, err := diffutil.ParseUnified(patch)
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, 0, len(fps))
+	for _, fp := range fps {
+		paths = append(paths, fp.Path())
+	}
+	return paths
+}
@@ -0,0 +1,97 @@
+package texttool
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/flexigpt/llmtools-go/checkpoint"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func TestTextTool_MemFS(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	mustNoErr(t, err)
+	m := vfs.NewMemFS()
+	mustNoErr(t, m.MkdirAll(root, 0o755))
+	path := filepath.Join(root, "a.txt")
+	mustNoErr(t, vfs.WriteFile(m, path, []byte("one\ntwo\nthree\n"), 0o644))
+
+	tt, err := NewTextTool(WithFS(m), WithWorkBaseDir(root), WithAllowedRoots([]string{root}))
+	mustNoErr(t, err)
+
+	_, err = tt.ReplaceTextLines(t.Context(), ReplaceTextLinesArgs{
+		Path:             "a.txt",
+		MatchLines:       []string{"two"},
+		ReplaceWithLines: []string{"TWO", "2"},
+	})
+	mustNoErr(t, err)
+
+	got, err := vfs.ReadFile(m, path)
+	mustNoErr(t, err)
+	if want := "one\nTWO\n2\nthree\n"; string(got) != want {
+		t.Fatalf("content=%q want %q", got, want)
+	}
+
+	_, err = tt.ReplaceTextLines(t.Context(), ReplaceTextLinesArgs{
+		Path:             filepath.Join(filepath.Dir(root), "other.txt"),
+		MatchLines:       []string{"x"},
+		ReplaceWithLines: []string{"y"},
+	})
+	mustErrContains(t, err, "outside")
+}
+
+func TestTextTool_Checkpoints(t *testing.T) {
+	t.Parallel()
+
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := vfs.NewMemFS()
+	if err := m.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(root, "a.txt")
+	if err := vfs.WriteFile(m, a, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cm, err := checkpoint.New(t.TempDir(), checkpoint.WithFS(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tt, err := NewTextTool(WithFS(m), WithWorkBaseDir(root), WithAllowedRoots([]string{root}), WithCheckpoints(cm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp, err := cm.Checkpoint("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := t.Context()
+	_, err = tt.InsertTextLines(ctx, InsertTextLinesArgs{Path: "a.txt", LinesToInsert: []string{"three"}})
+	if err != nil {
+		t.Fatalf("InsertTextLines: %v", err)
+	}
+	patch := "--- /dev/null\n+++ b/b.txt\n@@ -0,0 +1 @@\n+bee\n"
+	if _, err := tt.ApplyPatch(ctx, ApplyPatchArgs{Patch: patch, DryRun: true}); err != nil {
+		t.Fatalf("ApplyPatch dry run: %v", err)
+	}
+	if _, err := tt.ApplyPatch(ctx, ApplyPatchArgs{Patch: patch}); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	if got := len(cm.Entries()); got != 3 {
+		t.Fatalf("entries = %d, want 3 (dry runs are not recorded)", got)
+	}
+
+	if _, err := cm.RollbackTo(ctx, cp); err != nil {
+		t.Fatalf("RollbackTo: %v", err)
+	}
+	b, err := vfs.ReadFile(m, a)
+	if err != nil || string(b) != "one\ntwo\n" {
+		t.Fatalf("a.txt = %q, %v", b, err)
+	}
+	if _, err := m.Lstat(filepath.Join(root, "b.txt")); err == nil {
+		t.Fatal("patched-in file survived the rollback")
+	}
+}
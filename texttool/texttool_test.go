@@ -0,0 +1,141 @@
+package texttool
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/filelock"
+	"github.com/flexigpt/llmtools-go/quota"
+)
+
+func TestTextTool_ConcurrentEditsAreSerialized(t *testing.T) {
+	t.Parallel()
+
+	dir := newWorkDir(t)
+	path := writeTempTextFile(t, dir, "edit-*.txt", "start\n")
+	locks := filepath.Join(t.TempDir(), "locks")
+	const n = 8
+	var wg sync.WaitGroup
+	for i := range n {
+		// Separate instances stand in for separate agents sharing the workspace.
+		tt, err := NewTextTool(WithWorkBaseDir(dir), WithOSFileLocks(locks))
+		mustNoErr(t, err)
+		wg.Go(func() {
+			_, err := tt.InsertTextLines(t.Context(), InsertTextLinesArgs{
+				Path:          path,
+				LinesToInsert: []string{fmt.Sprintf("line %d", i)},
+			})
+			if err != nil {
+				t.Errorf("InsertTextLines %d: %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	got := readFileString(t, path)
+	for i := range n {
+		if !strings.Contains(got, fmt.Sprintf("line %d\n", i)) {
+			t.Fatalf("edit %d was lost:\n%s", i, got)
+		}
+	}
+}
+
+func TestTextTool_LockTimeout(t *testing.T) {
+	t.Parallel()
+
+	dir := newWorkDir(t)
+	path := writeTempTextFile(t, dir, "locked-*.txt", "a\nb\n")
+	tt, err := NewTextTool(WithWorkBaseDir(dir), WithLockTimeout(20*time.Millisecond))
+	mustNoErr(t, err)
+
+	unlock, err := filelock.Lock(t.Context(), nil, filelock.Config{}, path)
+	mustNoErr(t, err)
+	_, err = tt.DeleteTextLines(t.Context(), DeleteTextLinesArgs{Path: path, MatchLines: []string{"a"}})
+	unlock()
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("DeleteTextLines error = %v, want ErrLockTimeout", err)
+	}
+	if got := readFileString(t, path); got != "a\nb\n" {
+		t.Fatalf("content = %q, want unchanged", got)
+	}
+
+	_, err = tt.DeleteTextLines(t.Context(), DeleteTextLinesArgs{Path: path, MatchLines: []string{"a"}})
+	mustNoErr(t, err)
+
+	if _, err := NewTextTool(WithOSFileLocks("relative")); err == nil {
+		t.Fatal("expected error for a relative lock dir")
+	}
+}
+
+func TestTextTool_Quota(t *testing.T) {
+	t.Parallel()
+
+	dir := newWorkDir(t)
+	path := writeTempTextFile(t, dir, "quota-*.txt", "a\nb\n")
+	q, err := quota.New(quota.Limits{MaxFileBytes: 8, MaxFilesCreated: 1})
+	mustNoErr(t, err)
+	tt, err := NewTextTool(WithWorkBaseDir(dir), WithQuota(q))
+	mustNoErr(t, err)
+	ctx := t.Context()
+
+	_, err = tt.InsertTextLines(ctx, InsertTextLinesArgs{Path: path, LinesToInsert: []string{"c"}})
+	mustNoErr(t, err)
+	if u := q.Usage(); u.BytesWritten != 6 || u.FilesCreated != 0 {
+		t.Fatalf("usage = %+v, want 6 bytes and no files", u)
+	}
+
+	_, err = tt.InsertTextLines(ctx, InsertTextLinesArgs{Path: path, LinesToInsert: []string{"dd", "ee"}})
+	if !errors.Is(err, quota.ErrExceeded) {
+		t.Fatalf("InsertTextLines err = %v, want quota.ErrExceeded", err)
+	}
+	if got := readFileString(t, path); got != "a\nb\nc\n" {
+		t.Fatalf("content = %q, want unchanged", got)
+	}
+
+	patch := "--- /dev/null\n+++ b/new1.txt\n@@ -0,0 +1 @@\n+x\n--- /dev/null\n+++ b/new2.txt\n@@ -0,0 +1 @@\n+y\n"
+	if _, err := tt.ApplyPatch(ctx, ApplyPatchArgs{Patch: patch}); !errors.Is(err, quota.ErrExceeded) {
+		t.Fatalf("ApplyPatch err = %v, want quota.ErrExceeded", err)
+	}
+}
+
+func TestTextTool_QuotaPatchRollback(t *testing.T) {
+	t.Parallel()
+
+	dir := newWorkDir(t)
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	mustNoErr(t, os.WriteFile(a, []byte("aaaa\n"), 0o600))
+	mustNoErr(t, os.WriteFile(b, []byte("b\n"), 0o600))
+	q, err := quota.New(quota.Limits{MaxBytesWritten: 10, MaxFilesCreated: 1})
+	mustNoErr(t, err)
+	tt, err := NewTextTool(WithWorkBaseDir(dir), WithQuota(q))
+	mustNoErr(t, err)
+
+	// The first two files fit in the quota; the third runs it out, and restoring a.txt must not be
+	// metered against the bytes already used.
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-aaaa\n+A\n" +
+		"--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+xxxxxx\n" +
+		"--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-b\n+bbbbbbbbbbbb\n"
+	_, err = tt.ApplyPatch(t.Context(), ApplyPatchArgs{Patch: patch})
+	if !errors.Is(err, quota.ErrExceeded) || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("ApplyPatch err = %v, want quota.ErrExceeded with rollback", err)
+	}
+	if got := readFileString(t, a); got != "aaaa\n" {
+		t.Fatalf("a.txt = %q, want original", got)
+	}
+	if got := readFileString(t, b); got != "b\n" {
+		t.Fatalf("b.txt = %q, want original", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("new.txt stat err = %v, want not exist", err)
+	}
+	if u := q.Usage(); u.BytesWritten != 0 || u.FilesCreated != 0 {
+		t.Fatalf("usage = %+v, want the rolled back writes refunded", u)
+	}
+}
@@ -0,0 +1,735 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// maxSymlinkHops bounds symlink resolution, like the kernel's ELOOP limit.
+const maxSymlinkHops = 40
+
+var (
+	errNotDir   = syscall.ENOTDIR
+	errIsDir    = syscall.EISDIR
+	errNotEmpty = syscall.ENOTEMPTY
+	errLoop     = syscall.ELOOP
+)
+
+// MemFS is an in-memory FS. The zero value is not usable; create one with NewMemFS.
+//
+// It models directories, regular files, symlinks (absolute and relative targets) and hard links, with
+// permission bits and modification times. The owner read/write bits of regular files are enforced on
+// open, as for a non-root user. Every absolute path is valid: on Windows each volume ("C:") gets its own
+// root on first use. MemFS is safe for concurrent use.
+type MemFS struct {
+	mu    sync.RWMutex
+	roots map[string]*memNode // by upper-cased volume name ("" on Unix)
+	now   func() time.Time
+}
+
+type memNode struct {
+	mode     fs.FileMode // type and permission bits
+	modTime  time.Time
+	data     []byte              // regular files
+	children map[string]*memNode // directories
+	target   string              // symlinks
+}
+
+// NewMemFS returns an empty in-memory filesystem holding only the root directory.
+func NewMemFS() *MemFS {
+	return &MemFS{roots: make(map[string]*memNode), now: time.Now}
+}
+
+func (m *MemFS) newNode(mode fs.FileMode) *memNode {
+	n := &memNode{mode: mode, modTime: m.now()}
+	if mode.IsDir() {
+		n.children = make(map[string]*memNode)
+	}
+	return n
+}
+
+// walkResult is a resolved path: the parent directory, the final component and its node (nil when the
+// final component does not exist).
+type walkResult struct {
+	parent     *memNode
+	parentPath string
+	base       string
+	node       *memNode
+}
+
+func (r walkResult) path() string {
+	if r.base == "" {
+		return r.parentPath
+	}
+	return filepath.Join(r.parentPath, r.base)
+}
+
+// walk resolves name. Symlinks in intermediate components are always followed; the final component is
+// followed only when follow is set. Callers must hold m.mu.
+func (m *MemFS) walk(op, name string, follow bool) (walkResult, error) {
+	if !filepath.IsAbs(name) {
+		return walkResult{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	vol := filepath.VolumeName(name)
+	key := strings.ToUpper(vol)
+	root := m.roots[key]
+	if root == nil {
+		root = m.newNode(fs.ModeDir | 0o755)
+		m.roots[key] = root
+	}
+	rootPath := vol + string(filepath.Separator)
+
+	parts := splitPath(name[len(vol):])
+	// stack holds the directories from the root to the current one.
+	stack := []*memNode{root}
+	names := []string{}
+	hops := 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+		last := len(parts) == 0
+		switch part {
+		case ".":
+			continue
+		case "..":
+			if len(stack) > 1 {
+				stack, names = stack[:len(stack)-1], names[:len(names)-1]
+			}
+			continue
+		}
+		dir := stack[len(stack)-1]
+		curPath := filepath.Join(append([]string{rootPath}, names...)...)
+		child := dir.children[part]
+		if child == nil {
+			if !last {
+				return walkResult{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+			return walkResult{parent: dir, parentPath: curPath, base: part}, nil
+		}
+		if child.mode&fs.ModeSymlink != 0 && (!last || follow) {
+			hops++
+			if hops > maxSymlinkHops {
+				return walkResult{}, &fs.PathError{Op: op, Path: name, Err: errLoop}
+			}
+			target := filepath.FromSlash(child.target)
+			if filepath.IsAbs(target) {
+				tvol := filepath.VolumeName(target)
+				troot := m.roots[strings.ToUpper(tvol)]
+				if troot == nil {
+					troot = m.newNode(fs.ModeDir | 0o755)
+					m.roots[strings.ToUpper(tvol)] = troot
+				}
+				stack, names = []*memNode{troot}, nil
+				rootPath = tvol + string(filepath.Separator)
+				target = target[len(tvol):]
+			}
+			parts = append(splitPath(target), parts...)
+			continue
+		}
+		if last {
+			return walkResult{parent: dir, parentPath: curPath, base: part, node: child}, nil
+		}
+		if !child.mode.IsDir() {
+			return walkResult{}, &fs.PathError{Op: op, Path: name, Err: errNotDir}
+		}
+		stack = append(stack, child)
+		names = append(names, part)
+	}
+	// The path resolved to a directory on the stack (a root, or via "..").
+	cur := stack[len(stack)-1]
+	if len(stack) == 1 {
+		return walkResult{parentPath: rootPath, node: cur}, nil
+	}
+	parentPath := filepath.Join(append([]string{rootPath}, names[:len(names)-1]...)...)
+	return walkResult{parent: stack[len(stack)-2], parentPath: parentPath, base: names[len(names)-1], node: cur}, nil
+}
+
+func splitPath(p string) []string {
+	var out []string
+	for s := range strings.SplitSeq(p, string(filepath.Separator)) {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Open opens the named file or directory for reading.
+func (m *MemFS) Open(name string) (File, error) { return m.OpenFile(name, os.O_RDONLY, 0) }
+
+// OpenFile opens the named file like os.OpenFile.
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.walk("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	n := r.node
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	switch {
+	case n == nil:
+		if flag&os.O_CREATE == 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		if r.parent == nil || !r.parent.mode.IsDir() {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: errNotDir}
+		}
+		n = m.newNode(perm & fs.ModePerm)
+		r.parent.children[r.base] = n
+		r.parent.modTime = m.now()
+	case flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case n.mode.IsDir():
+		if writable {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+		}
+	default:
+		readable := flag&os.O_WRONLY == 0
+		if readable && n.mode&0o400 == 0 || writable && n.mode&0o200 == 0 {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+		}
+		if writable && flag&os.O_TRUNC != 0 {
+			n.data = nil
+			n.modTime = m.now()
+		}
+	}
+	return &memFile{fsys: m, node: n, name: name, flag: flag}, nil
+}
+
+// CreateTemp creates a new file in dir like os.CreateTemp.
+func (m *MemFS) CreateTemp(dir, pattern string) (File, error) {
+	for range 10000 {
+		f, err := m.OpenFile(tempName(dir, pattern), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+	return nil, &fs.PathError{Op: "createtemp", Path: filepath.Join(dir, pattern), Err: fs.ErrExist}
+}
+
+// MkdirTemp creates a new directory in dir like os.MkdirTemp.
+func (m *MemFS) MkdirTemp(dir, pattern string) (string, error) {
+	for range 10000 {
+		name := tempName(dir, pattern)
+		err := m.Mkdir(name, 0o700)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+	return "", &fs.PathError{Op: "mkdirtemp", Path: filepath.Join(dir, pattern), Err: fs.ErrExist}
+}
+
+func tempName(dir, pattern string) string {
+	prefix, suffix := pattern, ""
+	if i := strings.LastIndexByte(pattern, '*'); i >= 0 {
+		prefix, suffix = pattern[:i], pattern[i+1:]
+	}
+	return filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix) //nolint:gosec // Names only.
+}
+
+// Stat returns the FileInfo of the named file, following symlinks.
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) { return m.stat("stat", name, true) }
+
+// Lstat returns the FileInfo of the named file without following a final symlink.
+func (m *MemFS) Lstat(name string) (fs.FileInfo, error) { return m.stat("lstat", name, false) }
+
+func (m *MemFS) stat(op, name string, follow bool) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.walk(op, name, follow)
+	if err != nil {
+		return nil, err
+	}
+	if r.node == nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return r.node.info(filepath.Base(name)), nil
+}
+
+// ReadDir returns the directory entries of name sorted by file name.
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.walk("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if r.node == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !r.node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	return r.node.entries(), nil
+}
+
+func (n *memNode) entries() []fs.DirEntry {
+	names := make([]string, 0, len(n.children))
+	for k := range n.children {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	out := make([]fs.DirEntry, len(names))
+	for i, k := range names {
+		out[i] = fs.FileInfoToDirEntry(n.children[k].info(k))
+	}
+	return out
+}
+
+// Readlink returns the target of the named symlink.
+func (m *MemFS) Readlink(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.walk("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if r.node == nil {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrNotExist}
+	}
+	if r.node.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return r.node.target, nil
+}
+
+// EvalSymlinks returns name with all symlinks resolved; the path must exist.
+func (m *MemFS) EvalSymlinks(name string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.walk("lstat", name, true)
+	if err != nil {
+		return "", err
+	}
+	if r.node == nil {
+		return "", &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+	}
+	return r.path(), nil
+}
+
+// Mkdir creates a directory; its parent must exist.
+func (m *MemFS) Mkdir(name string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.walk("mkdir", name, false)
+	if err != nil {
+		return err
+	}
+	if r.node != nil {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+	}
+	r.parent.children[r.base] = m.newNode(fs.ModeDir | perm&fs.ModePerm)
+	r.parent.modTime = m.now()
+	return nil
+}
+
+// MkdirAll creates a directory and any missing parents.
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if st, err := m.Stat(name); err == nil {
+		if st.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: name, Err: errNotDir}
+	}
+	parent := filepath.Dir(name)
+	if parent != name {
+		if err := m.MkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+	if err := m.Mkdir(name, perm); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// Remove removes a file, symlink or empty directory.
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.walk("remove", name, false)
+	if err != nil {
+		return err
+	}
+	if r.node == nil || r.parent == nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if r.node.mode.IsDir() && len(r.node.children) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+	}
+	delete(r.parent.children, r.base)
+	r.parent.modTime = m.now()
+	return nil
+}
+
+// RemoveAll removes name and everything below it; a missing name is not an error.
+func (m *MemFS) RemoveAll(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.walk("removeall", name, false)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if r.node == nil {
+		return nil
+	}
+	if r.parent == nil {
+		return &fs.PathError{Op: "removeall", Path: name, Err: fs.ErrInvalid}
+	}
+	delete(r.parent.children, r.base)
+	r.parent.modTime = m.now()
+	return nil
+}
+
+// Rename moves oldname to newname, replacing an existing file or empty directory like os.Rename on Unix.
+func (m *MemFS) Rename(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	linkErr := func(err error) error { return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err} }
+	src, err := m.walk("rename", oldname, false)
+	if err != nil {
+		return linkErr(errors.Unwrap(err))
+	}
+	if src.node == nil || src.parent == nil {
+		return linkErr(fs.ErrNotExist)
+	}
+	dst, err := m.walk("rename", newname, false)
+	if err != nil {
+		return linkErr(errors.Unwrap(err))
+	}
+	if dst.parent == nil {
+		return linkErr(fs.ErrInvalid)
+	}
+	if dst.node == src.node {
+		return nil
+	}
+	if src.node.mode.IsDir() {
+		// A directory cannot move below itself.
+		if rel, err := filepath.Rel(src.path(), dst.path()); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			return linkErr(fs.ErrInvalid)
+		}
+	}
+	if dst.node != nil {
+		switch {
+		case dst.node.mode.IsDir() && !src.node.mode.IsDir():
+			return linkErr(errIsDir)
+		case !dst.node.mode.IsDir() && src.node.mode.IsDir():
+			return linkErr(errNotDir)
+		case dst.node.mode.IsDir() && len(dst.node.children) > 0:
+			return linkErr(errNotEmpty)
+		}
+	}
+	delete(src.parent.children, src.base)
+	dst.parent.children[dst.base] = src.node
+	now := m.now()
+	src.parent.modTime, dst.parent.modTime = now, now
+	return nil
+}
+
+// Link creates newname as a hard link to the file oldname.
+func (m *MemFS) Link(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	linkErr := func(err error) error { return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: err} }
+	src, err := m.walk("link", oldname, false)
+	if err != nil {
+		return linkErr(errors.Unwrap(err))
+	}
+	if src.node == nil {
+		return linkErr(fs.ErrNotExist)
+	}
+	if src.node.mode.IsDir() {
+		return linkErr(fs.ErrPermission)
+	}
+	dst, err := m.walk("link", newname, false)
+	if err != nil {
+		return linkErr(errors.Unwrap(err))
+	}
+	if dst.node != nil {
+		return linkErr(fs.ErrExist)
+	}
+	dst.parent.children[dst.base] = src.node
+	dst.parent.modTime = m.now()
+	return nil
+}
+
+// Symlink creates newname as a symbolic link to oldname.
+func (m *MemFS) Symlink(oldname, newname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dst, err := m.walk("symlink", newname, false)
+	if err != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: errors.Unwrap(err)}
+	}
+	if dst.node != nil {
+		return &os.LinkError{Op: "symlink", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+	n := m.newNode(fs.ModeSymlink | 0o777)
+	n.target = oldname
+	dst.parent.children[dst.base] = n
+	dst.parent.modTime = m.now()
+	return nil
+}
+
+// Chmod changes the permission, setuid, setgid and sticky bits of the named file, following symlinks.
+func (m *MemFS) Chmod(name string, mode fs.FileMode) error {
+	return m.update("chmod", name, func(n *memNode) {
+		const settable = fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky
+		n.mode = n.mode&^settable | mode&settable
+	})
+}
+
+// Chtimes sets the modification time of the named file; a zero mtime leaves it unchanged.
+func (m *MemFS) Chtimes(name string, _, mtime time.Time) error {
+	return m.update("chtimes", name, func(n *memNode) {
+		if !mtime.IsZero() {
+			n.modTime = mtime
+		}
+	})
+}
+
+func (m *MemFS) update(op, name string, fn func(n *memNode)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, err := m.walk(op, name, true)
+	if err != nil {
+		return err
+	}
+	if r.node == nil {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	fn(r.node)
+	return nil
+}
+
+func (n *memNode) info(name string) *memFileInfo {
+	size := int64(len(n.data))
+	if n.mode&fs.ModeSymlink != 0 {
+		size = int64(len(n.target))
+	}
+	return &memFileInfo{name: name, size: size, mode: n.mode, modTime: n.modTime, node: n}
+}
+
+// memFileInfo implements fs.FileInfo. Sys returns nil; SameFile compares the underlying node.
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	node    *memNode
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *memFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *memFileInfo) Sys() any           { return nil }
+
+// memFile is an open MemFS file. Reads and writes go straight to the shared node, so every open
+// handle sees the same contents, as with OS files.
+type memFile struct {
+	fsys    *MemFS
+	node    *memNode
+	name    string
+	flag    int
+	off     int64
+	dirRead int
+	closed  bool
+}
+
+func (f *memFile) Name() string { return f.name }
+
+func (f *memFile) check(op string, write bool) error {
+	switch {
+	case f.closed:
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	case write && f.flag&(os.O_WRONLY|os.O_RDWR) == 0:
+		return &fs.PathError{Op: op, Path: f.name, Err: syscall.EBADF}
+	case !write && f.flag&os.O_WRONLY != 0:
+		return &fs.PathError{Op: op, Path: f.name, Err: syscall.EBADF}
+	case f.node.mode.IsDir() && op != "readdir" && op != "stat" && op != "sync":
+		return &fs.PathError{Op: op, Path: f.name, Err: errIsDir}
+	}
+	return nil
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if err := f.check("read", false); err != nil {
+		return 0, err
+	}
+	if f.off >= int64(len(f.node.data)) {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.off:])
+	f.off += int64(n)
+	return n, nil
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if err := f.check("read", false); err != nil {
+		return 0, err
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "readat", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if off >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if err := f.check("write", true); err != nil {
+		return 0, err
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.off = int64(len(f.node.data))
+	}
+	f.writeAt(p, f.off)
+	f.off += int64(len(p))
+	return len(p), nil
+}
+
+func (f *memFile) WriteAt(p []byte, off int64) (int, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if err := f.check("write", true); err != nil {
+		return 0, err
+	}
+	if f.flag&os.O_APPEND != 0 {
+		return 0, errors.New("vfs: invalid use of WriteAt on file opened with O_APPEND")
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "writeat", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.writeAt(p, off)
+	return len(p), nil
+}
+
+func (f *memFile) writeAt(p []byte, off int64) {
+	end := off + int64(len(p))
+	if end > int64(len(f.node.data)) {
+		f.node.data = append(f.node.data, make([]byte, end-int64(len(f.node.data)))...)
+	}
+	copy(f.node.data[off:], p)
+	f.node.modTime = f.fsys.now()
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.closed {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrClosed}
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	f.off = offset
+	return offset, nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if err := f.check("stat", false); err != nil && !errors.Is(err, syscall.EBADF) {
+		return nil, err
+	}
+	return f.node.info(filepath.Base(f.name)), nil
+}
+
+func (f *memFile) Sync() error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.closed {
+		return &fs.PathError{Op: "sync", Path: f.name, Err: fs.ErrClosed}
+	}
+	return nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if err := f.check("truncate", true); err != nil {
+		return err
+	}
+	if size < 0 {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if size <= int64(len(f.node.data)) {
+		f.node.data = f.node.data[:size:size]
+	} else {
+		f.node.data = append(f.node.data, make([]byte, size-int64(len(f.node.data)))...)
+	}
+	f.node.modTime = f.fsys.now()
+	return nil
+}
+
+func (f *memFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if err := f.check("readdir", false); err != nil {
+		return nil, err
+	}
+	if !f.node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.name, Err: errNotDir}
+	}
+	all := f.node.entries()
+	rest := all[min(f.dirRead, len(all)):]
+	if n <= 0 {
+		f.dirRead = len(all)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	rest = rest[:min(n, len(rest))]
+	f.dirRead += len(rest)
+	return rest, nil
+}
+
+func (f *memFile) Close() error {
+	f.fsys.mu.Lock()
+	defer f.fsys.mu.Unlock()
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
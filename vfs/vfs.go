@@ -0,0 +1,277 @@
+// Package vfs defines the filesystem abstraction used by the file tools, with an OS implementation
+// (OS) and an in-memory implementation (MemFS).
+//
+// Unlike io/fs, names are native absolute paths (as produced by filepath.Abs), so an FS can back the
+// same policy-resolved paths the tools use against the real disk. Errors follow the os package
+// conventions: *fs.PathError / *os.LinkError wrapping fs.ErrNotExist, fs.ErrExist, fs.ErrPermission
+// and so on, so errors.Is checks behave the same on every implementation.
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// File is an open file. *os.File implements it.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Writer
+	io.WriterAt
+	io.Seeker
+	io.Closer
+
+	Name() string
+	Stat() (fs.FileInfo, error)
+	Sync() error
+	Truncate(size int64) error
+	// ReadDir reads directory entries like (*os.File).ReadDir.
+	ReadDir(n int) ([]fs.DirEntry, error)
+}
+
+// FS is a writable filesystem addressed by native absolute paths. The methods mirror the os package
+// functions of the same name.
+type FS interface {
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	// CreateTemp creates a new file in dir like os.CreateTemp; dir must not be empty.
+	CreateTemp(dir, pattern string) (File, error)
+	// MkdirTemp creates a new directory in dir like os.MkdirTemp; dir must not be empty.
+	MkdirTemp(dir, pattern string) (string, error)
+
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	// ReadDir returns the entries of a directory sorted by name.
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Readlink(name string) (string, error)
+	// EvalSymlinks returns name with all symlinks resolved, like filepath.EvalSymlinks.
+	EvalSymlinks(name string) (string, error)
+
+	Mkdir(name string, perm fs.FileMode) error
+	MkdirAll(name string, perm fs.FileMode) error
+	Remove(name string) error
+	RemoveAll(name string) error
+	Rename(oldname, newname string) error
+	Link(oldname, newname string) error
+	Symlink(oldname, newname string) error
+	Chmod(name string, mode fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+}
+
+// OS returns the FS backed by the operating system (the os package).
+func OS() FS { return osFS{} }
+
+// IsOS reports whether fsys is the operating-system FS. Code that needs OS facilities without an FS
+// equivalent (system trash, process working directories, fsync of directories) checks this first.
+func IsOS(fsys FS) bool {
+	_, ok := fsys.(osFS)
+	return ok
+}
+
+// OrOS returns fsys, or the OS FS when fsys is nil.
+func OrOS(fsys FS) FS {
+	if fsys == nil {
+		return osFS{}
+	}
+	return fsys
+}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (File, error) { return wrapOSFile(os.Open(name)) }
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	return wrapOSFile(os.OpenFile(name, flag, perm))
+}
+
+func (osFS) CreateTemp(dir, pattern string) (File, error) {
+	if dir == "" {
+		return nil, &fs.PathError{Op: "createtemp", Path: pattern, Err: fs.ErrInvalid}
+	}
+	return wrapOSFile(os.CreateTemp(dir, pattern))
+}
+
+func (osFS) MkdirTemp(dir, pattern string) (string, error) {
+	if dir == "" {
+		return "", &fs.PathError{Op: "mkdirtemp", Path: pattern, Err: fs.ErrInvalid}
+	}
+	return os.MkdirTemp(dir, pattern)
+}
+
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Readlink(name string) (string, error)       { return os.Readlink(name) }
+func (osFS) EvalSymlinks(name string) (string, error)   { return filepath.EvalSymlinks(name) }
+func (osFS) Mkdir(name string, perm fs.FileMode) error  { return os.Mkdir(name, perm) }
+func (osFS) MkdirAll(name string, perm fs.FileMode) error {
+	return os.MkdirAll(name, perm)
+}
+func (osFS) Remove(name string) error                  { return os.Remove(name) }
+func (osFS) RemoveAll(name string) error               { return os.RemoveAll(name) }
+func (osFS) Rename(oldname, newname string) error      { return os.Rename(oldname, newname) }
+func (osFS) Link(oldname, newname string) error        { return os.Link(oldname, newname) }
+func (osFS) Symlink(oldname, newname string) error     { return os.Symlink(oldname, newname) }
+func (osFS) Chmod(name string, mode fs.FileMode) error { return os.Chmod(name, mode) }
+func (osFS) Chtimes(name string, a, m time.Time) error { return os.Chtimes(name, a, m) }
+
+// wrapOSFile avoids returning a non-nil File interface holding a nil *os.File.
+func wrapOSFile(f *os.File, err error) (File, error) {
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ReadFile reads the whole named file, like os.ReadFile.
+func ReadFile(fsys FS, name string) ([]byte, error) {
+	if IsOS(fsys) {
+		return os.ReadFile(name)
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// WriteFile writes data to the named file, creating it with perm if needed, like os.WriteFile.
+func WriteFile(fsys FS, name string, data []byte, perm fs.FileMode) error {
+	f, err := fsys.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// SameFile reports whether fi1 and fi2 describe the same file, like os.SameFile. Both must come from
+// the same FS.
+func SameFile(fi1, fi2 fs.FileInfo) bool {
+	m1, ok1 := fi1.(*memFileInfo)
+	m2, ok2 := fi2.(*memFileInfo)
+	if ok1 || ok2 {
+		return ok1 && ok2 && m1.node == m2.node
+	}
+	return os.SameFile(fi1, fi2)
+}
+
+// WalkDir walks the tree rooted at root like filepath.WalkDir: in lexical order, without following
+// symlinks, calling fn for root and every entry below it.
+func WalkDir(fsys FS, root string, fn fs.WalkDirFunc) error {
+	if IsOS(fsys) {
+		return filepath.WalkDir(root, fn)
+	}
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fsys FS, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+			err = nil
+		}
+		return err
+	}
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Second call, to report the ReadDir error.
+		if err = fn(path, d, err); err != nil {
+			if errors.Is(err, filepath.SkipDir) && d.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, e := range entries {
+		if err := walkDir(fsys, filepath.Join(path, e.Name()), e, fn); err != nil {
+			if errors.Is(err, filepath.SkipDir) {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Glob returns the names matching pattern like filepath.Glob; pattern must be absolute for FS
+// implementations other than OS.
+func Glob(fsys FS, pattern string) ([]string, error) {
+	if IsOS(fsys) {
+		return filepath.Glob(pattern)
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return glob(fsys, pattern, 0)
+}
+
+func glob(fsys FS, pattern string, depth int) ([]string, error) {
+	if depth > 10000 {
+		return nil, filepath.ErrBadPattern
+	}
+	if !hasMeta(pattern) {
+		if _, err := fsys.Lstat(pattern); err != nil {
+			return nil, nil //nolint:nilerr // Like filepath.Glob, a missing literal path is not an error.
+		}
+		return []string{pattern}, nil
+	}
+	dir, file := filepath.Split(pattern)
+	dir = filepath.Clean(dir)
+	var dirs []string
+	if hasMeta(dir[len(filepath.VolumeName(dir)):]) {
+		var err error
+		if dirs, err = glob(fsys, dir, depth+1); err != nil {
+			return nil, err
+		}
+	} else {
+		dirs = []string{dir}
+	}
+	var matches []string
+	for _, d := range dirs {
+		entries, err := fsys.ReadDir(d)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if ok, err := filepath.Match(file, e.Name()); err != nil {
+				return nil, err
+			} else if ok {
+				matches = append(matches, filepath.Join(d, e.Name()))
+			}
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func hasMeta(path string) bool {
+	for i := range len(path) {
+		switch path[i] {
+		case '*', '?', '[':
+			return true
+		case '\\':
+			if filepath.Separator != '\\' {
+				return true
+			}
+		}
+	}
+	return false
+}
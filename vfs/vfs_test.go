@@ -0,0 +1,398 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+// backends returns each FS under test with an empty, existing absolute directory to work in.
+func backends(t *testing.T) map[string]func(t *testing.T) (FS, string) {
+	t.Helper()
+	return map[string]func(t *testing.T) (FS, string){
+		"os": func(t *testing.T) (FS, string) {
+			t.Helper()
+			dir, err := filepath.EvalSymlinks(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return OS(), dir
+		},
+		"mem": func(t *testing.T) (FS, string) {
+			t.Helper()
+			fsys := NewMemFS()
+			dir, _ := filepath.Abs(filepath.Join(string(filepath.Separator), "work"))
+			if err := fsys.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			return fsys, dir
+		},
+	}
+}
+
+func TestFSConformance(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, fsys FS, dir string)
+	}{
+		{
+			name: "write_read_stat",
+			run: func(t *testing.T, fsys FS, dir string) {
+				t.Helper()
+				p := filepath.Join(dir, "a.txt")
+				mustWrite(t, fsys, p, "hello")
+				if got := mustRead(t, fsys, p); got != "hello" {
+					t.Fatalf("read %q", got)
+				}
+				st, err := fsys.Stat(p)
+				if err != nil || st.Size() != 5 || !st.Mode().IsRegular() || st.Name() != "a.txt" {
+					t.Fatalf("stat = %v, %v", st, err)
+				}
+			},
+		},
+		{
+			name: "missing_is_not_exist",
+			run: func(t *testing.T, fsys FS, dir string) {
+				t.Helper()
+				p := filepath.Join(dir, "nope", "x")
+				if _, err := fsys.Stat(p); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("Stat err = %v", err)
+				}
+				if _, err := fsys.Open(p); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("Open err = %v", err)
+				}
+				if err := fsys.RemoveAll(p); err != nil {
+					t.Fatalf("RemoveAll of missing path: %v", err)
+				}
+			},
+		},
+		{
+			name: "excl_create_and_append",
+			run: func(t *testing.T, fsys FS, dir string) {
+				t.Helper()
+				p := filepath.Join(dir, "log")
+				mustWrite(t, fsys, p, "a")
+				if _, err := fsys.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600); !errors.Is(err, fs.ErrExist) {
+					t.Fatalf("O_EXCL err = %v", err)
+				}
+				f, err := fsys.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, _ = f.Write([]byte("bc"))
+				_ = f.Close()
+				if got := mustRead(t, fsys, p); got != "abc" {
+					t.Fatalf("after append %q", got)
+				}
+			},
+		},
+		{
+			name: "seek_readat_writeat_truncate",
+			run: func(t *testing.T, fsys FS, dir string) {
+				t.Helper()
+				p := filepath.Join(dir, "f")
+				f, err := fsys.OpenFile(p, os.O_RDWR|os.O_CREATE, 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+				_, _ = f.Write([]byte("0123456789"))
+				if _, err := f.WriteAt([]byte("AB"), 2); err != nil {
+					t.Fatal(err)
+				}
+				buf := make([]byte, 4)
+				if n, err := f.ReadAt(buf, 8); n != 2 || !errors.Is(err, io.EOF) {
+					t.Fatalf("ReadAt past end = %d, %v", n, err)
+				}
+				if err := f.Truncate(5); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(f)
+				if string(data) != "01AB4" {
+					t.Fatalf("content %q", data)
+				}
+			},
+		},
+		{
+			name: "mkdir_readdir_remove",
+			run: func(t *testing.T, fsys FS, dir string) {
+				t.Helper()
+				d := filepath.Join(dir, "x", "y")
+				if err := fsys.MkdirAll(d, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := fsys.Mkdir(d, 0o755); !errors.Is(err, fs.ErrExist) {
+					t.Fatalf("Mkdir existing err = %v", err)
+				}
+				mustWrite(t, fsys, filepath.Join(d, "b"), "")
+				mustWrite(t, fsys, filepath.Join(d, "a"), "")
+				entries, err := fsys.ReadDir(d)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var names []string
+				for _, e := range entries {
+					names = append(names, e.Name())
+				}
+				if !slices.Equal(names, []string{"a", "b"}) {
+					t.Fatalf("entries %v", names)
+				}
+				if err := fsys.Remove(d); err == nil {
+					t.Fatal("Remove of non-empty dir succeeded")
+				}
+				if err := fsys.RemoveAll(filepath.Join(dir, "x")); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := fsys.Lstat(d); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("after RemoveAll: %v", err)
+				}
+			},
+		},
+		{
+			name: "rename_replaces_and_link_refuses",
+			run: func(t *testing.T, fsys FS, dir string) {
+				t.Helper()
+				a, b, c := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")
+				mustWrite(t, fsys, a, "A")
+				mustWrite(t, fsys, b, "B")
+				if err := fsys.Link(a, b); !errors.Is(err, fs.ErrExist) {
+					t.Fatalf("Link onto existing err = %v", err)
+				}
+				if err := fsys.Link(a, c); err != nil {
+					t.Fatal(err)
+				}
+				sa, _ := fsys.Stat(a)
+				sc, _ := fsys.Stat(c)
+				if !SameFile(sa, sc) {
+					t.Fatal("hardlink is not the same file")
+				}
+				if err := fsys.Rename(a, b); err != nil {
+					t.Fatal(err)
+				}
+				if got := mustRead(t, fsys, b); got != "A" {
+					t.Fatalf("after rename %q", got)
+				}
+				if _, err := fsys.Stat(a); !errors.Is(err, fs.ErrNotExist) {
+					t.Fatalf("source after rename: %v", err)
+				}
+			},
+		},
+		{
+			name: "temp_files_and_chmod_chtimes",
+			run: func(t *testing.T, fsys FS, dir string) {
+				t.Helper()
+				f, err := fsys.CreateTemp(dir, ".tmp-*.txt")
+				if err != nil {
+					t.Fatal(err)
+				}
+				name := f.Name()
+				_ = f.Close()
+				if filepath.Dir(name) != dir || filepath.Ext(name) != ".txt" {
+					t.Fatalf("temp name %q", name)
+				}
+				d, err := fsys.MkdirTemp(dir, "d-*")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if st, err := fsys.Stat(d); err != nil || !st.IsDir() {
+					t.Fatalf("temp dir %v, %v", st, err)
+				}
+				mt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+				if err := fsys.Chtimes(name, mt, mt); err != nil {
+					t.Fatal(err)
+				}
+				if err := fsys.Chmod(name, 0o640); err != nil {
+					t.Fatal(err)
+				}
+				st, _ := fsys.Stat(name)
+				if !st.ModTime().Equal(mt) {
+					t.Fatalf("mtime %v", st.ModTime())
+				}
+				if runtime.GOOS != "windows" && st.Mode().Perm() != 0o640 {
+					t.Fatalf("perm %v", st.Mode().Perm())
+				}
+			},
+		},
+		{
+			name: "symlinks",
+			run: func(t *testing.T, fsys FS, dir string) {
+				t.Helper()
+				real := filepath.Join(dir, "real")
+				if err := fsys.MkdirAll(real, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				mustWrite(t, fsys, filepath.Join(real, "f"), "x")
+				link := filepath.Join(dir, "link")
+				if err := fsys.Symlink("real", link); err != nil {
+					t.Skipf("symlinks unavailable: %v", err)
+				}
+				if got, err := fsys.Readlink(link); err != nil || got != "real" {
+					t.Fatalf("Readlink = %q, %v", got, err)
+				}
+				if st, err := fsys.Lstat(link); err != nil || st.Mode()&fs.ModeSymlink == 0 {
+					t.Fatalf("Lstat = %v, %v", st, err)
+				}
+				if got := mustRead(t, fsys, filepath.Join(link, "f")); got != "x" {
+					t.Fatalf("read through link %q", got)
+				}
+				if got, err := fsys.EvalSymlinks(filepath.Join(link, "f")); err != nil || got != filepath.Join(real, "f") {
+					t.Fatalf("EvalSymlinks = %q, %v", got, err)
+				}
+				loop := filepath.Join(dir, "loop")
+				_ = fsys.Symlink(loop, loop)
+				if _, err := fsys.Stat(loop); err == nil {
+					t.Fatal("Stat of a symlink loop succeeded")
+				}
+			},
+		},
+		{
+			name: "walk_and_glob",
+			run: func(t *testing.T, fsys FS, dir string) {
+				t.Helper()
+				for _, p := range []string{"a/1.go", "a/2.txt", "b/3.go"} {
+					full := filepath.Join(dir, filepath.FromSlash(p))
+					if err := fsys.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+						t.Fatal(err)
+					}
+					mustWrite(t, fsys, full, "")
+				}
+				var walked []string
+				err := WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+					if err != nil {
+						return err
+					}
+					if d.IsDir() && d.Name() == "b" {
+						return filepath.SkipDir
+					}
+					rel, _ := filepath.Rel(dir, p)
+					walked = append(walked, filepath.ToSlash(rel))
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := []string{".", "a", "a/1.go", "a/2.txt"}; !slices.Equal(walked, want) {
+					t.Fatalf("walked %v want %v", walked, want)
+				}
+				got, err := Glob(fsys, filepath.Join(dir, "*", "*.go"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := []string{filepath.Join(dir, "a", "1.go"), filepath.Join(dir, "b", "3.go")}
+				if !slices.Equal(got, want) {
+					t.Fatalf("glob %v want %v", got, want)
+				}
+			},
+		},
+	}
+	for bname, newFS := range backends(t) {
+		for _, tc := range tests {
+			t.Run(bname+"/"+tc.name, func(t *testing.T) {
+				fsys, dir := newFS(t)
+				tc.run(t, fsys, dir)
+			})
+		}
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	root, _ := filepath.Abs(string(filepath.Separator))
+	tests := []struct {
+		name string
+		run  func(t *testing.T, m *MemFS)
+	}{
+		{
+			name: "relative_paths_rejected",
+			run: func(t *testing.T, m *MemFS) {
+				t.Helper()
+				if _, err := m.Stat("rel"); !errors.Is(err, fs.ErrInvalid) {
+					t.Fatalf("err = %v", err)
+				}
+			},
+		},
+		{
+			name: "owner_permission_bits_enforced",
+			run: func(t *testing.T, m *MemFS) {
+				t.Helper()
+				p := filepath.Join(root, "ro")
+				if err := WriteFile(m, p, []byte("x"), 0o444); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := m.OpenFile(p, os.O_WRONLY, 0); !errors.Is(err, fs.ErrPermission) {
+					t.Fatalf("write-open of read-only file err = %v", err)
+				}
+				if err := m.Chmod(p, 0o200); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := m.Open(p); !errors.Is(err, fs.ErrPermission) {
+					t.Fatalf("read-open of write-only file err = %v", err)
+				}
+			},
+		},
+		{
+			name: "open_handles_share_content",
+			run: func(t *testing.T, m *MemFS) {
+				t.Helper()
+				p := filepath.Join(root, "shared")
+				if err := WriteFile(m, p, []byte("old"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				r, err := m.Open(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer r.Close()
+				if err := WriteFile(m, p, []byte("new!"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				data, _ := io.ReadAll(r)
+				if string(data) != "new!" {
+					t.Fatalf("read %q", data)
+				}
+			},
+		},
+		{
+			name: "rename_dir_into_itself_refused",
+			run: func(t *testing.T, m *MemFS) {
+				t.Helper()
+				d := filepath.Join(root, "d")
+				if err := m.MkdirAll(filepath.Join(d, "sub"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := m.Rename(d, filepath.Join(d, "sub", "d")); err == nil {
+					t.Fatal("rename into own subtree succeeded")
+				}
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, NewMemFS())
+		})
+	}
+}
+
+func mustWrite(t *testing.T, fsys FS, p, data string) {
+	t.Helper()
+	if err := WriteFile(fsys, p, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile(%q): %v", p, err)
+	}
+}
+
+func mustRead(t *testing.T, fsys FS, p string) string {
+	t.Helper()
+	data, err := ReadFile(fsys, p)
+	if err != nil {
+		t.Fatalf("ReadFile(%q): %v", p, err)
+	}
+	return string(data)
+}
@@ -0,0 +1,161 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package watch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const nativeBackend = "kqueue"
+
+const kqueueFflags = unix.NOTE_WRITE | unix.NOTE_DELETE | unix.NOTE_RENAME | unix.NOTE_EXTEND | unix.NOTE_ATTRIB
+
+// kqueuePollTimeout bounds how long the reader waits before checking for close.
+const kqueuePollTimeout = 200 * time.Millisecond
+
+// kqueue watches every directory and file with one descriptor each: directory events announce
+// added and removed entries, file events announce content changes. Running out of descriptors
+// makes add fail, and the Watcher falls back to polling.
+type kqueue struct {
+	kq int
+
+	mu    sync.Mutex
+	fds   map[string]int
+	paths map[int]string
+	dirs  map[int]bool
+
+	ev   chan string
+	errc chan error
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func newNotifier(string) (notifier, error) {
+	kq, err := unix.Kqueue()
+	if err != nil {
+		return nil, os.NewSyscallError("kqueue", err)
+	}
+	unix.CloseOnExec(kq)
+	n := &kqueue{
+		kq:    kq,
+		fds:   map[string]int{},
+		paths: map[int]string{},
+		dirs:  map[int]bool{},
+		ev:    make(chan string, 64),
+		errc:  make(chan error, 1),
+		done:  make(chan struct{}),
+	}
+	n.wg.Add(1)
+	go n.read()
+	return n, nil
+}
+
+func (n *kqueue) events() <-chan string { return n.ev }
+func (n *kqueue) errors() <-chan error  { return n.errc }
+
+func (n *kqueue) add(path string, isDir bool) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.fds[path]; ok {
+		return nil
+	}
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		if errors.Is(err, unix.EMFILE) || errors.Is(err, unix.ENFILE) {
+			return os.NewSyscallError("open", err)
+		}
+		return nil // gone, a symlink, a socket or unreadable: left to the parent's rescan
+	}
+	var kev unix.Kevent_t
+	unix.SetKevent(&kev, fd, unix.EVFILT_VNODE, unix.EV_ADD|unix.EV_CLEAR|unix.EV_ENABLE)
+	kev.Fflags = kqueueFflags
+	if _, err := unix.Kevent(n.kq, []unix.Kevent_t{kev}, nil, nil); err != nil {
+		_ = unix.Close(fd)
+		return os.NewSyscallError("kevent", err)
+	}
+	n.fds[path], n.paths[fd], n.dirs[fd] = fd, path, isDir
+	return nil
+}
+
+func (n *kqueue) remove(path string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if fd, ok := n.fds[path]; ok {
+		n.forget(path, fd)
+	}
+}
+
+// forget closes fd, which also deletes its kevent.
+func (n *kqueue) forget(path string, fd int) {
+	delete(n.fds, path)
+	delete(n.paths, fd)
+	delete(n.dirs, fd)
+	_ = unix.Close(fd)
+}
+
+func (n *kqueue) read() {
+	defer n.wg.Done()
+	events := make([]unix.Kevent_t, 64)
+	timeout := unix.NsecToTimespec(int64(kqueuePollTimeout))
+	for {
+		select {
+		case <-n.done:
+			return
+		default:
+		}
+		k, err := unix.Kevent(n.kq, nil, events, &timeout)
+		if err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			select {
+			case n.errc <- os.NewSyscallError("kevent", err):
+			default:
+			}
+			return
+		}
+		dirs := map[string]struct{}{}
+		n.mu.Lock()
+		for _, e := range events[:k] {
+			fd := int(e.Ident)
+			p, ok := n.paths[fd]
+			if !ok {
+				continue
+			}
+			if !n.dirs[fd] || e.Fflags&(unix.NOTE_DELETE|unix.NOTE_RENAME) != 0 {
+				p = filepath.Dir(p)
+			}
+			dirs[p] = struct{}{}
+		}
+		n.mu.Unlock()
+		for d := range dirs {
+			select {
+			case n.ev <- d:
+			case <-n.done:
+				return
+			}
+		}
+	}
+}
+
+func (n *kqueue) close() error {
+	var err error
+	n.once.Do(func() {
+		close(n.done)
+		n.wg.Wait()
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		for path, fd := range n.fds {
+			n.forget(path, fd)
+		}
+		err = unix.Close(n.kq)
+	})
+	return err
+}
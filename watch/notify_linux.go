@@ -0,0 +1,160 @@
+//go:build linux
+
+package watch
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+const nativeBackend = "inotify"
+
+const inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_ATTRIB | unix.IN_CLOSE_WRITE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF |
+	unix.IN_ONLYDIR | unix.IN_DONT_FOLLOW | unix.IN_EXCL_UNLINK
+
+// inotify watches each directory; events on a directory's entries name that directory.
+type inotify struct {
+	fd int
+	f  *os.File // owns fd; reads through the runtime poller so Close unblocks them
+
+	mu     sync.Mutex
+	wds    map[int]string
+	paths  map[string]int
+	closed bool
+
+	ev   chan string
+	errc chan error
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func newNotifier(string) (notifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	n := &inotify{
+		fd:    fd,
+		f:     os.NewFile(uintptr(fd), "inotify"),
+		wds:   map[int]string{},
+		paths: map[string]int{},
+		ev:    make(chan string, 64),
+		errc:  make(chan error, 1),
+		done:  make(chan struct{}),
+	}
+	n.wg.Add(1)
+	go n.read()
+	return n, nil
+}
+
+func (n *inotify) events() <-chan string { return n.ev }
+func (n *inotify) errors() <-chan error  { return n.errc }
+
+func (n *inotify) add(path string, isDir bool) error {
+	if !isDir {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return nil
+	}
+	wd, err := unix.InotifyAddWatch(n.fd, path, inotifyMask)
+	if err != nil {
+		if errors.Is(err, unix.ENOENT) || errors.Is(err, unix.ENOTDIR) {
+			return nil // gone already; the parent's rescan notices
+		}
+		return os.NewSyscallError("inotify_add_watch", err)
+	}
+	// A renamed directory keeps its watch descriptor: move it to the new path.
+	if old, ok := n.wds[wd]; ok {
+		delete(n.paths, old)
+	}
+	n.wds[wd], n.paths[path] = path, wd
+	return nil
+}
+
+func (n *inotify) remove(path string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	wd, ok := n.paths[path]
+	if !ok || n.closed {
+		return
+	}
+	delete(n.paths, path)
+	delete(n.wds, wd)
+	_, _ = unix.InotifyRmWatch(n.fd, uint32(wd))
+}
+
+func (n *inotify) read() {
+	defer n.wg.Done()
+	buf := make([]byte, 64*1024)
+	for {
+		k, err := n.f.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				n.fail(err)
+			}
+			return
+		}
+		dirs := map[string]struct{}{}
+		n.mu.Lock()
+		for off := 0; off+unix.SizeofInotifyEvent <= k; {
+			wd := int(int32(binary.NativeEndian.Uint32(buf[off:])))
+			mask := binary.NativeEndian.Uint32(buf[off+4:])
+			off += unix.SizeofInotifyEvent + int(binary.NativeEndian.Uint32(buf[off+12:]))
+
+			if mask&unix.IN_Q_OVERFLOW != 0 {
+				dirs[""] = struct{}{}
+				continue
+			}
+			p, ok := n.wds[wd]
+			if !ok {
+				continue
+			}
+			if mask&unix.IN_IGNORED != 0 {
+				delete(n.wds, wd)
+				delete(n.paths, p)
+			}
+			if mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF|unix.IN_IGNORED) != 0 {
+				p = filepath.Dir(p)
+			}
+			dirs[p] = struct{}{}
+		}
+		n.mu.Unlock()
+		for d := range dirs {
+			select {
+			case n.ev <- d:
+			case <-n.done:
+				return
+			}
+		}
+	}
+}
+
+func (n *inotify) fail(err error) {
+	select {
+	case n.errc <- os.NewSyscallError("inotify read", err):
+	default:
+	}
+}
+
+func (n *inotify) close() error {
+	var err error
+	n.once.Do(func() {
+		close(n.done)
+		n.mu.Lock()
+		n.closed = true
+		err = n.f.Close()
+		n.mu.Unlock()
+		n.wg.Wait()
+	})
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !windows && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package watch
+
+import "errors"
+
+// nativeBackend is empty: this platform always polls.
+const nativeBackend = ""
+
+func newNotifier(string) (notifier, error) { return nil, errors.ErrUnsupported }
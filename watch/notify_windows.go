@@ -0,0 +1,170 @@
+//go:build windows
+
+package watch
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+const nativeBackend = "readdirectorychanges"
+
+const rdcwFilter = windows.FILE_NOTIFY_CHANGE_FILE_NAME | windows.FILE_NOTIFY_CHANGE_DIR_NAME |
+	windows.FILE_NOTIFY_CHANGE_ATTRIBUTES | windows.FILE_NOTIFY_CHANGE_SIZE |
+	windows.FILE_NOTIFY_CHANGE_LAST_WRITE | windows.FILE_NOTIFY_CHANGE_CREATION
+
+// rdcw watches the whole tree with one recursive ReadDirectoryChangesW handle on the root; each
+// notification names the parent directory of the changed entry.
+type rdcw struct {
+	root string
+	h    windows.Handle
+	ov   *windows.Overlapped // heap-allocated: the kernel writes to it while a read is pending
+	buf  []byte
+	stop windows.Handle
+
+	ev   chan string
+	errc chan error
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func newNotifier(root string) (notifier, error) {
+	p, err := windows.UTF16PtrFromString(root)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.CreateFile(p, windows.FILE_LIST_DIRECTORY,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE|windows.FILE_SHARE_DELETE,
+		nil, windows.OPEN_EXISTING, windows.FILE_FLAG_BACKUP_SEMANTICS|windows.FILE_FLAG_OVERLAPPED, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("CreateFile", err)
+	}
+	ovEvent, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		_ = windows.CloseHandle(h)
+		return nil, os.NewSyscallError("CreateEvent", err)
+	}
+	stop, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		_ = windows.CloseHandle(ovEvent)
+		_ = windows.CloseHandle(h)
+		return nil, os.NewSyscallError("CreateEvent", err)
+	}
+	n := &rdcw{
+		root: root,
+		h:    h,
+		ov:   &windows.Overlapped{HEvent: ovEvent},
+		buf:  make([]byte, 64*1024),
+		stop: stop,
+		ev:   make(chan string, 64),
+		errc: make(chan error, 1),
+		done: make(chan struct{}),
+	}
+	n.wg.Add(1)
+	go n.read()
+	return n, nil
+}
+
+func (n *rdcw) events() <-chan string { return n.ev }
+func (n *rdcw) errors() <-chan error  { return n.errc }
+
+// add and remove are no-ops: the root handle covers the whole tree.
+func (n *rdcw) add(string, bool) error { return nil }
+func (n *rdcw) remove(string)          {}
+
+func (n *rdcw) read() {
+	defer n.wg.Done()
+	for {
+		if err := windows.ResetEvent(n.ov.HEvent); err != nil {
+			n.fail(os.NewSyscallError("ResetEvent", err))
+			return
+		}
+		err := windows.ReadDirectoryChanges(n.h, &n.buf[0], uint32(len(n.buf)), true, rdcwFilter, nil, n.ov, 0)
+		if err != nil {
+			n.fail(os.NewSyscallError("ReadDirectoryChanges", err))
+			return
+		}
+		which, err := windows.WaitForMultipleObjects([]windows.Handle{n.ov.HEvent, n.stop}, false, windows.INFINITE)
+		if err != nil || which != windows.WAIT_OBJECT_0 {
+			var k uint32
+			_ = windows.CancelIoEx(n.h, n.ov)
+			_ = windows.GetOverlappedResult(n.h, n.ov, &k, true)
+			if err != nil {
+				n.fail(os.NewSyscallError("WaitForMultipleObjects", err))
+			}
+			return
+		}
+		var k uint32
+		if err := windows.GetOverlappedResult(n.h, n.ov, &k, false); err != nil {
+			if !errors.Is(err, windows.ERROR_NOTIFY_ENUM_DIR) {
+				n.fail(os.NewSyscallError("GetOverlappedResult", err))
+				return
+			}
+			k = 0
+		}
+		if !n.send(n.parse(k)) {
+			return
+		}
+	}
+}
+
+// parse returns the directories named by the first k bytes of FILE_NOTIFY_INFORMATION records in
+// buf. An empty read means the buffer overflowed: everything is rescanned.
+func (n *rdcw) parse(k uint32) map[string]struct{} {
+	dirs := map[string]struct{}{}
+	if k == 0 {
+		dirs[""] = struct{}{}
+		return dirs
+	}
+	for off := uint32(0); off+12 <= k; {
+		next := binary.LittleEndian.Uint32(n.buf[off:])
+		nameLen := binary.LittleEndian.Uint32(n.buf[off+8:])
+		name := make([]uint16, nameLen/2)
+		for i := range name {
+			name[i] = binary.LittleEndian.Uint16(n.buf[off+12+uint32(i)*2:])
+		}
+		dirs[filepath.Dir(filepath.Join(n.root, windows.UTF16ToString(name)))] = struct{}{}
+		if next == 0 {
+			break
+		}
+		off += next
+	}
+	return dirs
+}
+
+func (n *rdcw) send(dirs map[string]struct{}) bool {
+	for d := range dirs {
+		select {
+		case n.ev <- d:
+		case <-n.done:
+			return false
+		}
+	}
+	return true
+}
+
+func (n *rdcw) fail(err error) {
+	select {
+	case n.errc <- err:
+	default:
+	}
+}
+
+func (n *rdcw) close() error {
+	var err error
+	n.once.Do(func() {
+		close(n.done)
+		_ = windows.SetEvent(n.stop)
+		n.wg.Wait()
+		err = windows.CloseHandle(n.h)
+		_ = windows.CloseHandle(n.ov.HEvent)
+		_ = windows.CloseHandle(n.stop)
+	})
+	return err
+}
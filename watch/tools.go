@@ -0,0 +1,136 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/flexigpt/llmtools-go/internal/ignore"
+	"github.com/flexigpt/llmtools-go/internal/toolutil"
+	"github.com/flexigpt/llmtools-go/spec"
+)
+
+const changedFilesFuncID spec.FuncID = "github.com/flexigpt/llmtools-go/watch/changedfiles.ChangedFiles"
+
+var changedFilesTool = spec.Tool{
+	SchemaVersion: spec.SchemaVersion,
+	ID:            "01a14fb7-16f3-7740-a8fc-e12bb9a601b9",
+	Slug:          "changedfiles",
+	Version:       "v1.0.0",
+	DisplayName:   "Changed files",
+	Description:   "List the files and directories created, modified or deleted in the watched workspace since a cursor returned by an earlier call (e.g. by a build or dev server). Ignored paths (.git, .gitignore rules) are never reported. Pass the returned cursor to the next call to see only newer changes.",
+	Tags:          []string{"fs", "watch"},
+
+	ArgSchema: spec.JSONSchema(`{
+"$schema": "http://json-schema.org/draft-07/schema#",
+"type": "object",
+"properties": {
+	"cursor": {
+		"type": "string",
+		"description": "Cursor from a previous changedfiles call. Omit to list all changes since watching started."
+	},
+	"globs": {
+		"type": "array",
+		"items": { "type": "string" },
+		"description": "Only report paths matching one of these glob patterns, relative to the watched root, e.g. \"**/*.go\" or \"src/*\". ** matches any number of directories."
+	},
+	"maxResults": {
+		"type": "integer",
+		"description": "Return at most this many changes (0 = unlimited).",
+		"default": 200
+	}
+},
+"additionalProperties": false
+}`),
+	GoImpl: spec.GoToolImpl{FuncID: changedFilesFuncID},
+
+	CreatedAt:  spec.SchemaStartTime,
+	ModifiedAt: spec.SchemaStartTime,
+}
+
+type ChangedFilesArgs struct {
+	Cursor     string   `json:"cursor,omitempty"`
+	Globs      []string `json:"globs,omitempty"`
+	MaxResults int      `json:"maxResults,omitempty"`
+}
+
+type ChangedFilesOut struct {
+	Root    string   `json:"root"`
+	Backend string   `json:"backend"`
+	Cursor  string   `json:"cursor"`
+	Changes []Change `json:"changes"`
+	// Reset is set when the cursor was unknown or too old; some earlier changes may be missing.
+	Reset             bool `json:"reset,omitempty"`
+	ReachedMaxResults bool `json:"reachedMaxResults"`
+}
+
+func (w *Watcher) ChangedFilesTool() spec.Tool { return toolutil.CloneTool(changedFilesTool) }
+
+// ChangedFiles lists the changes since args.Cursor, filtered by args.Globs. The returned cursor
+// covers every change, including those past MaxResults or filtered out.
+func (w *Watcher) ChangedFiles(ctx context.Context, args ChangedFilesArgs) (*ChangedFilesOut, error) {
+	return toolutil.WithRecoveryResp(func() (*ChangedFilesOut, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if args.MaxResults < 0 {
+			return nil, fmt.Errorf("maxResults must not be negative: %d", args.MaxResults)
+		}
+		globs := make([]string, 0, len(args.Globs))
+		for _, g := range args.Globs {
+			g = strings.TrimSpace(filepath.ToSlash(g))
+			if g == "" {
+				continue
+			}
+			if path.IsAbs(g) || filepath.IsAbs(g) {
+				return nil, fmt.Errorf("glob must be relative to the watched root: %q", g)
+			}
+			g = path.Clean(g)
+			if !ignore.ValidGlob(g) {
+				return nil, fmt.Errorf("invalid glob: %q", g)
+			}
+			globs = append(globs, g)
+		}
+
+		cs, err := w.Changes(args.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		out := &ChangedFilesOut{
+			Root:    w.root,
+			Backend: w.Backend(),
+			Cursor:  cs.Cursor,
+			Changes: []Change{},
+			Reset:   cs.Reset,
+		}
+		for _, c := range cs.Changes {
+			if !w.matchAny(globs, c.Path) {
+				continue
+			}
+			if args.MaxResults > 0 && len(out.Changes) == args.MaxResults {
+				out.ReachedMaxResults = true
+				break
+			}
+			out.Changes = append(out.Changes, c)
+		}
+		return out, nil
+	})
+}
+
+func (w *Watcher) matchAny(globs []string, abs string) bool {
+	if len(globs) == 0 {
+		return true
+	}
+	rel, ok := w.Rel(abs)
+	if !ok {
+		return false
+	}
+	for _, g := range globs {
+		if ignore.Glob(g, rel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,577 @@
+// Package watch reports which files under a directory tree were created, modified or deleted, so a
+// model can ask what changed since its last look instead of polling statpath.
+//
+// A host starts a Watcher on a workspace subtree with New. It scans the tree once, then listens for
+// native change notifications (inotify on Linux, kqueue on macOS and the BSDs, ReadDirectoryChangesW
+// on Windows) and rescans the directories they name. Where notifications are unavailable (other
+// platforms, non-OS vfs backends, exhausted watch limits) it polls the tree instead.
+//
+// Changes are kept in a bounded in-memory log with a cursor. Changes (and the changedfiles tool,
+// see llmtools.RegisterWatchTools) return the net change per path since a cursor. Paths matched by
+// the ignore rules (.git, the tools' temp files, the root .gitignore and WithIgnore patterns) are
+// neither watched nor reported.
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/internal/ignore"
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+const (
+	// DefaultPollInterval is how often the polling backend rescans the tree.
+	DefaultPollInterval = 2 * time.Second
+	// DefaultMaxEvents is how many change events are retained for cursors.
+	DefaultMaxEvents = 10000
+
+	// BackendPoll is the Backend of a Watcher that polls.
+	BackendPoll = "poll"
+
+	// debounce batches bursts of native notifications into one rescan.
+	debounce = 50 * time.Millisecond
+)
+
+// ErrClosed is returned by Changes after Close.
+var ErrClosed = errors.New("watcher is closed")
+
+// defaultIgnore holds the rules applied before the root .gitignore and WithIgnore patterns.
+var defaultIgnore = []string{".git/", ".tmp-llmtools-*"}
+
+// Kind is the kind of change to a path.
+type Kind string
+
+const (
+	KindCreated  Kind = "created"
+	KindModified Kind = "modified"
+	KindDeleted  Kind = "deleted"
+)
+
+// Change is the net change to one path since a cursor.
+type Change struct {
+	Path  string `json:"path"`
+	Kind  Kind   `json:"kind"`
+	IsDir bool   `json:"isDir,omitempty"`
+}
+
+// ChangeSet is the result of Changes.
+type ChangeSet struct {
+	Changes []Change `json:"changes"`
+	// Cursor is passed to the next Changes call to see only newer changes.
+	Cursor string `json:"cursor"`
+	// Reset is set when the given cursor was from another watcher or older than the retained
+	// events: Changes then covers only what is retained, and earlier changes may be missing.
+	Reset bool `json:"reset,omitempty"`
+}
+
+// notifier is a native change notification backend. It only hints at which directories to
+// rescan; the Watcher's snapshot diff decides what changed.
+type notifier interface {
+	// add starts watching path; backends with recursive or directory-level watches ignore files.
+	add(path string, isDir bool) error
+	// remove stops watching path.
+	remove(path string)
+	// events delivers absolute directories whose entries may have changed, or "" for the whole tree.
+	events() <-chan string
+	// errors delivers a failure after which the backend stops delivering events.
+	errors() <-chan error
+	close() error
+}
+
+type event struct {
+	seq   uint64
+	rel   string
+	kind  Kind
+	isDir bool
+}
+
+type entry struct {
+	isDir    bool
+	size     int64
+	mode     fs.FileMode
+	mod      time.Time
+	children map[string]struct{} // names of tracked children, for directories
+}
+
+// Watcher tracks changes under a directory tree. It is safe for concurrent use.
+type Watcher struct {
+	fsys         vfs.FS
+	root         string
+	patterns     []string
+	gitignore    bool
+	pollInterval time.Duration
+	maxEvents    int
+	polling      bool
+
+	rules *ignore.Rules
+	id    string // distinguishes the cursors of different watchers
+
+	mu      sync.Mutex
+	snap    map[string]*entry // keyed by slash path relative to root; "." is root
+	log     []event
+	seq     uint64
+	dropped uint64 // seq of the newest event dropped from log
+	dirty   map[string]struct{}
+	full    bool // the whole tree needs a rescan
+	n       notifier
+	backend string
+	closed  bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type Option func(*Watcher) error
+
+// WithFS sets the filesystem to watch (default: the OS filesystem). Non-OS filesystems are polled.
+func WithFS(fsys vfs.FS) Option {
+	return func(w *Watcher) error {
+		w.fsys = vfs.OrOS(fsys)
+		return nil
+	}
+}
+
+// WithIgnore adds gitignore-style patterns, relative to the root, for paths that are not watched.
+// They are applied after the root .gitignore, so they can re-include paths with "!".
+func WithIgnore(patterns ...string) Option {
+	return func(w *Watcher) error {
+		w.patterns = append(w.patterns, patterns...)
+		return nil
+	}
+}
+
+// WithGitignore sets whether the .gitignore file at the root is applied (default true). It is read
+// once by New; nested .gitignore files are not consulted.
+func WithGitignore(enabled bool) Option {
+	return func(w *Watcher) error {
+		w.gitignore = enabled
+		return nil
+	}
+}
+
+// WithPolling makes the watcher poll even where native notifications are available.
+func WithPolling() Option {
+	return func(w *Watcher) error {
+		w.polling = true
+		return nil
+	}
+}
+
+// WithPollInterval sets how often the polling backend rescans the tree (default DefaultPollInterval).
+// Changes also rescans before answering when polling, so the interval only bounds how stale the
+// log gets between calls.
+func WithPollInterval(d time.Duration) Option {
+	return func(w *Watcher) error {
+		if d <= 0 {
+			return errors.New("poll interval must be positive")
+		}
+		w.pollInterval = d
+		return nil
+	}
+}
+
+// WithMaxEvents caps the retained change events (default DefaultMaxEvents). Cursors older than the
+// retained events get a Reset change set.
+func WithMaxEvents(n int) Option {
+	return func(w *Watcher) error {
+		if n <= 0 {
+			return errors.New("max events must be positive")
+		}
+		w.maxEvents = n
+		return nil
+	}
+}
+
+// New scans the directory root (absolute) and starts watching it until Close.
+func New(root string, opts ...Option) (*Watcher, error) {
+	if !filepath.IsAbs(root) {
+		return nil, fmt.Errorf("watch root must be absolute: %q", root)
+	}
+	w := &Watcher{
+		fsys:         vfs.OS(),
+		root:         filepath.Clean(root),
+		gitignore:    true,
+		pollInterval: DefaultPollInterval,
+		maxEvents:    DefaultMaxEvents,
+		id:           strconv.FormatInt(time.Now().UnixNano(), 36),
+		dirty:        map[string]struct{}{},
+		backend:      BackendPoll,
+		done:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(w); err != nil {
+			return nil, err
+		}
+	}
+	st, err := w.fsys.Stat(w.root)
+	if err != nil {
+		return nil, err
+	}
+	if !st.IsDir() {
+		return nil, fmt.Errorf("watch root is not a directory: %s", w.root)
+	}
+
+	w.rules = ignore.New(defaultIgnore...)
+	if w.gitignore {
+		data, err := vfs.ReadFile(w.fsys, filepath.Join(w.root, ".gitignore"))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		w.rules.Add(strings.Split(string(data), "\n")...)
+	}
+	w.rules.Add(w.patterns...)
+
+	if vfs.IsOS(w.fsys) && !w.polling && nativeBackend != "" {
+		if n, err := newNotifier(w.root); err == nil {
+			w.n, w.backend = n, nativeBackend
+		}
+	}
+
+	w.mu.Lock()
+	w.snap = map[string]*entry{".": {isDir: true, children: map[string]struct{}{}}}
+	if err := w.watch(w.root, true); err != nil {
+		w.fallback()
+	}
+	w.scanDir(".", true, false)
+	n := w.n
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go w.run(n)
+	return w, nil
+}
+
+// Root returns the watched directory.
+func (w *Watcher) Root() string { return w.root }
+
+// FS returns the watched filesystem.
+func (w *Watcher) FS() vfs.FS { return w.fsys }
+
+// Backend names the mechanism in use: "inotify", "kqueue", "readdirectorychanges" or BackendPoll.
+// A native backend that fails (e.g. out of watches) falls back to polling.
+func (w *Watcher) Backend() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.backend
+}
+
+// Close stops watching. Changes fails with ErrClosed afterwards.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.n != nil {
+		err := w.n.close()
+		w.n = nil
+		return err
+	}
+	return nil
+}
+
+// Changes returns the net change per path since cursor ("" means since the watcher started),
+// sorted by path. A path created and deleted again in between is omitted; one deleted and
+// recreated is reported as modified. Native notifications may lag by a few milliseconds.
+func (w *Watcher) Changes(cursor string) (*ChangeSet, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, ErrClosed
+	}
+
+	since, reset, err := w.parseCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if w.n == nil {
+		w.full = true
+	}
+	w.flush()
+	if since < w.dropped {
+		reset = true
+	}
+
+	type net struct {
+		existed, exists, isDir bool
+	}
+	byPath := map[string]*net{}
+	for _, e := range w.log {
+		if e.seq <= since {
+			continue
+		}
+		n := byPath[e.rel]
+		if n == nil {
+			n = &net{existed: e.kind != KindCreated}
+			byPath[e.rel] = n
+		}
+		n.exists, n.isDir = e.kind != KindDeleted, e.isDir
+	}
+	out := &ChangeSet{Changes: []Change{}, Cursor: w.id + "." + strconv.FormatUint(w.seq, 10), Reset: reset}
+	for rel, n := range byPath {
+		c := Change{Path: w.abs(rel), IsDir: n.isDir}
+		switch {
+		case !n.existed && n.exists:
+			c.Kind = KindCreated
+		case n.existed && !n.exists:
+			c.Kind = KindDeleted
+		case n.existed && n.exists:
+			c.Kind = KindModified
+		default:
+			continue
+		}
+		out.Changes = append(out.Changes, c)
+	}
+	slices.SortFunc(out.Changes, func(a, b Change) int { return strings.Compare(a.Path, b.Path) })
+	return out, nil
+}
+
+// parseCursor returns the sequence number after which events are new. A cursor from another
+// watcher is a reset to the start.
+func (w *Watcher) parseCursor(cursor string) (uint64, bool, error) {
+	cursor = strings.TrimSpace(cursor)
+	if cursor == "" {
+		return 0, false, nil
+	}
+	id, seq, ok := strings.Cut(cursor, ".")
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if !ok || err != nil {
+		return 0, false, fmt.Errorf("invalid cursor: %q", cursor)
+	}
+	if id != w.id || n > w.seq {
+		return 0, true, nil
+	}
+	return n, false, nil
+}
+
+// Rel returns the slash-separated path of abs relative to the root, or false if abs is outside it.
+func (w *Watcher) Rel(abs string) (string, bool) {
+	rel, err := filepath.Rel(w.root, abs)
+	if err != nil || (rel != "." && !filepath.IsLocal(rel)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func (w *Watcher) abs(rel string) string {
+	if rel == "." {
+		return w.root
+	}
+	return filepath.Join(w.root, filepath.FromSlash(rel))
+}
+
+func (w *Watcher) run(n notifier) {
+	defer w.wg.Done()
+	var evc <-chan string
+	var errc <-chan error
+	if n != nil {
+		evc, errc = n.events(), n.errors()
+	}
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	var flushc <-chan time.Time
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case dir := <-evc:
+			w.mu.Lock()
+			w.markDirty(dir)
+			w.mu.Unlock()
+			if flushc == nil {
+				flushc = time.After(debounce)
+			}
+		case <-errc:
+			evc, errc = nil, nil
+			w.mu.Lock()
+			w.fallback()
+			w.mu.Unlock()
+		case <-flushc:
+			flushc = nil
+			w.mu.Lock()
+			w.flush()
+			w.mu.Unlock()
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.n == nil {
+				w.full = true
+				w.flush()
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// fallback switches to polling after the native backend failed; the next rescan catches up.
+func (w *Watcher) fallback() {
+	if w.n == nil {
+		return
+	}
+	_ = w.n.close()
+	w.n, w.backend, w.full = nil, BackendPoll, true
+}
+
+func (w *Watcher) markDirty(dir string) {
+	rel, ok := w.Rel(dir)
+	if dir == "" || !ok {
+		w.full = true
+		return
+	}
+	w.dirty[rel] = struct{}{}
+}
+
+// flush rescans the dirty directories (or the whole tree), recording changes.
+func (w *Watcher) flush() {
+	if w.full {
+		w.full = false
+		clear(w.dirty)
+		w.scanDir(".", true, true)
+		return
+	}
+	for rel := range w.dirty {
+		delete(w.dirty, rel)
+		// A directory that is gone or not tracked yet is picked up by its nearest tracked ancestor.
+		for rel != "." {
+			if e := w.snap[rel]; e != nil && e.isDir {
+				break
+			}
+			rel = path.Dir(rel)
+		}
+		w.scanDir(rel, false, true)
+	}
+}
+
+// scanDir diffs the children of the tracked directory rel against the filesystem. New directories
+// are scanned recursively; existing ones only when deep.
+func (w *Watcher) scanDir(rel string, deep, emit bool) {
+	dir := w.snap[rel]
+	if dir == nil || !dir.isDir {
+		return
+	}
+	des, err := w.fsys.ReadDir(w.abs(rel))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return // unreadable: keep what we know
+	}
+	if err != nil && rel != "." {
+		w.scanDir(path.Dir(rel), false, emit)
+		return
+	}
+
+	seen := make(map[string]struct{}, len(des))
+	for _, de := range des {
+		crel := childRel(rel, de.Name())
+		info, err := de.Info()
+		if err != nil {
+			continue // vanished since ReadDir
+		}
+		if w.rules.Match(crel, info.IsDir()) {
+			continue
+		}
+		seen[de.Name()] = struct{}{}
+		old := w.snap[crel]
+		switch {
+		case old == nil:
+			w.track(rel, de.Name(), info, emit)
+		case old.isDir != info.IsDir():
+			w.untrack(crel, emit)
+			w.track(rel, de.Name(), info, emit)
+		case old.isDir:
+			if deep {
+				w.scanDir(crel, true, emit)
+			}
+		case old.size != info.Size() || old.mode != info.Mode() || !old.mod.Equal(info.ModTime()):
+			old.size, old.mode, old.mod = info.Size(), info.Mode(), info.ModTime()
+			if emit {
+				w.record(crel, KindModified, false)
+			}
+		}
+	}
+	for name := range dir.children {
+		if _, ok := seen[name]; !ok {
+			w.untrack(childRel(rel, name), emit)
+		}
+	}
+}
+
+func (w *Watcher) track(parent, name string, info fs.FileInfo, emit bool) {
+	rel := childRel(parent, name)
+	e := &entry{isDir: info.IsDir(), size: info.Size(), mode: info.Mode(), mod: info.ModTime()}
+	if e.isDir {
+		e.children = map[string]struct{}{}
+	}
+	w.snap[rel] = e
+	w.snap[parent].children[name] = struct{}{}
+	if emit {
+		w.record(rel, KindCreated, e.isDir)
+	}
+	// Watch before listing, so entries created meanwhile are not missed.
+	if err := w.watch(w.abs(rel), e.isDir); err != nil {
+		w.fallback()
+	}
+	if e.isDir {
+		w.scanDir(rel, true, emit)
+	}
+}
+
+func (w *Watcher) untrack(rel string, emit bool) {
+	e := w.snap[rel]
+	if e == nil {
+		return
+	}
+	for name := range e.children {
+		w.untrack(childRel(rel, name), emit)
+	}
+	delete(w.snap, rel)
+	if p := w.snap[path.Dir(rel)]; p != nil {
+		delete(p.children, path.Base(rel))
+	}
+	if w.n != nil {
+		w.n.remove(w.abs(rel))
+	}
+	if emit {
+		w.record(rel, KindDeleted, e.isDir)
+	}
+}
+
+func (w *Watcher) watch(abs string, isDir bool) error {
+	if w.n == nil {
+		return nil
+	}
+	return w.n.add(abs, isDir)
+}
+
+func (w *Watcher) record(rel string, kind Kind, isDir bool) {
+	w.seq++
+	w.log = append(w.log, event{seq: w.seq, rel: rel, kind: kind, isDir: isDir})
+	if over := len(w.log) - w.maxEvents; over > 0 {
+		w.dropped = w.log[over-1].seq
+		w.log = slices.Delete(w.log, 0, over)
+	}
+}
+
+func childRel(parent, name string) string {
+	if parent == "." {
+		return name
+	}
+	return parent + "/" + name
+}
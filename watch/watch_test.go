@@ -0,0 +1,407 @@
+package watch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flexigpt/llmtools-go/vfs"
+)
+
+func newMemWatcher(t *testing.T, files map[string]string, opts ...Option) (*vfs.MemFS, string, *Watcher) {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join(string(filepath.Separator), "ws"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := vfs.NewMemFS()
+	if err := m.MkdirAll(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for rel, content := range files {
+		writeFile(t, m, filepath.Join(root, filepath.FromSlash(rel)), content)
+	}
+	w, err := New(root, append([]Option{WithFS(m)}, opts...)...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	return m, root, w
+}
+
+func writeFile(t *testing.T, fsys vfs.FS, p, content string) {
+	t.Helper()
+	if err := fsys.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := vfs.WriteFile(fsys, p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// summarize renders changes as sorted "kind rel[/]" strings.
+func summarize(w *Watcher, changes []Change) []string {
+	out := make([]string, 0, len(changes))
+	for _, c := range changes {
+		rel, _ := w.Rel(c.Path)
+		if c.IsDir {
+			rel += "/"
+		}
+		out = append(out, string(c.Kind)+" "+rel)
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestWatcher_Changes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		opts  []Option
+		// steps run in order, with a rescan after each.
+		steps []func(t *testing.T, fsys vfs.FS, root string)
+		want  []string
+	}{
+		{
+			name:  "create modify delete",
+			files: map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"},
+			steps: []func(*testing.T, vfs.FS, string){
+				func(t *testing.T, fsys vfs.FS, root string) {
+					writeFile(t, fsys, filepath.Join(root, "new.txt"), "n")
+					writeFile(t, fsys, filepath.Join(root, "a.txt"), "a longer")
+					if err := fsys.Remove(filepath.Join(root, "b.txt")); err != nil {
+						t.Fatal(err)
+					}
+				},
+			},
+			want: []string{"created new.txt", "deleted b.txt", "modified a.txt"},
+		},
+		{
+			name: "new and removed directories",
+			files: map[string]string{
+				"old/x.txt":     "x",
+				"old/sub/y.txt": "y",
+			},
+			steps: []func(*testing.T, vfs.FS, string){
+				func(t *testing.T, fsys vfs.FS, root string) {
+					writeFile(t, fsys, filepath.Join(root, "d", "e", "f.txt"), "f")
+					if err := fsys.RemoveAll(filepath.Join(root, "old")); err != nil {
+						t.Fatal(err)
+					}
+				},
+			},
+			want: []string{
+				"created d/", "created d/e/", "created d/e/f.txt",
+				"deleted old/", "deleted old/sub/", "deleted old/sub/y.txt", "deleted old/x.txt",
+			},
+		},
+		{
+			name: "created then deleted is omitted, deleted then recreated is modified",
+			files: map[string]string{
+				"keep.txt": "k",
+			},
+			steps: []func(*testing.T, vfs.FS, string){
+				func(t *testing.T, fsys vfs.FS, root string) {
+					writeFile(t, fsys, filepath.Join(root, "tmp.txt"), "t")
+					if err := fsys.Remove(filepath.Join(root, "keep.txt")); err != nil {
+						t.Fatal(err)
+					}
+				},
+				func(t *testing.T, fsys vfs.FS, root string) {
+					if err := fsys.Remove(filepath.Join(root, "tmp.txt")); err != nil {
+						t.Fatal(err)
+					}
+					writeFile(t, fsys, filepath.Join(root, "keep.txt"), "k")
+				},
+			},
+			want: []string{"modified keep.txt"},
+		},
+		{
+			name:  "file replaced by directory",
+			files: map[string]string{"p": "file"},
+			steps: []func(*testing.T, vfs.FS, string){
+				func(t *testing.T, fsys vfs.FS, root string) {
+					if err := fsys.Remove(filepath.Join(root, "p")); err != nil {
+						t.Fatal(err)
+					}
+					writeFile(t, fsys, filepath.Join(root, "p", "q.txt"), "q")
+				},
+			},
+			want: []string{"created p/q.txt", "modified p/"},
+		},
+		{
+			name: "ignore rules",
+			files: map[string]string{
+				".gitignore": "*.log\nbuild/\n!keep.log\n",
+			},
+			opts: []Option{WithIgnore("*.tmp")},
+			steps: []func(*testing.T, vfs.FS, string){
+				func(t *testing.T, fsys vfs.FS, root string) {
+					for _, rel := range []string{
+						"x.log", "keep.log", "sub/y.log", "build/out.bin", ".git/HEAD",
+						".tmp-llmtools-write-1", "a.tmp", "src/main.go",
+					} {
+						writeFile(t, fsys, filepath.Join(root, filepath.FromSlash(rel)), rel)
+					}
+				},
+			},
+			want: []string{"created keep.log", "created src/", "created src/main.go", "created sub/"},
+		},
+		{
+			name:  "gitignore disabled",
+			files: map[string]string{".gitignore": "*.log\n"},
+			opts:  []Option{WithGitignore(false)},
+			steps: []func(*testing.T, vfs.FS, string){
+				func(t *testing.T, fsys vfs.FS, root string) {
+					writeFile(t, fsys, filepath.Join(root, "x.log"), "x")
+				},
+			},
+			want: []string{"created x.log"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			m, root, w := newMemWatcher(t, tc.files, tc.opts...)
+			if w.Backend() != BackendPoll {
+				t.Fatalf("Backend = %q, want %q for a non-OS filesystem", w.Backend(), BackendPoll)
+			}
+			for _, step := range tc.steps {
+				step(t, m, root)
+				if _, err := w.Changes(""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cs, err := w.Changes("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summarize(w, cs.Changes); !slices.Equal(got, tc.want) {
+				t.Fatalf("changes = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWatcher_Cursor(t *testing.T) {
+	t.Parallel()
+
+	m, root, w := newMemWatcher(t, nil, WithMaxEvents(3))
+	cs, err := w.Changes("")
+	if err != nil || len(cs.Changes) != 0 || cs.Reset {
+		t.Fatalf("initial Changes = %+v, %v", cs, err)
+	}
+	cur := cs.Cursor
+
+	writeFile(t, m, filepath.Join(root, "a.txt"), "a")
+	cs, err = w.Changes(cur)
+	if err != nil || !slices.Equal(summarize(w, cs.Changes), []string{"created a.txt"}) {
+		t.Fatalf("Changes = %+v, %v", cs, err)
+	}
+	cur = cs.Cursor
+	if cs, err = w.Changes(cur); err != nil || len(cs.Changes) != 0 {
+		t.Fatalf("Changes with current cursor = %+v, %v", cs, err)
+	}
+
+	// More events than retained: the old cursor is reset.
+	for _, name := range []string{"b", "c", "d", "e"} {
+		writeFile(t, m, filepath.Join(root, name), name)
+	}
+	if cs, err = w.Changes(cur); err != nil || !cs.Reset || len(cs.Changes) != 3 {
+		t.Fatalf("Changes past max events = %+v, %v", cs, err)
+	}
+
+	_, _, other := newMemWatcher(t, nil)
+	if cs, err = other.Changes(cur); err != nil || !cs.Reset {
+		t.Fatalf("Changes with a foreign cursor = %+v, %v", cs, err)
+	}
+	if _, err := w.Changes("bogus"); err == nil {
+		t.Fatal("expected error for an invalid cursor")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Changes(""); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Changes after Close err = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestWatcher_New_Errors(t *testing.T) {
+	t.Parallel()
+
+	m, root, _ := newMemWatcher(t, map[string]string{"f.txt": "f"})
+	tests := []struct {
+		name string
+		root string
+		opts []Option
+	}{
+		{name: "relative root", root: "ws"},
+		{name: "missing root", root: filepath.Join(root, "missing")},
+		{name: "file root", root: filepath.Join(root, "f.txt")},
+		{name: "bad poll interval", root: root, opts: []Option{WithPollInterval(0)}},
+		{name: "bad max events", root: root, opts: []Option{WithMaxEvents(-1)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if w, err := New(tc.root, append([]Option{WithFS(m)}, tc.opts...)...); err == nil {
+				_ = w.Close()
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestWatcher_ChangedFiles(t *testing.T) {
+	t.Parallel()
+
+	m, root, w := newMemWatcher(t, nil)
+	for _, rel := range []string{"main.go", "pkg/a.go", "pkg/a_test.go", "docs/readme.md"} {
+		writeFile(t, m, filepath.Join(root, filepath.FromSlash(rel)), rel)
+	}
+
+	tests := []struct {
+		name      string
+		args      ChangedFilesArgs
+		want      []string
+		wantMax   bool
+		wantError string
+	}{
+		{
+			name: "all",
+			args: ChangedFilesArgs{},
+			want: []string{
+				"created docs/", "created docs/readme.md", "created main.go",
+				"created pkg/", "created pkg/a.go", "created pkg/a_test.go",
+			},
+		},
+		{
+			name: "globs",
+			args: ChangedFilesArgs{Globs: []string{"**/*.go", "./docs/*"}},
+			want: []string{"created docs/readme.md", "created main.go", "created pkg/a.go", "created pkg/a_test.go"},
+		},
+		{
+			name: "single directory glob",
+			args: ChangedFilesArgs{Globs: []string{"*.go"}},
+			want: []string{"created main.go"},
+		},
+		{
+			name:    "max results",
+			args:    ChangedFilesArgs{Globs: []string{"**/*.go"}, MaxResults: 2},
+			want:    []string{"created main.go", "created pkg/a.go"},
+			wantMax: true,
+		},
+		{name: "invalid glob", args: ChangedFilesArgs{Globs: []string{"pkg/["}}, wantError: "invalid glob"},
+		{name: "absolute glob", args: ChangedFilesArgs{Globs: []string{"/x/*"}}, wantError: "relative"},
+		{name: "negative max", args: ChangedFilesArgs{MaxResults: -1}, wantError: "negative"},
+		{name: "bad cursor", args: ChangedFilesArgs{Cursor: "x"}, wantError: "invalid cursor"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			out, err := w.ChangedFiles(t.Context(), tc.args)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("err = %v, want %q", err, tc.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summarize(w, out.Changes); !slices.Equal(got, tc.want) {
+				t.Fatalf("changes = %q, want %q", got, tc.want)
+			}
+			if out.ReachedMaxResults != tc.wantMax || out.Root != root || out.Backend != BackendPoll || out.Cursor == "" {
+				t.Fatalf("out = %+v", out)
+			}
+		})
+	}
+}
+
+func TestWatcher_OS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantBackend string
+	}{
+		{name: "native", wantBackend: nativeBackend},
+		{name: "poll", opts: []Option{WithPolling(), WithPollInterval(20 * time.Millisecond)}, wantBackend: BackendPoll},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			root := t.TempDir()
+			if err := os.WriteFile(filepath.Join(root, "old.txt"), []byte("old"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			w, err := New(root, tc.opts...)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			defer w.Close()
+			want := tc.wantBackend
+			if want == "" {
+				want = BackendPoll
+			}
+			if w.Backend() != want {
+				t.Fatalf("Backend = %q, want %q", w.Backend(), want)
+			}
+
+			cs, err := w.Changes("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cur := cs.Cursor
+			if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "sub", "new.txt"), []byte("new"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Remove(filepath.Join(root, "old.txt")); err != nil {
+				t.Fatal(err)
+			}
+			cur = waitFor(t, w, cur, []string{"created sub/", "created sub/new.txt", "deleted old.txt"})
+
+			// The new directory is watched too.
+			if err := os.WriteFile(filepath.Join(root, "sub", "new.txt"), []byte("newer content"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			waitFor(t, w, cur, []string{"modified sub/new.txt"})
+		})
+	}
+}
+
+// waitFor polls w until the changes since cur are want, and returns the new cursor.
+func waitFor(t *testing.T, w *Watcher, cur string, want []string) string {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		cs, err := w.Changes(cur)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := summarize(w, cs.Changes)
+		if slices.Equal(got, want) {
+			return cs.Cursor
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("changes = %q, want %q", got, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}